@@ -0,0 +1,145 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A Backfill replays the runs a CronJob would have had over a past window, e.g. to reprocess the data of last month
+after a bug fix. Every activation of the schedule of the CronJob between `start` (inclusive) and `end` (exclusive) is
+a run, with a Job from the current template of the CronJob. The Job carries its logical time in the
+`batch.example.com/scheduled-at` annotation, on the Job and on its Pods, like the scheduled runs.
+
+The runs start in order, at most `maxParallelism` at a time. A failed run is not retried, the Backfill fails once all
+its runs finished and at least one of them failed. A Backfill is not changed once created, a new one replays
+another window.
+*/
+
+// BackfillPhase is the phase of a Backfill.
+// +kubebuilder:validation:Enum=Running;Succeeded;Failed;Invalid
+type BackfillPhase string
+
+const (
+	// BackfillRunning is a Backfill with runs left to finish.
+	BackfillRunning BackfillPhase = "Running"
+	// BackfillSucceeded is a Backfill whose runs all succeeded.
+	BackfillSucceeded BackfillPhase = "Succeeded"
+	// BackfillFailed is a Backfill whose runs finished, at least one of them failing.
+	BackfillFailed BackfillPhase = "Failed"
+	// BackfillInvalid is a Backfill which can not run, see its message.
+	BackfillInvalid BackfillPhase = "Invalid"
+)
+
+// BackfillSpec defines the desired state of Backfill
+type BackfillSpec struct {
+	// The name of the CronJob whose runs are replayed, in the namespace of the Backfill.
+	CronJob string `json:"cronJob"`
+
+	// The start of the window, inclusive.
+	Start metav1.Time `json:"start"`
+
+	// The end of the window, exclusive. It may not be in the future.
+	End metav1.Time `json:"end"`
+
+	//+kubebuilder:validation:Minimum=1
+
+	// The number of runs running at the same time at most. Defaults to 1.
+	// +optional
+	MaxParallelism *int32 `json:"maxParallelism,omitempty"`
+}
+
+// BackfillRunStatus is the state of a run of a Backfill.
+type BackfillRunStatus struct {
+	// The logical time of the run.
+	ScheduledTime metav1.Time `json:"scheduledTime"`
+
+	// The Job of the run.
+	// +optional
+	Job string `json:"job,omitempty"`
+
+	// The phase of the run, empty until its Job is created.
+	// +optional
+	Phase JobRunPhase `json:"phase,omitempty"`
+}
+
+// BackfillStatus defines the observed state of Backfill
+type BackfillStatus struct {
+	// The phase of the Backfill.
+	// +optional
+	Phase BackfillPhase `json:"phase,omitempty"`
+
+	// Why the Backfill is invalid.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The number of runs of the window.
+	// +optional
+	Total int32 `json:"total,omitempty"`
+
+	// The number of runs in progress.
+	// +optional
+	Active int32 `json:"active,omitempty"`
+
+	// The number of runs which succeeded.
+	// +optional
+	Succeeded int32 `json:"succeeded,omitempty"`
+
+	// The number of runs which failed.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// The runs of the window, in order.
+	// +optional
+	Runs []BackfillRunStatus `json:"runs,omitempty"`
+
+	// When all the runs finished.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="CronJob",type=string,JSONPath=`.spec.cronJob`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.total`
+//+kubebuilder:printcolumn:name="Succeeded",type=integer,JSONPath=`.status.succeeded`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Backfill is the Schema for the backfills API
+type Backfill struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BackfillSpec   `json:"spec,omitempty"`
+	Status BackfillStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BackfillList contains a list of Backfill
+type BackfillList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Backfill `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Backfill{}, &BackfillList{})
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A Calendar is a list of dates, e.g. the holidays of the company. The dates are either listed in the spec, or imported
+from an iCalendar (ICS) feed which the calendar controller downloads on every refresh interval, so the calendar
+published by the HR system does not need to be copied by hand. The imported dates are kept in the status: when the
+feed can not be downloaded or parsed, the dates of the last successful import stay in place.
+*/
+
+// CalendarDate is a day of a Calendar.
+type CalendarDate struct {
+	//+kubebuilder:validation:Pattern=`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`
+
+	// The day, as YYYY-MM-DD.
+	Date string `json:"date"`
+
+	// What the day is, e.g. `New Year's Day`.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// CalendarSource is an iCalendar feed the dates of a Calendar are imported from.
+type CalendarSource struct {
+	//+kubebuilder:validation:Pattern=`^https?://`
+
+	// The HTTP or HTTPS URL of the feed.
+	URL string `json:"url"`
+
+	// How often the feed is downloaded. Defaults to 24 hours, may not be less than 5 minutes.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// CalendarSpec defines the desired state of Calendar
+type CalendarSpec struct {
+	// The dates of the calendar, in addition to the imported ones.
+	// +optional
+	Dates []CalendarDate `json:"dates,omitempty"`
+
+	// The feed the dates are imported from.
+	// +optional
+	Source *CalendarSource `json:"source,omitempty"`
+}
+
+// CalendarStatus defines the observed state of Calendar
+type CalendarStatus struct {
+	// The generation of the spec the feed was last imported for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The dates imported from the feed, sorted.
+	// +optional
+	ImportedDates []CalendarDate `json:"importedDates,omitempty"`
+
+	// The number of the events of the feed which were skipped, because they could not be parsed.
+	// +optional
+	SkippedEvents int32 `json:"skippedEvents,omitempty"`
+
+	// When the feed was last imported successfully.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// The conditions of the Calendar, `Synced` is false when the last import failed.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.url`
+//+kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="Synced")].status`
+//+kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+
+// Calendar is the Schema for the calendars API
+type Calendar struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CalendarSpec   `json:"spec,omitempty"`
+	Status CalendarStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CalendarList contains a list of Calendar
+type CalendarList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Calendar `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Calendar{}, &CalendarList{})
+}
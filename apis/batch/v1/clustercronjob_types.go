@@ -0,0 +1,148 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A ClusterCronJob runs the same Job in many namespaces: on every activation it creates one Job in each namespace
+matched by its namespace selector, e.g. a nightly cleanup in every tenant namespace. Its status aggregates how the
+Jobs of the latest run did, per namespace.
+*/
+
+// ClusterCronJobSpec defines the desired state of ClusterCronJob
+type ClusterCronJobSpec struct {
+	// The namespaces to create the Jobs in, all of them if unset.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	//+kubebuilder:validation:MinLength=0
+
+	// The schedule in Cron format, see https://en.wikipedia.org/wiki/Cron.
+	Schedule string `json:"schedule"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// Optional deadline in seconds for starting a run if it misses its scheduled time for any reason.
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+	// This flag tells the controller to suspend subsequent runs, it does not apply to the Jobs already started.
+	// +optional
+	Suspend *bool `json:"suspend,omitempty"`
+
+	// Specifies the Job created in every namespace.
+	JobTemplate batchv1beta1.JobTemplateSpec `json:"jobTemplate"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The number of finished runs to retain, with their Jobs in all the namespaces. Defaults to 3.
+	// +optional
+	RunsHistoryLimit *int32 `json:"runsHistoryLimit,omitempty"`
+}
+
+// ClusterCronJobStatus defines the observed state of ClusterCronJob
+type ClusterCronJobStatus struct {
+	// Information when was the last time the job was successfully scheduled.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// The latest run.
+	// +optional
+	LastRun *ClusterCronJobRun `json:"lastRun,omitempty"`
+}
+
+// ClusterCronJobRun is the state of the Jobs of a run.
+type ClusterCronJobRun struct {
+	// The scheduled time of the run.
+	ScheduledTime metav1.Time `json:"scheduledTime"`
+
+	// The number of Jobs still running.
+	// +optional
+	Active int32 `json:"active,omitempty"`
+
+	// The number of Jobs which succeeded.
+	// +optional
+	Succeeded int32 `json:"succeeded,omitempty"`
+
+	// The number of Jobs which failed.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// The Job of every namespace, the failed ones first, at most 100 of them are listed.
+	// +optional
+	Namespaces []NamespaceJobStatus `json:"namespaces,omitempty"`
+}
+
+// NamespaceJobPhase is the phase of the Job of a namespace.
+// +kubebuilder:validation:Enum=Active;Succeeded;Failed
+type NamespaceJobPhase string
+
+const (
+	// NamespaceJobActive means the Job still runs.
+	NamespaceJobActive NamespaceJobPhase = "Active"
+	// NamespaceJobSucceeded means the Job succeeded.
+	NamespaceJobSucceeded NamespaceJobPhase = "Succeeded"
+	// NamespaceJobFailed means the Job failed.
+	NamespaceJobFailed NamespaceJobPhase = "Failed"
+)
+
+// NamespaceJobStatus is the Job of a run in a namespace.
+type NamespaceJobStatus struct {
+	// The namespace of the Job.
+	Namespace string `json:"namespace"`
+
+	// The name of the Job.
+	JobName string `json:"jobName"`
+
+	// The phase of the Job.
+	Phase NamespaceJobPhase `json:"phase"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Active",type=integer,JSONPath=`.status.lastRun.active`
+//+kubebuilder:printcolumn:name="Succeeded",type=integer,JSONPath=`.status.lastRun.succeeded`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.lastRun.failed`
+//+kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
+
+// ClusterCronJob is the Schema for the clustercronjobs API
+type ClusterCronJob struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterCronJobSpec   `json:"spec,omitempty"`
+	Status ClusterCronJobStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterCronJobList contains a list of ClusterCronJob
+type ClusterCronJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterCronJob `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterCronJob{}, &ClusterCronJobList{})
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A ClusterCronJobPolicy lets the platform admins set the guardrails of many namespaces at once. It has the fields of a
+CronJobPolicy and applies to the namespaces matched by its namespace selector. The CronJobPolicies of a namespace can
+only tighten the cluster policies selecting it: a CronJob has to satisfy all of them, so a looser namespaced field has
+no effect and is reported in the status of the CronJobPolicy.
+*/
+
+// ClusterCronJobPolicySpec defines the restrictions on the CronJobs of the selected namespaces
+type ClusterCronJobPolicySpec struct {
+	// The namespaces the policy applies to, all of them if unset.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	CronJobPolicySpec `json:",inline"`
+}
+
+// ClusterCronJobPolicyStatus defines the observed state of ClusterCronJobPolicy
+type ClusterCronJobPolicyStatus struct {
+	// The generation of the policy the CronJobs were last evaluated against.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The number of namespaces selected by the policy.
+	// +optional
+	SelectedNamespaces int32 `json:"selectedNamespaces,omitempty"`
+
+	// The number of CronJobs of the selected namespaces violating the policy.
+	// +optional
+	ViolatingCronJobs int32 `json:"violatingCronJobs,omitempty"`
+
+	// The violations of the CronJobs, at most 100 of them are listed.
+	// +optional
+	Violations []PolicyViolation `json:"violations,omitempty"`
+
+	// When the CronJobs were last evaluated.
+	// +optional
+	LastEvaluationTime *metav1.Time `json:"lastEvaluationTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Namespaces",type=integer,JSONPath=`.status.selectedNamespaces`
+//+kubebuilder:printcolumn:name="Violating",type=integer,JSONPath=`.status.violatingCronJobs`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterCronJobPolicy is the Schema for the clustercronjobpolicies API
+type ClusterCronJobPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterCronJobPolicySpec   `json:"spec,omitempty"`
+	Status ClusterCronJobPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterCronJobPolicyList contains a list of ClusterCronJobPolicy
+type ClusterCronJobPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterCronJobPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterCronJobPolicy{}, &ClusterCronJobPolicyList{})
+}
@@ -58,6 +58,11 @@ type CronJobSpec struct {
 	// The schedule in Cron format, see https://en.wikipedia.org/wiki/Cron.
 	Schedule string `json:"schedule"`
 
+	// The time zone name for the given schedule, see https://en.wikipedia.org/wiki/List_of_tz_database_time_zones.
+	// If not specified, the schedule is interpreted in the time zone of the controller.
+	// +optional
+	TimeZone *string `json:"timeZone,omitempty"`
+
 	//+kubebuilder:validation:Minimum=0
 
 	// Optional deadline in seconds for starting the job if it misses scheduled
@@ -78,9 +83,32 @@ type CronJobSpec struct {
 	// +optional
 	Suspend *bool `json:"suspend,omitempty"`
 
-	// Specifies the job that will be created when executing a CronJob.
+	// Specifies the job that will be created when executing a CronJob. Required by the Job runner.
+	// +optional
 	JobTemplate batchv1beta1.JobTemplateSpec `json:"jobTemplate"`
 
+	// The JobTemplate of the namespace the job template is taken from. When set, jobTemplate is filled in from the
+	// JobTemplate by the defaulting webhook, and kept in sync with it by the JobTemplate controller.
+	// +optional
+	JobTemplateRef *corev1.LocalObjectReference `json:"jobTemplateRef,omitempty"`
+
+	// Specifies what runs the CronJob.
+	// Valid values are:
+	// - "Job" (default): creates a Job from jobTemplate;
+	// - "ArgoWorkflow": submits an Argo Workflow from argoWorkflow, requires the ArgoWorkflowRunner feature gate;
+	// - "TektonPipeline": creates a Tekton PipelineRun from tektonPipeline, requires the TektonPipelineRunner feature
+	// gate
+	// +optional
+	Runner Runner `json:"runner,omitempty"`
+
+	// The Argo Workflow submitted by the ArgoWorkflow runner.
+	// +optional
+	ArgoWorkflow *ArgoWorkflowSpec `json:"argoWorkflow,omitempty"`
+
+	// The Tekton PipelineRun created by the TektonPipeline runner.
+	// +optional
+	TektonPipeline *TektonPipelineSpec `json:"tektonPipeline,omitempty"`
+
 	//+kubebuilder:validation:Minimum=0
 
 	// The number of successful finished jobs to retain.
@@ -94,6 +122,72 @@ type CronJobSpec struct {
 	// This is a pointer to distinguish between explicit zero and not specified.
 	// +optional
 	FailedJobsHistoryLimit *int32 `json:"failedJobsHistoryLimit,omitempty"`
+
+	// The NotificationChannels of the namespace told when the Jobs finish.
+	// +optional
+	Notifications []CronJobNotification `json:"notifications,omitempty"`
+
+	// The service level objective of the scheduled runs, tracked in the JobRuns and the status.
+	// +optional
+	SLO *CronJobSLO `json:"slo,omitempty"`
+}
+
+// CronJobSLO is the service level objective of the scheduled runs of a CronJob. A run meets it when it starts and
+// succeeds within the given delays after its scheduled time.
+type CronJobSLO struct {
+	// The longest a run may start after its scheduled time.
+	// +optional
+	MaxStartDelay *metav1.Duration `json:"maxStartDelay,omitempty"`
+
+	// The longest a run may take to succeed after its scheduled time. A failed run misses it.
+	// +optional
+	CompleteWithin *metav1.Duration `json:"completeWithin,omitempty"`
+
+	// The percentage of the runs meeting the objective, e.g. `99.5`. Defaults to `99`.
+	// +optional
+	Objective string `json:"objective,omitempty"`
+
+	// The number of the last runs the compliance is computed over. Defaults to 30.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Window *int32 `json:"window,omitempty"`
+}
+
+// SLOResult tells whether a run met the service level objective of its CronJob.
+// +kubebuilder:validation:Enum=Met;Breached
+type SLOResult string
+
+const (
+	// SLOMet is a run which started and succeeded in time.
+	SLOMet SLOResult = "Met"
+
+	// SLOBreached is a run which started late, or did not succeed in time.
+	SLOBreached SLOResult = "Breached"
+)
+
+// NotificationEvent is an event of a CronJob a NotificationChannel can be told about.
+// +kubebuilder:validation:Enum=JobSucceeded;JobFailed;MissedDeadline
+type NotificationEvent string
+
+const (
+	// JobSucceededEvent is a Job of the CronJob which completed.
+	JobSucceededEvent NotificationEvent = "JobSucceeded"
+
+	// JobFailedEvent is a Job of the CronJob which failed.
+	JobFailedEvent NotificationEvent = "JobFailed"
+
+	// MissedDeadlineEvent is a scheduled run of the CronJob which was not started before its starting deadline.
+	MissedDeadlineEvent NotificationEvent = "MissedDeadline"
+)
+
+// CronJobNotification sends the events of the CronJob to a NotificationChannel.
+type CronJobNotification struct {
+	// The name of the NotificationChannel, in the namespace of the CronJob.
+	Channel string `json:"channel"`
+
+	// The events sent to the channel. Defaults to JobFailed.
+	// +optional
+	Events []NotificationEvent `json:"events,omitempty"`
 }
 
 /*
@@ -122,6 +216,83 @@ const (
 	ReplaceConcurrent ConcurrencyPolicy = "Replace"
 )
 
+// Runner describes what runs the CronJob.
+// +kubebuilder:validation:Enum=Job;ArgoWorkflow;TektonPipeline
+type Runner string
+
+const (
+	// JobRunner creates a Job from the template of the CronJob.
+	JobRunner Runner = "Job"
+
+	// ArgoWorkflowRunner submits an Argo Workflow from a WorkflowTemplate.
+	ArgoWorkflowRunner Runner = "ArgoWorkflow"
+
+	// TektonPipelineRunner creates a Tekton PipelineRun from a Pipeline.
+	TektonPipelineRunner Runner = "TektonPipeline"
+)
+
+// ArgoWorkflowSpec describes the Argo Workflow submitted for a run.
+type ArgoWorkflowSpec struct {
+	// The WorkflowTemplate of the Workflow.
+	WorkflowTemplateRef ArgoWorkflowTemplateRef `json:"workflowTemplateRef"`
+
+	// The parameters passed to the Workflow, overriding the ones of the WorkflowTemplate.
+	// +optional
+	Parameters []ArgoWorkflowParameter `json:"parameters,omitempty"`
+}
+
+// ArgoWorkflowTemplateRef references a WorkflowTemplate, or a ClusterWorkflowTemplate.
+type ArgoWorkflowTemplateRef struct {
+	//+kubebuilder:validation:MinLength=1
+
+	// The name of the WorkflowTemplate, in the namespace of the CronJob.
+	Name string `json:"name"`
+
+	// References a ClusterWorkflowTemplate instead.
+	// +optional
+	ClusterScope bool `json:"clusterScope,omitempty"`
+}
+
+// ArgoWorkflowParameter is a parameter of an Argo Workflow.
+type ArgoWorkflowParameter struct {
+	// The name of the parameter.
+	Name string `json:"name"`
+
+	// The value of the parameter.
+	Value string `json:"value"`
+}
+
+// TektonPipelineSpec describes the Tekton PipelineRun created for a run.
+type TektonPipelineSpec struct {
+	// The Pipeline run, in the namespace of the CronJob.
+	PipelineRef TektonPipelineRef `json:"pipelineRef"`
+
+	// The parameters passed to the Pipeline.
+	// +optional
+	Params []TektonPipelineParam `json:"params,omitempty"`
+
+	// The ServiceAccount the tasks of the Pipeline run as. Defaults to the one of the Tekton configuration.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// TektonPipelineRef references a Pipeline.
+type TektonPipelineRef struct {
+	//+kubebuilder:validation:MinLength=1
+
+	// The name of the Pipeline.
+	Name string `json:"name"`
+}
+
+// TektonPipelineParam is a parameter of a Tekton Pipeline.
+type TektonPipelineParam struct {
+	// The name of the parameter.
+	Name string `json:"name"`
+
+	// The value of the parameter.
+	Value string `json:"value"`
+}
+
 /*
  Next, let's design our status, which holds observed state.  It contains any information
  we want users or other controllers to be able to easily obtain.
@@ -142,6 +313,34 @@ type CronJobStatus struct {
 	// Information when was the last time the job was successfully scheduled.
 	// +optional
 	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// When the last successful run of the CronJob finished.
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+
+	// The conditions of the CronJob, `MissedSchedule` is true while its last scheduled run has not started.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// The compliance of the last runs with the service level objective.
+	// +optional
+	SLO *CronJobSLOStatus `json:"slo,omitempty"`
+}
+
+// CronJobSLOStatus is the compliance of the last runs of a CronJob with its service level objective.
+type CronJobSLOStatus struct {
+	// The runs of the window with a result.
+	Runs int32 `json:"runs"`
+
+	// The runs of the window which met the objective.
+	MetRuns int32 `json:"metRuns"`
+
+	// The ratio of the runs which met the objective, e.g. `0.9667`, empty without runs.
+	// +optional
+	Ratio string `json:"ratio,omitempty"`
+
+	// Whether the ratio reaches the objective.
+	Compliant bool `json:"compliant"`
 }
 
 /*
@@ -0,0 +1,128 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A CronJobGroup manages the CronJobs of its namespace selected by its label selector as one, e.g. to suspend the 200
+CronJobs of a team during an incident. The group controller applies the fields set in the spec of the group to every
+member, and keeps applying them to the CronJobs joining the group, an unset field is left alone. Resuming the members
+is setting `suspend` to false, unsetting it leaves them as they are.
+
+The status of the group sums up the health of its members: how many are suspended, how many Jobs are running, and
+which members last run failed, as recorded in their JobRuns.
+*/
+
+// CronJobGroupSpec defines the desired state of CronJobGroup
+type CronJobGroupSpec struct {
+	// The CronJobs of the namespace in the group.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Whether the members are suspended.
+	// +optional
+	Suspend *bool `json:"suspend,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The number of successful finished jobs the members retain.
+	// +optional
+	SuccessfulJobsHistoryLimit *int32 `json:"successfulJobsHistoryLimit,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The number of failed finished jobs the members retain.
+	// +optional
+	FailedJobsHistoryLimit *int32 `json:"failedJobsHistoryLimit,omitempty"`
+
+	// The time zone of the schedules of the members.
+	// +optional
+	TimeZone *string `json:"timeZone,omitempty"`
+}
+
+// CronJobGroupStatus defines the observed state of CronJobGroup
+type CronJobGroupStatus struct {
+	// The generation of the group last applied to the members.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The number of CronJobs in the group.
+	// +optional
+	Members int32 `json:"members,omitempty"`
+
+	// The number of suspended members.
+	// +optional
+	Suspended int32 `json:"suspended,omitempty"`
+
+	// The number of running Jobs of the members.
+	// +optional
+	Active int32 `json:"active,omitempty"`
+
+	// The number of members whose last run failed.
+	// +optional
+	Failing int32 `json:"failing,omitempty"`
+
+	// The members whose last run failed, at most 100 of them are listed.
+	// +optional
+	FailingCronJobs []string `json:"failingCronJobs,omitempty"`
+
+	// The members the group could not be applied to, e.g. rejected by a CronJobPolicy, at most 100 of them are
+	// listed.
+	// +optional
+	UpdateErrors []CronJobGroupUpdateError `json:"updateErrors,omitempty"`
+}
+
+// CronJobGroupUpdateError is a member the group could not be applied to.
+type CronJobGroupUpdateError struct {
+	// The name of the CronJob.
+	CronJob string `json:"cronJob"`
+
+	// Why the CronJob could not be updated.
+	Message string `json:"message"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Members",type=integer,JSONPath=`.status.members`
+//+kubebuilder:printcolumn:name="Suspended",type=integer,JSONPath=`.status.suspended`
+//+kubebuilder:printcolumn:name="Active",type=integer,JSONPath=`.status.active`
+//+kubebuilder:printcolumn:name="Failing",type=integer,JSONPath=`.status.failing`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CronJobGroup is the Schema for the cronjobgroups API
+type CronJobGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CronJobGroupSpec   `json:"spec,omitempty"`
+	Status CronJobGroupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CronJobGroupList contains a list of CronJobGroup
+type CronJobGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronJobGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CronJobGroup{}, &CronJobGroupList{})
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A CronJobPolicy lets the platform teams put guardrails on the CronJobs of a namespace without touching the operator.
+The validating webhook enforces every CronJobPolicy of the namespace on the CronJobs created or updated there, and the
+policy controller reports the existing CronJobs violating it in its status, e.g. the ones created before the policy.
+All the fields of the spec are optional, an unset field does not restrict anything.
+*/
+
+// CronJobPolicySpec defines the restrictions on the CronJobs of the namespace
+type CronJobPolicySpec struct {
+	// The schedules the CronJobs may use. The schedules are compared field by field, a `*` field matches any value,
+	// e.g. `0 * * * *` allows the CronJobs running at any minute zero. Descriptors like `@daily` are expanded.
+	// +optional
+	AllowedSchedules []string `json:"allowedSchedules,omitempty"`
+
+	// The minimum time between two activations of a CronJob, which limits how frequently it may run.
+	// +optional
+	MinInterval *metav1.Duration `json:"minInterval,omitempty"`
+
+	// The time zones the CronJobs may be scheduled in. The CronJobs without a time zone are rejected if set.
+	// +optional
+	AllowedTimeZones []string `json:"allowedTimeZones,omitempty"`
+
+	// The label keys every CronJob must have.
+	// +optional
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+
+	// The bounds of the number of successful finished jobs to retain.
+	// +optional
+	SuccessfulJobsHistoryLimit *HistoryLimitBounds `json:"successfulJobsHistoryLimit,omitempty"`
+
+	// The bounds of the number of failed finished jobs to retain.
+	// +optional
+	FailedJobsHistoryLimit *HistoryLimitBounds `json:"failedJobsHistoryLimit,omitempty"`
+
+	// The concurrency policies the CronJobs may not use.
+	// +optional
+	ForbiddenConcurrencyPolicies []ConcurrencyPolicy `json:"forbiddenConcurrencyPolicies,omitempty"`
+
+	// The registries the images of the job template may come from.
+	// +optional
+	ImageRegistries *ImageRegistryRules `json:"imageRegistries,omitempty"`
+}
+
+// HistoryLimitBounds is the inclusive range a history limit must be in.
+type HistoryLimitBounds struct {
+	//+kubebuilder:validation:Minimum=0
+
+	// The lowest allowed limit.
+	// +optional
+	Min *int32 `json:"min,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The highest allowed limit.
+	// +optional
+	Max *int32 `json:"max,omitempty"`
+}
+
+// ImageRegistryRules restricts the registries of the images. The entries match the images under a registry or a
+// repository path, e.g. `registry.example.com/team-a/*`, and the path segments may contain `*` wildcards.
+type ImageRegistryRules struct {
+	// The registries the images must come from, any registry if empty.
+	// +optional
+	Allowed []string `json:"allowed,omitempty"`
+
+	// The registries the images may not come from, they take precedence over the allowed ones.
+	// +optional
+	Denied []string `json:"denied,omitempty"`
+}
+
+// CronJobPolicyStatus defines the observed state of CronJobPolicy
+type CronJobPolicyStatus struct {
+	// The generation of the policy the CronJobs were last evaluated against.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The number of CronJobs of the namespace violating the policy.
+	// +optional
+	ViolatingCronJobs int32 `json:"violatingCronJobs,omitempty"`
+
+	// The violations of the CronJobs, at most 100 of them are listed.
+	// +optional
+	Violations []PolicyViolation `json:"violations,omitempty"`
+
+	// When the CronJobs were last evaluated.
+	// +optional
+	LastEvaluationTime *metav1.Time `json:"lastEvaluationTime,omitempty"`
+
+	// The fields looser than a ClusterCronJobPolicy selecting the namespace. They have no effect, the cluster policy
+	// still applies.
+	// +optional
+	IneffectiveFields []string `json:"ineffectiveFields,omitempty"`
+}
+
+// PolicyViolation is a field of a CronJob violating the policy.
+type PolicyViolation struct {
+	// The namespace of the CronJob, only set for the cluster policies.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// The name of the CronJob.
+	CronJob string `json:"cronJob"`
+
+	// The path of the violating field, e.g. `spec.schedule`.
+	Field string `json:"field"`
+
+	// Why the field violates the policy.
+	Message string `json:"message"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Violating",type=integer,JSONPath=`.status.violatingCronJobs`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CronJobPolicy is the Schema for the cronjobpolicies API
+type CronJobPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CronJobPolicySpec   `json:"spec,omitempty"`
+	Status CronJobPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CronJobPolicyList contains a list of CronJobPolicy
+type CronJobPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronJobPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CronJobPolicy{}, &CronJobPolicyList{})
+}
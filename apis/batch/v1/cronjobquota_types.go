@@ -0,0 +1,110 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A CronJobQuota caps what the CronJobs of its namespace may use, like a ResourceQuota does for the native objects. The
+validating webhook rejects the CronJobs created beyond `maxCronJobs`, and the CronJob controller does not start the
+runs beyond `maxActiveJobs` or `maxRunsPerHour`. The quota controller reports the usage in the status of the quota.
+All the fields of the spec are optional, an unset field does not limit anything. When several quotas are in a
+namespace, all of them apply.
+*/
+
+// CronJobQuotaSpec defines the limits of the CronJobs of the namespace
+type CronJobQuotaSpec struct {
+	//+kubebuilder:validation:Minimum=0
+
+	// The maximum number of CronJobs.
+	// +optional
+	MaxCronJobs *int32 `json:"maxCronJobs,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The maximum number of Jobs of the CronJobs running at the same time.
+	// +optional
+	MaxActiveJobs *int32 `json:"maxActiveJobs,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The maximum number of runs of the CronJobs started within an hour.
+	// +optional
+	MaxRunsPerHour *int32 `json:"maxRunsPerHour,omitempty"`
+}
+
+// CronJobQuotaUsage is what the CronJobs of the namespace use.
+type CronJobQuotaUsage struct {
+	// The number of CronJobs.
+	CronJobs int32 `json:"cronJobs"`
+
+	// The number of Jobs of the CronJobs running.
+	ActiveJobs int32 `json:"activeJobs"`
+
+	// The number of runs of the CronJobs started within the last hour.
+	RunsLastHour int32 `json:"runsLastHour"`
+}
+
+// CronJobQuotaStatus defines the observed state of CronJobQuota
+type CronJobQuotaStatus struct {
+	// The generation of the quota last observed.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The usage of the namespace, when it was last counted.
+	// +optional
+	Used *CronJobQuotaUsage `json:"used,omitempty"`
+
+	// When the usage was last counted.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=cronjobquotas
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="CronJobs",type=integer,JSONPath=`.status.used.cronJobs`
+//+kubebuilder:printcolumn:name="Max CronJobs",type=integer,JSONPath=`.spec.maxCronJobs`,priority=1
+//+kubebuilder:printcolumn:name="Active",type=integer,JSONPath=`.status.used.activeJobs`
+//+kubebuilder:printcolumn:name="Max Active",type=integer,JSONPath=`.spec.maxActiveJobs`,priority=1
+//+kubebuilder:printcolumn:name="Runs Last Hour",type=integer,JSONPath=`.status.used.runsLastHour`
+//+kubebuilder:printcolumn:name="Max Runs Per Hour",type=integer,JSONPath=`.spec.maxRunsPerHour`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CronJobQuota is the Schema for the cronjobquotas API
+type CronJobQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CronJobQuotaSpec   `json:"spec,omitempty"`
+	Status CronJobQuotaStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CronJobQuotaList contains a list of CronJobQuota
+type CronJobQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronJobQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CronJobQuota{}, &CronJobQuotaList{})
+}
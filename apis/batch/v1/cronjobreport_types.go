@@ -0,0 +1,97 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A CronJobReport summarizes the CronJobs of its namespace, so the owners of the namespace look at one object instead of
+every CronJob. The report controller keeps one per namespace with CronJobs, named after CronJobReportName, and
+refreshes it when the CronJobs or their Jobs change and every minute, since the time since the last success grows on
+its own.
+*/
+
+// CronJobReportName is the name of the CronJobReport of a namespace.
+const CronJobReportName = "cronjob-report"
+
+// CronJobReportStatus defines the observed state of CronJobReport
+type CronJobReportStatus struct {
+	// The number of the CronJobs of the namespace.
+	CronJobs int32 `json:"cronJobs"`
+
+	// The number of the suspended CronJobs.
+	Suspended int32 `json:"suspended"`
+
+	// The number of the CronJobs whose last finished run failed.
+	Failing int32 `json:"failing"`
+
+	// The names of the failing CronJobs, sorted.
+	// +optional
+	FailingCronJobs []string `json:"failingCronJobs,omitempty"`
+
+	// When the next run of the namespace is scheduled.
+	// +optional
+	NextRunTime *metav1.Time `json:"nextRunTime,omitempty"`
+
+	// The name of the CronJob of the next run.
+	// +optional
+	NextRunCronJob string `json:"nextRunCronJob,omitempty"`
+
+	// The longest time since the last success of a CronJob, or since its creation if it never succeeded.
+	// +optional
+	LongestSinceSuccess *metav1.Duration `json:"longestSinceSuccess,omitempty"`
+
+	// The name of the CronJob which did not succeed for the longest time.
+	// +optional
+	LongestSinceSuccessCronJob string `json:"longestSinceSuccessCronJob,omitempty"`
+
+	// When the report was last refreshed.
+	// +optional
+	UpdateTime *metav1.Time `json:"updateTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="CronJobs",type=integer,JSONPath=`.status.cronJobs`
+//+kubebuilder:printcolumn:name="Suspended",type=integer,JSONPath=`.status.suspended`
+//+kubebuilder:printcolumn:name="Failing",type=integer,JSONPath=`.status.failing`
+//+kubebuilder:printcolumn:name="Next Run",type=date,JSONPath=`.status.nextRunTime`
+//+kubebuilder:printcolumn:name="Stalest",type=string,JSONPath=`.status.longestSinceSuccessCronJob`,priority=1
+//+kubebuilder:printcolumn:name="Updated",type=date,JSONPath=`.status.updateTime`
+
+// CronJobReport is the Schema for the cronjobreports API
+type CronJobReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status CronJobReportStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CronJobReportList contains a list of CronJobReport
+type CronJobReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronJobReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CronJobReport{}, &CronJobReportList{})
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A CronJobSet stamps out CronJobs from one template, like a ReplicaSet does Pods: a CronJob per namespace matched by
+its namespace selector, and per parameter tuple. The `$(name)` references in the string fields of the template are
+replaced with the values of the tuple, `$(namespace)` with the namespace of the CronJob. The set controller creates the
+missing CronJobs, updates the ones whose template changed and deletes the ones no longer generated, and reports every
+instance in the status.
+*/
+
+// CronJobSetSpec defines the desired state of CronJobSet
+type CronJobSetSpec struct {
+	// The namespaces to create the CronJobs in, for the parameter tuples without a namespace. No namespace is selected
+	// if unset.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// The parameter tuples, a CronJob is generated per tuple, in its namespace or in every selected namespace. A single
+	// tuple without values is used if empty.
+	// +optional
+	Parameters []CronJobSetParameters `json:"parameters,omitempty"`
+
+	// The template of the CronJobs.
+	Template CronJobSetTemplate `json:"template"`
+}
+
+// CronJobSetParameters is a parameter tuple of a CronJobSet.
+type CronJobSetParameters struct {
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+
+	// The name of the tuple, appended to the name of the set for the names of its CronJobs. It may only be empty for
+	// a single tuple.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// The namespace of the CronJob of the tuple. The tuple applies to every selected namespace if unset.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// The values of the parameters, by name.
+	// +optional
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// CronJobSetTemplate is the template of the CronJobs of a set.
+type CronJobSetTemplate struct {
+	// The labels of the CronJobs.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// The annotations of the CronJobs.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// The spec of the CronJobs.
+	Spec CronJobSpec `json:"spec"`
+}
+
+// CronJobSetStatus defines the observed state of CronJobSet
+type CronJobSetStatus struct {
+	// The generation of the set last applied to the CronJobs.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Why the CronJobs could not be generated, e.g. two parameter tuples with the same name.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The number of CronJobs generated by the set.
+	// +optional
+	Instances int32 `json:"instances,omitempty"`
+
+	// The number of CronJobs up to date with the template.
+	// +optional
+	UpToDate int32 `json:"upToDate,omitempty"`
+
+	// The number of CronJobs which could not be created or updated.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// The CronJobs of the set, the failed ones first, at most 100 of them are listed.
+	// +optional
+	InstanceStatuses []CronJobSetInstanceStatus `json:"instanceStatuses,omitempty"`
+}
+
+// CronJobSetInstanceStatus is the state of a CronJob of a set.
+type CronJobSetInstanceStatus struct {
+	// The namespace of the CronJob.
+	Namespace string `json:"namespace"`
+
+	// The name of the CronJob.
+	Name string `json:"name"`
+
+	// The name of the parameter tuple of the CronJob.
+	// +optional
+	Parameters string `json:"parameters,omitempty"`
+
+	// Whether the CronJob is up to date with the template.
+	UpToDate bool `json:"upToDate"`
+
+	// Why the CronJob could not be created or updated.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The number of running Jobs of the CronJob.
+	// +optional
+	Active int32 `json:"active,omitempty"`
+
+	// When the CronJob last started a run.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Instances",type=integer,JSONPath=`.status.instances`
+//+kubebuilder:printcolumn:name="Up To Date",type=integer,JSONPath=`.status.upToDate`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CronJobSet is the Schema for the cronjobsets API
+type CronJobSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CronJobSetSpec   `json:"spec,omitempty"`
+	Status CronJobSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CronJobSetList contains a list of CronJobSet
+type CronJobSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronJobSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CronJobSet{}, &CronJobSetList{})
+}
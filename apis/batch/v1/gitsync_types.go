@@ -0,0 +1,111 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A GitSync keeps the CronJobs of its namespace in step with a directory of a Git repository: the gitsync controller
+clones the branch on every interval, and applies the CronJob manifests of the directory with server-side apply. The
+CronJobs it applied which are no longer in the directory are deleted, the other CronJobs of the namespace are left
+alone. It is a lightweight GitOps for this one kind, where installing Flux or Argo CD would be too much.
+*/
+
+// GitSyncSpec defines the desired state of GitSync
+type GitSyncSpec struct {
+	//+kubebuilder:validation:Pattern=`^https?://`
+
+	// The HTTP or HTTPS URL of the repository.
+	URL string `json:"url"`
+
+	// The branch which is synced. Defaults to `main`.
+	// +optional
+	Branch string `json:"branch,omitempty"`
+
+	// The directory of the repository the manifests are in, including its subdirectories. Defaults to the root of the
+	// repository.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// How often the branch is cloned. Defaults to 5 minutes, may not be less than 1 minute.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// The secret of the namespace with the `username` and the `password`, or a token as the password, the repository
+	// is cloned with. The repository is cloned anonymously if not set.
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// Whether the syncs are suspended, the CronJobs already applied are left as they are.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// GitSyncStatus defines the observed state of GitSync
+type GitSyncStatus struct {
+	// The generation of the spec the branch was last synced for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The commit which was last synced.
+	// +optional
+	LastSyncedCommit string `json:"lastSyncedCommit,omitempty"`
+
+	// When the branch was last synced successfully.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// The names of the CronJobs applied by the last sync, sorted.
+	// +optional
+	CronJobs []string `json:"cronJobs,omitempty"`
+
+	// The conditions of the GitSync, `Synced` is false when the last sync failed.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.url`
+//+kubebuilder:printcolumn:name="Commit",type=string,JSONPath=`.status.lastSyncedCommit`
+//+kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="Synced")].status`
+//+kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+
+// GitSync is the Schema for the gitsyncs API
+type GitSync struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GitSyncSpec   `json:"spec,omitempty"`
+	Status GitSyncStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GitSyncList contains a list of GitSync
+type GitSyncList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GitSync `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GitSync{}, &GitSyncList{})
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A JobRun records a run of a CronJob: when it was scheduled, what started it, its Job and how it ended. The Jobs are
+deleted per the history limits of the CronJob, the JobRuns are kept for the TTL of the controller config, so the runs
+of the last week can be listed with `kubectl get jobruns -l batch.example.com/cronjob=<name>`.
+
+The JobRuns are created by the jobrun controller for the Jobs of the CronJobs, named like their Job. They are owned
+by their CronJob and deleted with it.
+*/
+
+// JobRunTrigger is what started a run.
+// +kubebuilder:validation:Enum=Scheduled;Backfill;Manual
+type JobRunTrigger string
+
+const (
+	// ScheduledTrigger is a run started by the schedule of its CronJob.
+	ScheduledTrigger JobRunTrigger = "Scheduled"
+	// BackfillTrigger is a past run of a CronJob replayed by a Backfill.
+	BackfillTrigger JobRunTrigger = "Backfill"
+	// ManualTrigger is a run started on demand, outside of the schedule of its CronJob.
+	ManualTrigger JobRunTrigger = "Manual"
+)
+
+// JobRunPhase is the phase of a run.
+// +kubebuilder:validation:Enum=Running;Succeeded;Failed;Lost
+type JobRunPhase string
+
+const (
+	// JobRunRunning is a run whose Job did not finish yet.
+	JobRunRunning JobRunPhase = "Running"
+	// JobRunSucceeded is a run whose Job completed.
+	JobRunSucceeded JobRunPhase = "Succeeded"
+	// JobRunFailed is a run whose Job failed.
+	JobRunFailed JobRunPhase = "Failed"
+	// JobRunLost is a run whose Job was deleted before it was seen finishing.
+	JobRunLost JobRunPhase = "Lost"
+)
+
+// JobRunSpec defines the desired state of JobRun
+type JobRunSpec struct {
+	// The name of the CronJob of the run.
+	CronJob string `json:"cronJob"`
+
+	// The time the run was scheduled at.
+	ScheduledTime metav1.Time `json:"scheduledTime"`
+
+	// What started the run.
+	Trigger JobRunTrigger `json:"trigger"`
+
+	// Who started a manual run, e.g. `grpc:ci.example.com` or `http:data-sensor`.
+	// +optional
+	TriggeredBy string `json:"triggeredBy,omitempty"`
+
+	// The ID of the run, shared by its Job, the pods of the Job, its events and its notifications.
+	// +optional
+	RunID string `json:"runID,omitempty"`
+
+	// The service level objective of the CronJob when the run was scheduled.
+	// +optional
+	SLO *CronJobSLO `json:"slo,omitempty"`
+
+	// The Job of the run.
+	Job corev1.ObjectReference `json:"job"`
+}
+
+// JobRunStatus defines the observed state of JobRun
+type JobRunStatus struct {
+	// The phase of the run.
+	// +optional
+	Phase JobRunPhase `json:"phase,omitempty"`
+
+	// When the Job started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// When the Job finished, or was found deleted.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// How long the Job ran.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Why the Job failed, e.g. `BackoffLimitExceeded`.
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// A human-readable description of the failure.
+	// +optional
+	FailureMessage string `json:"failureMessage,omitempty"`
+
+	// The result the run reported in the termination message of its last Pod, as a JSON object with a `result`
+	// field like `{"result":"succeeded-with-warnings"}`. Lowercase alphanumerics and dashes, set once the run
+	// finished.
+	// +optional
+	Result string `json:"result,omitempty"`
+
+	// Whether the run met the service level objective, set once known.
+	// +optional
+	SLOResult SLOResult `json:"sloResult,omitempty"`
+
+	// Why the run breached the service level objective.
+	// +optional
+	SLOMessage string `json:"sloMessage,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="CronJob",type=string,JSONPath=`.spec.cronJob`
+//+kubebuilder:printcolumn:name="Scheduled",type=date,JSONPath=`.spec.scheduledTime`
+//+kubebuilder:printcolumn:name="Trigger",type=string,JSONPath=`.spec.trigger`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Duration",type=string,JSONPath=`.status.duration`
+//+kubebuilder:printcolumn:name="Result",type=string,JSONPath=`.status.result`,priority=1
+
+// JobRun is the Schema for the jobruns API
+type JobRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JobRunSpec   `json:"spec,omitempty"`
+	Status JobRunStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// JobRunList contains a list of JobRun
+type JobRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JobRun `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JobRun{}, &JobRunList{})
+}
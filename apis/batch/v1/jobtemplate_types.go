@@ -0,0 +1,122 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strconv"
+	"strings"
+
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A JobTemplate holds a Job template shared by several CronJobs of a namespace. The CronJobs reference it with
+spec.jobTemplateRef, their spec.jobTemplate is then filled in from it by the defaulting webhook and kept in sync with
+it by the JobTemplate controller. The controller records the revision of the template each CronJob got in the
+JobTemplateRevisionAnnotation, and lists the consuming CronJobs in the status of the template.
+*/
+
+const (
+	// JobTemplateRevisionAnnotation records the JobTemplate and its generation the jobTemplate of a CronJob was
+	// copied from, as `<name>/<generation>`.
+	JobTemplateRevisionAnnotation = "batch.example.com/job-template-revision"
+	// CanaryRunAnnotation marks the Jobs started as canary runs of a JobTemplate change, with the revision of the
+	// template they run.
+	CanaryRunAnnotation = "batch.example.com/canary-run"
+)
+
+// JobTemplateSpec defines the Job template shared by the CronJobs
+type JobTemplateSpec struct {
+	// The Job template copied into the CronJobs referencing this JobTemplate.
+	Template batchv1beta1.JobTemplateSpec `json:"template"`
+
+	// Whether a change of the template starts a canary run of every consuming CronJob right away, instead of waiting
+	// for their next scheduled run to find out whether the new template works. Requires the JobTemplateCanaryRuns
+	// feature gate.
+	// +optional
+	CanaryRuns bool `json:"canaryRuns,omitempty"`
+}
+
+// JobTemplateConsumer is a CronJob referencing the JobTemplate.
+type JobTemplateConsumer struct {
+	// The name of the CronJob.
+	Name string `json:"name"`
+
+	// The generation of the JobTemplate the jobTemplate of the CronJob was last synced from.
+	// +optional
+	SyncedGeneration int64 `json:"syncedGeneration,omitempty"`
+
+	// The name of the Job started as the canary run of the synced generation, if any.
+	// +optional
+	CanaryJob string `json:"canaryJob,omitempty"`
+}
+
+// JobTemplateStatus defines the observed state of JobTemplate
+type JobTemplateStatus struct {
+	// The generation of the JobTemplate last rolled out to the consuming CronJobs.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The CronJobs referencing the JobTemplate, sorted by name.
+	// +optional
+	Consumers []JobTemplateConsumer `json:"consumers,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// JobTemplate is the Schema for the jobtemplates API
+type JobTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JobTemplateSpec   `json:"spec,omitempty"`
+	Status JobTemplateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// JobTemplateList contains a list of JobTemplate
+type JobTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JobTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JobTemplate{}, &JobTemplateList{})
+}
+
+// JobTemplateRevision returns the value of the JobTemplateRevisionAnnotation for a generation of a JobTemplate.
+func JobTemplateRevision(name string, generation int64) string {
+	return name + "/" + strconv.FormatInt(generation, 10)
+}
+
+// ParseJobTemplateRevision returns the name and the generation of the JobTemplate in the value of the
+// JobTemplateRevisionAnnotation, false if the value is malformed.
+func ParseJobTemplateRevision(revision string) (string, int64, bool) {
+	i := strings.LastIndex(revision, "/")
+	if i <= 0 {
+		return "", 0, false
+	}
+	generation, err := strconv.ParseInt(revision[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return revision[:i], generation, true
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A MaintenanceWindow keeps the CronJobs quiet during recurring maintenance, e.g. every Saturday night while the
+database is upgraded. While a window is open, the CronJobs it selects do not start new runs, and their active Jobs are
+deleted if the window drains them. The runs scheduled during the window are not caught up when it closes.
+
+The ClusterMaintenanceWindow has the same fields, and selects the CronJobs of the namespaces matched by its
+namespace selector.
+*/
+
+// MaintenanceWindowSpec defines the desired state of MaintenanceWindow
+type MaintenanceWindowSpec struct {
+	// The CronJobs the window applies to. An empty selector selects all of them.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	//+kubebuilder:validation:MinLength=0
+
+	// When the window opens, in Cron format.
+	Schedule string `json:"schedule"`
+
+	// How long the window stays open.
+	Duration metav1.Duration `json:"duration"`
+
+	// The time zone of the schedule, e.g. `Europe/Istanbul`. The one of the controller if unset.
+	// +optional
+	TimeZone *string `json:"timeZone,omitempty"`
+
+	// Deletes the active Jobs of the CronJobs when the window opens, instead of letting them finish.
+	// +optional
+	DrainActiveJobs bool `json:"drainActiveJobs,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Duration",type=string,JSONPath=`.spec.duration`
+//+kubebuilder:printcolumn:name="Drain",type=boolean,JSONPath=`.spec.drainActiveJobs`
+
+// MaintenanceWindow is the Schema for the maintenancewindows API
+type MaintenanceWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MaintenanceWindowSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// MaintenanceWindowList contains a list of MaintenanceWindow
+type MaintenanceWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenanceWindow `json:"items"`
+}
+
+// ClusterMaintenanceWindowSpec defines the desired state of ClusterMaintenanceWindow
+type ClusterMaintenanceWindowSpec struct {
+	// The namespaces the window applies to, all of them if unset.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	MaintenanceWindowSpec `json:",inline"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Duration",type=string,JSONPath=`.spec.duration`
+//+kubebuilder:printcolumn:name="Drain",type=boolean,JSONPath=`.spec.drainActiveJobs`
+
+// ClusterMaintenanceWindow is the Schema for the clustermaintenancewindows API
+type ClusterMaintenanceWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterMaintenanceWindowSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterMaintenanceWindowList contains a list of ClusterMaintenanceWindow
+type ClusterMaintenanceWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterMaintenanceWindow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenanceWindow{}, &MaintenanceWindowList{})
+	SchemeBuilder.Register(&ClusterMaintenanceWindow{}, &ClusterMaintenanceWindowList{})
+}
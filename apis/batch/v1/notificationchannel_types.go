@@ -0,0 +1,195 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A NotificationChannel is where the CronJobs referring to it in their `notifications` report their finished Jobs and
+missed runs: a Slack channel, any HTTP endpoint, mailboxes, a PagerDuty service or an Opsgenie team. The credentials
+are kept in Secrets of the namespace of the channel, only their references are in the spec. Exactly one of the kinds
+of channel is set.
+
+PagerDuty and Opsgenie raise alerts. With autoResolve, the failures of a CronJob share a single alert rather than one
+per Job, and the alert is resolved by the next Job of the CronJob which succeeds.
+
+The status counts the deliveries of the channel, so a broken webhook URL shows up without reading the logs of the
+manager.
+*/
+
+// NotificationChannelSpec defines the desired state of NotificationChannel
+type NotificationChannelSpec struct {
+	// Posts the notifications to a Slack incoming webhook.
+	// +optional
+	Slack *SlackChannel `json:"slack,omitempty"`
+
+	// Posts the notifications as JSON to an HTTP endpoint.
+	// +optional
+	Webhook *WebhookChannel `json:"webhook,omitempty"`
+
+	// Mails the notifications through an SMTP server.
+	// +optional
+	Email *EmailChannel `json:"email,omitempty"`
+
+	// Triggers PagerDuty incidents through the Events API v2.
+	// +optional
+	PagerDuty *PagerDutyChannel `json:"pagerDuty,omitempty"`
+
+	// Creates Opsgenie alerts through the Alert API.
+	// +optional
+	Opsgenie *OpsgenieChannel `json:"opsgenie,omitempty"`
+
+	//+kubebuilder:validation:Minimum=1
+
+	// The number of notifications delivered per hour at most, the others are dropped. Defaults to 60.
+	// +optional
+	MaxPerHour *int32 `json:"maxPerHour,omitempty"`
+}
+
+// SlackChannel is a Slack incoming webhook.
+type SlackChannel struct {
+	// The key of a Secret holding the URL of the incoming webhook.
+	WebhookURLSecretRef corev1.SecretKeySelector `json:"webhookURLSecretRef"`
+}
+
+// WebhookChannel is an HTTP endpoint the notifications are posted to.
+type WebhookChannel struct {
+	//+kubebuilder:validation:Pattern=`^https?://`
+
+	// The URL of the endpoint.
+	URL string `json:"url"`
+
+	// The key of a Secret holding the value of the Authorization header, e.g. `Bearer <token>`.
+	// +optional
+	AuthorizationSecretRef *corev1.SecretKeySelector `json:"authorizationSecretRef,omitempty"`
+}
+
+// EmailChannel is a list of mailboxes the notifications are mailed to.
+type EmailChannel struct {
+	// The host:port of the SMTP server, which supports STARTTLS when credentials are given.
+	SMTPAddress string `json:"smtpAddress"`
+
+	// The sender address.
+	From string `json:"from"`
+
+	//+kubebuilder:validation:MinItems=1
+
+	// The recipient addresses.
+	To []string `json:"to"`
+
+	// A Secret holding the `username` and the `password` of the SMTP server, if it needs them.
+	// +optional
+	CredentialsSecretRef *corev1.LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+}
+
+// PagerDutyChannel is a PagerDuty service integrated with the Events API v2.
+type PagerDutyChannel struct {
+	// The key of a Secret holding the routing key of the integration.
+	RoutingKeySecretRef corev1.SecretKeySelector `json:"routingKeySecretRef"`
+
+	//+kubebuilder:validation:Enum=critical;error;warning;info
+
+	// The severity of the failed Jobs and the missed runs, the succeeded Jobs are always info. Defaults to error.
+	// +optional
+	Severity string `json:"severity,omitempty"`
+
+	// Triggers a single incident per CronJob, resolved when one of its Jobs succeeds.
+	// +optional
+	AutoResolve bool `json:"autoResolve,omitempty"`
+}
+
+// OpsgenieChannel is an Opsgenie team integrated with the Alert API.
+type OpsgenieChannel struct {
+	// The key of a Secret holding the API key of the integration.
+	APIKeySecretRef corev1.SecretKeySelector `json:"apiKeySecretRef"`
+
+	//+kubebuilder:validation:Pattern=`^https://`
+
+	// The URL of the Alert API, e.g. `https://api.eu.opsgenie.com` for the EU instance. Defaults to
+	// `https://api.opsgenie.com`.
+	// +optional
+	APIURL string `json:"apiURL,omitempty"`
+
+	//+kubebuilder:validation:Enum=P1;P2;P3;P4;P5
+
+	// The priority of the failed Jobs and the missed runs, the succeeded Jobs are always P5. Defaults to P3.
+	// +optional
+	Priority string `json:"priority,omitempty"`
+
+	// Creates a single alert per CronJob, closed when one of its Jobs succeeds.
+	// +optional
+	AutoResolve bool `json:"autoResolve,omitempty"`
+}
+
+// NotificationChannelStatus defines the observed state of NotificationChannel
+type NotificationChannelStatus struct {
+	// The number of notifications delivered since the channel was created.
+	// +optional
+	Delivered int64 `json:"delivered,omitempty"`
+
+	// The number of notifications which could not be delivered, even after retries.
+	// +optional
+	Failed int64 `json:"failed,omitempty"`
+
+	// The number of notifications dropped over the rate limit.
+	// +optional
+	Dropped int64 `json:"dropped,omitempty"`
+
+	// When a notification was last delivered.
+	// +optional
+	LastDeliveryTime *metav1.Time `json:"lastDeliveryTime,omitempty"`
+
+	// When a notification last failed to be delivered.
+	// +optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+
+	// Why the notification failed to be delivered.
+	// +optional
+	LastFailureMessage string `json:"lastFailureMessage,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Delivered",type=integer,JSONPath=`.status.delivered`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+//+kubebuilder:printcolumn:name="Dropped",type=integer,JSONPath=`.status.dropped`
+//+kubebuilder:printcolumn:name="Last Delivery",type=date,JSONPath=`.status.lastDeliveryTime`
+
+// NotificationChannel is the Schema for the notificationchannels API
+type NotificationChannel struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NotificationChannelSpec   `json:"spec,omitempty"`
+	Status NotificationChannelStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NotificationChannelList contains a list of NotificationChannel
+type NotificationChannelList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NotificationChannel `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NotificationChannel{}, &NotificationChannelList{})
+}
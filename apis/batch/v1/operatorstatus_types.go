@@ -0,0 +1,114 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+The OperatorStatus is a singleton kept by the leader replica of the manager, so the fleet tooling assessing the
+operators of many clusters reads one object through the API server, like any other health signal, instead of scraping
+the metrics endpoint. It is named after OperatorStatusName, and refreshed on every interval of the manager: an update
+time lagging behind tells that no replica holds the lease, or that the leader is stuck.
+*/
+
+// OperatorStatusName is the name of the singleton OperatorStatus.
+const OperatorStatusName = "cronjob-operator"
+
+// ControllerHealth is the health of a controller of the manager.
+type ControllerHealth struct {
+	// The name of the controller, e.g. `cronjob`.
+	Name string `json:"name"`
+
+	// The number of the requests waiting in the work queue.
+	QueueDepth int64 `json:"queueDepth"`
+
+	// The number of the reconciles since the manager started.
+	Reconciles int64 `json:"reconciles"`
+
+	// The number of the reconciles which failed since the manager started.
+	Errors int64 `json:"errors"`
+
+	// The share of the reconciles which failed since the previous update, e.g. `0.05`.
+	// +optional
+	ErrorRate string `json:"errorRate,omitempty"`
+}
+
+// OperatorStatusStatus defines the observed state of OperatorStatus
+type OperatorStatusStatus struct {
+	// The version of the operator.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// The commit the operator was built from.
+	// +optional
+	GitCommit string `json:"gitCommit,omitempty"`
+
+	// The identity of the replica holding the leader election lease, the name of its pod.
+	// +optional
+	Leader string `json:"leader,omitempty"`
+
+	// When the leader acquired the lease.
+	// +optional
+	LeaderSince *metav1.Time `json:"leaderSince,omitempty"`
+
+	// The enabled feature gates, sorted.
+	// +optional
+	FeatureGates []string `json:"featureGates,omitempty"`
+
+	// The SHA-256 hash of the config in effect, the same hash on two clusters means the same settings.
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+
+	// The health of the controllers, sorted by name.
+	// +optional
+	Controllers []ControllerHealth `json:"controllers,omitempty"`
+
+	// When the status was last updated.
+	// +optional
+	UpdateTime *metav1.Time `json:"updateTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="Leader",type=string,JSONPath=`.status.leader`
+//+kubebuilder:printcolumn:name="Config",type=string,JSONPath=`.status.configHash`,priority=1
+//+kubebuilder:printcolumn:name="Updated",type=date,JSONPath=`.status.updateTime`
+
+// OperatorStatus is the Schema for the operatorstatuses API
+type OperatorStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status OperatorStatusStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OperatorStatusList contains a list of OperatorStatus
+type OperatorStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OperatorStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorStatus{}, &OperatorStatusList{})
+}
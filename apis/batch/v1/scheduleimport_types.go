@@ -0,0 +1,174 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A ScheduleImport mirrors the schedules of a cloud scheduler, AWS EventBridge Scheduler or Google Cloud Scheduler, as
+CronJobs of its namespace, for the teams moving off the cloud schedulers one schedule at a time. The scheduleimport
+controller lists the schedules on every interval with read-only credentials, and creates, updates and deletes a
+CronJob per schedule. The CronJobs run the job template of the import, which is told the target and the payload of its
+schedule through environment variables.
+
+The imported CronJobs are suspended by default, since the cloud scheduler still runs the schedules. A CronJob whose
+import label is removed is released: the import leaves it alone from then on, so it can be resumed before its
+schedule is deleted from the cloud.
+*/
+
+// ScheduleImportProvider is the cloud scheduler the schedules are imported from.
+// +kubebuilder:validation:Enum=EventBridgeScheduler;CloudScheduler
+type ScheduleImportProvider string
+
+const (
+	// EventBridgeSchedulerProvider imports the schedules of AWS EventBridge Scheduler.
+	EventBridgeSchedulerProvider ScheduleImportProvider = "EventBridgeScheduler"
+	// CloudSchedulerProvider imports the jobs of Google Cloud Scheduler.
+	CloudSchedulerProvider ScheduleImportProvider = "CloudScheduler"
+)
+
+// EventBridgeSchedulerSource are the schedules of AWS EventBridge Scheduler which are imported.
+type EventBridgeSchedulerSource struct {
+	// The region of the schedules, e.g. `eu-west-1`.
+	Region string `json:"region"`
+
+	// The schedule group of the schedules, all the groups if empty.
+	// +optional
+	GroupName string `json:"groupName,omitempty"`
+}
+
+// CloudSchedulerSource are the jobs of Google Cloud Scheduler which are imported.
+type CloudSchedulerSource struct {
+	// The project of the jobs.
+	Project string `json:"project"`
+
+	// The location of the jobs, e.g. `europe-west1`.
+	Location string `json:"location"`
+}
+
+// ScheduleImportSpec defines the desired state of ScheduleImport
+type ScheduleImportSpec struct {
+	// The cloud scheduler the schedules are imported from.
+	Provider ScheduleImportProvider `json:"provider"`
+
+	// The schedules of AWS EventBridge Scheduler, required by the EventBridgeScheduler provider.
+	// +optional
+	EventBridgeScheduler *EventBridgeSchedulerSource `json:"eventBridgeScheduler,omitempty"`
+
+	// The jobs of Google Cloud Scheduler, required by the CloudScheduler provider.
+	// +optional
+	CloudScheduler *CloudSchedulerSource `json:"cloudScheduler,omitempty"`
+
+	// Only the schedules whose names start with the prefix are imported, all of them if empty.
+	// +optional
+	NamePrefix string `json:"namePrefix,omitempty"`
+
+	// The secret of the namespace with the read-only credentials of the cloud scheduler: `accessKeyID` and
+	// `secretAccessKey` for EventBridge Scheduler, `credentials.json`, a service account key, for Cloud Scheduler.
+	CredentialsSecret corev1.LocalObjectReference `json:"credentialsSecret"`
+
+	// How often the schedules are listed. Defaults to 10 minutes, may not be less than 1 minute.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// The job the CronJobs run. Its containers are given the `SCHEDULE_NAME`, `SCHEDULE_TARGET` and
+	// `SCHEDULE_INPUT` environment variables of their schedule.
+	JobTemplate batchv1beta1.JobTemplateSpec `json:"jobTemplate"`
+
+	// Whether the CronJobs of the enabled schedules run. They are suspended if false, the cloud scheduler running the
+	// schedules already; the CronJobs of the disabled schedules are always suspended.
+	// +optional
+	Active bool `json:"active,omitempty"`
+
+	// Whether the imports are suspended, the CronJobs already imported are left as they are.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// ImportedScheduleStatus is the status of an imported schedule.
+type ImportedScheduleStatus struct {
+	// The name of the schedule in the cloud scheduler.
+	Name string `json:"name"`
+
+	// The name of the CronJob of the schedule.
+	CronJob string `json:"cronJob"`
+
+	// Why the schedule could not be imported, e.g. a schedule expression which has no cron equivalent.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ScheduleImportStatus defines the observed state of ScheduleImport
+type ScheduleImportStatus struct {
+	// The generation of the spec the schedules were last imported for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// When the schedules were last listed successfully.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// The number of the schedules imported as CronJobs.
+	// +optional
+	Imported int32 `json:"imported,omitempty"`
+
+	// The number of the schedules which could not be imported.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// The schedules, the failed ones first, at most 100 of them are listed.
+	// +optional
+	Schedules []ImportedScheduleStatus `json:"schedules,omitempty"`
+
+	// The conditions of the ScheduleImport, `Synced` is false when the schedules could not be listed.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Provider",type=string,JSONPath=`.spec.provider`
+//+kubebuilder:printcolumn:name="Imported",type=integer,JSONPath=`.status.imported`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+//+kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="Synced")].status`
+//+kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+
+// ScheduleImport is the Schema for the scheduleimports API
+type ScheduleImport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScheduleImportSpec   `json:"spec,omitempty"`
+	Status ScheduleImportStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ScheduleImportList contains a list of ScheduleImport
+type ScheduleImportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScheduleImport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ScheduleImport{}, &ScheduleImportList{})
+}
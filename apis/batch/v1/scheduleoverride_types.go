@@ -0,0 +1,78 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A ScheduleOverride changes the schedule of a set of CronJobs for a while, without editing them: "pause all the
+reporting jobs this weekend" is one object which expires by itself. The CronJob controller consults the overrides of
+the namespace on every reconcile. While an override applies, the CronJobs it selects are suspended or run on its
+schedule; the runs of the original schedule which fall in the window are not caught up afterwards.
+
+When several overrides select a CronJob at the same time, a suspension wins, then the first override by name.
+*/
+
+// ScheduleOverrideSpec defines the desired state of ScheduleOverride
+type ScheduleOverrideSpec struct {
+	// The CronJobs of the namespace the override applies to. An empty selector selects all of them.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// The schedule the CronJobs run on instead of theirs, in Cron format. Ignored if suspend is set.
+	// +optional
+	Schedule *string `json:"schedule,omitempty"`
+
+	// Suspends the CronJobs, the Jobs already started are not affected.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// When the override starts to apply, right away if unset.
+	// +optional
+	StartsAt *metav1.Time `json:"startsAt,omitempty"`
+
+	// When the override stops to apply. The expired overrides can be deleted.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+//+kubebuilder:printcolumn:name="Starts At",type=string,format=date-time,JSONPath=`.spec.startsAt`
+//+kubebuilder:printcolumn:name="Expires At",type=string,format=date-time,JSONPath=`.spec.expiresAt`
+
+// ScheduleOverride is the Schema for the scheduleoverrides API
+type ScheduleOverride struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ScheduleOverrideSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ScheduleOverrideList contains a list of ScheduleOverride
+type ScheduleOverrideList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScheduleOverride `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ScheduleOverride{}, &ScheduleOverrideList{})
+}
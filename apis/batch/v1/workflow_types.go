@@ -0,0 +1,198 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+A Workflow runs a small pipeline on a cron schedule: a DAG of job templates, where a node starts once all the nodes it
+depends on succeeded. Every activation is a run, and every node of a run is one Job, or a few when it is retried. A
+node whose dependency failed is skipped, the independent branches still run to the end.
+
+Like the CronJob, the status of a Workflow is rebuilt from its Jobs on every reconcile. The runs do not overlap: an
+activation while a run is in progress is skipped.
+*/
+
+// WorkflowSpec defines the desired state of Workflow
+type WorkflowSpec struct {
+	//+kubebuilder:validation:MinLength=0
+
+	// The schedule in Cron format, see https://en.wikipedia.org/wiki/Cron.
+	Schedule string `json:"schedule"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// Optional deadline in seconds for starting a run if it misses its scheduled time for any reason.
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+	// This flag tells the controller to suspend subsequent runs, it does not apply to the run in progress.
+	// +optional
+	Suspend *bool `json:"suspend,omitempty"`
+
+	//+kubebuilder:validation:MinItems=1
+
+	// The nodes of the DAG. The names are unique and the dependencies may not form a cycle.
+	Nodes []WorkflowNode `json:"nodes"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The number of successful finished runs to retain, with their Jobs. Defaults to 3.
+	// +optional
+	SuccessfulRunsHistoryLimit *int32 `json:"successfulRunsHistoryLimit,omitempty"`
+
+	//+kubebuilder:validation:Minimum=0
+
+	// The number of failed finished runs to retain, with their Jobs. Defaults to 1.
+	// +optional
+	FailedRunsHistoryLimit *int32 `json:"failedRunsHistoryLimit,omitempty"`
+}
+
+// WorkflowNode is a step of the Workflow.
+type WorkflowNode struct {
+	//+kubebuilder:validation:MaxLength=20
+	//+kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+
+	// The name of the node, part of the names of its Jobs.
+	Name string `json:"name"`
+
+	// The names of the nodes which must succeed before this one starts.
+	// +optional
+	Dependencies []string `json:"dependencies,omitempty"`
+
+	// How the node is retried when its Job fails. Not retried if unset.
+	// +optional
+	RetryPolicy *NodeRetryPolicy `json:"retryPolicy,omitempty"`
+
+	// The Job to create for the node.
+	JobTemplate batchv1beta1.JobTemplateSpec `json:"jobTemplate"`
+}
+
+// NodeRetryPolicy is how a failed node is retried.
+type NodeRetryPolicy struct {
+	//+kubebuilder:validation:Minimum=0
+
+	// The number of new Jobs created after the first one failed. The backoff limit of the Job applies to the retries
+	// of its Pods first.
+	Limit int32 `json:"limit"`
+}
+
+// WorkflowRunPhase is the phase of a run.
+// +kubebuilder:validation:Enum=Running;Succeeded;Failed
+type WorkflowRunPhase string
+
+const (
+	// WorkflowRunRunning means some nodes still run or wait for their dependencies.
+	WorkflowRunRunning WorkflowRunPhase = "Running"
+	// WorkflowRunSucceeded means all the nodes succeeded.
+	WorkflowRunSucceeded WorkflowRunPhase = "Succeeded"
+	// WorkflowRunFailed means a node failed after its retries, the others are finished or skipped.
+	WorkflowRunFailed WorkflowRunPhase = "Failed"
+)
+
+// NodePhase is the phase of a node in a run.
+// +kubebuilder:validation:Enum=Pending;Running;Succeeded;Failed;Skipped
+type NodePhase string
+
+const (
+	// NodePending means the node waits for its dependencies.
+	NodePending NodePhase = "Pending"
+	// NodeRunning means the Job of the node runs.
+	NodeRunning NodePhase = "Running"
+	// NodeSucceeded means the Job of the node succeeded.
+	NodeSucceeded NodePhase = "Succeeded"
+	// NodeFailed means the Jobs of the node failed, retries included.
+	NodeFailed NodePhase = "Failed"
+	// NodeSkipped means a dependency of the node failed, so it never ran.
+	NodeSkipped NodePhase = "Skipped"
+)
+
+// WorkflowStatus defines the observed state of Workflow
+type WorkflowStatus struct {
+	// Information when was the last time the workflow was successfully scheduled.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// The latest run.
+	// +optional
+	LastRun *WorkflowRun `json:"lastRun,omitempty"`
+
+	// The conditions of the Workflow, `Valid` is false when the DAG can not be run.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// WorkflowRun is the state of a run.
+type WorkflowRun struct {
+	// The scheduled time of the run.
+	ScheduledTime metav1.Time `json:"scheduledTime"`
+
+	// The phase of the run.
+	Phase WorkflowRunPhase `json:"phase"`
+
+	// The state of every node.
+	// +optional
+	Nodes []WorkflowNodeStatus `json:"nodes,omitempty"`
+}
+
+// WorkflowNodeStatus is the state of a node in a run.
+type WorkflowNodeStatus struct {
+	// The name of the node.
+	Name string `json:"name"`
+
+	// The phase of the node.
+	Phase NodePhase `json:"phase"`
+
+	// The number of Jobs created for the node.
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// The name of the Job of the latest attempt.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Last Run",type=string,JSONPath=`.status.lastRun.phase`
+//+kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
+
+// Workflow is the Schema for the workflows API
+type Workflow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkflowSpec   `json:"spec,omitempty"`
+	Status WorkflowStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// WorkflowList contains a list of Workflow
+type WorkflowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Workflow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Workflow{}, &WorkflowList{})
+}
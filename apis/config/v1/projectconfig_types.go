@@ -39,6 +39,670 @@ type ProjectConfig struct {
 	cfg.ControllerManagerConfigurationSpec `json:",inline"`
 
 	ClusterName string `json:"clusterName,omitempty"`
+
+	// WatchNamespaces restricts the namespaces the manager watches and reconciles. All namespaces are watched if
+	// empty. Overridden by the --namespace and --watch-namespaces flags.
+	// +optional
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+
+	// Controllers selects the controllers run by the manager: `*` enables the controllers which are on by default,
+	// `name` enables a controller and `-name` disables it, e.g. `["*", "-cronjob"]`. Defaults to `["*"]`. Overridden
+	// by the --controllers flag. Changing it requires a restart of the manager.
+	// +optional
+	Controllers []string `json:"controllers,omitempty"`
+
+	// FeatureGates enables or disables the experimental features, e.g. `CronJobTimeZone: true`. Overridden by the
+	// --feature-gates flag. Changing it requires a restart of the manager.
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// Client configures the client of the manager to the API server. Changing it requires a restart of the manager.
+	// +optional
+	Client ClientConfig `json:"client,omitempty"`
+
+	// Logging configures the logs of the manager. Reloaded when the config file changes.
+	// +optional
+	Logging LoggingConfig `json:"logging,omitempty"`
+
+	// Audit configures the audit stream of the scheduling decisions. Changing it requires a restart of the manager.
+	// +optional
+	Audit AuditConfig `json:"audit,omitempty"`
+
+	// CronJobController configures the CronJob controller
+	// +optional
+	CronJobController CronJobControllerConfig `json:"cronJobController,omitempty"`
+
+	// Tracing exports the traces of the reconciles and the admission requests. Changing it requires a restart of the
+	// manager.
+	// +optional
+	Tracing TracingConfig `json:"tracing,omitempty"`
+
+	// ErrorReporting reports the panics and the repeated reconcile errors to an error tracking service. Changing it
+	// requires a restart of the manager.
+	// +optional
+	ErrorReporting ErrorReportingConfig `json:"errorReporting,omitempty"`
+
+	// SecureMetrics serves the metrics over TLS with authentication and authorization, instead of the plain HTTP
+	// metrics endpoint
+	// +optional
+	SecureMetrics SecureMetricsConfig `json:"secureMetrics,omitempty"`
+
+	// WebhookServer configures the files of the webhook server, on top of the port, the host and the certDir of
+	// `webhook`. Changing it requires a restart of the manager.
+	// +optional
+	WebhookServer WebhookServerConfig `json:"webhookServer,omitempty"`
+
+	// Admission configures the admission webhooks of the CronJobs
+	// +optional
+	Admission AdmissionConfig `json:"admission,omitempty"`
+
+	// Archive writes the finished runs recorded in JobRuns to an object storage. Changing it requires a restart of the
+	// manager.
+	// +optional
+	Archive ArchiveConfig `json:"archive,omitempty"`
+
+	// Pushgateway pushes the results of the finished runs to a Prometheus Pushgateway. Changing it requires a restart
+	// of the manager.
+	// +optional
+	Pushgateway PushgatewayConfig `json:"pushgateway,omitempty"`
+
+	// API configures the read-only HTTP API serving the run history and the next runs of the CronJobs. Changing it
+	// requires a restart of the manager.
+	// +optional
+	API APIConfig `json:"api,omitempty"`
+
+	// GRPC configures the gRPC service starting, suspending and listing the runs of the CronJobs. Changing it requires
+	// a restart of the manager.
+	// +optional
+	GRPC GRPCConfig `json:"grpc,omitempty"`
+
+	// CloudEvents emits the lifecycle of the runs as CloudEvents to an HTTP endpoint or a Kafka topic. Changing it
+	// requires a restart of the manager.
+	// +optional
+	CloudEvents CloudEventsConfig `json:"cloudEvents,omitempty"`
+
+	// HTTPTrigger serves an authenticated webhook endpoint starting runs of the CronJobs for the external systems.
+	// Changing it requires a restart of the manager.
+	// +optional
+	HTTPTrigger HTTPTriggerConfig `json:"httpTrigger,omitempty"`
+
+	// OperatorStatus keeps the health of the operator in the OperatorStatus singleton. Changing it requires a restart
+	// of the manager.
+	// +optional
+	OperatorStatus OperatorStatusConfig `json:"operatorStatus,omitempty"`
+}
+
+// ClientConfig configures the client to the API server, shared by the controllers, the webhooks and the cache. Every
+// setting overrides the matching flag of the manager if set.
+type ClientConfig struct {
+	// QPS is the sustained number of requests per second to the API server. Defaults to 20.
+	// +optional
+	QPS float32 `json:"qps,omitempty"`
+
+	// Burst is the number of requests allowed above QPS for short periods. Defaults to 30.
+	// +optional
+	Burst int `json:"burst,omitempty"`
+
+	// Timeout of a request to the API server, the watches excluded. No timeout if unset.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// UserAgent is sent with every request and shows up in the audit logs of the API server. Defaults to
+	// `kubebuilder-tutorial/<version> (<os>/<arch>) <git commit>`.
+	// +optional
+	UserAgent string `json:"userAgent,omitempty"`
+}
+
+// LoggingConfig configures the logs of the manager. Every setting overrides the matching --zap-* flag if set. Only
+// Level is reloaded when the config file changes, the other settings require a restart of the manager.
+type LoggingConfig struct {
+	// Level is the minimum level of the logged messages, one of `debug`, `info` and `error`, or an integer verbosity
+	// like `2` for the more verbose debug logs. Defaults to `debug` in development mode and `info` otherwise.
+	// +optional
+	Level string `json:"level,omitempty"`
+
+	// Development switches the defaults of the other settings to the ones of the development mode: console encoder,
+	// `debug` level, stack traces from `warn` and no sampling. Defaults to the --zap-devel flag, true unless it is
+	// set to false.
+	// +optional
+	Development *bool `json:"development,omitempty"`
+
+	// Encoder is the format of the logs, `json` or `console`. Defaults to `console` in development mode and `json`
+	// otherwise.
+	// +optional
+	Encoder string `json:"encoder,omitempty"`
+
+	// StacktraceLevel is the level from which the stack traces are logged, one of `debug`, `info`, `warn`, `error`,
+	// `dpanic`, `panic` and `fatal`. Defaults to `warn` in development mode and `error` otherwise.
+	// +optional
+	StacktraceLevel string `json:"stacktraceLevel,omitempty"`
+
+	// TimeEncoding is the format of the timestamps, one of `epoch`, `millis`, `nanos`, `iso8601`, `rfc3339` and
+	// `rfc3339nano`. Defaults to `iso8601` in development mode and `epoch` otherwise.
+	// +optional
+	TimeEncoding string `json:"timeEncoding,omitempty"`
+
+	// Sampling caps the number of the logs with the same level and message per second. Defaults to the first 100,
+	// then every 100th in production mode, the development mode does not sample.
+	// +optional
+	Sampling *LogSamplingConfig `json:"sampling,omitempty"`
+
+	// DebugSampling caps the number of the debug logs with the same message per second, the more verbose ones
+	// included, like the `V(1)` logs of every reconcile. Defaults to Sampling for the `debug` level, the more verbose
+	// levels are not sampled.
+	// +optional
+	DebugSampling *LogSamplingConfig `json:"debugSampling,omitempty"`
+
+	// DebugObjects lists the objects whose logs are written from DebugObjectsLevel whatever Level is, and are not
+	// sampled: `namespace/name` for an object, `/name` for a cluster-scoped one and `namespace` for all the objects
+	// of the namespace. They are matched on the `namespace` and `name` of the loggers of the reconciles.
+	// +optional
+	DebugObjects []string `json:"debugObjects,omitempty"`
+
+	// DebugObjectsLevel is the level of the logs of DebugObjects, like Level. Defaults to `debug`.
+	// +optional
+	DebugObjectsLevel string `json:"debugObjectsLevel,omitempty"`
+
+	// File writes the logs to a rotated file too, besides the standard error.
+	// +optional
+	File *LogFileConfig `json:"file,omitempty"`
+
+	// Backend is the library writing the logs, `zap` or `slog`. The `slog` backend hands the logs to a handler of
+	// `log/slog`, the built-in JSON or text one unless a handler is plugged in, and ignores the sampling, the debug
+	// objects, the stack traces and the time encoding. Defaults to `zap`.
+	// +optional
+	Backend string `json:"backend,omitempty"`
+}
+
+// LogFileConfig configures the log file and its rotation. The file is rotated when it reaches MaxSizeMB, the rotated
+// files are named after their rotation time and removed once they are older than MaxAgeDays or beyond MaxBackups.
+type LogFileConfig struct {
+	// Path of the log file, e.g. `/var/log/kubebuilder-tutorial/manager.log`. Its directory must be writable.
+	Path string `json:"path"`
+
+	// MaxSizeMB is the size in megabytes the file is rotated at. Defaults to 100.
+	// +optional
+	MaxSizeMB int `json:"maxSizeMB,omitempty"`
+
+	// MaxAgeDays is how many days the rotated files are kept. Zero keeps them regardless of their age.
+	// +optional
+	MaxAgeDays int `json:"maxAgeDays,omitempty"`
+
+	// MaxBackups is how many rotated files are kept. Zero keeps all of them, unless MaxAgeDays removes them.
+	// +optional
+	MaxBackups int `json:"maxBackups,omitempty"`
+
+	// Compress gzips the rotated files.
+	// +optional
+	Compress bool `json:"compress,omitempty"`
+}
+
+// AuditConfig configures the audit stream, one JSON line for every run created or skipped, Job deleted, policy
+// applied and trigger accepted or denied, with the CronJob, the scheduled time and the inputs of the decision.
+type AuditConfig struct {
+	// Enabled writes the audit stream.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// File writes the audit stream to a rotated file instead of the standard output. The logs are written to the
+	// standard error, so that the two streams are not mixed either way.
+	// +optional
+	File *LogFileConfig `json:"file,omitempty"`
+}
+
+// LogSamplingConfig configures the sampling of the logs. The first Initial logs with the same level and message are
+// written every second, then every Thereafter-th one. A zero Initial disables the sampling.
+type LogSamplingConfig struct {
+	// Initial is the number of the logs written every second before sampling
+	// +optional
+	Initial int `json:"initial,omitempty"`
+
+	// Thereafter is the sampling rate of the logs beyond Initial
+	// +optional
+	Thereafter int `json:"thereafter,omitempty"`
+}
+
+// CronJobControllerConfig configures the CronJob controller
+type CronJobControllerConfig struct {
+	// MaxConcurrentReconciles is the number of CronJobs reconciled in parallel. Defaults to 1. Changing it requires a
+	// restart of the manager.
+	// +optional
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// RateLimit configures how fast the reconciles are retried. Reloaded when the config file changes.
+	// +optional
+	RateLimit RateLimitConfig `json:"rateLimit,omitempty"`
+
+	// RequeueJitter is the longest random delay added to the wake-ups of the CronJobs, so the many CronJobs sharing a
+	// schedule like `0 * * * *` do not all start their Jobs at the same instant. The Jobs start up to this late.
+	// Disabled by default. Changing it requires a restart of the manager.
+	// +optional
+	RequeueJitter *metav1.Duration `json:"requeueJitter,omitempty"`
+
+	// DeletePropagationPolicy is how the Pods of the deleted Jobs are removed: `Background`, `Foreground` or
+	// `Orphan`. It applies to the cleanup of the old Jobs and to the Jobs replaced by the Replace concurrency policy.
+	// Defaults to `Background`. Changing it requires a restart of the manager.
+	// +optional
+	DeletePropagationPolicy metav1.DeletionPropagation `json:"deletePropagationPolicy,omitempty"`
+
+	// JobRunTTL is how long the JobRuns of the finished runs are kept. Defaults to 7 days. Changing it requires a
+	// restart of the manager.
+	// +optional
+	JobRunTTL *metav1.Duration `json:"jobRunTTL,omitempty"`
+
+	// CreationDelayPerCronJob records how late the Jobs were created per CronJob too, besides per controller. It
+	// adds a histogram per CronJob. Disabled by default. Changing it requires a restart of the manager.
+	// +optional
+	CreationDelayPerCronJob bool `json:"creationDelayPerCronJob,omitempty"`
+
+	// StatusDebounce coalesces the writes of the status of a CronJob within this duration into one, e.g. when
+	// several of its Jobs finish together. The status is written up to this late. Disabled by default. Changing it
+	// requires a restart of the manager.
+	// +optional
+	StatusDebounce *metav1.Duration `json:"statusDebounce,omitempty"`
+}
+
+// RateLimitConfig configures the rate limiter of the work queue of a controller. A reconcile is delayed by the
+// longer of its exponential back-off and the overall rate limit.
+type RateLimitConfig struct {
+	// BaseDelay is the back-off of the first retry of a failed reconcile, doubled on every retry. Defaults to 5ms.
+	// +optional
+	BaseDelay *metav1.Duration `json:"baseDelay,omitempty"`
+
+	// MaxDelay caps the back-off of the retries. Defaults to 1000s.
+	// +optional
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+
+	// QPS is the overall number of reconciles per second. Defaults to 10.
+	// +optional
+	QPS int `json:"qps,omitempty"`
+
+	// Burst is the number of reconciles allowed above QPS for short periods. Defaults to 100.
+	// +optional
+	Burst int `json:"burst,omitempty"`
+}
+
+// TracingConfig configures the export of the traces with OTLP over gRPC
+type TracingConfig struct {
+	// Endpoint is the `host:port` of the OTLP collector, e.g. `otel-collector.observability:4317`. The traces are not
+	// exported if empty.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Insecure connects to the collector without TLS.
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+
+	// SamplingRatio is the fraction of the traces which are exported, between 0 and 1. Defaults to 1.
+	// +optional
+	SamplingRatio *float64 `json:"samplingRatio,omitempty"`
+}
+
+// ErrorReportingConfig configures the error tracking service the errors are reported to. At most one of the services
+// can be set, the errors are not reported if none is.
+type ErrorReportingConfig struct {
+	// SentryDSN is the DSN of the Sentry project the errors are sent to, e.g.
+	// `https://<public key>@o0.ingest.sentry.io/<project ID>`.
+	// +optional
+	SentryDSN string `json:"sentryDSN,omitempty"`
+
+	// GoogleCloudProject is the ID of the Google Cloud project whose Error Reporting the errors are sent to. The
+	// manager authenticates with the application default credentials, e.g. of Workload Identity.
+	// +optional
+	GoogleCloudProject string `json:"googleCloudProject,omitempty"`
+
+	// ReconcileErrorThreshold is the number of consecutive reconcile errors of a CronJob after which they are
+	// reported, once until the CronJob is reconciled successfully again. Defaults to 5.
+	// +optional
+	ReconcileErrorThreshold int `json:"reconcileErrorThreshold,omitempty"`
+}
+
+// ArchiveConfig configures the archival of the finished runs to an object storage.
+type ArchiveConfig struct {
+	// Provider is the object storage, `S3`, `GCS` or `AzureBlob`. The runs are not archived if empty.
+	// +optional
+	Provider string `json:"provider,omitempty"`
+
+	// Bucket is the bucket the runs are written to, or the container of Azure Blob Storage.
+	// +optional
+	Bucket string `json:"bucket,omitempty"`
+
+	// Prefix is prepended to the names of the objects, e.g. `cluster-a/`.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// Endpoint is the URL of the object storage. It is the URL of the storage account for Azure Blob Storage, e.g.
+	// `https://account.blob.core.windows.net`, and of an S3 compatible store like MinIO for S3, whose endpoint
+	// defaults to the one of AWS.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region is the region of the S3 bucket. Defaults to `us-east-1`.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// CredentialsSecret is the `namespace/name` of the Secret holding the credentials of the object storage. GCS
+	// falls back to the application default credentials without it.
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+
+	// Retention is how long the archived runs are kept, the older ones are deleted daily. Defaults to a year.
+	// +optional
+	Retention *metav1.Duration `json:"retention,omitempty"`
+
+	// LogTailLines is the number of the last lines of the logs of a run archived with it, read from the last Pod of
+	// its Job. The logs are not archived if zero.
+	// +optional
+	LogTailLines int64 `json:"logTailLines,omitempty"`
+}
+
+// PushgatewayConfig configures the Prometheus Pushgateway the results of the runs are pushed to.
+type PushgatewayConfig struct {
+	// URL is the URL of the Pushgateway, e.g. `http://pushgateway.monitoring:9091`. No result is pushed if empty.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Job is the `job` label grouping the results. Defaults to `cronjob`.
+	// +optional
+	Job string `json:"job,omitempty"`
+
+	// CredentialsSecret is the `namespace/name` of the Secret authenticating to the Pushgateway, with a `token` key for
+	// a bearer token, or `username` and `password` keys for basic authentication.
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+// CloudEventsConfig configures the sink of the CloudEvents of the runs.
+type CloudEventsConfig struct {
+	// Sink is the URL the events are sent to: the endpoint receiving them for HTTP, the Kafka REST proxy for Kafka.
+	// No event is emitted if empty.
+	// +optional
+	Sink string `json:"sink,omitempty"`
+
+	// Protocol is how the events are sent, `HTTP` or `Kafka`. Defaults to `HTTP`.
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+
+	// Topic is the Kafka topic of the events, required with the Kafka protocol.
+	// +optional
+	Topic string `json:"topic,omitempty"`
+
+	// Source is the prefix of the source of the events, followed by the namespace and the name of the CronJob.
+	// Defaults to `/apis/batch.example.com/v1`.
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// Types restricts the emitted events to these types, e.g. `["com.example.batch.run.failed"]`. All of them are
+	// emitted if empty.
+	// +optional
+	Types []string `json:"types,omitempty"`
+
+	// CredentialsSecret is the `namespace/name` of the Secret authenticating to the sink, with a `token` key for a
+	// bearer token, or `username` and `password` keys for basic authentication.
+	// +optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+// SecureMetricsConfig configures the secure metrics endpoint
+type SecureMetricsConfig struct {
+	// Enabled replaces the plain HTTP metrics endpoint with the secure one.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// BindAddress is the address of the secure metrics endpoint. Defaults to `:8443`.
+	// +optional
+	BindAddress string `json:"bindAddress,omitempty"`
+
+	// CertDir holds the tls.crt and tls.key files of the endpoint. A self-signed certificate is generated if empty.
+	// +optional
+	CertDir string `json:"certDir,omitempty"`
+
+	// TLS hardens the TLS settings of the endpoint.
+	// +optional
+	TLS TLSConfig `json:"tls,omitempty"`
+}
+
+// APIConfig configures the read-only HTTP API
+type APIConfig struct {
+	// Enabled starts the API on its own listener.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// BindAddress is the address of the API. Defaults to `:8444`.
+	// +optional
+	BindAddress string `json:"bindAddress,omitempty"`
+
+	// CertDir holds the tls.crt and tls.key files of the API. A self-signed certificate is generated if empty.
+	// +optional
+	CertDir string `json:"certDir,omitempty"`
+
+	// TLS hardens the TLS settings of the API.
+	// +optional
+	TLS TLSConfig `json:"tls,omitempty"`
+
+	// Dashboard also serves the web dashboard of the CronJobs under `/dashboard/`, with buttons starting and
+	// suspending them.
+	// +optional
+	Dashboard bool `json:"dashboard,omitempty"`
+}
+
+// GRPCConfig configures the gRPC service, served with mutual TLS
+type GRPCConfig struct {
+	// Enabled starts the gRPC service on its own listener.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// BindAddress is the address of the gRPC service. Defaults to `:9090`.
+	// +optional
+	BindAddress string `json:"bindAddress,omitempty"`
+
+	// CertDir holds the tls.crt and tls.key files of the service. A self-signed certificate is generated if empty.
+	// +optional
+	CertDir string `json:"certDir,omitempty"`
+
+	// ClientCAFile is the PEM file of the certificate authorities of the client certificates. Required when enabled.
+	// +optional
+	ClientCAFile string `json:"clientCAFile,omitempty"`
+
+	// AllowedClients are the common names or DNS names of the client certificates allowed to call the service. Every
+	// client with a certificate of ClientCAFile is allowed if empty.
+	// +optional
+	AllowedClients []string `json:"allowedClients,omitempty"`
+
+	// TLS hardens the TLS settings of the service.
+	// +optional
+	TLS TLSConfig `json:"tls,omitempty"`
+}
+
+// HTTPTriggerConfig configures the endpoint starting runs for the external systems, served over TLS
+type HTTPTriggerConfig struct {
+	// Enabled starts the endpoint on its own listener.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// BindAddress is the address of the endpoint. Defaults to `:8445`.
+	// +optional
+	BindAddress string `json:"bindAddress,omitempty"`
+
+	// CertDir holds the tls.crt and tls.key files of the endpoint. A self-signed certificate is generated if empty.
+	// +optional
+	CertDir string `json:"certDir,omitempty"`
+
+	// CallersSecret is the `<namespace>/<name>` of the Secret holding the callers, the name of a caller as key and its
+	// secret as value. Required when enabled, unless only the Slack commands are served.
+	// +optional
+	CallersSecret string `json:"callersSecret,omitempty"`
+
+	// Slack serves the slash commands of a Slack app on the endpoint.
+	// +optional
+	Slack SlackConfig `json:"slack,omitempty"`
+
+	// TLS hardens the TLS settings of the endpoint.
+	// +optional
+	TLS TLSConfig `json:"tls,omitempty"`
+}
+
+// OperatorStatusConfig configures the OperatorStatus singleton, kept by the leader for the fleet tooling
+type OperatorStatusConfig struct {
+	// Enabled keeps the OperatorStatus up to date.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Interval is how often the OperatorStatus is updated. Defaults to 30 seconds.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// SlackConfig configures the Slack slash commands, `/cronjob run <namespace>/<name>` and
+// `/cronjob status <namespace>/<name>`, served on the trigger endpoint at `/slack/commands`.
+type SlackConfig struct {
+	// SigningSecret is the `<namespace>/<name>` of the Secret holding the signing secret of the Slack app, under the
+	// `signingSecret` key. The commands are served when set.
+	// +optional
+	SigningSecret string `json:"signingSecret,omitempty"`
+
+	// UsersConfigMap is the `<namespace>/<name>` of the ConfigMap mapping the Slack user IDs to the namespaces whose
+	// CronJobs they may run and inspect, separated by commas, `*` for all of them. Required with SigningSecret.
+	// +optional
+	UsersConfigMap string `json:"usersConfigMap,omitempty"`
+}
+
+// WebhookServerConfig configures the serving certificate of the webhook server
+type WebhookServerConfig struct {
+	// CertName is the file name of the serving certificate in `webhook.certDir`. Defaults to `tls.crt`.
+	// +optional
+	CertName string `json:"certName,omitempty"`
+
+	// KeyName is the file name of the private key in `webhook.certDir`. Defaults to `tls.key`.
+	// +optional
+	KeyName string `json:"keyName,omitempty"`
+
+	// TLS hardens the TLS settings of the webhook server.
+	// +optional
+	TLS TLSConfig `json:"tls,omitempty"`
+}
+
+// TLSConfig hardens a TLS listener of the manager. The defaults follow the guidance of controller-runtime.
+type TLSConfig struct {
+	// MinVersion is the minimum TLS version, `VersionTLS12` or `VersionTLS13`. Defaults to `VersionTLS12`.
+	// +optional
+	MinVersion string `json:"minVersion,omitempty"`
+
+	// CipherSuites are the IANA names of the cipher suites allowed up to TLS 1.2, e.g.
+	// `TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256`. Defaults to the cipher suites of Go. The cipher suites of TLS 1.3 are
+	// not configurable.
+	// +optional
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+
+	// EnableHTTP2 serves HTTP/2 next to HTTP/1.1. It is disabled by default, since the HTTP/2 stream cancellation
+	// and rapid reset vulnerabilities have been found in the HTTP/2 servers.
+	// +optional
+	EnableHTTP2 bool `json:"enableHTTP2,omitempty"`
+}
+
+/*
+Besides the settings of the manager, the config file holds the settings of our own components. They are grouped
+per component, so that they don't collide with the fields of `cfg.ControllerManagerConfigurationSpec`.
+*/
+
+// AdmissionConfig configures the admission webhooks of the CronJobs
+type AdmissionConfig struct {
+	// MutatingWebhook serves the defaulting webhook of the CronJobs. Defaults to true. Changing it requires a restart
+	// of the manager.
+	// +optional
+	MutatingWebhook *bool `json:"mutatingWebhook,omitempty"`
+
+	// ValidatingWebhook serves the validating webhook of the CronJobs. Defaults to true. Changing it requires a
+	// restart of the manager.
+	// +optional
+	ValidatingWebhook *bool `json:"validatingWebhook,omitempty"`
+
+	// ActivationHorizon is how far ahead a CronJob must have at least one scheduled activation, CronJobs which would
+	// never run within the horizon are rejected. Defaults to 4 years, so the schedules of leap days are accepted.
+	// +optional
+	ActivationHorizon *metav1.Duration `json:"activationHorizon,omitempty"`
+
+	// MinStartingDeadline is the shortest startingDeadlineSeconds accepted. A shorter deadline is missed by the time
+	// the controller reacts to the schedule. Defaults to 10 seconds.
+	// +optional
+	MinStartingDeadline *metav1.Duration `json:"minStartingDeadline,omitempty"`
+
+	// Defaults are set on the CronJobs which leave the fields unset, instead of the defaults of the operator
+	// +optional
+	Defaults CronJobDefaults `json:"defaults,omitempty"`
+
+	// DefaultTimeZone is set as the time zone of the new CronJobs which do not specify one, e.g. `Europe/Istanbul`.
+	// The existing CronJobs are left alone and keep following the time zone of the controller.
+	// +optional
+	DefaultTimeZone string `json:"defaultTimeZone,omitempty"`
+
+	// ImageRegistries restricts the images the Jobs of the CronJobs may run
+	// +optional
+	ImageRegistries ImageRegistriesConfig `json:"imageRegistries,omitempty"`
+
+	// NativeCronJobCollision is what to do when a CronJob has the same name as a native batch CronJob in the same
+	// namespace. Both would create similarly named Jobs. Defaults to Warn.
+	// +optional
+	NativeCronJobCollision CollisionPolicy `json:"nativeCronJobCollision,omitempty"`
+
+	// MaxCronJobsPerNamespace is the maximum number of CronJobs in a namespace, the creates beyond it are rejected.
+	// Zero means no limit.
+	// +optional
+	MaxCronJobsPerNamespace int `json:"maxCronJobsPerNamespace,omitempty"`
+
+	// ExtraHandlers are the additional admission handlers served by the webhook server. The handlers are compiled
+	// into the operator and registered under their names with webhooks.RegisterHandler.
+	// +optional
+	ExtraHandlers []ExtraHandlerConfig `json:"extraHandlers,omitempty"`
+}
+
+// CronJobDefaults are the defaults of the fields of the CronJobs, set by the mutating webhook
+type CronJobDefaults struct {
+	// ConcurrencyPolicy is the default concurrency policy, `Allow`, `Forbid` or `Replace`. Defaults to `Allow`.
+	// +optional
+	ConcurrencyPolicy string `json:"concurrencyPolicy,omitempty"`
+
+	// SuccessfulJobsHistoryLimit is the default number of the successful Jobs kept. Defaults to 3.
+	// +optional
+	SuccessfulJobsHistoryLimit *int32 `json:"successfulJobsHistoryLimit,omitempty"`
+
+	// FailedJobsHistoryLimit is the default number of the failed Jobs kept. Defaults to 1.
+	// +optional
+	FailedJobsHistoryLimit *int32 `json:"failedJobsHistoryLimit,omitempty"`
+}
+
+// ExtraHandlerConfig serves a registered admission handler at the given path.
+type ExtraHandlerConfig struct {
+	// Name the handler is registered under
+	Name string `json:"name"`
+
+	// Path the handler is served at, e.g. `/validate-myorg-cronjob`
+	Path string `json:"path"`
+}
+
+// CollisionPolicy describes how a name collision is handled by the validating webhook.
+type CollisionPolicy string
+
+const (
+	// CollisionWarn accepts the object with a warning.
+	CollisionWarn CollisionPolicy = "Warn"
+	// CollisionReject rejects the object.
+	CollisionReject CollisionPolicy = "Reject"
+	// CollisionIgnore skips the check.
+	CollisionIgnore CollisionPolicy = "Ignore"
+)
+
+// ImageRegistriesConfig lists the allowed and the denied image registries. Every entry matches the images under the
+// given registry or repository path, e.g. `gcr.io`, `quay.io/myorg` or `docker.io/random/*`. Path segments may
+// contain `*` wildcards. The images without a registry are assumed to come from `docker.io`.
+type ImageRegistriesConfig struct {
+	// Allowed lists the registries the images must come from. All registries are allowed when empty.
+	// +optional
+	Allowed []string `json:"allowed,omitempty"`
+
+	// Denied lists the registries the images must not come from. It takes precedence over Allowed.
+	// +optional
+	Denied []string `json:"denied,omitempty"`
 }
 
 /*
@@ -21,14 +21,425 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APIConfig) DeepCopyInto(out *APIConfig) {
+	*out = *in
+	in.TLS.DeepCopyInto(&out.TLS)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIConfig.
+func (in *APIConfig) DeepCopy() *APIConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(APIConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionConfig) DeepCopyInto(out *AdmissionConfig) {
+	*out = *in
+	if in.MutatingWebhook != nil {
+		in, out := &in.MutatingWebhook, &out.MutatingWebhook
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ValidatingWebhook != nil {
+		in, out := &in.ValidatingWebhook, &out.ValidatingWebhook
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ActivationHorizon != nil {
+		in, out := &in.ActivationHorizon, &out.ActivationHorizon
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MinStartingDeadline != nil {
+		in, out := &in.MinStartingDeadline, &out.MinStartingDeadline
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	in.Defaults.DeepCopyInto(&out.Defaults)
+	in.ImageRegistries.DeepCopyInto(&out.ImageRegistries)
+	if in.ExtraHandlers != nil {
+		in, out := &in.ExtraHandlers, &out.ExtraHandlers
+		*out = make([]ExtraHandlerConfig, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionConfig.
+func (in *AdmissionConfig) DeepCopy() *AdmissionConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArchiveConfig) DeepCopyInto(out *ArchiveConfig) {
+	*out = *in
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArchiveConfig.
+func (in *ArchiveConfig) DeepCopy() *ArchiveConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ArchiveConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuditConfig) DeepCopyInto(out *AuditConfig) {
+	*out = *in
+	if in.File != nil {
+		in, out := &in.File, &out.File
+		*out = new(LogFileConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuditConfig.
+func (in *AuditConfig) DeepCopy() *AuditConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AuditConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientConfig) DeepCopyInto(out *ClientConfig) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientConfig.
+func (in *ClientConfig) DeepCopy() *ClientConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudEventsConfig) DeepCopyInto(out *CloudEventsConfig) {
+	*out = *in
+	if in.Types != nil {
+		in, out := &in.Types, &out.Types
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudEventsConfig.
+func (in *CloudEventsConfig) DeepCopy() *CloudEventsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudEventsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronJobControllerConfig) DeepCopyInto(out *CronJobControllerConfig) {
+	*out = *in
+	in.RateLimit.DeepCopyInto(&out.RateLimit)
+	if in.RequeueJitter != nil {
+		in, out := &in.RequeueJitter, &out.RequeueJitter
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.JobRunTTL != nil {
+		in, out := &in.JobRunTTL, &out.JobRunTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.StatusDebounce != nil {
+		in, out := &in.StatusDebounce, &out.StatusDebounce
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronJobControllerConfig.
+func (in *CronJobControllerConfig) DeepCopy() *CronJobControllerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CronJobControllerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronJobDefaults) DeepCopyInto(out *CronJobDefaults) {
+	*out = *in
+	if in.SuccessfulJobsHistoryLimit != nil {
+		in, out := &in.SuccessfulJobsHistoryLimit, &out.SuccessfulJobsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailedJobsHistoryLimit != nil {
+		in, out := &in.FailedJobsHistoryLimit, &out.FailedJobsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronJobDefaults.
+func (in *CronJobDefaults) DeepCopy() *CronJobDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(CronJobDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ErrorReportingConfig) DeepCopyInto(out *ErrorReportingConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErrorReportingConfig.
+func (in *ErrorReportingConfig) DeepCopy() *ErrorReportingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ErrorReportingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtraHandlerConfig) DeepCopyInto(out *ExtraHandlerConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtraHandlerConfig.
+func (in *ExtraHandlerConfig) DeepCopy() *ExtraHandlerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtraHandlerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCConfig) DeepCopyInto(out *GRPCConfig) {
+	*out = *in
+	if in.AllowedClients != nil {
+		in, out := &in.AllowedClients, &out.AllowedClients
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.TLS.DeepCopyInto(&out.TLS)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCConfig.
+func (in *GRPCConfig) DeepCopy() *GRPCConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPTriggerConfig) DeepCopyInto(out *HTTPTriggerConfig) {
+	*out = *in
+	out.Slack = in.Slack
+	in.TLS.DeepCopyInto(&out.TLS)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPTriggerConfig.
+func (in *HTTPTriggerConfig) DeepCopy() *HTTPTriggerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPTriggerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageRegistriesConfig) DeepCopyInto(out *ImageRegistriesConfig) {
+	*out = *in
+	if in.Allowed != nil {
+		in, out := &in.Allowed, &out.Allowed
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Denied != nil {
+		in, out := &in.Denied, &out.Denied
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageRegistriesConfig.
+func (in *ImageRegistriesConfig) DeepCopy() *ImageRegistriesConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageRegistriesConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogFileConfig) DeepCopyInto(out *LogFileConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogFileConfig.
+func (in *LogFileConfig) DeepCopy() *LogFileConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LogFileConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogSamplingConfig) DeepCopyInto(out *LogSamplingConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogSamplingConfig.
+func (in *LogSamplingConfig) DeepCopy() *LogSamplingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LogSamplingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoggingConfig) DeepCopyInto(out *LoggingConfig) {
+	*out = *in
+	if in.Development != nil {
+		in, out := &in.Development, &out.Development
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Sampling != nil {
+		in, out := &in.Sampling, &out.Sampling
+		*out = new(LogSamplingConfig)
+		**out = **in
+	}
+	if in.DebugSampling != nil {
+		in, out := &in.DebugSampling, &out.DebugSampling
+		*out = new(LogSamplingConfig)
+		**out = **in
+	}
+	if in.DebugObjects != nil {
+		in, out := &in.DebugObjects, &out.DebugObjects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.File != nil {
+		in, out := &in.File, &out.File
+		*out = new(LogFileConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoggingConfig.
+func (in *LoggingConfig) DeepCopy() *LoggingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LoggingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorStatusConfig) DeepCopyInto(out *OperatorStatusConfig) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorStatusConfig.
+func (in *OperatorStatusConfig) DeepCopy() *OperatorStatusConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorStatusConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectConfig) DeepCopyInto(out *ProjectConfig) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ControllerManagerConfigurationSpec.DeepCopyInto(&out.ControllerManagerConfigurationSpec)
+	if in.WatchNamespaces != nil {
+		in, out := &in.WatchNamespaces, &out.WatchNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Controllers != nil {
+		in, out := &in.Controllers, &out.Controllers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Client.DeepCopyInto(&out.Client)
+	in.Logging.DeepCopyInto(&out.Logging)
+	in.Audit.DeepCopyInto(&out.Audit)
+	in.CronJobController.DeepCopyInto(&out.CronJobController)
+	in.Tracing.DeepCopyInto(&out.Tracing)
+	out.ErrorReporting = in.ErrorReporting
+	in.SecureMetrics.DeepCopyInto(&out.SecureMetrics)
+	in.WebhookServer.DeepCopyInto(&out.WebhookServer)
+	in.Admission.DeepCopyInto(&out.Admission)
+	in.Archive.DeepCopyInto(&out.Archive)
+	out.Pushgateway = in.Pushgateway
+	in.API.DeepCopyInto(&out.API)
+	in.GRPC.DeepCopyInto(&out.GRPC)
+	in.CloudEvents.DeepCopyInto(&out.CloudEvents)
+	in.HTTPTrigger.DeepCopyInto(&out.HTTPTrigger)
+	in.OperatorStatus.DeepCopyInto(&out.OperatorStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectConfig.
@@ -48,3 +459,130 @@ func (in *ProjectConfig) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PushgatewayConfig) DeepCopyInto(out *PushgatewayConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PushgatewayConfig.
+func (in *PushgatewayConfig) DeepCopy() *PushgatewayConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PushgatewayConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitConfig) DeepCopyInto(out *RateLimitConfig) {
+	*out = *in
+	if in.BaseDelay != nil {
+		in, out := &in.BaseDelay, &out.BaseDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxDelay != nil {
+		in, out := &in.MaxDelay, &out.MaxDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitConfig.
+func (in *RateLimitConfig) DeepCopy() *RateLimitConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecureMetricsConfig) DeepCopyInto(out *SecureMetricsConfig) {
+	*out = *in
+	in.TLS.DeepCopyInto(&out.TLS)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecureMetricsConfig.
+func (in *SecureMetricsConfig) DeepCopy() *SecureMetricsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SecureMetricsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SlackConfig) DeepCopyInto(out *SlackConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SlackConfig.
+func (in *SlackConfig) DeepCopy() *SlackConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SlackConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSConfig) DeepCopyInto(out *TLSConfig) {
+	*out = *in
+	if in.CipherSuites != nil {
+		in, out := &in.CipherSuites, &out.CipherSuites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSConfig.
+func (in *TLSConfig) DeepCopy() *TLSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TracingConfig) DeepCopyInto(out *TracingConfig) {
+	*out = *in
+	if in.SamplingRatio != nil {
+		in, out := &in.SamplingRatio, &out.SamplingRatio
+		*out = new(float64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TracingConfig.
+func (in *TracingConfig) DeepCopy() *TracingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TracingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookServerConfig) DeepCopyInto(out *WebhookServerConfig) {
+	*out = *in
+	in.TLS.DeepCopyInto(&out.TLS)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookServerConfig.
+func (in *WebhookServerConfig) DeepCopy() *WebhookServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
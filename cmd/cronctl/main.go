@@ -0,0 +1,114 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// cronctl is the CLI of the platform admins for the operations on the CronJobs of whole clusters, e.g.
+// `cronctl --context prod-eu,prod-us stale -A --days 3`.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/config"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/cronctl"
+)
+
+func main() {
+	var kubeconfig, contexts string
+	flags := flag.NewFlagSet("cronctl", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, cronctl.Usage)
+	}
+	flags.StringVar(&kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
+	flags.StringVar(&contexts, "context", "",
+		"the contexts of the kubeconfig to run the command against, separated by commas, the current one if empty")
+	_ = flags.Parse(os.Args[1:])
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(batchv1.AddToScheme(scheme))
+	utilruntime.Must(configv1.AddToScheme(scheme))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the command runs against every context in turn, a failure in one cluster does not stop the others
+	names := []string{""}
+	if contexts != "" {
+		names = strings.Split(contexts, ",")
+	}
+	var failed bool
+	for _, name := range names {
+		if len(names) > 1 {
+			fmt.Printf("==> %s <==\n", name)
+		}
+		err := run(ctx, scheme, kubeconfig, name, flags.Args())
+		if errors.Is(err, cronctl.ErrUsage) {
+			flags.Usage()
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, scheme *runtime.Scheme, kubeconfig, kubeContext string, args []string) error {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+	restConfig, err := loader.ClientConfig()
+	if err != nil {
+		return err
+	}
+	namespace, _, err := loader.Namespace()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	return cronctl.Run(ctx, &cronctl.Options{
+		Client:    c,
+		Namespace: namespace,
+		Out:       os.Stdout,
+		In:        os.Stdin,
+		LoadConfig: func(paths []string) (*configv1.ProjectConfig, error) {
+			return config.Load(paths, scheme)
+		},
+	}, args)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-cronjob is a kubectl plugin for the day-two operations on the CronJobs, e.g. `kubectl cronjob trigger
+// report`. Install it by putting the binary in the PATH.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/cronjobctl"
+)
+
+func main() {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{}
+	flags := flag.NewFlagSet("kubectl-cronjob", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, cronjobctl.Usage)
+	}
+	flags.StringVar(&rules.ExplicitPath, "kubeconfig", "", "path to the kubeconfig file")
+	flags.StringVar(&overrides.CurrentContext, "context", "", "the context of the kubeconfig to use")
+	flags.StringVar(&overrides.Context.Namespace, "n", "", "the namespace of the CronJob")
+	flags.StringVar(&overrides.Context.Namespace, "namespace", "", "the namespace of the CronJob")
+	_ = flags.Parse(os.Args[1:])
+
+	if err := run(clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides), flags.Args()); err != nil {
+		if errors.Is(err, cronjobctl.ErrUsage) {
+			flags.Usage()
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(kubeconfig clientcmd.ClientConfig, args []string) error {
+	restConfig, err := kubeconfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	namespace, _, err := kubeconfig.Namespace()
+	if err != nil {
+		return err
+	}
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(batchv1.AddToScheme(scheme))
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return cronjobctl.Run(ctx, &cronjobctl.Options{
+		Client:    c,
+		Scheme:    scheme,
+		Pods:      clientset.CoreV1(),
+		Namespace: namespace,
+		Out:       os.Stdout,
+	}, args)
+}
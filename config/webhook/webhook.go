@@ -0,0 +1,25 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook embeds the webhook configurations generated by controller-gen, so that the manager can register
+// them itself.
+package webhook
+
+import _ "embed"
+
+// Manifests holds the MutatingWebhookConfiguration and the ValidatingWebhookConfiguration of manifests.yaml.
+//go:embed manifests.yaml
+var Manifests []byte
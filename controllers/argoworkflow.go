@@ -0,0 +1,88 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+/*
+The ArgoWorkflow runner submits an Argo Workflow from a WorkflowTemplate. The Workflow gets the scheduled time
+annotation of the Workflows of the CronWorkflows of Argo, `{{workflow.scheduledTime}}` in its templates. A Workflow is
+Pending or Running until it Succeeded, Failed, or stopped on an Error.
+*/
+
+//+kubebuilder:rbac:groups=argoproj.io,resources=workflows,verbs=get;list;watch;create;patch;delete
+
+// argoScheduledTimeAnnotation is the scheduled time of a Workflow.
+const argoScheduledTimeAnnotation = "workflows.argoproj.io/scheduled-time"
+
+var argoWorkflowRunner = &externalRunner{
+	gvk:  schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Workflow"},
+	spec: argoWorkflowSpec,
+	annotate: func(annotations map[string]string) {
+		annotations[argoScheduledTimeAnnotation] = annotations[scheduledTimeAnnotation]
+	},
+	status: argoWorkflowStatus,
+}
+
+// argoWorkflowSpec returns the spec of the Workflow of a run.
+func argoWorkflowSpec(cronJob *v1.CronJob) map[string]interface{} {
+	spec := cronJob.Spec.ArgoWorkflow
+	if spec == nil {
+		return nil
+	}
+	templateRef := map[string]interface{}{"name": spec.WorkflowTemplateRef.Name}
+	if spec.WorkflowTemplateRef.ClusterScope {
+		templateRef["clusterScope"] = true
+	}
+	workflowSpec := map[string]interface{}{"workflowTemplateRef": templateRef}
+	if len(spec.Parameters) > 0 {
+		var parameters []interface{}
+		for _, p := range spec.Parameters {
+			parameters = append(parameters, map[string]interface{}{"name": p.Name, "value": p.Value})
+		}
+		workflowSpec["arguments"] = map[string]interface{}{"parameters": parameters}
+	}
+	return workflowSpec
+}
+
+// argoWorkflowStatus sets the status of the Job from the phase of the Workflow.
+func argoWorkflowStatus(workflow *unstructured.Unstructured, job *kbatch.Job) {
+	job.Status.StartTime = statusTime(workflow, "startedAt")
+
+	phase, _, _ := unstructured.NestedString(workflow.Object, "status", "phase")
+	message, _, _ := unstructured.NestedString(workflow.Object, "status", "message")
+	condition := kbatch.JobCondition{Status: corev1.ConditionTrue, Reason: phase, Message: message}
+	switch phase {
+	case "Succeeded":
+		condition.Type = kbatch.JobComplete
+	case "Failed", "Error":
+		condition.Type = kbatch.JobFailed
+	default:
+		return
+	}
+	if finishedAt := statusTime(workflow, "finishedAt"); finishedAt != nil {
+		condition.LastTransitionTime = *finishedAt
+		job.Status.CompletionTime = finishedAt
+	}
+	job.Status.Conditions = []kbatch.JobCondition{condition}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/audit"
+)
+
+// auditRun records a decision about a run of the CronJob scheduled at the time in the audit stream. The zero time is
+// a decision about no run in particular.
+func auditRun(kind audit.Kind, cronJob *v1.CronJob, scheduledTime time.Time, job, reason string,
+	inputs map[string]interface{}) {
+	decision := audit.Decision{
+		Decision:  kind,
+		Namespace: cronJob.Namespace,
+		CronJob:   cronJob.Name,
+		Job:       job,
+		Reason:    reason,
+		Inputs:    inputs,
+	}
+	if !scheduledTime.IsZero() {
+		decision.ScheduledTime = audit.At(scheduledTime)
+	}
+	audit.Record(decision)
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+	"github.com/robfig/cron"
+	kbatch "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+/*
+The BackfillReconciler replays the runs of a window. The logical times of the runs are computed once, from the
+schedule and the time zone of the CronJob, and kept in the status, so changing the CronJob does not change a Backfill
+in progress. Like the other controllers, the state of the runs is rebuilt from the Jobs on every reconcile, the Jobs
+are found by their logical time, and have deterministic names.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=backfills,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=backfills/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+
+const (
+	// backfillOwnerKey indexes the Jobs by the name of their Backfill.
+	backfillOwnerKey = ".metadata.backfill"
+	// triggerAnnotation is what started the run of a Job, the scheduled runs do not have it.
+	triggerAnnotation = "batch.example.com/trigger"
+	// backfillErrorReportingComponent is the component of the errors reported by the backfill controller.
+	backfillErrorReportingComponent = "backfill-controller"
+	// maxBackfillRuns is the number of runs of a Backfill at most, so its status stays small.
+	maxBackfillRuns = 1000
+	// maxBackfillNameLength leaves room for the logical time in the names of the Jobs.
+	maxBackfillNameLength = 52
+)
+
+// BackfillReconciler replays the runs of the CronJobs over the windows of the Backfills.
+type BackfillReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile starts the next runs of the Backfill and reports its progress.
+func (r *BackfillReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() { r.ErrorReporter.ReconcileResult(backfillErrorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(backfillErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var backfill v1.Backfill
+	if err := r.Get(ctx, req.NamespacedName, &backfill); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if backfill.Status.CompletionTime != nil {
+		return ctrl.Result{}, nil
+	}
+
+	var cronJob v1.CronJob
+	if err := r.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: backfill.Spec.CronJob},
+		&cronJob); apierrors.IsNotFound(err) {
+		return ctrl.Result{}, r.invalid(ctx, &backfill, fmt.Sprintf("CronJob %q not found", backfill.Spec.CronJob))
+	} else if err != nil {
+		logger.Error(err, "unable to get CronJob")
+		return ctrl.Result{}, err
+	}
+
+	// the runs are planned once, on the first reconcile
+	if len(backfill.Status.Runs) == 0 {
+		if cronJob.Spec.Runner != "" && cronJob.Spec.Runner != v1.JobRunner {
+			return ctrl.Result{}, r.invalid(ctx, &backfill, "the Backfills only replay the runs of the Job runner")
+		}
+		times, err := r.backfillTimes(&backfill, &cronJob)
+		if err != nil {
+			return ctrl.Result{}, r.invalid(ctx, &backfill, err.Error())
+		}
+		for _, t := range times {
+			backfill.Status.Runs = append(backfill.Status.Runs, v1.BackfillRunStatus{ScheduledTime: metav1.Time{Time: t}})
+		}
+		backfill.Status.Total = int32(len(times))
+		backfill.Status.Message = ""
+	}
+
+	var childJobs kbatch.JobList
+	if err := r.List(ctx, &childJobs, client.InNamespace(req.Namespace),
+		client.MatchingFields{backfillOwnerKey: req.Name}); err != nil {
+		logger.Error(err, "unable to list child Jobs")
+		return ctrl.Result{}, err
+	}
+	jobs := map[string]*kbatch.Job{}
+	for i := range childJobs.Items {
+		jobs[childJobs.Items[i].Annotations[scheduledTimeAnnotation]] = &childJobs.Items[i]
+	}
+
+	status := &backfill.Status
+	status.Active, status.Succeeded, status.Failed = 0, 0, 0
+	for i := range status.Runs {
+		run := &status.Runs[i]
+		job, ok := jobs[run.ScheduledTime.UTC().Format(time.RFC3339)]
+		if !ok {
+			continue
+		}
+		run.Job = job.Name
+		switch _, finishedType := jobFinished(job); finishedType {
+		case kbatch.JobComplete:
+			run.Phase = v1.JobRunSucceeded
+			status.Succeeded++
+		case kbatch.JobFailed:
+			run.Phase = v1.JobRunFailed
+			status.Failed++
+		default:
+			run.Phase = v1.JobRunRunning
+			status.Active++
+		}
+	}
+
+	maxParallelism := int32(1)
+	if backfill.Spec.MaxParallelism != nil && *backfill.Spec.MaxParallelism > 0 {
+		maxParallelism = *backfill.Spec.MaxParallelism
+	}
+	for i := range status.Runs {
+		run := &status.Runs[i]
+		if status.Active >= maxParallelism {
+			break
+		}
+		if run.Phase != "" {
+			continue
+		}
+		job, err := r.constructBackfillJob(&backfill, &cronJob, run.ScheduledTime.Time)
+		if err != nil {
+			logger.Error(err, "unable to construct job from template")
+			return ctrl.Result{}, err
+		}
+		if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+			logger.Error(err, "unable to create Job for Backfill", "job", job)
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("created Job for Backfill run", "job", job)
+		run.Job, run.Phase = job.Name, v1.JobRunRunning
+		status.Active++
+	}
+
+	status.Phase = v1.BackfillRunning
+	if status.Succeeded+status.Failed == status.Total {
+		status.Phase = v1.BackfillSucceeded
+		if status.Failed > 0 {
+			status.Phase = v1.BackfillFailed
+		}
+		status.CompletionTime = &metav1.Time{Time: r.Now()}
+	}
+	if err := r.Status().Update(ctx, &backfill); err != nil {
+		logger.Error(err, "unable to update Backfill status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// invalid reports why the Backfill can not run. It is reconciled again when it changes.
+func (r *BackfillReconciler) invalid(ctx context.Context, backfill *v1.Backfill, message string) error {
+	log.FromContext(ctx).Info("invalid Backfill", "reason", message)
+	backfill.Status.Phase = v1.BackfillInvalid
+	backfill.Status.Message = message
+	return r.Status().Update(ctx, backfill)
+}
+
+// backfillTimes returns the activations of the schedule of the CronJob within the window of the Backfill.
+func (r *BackfillReconciler) backfillTimes(backfill *v1.Backfill, cronJob *v1.CronJob) ([]time.Time, error) {
+	if len(backfill.Name) > maxBackfillNameLength {
+		return nil, fmt.Errorf("the name must be no more than %d characters", maxBackfillNameLength)
+	}
+	start, end := backfill.Spec.Start.Time, backfill.Spec.End.Time
+	if !start.Before(end) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+	if end.After(r.Now()) {
+		return nil, fmt.Errorf("end must not be in the future")
+	}
+
+	sched, err := cron.ParseStandard(cronJob.Spec.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("unparseable schedule %q: %v", cronJob.Spec.Schedule, err)
+	}
+	// the activations are computed in the time zone of the CronJob, like the scheduled runs
+	if cronJob.Spec.TimeZone != nil && featuregates.Enabled(featuregates.CronJobTimeZone) {
+		loc, err := time.LoadLocation(*cronJob.Spec.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone %q: %v", *cronJob.Spec.TimeZone, err)
+		}
+		start = start.In(loc)
+	}
+
+	var times []time.Time
+	for t := sched.Next(start.Add(-time.Second)); !t.IsZero() && t.Before(end); t = sched.Next(t) {
+		if len(times) == maxBackfillRuns {
+			return nil, fmt.Errorf("the window has more than %d runs", maxBackfillRuns)
+		}
+		times = append(times, t)
+	}
+	if len(times) == 0 {
+		return nil, fmt.Errorf("the schedule has no activation within the window")
+	}
+	return times, nil
+}
+
+// constructBackfillJob returns the Job of the run of the Backfill at the logical time.
+func (r *BackfillReconciler) constructBackfillJob(backfill *v1.Backfill, cronJob *v1.CronJob,
+	scheduledTime time.Time) (*kbatch.Job, error) {
+	job := &kbatch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+			Name:        fmt.Sprintf("%s-%d", backfill.Name, scheduledTime.Unix()),
+			Namespace:   backfill.Namespace,
+		},
+		Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		job.Annotations[k] = v
+	}
+	job.Annotations[scheduledTimeAnnotation] = scheduledTime.UTC().Format(time.RFC3339)
+	job.Annotations[managedByVersionAnnotation] = version.Get().Version
+	job.Annotations[triggerAnnotation] = string(v1.BackfillTrigger)
+	for k, v := range cronJob.Spec.JobTemplate.Labels {
+		job.Labels[k] = v
+	}
+	job.Labels[jobRunCronJobLabel] = cronJob.Name
+	setRunID(job, scheduledRunID(backfill.UID, scheduledTime))
+
+	// the Pods read their logical time through the downward API
+	if job.Spec.Template.Annotations == nil {
+		job.Spec.Template.Annotations = map[string]string{}
+	}
+	job.Spec.Template.Annotations[scheduledTimeAnnotation] = job.Annotations[scheduledTimeAnnotation]
+
+	if err := ctrl.SetControllerReference(backfill, job, r.Scheme); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BackfillReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), jobObject(), backfillOwnerKey,
+		func(rawObj client.Object) []string {
+			owner := metav1.GetControllerOf(rawObj)
+			if owner == nil || owner.APIVersion != apiGVStr || owner.Kind != "Backfill" {
+				return nil
+			}
+			return []string{owner.Name}
+		}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.Backfill{}).
+		Owns(jobObject()).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none.
+func (r *BackfillReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "backfills", []string{"get", "list", "watch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "backfills/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs", []string{"get", "list", "watch"},
+		namespaces...)...)
+	return append(permissions, startup.Permissions("batch", "jobs", []string{"get", "list", "watch", "create"},
+		namespaces...)...)
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/calendar"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+/*
+The CalendarReconciler imports the feeds of the Calendars. A Calendar is reconciled when its spec changes and on every
+refresh interval, the status updates do not wake it up. A failed import is retried sooner than the refresh interval,
+and leaves the dates of the last successful one in place.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=calendars,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=calendars/status,verbs=get;update;patch
+
+const (
+	// calendarSyncedCondition is false when the last import of the feed of the Calendar failed.
+	calendarSyncedCondition = "Synced"
+	// calendarErrorReportingComponent is the component of the errors reported by the calendar controller.
+	calendarErrorReportingComponent = "calendar-controller"
+
+	defaultCalendarRefreshInterval = 24 * time.Hour
+	// minCalendarRefreshInterval is the shortest refresh interval, and how soon a failed import is retried.
+	minCalendarRefreshInterval = 5 * time.Minute
+	// calendarImportTimeout bounds the download of a feed.
+	calendarImportTimeout = 30 * time.Second
+)
+
+// CalendarReconciler imports the dates of the Calendars from their feeds.
+type CalendarReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// HTTPClient downloads the feeds, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile imports the feed of the Calendar when its refresh interval passed, or when its spec changed.
+func (r *CalendarReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() { r.ErrorReporter.ReconcileResult(calendarErrorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(calendarErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var cal v1.Calendar
+	if err := r.Get(ctx, req.NamespacedName, &cal); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// the dates imported from a feed which was removed go away with it
+	if cal.Spec.Source == nil {
+		if cal.Status.ObservedGeneration == cal.Generation && cal.Status.LastSyncTime == nil &&
+			len(cal.Status.Conditions) == 0 {
+			return ctrl.Result{}, nil
+		}
+		cal.Status = v1.CalendarStatus{ObservedGeneration: cal.Generation}
+		return ctrl.Result{}, r.Status().Update(ctx, &cal)
+	}
+
+	refreshInterval := calendarRefreshInterval(cal.Spec.Source)
+	if cal.Status.ObservedGeneration == cal.Generation && cal.Status.LastSyncTime != nil &&
+		meta.IsStatusConditionTrue(cal.Status.Conditions, calendarSyncedCondition) {
+		if wait := cal.Status.LastSyncTime.Add(refreshInterval).Sub(r.Now()); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	importCtx, cancel := context.WithTimeout(ctx, calendarImportTimeout)
+	defer cancel()
+	dates, skipped, err := calendar.Fetch(importCtx, r.httpClient(), cal.Spec.Source.URL)
+	cal.Status.ObservedGeneration = cal.Generation
+	if err != nil {
+		logger.Error(err, "unable to import the Calendar feed", "url", cal.Spec.Source.URL)
+		meta.SetStatusCondition(&cal.Status.Conditions, metav1.Condition{Type: calendarSyncedCondition,
+			Status: metav1.ConditionFalse, Reason: "ImportFailed", Message: err.Error(),
+			ObservedGeneration: cal.Generation})
+		if err := r.Status().Update(ctx, &cal); err != nil {
+			logger.Error(err, "unable to update Calendar status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: minCalendarRefreshInterval}, nil
+	}
+
+	cal.Status.ImportedDates = dates
+	cal.Status.SkippedEvents = int32(skipped)
+	cal.Status.LastSyncTime = &metav1.Time{Time: r.Now()}
+	meta.SetStatusCondition(&cal.Status.Conditions, metav1.Condition{Type: calendarSyncedCondition,
+		Status: metav1.ConditionTrue, Reason: "Imported",
+		Message:            fmt.Sprintf("imported %d dates, skipped %d events", len(dates), skipped),
+		ObservedGeneration: cal.Generation})
+	if err := r.Status().Update(ctx, &cal); err != nil {
+		logger.Error(err, "unable to update Calendar status")
+		return ctrl.Result{}, err
+	}
+	logger.V(1).Info("imported Calendar feed", "dates", len(dates), "skippedEvents", skipped)
+	return ctrl.Result{RequeueAfter: refreshInterval}, nil
+}
+
+// calendarRefreshInterval returns how often the feed is downloaded.
+func calendarRefreshInterval(source *v1.CalendarSource) time.Duration {
+	if source.RefreshInterval == nil {
+		return defaultCalendarRefreshInterval
+	}
+	if source.RefreshInterval.Duration < minCalendarRefreshInterval {
+		return minCalendarRefreshInterval
+	}
+	return source.RefreshInterval.Duration
+}
+
+// httpClient returns the client downloading the feeds.
+func (r *CalendarReconciler) httpClient() *http.Client {
+	if r.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return r.HTTPClient
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CalendarReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.Calendar{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none.
+func (r *CalendarReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "calendars", []string{"get", "list", "watch"}, namespaces...)
+	return append(permissions, startup.Permissions(group, "calendars/status", []string{"update"}, namespaces...)...)
+}
@@ -0,0 +1,337 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+/*
+The ClusterCronJobReconciler fans the runs of the ClusterCronJobs out to the namespaces. The Jobs of a run have the
+same name in every namespace and are owned by the cluster-scoped ClusterCronJob, so they are found through an index
+across all the namespaces, and a Job changing wakes its ClusterCronJob up. The namespaces are selected at the time of
+the activation, a namespace created later gets a Job from the next run. A namespace whose Job cannot be made does not
+hold the run back in the others, it is recorded in a Warning Event of the ClusterCronJob.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=clustercronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=clustercronjobs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+const (
+	// clusterCronJobOwnerKey indexes the Jobs by the name of their ClusterCronJob.
+	clusterCronJobOwnerKey = ".metadata.clustercronjob"
+	// clusterCronJobErrorReportingComponent is the component of the errors reported by the ClusterCronJob controller.
+	clusterCronJobErrorReportingComponent = "clustercronjob-controller"
+	// maxReportedNamespaces bounds the namespaces listed in the status of a run.
+	maxReportedNamespaces = 100
+
+	defaultRunsHistoryLimit = 3
+
+	// failedCreateJobReason is a Job of a run which could not be made in a namespace.
+	failedCreateJobReason = "FailedCreate"
+)
+
+// ClusterCronJobReconciler creates the Jobs of the ClusterCronJobs in the selected namespaces.
+type ClusterCronJobReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+	// Recorder records the Events of the runs which failed in a namespace, nothing is recorded if nil.
+	Recorder record.EventRecorder
+}
+
+// Reconcile aggregates the Jobs of the latest run in the status, and starts a new run when it is scheduled.
+func (r *ClusterCronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() {
+		r.ErrorReporter.ReconcileResult(clusterCronJobErrorReportingComponent, req.NamespacedName, err)
+	}()
+	defer r.ErrorReporter.Recover(clusterCronJobErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var clusterCronJob v1.ClusterCronJob
+	if err := r.Get(ctx, req.NamespacedName, &clusterCronJob); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var childJobs kbatch.JobList
+	if err := r.List(ctx, &childJobs, client.MatchingFields{clusterCronJobOwnerKey: req.Name}); err != nil {
+		logger.Error(err, "unable to list child Jobs")
+		return ctrl.Result{}, err
+	}
+
+	// the Jobs of a run share its scheduled time, the runs are sorted from the oldest one
+	runs := map[time.Time][]*kbatch.Job{}
+	for i := range childJobs.Items {
+		job := &childJobs.Items[i]
+		scheduledTime, err := time.Parse(time.RFC3339, job.Annotations[scheduledTimeAnnotation])
+		if err != nil {
+			logger.Error(err, "unable to parse schedule time for child job", "job", job)
+			continue
+		}
+		runs[scheduledTime] = append(runs[scheduledTime], job)
+	}
+	runTimes := make([]time.Time, 0, len(runs))
+	for scheduledTime := range runs {
+		runTimes = append(runTimes, scheduledTime)
+	}
+	sort.Slice(runTimes, func(i, j int) bool { return runTimes[i].Before(runTimes[j]) })
+
+	if len(runTimes) > 0 {
+		latest := runTimes[len(runTimes)-1]
+		clusterCronJob.Status.LastScheduleTime = &metav1.Time{Time: latest}
+		clusterCronJob.Status.LastRun = clusterCronJobRun(latest, runs[latest])
+	}
+	if err := r.Status().Update(ctx, &clusterCronJob); err != nil {
+		logger.Error(err, "unable to update ClusterCronJob status")
+		return ctrl.Result{}, err
+	}
+
+	r.deleteOldRuns(ctx, &clusterCronJob, runTimes, runs)
+
+	if clusterCronJob.Spec.Suspend != nil && *clusterCronJob.Spec.Suspend {
+		logger.V(1).Info("clustercronjob suspended, skipping")
+		return ctrl.Result{}, nil
+	}
+
+	missedRun, capped, nextRun, err := nextSchedule(clusterCronJob.Spec.Schedule, clusterCronJob.Status.LastScheduleTime,
+		clusterCronJob.CreationTimestamp, clusterCronJob.Spec.StartingDeadlineSeconds, r.Now())
+	if err != nil {
+		logger.Error(err, "unable to figure out ClusterCronJob schedule")
+		return ctrl.Result{}, nil
+	}
+	if capped {
+		logger.Info("too many missed start times, only the latest one is considered", "run", missedRun)
+		metrics.RecordMissedStartsCapped(metrics.ControllerClusterCronJob)
+	}
+	scheduledResult := ctrl.Result{RequeueAfter: nextRun.Sub(r.Now())}
+	if missedRun.IsZero() {
+		return scheduledResult, nil
+	}
+	if tooLate(missedRun, clusterCronJob.Spec.StartingDeadlineSeconds, r.Now()) {
+		logger.V(1).Info("missed starting deadline for last run, sleeping till next")
+		return scheduledResult, nil
+	}
+
+	namespaces, err := r.selectedNamespaces(ctx, &clusterCronJob)
+	if err != nil {
+		logger.Error(err, "unable to select the namespaces")
+		return ctrl.Result{}, err
+	}
+	for _, namespace := range namespaces {
+		job, err := r.constructJob(&clusterCronJob, namespace, missedRun)
+		if err != nil {
+			logger.Error(err, "unable to construct job from template", "namespace", namespace)
+			r.event(&clusterCronJob, corev1.EventTypeWarning, failedCreateJobReason,
+				"Unable to construct the Job of the run scheduled at %s in namespace %s: %v",
+				missedRun.Format(time.RFC3339), namespace, err)
+			continue
+		}
+		if err := r.Create(ctx, job); err == nil {
+			metrics.RecordJobCreationDelay(ctx, metrics.ControllerClusterCronJob, r.Now().Sub(missedRun))
+		} else if !apierrors.IsAlreadyExists(err) {
+			logger.Error(err, "unable to create Job for ClusterCronJob", "job", job)
+			return ctrl.Result{}, err
+		}
+	}
+	logger.V(1).Info("started ClusterCronJob run", "scheduledTime", missedRun, "namespaces", len(namespaces))
+	return scheduledResult, nil
+}
+
+// clusterCronJobRun aggregates the Jobs of a run.
+func clusterCronJobRun(scheduledTime time.Time, jobs []*kbatch.Job) *v1.ClusterCronJobRun {
+	run := &v1.ClusterCronJobRun{ScheduledTime: metav1.Time{Time: scheduledTime}}
+	var namespaces []v1.NamespaceJobStatus
+	for _, job := range jobs {
+		phase := v1.NamespaceJobActive
+		switch _, finishedType := jobFinished(job); finishedType {
+		case kbatch.JobComplete:
+			phase = v1.NamespaceJobSucceeded
+			run.Succeeded++
+		case kbatch.JobFailed:
+			phase = v1.NamespaceJobFailed
+			run.Failed++
+		default:
+			run.Active++
+		}
+		namespaces = append(namespaces, v1.NamespaceJobStatus{Namespace: job.Namespace, JobName: job.Name,
+			Phase: phase})
+	}
+
+	// the failed Jobs are the ones worth a look, so they are listed first
+	sort.Slice(namespaces, func(i, j int) bool {
+		if failed := namespaces[i].Phase == v1.NamespaceJobFailed; failed != (namespaces[j].Phase == v1.NamespaceJobFailed) {
+			return failed
+		}
+		return namespaces[i].Namespace < namespaces[j].Namespace
+	})
+	if len(namespaces) > maxReportedNamespaces {
+		namespaces = namespaces[:maxReportedNamespaces]
+	}
+	run.Namespaces = namespaces
+	return run
+}
+
+// selectedNamespaces returns the names of the namespaces matched by the namespace selector.
+func (r *ClusterCronJobReconciler) selectedNamespaces(ctx context.Context,
+	clusterCronJob *v1.ClusterCronJob) ([]string, error) {
+	selector := labels.Everything()
+	if clusterCronJob.Spec.NamespaceSelector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(clusterCronJob.Spec.NamespaceSelector); err != nil {
+			return nil, fmt.Errorf("invalid namespace selector: %w", err)
+		}
+	}
+
+	var namespaceList corev1.NamespaceList
+	if err := r.List(ctx, &namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	var namespaces []string
+	for _, namespace := range namespaceList.Items {
+		// a terminating namespace rejects the new objects
+		if namespace.Status.Phase != corev1.NamespaceTerminating {
+			namespaces = append(namespaces, namespace.Name)
+		}
+	}
+	return namespaces, nil
+}
+
+// constructJob returns the Job of the run in the namespace.
+func (r *ClusterCronJobReconciler) constructJob(clusterCronJob *v1.ClusterCronJob, namespace string,
+	scheduledTime time.Time) (*kbatch.Job, error) {
+	job := &kbatch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+			Name:        fmt.Sprintf("%s-%d", clusterCronJob.Name, scheduledTime.Unix()),
+			Namespace:   namespace,
+		},
+		Spec: *clusterCronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+
+	for k, v := range clusterCronJob.Spec.JobTemplate.Annotations {
+		job.Annotations[k] = v
+	}
+	job.Annotations[scheduledTimeAnnotation] = scheduledTime.Format(time.RFC3339)
+	job.Annotations[managedByVersionAnnotation] = version.Get().Version
+
+	for k, v := range clusterCronJob.Spec.JobTemplate.Labels {
+		job.Labels[k] = v
+	}
+	setRunID(job, scheduledRunID(clusterCronJob.UID, scheduledTime))
+
+	if err := ctrl.SetControllerReference(clusterCronJob, job, r.Scheme); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// deleteOldRuns deletes the Jobs of the finished runs beyond the history limit. A run is finished when none of its
+// Jobs is active.
+func (r *ClusterCronJobReconciler) deleteOldRuns(ctx context.Context, clusterCronJob *v1.ClusterCronJob,
+	runTimes []time.Time, runs map[time.Time][]*kbatch.Job) {
+	logger := log.FromContext(ctx)
+	keep := int32(defaultRunsHistoryLimit)
+	if limit := clusterCronJob.Spec.RunsHistoryLimit; limit != nil {
+		keep = *limit
+	}
+
+	for i := len(runTimes) - 1; i >= 0; i-- {
+		jobs := runs[runTimes[i]]
+		if clusterCronJobRun(runTimes[i], jobs).Active > 0 {
+			continue
+		}
+		if keep > 0 {
+			keep--
+			continue
+		}
+		for _, job := range jobs {
+			err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if client.IgnoreNotFound(err) != nil {
+				logger.Error(err, "unable to delete old clustercronjob job", "job", job)
+			} else {
+				logger.V(0).Info("deleted old clustercronjob job", "job", job)
+			}
+		}
+	}
+}
+
+// event records an Event of the ClusterCronJob, if the reconciler has a recorder.
+func (r *ClusterCronJobReconciler) event(clusterCronJob *v1.ClusterCronJob, eventType, reason, messageFmt string,
+	args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(clusterCronJob, eventType, reason, messageFmt, args...)
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterCronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), jobObject(), clusterCronJobOwnerKey,
+		func(rawObj client.Object) []string {
+			owner := metav1.GetControllerOf(rawObj)
+			if owner == nil || owner.APIVersion != apiGVStr || owner.Kind != "ClusterCronJob" {
+				return nil
+			}
+			return []string{owner.Name}
+		}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.ClusterCronJob{}).
+		Owns(jobObject()).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs, in the whole cluster since the namespaces of the
+// ClusterCronJobs are not limited to the watched ones.
+func (r *ClusterCronJobReconciler) Permissions([]string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "clustercronjobs", []string{"get", "list", "watch"})
+	permissions = append(permissions, startup.Permissions(group, "clustercronjobs/status", []string{"update"})...)
+	permissions = append(permissions, startup.Permissions("batch", "jobs",
+		[]string{"get", "list", "watch", "create", "delete"})...)
+	return append(permissions, startup.Permissions("", "namespaces", []string{"get", "list", "watch"})...)
+}
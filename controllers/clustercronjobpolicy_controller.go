@@ -0,0 +1,176 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/policy"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+/*
+The ClusterCronJobPolicyReconciler is the cluster-wide counterpart of the CronJobPolicyReconciler: it evaluates the
+CronJobs of every namespace selected by the policy and reports the violations, with their namespace, in the status of
+the policy. A CronJob or a namespace change re-evaluates all the cluster policies, there are only a few of them.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=clustercronjobpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=clustercronjobpolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// clusterPolicyErrorReportingComponent is the component of the errors reported by the cluster policy controller.
+const clusterPolicyErrorReportingComponent = "clustercronjobpolicy-controller"
+
+// ClusterCronJobPolicyReconciler reports the CronJobs violating the ClusterCronJobPolicies.
+type ClusterCronJobPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// ResyncPeriod is how often the policies are evaluated when nothing changes, defaults to an hour.
+	ResyncPeriod time.Duration
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile evaluates the CronJobs of the selected namespaces against the policy and updates its status.
+func (r *ClusterCronJobPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result,
+	err error) {
+	defer func() {
+		r.ErrorReporter.ReconcileResult(clusterPolicyErrorReportingComponent, req.NamespacedName, err)
+	}()
+	defer r.ErrorReporter.Recover(clusterPolicyErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var clusterPolicy v1.ClusterCronJobPolicy
+	if err := r.Get(ctx, req.NamespacedName, &clusterPolicy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var namespaces corev1.NamespaceList
+	if err := r.List(ctx, &namespaces); err != nil {
+		logger.Error(err, "unable to list namespaces")
+		return ctrl.Result{}, err
+	}
+	var cronJobs v1.CronJobList
+	if err := r.List(ctx, &cronJobs); err != nil {
+		logger.Error(err, "unable to list CronJobs")
+		return ctrl.Result{}, err
+	}
+
+	status := v1.ClusterCronJobPolicyStatus{
+		ObservedGeneration: clusterPolicy.Generation,
+		LastEvaluationTime: &metav1.Time{Time: r.Now()},
+	}
+	selected := map[string]bool{}
+	for i := range namespaces.Items {
+		ok, err := policy.Selects(&clusterPolicy, &namespaces.Items[i])
+		if err != nil {
+			// an invalid selector is not fixed by a retry, the webhook reports it on every CronJob
+			logger.Error(err, "unable to select the namespaces")
+			return ctrl.Result{}, nil
+		}
+		if ok {
+			selected[namespaces.Items[i].Name] = true
+		}
+	}
+	status.SelectedNamespaces = int32(len(selected))
+
+	var selectedCronJobs []v1.CronJob
+	for _, cronJob := range cronJobs.Items {
+		if selected[cronJob.Namespace] {
+			selectedCronJobs = append(selectedCronJobs, cronJob)
+		}
+	}
+	status.ViolatingCronJobs, status.Violations = evaluateCronJobs(selectedCronJobs,
+		&clusterPolicy.Spec.CronJobPolicySpec, true)
+
+	clusterPolicy.Status = status
+	if err := r.Status().Update(ctx, &clusterPolicy); err != nil {
+		logger.Error(err, "unable to update ClusterCronJobPolicy status")
+		return ctrl.Result{}, err
+	}
+	logger.V(1).Info("evaluated ClusterCronJobPolicy", "selectedNamespaces", status.SelectedNamespaces,
+		"violatingCronJobs", status.ViolatingCronJobs)
+	return ctrl.Result{RequeueAfter: r.resyncPeriod()}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterCronJobPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	allPolicies := handler.EnqueueRequestsFromMapFunc(r.allPolicies)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.ClusterCronJobPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &v1.CronJob{}}, allPolicies, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, allPolicies,
+			builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs: the CronJobs are read in the watched namespaces, all of
+// them if none, the rest in the whole cluster.
+func (r *ClusterCronJobPolicyReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "clustercronjobpolicies", []string{"get", "list", "watch"})
+	permissions = append(permissions, startup.Permissions(group, "clustercronjobpolicies/status",
+		[]string{"update"})...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs", []string{"get", "list", "watch"},
+		namespaces...)...)
+	return append(permissions, startup.Permissions("", "namespaces", []string{"get", "list", "watch"})...)
+}
+
+// allPolicies returns the requests of all the ClusterCronJobPolicies.
+func (r *ClusterCronJobPolicyReconciler) allPolicies(client.Object) []reconcile.Request {
+	var policies v1.ClusterCronJobPolicyList
+	if err := r.List(context.Background(), &policies); err != nil {
+		log.Log.Error(err, "unable to list ClusterCronJobPolicies")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(policies.Items))
+	for _, p := range policies.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&p)})
+	}
+	return requests
+}
+
+// resyncPeriod returns how often the policies are evaluated when nothing changes.
+func (r *ClusterCronJobPolicyReconciler) resyncPeriod() time.Duration {
+	if r.ResyncPeriod <= 0 {
+		return defaultPolicyResyncPeriod
+	}
+	return r.ResyncPeriod
+}
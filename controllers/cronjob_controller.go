@@ -25,13 +25,37 @@ import (
 	"context"
 	"fmt"
 	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/audit"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/cloudevents"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/diagnostics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/failure"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/handover"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/missedstarts"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/notification"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/slo"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/tracing"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
 	"github.com/robfig/cron"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	kbatch "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	ref "k8s.io/client-go/tools/reference"
+	"math/rand"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"sort"
 	"time"
 
@@ -54,6 +78,35 @@ type CronJobReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Clock
+	// MaxConcurrentReconciles is the number of CronJobs reconciled in parallel.
+	MaxConcurrentReconciles int
+	// RateLimit configures the rate limiter of the work queue, it can be changed later with UpdateRateLimit.
+	RateLimit configv1.RateLimitConfig
+	// RequeueJitter is the longest random delay added to the wake-ups of the CronJobs, none if zero.
+	RequeueJitter time.Duration
+	// DeletePropagationPolicy is how the Pods of the deleted Jobs are removed, defaults to Background.
+	DeletePropagationPolicy metav1.DeletionPropagation
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+	// Notifier delivers the notifications of the finished Jobs and the missed runs, nothing is delivered if nil.
+	Notifier *notification.Dispatcher
+	// Events emits the CloudEvents of the missed runs, nothing is emitted if nil.
+	Events *cloudevents.Emitter
+	// Recorder records the Events of the scheduling decisions on the CronJobs, nothing is recorded if nil.
+	Recorder record.EventRecorder
+	// CreationDelayPerCronJob records how late the Jobs were created per CronJob too, besides per controller.
+	CreationDelayPerCronJob bool
+	// StatusDebounce coalesces the writes of the status of a CronJob within this duration, none if zero.
+	StatusDebounce time.Duration
+	// Handover keeps the statuses which could not be written on shutdown for the next leader, nothing is kept if nil.
+	Handover *handover.Store
+
+	rateLimiter *reloadableRateLimiter
+	jobRunner   *JobRunner
+	wakeups     wakeupTable
+	scheduling  schedulingTable
+	// statusWriter is nil without a StatusDebounce
+	statusWriter *statusWriter
 }
 
 /*
@@ -83,15 +136,65 @@ permissions for those, which means adding a couple more [markers](/reference/mar
 //+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs/finalizers,verbs=update
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 var (
 	// we will add scheduledTimeAnnotation to our owned Job objects as annotation
 	scheduledTimeAnnotation = "batch.example.com/scheduled-at"
+	// managedByVersionAnnotation records the version of the operator which created the Job
+	managedByVersionAnnotation = "batch.example.com/managed-by-version"
+	// missedScheduleCondition is true while the last scheduled run of the CronJob has not started
+	missedScheduleCondition = "MissedSchedule"
+	// reconciledCondition is false while the reconciles of the CronJob fail, its reason is a failure.Reason
+	reconciledCondition = "Reconciled"
+	// tooManyMissedStartsCondition is true while the CronJob missed too many runs to walk them
+	tooManyMissedStartsCondition = "TooManyMissedStarts"
 )
 
+/*
+The scheduling decisions are recorded as Events of the CronJob, so `kubectl describe` tells why a run did or did not
+start. An Event is recorded again by every reconcile seeing the same decision about the same run, the recorder
+aggregates the repeated ones into a single Event with a count, and throttles the CronJobs recording too many.
+*/
+
+// The reasons of the Events of the CronJobs.
+const (
+	// createdJobReason is a Job created for a scheduled run.
+	createdJobReason = "SuccessfulCreate"
+	// missedStartingDeadlineReason is a run not started before its starting deadline.
+	missedStartingDeadlineReason = "MissedStartingDeadline"
+	// skippedConcurrencyForbidReason is a run not started while the previous ones are active.
+	skippedConcurrencyForbidReason = "SkippedConcurrencyForbid"
+	// replacedActiveJobReason is an active Job deleted to start a run with the Replace concurrency policy.
+	replacedActiveJobReason = "ReplacedActiveJob"
+	// tooManyMissedStartsReason is a schedule which missed too many runs to walk them.
+	tooManyMissedStartsReason = "TooManyMissedStarts"
+)
+
+// errorReportingComponent is the component of the errors reported by the controller.
+const errorReportingComponent = "cronjob-controller"
+
 // Reconcile makes CronJobReconciler a Reconciler
-func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	/*
+		Every reconcile is a trace, the calls of the client are its child spans when the client is wrapped with
+		tracing.WrapClient. The trace ID is logged, so the logs of a slow reconcile lead to its trace.
+	*/
+	ctx, span := tracing.Tracer().Start(ctx, "Reconcile CronJob", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace), attribute.String("k8s.object.name", req.Name)))
+	defer func() { tracing.End(span, err) }()
+	defer func(start time.Time) { metrics.RecordReconcile(ctx, metrics.ControllerCronJob, start, err) }(time.Now())
+	defer func() { r.ErrorReporter.ReconcileResult(errorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(errorReportingComponent, req.NamespacedName)
+	// what the reconcile made of the CronJob is served on /debug/scheduling
+	var scheduling diagnostics.Scheduling
+	defer func() { r.recordScheduling(req.NamespacedName, &scheduling, result, err) }()
+
 	logger := log.FromContext(ctx)
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		logger = logger.WithValues("traceID", traceID)
+		ctx = log.IntoContext(ctx, logger)
+	}
 	logger.Info("inside reconciliation logic", "name", req.String())
 
 	/*
@@ -111,38 +214,75 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			We'll ignore not-found errors, since they can't be fixed by an immediate requeue (we'll need to wait for a
 			new notification), and we can get them on deleted requests.
 		*/
+		r.wakeups.delete(req.NamespacedName)
+		if apierrors.IsNotFound(err) {
+			r.scheduling.delete(req.NamespacedName)
+			metrics.ForgetCronJob(req.Namespace, req.Name)
+			audit.Forget(req.Namespace, req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	scheduling.LastReconcile = r.Now()
+	/*
+		The outcome of the reconcile is reported on the Reconciled condition, with the reason of its error. The errors
+		waiting for a new spec, like an invalid template, are not returned to avoid the retries: they are reported
+		through terminal instead.
+	*/
+	var terminal error
+	defer func() {
+		if terminal != nil && err == nil {
+			metrics.RecordReconcileError(metrics.ControllerCronJob, failure.ReasonOf(terminal))
+		}
+		failed := err
+		if failed == nil {
+			failed = terminal
+		}
+		if statusErr := r.setReconciled(ctx, &cronJob, failed); statusErr != nil && err == nil {
+			logger.Error(statusErr, "unable to update CronJob status")
+			err = statusErr
+		}
+	}()
 
 	/*
 		######### 2: List all active jobs, and update the status
 
 		To fully update our status, we'll need to list all child jobs in this namespace that belong to this CronJob.
 
-		Similarly to Get, we can use the List method to list the child jobs.  Notice that we use variadic options to
-		set the namespace and field match (which is actually an index lookup that we set up below).
+		Similarly to Get, we list the child jobs, through the Runner of the CronJob which also gives back the runs of
+		the other backends seen as jobs. The Job runner uses the List method, with variadic options to set the
+		namespace and field match (which is actually an index lookup that we set up below). The active jobs and the
+		finished ones are listed apart, the former decide the concurrency and the latter are the ones to clean up.
 	*/
 	var childJobs kbatch.JobList
-	if err := r.List(ctx, &childJobs, client.InNamespace(req.Namespace), client.MatchingFields{jobOwnerKey: req.Name}); err != nil {
-		logger.Error(err, "unable to list child Jobs")
+	activeRuns, err := listRunsInState(ctx, r.Client, &cronJob, r.jobRunner, runActive)
+	if err != nil {
+		logger.Error(err, "unable to list child Jobs", "runner", cronJob.Spec.Runner)
 		return ctrl.Result{}, err
 	}
+	finishedRuns, err := listRunsInState(ctx, r.Client, &cronJob, r.jobRunner, runFinished)
+	if err != nil {
+		logger.Error(err, "unable to list child Jobs", "runner", cronJob.Spec.Runner)
+		return ctrl.Result{}, err
+	}
+	childJobs.Items = append(activeRuns, finishedRuns...)
 	/*
-		### What is this index about?(on the r.List function call client.MatchingFields{jobOwnerKey: req.Name})
+		### What is this index about?(on the List call of the Job runner, client.MatchingFields{jobStateKey: ...})
 
 		The reconciler fetches all jobs owned by the cronjob for the status. As our number of cronjobs increases,
 		looking these up can become quite slow as we have to filter through all of them. For a more efficient lookup,
-		these jobs will be indexed locally on the controller's name. A jobOwnerKey field is added to the cached job
-		objects. This key references the owning controller and functions as the index. Later in this
-		document we will configure the manager to actually index this field.
+		these jobs will be indexed locally on the controller's name and on whether they finished. A jobStateKey field
+		is added to the cached job objects, like `nightly/active`. This key references the owning controller and the
+		state of the job, and functions as the index. Later in this document we will configure the manager to
+		actually index this field. With the MetadataOnlyJobs feature gate, the cache holds no status to tell whether
+		a job finished: the jobs are only indexed on the controller's name, and filtered on their status.
 
 		Once we have all the jobs we own, we'll split them into active, successful, and failed jobs, keeping track
 		of the most recent run so that we can record it in status.  Remember, status should be able to be
 		reconstituted from the state of the world, so it's generally not a good idea to read from the status of the
 		root object. Instead, you should reconstruct it every run.  That's what we'll do here.
 
-		We can check if a job is "finished" and whether it succeeded or failed using status conditions.  We'll
-		put that logic in a helper to make our code cleaner.
+		The index already tells the finished jobs apart, whether they succeeded or failed is in their status
+		conditions.
 	*/
 
 	// find the active list of jobs
@@ -150,22 +290,7 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	var successfulJobs []*kbatch.Job
 	var failedJobs []*kbatch.Job
 	var mostRecentTime *time.Time // find the last run so we can update the status
-
-	/*
-		We consider a job "finished" if it has a "Complete" or "Failed" condition marked as true. Status conditions
-		allow us to add extensible status information to our objects that other humans and controllers can examine to
-		check things like completion and health.
-	*/
-	isJobFinished := func(job *kbatch.Job) (bool, kbatch.JobConditionType) {
-		for _, c := range job.Status.Conditions {
-			if (c.Type == kbatch.JobComplete || c.Type == kbatch.JobFailed) && c.Status == corev1.ConditionTrue {
-				return true, c.Type
-			}
-		}
-
-		return false, ""
-	}
-	// +kubebuilder:docs-gen:collapse=isJobFinished
+	var lastSuccessfulTime *time.Time
 
 	// We'll use a helper to extract the scheduled time from the annotation that we added during job creation.
 	getScheduledTimeForJob := func(job *kbatch.Job) (*time.Time, error) {
@@ -184,7 +309,12 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	// In that for loop, we are splitting our child jobs to relevant slices
 	for i, job := range childJobs.Items {
-		_, finishedType := isJobFinished(&job)
+		// We consider a job "finished" if it has a "Complete" or "Failed" condition marked as true, which the index
+		// checked when the job changed.
+		var finishedType kbatch.JobConditionType
+		if i >= len(activeRuns) {
+			finishedType = finishedCondition(&job).Type
+		}
 		switch finishedType {
 		case "": // ongoing
 			activeJobs = append(activeJobs, &childJobs.Items[i])
@@ -192,6 +322,18 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			failedJobs = append(failedJobs, &childJobs.Items[i])
 		case kbatch.JobComplete:
 			successfulJobs = append(successfulJobs, &childJobs.Items[i])
+			completed := finishedCondition(&job).LastTransitionTime.Time
+			if job.Status.CompletionTime != nil {
+				completed = job.Status.CompletionTime.Time
+			}
+			if lastSuccessfulTime == nil || completed.After(*lastSuccessfulTime) {
+				lastSuccessfulTime = &completed
+			}
+		}
+
+		// the manual runs are not part of the schedule, they do not move the last schedule time
+		if job.Annotations[triggerAnnotation] == string(v1.ManualTrigger) {
+			continue
 		}
 
 		// We'll store the launch time in an annotation, so we'll reconstitute that from the active jobs themselves.
@@ -217,6 +359,40 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		cronJob.Status.LastScheduleTime = nil
 	}
 
+	/*
+		The successful Jobs might have been deleted by the history limits since, the last successful time is then kept.
+		It is exported as the time since the last success of the CronJob, the time since its creation until one
+		succeeds, so a CronJob which never runs is alerted on too.
+	*/
+	if lastSuccessfulTime != nil && (cronJob.Status.LastSuccessfulTime == nil ||
+		lastSuccessfulTime.After(cronJob.Status.LastSuccessfulTime.Time)) {
+		cronJob.Status.LastSuccessfulTime = &metav1.Time{Time: *lastSuccessfulTime}
+	}
+	if cronJob.Status.LastSuccessfulTime != nil {
+		metrics.SetLastSuccess(cronJob.Namespace, cronJob.Name, cronJob.Status.LastSuccessfulTime.Time)
+	} else {
+		metrics.SetLastSuccess(cronJob.Namespace, cronJob.Name, cronJob.CreationTimestamp.Time)
+	}
+
+	// The compliance with the SLO is computed from the results the jobrun controller recorded in the JobRuns.
+	cronJob.Status.SLO = nil
+	var objective float64
+	if cronJob.Spec.SLO != nil {
+		var runs v1.JobRunList
+		if err := r.List(ctx, &runs, client.InNamespace(req.Namespace),
+			client.MatchingLabels{jobRunCronJobLabel: req.Name}); err != nil {
+			logger.Error(err, "unable to list JobRuns")
+			return ctrl.Result{}, err
+		}
+		var sloErr error
+		if cronJob.Status.SLO, sloErr = slo.Compliance(cronJob.Spec.SLO, runs.Items); sloErr != nil {
+			logger.Error(sloErr, "invalid SLO")
+		} else {
+			objective, _ = slo.Objective(cronJob.Spec.SLO)
+		}
+	}
+	metrics.SetSLOObjective(cronJob.Namespace, cronJob.Name, objective, cronJob.Status.SLO != nil)
+
 	// In here, we are setting .status.active with our currently running job references
 	cronJob.Status.Active = nil
 	for _, activeJob := range activeJobs {
@@ -235,18 +411,24 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	*/
 	logger.V(1).Info("job count", "active jobs", len(activeJobs), "successful jobs",
 		len(successfulJobs), "failed jobs", len(failedJobs))
+	metrics.SetActiveJobs(cronJob.Namespace, cronJob.Name, len(activeJobs))
 
 	/*
 		Using the date we've gathered, we'll update the status of our CRD. Just like before, we use our client.
 		To specifically update the status subresource, we'll use the `Status` part of the client, with the `Update`
 		method. The status subresource ignores changes to spec, so it's less likely to conflict with any other
-		updates, and can have separate permissions.
+		updates, and can have separate permissions. With a StatusDebounce, the writes of a burst of reconciles are
+		coalesced into one instead.
 	*/
-	if err := r.Status().Update(ctx, &cronJob); err != nil {
+	if err := r.updateStatus(ctx, &cronJob); err != nil {
 		logger.Error(err, "unable to update CronJob status")
 		return ctrl.Result{}, err
 	}
 
+	// The channels of the CronJob are told about the finished Jobs before the old ones are cleaned up.
+	r.notifyFinishedJobs(&cronJob, v1.JobSucceededEvent, successfulJobs)
+	r.notifyFinishedJobs(&cronJob, v1.JobFailedEvent, failedJobs)
+
 	/*
 		######### 3: Clean up old jobs according to the history limit
 
@@ -256,7 +438,11 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		First, we'll try to clean up old jobs, so that we don't leave too many lying around.
 	*/
 
-	// NB: deleting these is "best effort" -- if we fail on a particular one, we won't requeue just to finish the deleting.
+	/*
+		NB: deleting these is "best effort" -- if we fail on a particular one, we won't requeue just to finish the
+		deleting. When the manager is shutting down, the context is cancelled and we stop between two deletions, instead
+		of being killed in the middle of a long cleanup. The next reconcile picks up where we left.
+	*/
 	if cronJob.Spec.FailedJobsHistoryLimit != nil {
 		sort.Slice(failedJobs, func(i, j int) bool {
 			if failedJobs[i].Status.StartTime == nil {
@@ -268,11 +454,18 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			if int32(i) >= int32(len(failedJobs))-*cronJob.Spec.FailedJobsHistoryLimit {
 				break
 			}
+			if ctx.Err() != nil {
+				break
+			}
 
-			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			if err := r.deleteRun(ctx, job); client.IgnoreNotFound(err) != nil {
 				logger.Error(err, "unable to delete old failed job", "job", job)
 			} else {
 				logger.V(0).Info("deleted old failed job", "job", job)
+				metrics.RecordJobDeleted(metrics.DeleteHistoryLimit)
+				auditRun(audit.JobDeleted, &cronJob, time.Time{}, job.Name, string(metrics.DeleteHistoryLimit),
+					map[string]interface{}{"failedJobsHistoryLimit": *cronJob.Spec.FailedJobsHistoryLimit,
+						"failedJobs": len(failedJobs)})
 			}
 		}
 	}
@@ -288,15 +481,27 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			if int32(i) >= int32(len(successfulJobs))-*cronJob.Spec.SuccessfulJobsHistoryLimit {
 				break
 			}
+			if ctx.Err() != nil {
+				break
+			}
 
-			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); (err) != nil {
+			if err := r.deleteRun(ctx, job); (err) != nil {
 				logger.Error(err, "unable to delete old successful job", "job", job)
 			} else {
 				logger.V(0).Info("deleted old successful job", "job", job)
+				metrics.RecordJobDeleted(metrics.DeleteHistoryLimit)
+				auditRun(audit.JobDeleted, &cronJob, time.Time{}, job.Name, string(metrics.DeleteHistoryLimit),
+					map[string]interface{}{"successfulJobsHistoryLimit": *cronJob.Spec.SuccessfulJobsHistoryLimit,
+						"successfulJobs": len(successfulJobs)})
 			}
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		logger.Info("shutting down, leaving the rest of the reconciliation to the next run")
+		return ctrl.Result{}, err
+	}
+
 	/*
 		######### 4: Check if we're suspended
 
@@ -307,9 +512,90 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
 		logger.V(1).Info("cronjob suspended, skipping")
+		r.wakeups.delete(req.NamespacedName)
+		scheduling.Skipped = "suspended"
 		return ctrl.Result{}, nil
 	}
 
+	/*
+		A CronJob referencing a JobTemplate has nothing to run until the template is copied into it, by the defaulting
+		webhook or the JobTemplate controller. The copy updates the CronJob, which brings us back here.
+	*/
+	if !jobTemplateResolved(&cronJob) {
+		logger.V(1).Info("waiting for the JobTemplate to be copied, skipping", "jobTemplate",
+			cronJob.Spec.JobTemplateRef.Name)
+		r.wakeups.delete(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	/*
+		A ScheduleOverride can suspend the CronJob, or replace its schedule, for a while. We'll wake up when an
+		override starts or expires, so the CronJob goes back to its own schedule by itself.
+	*/
+	overrides, err := r.scheduleOverrides(ctx, &cronJob)
+	if err != nil {
+		logger.Error(err, "unable to list ScheduleOverrides")
+		return ctrl.Result{}, err
+	}
+	override, overrideChange := activeOverride(overrides, r.Now()), nextOverrideChange(overrides, r.Now())
+	if override != nil {
+		scheduling.ScheduleOverride = override.Name
+	}
+	if override != nil && override.Spec.Suspend {
+		logger.V(1).Info("cronjob suspended by a schedule override, skipping", "scheduleOverride", override.Name)
+		r.wakeups.delete(req.NamespacedName)
+		scheduling.Skipped = "suspended by the schedule override"
+		return ctrl.Result{RequeueAfter: overrideChange.Sub(r.Now())}, nil
+	}
+
+	/*
+		A MaintenanceWindow keeps the CronJob from starting runs while it is open, and deletes its active Jobs if it
+		drains them. We'll wake up when a window opens or closes, too.
+	*/
+	windows, err := r.maintenanceWindows(ctx, &cronJob)
+	if err != nil {
+		logger.Error(err, "unable to list MaintenanceWindows")
+		return ctrl.Result{}, err
+	}
+	window, windowChange := openMaintenanceWindow(windows, r.Now()), nextMaintenanceWindowChange(windows, r.Now())
+	if window != nil {
+		logger.V(1).Info("cronjob in a maintenance window, skipping", "kind", window.Kind, "maintenanceWindow",
+			window.Name)
+		scheduling.MaintenanceWindow = window.Kind + "/" + window.Name
+		scheduling.Skipped = "in the maintenance window"
+		if window.Drain {
+			for _, activeJob := range activeJobs {
+				if err := r.deleteRun(ctx, activeJob); client.IgnoreNotFound(err) != nil {
+					logger.Error(err, "unable to drain active job", "job", activeJob)
+					return ctrl.Result{}, err
+				} else if err == nil {
+					metrics.RecordJobDeleted(metrics.DeleteMaintenanceWindow)
+					auditRun(audit.JobDeleted, &cronJob, time.Time{}, activeJob.Name,
+						string(metrics.DeleteMaintenanceWindow),
+						map[string]interface{}{"kind": window.Kind, "maintenanceWindow": window.Name})
+				}
+			}
+		}
+		r.wakeups.delete(req.NamespacedName)
+		return ctrl.Result{RequeueAfter: windowChange.Sub(r.Now())}, nil
+	}
+
+	scheduledCronJob := &cronJob
+	if override != nil {
+		scheduledCronJob = cronJob.DeepCopy()
+		scheduledCronJob.Spec.Schedule = *override.Spec.Schedule
+		logger = logger.WithValues("scheduleOverride", override.Name)
+	}
+	// the runs scheduled before an override started or expired, or a maintenance window closed, are not caught up
+	since := lastOverrideChange(overrides, r.Now())
+	if closed := lastMaintenanceWindowClose(windows, lastScheduleOrCreation(&cronJob), r.Now()); closed.After(since) {
+		since = closed
+	}
+	if since = since.Add(-time.Second); since.After(lastScheduleOrCreation(&cronJob)) {
+		scheduledCronJob = scheduledCronJob.DeepCopy()
+		scheduledCronJob.Status.LastScheduleTime = &metav1.Time{Time: since}
+	}
+
 	/*
 		######### 5: Get the next scheduled run
 
@@ -319,15 +605,32 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		We'll calculate the next scheduled time using our helpful cron library. We'll start calculating appropriate
 		times from our last run, or the creation of the CronJob if we can't find a last run.
 
-		If there are too many missed runs and we don't have any deadlines set, we'll bail so that we don't cause
-		issues on controller restarts or wedges. Otherwise, we'll just return the missed runs (of which we'll
-		just use the latest), and the next run, so that we can know when it's time to reconcile again.
+		If there are too many missed runs and we don't have any deadlines set, we'll stop walking them so that we
+		don't cause issues on controller restarts or wedges, and search for the latest one instead. Either way, we'll
+		just return the latest missed run, whether there were too many, and the next run, so that we can know when
+		it's time to reconcile again.
 	*/
 
-	getNextSchedule := func(cronJob *v1.CronJob, now time.Time) (lastMissed time.Time, next time.Time, err error) {
+	getNextSchedule := func(cronJob *v1.CronJob, now time.Time) (lastMissed time.Time, capped bool, next time.Time,
+		err error) {
 		sched, err := cron.ParseStandard(cronJob.Spec.Schedule)
 		if err != nil {
-			return time.Time{}, time.Time{}, fmt.Errorf("unparseable schedule %q: %v", cronJob.Spec.Schedule, err)
+			return time.Time{}, false, time.Time{}, fmt.Errorf("unparseable schedule %q: %v", cronJob.Spec.Schedule,
+				err)
+		}
+
+		/*
+			The cron library computes the activations in the location of the time it is given, so we move the
+			current time into the time zone of the CronJob. Without a time zone, or with the CronJobTimeZone feature
+			gate disabled, we stay in the one of the controller.
+		*/
+		if cronJob.Spec.TimeZone != nil && featuregates.Enabled(featuregates.CronJobTimeZone) {
+			loc, err := time.LoadLocation(*cronJob.Spec.TimeZone)
+			if err != nil {
+				return time.Time{}, false, time.Time{}, fmt.Errorf("unknown time zone %q: %v",
+					*cronJob.Spec.TimeZone, err)
+			}
+			now = now.In(loc)
 		}
 
 		/*
@@ -336,9 +639,9 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		*/
 		var earliestTime time.Time
 		if cronJob.Status.LastScheduleTime != nil {
-			earliestTime = cronJob.Status.LastScheduleTime.Time
+			earliestTime = cronJob.Status.LastScheduleTime.Time.In(now.Location())
 		} else {
-			earliestTime = cronJob.ObjectMeta.CreationTimestamp.Time
+			earliestTime = cronJob.ObjectMeta.CreationTimestamp.Time.In(now.Location())
 		}
 
 		if cronJob.Spec.StartingDeadlineSeconds != nil {
@@ -350,44 +653,63 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			}
 		}
 		if earliestTime.After(now) {
-			return time.Time{}, sched.Next(now), nil
-		}
-
-		starts := 0
-		for t := sched.Next(earliestTime); !t.After(now); t = sched.Next(t) {
-			lastMissed = t
-			/*
-				An object might miss several starts. For example, if controller gets wedged on Friday at 5:01pm when
-				everyone has gone home, and someone comes in on Tuesday AM and discovers the problem and restarts the
-				controller, then all the hourly jobs, more than 80 of them for one hourly scheduledJob, should all
-				start running with no further intervention (if the scheduledJob allows concurrency and late starts).
-
-				However, if there is a bug somewhere, or incorrect clock on controller's server or apiservers (for
-				setting creationTimestamp) then there could be so many missed start times (it could be off by decades
-				or more), that it would eat up all the CPU and memory of this controller. In that case, we want to not
-				try to list all the missed start times.
-			*/
-			starts++
-			if starts > 100 {
-				// We can't get the most recent times so just return an empty slice
-				return time.Time{}, time.Time{}, fmt.Errorf("too many missed start times (> 100). set or " +
-					"decrease .spec.startingDeadlineSeconds or check clock skew")
-			}
+			return time.Time{}, false, sched.Next(now), nil
 		}
-		return lastMissed, sched.Next(now), nil
+
+		/*
+			An object might miss several starts. For example, if controller gets wedged on Friday at 5:01pm when
+			everyone has gone home, and someone comes in on Tuesday AM and discovers the problem and restarts the
+			controller, then all the hourly jobs, more than 80 of them for one hourly scheduledJob, should all
+			start running with no further intervention (if the scheduledJob allows concurrency and late starts).
+
+			However, if there is a bug somewhere, or incorrect clock on controller's server or apiservers (for
+			setting creationTimestamp) then there could be so many missed start times (it could be off by decades
+			or more), that it would eat up all the CPU and memory of this controller. In that case, we want to not
+			try to list all the missed start times: beyond missedstarts.MaxWalked of them, the latest one is
+			searched for.
+		*/
+		lastMissed, capped = missedstarts.Latest(sched, earliestTime, now)
+		return lastMissed, capped, sched.Next(now), nil
 	}
 	// +kubebuilder:docs-gen:collapse=getNextSchedule
 
 	// Figure out the next times that we need to create jobs at (or anything we missed).
-	missedRun, nextRun, err := getNextSchedule(&cronJob, r.Now())
+	missedRun, capped, nextRun, err := getNextSchedule(scheduledCronJob, r.Now())
 	if err != nil {
 		logger.Error(err, "unable to figure out CronJob schedule")
+		r.wakeups.delete(req.NamespacedName)
+		scheduling.Skipped = "unable to figure out the schedule: " + err.Error()
+		terminal = failure.New(failure.TemplateInvalid, err)
 		// We don't really care about requeuing until we get an update that fixes the schedule, so don't return an error
 		return ctrl.Result{}, nil
 	}
+	r.wakeups.set(req.NamespacedName, nextRun)
+	scheduling.NextRun = &nextRun
+
+	/*
+		A CronJob which missed too many runs to walk them, like an every-minute one suspended for months, only catches
+		up with the latest one. The TooManyMissedStarts condition tells so until a run is scheduled again.
+	*/
+	if capped {
+		logger.Info("too many missed start times, only the latest one is considered", "current run", missedRun)
+		metrics.RecordMissedStartsCapped(metrics.ControllerCronJob)
+		r.event(&cronJob, corev1.EventTypeWarning, tooManyMissedStartsReason, "Missed more than %d start times, only "+
+			"the latest one at %s is considered: set or decrease .spec.startingDeadlineSeconds or check clock skew",
+			missedstarts.MaxWalked, missedRun.Format(time.RFC3339))
+	}
+	if err := r.setTooManyMissedStarts(ctx, &cronJob, capped); err != nil {
+		logger.Error(err, "unable to update CronJob status")
+		return ctrl.Result{}, err
+	}
 
 	// We'll prep our eventual request to requeue until the next job, and then figure out if we actually need to run.
-	scheduledResult := ctrl.Result{RequeueAfter: nextRun.Sub(r.Now())} // save this so we can re-use it elsewhere
+	scheduledResult := ctrl.Result{RequeueAfter: nextRun.Sub(r.Now()) + r.requeueJitter()} // save this so we can re-use it elsewhere
+	if !overrideChange.IsZero() && overrideChange.Before(nextRun) {
+		scheduledResult.RequeueAfter = overrideChange.Sub(r.Now())
+	}
+	if !windowChange.IsZero() && windowChange.Sub(r.Now()) < scheduledResult.RequeueAfter {
+		scheduledResult.RequeueAfter = windowChange.Sub(r.Now())
+	}
 	logger = logger.WithValues("now", r.Now(), "next run", nextRun, "diff", nextRun.Sub(r.Now()))
 
 	/*
@@ -408,7 +730,34 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 	if tooLate {
 		logger.V(1).Info("missed starting deadline for last run, sleeping till next")
-		// TODO(directxman12): events
+		metrics.RecordRunSkipped(metrics.SkipStartingDeadline)
+		metrics.RecordMissedRun(cronJob.Namespace, cronJob.Name, missedRun)
+		auditRun(audit.RunSkipped, &cronJob, missedRun, "", string(metrics.SkipStartingDeadline),
+			map[string]interface{}{"startingDeadlineSeconds": *cronJob.Spec.StartingDeadlineSeconds})
+		r.Events.MissedRun(req.NamespacedName, cronJob.UID, missedRun)
+		r.notifyMissedRun(&cronJob, missedRun)
+		r.event(&cronJob, corev1.EventTypeWarning, missedStartingDeadlineReason,
+			"Missed the starting deadline of %ds of the run scheduled at %s", *cronJob.Spec.StartingDeadlineSeconds,
+			missedRun.Format(time.RFC3339))
+		if err := r.setMissedSchedule(ctx, &cronJob, metav1.ConditionTrue, "StartingDeadlineExceeded",
+			fmt.Sprintf("the run scheduled at %s missed its starting deadline", missedRun.Format(time.RFC3339))); err != nil {
+			logger.Error(err, "unable to update CronJob status")
+			return ctrl.Result{}, err
+		}
+		return scheduledResult, nil
+	}
+
+	// The runner might not be able to start the run, e.g. when its feature gate was disabled since.
+	if denied := runnerDenied(&cronJob); denied != "" {
+		logger.Info("runner can not start the run, skipping", "reason", denied)
+		metrics.RecordRunSkipped(metrics.SkipRunner)
+		auditRun(audit.RunSkipped, &cronJob, missedRun, "", string(metrics.SkipRunner),
+			map[string]interface{}{"denied": denied})
+		if err := r.setMissedSchedule(ctx, &cronJob, metav1.ConditionTrue, "RunnerUnavailable",
+			fmt.Sprintf("the run scheduled at %s can not start: %s", missedRun.Format(time.RFC3339), denied)); err != nil {
+			logger.Error(err, "unable to update CronJob status")
+			return ctrl.Result{}, err
+		}
 		return scheduledResult, nil
 	}
 
@@ -422,6 +771,43 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	*/
 	if cronJob.Spec.ConcurrencyPolicy == v1.ForbidConcurrent && len(activeJobs) > 0 {
 		logger.V(1).Info("concurrency policy blocks concurrent runs, skipping", "num active", len(activeJobs))
+		metrics.RecordRunSkipped(metrics.SkipConcurrencyPolicy)
+		auditRun(audit.RunSkipped, &cronJob, missedRun, "", string(metrics.SkipConcurrencyPolicy),
+			map[string]interface{}{"concurrencyPolicy": cronJob.Spec.ConcurrencyPolicy, "activeJobs": len(activeJobs)})
+		r.event(&cronJob, corev1.EventTypeNormal, skippedConcurrencyForbidReason,
+			"Skipped the run scheduled at %s, the concurrency policy is Forbid and %d Jobs are active",
+			missedRun.Format(time.RFC3339), len(activeJobs))
+		if err := r.setMissedSchedule(ctx, &cronJob, metav1.ConditionTrue, "ConcurrencyPolicy",
+			fmt.Sprintf("the run scheduled at %s waits for %d active Jobs", missedRun.Format(time.RFC3339),
+				len(activeJobs))); err != nil {
+			logger.Error(err, "unable to update CronJob status")
+			return ctrl.Result{}, err
+		}
+		return scheduledResult, nil
+	}
+
+	// ...the CronJobQuotas of the namespace might not allow one more run either...
+	replaced := 0
+	if cronJob.Spec.ConcurrencyPolicy == v1.ReplaceConcurrent {
+		replaced = len(activeJobs)
+	}
+	if denied, err := r.quotaDenied(ctx, &cronJob, replaced); err != nil {
+		logger.Error(err, "unable to check the CronJobQuotas")
+		return ctrl.Result{}, err
+	} else if denied != "" {
+		logger.V(1).Info("quota blocks the run, retrying later", "reason", denied)
+		metrics.RecordRunSkipped(metrics.SkipQuota)
+		auditRun(audit.RunSkipped, &cronJob, missedRun, "", string(metrics.SkipQuota),
+			map[string]interface{}{"denied": denied})
+		if err := r.setMissedSchedule(ctx, &cronJob, metav1.ConditionTrue, "QuotaExceeded",
+			fmt.Sprintf("the run scheduled at %s is denied by a quota: %s", missedRun.Format(time.RFC3339),
+				denied)); err != nil {
+			logger.Error(err, "unable to update CronJob status")
+			return ctrl.Result{}, err
+		}
+		if scheduledResult.RequeueAfter > quotaRetryInterval {
+			scheduledResult.RequeueAfter = quotaRetryInterval
+		}
 		return scheduledResult, nil
 	}
 
@@ -429,9 +815,16 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if cronJob.Spec.ConcurrencyPolicy == v1.ReplaceConcurrent {
 		for _, activeJob := range activeJobs {
 			// We don't care if the job was already deleted
-			if err := r.Delete(ctx, activeJob, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			if err := r.deleteRun(ctx, activeJob); client.IgnoreNotFound(err) != nil {
 				logger.Error(err, "unable to delete active job", "job", activeJob)
 				return ctrl.Result{}, err
+			} else if err == nil {
+				metrics.RecordJobDeleted(metrics.DeleteReplaced)
+				auditRun(audit.JobDeleted, &cronJob, missedRun, activeJob.Name, string(metrics.DeleteReplaced),
+					map[string]interface{}{"concurrencyPolicy": cronJob.Spec.ConcurrencyPolicy})
+				r.event(&cronJob, corev1.EventTypeNormal, replacedActiveJobReason,
+					"Deleted the active Job %s to start the run scheduled at %s", activeJob.Name,
+					missedRun.Format(time.RFC3339))
 			}
 		}
 	}
@@ -465,10 +858,12 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			job.Annotations[k] = v
 		}
 		job.Annotations[scheduledTimeAnnotation] = scheduledTime.Format(time.RFC3339)
+		job.Annotations[managedByVersionAnnotation] = version.Get().Version
 
 		for k, v := range cronJob.Spec.JobTemplate.Labels {
 			job.Labels[k] = v
 		}
+		setRunID(job, scheduledRunID(cronJob.UID, scheduledTime))
 
 		if err := ctrl.SetControllerReference(cronJob, job, r.Scheme); err != nil {
 			return nil, err
@@ -482,17 +877,47 @@ func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	job, err := constructJobForCronJob(&cronJob, missedRun)
 	if err != nil {
 		logger.Error(err, "unable to construct job from template")
+		terminal = failure.New(failure.TemplateInvalid, err)
 		// Don't bother requeuing until we get a change to the spec
 		return scheduledResult, nil
 	}
 
-	// ...and create it on the cluster
-	if err := r.Create(ctx, job); err != nil {
+	// ...and create it on the cluster, through the Runner of the CronJob
+	if err := runnerOf(&cronJob, r.jobRunner).CreateRun(ctx, r.Client, &cronJob, job); err != nil {
 		logger.Error(err, "unable to create Job for CronJob", "job", job)
 		return ctrl.Result{}, err
 	}
 
-	logger.V(1).Info("created Job for CronJob run", "job", job)
+	logger.V(1).Info("created Job for CronJob run", "job", job, "runID", job.Labels[runIDLabel])
+	r.event(&cronJob, corev1.EventTypeNormal, createdJobReason, "Created Job %s for the run scheduled at %s", job.Name,
+		missedRun.Format(time.RFC3339))
+	metrics.RecordJobCreated(job.Namespace)
+	// how late the run starts because of us, the requeue jitter included
+	creationDelay := r.Now().Sub(missedRun)
+	metrics.RecordJobCreationDelay(ctx, metrics.ControllerCronJob, creationDelay)
+	if r.CreationDelayPerCronJob {
+		metrics.RecordCronJobCreationDelay(ctx, cronJob.Namespace, cronJob.Name, creationDelay)
+	}
+	inputs := map[string]interface{}{"runID": job.Labels[runIDLabel], "schedule": scheduledCronJob.Spec.Schedule,
+		"concurrencyPolicy": cronJob.Spec.ConcurrencyPolicy, "activeJobs": len(activeJobs)}
+	if override != nil {
+		inputs["scheduleOverride"] = override.Name
+	}
+	if cronJob.Spec.TimeZone != nil {
+		inputs["timeZone"] = *cronJob.Spec.TimeZone
+	}
+	auditRun(audit.RunCreated, &cronJob, missedRun, job.Name, "", inputs)
+	if err := r.setMissedSchedule(ctx, &cronJob, metav1.ConditionFalse, "RunStarted",
+		fmt.Sprintf("the run scheduled at %s started", missedRun.Format(time.RFC3339))); err != nil {
+		logger.Error(err, "unable to update CronJob status")
+		return ctrl.Result{}, err
+	}
+
+	/*
+		The status does not list the new Job yet. If the manager shuts down right now, the status writer flushes the
+		statuses it holds before the manager exits, and whatever it could not write is rebuilt from the Jobs by the
+		next leader; the deterministic name of the Job keeps the next leader from creating the same run twice.
+	*/
 
 	/*
 		######### 7: Requeue when we either see a running job or it's time for the next scheduled run
@@ -519,6 +944,7 @@ call Reconcile on the underlying CronJob when a Job changes, is deleted, etc.
 */
 var (
 	jobOwnerKey = ".metadata.controller"
+	jobStateKey = ".metadata.controller.state"
 	apiGVStr    = v1.GroupVersion.String()
 )
 
@@ -528,29 +954,213 @@ func (r *CronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		r.Clock = realClock{}
 	}
 
-	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &kbatch.Job{}, jobOwnerKey, func(rawObj client.Object) []string {
-		// grab the job object, extract the owner...
-		job := rawObj.(*kbatch.Job)
-		owner := metav1.GetControllerOf(job)
-		if owner == nil {
-			return nil
-		}
-		// ...make sure it's a CronJob...
-		if owner.APIVersion != apiGVStr || owner.Kind != "CronJob" {
-			return nil
-		}
-
-		// ...and if so, return it
-		return []string{owner.Name}
-	}); err != nil {
+	jobRunner, err := newJobRunner(mgr.GetFieldIndexer())
+	if err != nil {
 		return err
 	}
+	r.jobRunner = jobRunner
+
+	r.rateLimiter = newReloadableRateLimiter(r.RateLimit)
+	if r.StatusDebounce > 0 {
+		r.statusWriter = newStatusWriter(r.Client, mgr.GetAPIReader(), r.StatusDebounce, r.Handover)
+		if err := mgr.Add(r.statusWriter); err != nil {
+			return err
+		}
+	}
 
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&v1.CronJob{}).
-		Owns(&kbatch.Job{}).
+		Owns(jobObject()).
+		// the results of the runs recorded by the jobrun controller change the compliance with the SLO
+		Owns(&v1.JobRun{})
+	// the objects of the external runners are only watched with their feature gate, their CRDs might be missing
+	for _, runner := range enabledExternalRunners() {
+		builder = builder.Owns(runner.Object())
+	}
+	return builder.
+		Watches(&source.Kind{Type: &v1.ScheduleOverride{}}, handler.EnqueueRequestsFromMapFunc(r.cronJobsOfOverride)).
+		Watches(&source.Kind{Type: &v1.MaintenanceWindow{}},
+			handler.EnqueueRequestsFromMapFunc(r.cronJobsOfMaintenanceWindow)).
+		Watches(&source.Kind{Type: &v1.ClusterMaintenanceWindow{}},
+			handler.EnqueueRequestsFromMapFunc(r.cronJobsOfClusterMaintenanceWindow)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles, RateLimiter: r.rateLimiter}).
 		Complete(r)
 }
 
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none, including
+// the ones of the notifications, of the runners of the feature gates and of the handover.
+func (r *CronJobReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "cronjobs", []string{"get", "list", "watch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "scheduleoverrides",
+		[]string{"get", "list", "watch"}, namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "maintenancewindows",
+		[]string{"get", "list", "watch"}, namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "clustermaintenancewindows",
+		[]string{"get", "list", "watch"})...)
+	permissions = append(permissions, startup.Permissions("", "namespaces", []string{"get", "list", "watch"})...)
+	permissions = append(permissions, startup.Permissions(group, "notificationchannels", []string{"get"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "notificationchannels/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions("", "secrets", []string{"get"}, namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobquotas", []string{"get", "list", "watch"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "jobruns", []string{"get", "list", "watch"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions("batch", "jobs",
+		[]string{"get", "list", "watch", "create", "patch", "delete"}, namespaces...)...)
+	if featuregates.Enabled(featuregates.ArgoWorkflowRunner) {
+		permissions = append(permissions, startup.Permissions("argoproj.io", "workflows",
+			[]string{"get", "list", "watch", "create", "patch", "delete"}, namespaces...)...)
+	}
+	if featuregates.Enabled(featuregates.TektonPipelineRunner) {
+		permissions = append(permissions, startup.Permissions("tekton.dev", "pipelineruns",
+			[]string{"get", "list", "watch", "create", "patch", "delete"}, namespaces...)...)
+	}
+	if r.Handover != nil {
+		if key, err := r.Handover.ObjectKey(); err == nil {
+			permissions = append(permissions, startup.Permissions("", "configmaps", []string{"get", "create", "update"},
+				key.Namespace)...)
+		}
+	}
+	return permissions
+}
+
+// newJobRunner indexes the Jobs on the name of their CronJob, and on whether they finished unless the MetadataOnlyJobs
+// feature gate leaves no status in the cache, and returns the Job runner listing them with these indexes.
+func newJobRunner(indexer client.FieldIndexer) (*JobRunner, error) {
+	if err := indexer.IndexField(context.Background(), jobObject(), jobOwnerKey, indexJobOwner); err != nil {
+		return nil, err
+	}
+	jobRunner := &JobRunner{OwnerIndex: jobOwnerKey}
+	if !featuregates.Enabled(featuregates.MetadataOnlyJobs) {
+		if err := indexer.IndexField(context.Background(), &kbatch.Job{}, jobStateKey, indexJobState); err != nil {
+			return nil, err
+		}
+		jobRunner.StateIndex = jobStateKey
+	}
+	return jobRunner, nil
+}
+
+// indexJobOwner returns the name of the CronJob controlling the Job, if any.
+func indexJobOwner(rawObj client.Object) []string {
+	// grab the job object, extract the owner...
+	owner := metav1.GetControllerOf(rawObj)
+	if owner == nil {
+		return nil
+	}
+	// ...make sure it's a CronJob...
+	if owner.APIVersion != apiGVStr || owner.Kind != "CronJob" {
+		return nil
+	}
+
+	// ...and if so, return it
+	return []string{owner.Name}
+}
+
+// indexJobState returns the name of the CronJob controlling the Job with whether the Job finished, if any.
+func indexJobState(rawObj client.Object) []string {
+	owner := indexJobOwner(rawObj)
+	if owner == nil {
+		return nil
+	}
+	return []string{stateIndexValue(owner[0], stateOf(rawObj.(*kbatch.Job)))}
+}
+
+// UpdateRateLimit applies the rate limits to the running controller.
+func (r *CronJobReconciler) UpdateRateLimit(config configv1.RateLimitConfig) {
+	r.rateLimiter.update(config)
+}
+
+// requeueJitter returns a random delay up to RequeueJitter, spreading the wake-ups of the CronJobs sharing a schedule.
+func (r *CronJobReconciler) requeueJitter() time.Duration {
+	if r.RequeueJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(r.RequeueJitter)))
+}
+
+// deleteRun deletes a run through its runner, with the propagation policy of the deletes of the Jobs.
+func (r *CronJobReconciler) deleteRun(ctx context.Context, run *kbatch.Job) error {
+	return runnerOfRun(run).DeleteRun(ctx, r.Client, run, r.deletePropagation())
+}
+
+// deletePropagation returns the propagation policy of the deletes of the Jobs.
+func (r *CronJobReconciler) deletePropagation() client.PropagationPolicy {
+	if r.DeletePropagationPolicy == "" {
+		return client.PropagationPolicy(metav1.DeletePropagationBackground)
+	}
+	return client.PropagationPolicy(r.DeletePropagationPolicy)
+}
+
 // TODO: add successful job references to status subresource
 // TODO: add failed job references to status subresource
+
+// setMissedSchedule sets the MissedSchedule condition of the CronJob, and updates its status if the condition changed.
+func (r *CronJobReconciler) setMissedSchedule(ctx context.Context, cronJob *v1.CronJob, status metav1.ConditionStatus,
+	reason, message string) error {
+	return r.setCondition(ctx, cronJob, missedScheduleCondition, status, reason, message)
+}
+
+// setTooManyMissedStarts sets the TooManyMissedStarts condition of the CronJob, once it missed too many runs.
+func (r *CronJobReconciler) setTooManyMissedStarts(ctx context.Context, cronJob *v1.CronJob, capped bool) error {
+	if capped {
+		return r.setCondition(ctx, cronJob, tooManyMissedStartsCondition, metav1.ConditionTrue, "MissedStartsCapped",
+			fmt.Sprintf("more than %d start times were missed, only the latest one is considered",
+				missedstarts.MaxWalked))
+	}
+	if !meta.IsStatusConditionTrue(cronJob.Status.Conditions, tooManyMissedStartsCondition) {
+		return nil
+	}
+	return r.setCondition(ctx, cronJob, tooManyMissedStartsCondition, metav1.ConditionFalse, "CaughtUp",
+		"the CronJob is back on its schedule")
+}
+
+// setCondition sets the condition of the CronJob, and updates its status if the condition changed.
+func (r *CronJobReconciler) setCondition(ctx context.Context, cronJob *v1.CronJob, conditionType string,
+	status metav1.ConditionStatus, reason, message string) error {
+	previous := meta.FindStatusCondition(cronJob.Status.Conditions, conditionType)
+	if previous != nil && previous.Status == status && previous.Reason == reason && previous.Message == message {
+		return nil
+	}
+	meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{Type: conditionType, Status: status,
+		Reason: reason, Message: message, ObservedGeneration: cronJob.Generation})
+	return r.updateStatus(ctx, cronJob)
+}
+
+// updateStatus writes the status of the CronJob, through the statusWriter if any.
+func (r *CronJobReconciler) updateStatus(ctx context.Context, cronJob *v1.CronJob) error {
+	if r.statusWriter != nil {
+		return r.statusWriter.write(ctx, cronJob)
+	}
+	return r.Status().Update(ctx, cronJob)
+}
+
+// setReconciled sets the Reconciled condition of the CronJob from the error of the reconcile, if it changed. A failed
+// update is only returned after a successful reconcile, a failed one is retried anyway.
+func (r *CronJobReconciler) setReconciled(ctx context.Context, cronJob *v1.CronJob, failed error) error {
+	status, reason, message := metav1.ConditionTrue, "ReconcileSucceeded", "the CronJob was reconciled"
+	if failed != nil {
+		status, reason, message = metav1.ConditionFalse, string(failure.ReasonOf(failed)), failed.Error()
+	}
+	previous := meta.FindStatusCondition(cronJob.Status.Conditions, reconciledCondition)
+	if previous != nil && previous.Status == status && previous.Reason == reason && previous.Message == message {
+		return nil
+	}
+	meta.SetStatusCondition(&cronJob.Status.Conditions, metav1.Condition{Type: reconciledCondition, Status: status,
+		Reason: reason, Message: message, ObservedGeneration: cronJob.Generation})
+	if err := r.updateStatus(ctx, cronJob); client.IgnoreNotFound(err) != nil && failed == nil {
+		return err
+	}
+	return nil
+}
+
+// event records an Event of the CronJob, if the reconciler has a recorder.
+func (r *CronJobReconciler) event(cronJob *v1.CronJob, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(cronJob, eventType, reason, messageFmt, args...)
+	}
+}
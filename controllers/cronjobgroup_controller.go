@@ -0,0 +1,220 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+/*
+The CronJobGroupReconciler fans the fields set in a group out to its members, with a patch of the members differing
+from it, so the webhook validates the new fields like any other update. A member whose update is rejected is reported
+in the status of the group, and does not stop the others from being updated.
+
+The group is reconciled again when it changes, and when a CronJob or a JobRun of its namespace changes, since they
+decide its members and their health.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobgroups,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobgroups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobruns,verbs=get;list;watch
+
+const (
+	// maxReportedMembers bounds the members listed in the status of a group.
+	maxReportedMembers = 100
+	// groupErrorReportingComponent is the component of the errors reported by the group controller.
+	groupErrorReportingComponent = "cronjobgroup-controller"
+)
+
+// CronJobGroupReconciler applies the CronJobGroups to their members and sums up their health.
+type CronJobGroupReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile applies the group to its members and updates its status.
+func (r *CronJobGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() { r.ErrorReporter.ReconcileResult(groupErrorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(groupErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var group v1.CronJobGroup
+	if err := r.Get(ctx, req.NamespacedName, &group); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(&group.Spec.Selector)
+	if err != nil {
+		// the selector is validated by the API server, it can not be fixed by retrying
+		logger.Error(err, "invalid selector")
+		return ctrl.Result{}, nil
+	}
+	var members v1.CronJobList
+	if err := r.List(ctx, &members, client.InNamespace(req.Namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		logger.Error(err, "unable to list CronJobs")
+		return ctrl.Result{}, err
+	}
+	sort.Slice(members.Items, func(i, j int) bool { return members.Items[i].Name < members.Items[j].Name })
+
+	var runs v1.JobRunList
+	if err := r.List(ctx, &runs, client.InNamespace(req.Namespace)); err != nil {
+		logger.Error(err, "unable to list JobRuns")
+		return ctrl.Result{}, err
+	}
+	lastRuns := lastFinishedRuns(runs.Items)
+
+	status := v1.CronJobGroupStatus{ObservedGeneration: group.Generation}
+	for i := range members.Items {
+		cronJob := &members.Items[i]
+		if err := r.applyGroup(ctx, &group.Spec, cronJob); apierrors.IsInvalid(err) || apierrors.IsForbidden(err) {
+			if len(status.UpdateErrors) < maxReportedMembers {
+				status.UpdateErrors = append(status.UpdateErrors,
+					v1.CronJobGroupUpdateError{CronJob: cronJob.Name, Message: err.Error()})
+			}
+		} else if err != nil {
+			logger.Error(err, "unable to update CronJob", "cronJob", cronJob.Name)
+			return ctrl.Result{}, err
+		}
+
+		status.Members++
+		if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+			status.Suspended++
+		}
+		status.Active += int32(len(cronJob.Status.Active))
+		if run, ok := lastRuns[cronJob.Name]; ok && run.Status.Phase != v1.JobRunSucceeded {
+			status.Failing++
+			if len(status.FailingCronJobs) < maxReportedMembers {
+				status.FailingCronJobs = append(status.FailingCronJobs, cronJob.Name)
+			}
+		}
+	}
+
+	group.Status = status
+	if err := r.Status().Update(ctx, &group); err != nil {
+		logger.Error(err, "unable to update CronJobGroup status")
+		return ctrl.Result{}, err
+	}
+	logger.V(1).Info("applied CronJobGroup", "members", status.Members, "failing", status.Failing)
+	return ctrl.Result{}, nil
+}
+
+// applyGroup patches the fields set in the group on the CronJob, when they differ. The CronJob is left as it was if
+// the patch fails.
+func (r *CronJobGroupReconciler) applyGroup(ctx context.Context, spec *v1.CronJobGroupSpec,
+	member *v1.CronJob) error {
+	patch := client.MergeFrom(member)
+	cronJob := member.DeepCopy()
+	changed := false
+	setBool := func(field **bool, value *bool) {
+		if value != nil && (*field == nil || **field != *value) {
+			*field, changed = value, true
+		}
+	}
+	setInt32 := func(field **int32, value *int32) {
+		if value != nil && (*field == nil || **field != *value) {
+			*field, changed = value, true
+		}
+	}
+	setBool(&cronJob.Spec.Suspend, spec.Suspend)
+	setInt32(&cronJob.Spec.SuccessfulJobsHistoryLimit, spec.SuccessfulJobsHistoryLimit)
+	setInt32(&cronJob.Spec.FailedJobsHistoryLimit, spec.FailedJobsHistoryLimit)
+	if spec.TimeZone != nil && (cronJob.Spec.TimeZone == nil || *cronJob.Spec.TimeZone != *spec.TimeZone) {
+		cronJob.Spec.TimeZone, changed = spec.TimeZone, true
+	}
+	if !changed {
+		return nil
+	}
+	if err := r.Patch(ctx, cronJob, patch); err != nil {
+		return err
+	}
+	*member = *cronJob
+	return nil
+}
+
+// lastFinishedRuns returns the last finished JobRun of every CronJob, by the name of the CronJob.
+func lastFinishedRuns(runs []v1.JobRun) map[string]*v1.JobRun {
+	last := map[string]*v1.JobRun{}
+	for i := range runs {
+		run := &runs[i]
+		if run.Status.Phase == "" || run.Status.Phase == v1.JobRunRunning {
+			continue
+		}
+		if prev, ok := last[run.Spec.CronJob]; !ok || prev.Spec.ScheduledTime.Before(&run.Spec.ScheduledTime) {
+			last[run.Spec.CronJob] = run
+		}
+	}
+	return last
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CronJobGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	byNamespace := handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		return r.groupsOfNamespace(obj.GetNamespace())
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.CronJobGroup{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &v1.CronJob{}}, byNamespace).
+		Watches(&source.Kind{Type: &v1.JobRun{}}, byNamespace).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none.
+func (r *CronJobGroupReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "cronjobgroups", []string{"get", "list", "watch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobgroups/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs",
+		[]string{"get", "list", "watch", "patch"}, namespaces...)...)
+	return append(permissions, startup.Permissions(group, "jobruns", []string{"get", "list", "watch"},
+		namespaces...)...)
+}
+
+// groupsOfNamespace returns the requests of all the CronJobGroups of the namespace.
+func (r *CronJobGroupReconciler) groupsOfNamespace(namespace string) []reconcile.Request {
+	var groups v1.CronJobGroupList
+	if err := r.List(context.Background(), &groups, client.InNamespace(namespace)); err != nil {
+		log.Log.Error(err, "unable to list CronJobGroups", "namespace", namespace)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(groups.Items))
+	for _, g := range groups.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&g)})
+	}
+	return requests
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/policy"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+/*
+The webhook only sees the CronJobs created or updated after a CronJobPolicy, and it can be bypassed. The
+CronJobPolicyReconciler evaluates the policy on all the existing CronJobs of its namespace, with the same rules as the
+webhook, and reports the violations in the status of the policy. It never changes the CronJobs.
+
+The policy is evaluated again when it changes, when a CronJob of its namespace changes, and periodically, since a
+minimum interval depends on the upcoming activations. The fields looser than a ClusterCronJobPolicy selecting the
+namespace are reported too, since they have no effect.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobpolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=clustercronjobpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+const (
+	// maxReportedViolations bounds the violations listed in the status, so it stays well under the size limit.
+	maxReportedViolations = 100
+	// defaultPolicyResyncPeriod is how often the policies are evaluated when nothing changes.
+	defaultPolicyResyncPeriod = time.Hour
+	// policyErrorReportingComponent is the component of the errors reported by the policy controller.
+	policyErrorReportingComponent = "cronjobpolicy-controller"
+)
+
+// CronJobPolicyReconciler reports the CronJobs violating the CronJobPolicies.
+type CronJobPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// ResyncPeriod is how often the policies are evaluated when nothing changes, defaults to an hour.
+	ResyncPeriod time.Duration
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile evaluates the CronJobs of the namespace against the policy and updates its status.
+func (r *CronJobPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() { r.ErrorReporter.ReconcileResult(policyErrorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(policyErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var cronJobPolicy v1.CronJobPolicy
+	if err := r.Get(ctx, req.NamespacedName, &cronJobPolicy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var cronJobs v1.CronJobList
+	if err := r.List(ctx, &cronJobs, client.InNamespace(req.Namespace)); err != nil {
+		logger.Error(err, "unable to list CronJobs")
+		return ctrl.Result{}, err
+	}
+
+	status := v1.CronJobPolicyStatus{
+		ObservedGeneration: cronJobPolicy.Generation,
+		LastEvaluationTime: &metav1.Time{Time: r.Now()},
+	}
+	status.ViolatingCronJobs, status.Violations = evaluateCronJobs(cronJobs.Items, &cronJobPolicy.Spec, false)
+
+	ineffectiveFields, err := r.ineffectiveFields(ctx, &cronJobPolicy)
+	if err != nil {
+		logger.Error(err, "unable to compare the CronJobPolicy to the ClusterCronJobPolicies")
+		return ctrl.Result{}, err
+	}
+	status.IneffectiveFields = ineffectiveFields
+
+	cronJobPolicy.Status = status
+	if err := r.Status().Update(ctx, &cronJobPolicy); err != nil {
+		logger.Error(err, "unable to update CronJobPolicy status")
+		return ctrl.Result{}, err
+	}
+	logger.V(1).Info("evaluated CronJobPolicy", "violatingCronJobs", status.ViolatingCronJobs)
+	return ctrl.Result{RequeueAfter: r.resyncPeriod()}, nil
+}
+
+// ineffectiveFields returns the fields of the policy looser than the cluster policies selecting its namespace.
+func (r *CronJobPolicyReconciler) ineffectiveFields(ctx context.Context, cronJobPolicy *v1.CronJobPolicy) ([]string,
+	error) {
+	var clusterPolicies v1.ClusterCronJobPolicyList
+	if err := r.List(ctx, &clusterPolicies); err != nil || len(clusterPolicies.Items) == 0 {
+		return nil, err
+	}
+	var namespace corev1.Namespace
+	if err := r.Get(ctx, client.ObjectKey{Name: cronJobPolicy.Namespace}, &namespace); err != nil {
+		return nil, err
+	}
+	layers, err := policy.Resolve(&namespace, clusterPolicies.Items, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, layer := range layers {
+		fields = append(fields, policy.IneffectiveFields(&cronJobPolicy.Spec, layer)...)
+	}
+	return fields, nil
+}
+
+// evaluateCronJobs evaluates the CronJobs against the policy, and returns the number of violating CronJobs and the
+// first violations. The namespaces of the CronJobs are recorded in the violations if withNamespace is set.
+func evaluateCronJobs(cronJobs []v1.CronJob, spec *v1.CronJobPolicySpec, withNamespace bool) (int32,
+	[]v1.PolicyViolation) {
+	sort.Slice(cronJobs, func(i, j int) bool {
+		if cronJobs[i].Namespace != cronJobs[j].Namespace {
+			return cronJobs[i].Namespace < cronJobs[j].Namespace
+		}
+		return cronJobs[i].Name < cronJobs[j].Name
+	})
+
+	var violating int32
+	var violations []v1.PolicyViolation
+	for i := range cronJobs {
+		cronJob := &cronJobs[i]
+		errs := policy.Evaluate(cronJob, spec)
+		if len(errs) == 0 {
+			continue
+		}
+		violating++
+		for _, err := range errs {
+			if len(violations) == maxReportedViolations {
+				break
+			}
+			violation := v1.PolicyViolation{CronJob: cronJob.Name, Field: err.Field, Message: err.Detail}
+			if withNamespace {
+				violation.Namespace = cronJob.Namespace
+			}
+			violations = append(violations, violation)
+		}
+	}
+	return violating, violations
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CronJobPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	/*
+		The status updates of the policies and of the CronJobs do not change what is evaluated, so only the changes of
+		their generation and of the labels of the CronJobs trigger a reconcile. The labels of the namespaces and the
+		cluster policies decide the ineffective fields.
+	*/
+	changed := builder.WithPredicates(predicate.GenerationChangedPredicate{})
+	byCronJob := handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		return r.policiesOfNamespace(obj.GetNamespace())
+	})
+	byNamespace := handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		return r.policiesOfNamespace(obj.GetName())
+	})
+	byClusterPolicy := handler.EnqueueRequestsFromMapFunc(func(client.Object) []reconcile.Request {
+		return r.policiesOfNamespace(metav1.NamespaceAll)
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.CronJobPolicy{}, changed).
+		Watches(&source.Kind{Type: &v1.CronJob{}}, byCronJob, builder.WithPredicates(
+			predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, byNamespace,
+			builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Watches(&source.Kind{Type: &v1.ClusterCronJobPolicy{}}, byClusterPolicy, changed).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none. The
+// ClusterCronJobPolicies and the namespaces are read in the whole cluster.
+func (r *CronJobPolicyReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "cronjobpolicies", []string{"get", "list", "watch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobpolicies/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs", []string{"get", "list", "watch"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "clustercronjobpolicies",
+		[]string{"get", "list", "watch"})...)
+	return append(permissions, startup.Permissions("", "namespaces", []string{"get", "list", "watch"})...)
+}
+
+// policiesOfNamespace returns the requests of all the CronJobPolicies of the namespace, or of all the namespaces.
+func (r *CronJobPolicyReconciler) policiesOfNamespace(namespace string) []reconcile.Request {
+	var policies v1.CronJobPolicyList
+	if err := r.List(context.Background(), &policies, client.InNamespace(namespace)); err != nil {
+		log.Log.Error(err, "unable to list CronJobPolicies", "namespace", namespace)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(policies.Items))
+	for _, p := range policies.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&p)})
+	}
+	return requests
+}
+
+// resyncPeriod returns how often the policies are evaluated when nothing changes.
+func (r *CronJobPolicyReconciler) resyncPeriod() time.Duration {
+	if r.ResyncPeriod <= 0 {
+		return defaultPolicyResyncPeriod
+	}
+	return r.ResyncPeriod
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/quota"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+/*
+The CronJobQuotaReconciler only reports the usage in the status of the quotas, the CronJob controller and the webhook
+enforce them. The usage is counted again when a CronJob is created or deleted, when a Job of a CronJob changes, and
+every minute, since the runs of the last hour are a sliding window.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobquotas/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobruns,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+
+// quotaErrorReportingComponent is the component of the errors reported by the quota controller.
+const quotaErrorReportingComponent = "cronjobquota-controller"
+
+// CronJobQuotaReconciler reports the usage of the CronJobQuotas.
+type CronJobQuotaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile counts the usage of the namespace of the quota and updates its status.
+func (r *CronJobQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() { r.ErrorReporter.ReconcileResult(quotaErrorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(quotaErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var cronJobQuota v1.CronJobQuota
+	if err := r.Get(ctx, req.NamespacedName, &cronJobQuota); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	now := r.Now()
+	usage, err := quota.Usage(ctx, r, req.Namespace, now)
+	if err != nil {
+		logger.Error(err, "unable to count the usage of the CronJobQuota")
+		return ctrl.Result{}, err
+	}
+
+	cronJobQuota.Status = v1.CronJobQuotaStatus{
+		ObservedGeneration: cronJobQuota.Generation,
+		Used:               &usage,
+		LastSyncTime:       &metav1.Time{Time: now},
+	}
+	if err := r.Status().Update(ctx, &cronJobQuota); err != nil {
+		logger.Error(err, "unable to update CronJobQuota status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: quotaRetryInterval}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CronJobQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	byNamespace := handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		return r.quotasOfNamespace(obj.GetNamespace())
+	})
+	cronJobRuns := predicate.NewPredicateFuncs(isCronJobRun)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.CronJobQuota{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &v1.CronJob{}}, byNamespace, builder.WithPredicates(predicate.Funcs{
+			UpdateFunc: func(event.UpdateEvent) bool { return false },
+		})).
+		Watches(&source.Kind{Type: jobObject()}, byNamespace, builder.WithPredicates(cronJobRuns)).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none.
+func (r *CronJobQuotaReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "cronjobquotas", []string{"get", "list", "watch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobquotas/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs", []string{"get", "list", "watch"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "jobruns", []string{"get", "list", "watch"},
+		namespaces...)...)
+	return append(permissions, startup.Permissions("batch", "jobs", []string{"get", "list", "watch"},
+		namespaces...)...)
+}
+
+// quotasOfNamespace returns the requests of all the CronJobQuotas of the namespace.
+func (r *CronJobQuotaReconciler) quotasOfNamespace(namespace string) []reconcile.Request {
+	var quotas v1.CronJobQuotaList
+	if err := r.List(context.Background(), &quotas, client.InNamespace(namespace)); err != nil {
+		log.Log.Error(err, "unable to list CronJobQuotas", "namespace", namespace)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(quotas.Items))
+	for _, q := range quotas.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&q)})
+	}
+	return requests
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/cronjobreport"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/diagnostics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+/*
+The CronJobReportReconciler keeps the CronJobReport of every namespace with CronJobs, and deletes it once the last
+CronJob of the namespace is gone. The CronJobs and their Jobs changing refresh the report of their namespace, which is
+refreshed every minute too. Its own status updates do not, the report has no spec and so never changes its generation.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobreports,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobreports/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobruns,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+
+// reportErrorReportingComponent is the component of the errors reported by the report controller.
+const reportErrorReportingComponent = "cronjobreport-controller"
+
+// reportInterval is how often the CronJobReports are refreshed.
+const reportInterval = time.Minute
+
+// CronJobReportReconciler keeps the CronJobReports of the namespaces.
+type CronJobReportReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// NextRuns are the next runs of the CronJobs, the reports have no next run if nil.
+	NextRuns diagnostics.WakeupSource
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile summarizes the CronJobs of the namespace into its CronJobReport.
+func (r *CronJobReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() { r.ErrorReporter.ReconcileResult(reportErrorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(reportErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	// the reports named otherwise are not ours
+	if req.Name != v1.CronJobReportName {
+		return ctrl.Result{}, nil
+	}
+
+	status, err := cronjobreport.Summarize(ctx, r, req.Namespace, r.nextRuns(req.Namespace), r.Now())
+	if err != nil {
+		logger.Error(err, "unable to summarize the CronJobs")
+		return ctrl.Result{}, err
+	}
+
+	var report v1.CronJobReport
+	err = r.Get(ctx, req.NamespacedName, &report)
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Error(err, "unable to get CronJobReport")
+		return ctrl.Result{}, err
+	}
+	found := err == nil
+	if status.CronJobs == 0 {
+		if found {
+			if err := r.Delete(ctx, &report); client.IgnoreNotFound(err) != nil {
+				logger.Error(err, "unable to delete CronJobReport")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+	if !found {
+		report = v1.CronJobReport{ObjectMeta: metav1.ObjectMeta{Namespace: req.Namespace, Name: req.Name}}
+		if err := r.Create(ctx, &report); err != nil {
+			logger.Error(err, "unable to create CronJobReport")
+			return ctrl.Result{}, err
+		}
+	}
+
+	report.Status = status
+	if err := r.Status().Update(ctx, &report); err != nil {
+		logger.Error(err, "unable to update CronJobReport status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: reportInterval}, nil
+}
+
+// nextRuns returns the next runs of the CronJobs of the namespace by name.
+func (r *CronJobReportReconciler) nextRuns(namespace string) map[string]time.Time {
+	if r.NextRuns == nil {
+		return nil
+	}
+	nextRuns := map[string]time.Time{}
+	for _, wakeup := range r.NextRuns.Wakeups() {
+		if name := strings.TrimPrefix(wakeup.CronJob, namespace+"/"); name != wakeup.CronJob {
+			nextRuns[name] = wakeup.NextRun
+		}
+	}
+	return nextRuns
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CronJobReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	reportOfNamespace := handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(),
+			Name: v1.CronJobReportName}}}
+	})
+	cronJobRuns := predicate.NewPredicateFuncs(isCronJobRun)
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.CronJobReport{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &v1.CronJob{}}, reportOfNamespace).
+		Watches(&source.Kind{Type: jobObject()}, reportOfNamespace, builder.WithPredicates(cronJobRuns)).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none.
+func (r *CronJobReportReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "cronjobreports", []string{"get", "list", "watch", "create", "delete"},
+		namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobreports/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs", []string{"get", "list", "watch"},
+		namespaces...)...)
+	return append(permissions, startup.Permissions("batch", "jobs", []string{"get", "list", "watch"},
+		namespaces...)...)
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/cronjobset"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+/*
+The CronJobSetReconciler keeps the CronJobs of the sets in line with their instances, like the ReplicaSet controller
+does with the Pods. The CronJobs are owned by the cluster-scoped set, so they are found through an index across all
+the namespaces, and deleted with the set by the garbage collector.
+
+A CronJob is only updated when the hash of its rendered template changes, so the defaults set by the webhook do not
+look like changes, and the changes made to a CronJob directly stay until the template or the parameters change. The
+CronJobs rejected by the webhook are reported per instance, they do not stop the other instances.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobsets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+const (
+	// cronJobSetOwnerKey indexes the CronJobs by the name of their CronJobSet.
+	cronJobSetOwnerKey = ".metadata.cronjobset"
+	// cronJobSetErrorReportingComponent is the component of the errors reported by the set controller.
+	cronJobSetErrorReportingComponent = "cronjobset-controller"
+)
+
+// CronJobSetReconciler creates, updates and deletes the CronJobs of the CronJobSets.
+type CronJobSetReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile syncs the CronJobs of the set with its instances and reports them in its status.
+func (r *CronJobSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() {
+		r.ErrorReporter.ReconcileResult(cronJobSetErrorReportingComponent, req.NamespacedName, err)
+	}()
+	defer r.ErrorReporter.Recover(cronJobSetErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var set v1.CronJobSet
+	if err := r.Get(ctx, req.NamespacedName, &set); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	status := v1.CronJobSetStatus{ObservedGeneration: set.Generation}
+	namespaces, err := r.selectedNamespaces(ctx, &set)
+	if err != nil {
+		logger.Error(err, "unable to select the namespaces")
+		return ctrl.Result{}, err
+	}
+	instances, err := cronjobset.Instances(&set, namespaces)
+	if err != nil {
+		// the CronJobs are left as they are until the set is fixed
+		status.Message = err.Error()
+		set.Status = status
+		return ctrl.Result{}, r.Status().Update(ctx, &set)
+	}
+
+	var childCronJobs v1.CronJobList
+	if err := r.List(ctx, &childCronJobs, client.MatchingFields{cronJobSetOwnerKey: req.Name}); err != nil {
+		logger.Error(err, "unable to list child CronJobs")
+		return ctrl.Result{}, err
+	}
+	existing := map[client.ObjectKey]*v1.CronJob{}
+	for i := range childCronJobs.Items {
+		existing[client.ObjectKeyFromObject(&childCronJobs.Items[i])] = &childCronJobs.Items[i]
+	}
+
+	var statuses []v1.CronJobSetInstanceStatus
+	for _, instance := range instances {
+		key := client.ObjectKey{Namespace: instance.Namespace, Name: instance.Name}
+		current := existing[key]
+		delete(existing, key)
+
+		instanceStatus, err := r.syncInstance(ctx, &set, instance, current)
+		if err != nil {
+			logger.Error(err, "unable to sync CronJob", "cronJob", key)
+			return ctrl.Result{}, err
+		}
+		status.Instances++
+		if instanceStatus.UpToDate {
+			status.UpToDate++
+		}
+		if instanceStatus.Message != "" {
+			status.Failed++
+		}
+		statuses = append(statuses, instanceStatus)
+	}
+
+	// the CronJobs no longer generated, e.g. of a namespace which is not selected anymore
+	for key, cronJob := range existing {
+		if err := r.Delete(ctx, cronJob); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "unable to delete CronJob", "cronJob", key)
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("deleted CronJob no longer generated", "cronJob", key)
+	}
+
+	// the failed instances are the ones worth a look, so they are listed first
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Message != "" && statuses[j].Message == "" })
+	if len(statuses) > maxReportedNamespaces {
+		statuses = statuses[:maxReportedNamespaces]
+	}
+	status.InstanceStatuses = statuses
+	set.Status = status
+	if err := r.Status().Update(ctx, &set); err != nil {
+		logger.Error(err, "unable to update CronJobSet status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// syncInstance creates the CronJob of the instance, or updates it when its template changed. The errors which a retry
+// does not fix are reported in the status of the instance, not returned.
+func (r *CronJobSetReconciler) syncInstance(ctx context.Context, set *v1.CronJobSet, instance cronjobset.Instance,
+	current *v1.CronJob) (v1.CronJobSetInstanceStatus, error) {
+	status := v1.CronJobSetInstanceStatus{Namespace: instance.Namespace, Name: instance.Name,
+		Parameters: instance.Parameters}
+	if current != nil {
+		status.Active = int32(len(current.Status.Active))
+		status.LastScheduleTime = current.Status.LastScheduleTime
+	}
+
+	desired, err := cronjobset.Render(set, instance)
+	if err != nil {
+		status.Message = err.Error()
+		return status, nil
+	}
+	if current != nil && current.Annotations[cronjobset.TemplateHashAnnotation] ==
+		desired.Annotations[cronjobset.TemplateHashAnnotation] {
+		status.UpToDate = true
+		return status, nil
+	}
+
+	if current == nil {
+		if err := ctrl.SetControllerReference(set, desired, r.Scheme); err != nil {
+			return status, err
+		}
+		err = r.Create(ctx, desired)
+		if apierrors.IsAlreadyExists(err) {
+			status.Message = fmt.Sprintf("CronJob %s/%s already exists and is not owned by the set",
+				instance.Namespace, instance.Name)
+			return status, nil
+		}
+	} else {
+		current.Labels, current.Annotations, current.Spec = desired.Labels, desired.Annotations, desired.Spec
+		err = r.Update(ctx, current)
+	}
+	if apierrors.IsInvalid(err) || apierrors.IsForbidden(err) {
+		status.Message = err.Error()
+		return status, nil
+	} else if err != nil {
+		return status, err
+	}
+	status.UpToDate = true
+	return status, nil
+}
+
+// selectedNamespaces returns the names of the namespaces matched by the namespace selector, none if unset.
+func (r *CronJobSetReconciler) selectedNamespaces(ctx context.Context, set *v1.CronJobSet) ([]string, error) {
+	if set.Spec.NamespaceSelector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(set.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespace selector: %w", err)
+	}
+
+	var namespaceList corev1.NamespaceList
+	if err := r.List(ctx, &namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	var namespaces []string
+	for _, namespace := range namespaceList.Items {
+		// a terminating namespace rejects the new objects
+		if namespace.Status.Phase != corev1.NamespaceTerminating {
+			namespaces = append(namespaces, namespace.Name)
+		}
+	}
+	return namespaces, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CronJobSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1.CronJob{}, cronJobSetOwnerKey,
+		func(rawObj client.Object) []string {
+			owner := metav1.GetControllerOf(rawObj)
+			if owner == nil || owner.APIVersion != apiGVStr || owner.Kind != "CronJobSet" {
+				return nil
+			}
+			return []string{owner.Name}
+		}); err != nil {
+		return err
+	}
+
+	// any namespace may join or leave the selection of any set
+	allSets := handler.EnqueueRequestsFromMapFunc(func(client.Object) []reconcile.Request {
+		var sets v1.CronJobSetList
+		if err := r.List(context.Background(), &sets); err != nil {
+			log.Log.Error(err, "unable to list CronJobSets")
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(sets.Items))
+		for _, set := range sets.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&set)})
+		}
+		return requests
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.CronJobSet{}).
+		Owns(&v1.CronJob{}).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, allSets).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs, in the whole cluster since the CronJobSets stamp out
+// CronJobs in any namespace.
+func (r *CronJobSetReconciler) Permissions([]string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "cronjobsets", []string{"get", "list", "watch"})
+	permissions = append(permissions, startup.Permissions(group, "cronjobsets/status", []string{"update"})...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs",
+		[]string{"get", "list", "watch", "create", "update", "delete"})...)
+	return append(permissions, startup.Permissions("", "namespaces", []string{"get", "list", "watch"})...)
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/gitsync"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+/*
+The GitSyncReconciler syncs the CronJobs of the GitSyncs. Like the CalendarReconciler, a GitSync is reconciled when
+its spec changes and on every interval, and a failed sync is retried sooner. A sync applies every CronJob of the
+directory before it deletes the ones which left it, and it neither applies nor deletes anything when the directory
+can not be rendered. A CronJob of the namespace which was not applied by the GitSync is never taken over: the sync
+fails instead, so a typo in a manifest does not overwrite a CronJob created by hand.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=gitsyncs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=gitsyncs/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+const (
+	// gitSyncSyncedCondition is false when the last sync of the GitSync failed.
+	gitSyncSyncedCondition = "Synced"
+	// gitSyncErrorReportingComponent is the component of the errors reported by the gitsync controller.
+	gitSyncErrorReportingComponent = "gitsync-controller"
+	// gitSyncFieldManager is the field manager of the CronJobs applied by the GitSyncs.
+	gitSyncFieldManager = "gitsync-controller"
+
+	defaultGitSyncInterval = 5 * time.Minute
+	// minGitSyncInterval is the shortest interval, and how soon a failed sync is retried.
+	minGitSyncInterval = time.Minute
+	// gitSyncCloneTimeout bounds the clone of a branch.
+	gitSyncCloneTimeout = time.Minute
+)
+
+// GitSyncReconciler applies the CronJobs of the GitSyncs from their repositories.
+type GitSyncReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// APIReader reads the secrets of the GitSyncs, rather than caching all the secrets of the cluster.
+	APIReader client.Reader
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile syncs the CronJobs of the GitSync when its interval passed, or when its spec changed.
+func (r *GitSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() { r.ErrorReporter.ReconcileResult(gitSyncErrorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(gitSyncErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var gitSync v1.GitSync
+	if err := r.Get(ctx, req.NamespacedName, &gitSync); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if gitSync.Spec.Suspend {
+		return ctrl.Result{}, nil
+	}
+
+	interval := gitSyncInterval(&gitSync.Spec)
+	if gitSync.Status.ObservedGeneration == gitSync.Generation && gitSync.Status.LastSyncTime != nil &&
+		meta.IsStatusConditionTrue(gitSync.Status.Conditions, gitSyncSyncedCondition) {
+		if wait := gitSync.Status.LastSyncTime.Add(interval).Sub(r.Now()); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	commit, names, err := r.sync(ctx, &gitSync)
+	gitSync.Status.ObservedGeneration = gitSync.Generation
+	if err != nil {
+		logger.Error(err, "unable to sync the GitSync", "url", gitSync.Spec.URL)
+		meta.SetStatusCondition(&gitSync.Status.Conditions, metav1.Condition{Type: gitSyncSyncedCondition,
+			Status: metav1.ConditionFalse, Reason: "SyncFailed", Message: err.Error(),
+			ObservedGeneration: gitSync.Generation})
+		if err := r.Status().Update(ctx, &gitSync); err != nil {
+			logger.Error(err, "unable to update GitSync status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: minGitSyncInterval}, nil
+	}
+
+	gitSync.Status.LastSyncedCommit = commit
+	gitSync.Status.CronJobs = names
+	gitSync.Status.LastSyncTime = &metav1.Time{Time: r.Now()}
+	meta.SetStatusCondition(&gitSync.Status.Conditions, metav1.Condition{Type: gitSyncSyncedCondition,
+		Status: metav1.ConditionTrue, Reason: "Synced",
+		Message:            fmt.Sprintf("applied %d CronJobs of the commit %s", len(names), commit),
+		ObservedGeneration: gitSync.Generation})
+	if err := r.Status().Update(ctx, &gitSync); err != nil {
+		logger.Error(err, "unable to update GitSync status")
+		return ctrl.Result{}, err
+	}
+	logger.V(1).Info("synced GitSync", "commit", commit, "cronJobs", len(names))
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// sync clones the branch of the GitSync, applies its CronJobs and deletes the ones which left it. It returns the
+// commit and the names of the CronJobs.
+func (r *GitSyncReconciler) sync(ctx context.Context, gitSync *v1.GitSync) (string, []string, error) {
+	var auth transport.AuthMethod
+	if ref := gitSync.Spec.SecretRef; ref != nil {
+		var secret corev1.Secret
+		key := client.ObjectKey{Namespace: gitSync.Namespace, Name: ref.Name}
+		if err := r.APIReader.Get(ctx, key, &secret); err != nil {
+			return "", nil, fmt.Errorf("unable to get the secret %s: %w", ref.Name, err)
+		}
+		var err error
+		if auth, err = gitsync.Auth(&secret); err != nil {
+			return "", nil, err
+		}
+	}
+
+	cloneCtx, cancel := context.WithTimeout(ctx, gitSyncCloneTimeout)
+	defer cancel()
+	checkout, err := gitsync.Clone(cloneCtx, gitSync.Spec.URL, gitSync.Spec.Branch, auth)
+	if err != nil {
+		return "", nil, err
+	}
+	cronJobs, err := gitsync.Render(checkout.Files, gitSync.Spec.Path, gitSync.Namespace, gitSync.Name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	names := make([]string, 0, len(cronJobs))
+	rendered := map[string]bool{}
+	for _, cronJob := range cronJobs {
+		if err := r.apply(ctx, gitSync, cronJob); err != nil {
+			return "", nil, err
+		}
+		names = append(names, cronJob.GetName())
+		rendered[cronJob.GetName()] = true
+	}
+
+	var applied v1.CronJobList
+	if err := r.List(ctx, &applied, client.InNamespace(gitSync.Namespace),
+		client.MatchingLabels{gitsync.ManagedByLabel: gitSync.Name}); err != nil {
+		return "", nil, err
+	}
+	for i := range applied.Items {
+		cronJob := &applied.Items[i]
+		if rendered[cronJob.Name] {
+			continue
+		}
+		if err := r.Delete(ctx, cronJob); client.IgnoreNotFound(err) != nil {
+			return "", nil, fmt.Errorf("unable to delete the CronJob %s: %w", cronJob.Name, err)
+		}
+		log.FromContext(ctx).Info("deleted CronJob which left the repository", "cronJob", cronJob.Name)
+	}
+	return checkout.Commit, names, nil
+}
+
+// apply applies the CronJob with server-side apply, unless a CronJob of the same name was not applied by the GitSync.
+func (r *GitSyncReconciler) apply(ctx context.Context, gitSync *v1.GitSync, cronJob *unstructured.Unstructured) error {
+	var existing v1.CronJob
+	err := r.Get(ctx, client.ObjectKey{Namespace: gitSync.Namespace, Name: cronJob.GetName()}, &existing)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && existing.Labels[gitsync.ManagedByLabel] != gitSync.Name {
+		return fmt.Errorf("the CronJob %s was not applied by this GitSync", cronJob.GetName())
+	}
+	if err := r.Patch(ctx, cronJob, client.Apply, client.FieldOwner(gitSyncFieldManager),
+		client.ForceOwnership); err != nil {
+		return fmt.Errorf("unable to apply the CronJob %s: %w", cronJob.GetName(), err)
+	}
+	return nil
+}
+
+// gitSyncInterval returns how often the branch is cloned.
+func gitSyncInterval(spec *v1.GitSyncSpec) time.Duration {
+	if spec.Interval == nil {
+		return defaultGitSyncInterval
+	}
+	if spec.Interval.Duration < minGitSyncInterval {
+		return minGitSyncInterval
+	}
+	return spec.Interval.Duration
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GitSyncReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.GitSync{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none, the
+// Secrets being the credentials of the repositories.
+func (r *GitSyncReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "gitsyncs", []string{"get", "list", "watch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "gitsyncs/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs",
+		[]string{"get", "list", "watch", "patch", "delete"}, namespaces...)...)
+	return append(permissions, startup.Permissions("", "secrets", []string{"get"}, namespaces...)...)
+}
@@ -0,0 +1,459 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/archive"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/cloudevents"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/pushgateway"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/runresult"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/slo"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ref "k8s.io/client-go/tools/reference"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+/*
+The JobRunReconciler keeps a JobRun for every Job of a CronJob, or object of an external runner seen as a Job. A
+JobRun is named like its Job, so the Job and the JobRun changing both reconcile the same request: the JobRun is created
+for a new Job, follows the Job until it finishes, and is deleted once its TTL passed. The JobRun outlives its Job,
+which the CronJob controller deletes per the history limits of the CronJob.
+
+With an archive configured, a finished run is also written to the object storage, with the end of the logs of its last
+Pod, before it can expire. The JobRun is annotated once archived, so it is archived only once. The results of the
+finished runs are pushed to a Pushgateway the same way, except for a run superseded by a newer finished run of its
+CronJob, whose results already replaced the ones of the run in the Pushgateway.
+
+A finished run may also report a result in the termination message of its container, like `{"result":"partial"}`
+written to /dev/termination-log, for the outcomes its phase can not tell, e.g. a success with warnings. The result of
+the last Pod is kept in the status of the JobRun and counted by CronJob.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobruns,verbs=get;list;watch;create;patch;delete
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobruns/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+//+kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+
+const (
+	// jobRunCronJobLabel is the name of the CronJob of a JobRun.
+	jobRunCronJobLabel = "batch.example.com/cronjob"
+	// archivedAnnotation is set on the JobRuns written to the archive.
+	archivedAnnotation = "batch.example.com/archived"
+	// pushedAnnotation is set on the JobRuns whose results were pushed to the Pushgateway.
+	pushedAnnotation = "batch.example.com/pushed"
+	// jobRunErrorReportingComponent is the component of the errors reported by the jobrun controller.
+	jobRunErrorReportingComponent = "jobrun-controller"
+
+	defaultJobRunTTL = 7 * 24 * time.Hour
+)
+
+// JobRunReconciler records the runs of the CronJobs in JobRuns.
+type JobRunReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// TTL is how long the JobRuns of the finished runs are kept, defaults to 7 days.
+	TTL time.Duration
+	// Archiver archives the finished runs, nothing is archived if nil.
+	Archiver *archive.Archiver
+	// Pusher pushes the results of the finished runs to a Pushgateway, nothing is pushed if nil.
+	Pusher *pushgateway.Pusher
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+	// Events emits the CloudEvents of the runs, nothing is emitted if nil.
+	Events *cloudevents.Emitter
+	// APIReader reads the Pods of the finished runs uncached, for the result in their termination message. No result
+	// is read if nil.
+	APIReader client.Reader
+}
+
+// Reconcile creates the JobRun of a Job, updates it from the Job, and deletes it once expired.
+func (r *JobRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	// a reconcile is a trace like the ones of the CronJobs, the run durations it records point to it
+	ctx, span := tracing.Tracer().Start(ctx, "Reconcile JobRun", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace), attribute.String("k8s.object.name", req.Name)))
+	defer func() { tracing.End(span, err) }()
+	defer func(start time.Time) { metrics.RecordReconcile(ctx, metrics.ControllerJobRun, start, err) }(time.Now())
+	defer func() { r.ErrorReporter.ReconcileResult(jobRunErrorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(jobRunErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		logger = logger.WithValues("traceID", traceID)
+		ctx = log.IntoContext(ctx, logger)
+	}
+
+	// the run is a Job, or the object of an external runner, named like its JobRun
+	var job *kbatch.Job
+	for _, runner := range enabledRunners() {
+		found, err := runner.GetRunStatus(ctx, r, req.NamespacedName)
+		if err == nil {
+			job = found
+			break
+		} else if !apierrors.IsNotFound(err) {
+			logger.Error(err, "unable to get Job")
+			return ctrl.Result{}, err
+		}
+	}
+
+	var run v1.JobRun
+	if err := r.Get(ctx, req.NamespacedName, &run); apierrors.IsNotFound(err) {
+		// the Jobs kept longer than the TTL do not get their JobRun back
+		if job == nil || !isCronJobRun(job) || r.expired(finishedCondition(job)) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.createJobRun(ctx, job, &run); err != nil {
+			logger.Error(err, "unable to create JobRun")
+			return ctrl.Result{}, err
+		}
+		r.Events.RunEvent(cloudevents.TypeRunScheduled, &run)
+	} else if err != nil {
+		logger.Error(err, "unable to get JobRun")
+		return ctrl.Result{}, err
+	}
+	if run.Spec.RunID != "" {
+		logger = logger.WithValues("runID", run.Spec.RunID)
+		ctx = log.IntoContext(ctx, logger)
+	}
+
+	// the run follows its Job until it finishes, a Job deleted before is lost
+	if run.Status.CompletionTime == nil {
+		status := run.Status.DeepCopy()
+		if job != nil && job.UID == run.Spec.Job.UID {
+			jobRunStatus(job, status)
+			if status.CompletionTime != nil {
+				result, err := r.result(ctx, job)
+				if err != nil {
+					logger.Error(err, "unable to read the result of the run")
+					return ctrl.Result{}, err
+				}
+				status.Result = result
+			}
+		} else {
+			status.Phase = v1.JobRunLost
+			status.CompletionTime = &metav1.Time{Time: r.Now()}
+		}
+		// the result is known once the run finished at the latest
+		var sloRecheck time.Time
+		if run.Spec.SLO != nil && status.SLOResult == "" {
+			status.SLOResult, status.SLOMessage, sloRecheck = slo.Evaluate(run.Spec.SLO, run.Spec.ScheduledTime.Time,
+				status, r.Now())
+		}
+		if !equality.Semantic.DeepEqual(status, &run.Status) {
+			previous := run.Status
+			run.Status = *status
+			if err := r.Status().Update(ctx, &run); err != nil {
+				logger.Error(err, "unable to update JobRun status")
+				return ctrl.Result{}, err
+			}
+			r.emitTransitions(ctx, &previous, &run)
+		}
+		if run.Status.CompletionTime == nil {
+			if !sloRecheck.IsZero() {
+				return ctrl.Result{RequeueAfter: sloRecheck.Sub(r.Now())}, nil
+			}
+			return ctrl.Result{}, nil
+		}
+	}
+
+	// a run failing to be archived is retried, and kept past its TTL until archived
+	if r.Archiver != nil {
+		if _, archived := run.Annotations[archivedAnnotation]; !archived {
+			if err := r.Archiver.Archive(ctx, &run); err != nil {
+				logger.Error(err, "unable to archive JobRun")
+				return ctrl.Result{}, err
+			}
+			if err := r.annotate(ctx, &run, archivedAnnotation); err != nil {
+				logger.Error(err, "unable to annotate archived JobRun")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+	if r.Pusher != nil {
+		if _, pushed := run.Annotations[pushedAnnotation]; !pushed {
+			superseded, err := r.superseded(ctx, &run)
+			if err != nil {
+				logger.Error(err, "unable to list the JobRuns of the CronJob")
+				return ctrl.Result{}, err
+			}
+			if !superseded {
+				if err := r.Pusher.Push(ctx, &run); err != nil {
+					logger.Error(err, "unable to push JobRun results")
+					return ctrl.Result{}, err
+				}
+			}
+			if err := r.annotate(ctx, &run, pushedAnnotation); err != nil {
+				logger.Error(err, "unable to annotate pushed JobRun")
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	expiry := run.Status.CompletionTime.Add(r.ttl())
+	if wait := expiry.Sub(r.Now()); wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+	if err := r.Delete(ctx, &run); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "unable to delete expired JobRun")
+		return ctrl.Result{}, err
+	}
+	logger.V(1).Info("deleted expired JobRun")
+	return ctrl.Result{}, nil
+}
+
+// annotate sets the annotation on the run, with the current time.
+func (r *JobRunReconciler) annotate(ctx context.Context, run *v1.JobRun, annotation string) error {
+	patch := client.MergeFrom(run.DeepCopy())
+	metav1.SetMetaDataAnnotation(&run.ObjectMeta, annotation, r.Now().UTC().Format(time.RFC3339))
+	return r.Patch(ctx, run, patch)
+}
+
+// superseded returns whether another run of the CronJob of the finished run finished after it.
+func (r *JobRunReconciler) superseded(ctx context.Context, run *v1.JobRun) (bool, error) {
+	var runs v1.JobRunList
+	if err := r.List(ctx, &runs, client.InNamespace(run.Namespace),
+		client.MatchingLabels{jobRunCronJobLabel: run.Spec.CronJob}); err != nil {
+		return false, err
+	}
+	for _, other := range runs.Items {
+		if other.Name != run.Name && other.Status.CompletionTime != nil &&
+			other.Status.CompletionTime.After(run.Status.CompletionTime.Time) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// emitTransitions emits the events and records the metrics of the run started or finished since the previous status.
+// The status updates conflict when two reconciles race, so a transition is emitted by one of them only.
+func (r *JobRunReconciler) emitTransitions(ctx context.Context, previous *v1.JobRunStatus, run *v1.JobRun) {
+	if previous.StartTime == nil && run.Status.StartTime != nil {
+		r.Events.RunEvent(cloudevents.TypeRunStarted, run)
+		// the manual runs start when triggered, the backfilled ones long after their scheduled time
+		if run.Spec.Trigger == v1.ScheduledTrigger {
+			metrics.RecordRunStarted(run.Namespace, run.Spec.CronJob,
+				run.Status.StartTime.Sub(run.Spec.ScheduledTime.Time))
+		}
+	}
+	if previous.SLOResult == "" && run.Status.SLOResult != "" {
+		result := metrics.SLOBreached
+		if run.Status.SLOResult == v1.SLOMet {
+			result = metrics.SLOMet
+		}
+		metrics.RecordSLOResult(run.Namespace, run.Spec.CronJob, result)
+	}
+	if previous.CompletionTime == nil && run.Status.CompletionTime != nil {
+		var duration *time.Duration
+		if run.Status.Duration != nil {
+			duration = &run.Status.Duration.Duration
+		}
+		metrics.RecordRunFinished(ctx, run.Namespace, run.Spec.CronJob, run.Status.Phase == v1.JobRunSucceeded,
+			duration)
+		if run.Status.Result != "" {
+			metrics.RecordRunResult(run.Namespace, run.Spec.CronJob, run.Status.Result)
+		}
+		if run.Status.Phase == v1.JobRunSucceeded {
+			r.Events.RunEvent(cloudevents.TypeRunSucceeded, run)
+		} else {
+			r.Events.RunEvent(cloudevents.TypeRunFailed, run)
+		}
+	}
+}
+
+// result returns the result reported in the termination message of the last Pod of the finished Job, if any. The
+// Pods are only read once per run, so they are listed uncached instead of all being watched.
+func (r *JobRunReconciler) result(ctx context.Context, job *kbatch.Job) (string, error) {
+	if r.APIReader == nil {
+		return "", nil
+	}
+	var pods corev1.PodList
+	if err := r.APIReader.List(ctx, &pods, client.InNamespace(job.Namespace),
+		client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", err
+	}
+	return runresult.FromPods(pods.Items), nil
+}
+
+// createJobRun creates the JobRun of the Job of a CronJob.
+func (r *JobRunReconciler) createJobRun(ctx context.Context, job *kbatch.Job, run *v1.JobRun) error {
+	cronJobName := jobCronJob(job)
+	jobRef, err := ref.GetReference(r.Scheme, job)
+	if err != nil {
+		return err
+	}
+	*run = v1.JobRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: job.Namespace,
+			Name:      job.Name,
+			Labels:    map[string]string{jobRunCronJobLabel: cronJobName},
+		},
+		Spec: v1.JobRunSpec{
+			CronJob: cronJobName,
+			Trigger: v1.ScheduledTrigger,
+			Job:     *jobRef,
+		},
+	}
+	if trigger, ok := job.Annotations[triggerAnnotation]; ok {
+		run.Spec.Trigger = v1.JobRunTrigger(trigger)
+	}
+	run.Spec.TriggeredBy = job.Annotations[triggeredByAnnotation]
+	run.Spec.RunID = job.Labels[runIDLabel]
+	if scheduledTime, err := time.Parse(time.RFC3339, job.Annotations[scheduledTimeAnnotation]); err == nil {
+		run.Spec.ScheduledTime = metav1.Time{Time: scheduledTime}
+	} else {
+		run.Spec.ScheduledTime = job.CreationTimestamp
+	}
+
+	var cronJob v1.CronJob
+	if err := r.Get(ctx, client.ObjectKey{Namespace: job.Namespace, Name: cronJobName}, &cronJob); err != nil {
+		return err
+	}
+	if cronJob.Spec.SLO != nil && run.Spec.Trigger == v1.ScheduledTrigger {
+		run.Spec.SLO = cronJob.Spec.SLO.DeepCopy()
+	}
+	if err := controllerutil.SetControllerReference(&cronJob, run, r.Scheme); err != nil {
+		return err
+	}
+	return r.Create(ctx, run)
+}
+
+// jobRunStatus sets the status of the run from its Job.
+func jobRunStatus(job *kbatch.Job, status *v1.JobRunStatus) {
+	status.Phase = v1.JobRunRunning
+	status.StartTime = job.Status.StartTime
+	condition := finishedCondition(job)
+	if condition == nil {
+		return
+	}
+
+	status.CompletionTime = &condition.LastTransitionTime
+	if condition.Type == kbatch.JobComplete {
+		status.Phase = v1.JobRunSucceeded
+		if job.Status.CompletionTime != nil {
+			status.CompletionTime = job.Status.CompletionTime
+		}
+	} else {
+		status.Phase = v1.JobRunFailed
+		status.FailureReason = condition.Reason
+		status.FailureMessage = condition.Message
+	}
+	if status.StartTime != nil {
+		status.Duration = &metav1.Duration{Duration: status.CompletionTime.Sub(status.StartTime.Time)}
+	}
+}
+
+// isCronJobRun returns whether the Job is a run of a CronJob, scheduled or backfilled.
+func isCronJobRun(job client.Object) bool {
+	return jobCronJob(job) != ""
+}
+
+// jobCronJob returns the name of the CronJob of the run, the Jobs of the Backfills name it in a label.
+func jobCronJob(job client.Object) string {
+	owner := metav1.GetControllerOf(job)
+	if owner == nil || owner.APIVersion != apiGVStr {
+		return ""
+	}
+	switch owner.Kind {
+	case "CronJob":
+		return owner.Name
+	case "Backfill":
+		return job.GetLabels()[jobRunCronJobLabel]
+	}
+	return ""
+}
+
+// expired returns whether the Job finished longer than the TTL ago.
+func (r *JobRunReconciler) expired(finished *kbatch.JobCondition) bool {
+	return finished != nil && r.Now().Sub(finished.LastTransitionTime.Time) > r.ttl()
+}
+
+// ttl returns how long the JobRuns of the finished runs are kept.
+func (r *JobRunReconciler) ttl() time.Duration {
+	if r.TTL <= 0 {
+		return defaultJobRunTTL
+	}
+	return r.TTL
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JobRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	cronJobRuns := predicate.NewPredicateFuncs(isCronJobRun)
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		For(&v1.JobRun{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: jobObject()}, &handler.EnqueueRequestForObject{},
+			builder.WithPredicates(cronJobRuns))
+	// the objects of the external runners are only created by the CronJobs
+	ownedByCronJob := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		owner := metav1.GetControllerOf(obj)
+		return owner != nil && owner.APIVersion == apiGVStr && owner.Kind == "CronJob"
+	})
+	for _, runner := range enabledExternalRunners() {
+		controllerBuilder = controllerBuilder.Watches(&source.Kind{Type: runner.Object()},
+			&handler.EnqueueRequestForObject{}, builder.WithPredicates(ownedByCronJob))
+	}
+	return controllerBuilder.Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none, including
+// the ones of the runners of the feature gates. The archive and the Pushgateway have their own.
+func (r *JobRunReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "jobruns", []string{"get", "list", "watch", "create", "delete"},
+		namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "jobruns/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs", []string{"get", "list", "watch"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions("batch", "jobs", []string{"get", "list", "watch"},
+		namespaces...)...)
+	if featuregates.Enabled(featuregates.ArgoWorkflowRunner) {
+		permissions = append(permissions, startup.Permissions("argoproj.io", "workflows",
+			[]string{"get", "list", "watch"}, namespaces...)...)
+	}
+	if featuregates.Enabled(featuregates.TektonPipelineRunner) {
+		permissions = append(permissions, startup.Permissions("tekton.dev", "pipelineruns",
+			[]string{"get", "list", "watch"}, namespaces...)...)
+	}
+	return permissions
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+	kbatch "k8s.io/api/batch/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	validationutils "k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+/*
+The JobTemplate controller rolls the changes of a JobTemplate out to the CronJobs referencing it. The defaulting
+webhook copies the template into a CronJob when the reference is set, the controller copies every later generation
+and records it in the JobTemplateRevisionAnnotation of the CronJob. With canaryRuns and the JobTemplateCanaryRuns
+feature gate, each CronJob which got a new generation also runs it right away in a canary Job, so a broken template
+shows up when it is changed rather than at the next scheduled run.
+
+The status of the JobTemplate lists the consuming CronJobs with the generation they run and their canary Job.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobtemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobtemplates/status,verbs=get;update;patch
+
+// jobTemplateRefKey indexes the CronJobs on the name of the JobTemplate they reference.
+const jobTemplateRefKey = ".spec.jobTemplateRef.name"
+
+// JobTemplateReconciler reconciles a JobTemplate object
+type JobTemplateReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// Reconcile syncs the consuming CronJobs with the JobTemplate and reports them in its status.
+func (r *JobTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var template v1.JobTemplate
+	if err := r.Get(ctx, req.NamespacedName, &template); err != nil {
+		// the CronJobs keep the last copy of a deleted JobTemplate
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var cronJobs v1.CronJobList
+	if err := r.List(ctx, &cronJobs, client.InNamespace(req.Namespace),
+		client.MatchingFields{jobTemplateRefKey: req.Name}); err != nil {
+		logger.Error(err, "unable to list the consuming CronJobs")
+		return ctrl.Result{}, err
+	}
+	sort.Slice(cronJobs.Items, func(i, j int) bool { return cronJobs.Items[i].Name < cronJobs.Items[j].Name })
+
+	previous := map[string]v1.JobTemplateConsumer{}
+	for _, consumer := range template.Status.Consumers {
+		previous[consumer.Name] = consumer
+	}
+	canaryRuns := template.Spec.CanaryRuns && featuregates.Enabled(featuregates.JobTemplateCanaryRuns)
+	revision := v1.JobTemplateRevision(template.Name, template.Generation)
+
+	consumers := make([]v1.JobTemplateConsumer, 0, len(cronJobs.Items))
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		// the index may lag behind a CronJob which just changed its reference
+		if ref := cronJob.Spec.JobTemplateRef; ref == nil || ref.Name != template.Name {
+			continue
+		}
+		consumer := v1.JobTemplateConsumer{Name: cronJob.Name}
+		name, generation, ok := v1.ParseJobTemplateRevision(cronJob.Annotations[v1.JobTemplateRevisionAnnotation])
+		changed := ok && name == template.Name
+		if changed && generation == template.Generation {
+			consumer.SyncedGeneration = generation
+			if last, ok := previous[cronJob.Name]; ok && last.SyncedGeneration == generation {
+				consumer.CanaryJob = last.CanaryJob
+			}
+			consumers = append(consumers, consumer)
+			continue
+		}
+
+		cronJob.Spec.JobTemplate = *template.Spec.Template.DeepCopy()
+		if cronJob.Annotations == nil {
+			cronJob.Annotations = map[string]string{}
+		}
+		cronJob.Annotations[v1.JobTemplateRevisionAnnotation] = revision
+		if err := r.Update(ctx, cronJob); err != nil {
+			logger.Error(err, "unable to sync the CronJob with the JobTemplate", "cronJob", cronJob.Name)
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("synced the CronJob with the JobTemplate", "cronJob", cronJob.Name, "revision", revision)
+		consumer.SyncedGeneration = template.Generation
+
+		// The first copy of the template is not a change of it, the CronJob did not run another generation yet.
+		if canaryRuns && changed && !isSuspended(cronJob) {
+			job, err := r.startCanaryRun(ctx, cronJob, revision)
+			if err != nil {
+				logger.Error(err, "unable to start the canary run", "cronJob", cronJob.Name)
+				return ctrl.Result{}, err
+			}
+			logger.Info("started the canary run of the JobTemplate", "cronJob", cronJob.Name, "job", job.Name)
+			consumer.CanaryJob = job.Name
+		}
+		consumers = append(consumers, consumer)
+	}
+
+	status := v1.JobTemplateStatus{ObservedGeneration: template.Generation, Consumers: consumers}
+	if apiequality.Semantic.DeepEqual(status, template.Status) {
+		return ctrl.Result{}, nil
+	}
+	template.Status = status
+	if err := r.Status().Update(ctx, &template); err != nil {
+		logger.Error(err, "unable to update JobTemplate status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// startCanaryRun creates a Job from the synced jobTemplate of the CronJob. The Job is owned by the CronJob like its
+// scheduled runs, but has no scheduled time. Its name is deterministic, so the canary run of a revision is only
+// started once.
+func (r *JobTemplateReconciler) startCanaryRun(ctx context.Context, cronJob *v1.CronJob,
+	revision string) (*kbatch.Job, error) {
+	_, generation, _ := v1.ParseJobTemplateRevision(revision)
+	suffix := fmt.Sprintf("-canary-%d", generation)
+	prefix := cronJob.Name
+	if max := validationutils.DNS1123LabelMaxLength - len(suffix); len(prefix) > max {
+		prefix = prefix[:max]
+	}
+
+	job := &kbatch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+			Name:        prefix + suffix,
+			Namespace:   cronJob.Namespace,
+		},
+		Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		job.Annotations[k] = v
+	}
+	job.Annotations[v1.CanaryRunAnnotation] = revision
+	job.Annotations[managedByVersionAnnotation] = version.Get().Version
+	for k, v := range cronJob.Spec.JobTemplate.Labels {
+		job.Labels[k] = v
+	}
+	if err := ctrl.SetControllerReference(cronJob, job, r.Scheme); err != nil {
+		return nil, err
+	}
+
+	if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	return job, nil
+}
+
+// isSuspended returns whether the CronJob is suspended.
+func isSuspended(cronJob *v1.CronJob) bool {
+	return cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+}
+
+// jobTemplateResolved returns whether the jobTemplate of the CronJob holds the JobTemplate it references, if any.
+func jobTemplateResolved(cronJob *v1.CronJob) bool {
+	if cronJob.Spec.JobTemplateRef == nil {
+		return true
+	}
+	name, _, ok := v1.ParseJobTemplateRevision(cronJob.Annotations[v1.JobTemplateRevisionAnnotation])
+	return ok && name == cronJob.Spec.JobTemplateRef.Name
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JobTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1.CronJob{}, jobTemplateRefKey,
+		func(rawObj client.Object) []string {
+			cronJob := rawObj.(*v1.CronJob)
+			if cronJob.Spec.JobTemplateRef == nil {
+				return nil
+			}
+			return []string{cronJob.Spec.JobTemplateRef.Name}
+		}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.JobTemplate{}).
+		Watches(&source.Kind{Type: &v1.CronJob{}}, handler.EnqueueRequestsFromMapFunc(jobTemplatesOfCronJob)).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none: the
+// consuming CronJobs are updated, and the canary Jobs created.
+func (r *JobTemplateReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "jobtemplates", []string{"get", "list", "watch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "jobtemplates/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs", []string{"get", "list", "watch", "update"},
+		namespaces...)...)
+	return append(permissions, startup.Permissions("batch", "jobs", []string{"create"}, namespaces...)...)
+}
+
+// jobTemplatesOfCronJob returns the JobTemplate the CronJob references, and the one it was last synced from if it
+// changed its reference, so both keep an accurate list of consumers.
+func jobTemplatesOfCronJob(obj client.Object) []reconcile.Request {
+	cronJob := obj.(*v1.CronJob)
+	var requests []reconcile.Request
+	if ref := cronJob.Spec.JobTemplateRef; ref != nil {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: cronJob.Namespace, Name: ref.Name}})
+	}
+	if name, _, ok := v1.ParseJobTemplateRevision(cronJob.Annotations[v1.JobTemplateRevisionAnnotation]); ok &&
+		(cronJob.Spec.JobTemplateRef == nil || name != cronJob.Spec.JobTemplateRef.Name) {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: cronJob.Namespace, Name: name}})
+	}
+	return requests
+}
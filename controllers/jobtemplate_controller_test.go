@@ -0,0 +1,149 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	v1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kbatch "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("JobTemplate controller", func() {
+	var (
+		ctx        context.Context
+		c          client.Client
+		reconciler *JobTemplateReconciler
+		template   *v1.JobTemplate
+	)
+
+	// jobTemplate returns a Job template running the image.
+	jobTemplate := func(image string) batchv1beta1.JobTemplateSpec {
+		return batchv1beta1.JobTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "data"}},
+			Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "etl", Image: image}},
+			}}},
+		}
+	}
+
+	// consumer returns a CronJob referencing the template, synced from the given revision if not empty.
+	consumer := func(name, revision string) *v1.CronJob {
+		cronJob := &v1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, UID: types.UID(name + "-uid")},
+			Spec: v1.CronJobSpec{
+				Schedule:       "0 2 * * *",
+				JobTemplateRef: &corev1.LocalObjectReference{Name: "etl"},
+			},
+		}
+		if revision != "" {
+			cronJob.Annotations = map[string]string{v1.JobTemplateRevisionAnnotation: revision}
+			cronJob.Spec.JobTemplate = jobTemplate("etl:1")
+		}
+		return cronJob
+	}
+
+	reconcile := func() *v1.JobTemplate {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{
+			Namespace: "default", Name: "etl"}})
+		Expect(err).NotTo(HaveOccurred())
+		var updated v1.JobTemplate
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "etl"}, &updated)).To(Succeed())
+		return &updated
+	}
+
+	getCronJob := func(name string) *v1.CronJob {
+		var cronJob v1.CronJob
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &cronJob)).To(Succeed())
+		return &cronJob
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(v1.AddToScheme(s)).To(Succeed())
+		template = &v1.JobTemplate{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "etl", Generation: 2},
+			Spec:       v1.JobTemplateSpec{Template: jobTemplate("etl:2"), CanaryRuns: true},
+		}
+		other := consumer("other", "")
+		other.Spec.JobTemplateRef.Name = "other"
+		c = fake.NewClientBuilder().WithScheme(s).
+			WithObjects(template, consumer("nightly", "etl/1"), consumer("hourly", ""), other).
+			Build()
+		reconciler = &JobTemplateReconciler{Client: c, Scheme: s}
+	})
+
+	It("Should copy the template into the consuming CronJobs and list them in the status", func() {
+		updated := reconcile()
+		Expect(updated.Status.ObservedGeneration).To(Equal(int64(2)))
+		Expect(updated.Status.Consumers).To(Equal([]v1.JobTemplateConsumer{
+			{Name: "hourly", SyncedGeneration: 2},
+			{Name: "nightly", SyncedGeneration: 2},
+		}))
+
+		for _, name := range []string{"hourly", "nightly"} {
+			cronJob := getCronJob(name)
+			Expect(cronJob.Annotations).To(HaveKeyWithValue(v1.JobTemplateRevisionAnnotation, "etl/2"))
+			Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image).To(Equal("etl:2"))
+			Expect(jobTemplateResolved(cronJob)).To(BeTrue())
+		}
+		Expect(jobTemplateResolved(getCronJob("other"))).To(BeFalse())
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs)).To(Succeed())
+		Expect(jobs.Items).To(BeEmpty(), "no canary run without the feature gate")
+	})
+
+	It("Should start a canary run for the changed template only with the feature gate", func() {
+		Expect(featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.JobTemplateCanaryRuns): true})).
+			To(Succeed())
+		defer func() {
+			Expect(featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.JobTemplateCanaryRuns): false})).
+				To(Succeed())
+		}()
+
+		updated := reconcile()
+		Expect(updated.Status.Consumers).To(Equal([]v1.JobTemplateConsumer{
+			{Name: "hourly", SyncedGeneration: 2},
+			{Name: "nightly", SyncedGeneration: 2, CanaryJob: "nightly-canary-2"},
+		}))
+
+		var job kbatch.Job
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nightly-canary-2"}, &job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue(v1.CanaryRunAnnotation, "etl/2"))
+		Expect(job.Labels).To(HaveKeyWithValue("team", "data"))
+		Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("etl:2"))
+		Expect(metav1.GetControllerOf(&job).Name).To(Equal("nightly"))
+
+		By("keeping the canary Job of the synced generation on the next reconcile")
+		Expect(reconcile().Status.Consumers).To(Equal(updated.Status.Consumers))
+	})
+})
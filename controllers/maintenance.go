@@ -0,0 +1,160 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/maintenance"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+The MaintenanceWindows of the namespace of a CronJob, and the ClusterMaintenanceWindows selecting its namespace, are
+read from the cache on every reconcile, like the ScheduleOverrides. The runs scheduled while a window was open are
+not caught up: the activations are looked for from the last time a window closed at the earliest.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=maintenancewindows,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=clustermaintenancewindows,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// maintenanceWindows returns the windows applying to the CronJob. The windows with an invalid selector, schedule or
+// time zone are skipped.
+func (r *CronJobReconciler) maintenanceWindows(ctx context.Context, cronJob *v1.CronJob) ([]maintenance.Named,
+	error) {
+	logger := log.FromContext(ctx)
+	var namespaced v1.MaintenanceWindowList
+	if err := r.List(ctx, &namespaced, client.InNamespace(cronJob.Namespace)); err != nil {
+		return nil, err
+	}
+	var clusterWide v1.ClusterMaintenanceWindowList
+	if err := r.List(ctx, &clusterWide); err != nil {
+		return nil, err
+	}
+	return maintenance.Select(cronJob, namespaced.Items, clusterWide.Items, func() (*corev1.Namespace, error) {
+		namespace := &corev1.Namespace{}
+		return namespace, r.Get(ctx, client.ObjectKey{Name: cronJob.Namespace}, namespace)
+	}, func(kind, name string, err error) {
+		logger.Error(err, "invalid "+kind, "maintenanceWindow", name)
+	})
+}
+
+// openMaintenanceWindow returns a window open at the given time, nil if none. A window draining the active Jobs wins.
+func openMaintenanceWindow(windows []maintenance.Named, t time.Time) *maintenance.Named {
+	var open *maintenance.Named
+	for i := range windows {
+		if ok, _ := windows[i].Window.Open(t); ok && (open == nil || !open.Drain && windows[i].Drain) {
+			open = &windows[i]
+		}
+	}
+	return open
+}
+
+// nextMaintenanceWindowChange returns the next time a window opens or closes, zero if none.
+func nextMaintenanceWindowChange(windows []maintenance.Named, now time.Time) time.Time {
+	var next time.Time
+	for _, w := range windows {
+		if _, t := w.Window.Open(now); !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	return next
+}
+
+// lastMaintenanceWindowClose returns the last time a window closed after since, zero if none.
+func lastMaintenanceWindowClose(windows []maintenance.Named, since, now time.Time) time.Time {
+	var last time.Time
+	for _, w := range windows {
+		if t := w.Window.LastClose(since, now); t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// cronJobsOfMaintenanceWindow returns the requests of the CronJobs selected by the MaintenanceWindow.
+func (r *CronJobReconciler) cronJobsOfMaintenanceWindow(obj client.Object) []reconcile.Request {
+	window, ok := obj.(*v1.MaintenanceWindow)
+	if !ok {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&window.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	return r.selectedCronJobs(window.Namespace, selector, nil)
+}
+
+// cronJobsOfClusterMaintenanceWindow returns the requests of the CronJobs selected by the ClusterMaintenanceWindow.
+func (r *CronJobReconciler) cronJobsOfClusterMaintenanceWindow(obj client.Object) []reconcile.Request {
+	window, ok := obj.(*v1.ClusterMaintenanceWindow)
+	if !ok {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&window.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	if window.Spec.NamespaceSelector == nil {
+		return r.selectedCronJobs(metav1.NamespaceAll, selector, nil)
+	}
+
+	namespaceSelector, err := metav1.LabelSelectorAsSelector(window.Spec.NamespaceSelector)
+	if err != nil {
+		return nil
+	}
+	var namespaces corev1.NamespaceList
+	if err := r.List(context.Background(), &namespaces,
+		client.MatchingLabelsSelector{Selector: namespaceSelector}); err != nil {
+		log.Log.Error(err, "unable to list namespaces")
+		return nil
+	}
+	names := sets.NewString()
+	for _, namespace := range namespaces.Items {
+		names.Insert(namespace.Name)
+	}
+	return r.selectedCronJobs(metav1.NamespaceAll, selector, names)
+}
+
+// selectedCronJobs returns the requests of the CronJobs of the namespace matching the selector, only the ones of the
+// given namespaces if not nil.
+func (r *CronJobReconciler) selectedCronJobs(namespace string, selector labels.Selector,
+	namespaces sets.String) []reconcile.Request {
+	var cronJobs v1.CronJobList
+	if err := r.List(context.Background(), &cronJobs, client.InNamespace(namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		log.Log.Error(err, "unable to list CronJobs", "namespace", namespace)
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(cronJobs.Items))
+	for _, cronJob := range cronJobs.Items {
+		if namespaces != nil && !namespaces.Has(cronJob.Namespace) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cronJob)})
+	}
+	return requests
+}
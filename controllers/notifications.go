@@ -0,0 +1,179 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/notification"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+/*
+The channels of a CronJob are told about a finished Job once: the Job is annotated once its notifications were
+delivered, so neither the next reconciles nor a new leader send them again. The notifications still queued when the
+manager stops are not lost, the Job is not annotated yet and the next leader queues them again. The Jobs which
+finished long ago, e.g. when the notifications of an old CronJob are set up, are not reported.
+
+The succeeded Jobs are also sent to the channels told only about the failures and the missed runs, to resolve their
+alerts. The channels which do not resolve alerts drop them.
+*/
+
+const (
+	// notifiedAnnotation is set on the finished Jobs whose notifications were delivered.
+	notifiedAnnotation = "batch.example.com/notified"
+	// notificationMaxAge is how long after it finished a Job is still reported.
+	notificationMaxAge = time.Hour
+)
+
+// notifyFinishedJobs queues the notifications of the finished Jobs of the CronJob for its channels.
+func (r *CronJobReconciler) notifyFinishedJobs(cronJob *v1.CronJob, event v1.NotificationEvent, jobs []*kbatch.Job) {
+	if r.Notifier == nil {
+		return
+	}
+	channels := notifiedChannels(cronJob, event)
+	var resolveChannels []string
+	if event == v1.JobSucceededEvent {
+		resolveChannels = notifiedChannels(cronJob, v1.JobFailedEvent, v1.MissedDeadlineEvent)
+	}
+	if len(channels) == 0 && len(resolveChannels) == 0 {
+		return
+	}
+
+	for _, job := range jobs {
+		if _, ok := job.Annotations[notifiedAnnotation]; ok {
+			continue
+		}
+		condition := finishedCondition(job)
+		if condition == nil || r.Now().Sub(condition.LastTransitionTime.Time) > notificationMaxAge {
+			continue
+		}
+
+		message := notification.Message{
+			Event:   event,
+			CronJob: client.ObjectKeyFromObject(cronJob),
+			Job:     job.Name,
+			RunID:   job.Labels[runIDLabel],
+			Time:    condition.LastTransitionTime.Time,
+		}
+		if event == v1.JobFailedEvent {
+			message.Reason = condition.Message
+		}
+		var notifications []notification.Notification
+		for _, channel := range channels {
+			notifications = append(notifications, notification.Notification{
+				Channel: types.NamespacedName{Namespace: cronJob.Namespace, Name: channel},
+				Message: message,
+			})
+		}
+		resolve := message
+		resolve.ResolveOnly = true
+		for _, channel := range resolveChannels {
+			if !containsString(channels, channel) {
+				notifications = append(notifications, notification.Notification{
+					Channel: types.NamespacedName{Namespace: cronJob.Namespace, Name: channel},
+					Message: resolve,
+				})
+			}
+		}
+		job := job.DeepCopy()
+		r.Notifier.DeliverAll(client.ObjectKeyFromObject(job).String(), notifications, func(ctx context.Context) error {
+			return r.markNotified(ctx, job)
+		})
+	}
+}
+
+// markNotified annotates the Job whose notifications were delivered.
+func (r *CronJobReconciler) markNotified(ctx context.Context, job *kbatch.Job) error {
+	patch := client.MergeFrom(job.DeepCopy())
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	job.Annotations[notifiedAnnotation] = "true"
+	if err := runnerOfRun(job).PatchRun(ctx, r.Client, job, patch); client.IgnoreNotFound(err) != nil {
+		log.FromContext(ctx).Error(err, "unable to mark job notified", "job", job)
+		return err
+	}
+	return nil
+}
+
+// notifyMissedRun queues the notifications of a scheduled run of the CronJob which missed its starting deadline.
+func (r *CronJobReconciler) notifyMissedRun(cronJob *v1.CronJob, scheduledTime time.Time) {
+	if r.Notifier == nil {
+		return
+	}
+	message := notification.Message{
+		Event:   v1.MissedDeadlineEvent,
+		CronJob: client.ObjectKeyFromObject(cronJob),
+		Time:    scheduledTime,
+	}
+	for _, channel := range notifiedChannels(cronJob, v1.MissedDeadlineEvent) {
+		r.Notifier.Deliver(types.NamespacedName{Namespace: cronJob.Namespace, Name: channel}, message)
+	}
+}
+
+// notifiedChannels returns the channels of the CronJob told about any of the events.
+func notifiedChannels(cronJob *v1.CronJob, events ...v1.NotificationEvent) []string {
+	var channels []string
+	for _, n := range cronJob.Spec.Notifications {
+		notified := n.Events
+		if len(notified) == 0 {
+			notified = []v1.NotificationEvent{v1.JobFailedEvent}
+		}
+		for _, event := range events {
+			if containsEvent(notified, event) {
+				channels = append(channels, n.Channel)
+				break
+			}
+		}
+	}
+	return channels
+}
+
+func containsEvent(list []v1.NotificationEvent, event v1.NotificationEvent) bool {
+	for _, e := range list {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// finishedCondition returns the Complete or Failed condition of the Job, nil if it did not finish.
+func finishedCondition(job *kbatch.Job) *kbatch.JobCondition {
+	for i, c := range job.Status.Conditions {
+		if (c.Type == kbatch.JobComplete || c.Type == kbatch.JobFailed) && c.Status == corev1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+The ScheduleOverrides of a namespace are read from the cache on every reconcile of its CronJobs. The activations are
+looked for from the last time an override started or expired at the earliest, so the runs missed while the CronJob
+was suspended, or the runs of the schedule which no longer applies, are not caught up.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=scheduleoverrides,verbs=get;list;watch
+
+// scheduleOverrides returns the ScheduleOverrides selecting the CronJob, sorted by name. The overrides with an
+// invalid selector are skipped.
+func (r *CronJobReconciler) scheduleOverrides(ctx context.Context, cronJob *v1.CronJob) ([]v1.ScheduleOverride,
+	error) {
+	var overrides v1.ScheduleOverrideList
+	if err := r.List(ctx, &overrides, client.InNamespace(cronJob.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var selected []v1.ScheduleOverride
+	for _, override := range overrides.Items {
+		selector, err := metav1.LabelSelectorAsSelector(&override.Spec.Selector)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "invalid selector of ScheduleOverride", "scheduleOverride", override.Name)
+			continue
+		}
+		if selector.Matches(labels.Set(cronJob.Labels)) {
+			selected = append(selected, override)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Name < selected[j].Name })
+	return selected, nil
+}
+
+// activeOverride returns the override applying at the given time, nil if none. A suspension wins over a schedule.
+func activeOverride(overrides []v1.ScheduleOverride, t time.Time) *v1.ScheduleOverride {
+	var active *v1.ScheduleOverride
+	for i := range overrides {
+		override := &overrides[i]
+		if override.Spec.StartsAt != nil && t.Before(override.Spec.StartsAt.Time) ||
+			!t.Before(override.Spec.ExpiresAt.Time) {
+			continue
+		}
+		if override.Spec.Suspend {
+			return override
+		}
+		if active == nil && override.Spec.Schedule != nil {
+			active = override
+		}
+	}
+	return active
+}
+
+// nextOverrideChange returns the next time an override starts or expires, zero if none.
+func nextOverrideChange(overrides []v1.ScheduleOverride, now time.Time) time.Time {
+	var next time.Time
+	consider := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	for _, override := range overrides {
+		if override.Spec.StartsAt != nil {
+			consider(override.Spec.StartsAt.Time)
+		}
+		consider(override.Spec.ExpiresAt.Time)
+	}
+	return next
+}
+
+// lastOverrideChange returns the last time an override started or expired, zero if none.
+func lastOverrideChange(overrides []v1.ScheduleOverride, now time.Time) time.Time {
+	var last time.Time
+	consider := func(t time.Time) {
+		if !t.After(now) && t.After(last) {
+			last = t
+		}
+	}
+	for _, override := range overrides {
+		if override.Spec.StartsAt != nil {
+			consider(override.Spec.StartsAt.Time)
+		}
+		consider(override.Spec.ExpiresAt.Time)
+	}
+	return last
+}
+
+// lastScheduleOrCreation returns the time the activations of the CronJob are looked for from.
+func lastScheduleOrCreation(cronJob *v1.CronJob) time.Time {
+	if cronJob.Status.LastScheduleTime != nil {
+		return cronJob.Status.LastScheduleTime.Time
+	}
+	return cronJob.CreationTimestamp.Time
+}
+
+// cronJobsOfOverride returns the requests of the CronJobs selected by the ScheduleOverride.
+func (r *CronJobReconciler) cronJobsOfOverride(obj client.Object) []reconcile.Request {
+	override, ok := obj.(*v1.ScheduleOverride)
+	if !ok {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&override.Spec.Selector)
+	if err != nil {
+		return nil
+	}
+	return r.selectedCronJobs(override.Namespace, selector, nil)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/quota"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+The CronJobQuotas are enforced by the CronJob controller before it starts a run, with the usage counted from the cache.
+A run denied by a quota is not skipped for good: the CronJob is reconciled again a minute later, and the run starts
+then if the quota allows it and it is still within the starting deadline. The Jobs a Replace concurrency policy is
+about to delete do not count as active.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobruns,verbs=get;list;watch
+
+// quotaRetryInterval is how often a run denied by a quota is retried, and how often the usage is counted.
+const quotaRetryInterval = time.Minute
+
+// quotaDenied returns why a CronJobQuota of the namespace does not allow the CronJob one more run, or an empty string
+// if they all do. replaced is the number of active Jobs deleted before the run starts.
+func (r *CronJobReconciler) quotaDenied(ctx context.Context, cronJob *v1.CronJob, replaced int) (string, error) {
+	return runQuotaDenied(ctx, r, cronJob, replaced, r.Now())
+}
+
+// runQuotaDenied is quotaDenied for the callers without a CronJobReconciler, like the manual runs.
+func runQuotaDenied(ctx context.Context, c client.Reader, cronJob *v1.CronJob, replaced int, now time.Time) (string,
+	error) {
+	var quotas v1.CronJobQuotaList
+	if err := c.List(ctx, &quotas, client.InNamespace(cronJob.Namespace)); err != nil || len(quotas.Items) == 0 {
+		return "", err
+	}
+	usage, err := quota.Usage(ctx, c, cronJob.Namespace, now)
+	if err != nil {
+		return "", err
+	}
+	usage.ActiveJobs -= int32(replaced)
+
+	sort.Slice(quotas.Items, func(i, j int) bool { return quotas.Items[i].Name < quotas.Items[j].Name })
+	for i := range quotas.Items {
+		if denied := quota.RunDenied(&quotas.Items[i], usage); denied != "" {
+			return denied, nil
+		}
+	}
+	return "", nil
+}
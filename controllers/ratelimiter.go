@@ -0,0 +1,98 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+/*
+The work queue of a controller keeps its rate limiter for its whole life, so to change the rate limits while the
+manager runs, the queue gets a rate limiter which delegates to a replaceable one. The defaults are the ones of
+workqueue.DefaultControllerRateLimiter. Replacing the rate limiter resets the back-off of the failing CronJobs.
+*/
+
+const (
+	defaultBaseDelay = 5 * time.Millisecond
+	defaultMaxDelay  = 1000 * time.Second
+	defaultQPS       = 10
+	defaultBurst     = 100
+)
+
+// reloadableRateLimiter is a workqueue.RateLimiter whose settings can be changed.
+type reloadableRateLimiter struct {
+	lock    sync.RWMutex
+	limiter workqueue.RateLimiter
+}
+
+var _ workqueue.RateLimiter = &reloadableRateLimiter{}
+
+func newReloadableRateLimiter(config configv1.RateLimitConfig) *reloadableRateLimiter {
+	r := &reloadableRateLimiter{}
+	r.update(config)
+	return r
+}
+
+// update replaces the rate limiter with one built from the config.
+func (r *reloadableRateLimiter) update(config configv1.RateLimitConfig) {
+	baseDelay, maxDelay := defaultBaseDelay, defaultMaxDelay
+	if config.BaseDelay != nil {
+		baseDelay = config.BaseDelay.Duration
+	}
+	if config.MaxDelay != nil {
+		maxDelay = config.MaxDelay.Duration
+	}
+	qps, burst := defaultQPS, defaultBurst
+	if config.QPS > 0 {
+		qps = config.QPS
+	}
+	if config.Burst > 0 {
+		burst = config.Burst
+	}
+
+	limiter := workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.limiter = limiter
+}
+
+func (r *reloadableRateLimiter) When(item interface{}) time.Duration {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.limiter.When(item)
+}
+
+func (r *reloadableRateLimiter) Forget(item interface{}) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	r.limiter.Forget(item)
+}
+
+func (r *reloadableRateLimiter) NumRequeues(item interface{}) int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.limiter.NumRequeues(item)
+}
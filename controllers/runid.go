@@ -0,0 +1,81 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/*
+Every run gets a run ID when its Job is created, so the logs of the application, the logs of the controllers, the
+JobRun, the events and the notifications of a run can be joined on it. The ID is a label of the Job and of its pods,
+which the log collectors usually attach to the logs, and an environment variable of the containers, for the
+applications logging it themselves. The runs of the external runners carry the label on their object.
+
+The ID of a scheduled run is derived from its owner and its scheduled time: a Job created again after a conflict gets
+the same ID, and all the Jobs of an activation of a Workflow share it. A manual run gets a random ID.
+*/
+
+const (
+	// runIDLabel is the run ID of a Job and its pods.
+	runIDLabel = "batch.example.com/run-id"
+	// runIDEnv is the environment variable of the containers holding the run ID.
+	runIDEnv = "CRONJOB_RUN_ID"
+)
+
+// scheduledRunID returns the run ID of the activation of the owner at the scheduled time.
+func scheduledRunID(owner types.UID, scheduledTime time.Time) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("%s/%d", owner, scheduledTime.Unix()))).String()
+}
+
+// manualRunID returns the run ID of a manual run.
+func manualRunID() string {
+	return uuid.New().String()
+}
+
+// setRunID sets the run ID of the Job and its pods. A variable of the template named like runIDEnv is kept.
+func setRunID(job *kbatch.Job, runID string) {
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	job.Labels[runIDLabel] = runID
+	template := &job.Spec.Template
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	template.Labels[runIDLabel] = runID
+	setEnv(template.Spec.InitContainers, runID)
+	setEnv(template.Spec.Containers, runID)
+}
+
+func setEnv(containers []corev1.Container, runID string) {
+	for i := range containers {
+		found := false
+		for _, env := range containers[i].Env {
+			found = found || env.Name == runIDEnv
+		}
+		if !found {
+			containers[i].Env = append(containers[i].Env, corev1.EnvVar{Name: runIDEnv, Value: runID})
+		}
+	}
+}
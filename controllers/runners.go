@@ -0,0 +1,424 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/jobcache"
+	kbatch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/component-base/featuregate"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+A Runner creates, tracks and deletes the runs of the CronJobs on a backend. The scheduling core only goes through it:
+the Job built from the template of the CronJob describes the run, with its name, annotations and owner, the Runner
+creates it, and gives the runs back seen as Jobs, with the conditions of a Job once they finished. So the status of the
+CronJob, the concurrency policy, the history limits, the notifications and the JobRuns work the same on every backend,
+and a new backend is a Runner and an entry in externalRunners.
+
+The Jobs are the default backend. Beside them, the runs can be the custom resources of another operator, like the
+Workflows of Argo or the PipelineRuns of Tekton, for the teams running their pipelines there but wanting the schedules,
+the policies and the quotas of the CronJobs. We do not depend on the APIs of these operators: their objects are
+unstructured. The log archive and the Backfills still only follow the Jobs.
+
+Every external runner is behind its feature gate, since the controllers only watch its objects when it is enabled,
+and its CRDs have to be installed then.
+*/
+
+// Runner runs the CronJobs on a backend.
+type Runner interface {
+	// Object returns an empty object of the runs, whose kind the controllers watch.
+	Object() client.Object
+	// CreateRun creates the run the Job describes, and sets the Job to the created run.
+	CreateRun(ctx context.Context, c client.Client, cronJob *v1.CronJob, job *kbatch.Job) error
+	// ListRuns returns the runs of the CronJob seen as Jobs.
+	ListRuns(ctx context.Context, c client.Reader, cronJob *v1.CronJob) ([]kbatch.Job, error)
+	// GetRunStatus returns the run seen as a Job, with its status.
+	GetRunStatus(ctx context.Context, c client.Reader, key client.ObjectKey) (*kbatch.Job, error)
+	// DeleteRun deletes the run.
+	DeleteRun(ctx context.Context, c client.Client, run *kbatch.Job, opts ...client.DeleteOption) error
+	// PatchRun applies the patch, computed on the run seen as a Job, to the run.
+	PatchRun(ctx context.Context, c client.Client, run *kbatch.Job, patch client.Patch) error
+}
+
+// JobRunner runs the CronJobs with Jobs, the default runner.
+type JobRunner struct {
+	// OwnerIndex is the field index of the Jobs by the name of their CronJob. Without it, all the Jobs of the namespace
+	// are listed.
+	OwnerIndex string
+	// StateIndex is the field index of the Jobs by the name of their CronJob and their runState, as
+	// `<cronjob>/<state>`. Without it, the Jobs of the CronJob are listed and filtered by their state.
+	StateIndex string
+}
+
+// runState tells whether a run finished.
+type runState string
+
+const (
+	runActive   runState = "active"
+	runFinished runState = "finished"
+)
+
+// stateOf returns the state of the run.
+func stateOf(run *kbatch.Job) runState {
+	if finishedCondition(run) == nil {
+		return runActive
+	}
+	return runFinished
+}
+
+// stateIndexValue returns the value of the run of the CronJob in the StateIndex.
+func stateIndexValue(cronJob string, state runState) string {
+	return cronJob + "/" + string(state)
+}
+
+var _ Runner = &JobRunner{}
+
+// jobObject returns the object the Jobs are watched and indexed as, their metadata only with the MetadataOnlyJobs
+// feature gate.
+func jobObject() client.Object {
+	if featuregates.Enabled(featuregates.MetadataOnlyJobs) {
+		return jobcache.Object()
+	}
+	return &kbatch.Job{}
+}
+
+// Object implements Runner
+func (r *JobRunner) Object() client.Object {
+	return &kbatch.Job{}
+}
+
+// CreateRun implements Runner
+func (r *JobRunner) CreateRun(ctx context.Context, c client.Client, _ *v1.CronJob, job *kbatch.Job) error {
+	return c.Create(ctx, job)
+}
+
+// ListRuns implements Runner
+func (r *JobRunner) ListRuns(ctx context.Context, c client.Reader, cronJob *v1.CronJob) ([]kbatch.Job, error) {
+	opts := []client.ListOption{client.InNamespace(cronJob.Namespace)}
+	if r.OwnerIndex != "" {
+		opts = append(opts, client.MatchingFields{r.OwnerIndex: cronJob.Name})
+	}
+	var jobs kbatch.JobList
+	if err := c.List(ctx, &jobs, opts...); err != nil {
+		return nil, err
+	}
+	runs := jobs.Items[:0]
+	for i := range jobs.Items {
+		if metav1.IsControlledBy(&jobs.Items[i], cronJob) {
+			runs = append(runs, jobs.Items[i])
+		}
+	}
+	return runs, nil
+}
+
+// ListRunsInState returns the Jobs of the CronJob in the state, looked up in the StateIndex if any.
+func (r *JobRunner) ListRunsInState(ctx context.Context, c client.Reader, cronJob *v1.CronJob,
+	state runState) ([]kbatch.Job, error) {
+	if r.StateIndex == "" {
+		runs, err := r.ListRuns(ctx, c, cronJob)
+		return filterRuns(runs, state), err
+	}
+	var jobs kbatch.JobList
+	if err := c.List(ctx, &jobs, client.InNamespace(cronJob.Namespace),
+		client.MatchingFields{r.StateIndex: stateIndexValue(cronJob.Name, state)}); err != nil {
+		return nil, err
+	}
+	runs := jobs.Items[:0]
+	for i := range jobs.Items {
+		if metav1.IsControlledBy(&jobs.Items[i], cronJob) {
+			runs = append(runs, jobs.Items[i])
+		}
+	}
+	return runs, nil
+}
+
+// filterRuns returns the runs in the state, reusing the slice.
+func filterRuns(runs []kbatch.Job, state runState) []kbatch.Job {
+	filtered := runs[:0]
+	for i := range runs {
+		if stateOf(&runs[i]) == state {
+			filtered = append(filtered, runs[i])
+		}
+	}
+	return filtered
+}
+
+// GetRunStatus implements Runner
+func (r *JobRunner) GetRunStatus(ctx context.Context, c client.Reader, key client.ObjectKey) (*kbatch.Job, error) {
+	var job kbatch.Job
+	if err := c.Get(ctx, key, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// DeleteRun implements Runner
+func (r *JobRunner) DeleteRun(ctx context.Context, c client.Client, run *kbatch.Job,
+	opts ...client.DeleteOption) error {
+	return c.Delete(ctx, run, opts...)
+}
+
+// PatchRun implements Runner
+func (r *JobRunner) PatchRun(ctx context.Context, c client.Client, run *kbatch.Job, patch client.Patch) error {
+	return c.Patch(ctx, run, patch)
+}
+
+// externalRunner runs the CronJobs with the custom resources of another operator.
+type externalRunner struct {
+	gvk schema.GroupVersionKind
+	// spec returns the spec of the object of a run of the CronJob.
+	spec func(cronJob *v1.CronJob) map[string]interface{}
+	// annotate adds the annotations of the operator to the object of a run.
+	annotate func(annotations map[string]string)
+	// status sets the status of the Job from the object of a run.
+	status func(object *unstructured.Unstructured, job *kbatch.Job)
+}
+
+var _ Runner = &externalRunner{}
+
+// runnerCronJobLabel is the name of the CronJob of the object of a run of an external runner.
+const runnerCronJobLabel = "batch.example.com/cronjob"
+
+// registeredRunner is a runner of the CronJobs beside the Job runner.
+type registeredRunner struct {
+	Runner
+	// name is the runner in the spec of the CronJobs.
+	name v1.Runner
+	// feature is the feature gate enabling the runner.
+	feature featuregate.Feature
+	// described returns whether the CronJob describes the runs of the runner.
+	described func(cronJob *v1.CronJob) bool
+}
+
+// externalRunners are the runners of the CronJobs beside the Job runner.
+var externalRunners = []registeredRunner{
+	{
+		Runner:    argoWorkflowRunner,
+		name:      v1.ArgoWorkflowRunner,
+		feature:   featuregates.ArgoWorkflowRunner,
+		described: func(cronJob *v1.CronJob) bool { return cronJob.Spec.ArgoWorkflow != nil },
+	},
+	{
+		Runner:    tektonPipelineRunner,
+		name:      v1.TektonPipelineRunner,
+		feature:   featuregates.TektonPipelineRunner,
+		described: func(cronJob *v1.CronJob) bool { return cronJob.Spec.TektonPipeline != nil },
+	},
+}
+
+// enabledExternalRunners returns the external runners whose feature gate is enabled.
+func enabledExternalRunners() []Runner {
+	var runners []Runner
+	for _, runner := range externalRunners {
+		if featuregates.Enabled(runner.feature) {
+			runners = append(runners, runner.Runner)
+		}
+	}
+	return runners
+}
+
+// enabledRunners returns the Job runner and the enabled external runners.
+func enabledRunners() []Runner {
+	return append([]Runner{&JobRunner{}}, enabledExternalRunners()...)
+}
+
+// runnerOf returns the runner of the CronJob, the Job runner if its runner is disabled.
+func runnerOf(cronJob *v1.CronJob, jobRunner *JobRunner) Runner {
+	for _, runner := range externalRunners {
+		if runner.name == cronJob.Spec.Runner && featuregates.Enabled(runner.feature) {
+			return runner.Runner
+		}
+	}
+	return jobRunner
+}
+
+// runnerOfRun returns the runner of a run seen as a Job, by its kind.
+func runnerOfRun(run *kbatch.Job) Runner {
+	for _, runner := range externalRunners {
+		if run.GroupVersionKind() == runner.Object().GetObjectKind().GroupVersionKind() {
+			return runner.Runner
+		}
+	}
+	return &JobRunner{}
+}
+
+/*
+The Jobs of a CronJob whose runner changed are still its runs until they are deleted, so they count in its active runs
+and its history.
+*/
+
+// listRunsInState returns the Jobs of the CronJob and the runs of its external runner, seen as Jobs, in the state.
+// The external runs are not indexed, they are filtered.
+func listRunsInState(ctx context.Context, c client.Reader, cronJob *v1.CronJob, jobRunner *JobRunner,
+	state runState) ([]kbatch.Job, error) {
+	runs, err := jobRunner.ListRunsInState(ctx, c, cronJob, state)
+	if err != nil {
+		return nil, err
+	}
+	if runner := runnerOf(cronJob, jobRunner); runner != Runner(jobRunner) {
+		externalRuns, err := runner.ListRuns(ctx, c, cronJob)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, filterRuns(externalRuns, state)...)
+	}
+	return runs, nil
+}
+
+// runnerDenied returns why the runner of the CronJob can not start its runs, empty if it can.
+func runnerDenied(cronJob *v1.CronJob) string {
+	for _, runner := range externalRunners {
+		if runner.name != cronJob.Spec.Runner {
+			continue
+		}
+		if !featuregates.Enabled(runner.feature) {
+			return "the " + string(runner.name) + " runner requires the " + string(runner.feature) + " feature gate"
+		}
+		if !runner.described(cronJob) {
+			return "the " + string(runner.name) + " runner requires its spec"
+		}
+	}
+	return ""
+}
+
+// Object implements Runner
+func (r *externalRunner) Object() client.Object {
+	return r.newObject()
+}
+
+// newObject returns an empty object of the runner.
+func (r *externalRunner) newObject() *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(r.gvk)
+	return object
+}
+
+// job returns the object of a run seen as a Job, with its kind so it is not mistaken for one.
+func (r *externalRunner) job(object *unstructured.Unstructured) kbatch.Job {
+	job := kbatch.Job{
+		TypeMeta: metav1.TypeMeta{APIVersion: r.gvk.GroupVersion().String(), Kind: r.gvk.Kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              object.GetName(),
+			Namespace:         object.GetNamespace(),
+			UID:               object.GetUID(),
+			ResourceVersion:   object.GetResourceVersion(),
+			CreationTimestamp: object.GetCreationTimestamp(),
+			Labels:            object.GetLabels(),
+			Annotations:       object.GetAnnotations(),
+			OwnerReferences:   object.GetOwnerReferences(),
+		},
+	}
+	r.status(object, &job)
+	return job
+}
+
+// runObject returns the object of a run seen as a Job.
+func (r *externalRunner) runObject(run *kbatch.Job) *unstructured.Unstructured {
+	object := r.newObject()
+	object.SetNamespace(run.Namespace)
+	object.SetName(run.Name)
+	return object
+}
+
+// GetRunStatus implements Runner
+func (r *externalRunner) GetRunStatus(ctx context.Context, c client.Reader, key client.ObjectKey) (*kbatch.Job,
+	error) {
+	object := r.newObject()
+	if err := c.Get(ctx, key, object); err != nil {
+		return nil, err
+	}
+	job := r.job(object)
+	return &job, nil
+}
+
+// ListRuns implements Runner
+func (r *externalRunner) ListRuns(ctx context.Context, c client.Reader, cronJob *v1.CronJob) ([]kbatch.Job, error) {
+	objects := &unstructured.UnstructuredList{}
+	objects.SetGroupVersionKind(r.gvk.GroupVersion().WithKind(r.gvk.Kind + "List"))
+	if err := c.List(ctx, objects, client.InNamespace(cronJob.Namespace),
+		client.MatchingLabels{runnerCronJobLabel: cronJob.Name}); err != nil {
+		return nil, err
+	}
+	var jobs []kbatch.Job
+	for i := range objects.Items {
+		if metav1.IsControlledBy(&objects.Items[i], cronJob) {
+			jobs = append(jobs, r.job(&objects.Items[i]))
+		}
+	}
+	return jobs, nil
+}
+
+// DeleteRun implements Runner
+func (r *externalRunner) DeleteRun(ctx context.Context, c client.Client, run *kbatch.Job,
+	opts ...client.DeleteOption) error {
+	return c.Delete(ctx, r.runObject(run), opts...)
+}
+
+// PatchRun implements Runner
+func (r *externalRunner) PatchRun(ctx context.Context, c client.Client, run *kbatch.Job, patch client.Patch) error {
+	data, err := patch.Data(run)
+	if err != nil {
+		return err
+	}
+	return c.Patch(ctx, r.runObject(run), client.RawPatch(patch.Type(), data))
+}
+
+// CreateRun implements Runner, the object of the run is made of the metadata of the Job.
+func (r *externalRunner) CreateRun(ctx context.Context, c client.Client, cronJob *v1.CronJob, job *kbatch.Job) error {
+	object := r.runObject(job)
+	object.SetGenerateName(job.GenerateName)
+	labels := map[string]string{runnerCronJobLabel: cronJob.Name}
+	for k, v := range job.Labels {
+		labels[k] = v
+	}
+	object.SetLabels(labels)
+	annotations := map[string]string{}
+	for k, v := range job.Annotations {
+		annotations[k] = v
+	}
+	if r.annotate != nil {
+		r.annotate(annotations)
+	}
+	object.SetAnnotations(annotations)
+	object.SetOwnerReferences(job.OwnerReferences)
+	object.Object["spec"] = r.spec(cronJob)
+
+	if err := c.Create(ctx, object); err != nil {
+		return err
+	}
+	*job = r.job(object)
+	return nil
+}
+
+// statusTime returns the time of a field of the status of an object, nil if not set.
+func statusTime(object *unstructured.Unstructured, fields ...string) *metav1.Time {
+	raw, _, _ := unstructured.NestedString(object.Object, append([]string{"status"}, fields...)...)
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil
+	}
+	return &metav1.Time{Time: t}
+}
@@ -0,0 +1,425 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kbatch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+)
+
+// newRunnersScheme returns a scheme knowing the Jobs, the CronJobs and the unstructured runs of the external runners.
+func newRunnersScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+	Expect(v1.AddToScheme(s)).To(Succeed())
+	for _, runner := range externalRunners {
+		gvk := runner.Object().GetObjectKind().GroupVersionKind()
+		s.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		s.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+	return s
+}
+
+// recordingIndexer records the index functions, as the cache of the manager would run them.
+type recordingIndexer map[string]client.IndexerFunc
+
+func (i recordingIndexer) IndexField(_ context.Context, _ client.Object, field string, fn client.IndexerFunc) error {
+	i[field] = fn
+	return nil
+}
+
+// indexedReader lists the Jobs matching the fields with the recorded index functions, the fake client ignoring the
+// field selectors. It records the fields it was queried with.
+type indexedReader struct {
+	client.Reader
+	indexes recordingIndexer
+	queried []string
+}
+
+func (r *indexedReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+	fields := listOpts.FieldSelector
+	listOpts.FieldSelector = nil
+	if err := r.Reader.List(ctx, list, listOpts); err != nil {
+		return err
+	}
+	jobs, ok := list.(*kbatch.JobList)
+	if !ok || fields == nil {
+		return nil
+	}
+	for field, fn := range r.indexes {
+		value, found := fields.RequiresExactMatch(field)
+		if !found {
+			continue
+		}
+		r.queried = append(r.queried, field)
+		matching := jobs.Items[:0]
+		for i := range jobs.Items {
+			for _, indexed := range fn(&jobs.Items[i]) {
+				if indexed == value {
+					matching = append(matching, jobs.Items[i])
+					break
+				}
+			}
+		}
+		jobs.Items = matching
+	}
+	return nil
+}
+
+var _ = Describe("Runners", func() {
+	var (
+		ctx       context.Context
+		s         *runtime.Scheme
+		c         client.Client
+		cronJob   *v1.CronJob
+		scheduled time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		s = newRunnersScheme()
+		scheduled = time.Date(2021, time.June, 5, 2, 0, 0, 0, time.UTC)
+		cronJob = &v1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly", UID: types.UID("nightly-uid")},
+			Spec: v1.CronJobSpec{
+				Schedule: "0 2 * * *",
+				ArgoWorkflow: &v1.ArgoWorkflowSpec{
+					WorkflowTemplateRef: v1.ArgoWorkflowTemplateRef{Name: "etl", ClusterScope: true},
+					Parameters:          []v1.ArgoWorkflowParameter{{Name: "date", Value: "2021-06-05"}},
+				},
+				TektonPipeline: &v1.TektonPipelineSpec{
+					PipelineRef:        v1.TektonPipelineRef{Name: "build"},
+					Params:             []v1.TektonPipelineParam{{Name: "revision", Value: "main"}},
+					ServiceAccountName: "builder",
+				},
+			},
+		}
+		c = fake.NewClientBuilder().WithScheme(s).WithObjects(cronJob).Build()
+	})
+
+	// run returns the Job describing the run of the CronJob at the scheduled time.
+	run := func() *kbatch.Job {
+		job := &kbatch.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   cronJob.Namespace,
+				Name:        "nightly-1622858400",
+				Labels:      map[string]string{"team": "data"},
+				Annotations: map[string]string{scheduledTimeAnnotation: scheduled.Format(time.RFC3339)},
+			},
+		}
+		Expect(controllerutil.SetControllerReference(cronJob, job, s)).To(Succeed())
+		return job
+	}
+
+	// runObject returns the object of the run of the external runner.
+	runObject := func(runner *externalRunner, name string) *unstructured.Unstructured {
+		object := runner.newObject()
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: cronJob.Namespace, Name: name}, object)).To(Succeed())
+		return object
+	}
+
+	// setStatus sets the status of the object of the run.
+	setStatus := func(object *unstructured.Unstructured, status map[string]interface{}) {
+		object.Object["status"] = status
+		Expect(c.Update(ctx, object)).To(Succeed())
+	}
+
+	Context("JobRunner", func() {
+		It("Should create the Jobs and list the ones the CronJob controls", func() {
+			runner := &JobRunner{}
+			job := run()
+			Expect(runner.CreateRun(ctx, c, cronJob, job)).To(Succeed())
+			Expect(c.Create(ctx, &kbatch.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "other"}})).
+				To(Succeed())
+
+			runs, err := runner.ListRuns(ctx, c, cronJob)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs).To(HaveLen(1))
+			Expect(runs[0].Name).To(Equal(job.Name))
+
+			got, err := runner.GetRunStatus(ctx, c, client.ObjectKeyFromObject(job))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stateOf(got)).To(Equal(runActive))
+
+			Expect(runner.DeleteRun(ctx, c, got)).To(Succeed())
+			runs, err = runner.ListRuns(ctx, c, cronJob)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs).To(BeEmpty())
+		})
+	})
+
+	Context("ArgoWorkflow runner", func() {
+		It("Should submit a Workflow from the WorkflowTemplate of the CronJob", func() {
+			job := run()
+			Expect(argoWorkflowRunner.CreateRun(ctx, c, cronJob, job)).To(Succeed())
+			Expect(job.Kind).To(Equal("Workflow"))
+			Expect(runnerOfRun(job)).To(Equal(Runner(argoWorkflowRunner)))
+
+			workflow := runObject(argoWorkflowRunner, job.Name)
+			Expect(workflow.GetLabels()).To(HaveKeyWithValue(runnerCronJobLabel, "nightly"))
+			Expect(workflow.GetLabels()).To(HaveKeyWithValue("team", "data"))
+			Expect(workflow.GetAnnotations()).To(HaveKeyWithValue(argoScheduledTimeAnnotation,
+				scheduled.Format(time.RFC3339)))
+			Expect(metav1.IsControlledBy(workflow, cronJob)).To(BeTrue())
+			Expect(workflow.Object["spec"]).To(Equal(map[string]interface{}{
+				"workflowTemplateRef": map[string]interface{}{"name": "etl", "clusterScope": true},
+				"arguments": map[string]interface{}{
+					"parameters": []interface{}{map[string]interface{}{"name": "date", "value": "2021-06-05"}},
+				},
+			}))
+		})
+
+		It("Should report the phase of the Workflow as the state of the run", func() {
+			job := run()
+			Expect(argoWorkflowRunner.CreateRun(ctx, c, cronJob, job)).To(Succeed())
+			key := client.ObjectKeyFromObject(job)
+
+			setStatus(runObject(argoWorkflowRunner, job.Name), map[string]interface{}{
+				"phase": "Running", "startedAt": "2021-06-05T02:00:05Z",
+			})
+			got, err := argoWorkflowRunner.GetRunStatus(ctx, c, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stateOf(got)).To(Equal(runActive))
+			Expect(got.Status.StartTime.Time).To(BeTemporally("==", scheduled.Add(5*time.Second)))
+
+			setStatus(runObject(argoWorkflowRunner, job.Name), map[string]interface{}{
+				"phase": "Error", "message": "pod deleted", "finishedAt": "2021-06-05T02:10:00Z",
+			})
+			got, err = argoWorkflowRunner.GetRunStatus(ctx, c, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stateOf(got)).To(Equal(runFinished))
+			condition := finishedCondition(got)
+			Expect(condition.Type).To(Equal(kbatch.JobFailed))
+			Expect(condition.Message).To(Equal("pod deleted"))
+			Expect(got.Status.CompletionTime.Time).To(BeTemporally("==", scheduled.Add(10*time.Minute)))
+
+			setStatus(runObject(argoWorkflowRunner, job.Name), map[string]interface{}{"phase": "Succeeded"})
+			runs, err := argoWorkflowRunner.ListRuns(ctx, c, cronJob)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs).To(HaveLen(1))
+			Expect(finishedCondition(&runs[0]).Type).To(Equal(kbatch.JobComplete))
+		})
+
+		It("Should patch and delete the Workflow of the run", func() {
+			job := run()
+			Expect(argoWorkflowRunner.CreateRun(ctx, c, cronJob, job)).To(Succeed())
+
+			patch := client.MergeFrom(job.DeepCopy())
+			job.Annotations[notifiedAnnotation] = "true"
+			Expect(argoWorkflowRunner.PatchRun(ctx, c, job, patch)).To(Succeed())
+			Expect(runObject(argoWorkflowRunner, job.Name).GetAnnotations()).To(HaveKeyWithValue(notifiedAnnotation,
+				"true"))
+
+			Expect(argoWorkflowRunner.DeleteRun(ctx, c, job)).To(Succeed())
+			runs, err := argoWorkflowRunner.ListRuns(ctx, c, cronJob)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs).To(BeEmpty())
+		})
+	})
+
+	Context("TektonPipeline runner", func() {
+		It("Should create a PipelineRun from the Pipeline of the CronJob", func() {
+			job := run()
+			Expect(tektonPipelineRunner.CreateRun(ctx, c, cronJob, job)).To(Succeed())
+			Expect(job.Kind).To(Equal("PipelineRun"))
+
+			pipelineRun := runObject(tektonPipelineRunner, job.Name)
+			Expect(pipelineRun.GetLabels()).To(HaveKeyWithValue(runnerCronJobLabel, "nightly"))
+			Expect(pipelineRun.GetAnnotations()).To(HaveKeyWithValue(scheduledTimeAnnotation,
+				scheduled.Format(time.RFC3339)))
+			Expect(pipelineRun.Object["spec"]).To(Equal(map[string]interface{}{
+				"pipelineRef":        map[string]interface{}{"name": "build"},
+				"params":             []interface{}{map[string]interface{}{"name": "revision", "value": "main"}},
+				"serviceAccountName": "builder",
+			}))
+		})
+
+		It("Should report the Succeeded condition of the PipelineRun as the state of the run", func() {
+			job := run()
+			Expect(tektonPipelineRunner.CreateRun(ctx, c, cronJob, job)).To(Succeed())
+			key := client.ObjectKeyFromObject(job)
+			succeeded := func(status, reason string) map[string]interface{} {
+				return map[string]interface{}{
+					"startTime":      "2021-06-05T02:00:05Z",
+					"completionTime": "2021-06-05T02:30:00Z",
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Succeeded", "status": status, "reason": reason},
+					},
+				}
+			}
+
+			setStatus(runObject(tektonPipelineRunner, job.Name), succeeded("Unknown", "Running"))
+			got, err := tektonPipelineRunner.GetRunStatus(ctx, c, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stateOf(got)).To(Equal(runActive))
+			Expect(got.Status.StartTime).NotTo(BeNil())
+
+			setStatus(runObject(tektonPipelineRunner, job.Name), succeeded("False", "PipelineRunTimeout"))
+			got, err = tektonPipelineRunner.GetRunStatus(ctx, c, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(finishedCondition(got).Type).To(Equal(kbatch.JobFailed))
+			Expect(finishedCondition(got).Reason).To(Equal("PipelineRunTimeout"))
+			Expect(got.Status.CompletionTime.Time).To(BeTemporally("==", scheduled.Add(30*time.Minute)))
+
+			setStatus(runObject(tektonPipelineRunner, job.Name), succeeded("True", "Succeeded"))
+			got, err = tektonPipelineRunner.GetRunStatus(ctx, c, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(finishedCondition(got).Type).To(Equal(kbatch.JobComplete))
+		})
+	})
+
+	Context("runner selection", func() {
+		AfterEach(func() {
+			Expect(featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.ArgoWorkflowRunner): false})).
+				To(Succeed())
+		})
+
+		It("Should only run the CronJobs with an external runner whose feature gate is enabled", func() {
+			jobRunner := &JobRunner{}
+			cronJob.Spec.Runner = v1.ArgoWorkflowRunner
+			Expect(runnerOf(cronJob, jobRunner)).To(Equal(Runner(jobRunner)))
+			Expect(runnerDenied(cronJob)).To(ContainSubstring("feature gate"))
+
+			Expect(featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.ArgoWorkflowRunner): true})).
+				To(Succeed())
+			Expect(runnerOf(cronJob, jobRunner)).To(Equal(Runner(argoWorkflowRunner)))
+			Expect(runnerDenied(cronJob)).To(BeEmpty())
+			Expect(enabledRunners()).To(ConsistOf(Runner(&JobRunner{}), Runner(argoWorkflowRunner)))
+
+			cronJob.Spec.ArgoWorkflow = nil
+			Expect(runnerDenied(cronJob)).To(ContainSubstring("requires its spec"))
+		})
+	})
+
+	Context("listing the runs in a state", func() {
+		// listFinished lists the finished runs of the CronJob through the indexes of the Job runner set up under the
+		// MetadataOnlyJobs feature gate, and returns their names with the fields the Jobs were queried with.
+		listFinished := func(metadataOnly bool) ([]string, []string) {
+			Expect(featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.MetadataOnlyJobs): metadataOnly})).
+				To(Succeed())
+			defer func() {
+				Expect(featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.MetadataOnlyJobs): false})).
+					To(Succeed())
+			}()
+
+			indexes := recordingIndexer{}
+			jobRunner, err := newJobRunner(indexes)
+			Expect(err).NotTo(HaveOccurred())
+			if metadataOnly {
+				Expect(indexes).To(HaveLen(1))
+				Expect(jobRunner.StateIndex).To(BeEmpty())
+			} else {
+				Expect(indexes).To(HaveKey(jobStateKey))
+				Expect(jobRunner.StateIndex).To(Equal(jobStateKey))
+			}
+			Expect(jobRunner.OwnerIndex).To(Equal(jobOwnerKey))
+
+			reader := &indexedReader{Reader: c, indexes: indexes}
+			runs, err := listRunsInState(ctx, reader, cronJob, jobRunner, runFinished)
+			Expect(err).NotTo(HaveOccurred())
+			var names []string
+			for _, run := range runs {
+				names = append(names, run.Name)
+			}
+			return names, reader.queried
+		}
+
+		BeforeEach(func() {
+			other := &v1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hourly", UID: "hourly-uid"}}
+			finished := []kbatch.JobCondition{{Type: kbatch.JobComplete, Status: "True"}}
+			for _, job := range []struct {
+				name       string
+				owner      *v1.CronJob
+				conditions []kbatch.JobCondition
+			}{
+				{name: "nightly-active", owner: cronJob},
+				{name: "nightly-finished", owner: cronJob, conditions: finished},
+				{name: "hourly-finished", owner: other, conditions: finished},
+				{name: "orphan-finished", conditions: finished},
+			} {
+				object := &kbatch.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: job.name}}
+				if job.owner != nil {
+					Expect(controllerutil.SetControllerReference(job.owner, object, s)).To(Succeed())
+				}
+				object.Status.Conditions = job.conditions
+				Expect(c.Create(ctx, object)).To(Succeed())
+			}
+		})
+
+		It("Should index the Jobs on their CronJob and on whether they finished", func() {
+			job := &kbatch.Job{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "nightly-finished"}, job)).To(Succeed())
+			Expect(indexJobOwner(job)).To(Equal([]string{"nightly"}))
+			Expect(indexJobState(job)).To(Equal([]string{"nightly/finished"}))
+
+			job = &kbatch.Job{}
+			Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "orphan-finished"}, job)).To(Succeed())
+			Expect(indexJobOwner(job)).To(BeNil())
+			Expect(indexJobState(job)).To(BeNil())
+		})
+
+		It("Should look up the finished Jobs in the state index", func() {
+			names, queried := listFinished(false)
+			Expect(names).To(ConsistOf("nightly-finished"))
+			Expect(queried).To(Equal([]string{jobStateKey}))
+		})
+
+		It("Should filter the Jobs of the CronJob on their state with the MetadataOnlyJobs feature gate", func() {
+			names, queried := listFinished(true)
+			Expect(names).To(ConsistOf("nightly-finished"))
+			Expect(queried).To(Equal([]string{jobOwnerKey}))
+		})
+
+		It("Should add the finished runs of the external runner under both settings", func() {
+			Expect(featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.ArgoWorkflowRunner): true})).
+				To(Succeed())
+			defer func() {
+				Expect(featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.ArgoWorkflowRunner): false})).
+					To(Succeed())
+			}()
+			cronJob.Spec.Runner = v1.ArgoWorkflowRunner
+			for _, name := range []string{"nightly-workflow-active", "nightly-workflow-finished"} {
+				job := &kbatch.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+				Expect(controllerutil.SetControllerReference(cronJob, job, s)).To(Succeed())
+				Expect(argoWorkflowRunner.CreateRun(ctx, c, cronJob, job)).To(Succeed())
+			}
+			workflow := runObject(argoWorkflowRunner, "nightly-workflow-finished")
+			setStatus(workflow, map[string]interface{}{"phase": "Succeeded"})
+
+			for _, metadataOnly := range []bool{false, true} {
+				names, _ := listFinished(metadataOnly)
+				Expect(names).To(ConsistOf("nightly-finished", "nightly-workflow-finished"))
+			}
+		})
+	})
+})
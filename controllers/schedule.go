@@ -0,0 +1,65 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/missedstarts"
+)
+
+// nextSchedule returns the latest missed activation of the schedule since the last one, zero if none, whether too
+// many activations were missed to walk them, and the next activation. It is the getNextSchedule of the
+// CronJobReconciler for the Workflows and the ClusterCronJobs, which run in the time zone of the controller.
+func nextSchedule(schedule string, lastScheduleTime *metav1.Time, created metav1.Time, startingDeadlineSeconds *int64,
+	now time.Time) (lastMissed time.Time, capped bool, next time.Time, err error) {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return time.Time{}, false, time.Time{}, fmt.Errorf("unparseable schedule %q: %v", schedule, err)
+	}
+
+	earliestTime := created.Time
+	if lastScheduleTime != nil {
+		earliestTime = lastScheduleTime.Time
+	}
+	if startingDeadlineSeconds != nil {
+		// controller is not going to schedule anything below this point
+		schedulingDeadline := now.Add(-time.Second * time.Duration(*startingDeadlineSeconds))
+		if schedulingDeadline.After(earliestTime) {
+			earliestTime = schedulingDeadline
+		}
+	}
+	if earliestTime.After(now) {
+		return time.Time{}, false, sched.Next(now), nil
+	}
+
+	// only the latest missed activation is searched for beyond the cap, like getNextSchedule
+	lastMissed, capped = missedstarts.Latest(sched, earliestTime, now)
+	return lastMissed, capped, sched.Next(now), nil
+}
+
+// tooLate returns whether the scheduled time is past the starting deadline.
+func tooLate(scheduledTime time.Time, startingDeadlineSeconds *int64, now time.Time) bool {
+	if startingDeadlineSeconds == nil {
+		return false
+	}
+	return scheduledTime.Add(time.Duration(*startingDeadlineSeconds) * time.Second).Before(now)
+}
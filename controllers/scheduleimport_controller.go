@@ -0,0 +1,263 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/scheduleimport"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+/*
+The ScheduleImportReconciler mirrors the schedules of the cloud schedulers as CronJobs. Like the CalendarReconciler,
+an import is reconciled when its spec changes and on every interval, and a failed listing is retried sooner, leaving
+the CronJobs as they are. Like the CronJobSetReconciler, a CronJob is only updated when its hash changes, and the
+schedules which can not be imported are reported one by one, they do not stop the others.
+
+The CronJobs are found by their import label rather than owned by the import, so removing the label releases one, and
+deleting the import leaves them in place. A schedule whose expression stops having a cron equivalent keeps its CronJob
+as it was, only the CronJobs of the schedules gone from the cloud are deleted.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=scheduleimports,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=scheduleimports/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+const (
+	// scheduleImportSyncedCondition is false when the last listing of the schedules of the import failed.
+	scheduleImportSyncedCondition = "Synced"
+	// scheduleImportErrorReportingComponent is the component of the errors reported by the import controller.
+	scheduleImportErrorReportingComponent = "scheduleimport-controller"
+
+	defaultScheduleImportInterval = 10 * time.Minute
+	// minScheduleImportInterval is the shortest interval, and how soon a failed listing is retried.
+	minScheduleImportInterval = time.Minute
+	// scheduleImportListTimeout bounds the listing of the schedules.
+	scheduleImportListTimeout = 2 * time.Minute
+	// maxReportedSchedules bounds the schedules listed in the status of an import.
+	maxReportedSchedules = 100
+)
+
+// ScheduleImportReconciler creates, updates and deletes the CronJobs of the schedules of the ScheduleImports.
+type ScheduleImportReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// APIReader reads the secrets of the imports, rather than caching all the secrets of the cluster.
+	APIReader client.Reader
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// Reconcile imports the schedules of the import when its interval passed, or when its spec changed.
+func (r *ScheduleImportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() {
+		r.ErrorReporter.ReconcileResult(scheduleImportErrorReportingComponent, req.NamespacedName, err)
+	}()
+	defer r.ErrorReporter.Recover(scheduleImportErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var imp v1.ScheduleImport
+	if err := r.Get(ctx, req.NamespacedName, &imp); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if imp.Spec.Suspend {
+		return ctrl.Result{}, nil
+	}
+
+	interval := scheduleImportInterval(&imp.Spec)
+	if imp.Status.ObservedGeneration == imp.Generation && imp.Status.LastSyncTime != nil &&
+		meta.IsStatusConditionTrue(imp.Status.Conditions, scheduleImportSyncedCondition) {
+		if wait := imp.Status.LastSyncTime.Add(interval).Sub(r.Now()); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	schedules, err := r.listSchedules(ctx, &imp)
+	imp.Status.ObservedGeneration = imp.Generation
+	if err != nil {
+		logger.Error(err, "unable to list the schedules", "provider", imp.Spec.Provider)
+		meta.SetStatusCondition(&imp.Status.Conditions, metav1.Condition{Type: scheduleImportSyncedCondition,
+			Status: metav1.ConditionFalse, Reason: "ListFailed", Message: err.Error(),
+			ObservedGeneration: imp.Generation})
+		if err := r.Status().Update(ctx, &imp); err != nil {
+			logger.Error(err, "unable to update ScheduleImport status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: minScheduleImportInterval}, nil
+	}
+
+	var imported v1.CronJobList
+	if err := r.List(ctx, &imported, client.InNamespace(imp.Namespace),
+		client.MatchingLabels{scheduleimport.ImportLabel: imp.Name}); err != nil {
+		logger.Error(err, "unable to list imported CronJobs")
+		return ctrl.Result{}, err
+	}
+	existing := map[string]*v1.CronJob{}
+	for i := range imported.Items {
+		existing[imported.Items[i].Name] = &imported.Items[i]
+	}
+
+	var statuses []v1.ImportedScheduleStatus
+	schedulesOf := map[string]string{}
+	var importedCount, failed int32
+	for _, schedule := range schedules {
+		name := scheduleimport.CronJobName(imp.Name, schedule.Name)
+		status := v1.ImportedScheduleStatus{Name: schedule.Name, CronJob: name}
+		if other, ok := schedulesOf[name]; ok {
+			status.Message = fmt.Sprintf("the CronJob %s is also the one of the schedule %s", name, other)
+		} else {
+			schedulesOf[name] = schedule.Name
+			if status.Message, err = r.syncSchedule(ctx, &imp, schedule, existing[name]); err != nil {
+				logger.Error(err, "unable to sync CronJob", "cronJob", name)
+				return ctrl.Result{}, err
+			}
+		}
+		if status.Message != "" {
+			failed++
+		} else {
+			importedCount++
+		}
+		statuses = append(statuses, status)
+	}
+
+	// the CronJobs of the schedules which were deleted from the cloud, or which no longer match the prefix
+	for name, cronJob := range existing {
+		if _, ok := schedulesOf[name]; ok {
+			continue
+		}
+		if err := r.Delete(ctx, cronJob); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "unable to delete CronJob", "cronJob", name)
+			return ctrl.Result{}, err
+		}
+		logger.V(1).Info("deleted CronJob of a schedule gone from the cloud", "cronJob", name)
+	}
+
+	// the failed schedules are the ones worth a look, so they are listed first
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Message != "" && statuses[j].Message == "" })
+	if len(statuses) > maxReportedSchedules {
+		statuses = statuses[:maxReportedSchedules]
+	}
+	imp.Status.Schedules = statuses
+	imp.Status.Imported, imp.Status.Failed = importedCount, failed
+	imp.Status.LastSyncTime = &metav1.Time{Time: r.Now()}
+	meta.SetStatusCondition(&imp.Status.Conditions, metav1.Condition{Type: scheduleImportSyncedCondition,
+		Status: metav1.ConditionTrue, Reason: "Listed",
+		Message:            fmt.Sprintf("imported %d of %d schedules", importedCount, len(schedules)),
+		ObservedGeneration: imp.Generation})
+	if err := r.Status().Update(ctx, &imp); err != nil {
+		logger.Error(err, "unable to update ScheduleImport status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// listSchedules reads the credentials of the import and lists its schedules.
+func (r *ScheduleImportReconciler) listSchedules(ctx context.Context,
+	imp *v1.ScheduleImport) ([]scheduleimport.Schedule, error) {
+	var secret corev1.Secret
+	key := client.ObjectKey{Namespace: imp.Namespace, Name: imp.Spec.CredentialsSecret.Name}
+	if err := r.APIReader.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("unable to get the secret %s: %w", key.Name, err)
+	}
+	listCtx, cancel := context.WithTimeout(ctx, scheduleImportListTimeout)
+	defer cancel()
+	source, err := scheduleimport.NewSource(listCtx, &imp.Spec, secret.Data)
+	if err != nil {
+		return nil, err
+	}
+	return source.List(listCtx, imp.Spec.NamePrefix)
+}
+
+// syncSchedule creates the CronJob of the schedule, or updates it when its hash changed. The errors which a retry does
+// not fix are returned as the message of the schedule.
+func (r *ScheduleImportReconciler) syncSchedule(ctx context.Context, imp *v1.ScheduleImport,
+	schedule scheduleimport.Schedule, current *v1.CronJob) (string, error) {
+	desired, err := scheduleimport.Render(imp, schedule)
+	if err != nil {
+		return err.Error(), nil
+	}
+	if current != nil && current.Annotations[scheduleimport.HashAnnotation] ==
+		desired.Annotations[scheduleimport.HashAnnotation] {
+		return "", nil
+	}
+
+	if current == nil {
+		err = r.Create(ctx, desired)
+		if apierrors.IsAlreadyExists(err) {
+			return fmt.Sprintf("CronJob %s already exists and was not imported", desired.Name), nil
+		}
+	} else {
+		current.Labels, current.Annotations, current.Spec = desired.Labels, desired.Annotations, desired.Spec
+		err = r.Update(ctx, current)
+	}
+	if apierrors.IsInvalid(err) || apierrors.IsForbidden(err) {
+		return err.Error(), nil
+	}
+	return "", err
+}
+
+// scheduleImportInterval returns how often the schedules are listed.
+func scheduleImportInterval(spec *v1.ScheduleImportSpec) time.Duration {
+	if spec.Interval == nil {
+		return defaultScheduleImportInterval
+	}
+	if spec.Interval.Duration < minScheduleImportInterval {
+		return minScheduleImportInterval
+	}
+	return spec.Interval.Duration
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ScheduleImportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.ScheduleImport{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none, the
+// Secrets being the credentials of the cloud schedulers.
+func (r *ScheduleImportReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "scheduleimports", []string{"get", "list", "watch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "scheduleimports/status", []string{"update"},
+		namespaces...)...)
+	permissions = append(permissions, startup.Permissions(group, "cronjobs",
+		[]string{"get", "list", "watch", "create", "update", "delete"}, namespaces...)...)
+	return append(permissions, startup.Permissions("", "secrets", []string{"get"}, namespaces...)...)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/diagnostics"
+)
+
+/*
+Like the wakeups, the reconciler remembers what its last reconcile of every CronJob made of it, for the scheduling
+state served on /debug/scheduling. The reconcile fills its diagnostics.Scheduling as it goes, and the table records it
+with the requeue of the result once the reconcile returns.
+*/
+
+// schedulingTable holds what the last reconcile made of the CronJobs.
+type schedulingTable struct {
+	lock       sync.RWMutex
+	scheduling map[types.NamespacedName]diagnostics.Scheduling
+}
+
+func (t *schedulingTable) set(key types.NamespacedName, scheduling diagnostics.Scheduling) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.scheduling == nil {
+		t.scheduling = map[types.NamespacedName]diagnostics.Scheduling{}
+	}
+	t.scheduling[key] = scheduling
+}
+
+func (t *schedulingTable) delete(key types.NamespacedName) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.scheduling, key)
+}
+
+// recordScheduling records the scheduling of the reconciled CronJob with the result of the reconcile. Nothing is
+// recorded if the CronJob could not be read.
+func (r *CronJobReconciler) recordScheduling(key types.NamespacedName, scheduling *diagnostics.Scheduling,
+	result ctrl.Result, err error) {
+	if scheduling.LastReconcile.IsZero() {
+		return
+	}
+	if err != nil {
+		scheduling.Error = err.Error()
+	} else if result.RequeueAfter > 0 {
+		requeue := scheduling.LastReconcile.Add(result.RequeueAfter)
+		scheduling.Requeue = &requeue
+	}
+	r.scheduling.set(key, *scheduling)
+}
+
+// Scheduling implements diagnostics.SchedulingSource
+func (r *CronJobReconciler) Scheduling(key types.NamespacedName) (diagnostics.Scheduling, bool) {
+	r.scheduling.lock.RLock()
+	defer r.scheduling.lock.RUnlock()
+	scheduling, ok := r.scheduling.scheduling[key]
+	return scheduling, ok
+}
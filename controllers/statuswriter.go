@@ -0,0 +1,279 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/handover"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+)
+
+// statusesHandoverKey is the key of the unwritten statuses in the handover ConfigMap.
+const statusesHandoverKey = "statuses"
+
+/*
+Every Job of a CronJob changing reconciles the CronJob, and every reconcile writes the status it rebuilt from the Jobs.
+A burst of Jobs finishing together then writes the status once per Job. With a debounce, the statusWriter keeps the
+latest status of every CronJob instead, and writes it once the debounce passed since the first one it kept. The status
+is rebuilt by every reconcile, so a status lost with the manager is written again by the next leader. The history
+limits may have deleted the Jobs it was rebuilt from in between though, so with a handover store the statuses which
+could not be written on shutdown are saved in the handover ConfigMap, and the next leader keeps them as if they were
+written by its reconciles.
+
+The conditions set by the reconciles are coalesced too, so a burst of condition changes is written once. They are
+merged by type: a kept condition replaces the one of the same type, and the conditions of an earlier status of the
+burst which the later ones do not set are kept.
+
+A reconcile may run on a cache older than the one of the previous reconcile, and the history limits may have deleted
+the Jobs a status was rebuilt from. The last schedule time and the last successful time therefore never move back:
+the kept statuses are merged, and the later times of the CronJob are kept too. The status is patched with the
+resourceVersion of the CronJob read from the API server, so a status written in between makes the patch conflict
+instead of being overwritten, and the status is kept for the next debounce.
+*/
+
+var statusWriterLog = log.Log.WithName("status-writer")
+
+// unwrittenStatus is a status left to the next leader, as saved in the handover ConfigMap.
+type unwrittenStatus struct {
+	CronJob types.NamespacedName `json:"cronJob"`
+	Status  v1.CronJobStatus     `json:"status"`
+}
+
+// statusWriter coalesces the writes of the status of the same CronJob within the debounce.
+type statusWriter struct {
+	client client.Client
+	// reader reads the CronJobs before their status is patched, uncached so the patches conflict less.
+	reader   client.Reader
+	debounce time.Duration
+	// handover keeps the unwritten statuses for the next leader, nil if they are not kept.
+	handover *handover.Store
+
+	lock    sync.Mutex
+	pending map[types.NamespacedName]*v1.CronJobStatus
+	stopped bool
+}
+
+var _ manager.Runnable = &statusWriter{}
+var _ manager.LeaderElectionRunnable = &statusWriter{}
+
+func newStatusWriter(c client.Client, reader client.Reader, debounce time.Duration,
+	store *handover.Store) *statusWriter {
+	return &statusWriter{client: c, reader: reader, debounce: debounce, handover: store,
+		pending: map[types.NamespacedName]*v1.CronJobStatus{}}
+}
+
+// write keeps the status of the CronJob, merged with the one kept already, and writes it once the debounce passed. It
+// writes it right away once the manager is stopping.
+func (w *statusWriter) write(ctx context.Context, cronJob *v1.CronJob) error {
+	key := client.ObjectKeyFromObject(cronJob)
+	status := cronJob.Status.DeepCopy()
+	w.lock.Lock()
+	if w.stopped {
+		w.lock.Unlock()
+		return w.flush(ctx, key, status)
+	}
+	kept, coalesced := w.pending[key]
+	if coalesced {
+		mergeStatus(status, kept)
+	}
+	w.pending[key] = status
+	w.lock.Unlock()
+
+	if coalesced {
+		metrics.RecordStatusWrite(metrics.StatusWriteCoalesced)
+		return nil
+	}
+	time.AfterFunc(w.debounce, func() { w.flushPending(key) })
+	return nil
+}
+
+// flushPending writes the kept status of the CronJob, and keeps it again if the patch conflicted.
+func (w *statusWriter) flushPending(key types.NamespacedName) {
+	status := w.take(key)
+	if status == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := w.flush(ctx, key, status)
+	if apierrors.IsConflict(err) {
+		metrics.RecordStatusWrite(metrics.StatusWriteConflict)
+		w.requeue(key, status)
+	} else if err != nil {
+		statusWriterLog.Error(err, "unable to update CronJob status", "cronjob", key)
+	}
+}
+
+// requeue keeps the status of the CronJob again, merged with the one kept since, for the next debounce.
+func (w *statusWriter) requeue(key types.NamespacedName, status *v1.CronJobStatus) {
+	w.lock.Lock()
+	if w.stopped {
+		w.lock.Unlock()
+		// Start is writing the kept statuses, this one is tried once more
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := w.flush(ctx, key, status); err != nil {
+			statusWriterLog.Error(err, "unable to update CronJob status", "cronjob", key)
+		}
+		return
+	}
+	if kept, ok := w.pending[key]; ok {
+		mergeStatus(kept, status)
+		w.lock.Unlock()
+		return
+	}
+	w.pending[key] = status
+	w.lock.Unlock()
+	time.AfterFunc(w.debounce, func() { w.flushPending(key) })
+}
+
+// take removes the pending status of the CronJob.
+func (w *statusWriter) take(key types.NamespacedName) *v1.CronJobStatus {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	status := w.pending[key]
+	delete(w.pending, key)
+	return status
+}
+
+// flush patches the coalesced fields of the status on the CronJob, unless it has them already. The patch conflicts
+// if the CronJob changed since it was read.
+func (w *statusWriter) flush(ctx context.Context, key types.NamespacedName, status *v1.CronJobStatus) error {
+	var cronJob v1.CronJob
+	if err := w.reader.Get(ctx, key, &cronJob); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	previous := cronJob.DeepCopy()
+	cronJob.Status.Active = status.Active
+	cronJob.Status.LastScheduleTime = laterTime(status.LastScheduleTime, previous.Status.LastScheduleTime)
+	cronJob.Status.LastSuccessfulTime = laterTime(status.LastSuccessfulTime, previous.Status.LastSuccessfulTime)
+	cronJob.Status.SLO = status.SLO
+	for _, condition := range status.Conditions {
+		meta.SetStatusCondition(&cronJob.Status.Conditions, condition)
+	}
+	if equality.Semantic.DeepEqual(previous.Status, cronJob.Status) {
+		metrics.RecordStatusWrite(metrics.StatusWriteUnchanged)
+		return nil
+	}
+	patch := client.MergeFromWithOptions(previous, client.MergeFromWithOptimisticLock{})
+	if err := w.client.Status().Patch(ctx, &cronJob, patch); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	metrics.RecordStatusWrite(metrics.StatusWriteWritten)
+	return nil
+}
+
+// mergeStatus merges an older status of a CronJob into a newer one: the newer one is kept, except for the times the
+// older one has later and the conditions only the older one has.
+func mergeStatus(newer, older *v1.CronJobStatus) {
+	newer.LastScheduleTime = laterTime(newer.LastScheduleTime, older.LastScheduleTime)
+	newer.LastSuccessfulTime = laterTime(newer.LastSuccessfulTime, older.LastSuccessfulTime)
+	for _, condition := range older.Conditions {
+		if meta.FindStatusCondition(newer.Conditions, condition.Type) == nil {
+			newer.Conditions = append(newer.Conditions, condition)
+		}
+	}
+}
+
+// laterTime returns the later of the times, nil ones being the earliest.
+func laterTime(a, b *metav1.Time) *metav1.Time {
+	if a == nil || (b != nil && b.After(a.Time)) {
+		return b
+	}
+	return a
+}
+
+// Start implements manager.Runnable, it keeps the statuses of the previous leader, and writes the pending statuses once
+// the manager stops.
+func (w *statusWriter) Start(ctx context.Context) error {
+	w.resume(ctx)
+	<-ctx.Done()
+	w.lock.Lock()
+	w.stopped = true
+	pending := w.pending
+	w.pending = map[types.NamespacedName]*v1.CronJobStatus{}
+	w.lock.Unlock()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var unwritten []unwrittenStatus
+	for key, status := range pending {
+		err := w.flush(flushCtx, key, status)
+		if apierrors.IsConflict(err) {
+			// the CronJob changed since it was read, it is read again
+			err = w.flush(flushCtx, key, status)
+		}
+		if err != nil {
+			statusWriterLog.Error(err, "unable to update CronJob status", "cronjob", key)
+			unwritten = append(unwritten, unwrittenStatus{CronJob: key, Status: *status})
+		}
+	}
+	w.handOver(unwritten)
+	return nil
+}
+
+// resume keeps the statuses saved by the previous leader for the next debounce.
+func (w *statusWriter) resume(ctx context.Context) {
+	if w.handover == nil {
+		return
+	}
+	var unwritten []unwrittenStatus
+	found, err := w.handover.Take(ctx, statusesHandoverKey, &unwritten)
+	if err != nil {
+		statusWriterLog.Error(err, "unable to resume the statuses of the previous leader")
+		return
+	}
+	if !found {
+		return
+	}
+	for i := range unwritten {
+		w.requeue(unwritten[i].CronJob, &unwritten[i].Status)
+	}
+	statusWriterLog.Info("resumed the statuses of the previous leader", "statuses", len(unwritten))
+}
+
+// handOver saves the statuses which could not be written for the next leader.
+func (w *statusWriter) handOver(unwritten []unwrittenStatus) {
+	if w.handover == nil || len(unwritten) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := w.handover.Save(ctx, statusesHandoverKey, unwritten); err != nil {
+		statusWriterLog.Error(err, "unable to hand the unwritten statuses over", "statuses", len(unwritten))
+		return
+	}
+	statusWriterLog.Info("handed the unwritten statuses over to the next leader", "statuses", len(unwritten))
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the leader reconciles the CronJobs.
+func (w *statusWriter) NeedLeaderElection() bool {
+	return true
+}
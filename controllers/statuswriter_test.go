@@ -0,0 +1,191 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/handover"
+)
+
+// patchCountingClient counts the status patches of a client.
+type patchCountingClient struct {
+	client.Client
+	patches int
+}
+
+func (c *patchCountingClient) Status() client.StatusWriter {
+	return &patchCountingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type patchCountingStatusWriter struct {
+	client.StatusWriter
+	client *patchCountingClient
+}
+
+func (w *patchCountingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch,
+	opts ...client.PatchOption) error {
+	w.client.patches++
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("statusWriter", func() {
+	var (
+		ctx     context.Context
+		c       *patchCountingClient
+		writer  *statusWriter
+		cronJob *v1.CronJob
+		key     client.ObjectKey
+		earlier metav1.Time
+		later   metav1.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		earlier = metav1.NewTime(time.Date(2021, time.June, 1, 10, 0, 0, 0, time.UTC))
+		later = metav1.NewTime(earlier.Add(time.Hour))
+		cronJob = &v1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly"}}
+		key = client.ObjectKeyFromObject(cronJob)
+		c = &patchCountingClient{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cronJob).Build()}
+		writer = newStatusWriter(c, c, 50*time.Millisecond, nil)
+	})
+
+	written := func() v1.CronJobStatus {
+		var cronJob v1.CronJob
+		Expect(c.Get(ctx, key, &cronJob)).To(Succeed())
+		return cronJob.Status
+	}
+
+	It("Should write the statuses kept within the debounce once", func() {
+		first := cronJob.DeepCopy()
+		first.Status.Active = []corev1.ObjectReference{{Name: "nightly-1"}}
+		Expect(writer.write(ctx, first)).To(Succeed())
+		second := cronJob.DeepCopy()
+		second.Status.Active = []corev1.ObjectReference{{Name: "nightly-1"}, {Name: "nightly-2"}}
+		Expect(writer.write(ctx, second)).To(Succeed())
+		Expect(written().Active).To(BeEmpty())
+
+		Eventually(func() []corev1.ObjectReference { return written().Active }).Should(HaveLen(2))
+		Consistently(func() int { return c.patches }, 200*time.Millisecond).Should(Equal(1))
+	})
+
+	It("Should write the conditions set by a burst of reconciles once", func() {
+		r := &CronJobReconciler{Client: c, statusWriter: writer}
+		reconciled := cronJob.DeepCopy()
+		Expect(r.setMissedSchedule(ctx, reconciled, metav1.ConditionTrue, "Missed", "missed a run")).To(Succeed())
+		Expect(r.setTooManyMissedStarts(ctx, reconciled, true)).To(Succeed())
+		Expect(r.setReconciled(ctx, reconciled, errors.New("unable to list the Jobs"))).To(Succeed())
+		// a later reconcile, on a cache which does not have the conditions yet
+		reconciled = cronJob.DeepCopy()
+		Expect(r.setMissedSchedule(ctx, reconciled, metav1.ConditionFalse, "OnSchedule", "ran on time")).To(Succeed())
+		Expect(r.setReconciled(ctx, reconciled, nil)).To(Succeed())
+		Expect(written().Conditions).To(BeEmpty())
+
+		Eventually(func() []metav1.Condition { return written().Conditions }).Should(HaveLen(3))
+		Consistently(func() int { return c.patches }, 200*time.Millisecond).Should(Equal(1))
+		conditions := written().Conditions
+		Expect(meta.IsStatusConditionFalse(conditions, missedScheduleCondition)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(conditions, tooManyMissedStartsCondition)).To(BeTrue())
+		Expect(meta.FindStatusCondition(conditions, reconciledCondition).Reason).To(Equal("ReconcileSucceeded"))
+	})
+
+	It("Should keep the later times of the statuses it merges", func() {
+		newer := cronJob.DeepCopy()
+		newer.Status.LastSuccessfulTime = &later
+		newer.Status.LastScheduleTime = &later
+		Expect(writer.write(ctx, newer)).To(Succeed())
+		// a reconcile on a stale cache, after the history limit deleted the successful Job
+		stale := cronJob.DeepCopy()
+		stale.Status.LastSuccessfulTime = &earlier
+		stale.Status.Active = []corev1.ObjectReference{{Name: "nightly-2"}}
+		Expect(writer.write(ctx, stale)).To(Succeed())
+
+		Eventually(func() []corev1.ObjectReference { return written().Active }).Should(HaveLen(1))
+		status := written()
+		Expect(status.LastSuccessfulTime.Time).To(BeTemporally("==", later.Time))
+		Expect(status.LastScheduleTime.Time).To(BeTemporally("==", later.Time))
+	})
+
+	It("Should not move the times of the CronJob back", func() {
+		cronJob.Status.LastSuccessfulTime = &later
+		Expect(c.Status().Update(ctx, cronJob)).To(Succeed())
+		stale := cronJob.DeepCopy()
+		stale.Status.LastSuccessfulTime = &earlier
+		Expect(writer.flush(ctx, key, &stale.Status)).To(Succeed())
+		Expect(written().LastSuccessfulTime.Time).To(BeTemporally("==", later.Time))
+	})
+
+	It("Should keep the status for the next debounce when the CronJob changed in between", func() {
+		var read v1.CronJob
+		Expect(c.Get(ctx, key, &read)).To(Succeed())
+		// the CronJob changes after the writer read it
+		writer.reader = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(read.DeepCopy()).Build()
+		read.Status.Active = []corev1.ObjectReference{{Name: "nightly-1"}}
+		Expect(c.Status().Update(ctx, &read)).To(Succeed())
+
+		status := cronJob.Status.DeepCopy()
+		status.LastScheduleTime = &later
+		err := writer.flush(ctx, key, status)
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+		Expect(written().LastScheduleTime).To(BeNil())
+
+		// the debounce is left to the spec
+		writer.debounce = time.Hour
+		writer.pending[key] = status
+		writer.flushPending(key)
+		Expect(writer.pending).To(HaveKey(key))
+
+		writer.reader = c
+		writer.flushPending(key)
+		Expect(writer.pending).NotTo(HaveKey(key))
+		Expect(written().LastScheduleTime).NotTo(BeNil())
+		Expect(written().Active).To(BeEmpty())
+	})
+	It("Should hand the statuses it could not write on shutdown over to the next leader", func() {
+		store := &handover.Store{Client: c, Reader: c, Namespace: "operator-system"}
+		writer.handover = store
+		// the CronJob cannot be read on shutdown
+		writer.reader = fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+		status := cronJob.Status.DeepCopy()
+		status.LastScheduleTime = &later
+		writer.pending[key] = status
+		stopped, cancel := context.WithCancel(ctx)
+		cancel()
+		Expect(writer.Start(stopped)).To(Succeed())
+		Expect(written().LastScheduleTime).To(BeNil())
+
+		next := newStatusWriter(c, c, time.Hour, store)
+		next.resume(ctx)
+		Expect(next.pending).To(HaveKey(key))
+		next.flushPending(key)
+		Expect(written().LastScheduleTime.Time).To(BeTemporally("==", later.Time))
+	})
+})
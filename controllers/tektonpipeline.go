@@ -0,0 +1,89 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+/*
+The TektonPipeline runner creates a Tekton PipelineRun from a Pipeline. A PipelineRun reports its progress in its
+Succeeded condition: Unknown while it runs, then True or False, with the reason of the failure, e.g. a timeout or a
+cancellation.
+*/
+
+//+kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=get;list;watch;create;patch;delete
+
+var tektonPipelineRunner = &externalRunner{
+	gvk:    schema.GroupVersionKind{Group: "tekton.dev", Version: "v1beta1", Kind: "PipelineRun"},
+	spec:   tektonPipelineRunSpec,
+	status: tektonPipelineRunStatus,
+}
+
+// tektonPipelineRunSpec returns the spec of the PipelineRun of a run.
+func tektonPipelineRunSpec(cronJob *v1.CronJob) map[string]interface{} {
+	spec := cronJob.Spec.TektonPipeline
+	if spec == nil {
+		return nil
+	}
+	runSpec := map[string]interface{}{"pipelineRef": map[string]interface{}{"name": spec.PipelineRef.Name}}
+	if len(spec.Params) > 0 {
+		var params []interface{}
+		for _, p := range spec.Params {
+			params = append(params, map[string]interface{}{"name": p.Name, "value": p.Value})
+		}
+		runSpec["params"] = params
+	}
+	if spec.ServiceAccountName != "" {
+		runSpec["serviceAccountName"] = spec.ServiceAccountName
+	}
+	return runSpec
+}
+
+// tektonPipelineRunStatus sets the status of the Job from the Succeeded condition of the PipelineRun.
+func tektonPipelineRunStatus(pipelineRun *unstructured.Unstructured, job *kbatch.Job) {
+	job.Status.StartTime = statusTime(pipelineRun, "startTime")
+
+	conditions, _, _ := unstructured.NestedSlice(pipelineRun.Object, "status", "conditions")
+	for _, c := range conditions {
+		succeeded, ok := c.(map[string]interface{})
+		if !ok || succeeded["type"] != "Succeeded" {
+			continue
+		}
+		reason, _, _ := unstructured.NestedString(succeeded, "reason")
+		message, _, _ := unstructured.NestedString(succeeded, "message")
+		condition := kbatch.JobCondition{Status: corev1.ConditionTrue, Reason: reason, Message: message}
+		switch succeeded["status"] {
+		case "True":
+			condition.Type = kbatch.JobComplete
+		case "False":
+			condition.Type = kbatch.JobFailed
+		default:
+			return
+		}
+		if completionTime := statusTime(pipelineRun, "completionTime"); completionTime != nil {
+			condition.LastTransitionTime = *completionTime
+			job.Status.CompletionTime = completionTime
+		}
+		job.Status.Conditions = []kbatch.JobCondition{condition}
+		return
+	}
+}
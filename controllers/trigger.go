@@ -0,0 +1,131 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/audit"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+	kbatch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+A run can also be started on demand, outside of the schedule, e.g. through the gRPC service. Its Job is built from the
+template of the CronJob like the one of a scheduled run and owned by the CronJob, so the run gets its JobRun and counts
+in the active Jobs and the history limits. Its scheduled time is the time it was triggered, but the CronJob controller
+skips the manual runs when it rebuilds the last schedule time, so they never shift the schedule. The Forbid
+concurrency policy and the CronJobQuotas apply like to the scheduled runs, a manual run never replaces the active Jobs.
+Who started the run is recorded in an annotation of its Job, and in its JobRun. With an external runner, the run is
+the object of the runner, returned seen as a Job.
+*/
+
+// triggeredByAnnotation is the caller which started a manual run.
+const triggeredByAnnotation = "batch.example.com/triggered-by"
+
+// RunDeniedError tells why a manual run was not started.
+type RunDeniedError struct {
+	Reason string
+}
+
+func (e *RunDeniedError) Error() string {
+	return "run denied: " + e.Reason
+}
+
+// TriggerRun starts a run of the CronJob now on behalf of the caller, and returns its Job. The Job gets a generated
+// name, so every call starts a new run.
+func TriggerRun(ctx context.Context, c client.Client, scheme *runtime.Scheme, cronJob *v1.CronJob, now time.Time,
+	triggeredBy string) (*kbatch.Job, error) {
+	deny := func(reason string, inputs map[string]interface{}) error {
+		inputs["triggeredBy"] = triggeredBy
+		auditRun(audit.TriggerDenied, cronJob, now, "", reason, inputs)
+		return &RunDeniedError{Reason: reason}
+	}
+	if denied := runnerDenied(cronJob); denied != "" {
+		return nil, deny(denied, map[string]interface{}{})
+	}
+	runner := runnerOf(cronJob, &JobRunner{})
+	activeRuns, err := listRunsInState(ctx, c, cronJob, &JobRunner{}, runActive)
+	if err != nil {
+		return nil, err
+	}
+	active := len(activeRuns)
+	if cronJob.Spec.ConcurrencyPolicy == v1.ForbidConcurrent && active > 0 {
+		return nil, deny(fmt.Sprintf("the concurrency policy is Forbid and %d Jobs are active", active),
+			map[string]interface{}{"concurrencyPolicy": cronJob.Spec.ConcurrencyPolicy, "activeJobs": active})
+	}
+	if denied, err := runQuotaDenied(ctx, c, cronJob, 0, now); err != nil {
+		return nil, err
+	} else if denied != "" {
+		return nil, deny(denied, map[string]interface{}{"activeJobs": active})
+	}
+
+	job := &kbatch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:       map[string]string{},
+			Annotations:  map[string]string{},
+			GenerateName: cronJob.Name + "-",
+			Namespace:    cronJob.Namespace,
+		},
+		Spec: *cronJob.Spec.JobTemplate.Spec.DeepCopy(),
+	}
+	for k, v := range cronJob.Spec.JobTemplate.Annotations {
+		job.Annotations[k] = v
+	}
+	job.Annotations[scheduledTimeAnnotation] = now.UTC().Format(time.RFC3339)
+	job.Annotations[managedByVersionAnnotation] = version.Get().Version
+	job.Annotations[triggerAnnotation] = string(v1.ManualTrigger)
+	if triggeredBy != "" {
+		job.Annotations[triggeredByAnnotation] = triggeredBy
+	}
+	for k, v := range cronJob.Spec.JobTemplate.Labels {
+		job.Labels[k] = v
+	}
+	setRunID(job, manualRunID())
+	if err := ctrl.SetControllerReference(cronJob, job, scheme); err != nil {
+		return nil, err
+	}
+
+	if err := runner.CreateRun(ctx, c, cronJob, job); err != nil {
+		return nil, err
+	}
+	metrics.RecordJobCreated(job.Namespace)
+	auditRun(audit.TriggerAccepted, cronJob, now, job.Name, "",
+		map[string]interface{}{"runID": job.Labels[runIDLabel], "triggeredBy": triggeredBy, "activeJobs": active})
+	return job, nil
+}
+
+// SuspendCronJob suspends or resumes the CronJob, and returns whether it changed.
+func SuspendCronJob(ctx context.Context, c client.Client, cronJob *v1.CronJob, suspend bool) (bool, error) {
+	if suspended := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend; suspended == suspend {
+		return false, nil
+	}
+	patch := client.MergeFrom(cronJob.DeepCopy())
+	cronJob.Spec.Suspend = &suspend
+	if err := c.Patch(ctx, cronJob, patch); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/diagnostics"
+)
+
+/*
+The reconciler remembers when every CronJob is due next, so the diagnostics can tell why a run did not start yet.
+The table only holds the CronJobs reconciled by this replica, the standbys have an empty one.
+*/
+
+// wakeupTable holds the next scheduled run of the CronJobs.
+type wakeupTable struct {
+	lock    sync.RWMutex
+	wakeups map[types.NamespacedName]time.Time
+}
+
+func (t *wakeupTable) set(key types.NamespacedName, nextRun time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.wakeups == nil {
+		t.wakeups = map[types.NamespacedName]time.Time{}
+	}
+	t.wakeups[key] = nextRun
+}
+
+func (t *wakeupTable) delete(key types.NamespacedName) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.wakeups, key)
+}
+
+// Wakeups implements diagnostics.WakeupSource
+func (r *CronJobReconciler) Wakeups() []diagnostics.Wakeup {
+	r.wakeups.lock.RLock()
+	defer r.wakeups.lock.RUnlock()
+
+	wakeups := make([]diagnostics.Wakeup, 0, len(r.wakeups.wakeups))
+	for key, nextRun := range r.wakeups.wakeups {
+		wakeups = append(wakeups, diagnostics.Wakeup{CronJob: key.String(), NextRun: nextRun})
+	}
+	return wakeups
+}
@@ -0,0 +1,353 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/workflow"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+/*
+The WorkflowReconciler runs the Workflows. It works like the CronJobReconciler: the runs are rebuilt from the Jobs of
+the Workflow on every reconcile, and a Job changing wakes the Workflow up. The Jobs of a run carry its scheduled time,
+their node and their attempt, and have deterministic names, so a new leader never creates the same Job twice.
+
+The workflow package decides the next nodes of a run, the controller only creates their Jobs.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=workflows,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=workflows/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+
+const (
+	// workflowOwnerKey indexes the Jobs by the name of their Workflow.
+	workflowOwnerKey = ".metadata.workflow"
+	// workflowNodeLabel is the node of a Job of a Workflow.
+	workflowNodeLabel = "batch.example.com/workflow-node"
+	// workflowAttemptAnnotation is the attempt of the node of a Job of a Workflow, starting at 1.
+	workflowAttemptAnnotation = "batch.example.com/workflow-attempt"
+	// workflowValidCondition is false when the DAG of the Workflow can not be run.
+	workflowValidCondition = "Valid"
+	// workflowErrorReportingComponent is the component of the errors reported by the workflow controller.
+	workflowErrorReportingComponent = "workflow-controller"
+
+	defaultSuccessfulRunsHistoryLimit = 3
+	defaultFailedRunsHistoryLimit     = 1
+)
+
+// WorkflowReconciler runs the DAGs of the Workflows on their schedule.
+type WorkflowReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Clock
+	// ErrorReporter reports the panics and the repeated errors of the reconciles, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+}
+
+// workflowRun is a run of a Workflow, rebuilt from its Jobs.
+type workflowRun struct {
+	scheduledTime time.Time
+	states        map[string]workflow.NodeState
+	jobs          []*kbatch.Job
+	phase         v1.WorkflowRunPhase
+	nodes         []v1.WorkflowNodeStatus
+	start         []string
+}
+
+// Reconcile advances the latest run of the Workflow, and starts a new one when it is scheduled.
+func (r *WorkflowReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	defer func() { r.ErrorReporter.ReconcileResult(workflowErrorReportingComponent, req.NamespacedName, err) }()
+	defer r.ErrorReporter.Recover(workflowErrorReportingComponent, req.NamespacedName)
+	logger := log.FromContext(ctx)
+
+	var wf v1.Workflow
+	if err := r.Get(ctx, req.NamespacedName, &wf); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	/*
+		The CRD can not check the dependencies, so an invalid DAG is reported in the conditions and nothing runs
+		until the Workflow is fixed.
+	*/
+	if err := workflow.Validate(wf.Spec.Nodes); err != nil {
+		logger.Error(err, "invalid Workflow")
+		meta.SetStatusCondition(&wf.Status.Conditions, metav1.Condition{Type: workflowValidCondition,
+			Status: metav1.ConditionFalse, Reason: "InvalidDAG", Message: err.Error(),
+			ObservedGeneration: wf.Generation})
+		return ctrl.Result{}, r.Status().Update(ctx, &wf)
+	}
+
+	var childJobs kbatch.JobList
+	if err := r.List(ctx, &childJobs, client.InNamespace(req.Namespace),
+		client.MatchingFields{workflowOwnerKey: req.Name}); err != nil {
+		logger.Error(err, "unable to list child Jobs")
+		return ctrl.Result{}, err
+	}
+	runs := r.workflowRuns(ctx, &wf, childJobs.Items)
+
+	var latest *workflowRun
+	if len(runs) > 0 {
+		latest = runs[len(runs)-1]
+		wf.Status.LastScheduleTime = &metav1.Time{Time: latest.scheduledTime}
+		wf.Status.LastRun = &v1.WorkflowRun{ScheduledTime: metav1.Time{Time: latest.scheduledTime},
+			Phase: latest.phase, Nodes: latest.nodes}
+	}
+	meta.SetStatusCondition(&wf.Status.Conditions, metav1.Condition{Type: workflowValidCondition,
+		Status: metav1.ConditionTrue, Reason: "ValidDAG", ObservedGeneration: wf.Generation})
+	if err := r.Status().Update(ctx, &wf); err != nil {
+		logger.Error(err, "unable to update Workflow status")
+		return ctrl.Result{}, err
+	}
+
+	// the nodes ready in the latest run start first, the Jobs changing wake the Workflow up for the next ones
+	if latest != nil && latest.phase == v1.WorkflowRunRunning {
+		if err := r.startNodes(ctx, &wf, latest); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	r.deleteOldRuns(ctx, &wf, runs)
+
+	if wf.Spec.Suspend != nil && *wf.Spec.Suspend {
+		logger.V(1).Info("workflow suspended, skipping")
+		return ctrl.Result{}, nil
+	}
+
+	missedRun, capped, nextRun, err := nextSchedule(wf.Spec.Schedule, wf.Status.LastScheduleTime, wf.CreationTimestamp,
+		wf.Spec.StartingDeadlineSeconds, r.Now())
+	if err != nil {
+		logger.Error(err, "unable to figure out Workflow schedule")
+		return ctrl.Result{}, nil
+	}
+	if capped {
+		logger.Info("too many missed start times, only the latest one is considered", "run", missedRun)
+		metrics.RecordMissedStartsCapped(metrics.ControllerWorkflow)
+	}
+	scheduledResult := ctrl.Result{RequeueAfter: nextRun.Sub(r.Now())}
+	if missedRun.IsZero() {
+		return scheduledResult, nil
+	}
+
+	if tooLate(missedRun, wf.Spec.StartingDeadlineSeconds, r.Now()) {
+		logger.V(1).Info("missed starting deadline for last run, sleeping till next")
+		return scheduledResult, nil
+	}
+	if latest != nil && latest.phase == v1.WorkflowRunRunning {
+		logger.V(1).Info("run in progress, skipping the scheduled run", "scheduledTime", missedRun)
+		return scheduledResult, nil
+	}
+
+	run := &workflowRun{scheduledTime: missedRun}
+	run.phase, run.nodes, run.start = workflow.Step(wf.Spec.Nodes, nil)
+	if err := r.startNodes(ctx, &wf, run); err != nil {
+		return ctrl.Result{}, err
+	}
+	logger.V(1).Info("started Workflow run", "scheduledTime", missedRun)
+	return scheduledResult, nil
+}
+
+// workflowRuns groups the Jobs of the Workflow by run and steps each run, the runs are sorted by scheduled time.
+func (r *WorkflowReconciler) workflowRuns(ctx context.Context, wf *v1.Workflow, jobs []kbatch.Job) []*workflowRun {
+	logger := log.FromContext(ctx)
+	byTime := map[time.Time]*workflowRun{}
+	for i := range jobs {
+		job := &jobs[i]
+		scheduledTime, err := time.Parse(time.RFC3339, job.Annotations[scheduledTimeAnnotation])
+		if err != nil {
+			logger.Error(err, "unable to parse schedule time for child job", "job", job)
+			continue
+		}
+		attempt, err := strconv.Atoi(job.Annotations[workflowAttemptAnnotation])
+		if err != nil {
+			logger.Error(err, "unable to parse the attempt of child job", "job", job)
+			continue
+		}
+
+		run, ok := byTime[scheduledTime]
+		if !ok {
+			run = &workflowRun{scheduledTime: scheduledTime, states: map[string]workflow.NodeState{}}
+			byTime[scheduledTime] = run
+		}
+		run.jobs = append(run.jobs, job)
+
+		node := job.Labels[workflowNodeLabel]
+		if state := run.states[node]; int32(attempt) > state.Attempts {
+			finished, finishedType := jobFinished(job)
+			run.states[node] = workflow.NodeState{Attempts: int32(attempt), JobName: job.Name, Finished: finished,
+				Succeeded: finishedType == kbatch.JobComplete}
+		}
+	}
+
+	runs := make([]*workflowRun, 0, len(byTime))
+	for _, run := range byTime {
+		run.phase, run.nodes, run.start = workflow.Step(wf.Spec.Nodes, run.states)
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].scheduledTime.Before(runs[j].scheduledTime) })
+	return runs
+}
+
+// startNodes creates the Jobs of the nodes ready to start in the run.
+func (r *WorkflowReconciler) startNodes(ctx context.Context, wf *v1.Workflow, run *workflowRun) error {
+	logger := log.FromContext(ctx)
+	for _, name := range run.start {
+		var node *v1.WorkflowNode
+		for i := range wf.Spec.Nodes {
+			if wf.Spec.Nodes[i].Name == name {
+				node = &wf.Spec.Nodes[i]
+			}
+		}
+		job, err := r.constructJob(wf, node, run.scheduledTime, run.states[name].Attempts+1)
+		if err != nil {
+			logger.Error(err, "unable to construct job from template", "node", name)
+			return err
+		}
+		if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+			logger.Error(err, "unable to create Job for Workflow node", "job", job)
+			return err
+		}
+		logger.V(1).Info("created Job for Workflow node", "job", job)
+	}
+	return nil
+}
+
+// constructJob returns the Job of an attempt of the node in the run.
+func (r *WorkflowReconciler) constructJob(wf *v1.Workflow, node *v1.WorkflowNode, scheduledTime time.Time,
+	attempt int32) (*kbatch.Job, error) {
+	job := &kbatch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      make(map[string]string),
+			Annotations: make(map[string]string),
+			Name:        fmt.Sprintf("%s-%d-%s-%d", wf.Name, scheduledTime.Unix(), node.Name, attempt),
+			Namespace:   wf.Namespace,
+		},
+		Spec: *node.JobTemplate.Spec.DeepCopy(),
+	}
+
+	for k, v := range node.JobTemplate.Annotations {
+		job.Annotations[k] = v
+	}
+	job.Annotations[scheduledTimeAnnotation] = scheduledTime.Format(time.RFC3339)
+	job.Annotations[workflowAttemptAnnotation] = strconv.Itoa(int(attempt))
+	job.Annotations[managedByVersionAnnotation] = version.Get().Version
+
+	for k, v := range node.JobTemplate.Labels {
+		job.Labels[k] = v
+	}
+	job.Labels[workflowNodeLabel] = node.Name
+	setRunID(job, scheduledRunID(wf.UID, scheduledTime))
+
+	if err := ctrl.SetControllerReference(wf, job, r.Scheme); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// deleteOldRuns deletes the Jobs of the finished runs beyond the history limits of the Workflow.
+func (r *WorkflowReconciler) deleteOldRuns(ctx context.Context, wf *v1.Workflow, runs []*workflowRun) {
+	logger := log.FromContext(ctx)
+	keep := map[v1.WorkflowRunPhase]int32{
+		v1.WorkflowRunSucceeded: defaultSuccessfulRunsHistoryLimit,
+		v1.WorkflowRunFailed:    defaultFailedRunsHistoryLimit,
+	}
+	if limit := wf.Spec.SuccessfulRunsHistoryLimit; limit != nil {
+		keep[v1.WorkflowRunSucceeded] = *limit
+	}
+	if limit := wf.Spec.FailedRunsHistoryLimit; limit != nil {
+		keep[v1.WorkflowRunFailed] = *limit
+	}
+
+	// the newest runs are kept, so the runs are walked from the newest one
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if run.phase == v1.WorkflowRunRunning {
+			continue
+		}
+		if keep[run.phase] > 0 {
+			keep[run.phase]--
+			continue
+		}
+		for _, job := range run.jobs {
+			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+				logger.Error(err, "unable to delete old workflow job", "job", job)
+			} else {
+				logger.V(0).Info("deleted old workflow job", "job", job)
+			}
+		}
+	}
+}
+
+// jobFinished returns whether the Job is finished, and how.
+func jobFinished(job *kbatch.Job) (bool, kbatch.JobConditionType) {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == kbatch.JobComplete || c.Type == kbatch.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true, c.Type
+		}
+	}
+	return false, ""
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WorkflowReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), jobObject(), workflowOwnerKey,
+		func(rawObj client.Object) []string {
+			owner := metav1.GetControllerOf(rawObj)
+			if owner == nil || owner.APIVersion != apiGVStr || owner.Kind != "Workflow" {
+				return nil
+			}
+			return []string{owner.Name}
+		}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.Workflow{}).
+		Owns(jobObject()).
+		Complete(r)
+}
+
+// Permissions returns the permissions the controller needs in the watched namespaces, all of them if none.
+func (r *WorkflowReconciler) Permissions(namespaces []string) []startup.Permission {
+	group := v1.GroupVersion.Group
+	permissions := startup.Permissions(group, "workflows", []string{"get", "list", "watch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions(group, "workflows/status", []string{"update"},
+		namespaces...)...)
+	return append(permissions, startup.Permissions("batch", "jobs",
+		[]string{"get", "list", "watch", "create", "delete"}, namespaces...)...)
+}
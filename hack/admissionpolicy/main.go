@@ -0,0 +1,32 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// admissionpolicy writes the ValidatingAdmissionPolicy of config/admission-policy, see `make admission-policy`.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/admissionpolicy"
+)
+
+func main() {
+	if err := admissionpolicy.Generate(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
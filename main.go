@@ -41,22 +41,72 @@ import (
 */
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
-	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"fmt"
+	"net/http"
 	"os"
-	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sort"
+	"strings"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	cliflag "k8s.io/component-base/cli/flag"
+	componentconfigv1alpha1 "k8s.io/component-base/config/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	kubeconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/config/crd"
+	webhookmanifests "github.com/bilalcaliskan/kubebuilder-tutorial/config/webhook"
 	"github.com/bilalcaliskan/kubebuilder-tutorial/controllers"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/archive"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/audit"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/certrotation"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/cloudevents"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/config"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/dashboard"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/diagnostics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/dryrun"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/grpcapi"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/handover"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/httptrigger"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/jobcache"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/leaderstatus"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/loglevel"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metricsserver"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/notification"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/operatorstatus"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/pushgateway"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/restapi"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/simulation"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/tracing"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/webhookserver"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/webhooks"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	// Embed the time zone database, so the time zones of the CronJobs resolve in images without one.
+	_ "time/tzdata"
+
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
@@ -116,70 +166,1030 @@ func main() {
 	var configFile string
 	flag.StringVar(&configFile, "config", "config/manager/controller_manager_config.yaml",
 		"The controller will load its initial configuration from this file. Omit this flag to use the "+
-			"default configuration values. Command-line flags override configuration from this file.")
+			"default configuration values. Command-line flags override configuration from this file. A comma "+
+			"separated list of files and directories is merged in order, the later files override the earlier ones.")
 
-	opts := zap.Options{
-		Development: true,
-	}
+	// The config files can be checked in CI before they are shipped, without starting the manager.
+	var validateConfig bool
+	flag.BoolVar(&validateConfig, "validate-config", false,
+		"Validate the config files, with the flags and the environment variables applied, print a JSON report "+
+			"and exit. Exits with 1 if the config is invalid.")
+
+	// The schedules can be checked without running them.
+	var simulate bool
+	var simulateFiles string
+	var simulateHorizon time.Duration
+	flag.BoolVar(&simulate, "simulate", false,
+		"Print the activations of the CronJobs over --simulate-horizon and exit, without writing to the cluster.")
+	flag.StringVar(&simulateFiles, "simulate-files", "",
+		"Comma-separated list of YAML or JSON files, or directories of them, holding the CronJobs to simulate. "+
+			"Defaults to the CronJobs of the watched namespaces in the cluster.")
+	flag.DurationVar(&simulateHorizon, "simulate-horizon", 24*time.Hour, "How far ahead the activations are simulated.")
+
+	var strictConfig bool
+	flag.BoolVar(&strictConfig, "strict-config", true,
+		"Reject the unknown fields and the invalid values of the config file, instead of ignoring them.")
+
+	// The cache, and so the controller, can be scoped to a set of namespaces instead of the whole cluster.
+	var namespace, watchNamespaces string
+	flag.StringVar(&namespace, "namespace", "",
+		"Only watch and reconcile the CronJobs of this namespace. Shorthand for a single --watch-namespaces entry.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma separated list of the namespaces to watch and reconcile. Overrides watchNamespaces of the config file. "+
+			"All namespaces are watched if neither is set.")
+
+	// The runnables get this long to stop on shutdown, it should be shorter than the termination grace period of the pod.
+	var gracefulShutdownTimeout time.Duration
+	flag.DurationVar(&gracefulShutdownTimeout, "graceful-shutdown-timeout", 0,
+		"How long the controllers get to finish their work on shutdown. Overrides gracefulShutDown of the config "+
+			"file. Defaults to 25s.")
+
+	// The plain HTTP metrics endpoint and the health probes, which the default deployment patches set.
+	var metricsAddr, probeAddr string
+	flag.StringVar(&metricsAddr, "metrics-bind-address", "",
+		"The address the metrics endpoint binds to, 0 disables it. Overrides metrics.bindAddress of the config "+
+			"file. Defaults to :8080.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", "",
+		"The address the health probe endpoint binds to, 0 disables it. Overrides health.healthProbeBindAddress of "+
+			"the config file.")
+
+	// The metrics can be served over TLS to the authorized clients only, without kube-rbac-proxy.
+	var secureMetrics bool
+	var secureMetricsAddr, metricsCertDir string
+	flag.BoolVar(&secureMetrics, "secure-metrics", false,
+		"Serve the metrics over TLS with authentication and authorization, instead of plain HTTP.")
+	flag.StringVar(&secureMetricsAddr, "secure-metrics-bind-address", "",
+		"The address the secure metrics endpoint binds to. Defaults to :8443.")
+	flag.StringVar(&metricsCertDir, "metrics-cert-dir", "",
+		"Directory holding the tls.crt and tls.key of the secure metrics endpoint. "+
+			"A self-signed certificate is generated if empty.")
+
+	// The controllers which are not used in a cluster can be switched off, like the ones of kube-controller-manager.
+	var controllerSelection string
+	flag.StringVar(&controllerSelection, "controllers", "",
+		"Comma separated list of the controllers to run: '*' enables the controllers which are on by default, 'foo' "+
+			"enables the controller named foo and '-foo' disables it. Overrides controllers of the config file. "+
+			"Known controllers: "+strings.Join(config.KnownControllers(), ", "))
+
+	// The experimental features are enabled per cluster, on top of the featureGates of the config file.
+	var featureGates map[string]bool
+	flag.Var(cliflag.NewMapStringBool(&featureGates), "feature-gates",
+		"Comma separated Name=true|false pairs enabling or disabling the experimental features. Overrides "+
+			"featureGates of the config file. Known features: "+strings.Join(featuregates.Gates.KnownFeatures(), ", "))
+
+	/*
+		The leader election lock can be moved to another resource, namespace or name, since some managed clusters
+		restrict which resources the operators may use for locking.
+	*/
+	var leaderElect bool
+	var leaderElectionResourceLock, leaderElectionNamespace, leaderElectionID string
+	flag.BoolVar(&leaderElect, "leader-elect", false,
+		"Enable leader election, so that only one replica runs the controllers. Overrides "+
+			"leaderElection.leaderElect of the config file.")
+	flag.StringVar(&leaderElectionResourceLock, "leader-election-resource-lock", "",
+		"Kind of the leader election lock, leases, configmapsleases, endpointsleases, configmaps or endpoints. "+
+			"Overrides leaderElection.resourceLock of the config file. Defaults to configmapsleases.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election lock. Overrides leaderElection.resourceNamespace of the config file. "+
+			"Defaults to the namespace of the pod.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "",
+		"Name of the leader election lock. Overrides leaderElection.resourceName of the config file.")
+
+	// The webhooks are served by every replica, so the standbys are only reported unready when asked for.
+	var leaderReadiness bool
+	flag.BoolVar(&leaderReadiness, "leader-readiness", false,
+		"Report the replicas which do not hold the leader election lease as not ready on /readyz. Only enable it "+
+			"when the webhooks are served by another deployment, the standbys stop receiving the admission requests.")
+
+	/*
+		Out of the cluster, the manager connects to the current context of the kubeconfig. The --kubeconfig flag is
+		registered by controller-runtime, the context can be switched without touching the kubeconfig, e.g.
+		`go run ./main.go --context kind-test`.
+	*/
+	var kubeContext string
+	var insecureSkipTLSVerify bool
+	flag.StringVar(&kubeContext, "context", "",
+		"The kubeconfig context to connect to. Defaults to the current context of the kubeconfig.")
+	flag.BoolVar(&insecureSkipTLSVerify, "insecure-skip-tls-verify", false,
+		"Do not verify the certificate of the API server. Only meant for the local development clusters.")
+
+	// The client defaults to 20 requests per second, which throttles the reconciles of large clusters.
+	var kubeAPIQPS float64
+	var kubeAPIBurst int
+	var kubeAPITimeout time.Duration
+	var userAgent string
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", 0,
+		"Requests per second to the API server. Overrides client.qps of the config file. Defaults to 20.")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", 0,
+		"Requests allowed above --kube-api-qps for short periods. Overrides client.burst of the config file. "+
+			"Defaults to 30.")
+	flag.DurationVar(&kubeAPITimeout, "kube-api-timeout", 0,
+		"Timeout of the requests to the API server. Overrides client.timeout of the config file. No timeout if unset.")
+	flag.StringVar(&userAgent, "user-agent", "",
+		"User agent of the requests to the API server. Overrides client.userAgent of the config file. Defaults to "+
+			"kubebuilder-tutorial/<version> (<os>/<arch>) <git commit>.")
+
+	// A dry-run instance reconciles the production data without changing it, e.g. to try out a new version.
+	var dryRun bool
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Send every write of the controllers and the webhooks as a dry run, so nothing is persisted. Disables the "+
+			"leader election, the instance runs next to the production one.")
+
+	// The webhooks are served unless they are switched off here or in the config file.
+	var mutatingWebhook, validatingWebhook bool
+	flag.BoolVar(&mutatingWebhook, "enable-mutating-webhook", true,
+		"Serve the defaulting webhook of the CronJobs. Overrides admission.mutatingWebhook of the config file.")
+	flag.BoolVar(&validatingWebhook, "enable-validating-webhook", true,
+		"Serve the validating webhook of the CronJobs. Overrides admission.validatingWebhook of the config file.")
+
+	// The webhook server listens on 9443 and loads tls.crt and tls.key from a temporary directory by default.
+	var webhookPort int
+	var webhookHost, webhookCertDir, webhookCertName, webhookKeyName string
+	flag.IntVar(&webhookPort, "webhook-port", 0,
+		"The port the webhook server listens on. Overrides webhook.port of the config file. Defaults to 9443.")
+	flag.StringVar(&webhookHost, "webhook-host", "",
+		"The address the webhook server binds to. Overrides webhook.host of the config file. Defaults to all the "+
+			"addresses.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "",
+		"Directory holding the serving certificate of the webhook server. Overrides webhook.certDir of the config "+
+			"file. Defaults to <tmp>/k8s-webhook-server/serving-certs.")
+	flag.StringVar(&webhookCertName, "webhook-cert-name", "",
+		"File name of the serving certificate in the certificate directory. Overrides webhookServer.certName of the "+
+			"config file. Defaults to tls.crt.")
+	flag.StringVar(&webhookKeyName, "webhook-key-name", "",
+		"File name of the private key in the certificate directory. Overrides webhookServer.keyName of the config "+
+			"file. Defaults to tls.key.")
+
+	// The TLS settings apply to both the webhook server and the secure metrics endpoint.
+	var tlsMinVersion, tlsCipherSuites string
+	var enableHTTP2 bool
+	flag.StringVar(&tlsMinVersion, "tls-min-version", "",
+		"Minimum TLS version of the webhook server and the metrics endpoint, VersionTLS12 or VersionTLS13. Overrides "+
+			"webhookServer.tls.minVersion and secureMetrics.tls.minVersion of the config file. Defaults to "+
+			"VersionTLS12.")
+	flag.StringVar(&tlsCipherSuites, "tls-cipher-suites", "",
+		"Comma-separated list of the cipher suites allowed up to TLS 1.2. Overrides webhookServer.tls.cipherSuites "+
+			"and secureMetrics.tls.cipherSuites of the config file. Defaults to the ones of Go.")
+	flag.BoolVar(&enableHTTP2, "enable-http2", false,
+		"Serve HTTP/2 on the webhook server and the metrics endpoint. Overrides webhookServer.tls.enableHTTP2 and "+
+			"secureMetrics.tls.enableHTTP2 of the config file.")
+
+	// The API server may still be starting when the manager starts, at the boot of the cluster.
+	startupBackoff := startup.DefaultBackoff
+	flag.DurationVar(&startupBackoff.Timeout, "startup-timeout", startupBackoff.Timeout,
+		"How long the startup steps talking to the API server are retried when it is unavailable, before exiting.")
+
+	// The CRDs can be installed by the manager itself, e.g. for a demo.
+	var installCRDs bool
+	flag.BoolVar(&installCRDs, "install-crds", false,
+		"Apply the CRDs embedded in the binary with server-side apply on startup. Requires the permissions of "+
+			"config/rbac/crd_installer_role.yaml.")
+
+	var certRotation certrotation.Options
+	certRotation.BindFlags(flag.CommandLine)
+
+	var printVersion bool
+	flag.BoolVar(&printVersion, "version", false, "Print the version of the operator and exit.")
+
+	// The logs of a few objects can be raised to the debug level without raising the logs of all the others.
+	var debugObjects string
+	flag.StringVar(&debugObjects, "debug-objects", "",
+		"Comma separated list of the objects whose logs are written from the debug level, as namespace/name or "+
+			"namespace. Overrides logging.debugObjects of the config file.")
+
+	// The logs keep defaulting to the development mode, pass --zap-devel=false or set logging.development to false
+	// for the JSON logs of the production mode.
+	opts := zap.Options{Development: true}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	// The boolean flags which default to true only override the config file when they are given.
+	givenFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		givenFlags[f.Name] = true
+	})
+
+	if printVersion {
+		fmt.Println(version.Get())
+		os.Exit(0)
+	}
 
 	/*
 		Now, we can setup the Options struct and check if the configFile is set, this allows backwards compatibility,
-		if it’s set we’ll then use the AndFrom function on Options to parse and populate the Options from the config.
+		if it’s set we’ll then use config.ManagerOptions to populate the Options from the config.
+
+		The settings are layered: the config file is overridden by the flags, which are overridden by the
+		CRONJOB_OPERATOR_* environment variables. The same overrides are applied when the config file is reloaded.
 	*/
 	var err error
 	options := ctrl.Options{Scheme: scheme}
-	if configFile != "" {
-		options, err = options.AndFrom(ctrl.ConfigFile().AtPath(configFile))
+	overrides := func(c *configv1.ProjectConfig) error {
+		if namespace != "" || watchNamespaces != "" {
+			c.WatchNamespaces = splitList(namespace + "," + watchNamespaces)
+		}
+		if controllerSelection != "" {
+			c.Controllers = splitList(controllerSelection)
+		}
+		if debugObjects != "" {
+			c.Logging.DebugObjects = splitList(debugObjects)
+		}
+		if givenFlags["leader-elect"] || leaderElectionResourceLock != "" || leaderElectionNamespace != "" ||
+			leaderElectionID != "" {
+			if c.LeaderElection == nil {
+				c.LeaderElection = &componentconfigv1alpha1.LeaderElectionConfiguration{}
+			}
+			if givenFlags["leader-elect"] {
+				c.LeaderElection.LeaderElect = &leaderElect
+			}
+			if leaderElectionResourceLock != "" {
+				c.LeaderElection.ResourceLock = leaderElectionResourceLock
+			}
+			if leaderElectionNamespace != "" {
+				c.LeaderElection.ResourceNamespace = leaderElectionNamespace
+			}
+			if leaderElectionID != "" {
+				c.LeaderElection.ResourceName = leaderElectionID
+			}
+		}
+		for name, enabled := range featureGates {
+			if c.FeatureGates == nil {
+				c.FeatureGates = map[string]bool{}
+			}
+			c.FeatureGates[name] = enabled
+		}
+		if gracefulShutdownTimeout > 0 {
+			c.GracefulShutdownTimeout = &metav1.Duration{Duration: gracefulShutdownTimeout}
+		}
+		if kubeAPIQPS > 0 {
+			c.Client.QPS = float32(kubeAPIQPS)
+		}
+		if kubeAPIBurst > 0 {
+			c.Client.Burst = kubeAPIBurst
+		}
+		if kubeAPITimeout > 0 {
+			c.Client.Timeout = &metav1.Duration{Duration: kubeAPITimeout}
+		}
+		if userAgent != "" {
+			c.Client.UserAgent = userAgent
+		}
+		if os.Getenv("ENABLE_WEBHOOKS") == "false" {
+			// the switch of the scaffolding, deprecated in favor of the flags below
+			disabled := false
+			c.Admission.MutatingWebhook, c.Admission.ValidatingWebhook = &disabled, &disabled
+		}
+		if givenFlags["enable-mutating-webhook"] {
+			c.Admission.MutatingWebhook = &mutatingWebhook
+		}
+		if givenFlags["enable-validating-webhook"] {
+			c.Admission.ValidatingWebhook = &validatingWebhook
+		}
+		if webhookPort != 0 {
+			c.Webhook.Port = &webhookPort
+		}
+		if webhookHost != "" {
+			c.Webhook.Host = webhookHost
+		}
+		if webhookCertDir != "" {
+			c.Webhook.CertDir = webhookCertDir
+		}
+		if webhookCertName != "" {
+			c.WebhookServer.CertName = webhookCertName
+		}
+		if webhookKeyName != "" {
+			c.WebhookServer.KeyName = webhookKeyName
+		}
+		for _, tlsConfig := range []*configv1.TLSConfig{&c.WebhookServer.TLS, &c.SecureMetrics.TLS} {
+			if tlsMinVersion != "" {
+				tlsConfig.MinVersion = tlsMinVersion
+			}
+			if tlsCipherSuites != "" {
+				tlsConfig.CipherSuites = splitList(tlsCipherSuites)
+			}
+			if givenFlags["enable-http2"] {
+				tlsConfig.EnableHTTP2 = enableHTTP2
+			}
+		}
+		if metricsAddr != "" {
+			c.Metrics.BindAddress = metricsAddr
+		}
+		if probeAddr != "" {
+			c.Health.HealthProbeBindAddress = probeAddr
+		}
+		c.SecureMetrics.Enabled = c.SecureMetrics.Enabled || secureMetrics
+		if secureMetricsAddr != "" {
+			c.SecureMetrics.BindAddress = secureMetricsAddr
+		}
+		if metricsCertDir != "" {
+			c.SecureMetrics.CertDir = metricsCertDir
+		}
+		if err := config.ApplyEnv(c, os.Environ()); err != nil {
+			return err
+		}
+		if strictConfig {
+			return config.Validate(c).ToAggregate()
+		}
+		return nil
+	}
+
+	configFiles := splitList(configFile)
+	if validateConfig {
+		report := config.Check(configFiles, scheme, overrides)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil || !report.Valid {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// ctrlConfig holds the settings of our own components, which are read from the same file.
+	ctrlConfig := configv1.ProjectConfig{}
+	if len(configFiles) > 0 {
+		load := config.Load
+		if strictConfig {
+			load = config.LoadStrict
+		}
+		loaded, err := load(configFiles, scheme)
 		if err != nil {
+			ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 			setupLog.Error(err, "unable to load the config file")
 			os.Exit(1)
 		}
+		ctrlConfig = *loaded
+	}
+	if err := overrides(&ctrlConfig); err != nil {
+		ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+		setupLog.Error(err, "unable to apply the overrides of the config file")
+		os.Exit(1)
+	}
+
+	/*
+		The logger needs the logging settings of the config file, so it is only set now. The errors above are logged
+		with a logger built from the flags alone. The level is kept in an atomic level, so that it can be changed when
+		the config file is reloaded.
+	*/
+	logger, logLevel, err := config.NewLogger(&opts, ctrlConfig.Logging)
+	if err != nil {
+		ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+		setupLog.Error(err, "unable to set up the logger")
+		os.Exit(1)
+	}
+	ctrl.SetLogger(logger)
+	if ctrlConfig.Audit.Enabled {
+		if file := ctrlConfig.Audit.File; file != nil {
+			audit.SetOutput(config.NewLogFile(file))
+		} else {
+			audit.SetOutput(os.Stdout)
+		}
+	}
+	// The gates are set once, the components check them while they run.
+	exitOnError(featuregates.Gates.SetFromMap(ctrlConfig.FeatureGates), "unable to set the feature gates")
+	setupLog.Info("feature gates", "gates", ctrlConfig.FeatureGates)
+
+	/*
+		The simulation reads the CronJobs from the files, or from the cluster, and prints when they would be
+		activated. It honors the time zones like the controller does, so it runs after the feature gates are set.
+	*/
+	if simulate {
+		var cronJobs []batchv1.CronJob
+		if simulateFiles != "" {
+			cronJobs, err = simulation.ReadCronJobs(splitList(simulateFiles), scheme)
+		} else {
+			var restConfig *rest.Config
+			if restConfig, err = kubeconfig.GetConfigWithContext(kubeContext); err == nil {
+				err = startupBackoff.Retry(context.Background(), "list the CronJobs to simulate", func() error {
+					c, err := client.New(restConfig, client.Options{Scheme: scheme})
+					if err != nil {
+						return err
+					}
+					cronJobs, err = simulation.ListCronJobs(context.Background(), c, ctrlConfig.WatchNamespaces)
+					return err
+				})
+			}
+		}
+		exitOnError(err, "unable to load the CronJobs to simulate")
+		simulator := simulation.Simulator{
+			From:      time.Now(),
+			Horizon:   simulateHorizon,
+			TimeZones: featuregates.Enabled(featuregates.CronJobTimeZone),
+		}
+		if err := simulator.Run(cronJobs).Print(os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	options = config.ManagerOptions(options, &ctrlConfig)
+	if options.LeaderElection {
+		setupLog.Info("leader election", "resourceLock", options.LeaderElectionResourceLock,
+			"namespace", options.LeaderElectionNamespace, "name", options.LeaderElectionID)
+	}
+
+	/*
+		A single watched namespace is supported by the cache out of the box, several namespaces need a cache per
+		namespace.
+	*/
+	if namespaces := ctrlConfig.WatchNamespaces; len(namespaces) == 1 {
+		options.Namespace = namespaces[0]
+		setupLog.Info("watching a subset of the namespaces", "namespaces", namespaces)
+	} else if len(namespaces) > 1 {
+		options.Namespace = ""
+		options.NewCache = cache.MultiNamespacedCacheBuilder(namespaces)
+		setupLog.Info("watching a subset of the namespaces", "namespaces", namespaces)
+	}
+
+	secureMetricsConfig := ctrlConfig.SecureMetrics
+	if secureMetricsConfig.BindAddress == "" {
+		secureMetricsConfig.BindAddress = ":8443"
+	}
+	if secureMetricsConfig.Enabled {
+		// the plain HTTP endpoint of controller-runtime is replaced by ours
+		options.MetricsBindAddress = "0"
+	}
+
+	/*
+		In dry-run mode, the client of the manager sends every write with `dryRun=All`. The API server validates the
+		writes and runs the admission webhooks, but persists nothing. The instance does not take part in the leader
+		election, so it neither waits for the lease nor takes it from the production instance.
+	*/
+	if dryRun {
+		options.ClientBuilder = dryrun.NewClientBuilder(cluster.NewClientBuilder())
+		options.LeaderElection = false
+		setupLog.Info("running in dry-run mode, the writes are not persisted")
+	}
+
+	/*
+		With the MetadataOnlyJobs feature gate, the cache holds the metadata of the Jobs only, and the client of the
+		manager reads their status from the API server when a controller reads them, keeping it until they change.
+	*/
+	if featuregates.Enabled(featuregates.MetadataOnlyJobs) {
+		builder := options.ClientBuilder
+		if builder == nil {
+			builder = cluster.NewClientBuilder()
+		}
+		options.ClientBuilder = jobcache.NewClientBuilder(builder)
 	}
 
 	// Lastly, we’ll change the NewManager call to use the options varible we defined above.
+	restConfig, err := kubeconfig.GetConfigWithContext(kubeContext)
+	exitOnError(err, "unable to load the kubeconfig", "context", kubeContext)
+	if insecureSkipTLSVerify {
+		setupLog.Info("WARNING: the certificate of the API server is not verified")
+		restConfig.Insecure = true
+		restConfig.TLSClientConfig.CAFile = ""
+		restConfig.TLSClientConfig.CAData = nil
+	}
+	config.ApplyClientConfig(restConfig, ctrlConfig.Client)
+	setupLog.Info("configured the API client", "qps", restConfig.QPS, "burst", restConfig.Burst,
+		"timeout", restConfig.Timeout, "userAgent", restConfig.UserAgent)
+	/*
+		Creating the manager discovers the APIs of the API server, which fails while the API server is unavailable.
+		The steps which talk to the API server are retried with an exponential back-off, bounded by
+		--startup-timeout, until a signal stops the manager.
+	*/
+	ctx := ctrl.SetupSignalHandler()
 	var mgr manager.Manager
-	if mgr, err = ctrl.NewManager(ctrl.GetConfigOrDie(), options); err != nil {
-		setupLog.Error(err, "unable to start manager")
-		os.Exit(1)
+	exitOnError(startupBackoff.Retry(ctx, "create manager", func() (err error) {
+		mgr, err = ctrl.NewManager(restConfig, options)
+		return err
+	}), "unable to start manager")
+
+	/*
+		The reconciles and the admission requests are traced when an OTLP collector is configured. The clients of
+		the controller and the webhooks are wrapped below, so that the calls to the API server are part of the traces.
+	*/
+	tracingProvider, err := tracing.Setup(context.Background(), ctrlConfig.Tracing, ctrlConfig.ClusterName)
+	exitOnError(err, "unable to set up tracing")
+	if tracingProvider != nil {
+		exitOnError(mgr.Add(tracingProvider), "unable to set up tracing")
 	}
 
-	// Kubebuilder has added a block calling our CronJob controller’s SetupWithManager method.
-	if err = (&controllers.CronJobReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "CronJob")
-		os.Exit(1)
+	/*
+		The panics of the reconciles and the webhooks, and the CronJobs failing their reconciles again and again, are
+		reported to Sentry or Cloud Error Reporting when one of them is configured.
+	*/
+	errorReporter, err := errorreporting.Setup(context.Background(), ctrlConfig.ErrorReporting,
+		ctrlConfig.ClusterName)
+	exitOnError(err, "unable to set up error reporting")
+	if errorReporter != nil {
+		exitOnError(mgr.Add(errorReporter), "unable to set up error reporting")
+	}
+
+	/*
+		The lifecycle of the runs is emitted as CloudEvents when a sink is configured: the jobrun controller emits the
+		scheduled, started and finished runs, the CronJob controller the missed ones. The credentials are read once,
+		uncached, like the ones of the archive.
+	*/
+	secrets := startupBackoff.SecretReader(mgr.GetAPIReader())
+	events, err := cloudevents.Setup(ctx, ctrlConfig.CloudEvents, ctrlConfig.ClusterName, secrets)
+	exitOnError(err, "unable to set up CloudEvents")
+	if events != nil {
+		if dryRun {
+			events.DryRun()
+		}
+		exitOnError(mgr.Add(events), "unable to set up CloudEvents")
+	}
+
+	reloaders := []config.Reloader{
+		func(c *configv1.ProjectConfig) error {
+			return config.ApplyLogLevel(logLevel, c.Logging)
+		},
+	}
+
+	// What a leader could not deliver or write on shutdown is kept next to the leader election lock.
+	handoverStore := &handover.Store{Client: mgr.GetClient(), Reader: mgr.GetAPIReader(),
+		Namespace: options.LeaderElectionNamespace}
+
+	/*
+		Kubebuilder has added a block calling the SetupWithManager method of our controllers. The CronJob and the
+		jobrun controllers are wired to the notifications, the CloudEvents, the archive and the Pushgateway first.
+	*/
+	var controllersToSetUp []managedController
+	var reconciler *controllers.CronJobReconciler
+	if config.IsControllerEnabled(config.CronJobController, ctrlConfig.Controllers) {
+		// The notifications of the finished Jobs are delivered in the background, reading the Secrets uncached.
+		notifier := notification.NewDispatcher(mgr.GetClient(), mgr.GetAPIReader())
+		if dryRun {
+			notifier.DryRun()
+		}
+		notifier.Handover(handoverStore)
+		exitOnError(mgr.Add(notifier), "unable to set up notifications")
+		reconciler = &controllers.CronJobReconciler{
+			Client:                  tracing.WrapClient(mgr.GetClient()),
+			Scheme:                  mgr.GetScheme(),
+			MaxConcurrentReconciles: ctrlConfig.CronJobController.MaxConcurrentReconciles,
+			RateLimit:               ctrlConfig.CronJobController.RateLimit,
+			DeletePropagationPolicy: ctrlConfig.CronJobController.DeletePropagationPolicy,
+			ErrorReporter:           errorReporter,
+			Notifier:                notifier,
+			Events:                  events,
+			Recorder:                mgr.GetEventRecorderFor("cronjob-controller"),
+			CreationDelayPerCronJob: ctrlConfig.CronJobController.CreationDelayPerCronJob,
+			Handover:                handoverStore,
+		}
+		if jitter := ctrlConfig.CronJobController.RequeueJitter; jitter != nil {
+			reconciler.RequeueJitter = jitter.Duration
+		}
+		if debounce := ctrlConfig.CronJobController.StatusDebounce; debounce != nil {
+			reconciler.StatusDebounce = debounce.Duration
+		}
+		controllersToSetUp = append(controllersToSetUp, managedController{name: config.CronJobController,
+			kind: "CronJob", reconciler: reconciler})
+		reloaders = append(reloaders, func(c *configv1.ProjectConfig) error {
+			reconciler.UpdateRateLimit(c.CronJobController.RateLimit)
+			return nil
+		})
+	} else {
+		setupLog.Info("controller disabled", "controller", config.CronJobController)
+	}
+
+	// The jobrun controller records the runs of the CronJobs in JobRuns, kept longer than the Jobs.
+	if config.IsControllerEnabled(config.JobRunController, ctrlConfig.Controllers) {
+		jobRunReconciler := &controllers.JobRunReconciler{
+			Client:        tracing.WrapClient(mgr.GetClient()),
+			Scheme:        mgr.GetScheme(),
+			ErrorReporter: errorReporter,
+			Events:        events,
+			APIReader:     mgr.GetAPIReader(),
+		}
+		if ttl := ctrlConfig.CronJobController.JobRunTTL; ttl != nil {
+			jobRunReconciler.TTL = ttl.Duration
+		}
+		// The finished runs are archived to an object storage, and their results pushed to a Pushgateway, when they
+		// are configured.
+		pods, err := corev1client.NewForConfig(restConfig)
+		exitOnError(err, "unable to create client for the pods")
+		jobRunReconciler.Archiver, err = archive.Setup(ctx, ctrlConfig.Archive, ctrlConfig.ClusterName, secrets, pods)
+		exitOnError(err, "unable to set up archive")
+		if jobRunReconciler.Archiver != nil {
+			if dryRun {
+				jobRunReconciler.Archiver.DryRun()
+			}
+			exitOnError(mgr.Add(jobRunReconciler.Archiver), "unable to set up archive")
+		}
+		jobRunReconciler.Pusher, err = pushgateway.Setup(ctx, ctrlConfig.Pushgateway, ctrlConfig.ClusterName, secrets,
+			pods)
+		exitOnError(err, "unable to set up Pushgateway")
+		if jobRunReconciler.Pusher != nil && dryRun {
+			jobRunReconciler.Pusher.DryRun()
+		}
+		controllersToSetUp = append(controllersToSetUp, managedController{name: config.JobRunController, kind: "JobRun",
+			reconciler: jobRunReconciler, crds: []string{"jobruns"}})
+	} else {
+		setupLog.Info("controller disabled", "controller", config.JobRunController)
+	}
+
+	// The report controller gets the next runs from the CronJob controller, when it runs in this manager.
+	reportReconciler := &controllers.CronJobReportReconciler{
+		Client:        tracing.WrapClient(mgr.GetClient()),
+		Scheme:        mgr.GetScheme(),
+		ErrorReporter: errorReporter,
+	}
+	if reconciler != nil {
+		reportReconciler.NextRuns = reconciler
+	}
+
+	/*
+		The other controllers only need the clients of the manager. The workflow, calendar, gitsync, scheduleimport and
+		cronjobreport controllers only run when they are named in --controllers.
+	*/
+	for _, c := range []managedController{
+		// The policy controllers report the existing CronJobs violating the CronJobPolicies and the
+		// ClusterCronJobPolicies in their status.
+		{name: config.CronJobPolicyController, kind: "CronJobPolicy", reconciler: &controllers.CronJobPolicyReconciler{
+			Client: tracing.WrapClient(mgr.GetClient()), Scheme: mgr.GetScheme(), ErrorReporter: errorReporter}},
+		{name: config.ClusterCronJobPolicyController, kind: "ClusterCronJobPolicy",
+			reconciler: &controllers.ClusterCronJobPolicyReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), ErrorReporter: errorReporter}},
+		// The ClusterCronJob controller creates the Jobs of the ClusterCronJobs in every selected namespace.
+		{name: config.ClusterCronJobController, kind: "ClusterCronJob",
+			reconciler: &controllers.ClusterCronJobReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), ErrorReporter: errorReporter,
+				Recorder: mgr.GetEventRecorderFor("clustercronjob-controller")}},
+		// The set controller stamps out the CronJobs of the CronJobSets in the namespaces.
+		{name: config.CronJobSetController, kind: "CronJobSet", crds: []string{"cronjobsets"},
+			reconciler: &controllers.CronJobSetReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), ErrorReporter: errorReporter}},
+		// The workflow controller runs the DAGs of the Workflows.
+		{name: config.WorkflowController, kind: "Workflow", crds: []string{"workflows"},
+			reconciler: &controllers.WorkflowReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), ErrorReporter: errorReporter}},
+		// The quota controller reports the usage of the CronJobQuotas, they are enforced by the CronJob controller.
+		{name: config.CronJobQuotaController, kind: "CronJobQuota",
+			reconciler: &controllers.CronJobQuotaReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), ErrorReporter: errorReporter}},
+		// The group controller applies the CronJobGroups to their members.
+		{name: config.CronJobGroupController, kind: "CronJobGroup", crds: []string{"cronjobgroups", "jobruns"},
+			reconciler: &controllers.CronJobGroupReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), ErrorReporter: errorReporter}},
+		// The backfill controller replays the runs of the CronJobs over past windows.
+		{name: config.BackfillController, kind: "Backfill", crds: []string{"backfills"},
+			reconciler: &controllers.BackfillReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), ErrorReporter: errorReporter}},
+		// The calendar controller imports the feeds of the Calendars.
+		{name: config.CalendarController, kind: "Calendar", crds: []string{"calendars"},
+			reconciler: &controllers.CalendarReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), ErrorReporter: errorReporter}},
+		// The gitsync controller applies the CronJobs of the GitSyncs.
+		{name: config.GitSyncController, kind: "GitSync", crds: []string{"gitsyncs"},
+			reconciler: &controllers.GitSyncReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), APIReader: mgr.GetAPIReader(), ErrorReporter: errorReporter}},
+		// The scheduleimport controller imports the schedules of the cloud schedulers.
+		{name: config.ScheduleImportController, kind: "ScheduleImport", crds: []string{"scheduleimports"},
+			reconciler: &controllers.ScheduleImportReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme(), APIReader: mgr.GetAPIReader(), ErrorReporter: errorReporter}},
+		// The report controller summarizes the CronJobs of every namespace.
+		{name: config.CronJobReportController, kind: "CronJobReport", crds: []string{"cronjobreports"},
+			reconciler: reportReconciler},
+		// The jobtemplate controller rolls the JobTemplates out to the CronJobs referencing them.
+		{name: config.JobTemplateController, kind: "JobTemplate",
+			reconciler: &controllers.JobTemplateReconciler{Client: tracing.WrapClient(mgr.GetClient()),
+				Scheme: mgr.GetScheme()}},
+	} {
+		if config.IsControllerEnabled(c.name, ctrlConfig.Controllers) {
+			controllersToSetUp = append(controllersToSetUp, c)
+		} else {
+			setupLog.Info("controller disabled", "controller", c.name)
+		}
+	}
+	for _, c := range controllersToSetUp {
+		exitOnError(c.reconciler.SetupWithManager(mgr), "unable to create controller", "controller", c.kind)
 	}
 
 	// +kubebuilder:docs-gen:collapse=existing setup
 
-	// Our existing call to SetupWebhookWithManager registers our conversion webhooks with the manager, too.
-	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err = (&batchv1.CronJob{}).SetupWebhookWithManager(mgr); err != nil {
-			setupLog.Error(err, "unable to create webhook", "webhook", "CronJob")
-			os.Exit(1)
+	/*
+		Our existing call to SetupWebhookWithManager registers our conversion webhooks with the manager, too. The
+		webhook server is only created, and so only listens, when there is at least one webhook to serve.
+	*/
+	if os.Getenv("ENABLE_WEBHOOKS") != "" {
+		setupLog.Info("ENABLE_WEBHOOKS is deprecated, use --enable-mutating-webhook and --enable-validating-webhook")
+	}
+	mutating := webhooks.MutatingWebhookEnabled(ctrlConfig.Admission)
+	validating := webhooks.ValidatingWebhookEnabled(ctrlConfig.Admission)
+	if mutating || validating || len(ctrlConfig.Admission.ExtraHandlers) > 0 {
+		setupLog.Info("serving the webhooks", "mutating", mutating, "validating", validating,
+			"extraHandlers", len(ctrlConfig.Admission.ExtraHandlers))
+
+		/*
+			The options of the manager carry the port, the host and the directory of the server, the config file
+			carries the file names and the TLS settings. Our server replaces the one of the manager, whose TLS
+			settings can not be hardened.
+		*/
+		webhookServer := webhookserver.New(options, ctrlConfig.WebhookServer.CertName,
+			ctrlConfig.WebhookServer.KeyName, ctrlConfig.WebhookServer.TLS)
+		exitOnError(mgr.Add(webhookServer), "unable to set up the webhook server")
+
+		/*
+			Without cert-manager, we take care of the serving certificate ourselves. The certificate has to be in
+			place before the webhook server starts, the rotator then keeps it fresh while the manager runs. The
+			cache of the manager is not started yet, so the rotator talks to the API server directly.
+		*/
+		if certRotation.Enabled {
+			var uncachedClient client.Client
+			exitOnError(startupBackoff.Retry(ctx, "create cert rotation client", func() (err error) {
+				uncachedClient, err = client.New(restConfig, client.Options{Scheme: scheme})
+				return err
+			}), "unable to create client for cert rotation")
+			if dryRun {
+				uncachedClient = dryrun.WrapClient(uncachedClient)
+			}
+			rotator := &certrotation.Rotator{
+				Client:   uncachedClient,
+				Options:  certRotation,
+				CertDir:  webhookServer.CertDir,
+				CertName: webhookServer.CertName,
+				KeyName:  webhookServer.KeyName,
+			}
+			// Only the webhooks which are served are registered.
+			mutatingWebhooks, validatingWebhooks, err := certrotation.WebhooksFromManifests(webhookmanifests.Manifests)
+			exitOnError(err, "unable to read the embedded webhook configurations")
+			// The CronJobs of the namespaces which are not watched are neither reconciled nor sent to the webhooks.
+			certrotation.SelectNamespaces(mutatingWebhooks, validatingWebhooks, ctrlConfig.WatchNamespaces)
+			if mutating {
+				rotator.MutatingWebhooks = mutatingWebhooks
+			}
+			if validating {
+				rotator.ValidatingWebhooks = validatingWebhooks
+			}
+			exitOnError(startupBackoff.Retry(ctx, "provision webhook certificates", func() error {
+				return rotator.EnsureCerts(ctx)
+			}), "unable to provision webhook certificates")
+			exitOnError(mgr.Add(rotator), "unable to set up cert rotation")
+		}
+
+		cronJobWebhook := &webhooks.CronJobWebhook{
+			Client:          tracing.WrapClient(mgr.GetClient()),
+			APIReader:       mgr.GetAPIReader(),
+			WatchNamespaces: ctrlConfig.WatchNamespaces,
+			Config:          ctrlConfig.Admission,
+			Server:          webhookServer.Server,
+			ErrorReporter:   errorReporter,
 		}
+		exitOnError(cronJobWebhook.SetupWebhookWithManager(mgr), "unable to create webhook", "webhook", "CronJob")
+		reloaders = append(reloaders, func(c *configv1.ProjectConfig) error {
+			return cronJobWebhook.UpdateConfig(c.Admission)
+		})
+	} else {
+		setupLog.Info("all the webhooks are disabled, not starting the webhook server")
+	}
+
+	/*
+		The leader keeps the OperatorStatus singleton, the health of the operator for the fleet tooling. Its config
+		hash follows the reloads of the config file.
+	*/
+	operatorStatus, err := operatorstatus.Setup(&ctrlConfig, mgr.GetClient(), mgr.GetAPIReader(), metrics.Registry)
+	exitOnError(err, "unable to set up the OperatorStatus")
+	if operatorStatus != nil {
+		reloaders = append(reloaders, operatorStatus.SetConfig)
+		exitOnError(mgr.Add(operatorStatus), "unable to set up the OperatorStatus")
+	}
+
+	/*
+		Some of the settings are applied on the fly when the config file changes, without restarting the manager and
+		losing the leadership. Every component with reloadable settings adds a reloader above.
+	*/
+	if len(configFiles) > 0 {
+		exitOnError(mgr.Add(&config.Watcher{
+			Paths:     configFiles,
+			Scheme:    scheme,
+			Strict:    strictConfig,
+			Overrides: overrides,
+			Initial:   &ctrlConfig,
+			Reloaders: reloaders,
+		}), "unable to set up the config file watcher")
 	}
 
 	//+kubebuilder:scaffold:builder
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up health check")
-		os.Exit(1)
+	exitOnError(mgr.AddHealthzCheck("healthz", healthz.Ping), "unable to set up health check")
+	exitOnError(mgr.AddReadyzCheck("readyz", healthz.Ping), "unable to set up ready check")
+
+	/*
+		The leader status is exported by the cronjob_operator_replica_info metric on every replica. With
+		--leader-readiness, it is a readiness check as well, so a Service only routes to the active replica.
+	*/
+	leaderStatus := leaderstatus.New(mgr.Elected())
+	exitOnError(mgr.Add(leaderStatus), "unable to set up the leader status")
+	if leaderReadiness {
+		exitOnError(mgr.AddReadyzCheck("leader", leaderStatus.Check), "unable to set up leader ready check")
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
-		os.Exit(1)
+
+	/*
+		The secure metrics endpoint serves the debugging endpoints too: the log level can be changed at runtime by the
+		users allowed to `put` the /loglevel non-resource URL, and the internal state is served to the users allowed to
+		`get` /debug/state. The scheduling state of the CronJobs is served to the users allowed to `get`
+		/debug/scheduling. They are not served by the plain HTTP endpoint, whose bearer tokens would travel in clear
+		text. The state is also logged on SIGUSR1.
+	*/
+	dumper := &diagnostics.Dumper{
+		IsLeader: leaderStatus.IsLeader,
+		Gatherer: metrics.Registry,
+	}
+	schedulingHandler := &diagnostics.SchedulingHandler{Reader: mgr.GetClient()}
+	if reconciler != nil {
+		dumper.Wakeups = reconciler
+		schedulingHandler.Source = reconciler
+	}
+	exitOnError(mgr.Add(dumper), "unable to set up the state dump")
+	debugHandlers := map[string]http.Handler{
+		loglevel.Path:              &loglevel.Handler{Level: logLevel},
+		diagnostics.Path:           dumper,
+		diagnostics.SchedulingPath: schedulingHandler,
+	}
+	if secureMetricsConfig.Enabled {
+		exitOnError(mgr.Add(&metricsserver.Server{
+			BindAddress:   secureMetricsConfig.BindAddress,
+			CertDir:       secureMetricsConfig.CertDir,
+			Client:        mgr.GetClient(),
+			ExtraHandlers: debugHandlers,
+			TLS:           secureMetricsConfig.TLS,
+		}), "unable to set up the secure metrics endpoint")
+	} else {
+		paths := make([]string, 0, len(debugHandlers))
+		for path := range debugHandlers {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		setupLog.Info("WARNING: the debugging endpoints are not served, they need --secure-metrics", "paths", paths)
+	}
+
+	// The read-only API serves the run history and the next runs of the CronJobs from the cache, on its own listener.
+	if apiConfig := ctrlConfig.API; apiConfig.Enabled {
+		if apiConfig.BindAddress == "" {
+			apiConfig.BindAddress = ":8444"
+		}
+		// The dashboard is served by the same listener, its buttons start and suspend the CronJobs like the gRPC service.
+		var handlers map[string]http.Handler
+		if apiConfig.Dashboard {
+			handlers = map[string]http.Handler{
+				dashboard.Prefix: dashboard.NewHandler(tracing.WrapClient(mgr.GetClient()), mgr.GetScheme()),
+			}
+		}
+		exitOnError(mgr.Add(&restapi.Server{
+			BindAddress: apiConfig.BindAddress,
+			CertDir:     apiConfig.CertDir,
+			Client:      mgr.GetClient(),
+			Reader:      mgr.GetClient(),
+			TLS:         apiConfig.TLS,
+			Handlers:    handlers,
+		}), "unable to set up the API")
+	}
+
+	// The gRPC service lets the external schedulers and the CI systems start, suspend and follow the runs.
+	if grpcConfig := ctrlConfig.GRPC; grpcConfig.Enabled {
+		if grpcConfig.BindAddress == "" {
+			grpcConfig.BindAddress = ":9090"
+		}
+		exitOnError(mgr.Add(&grpcapi.Server{
+			BindAddress:    grpcConfig.BindAddress,
+			CertDir:        grpcConfig.CertDir,
+			ClientCAFile:   grpcConfig.ClientCAFile,
+			AllowedClients: grpcConfig.AllowedClients,
+			Client:         tracing.WrapClient(mgr.GetClient()),
+			Scheme:         mgr.GetScheme(),
+			TLS:            grpcConfig.TLS,
+		}), "unable to set up the gRPC service")
+	}
+
+	// The trigger endpoint lets the external systems without a gRPC client start runs with a webhook.
+	if triggerServer := httptrigger.Setup(ctrlConfig.HTTPTrigger, tracing.WrapClient(mgr.GetClient()),
+		mgr.GetAPIReader(), mgr.GetScheme()); triggerServer != nil {
+		exitOnError(mgr.Add(triggerServer), "unable to set up the trigger endpoint")
+	}
+
+	/*
+		Without its CRDs, the caches of the manager never sync and the manager fails with a timeout which does not say
+		why. So the CRDs, and the webhook configurations of the webhooks we serve, are checked first. Like an
+		unavailable API server, they are waited for until --startup-timeout, the error then tells what is missing.
+	*/
+	prerequisites := startup.Prerequisites{CRDs: []schema.GroupVersionResource{
+		batchv1.GroupVersion.WithResource("cronjobs"),
+		batchv1.GroupVersion.WithResource("cronjobpolicies"),
+		batchv1.GroupVersion.WithResource("clustercronjobpolicies"),
+		batchv1.GroupVersion.WithResource("clustercronjobs"),
+		batchv1.GroupVersion.WithResource("scheduleoverrides"),
+		batchv1.GroupVersion.WithResource("maintenancewindows"),
+		batchv1.GroupVersion.WithResource("clustermaintenancewindows"),
+		batchv1.GroupVersion.WithResource("notificationchannels"),
+		batchv1.GroupVersion.WithResource("cronjobquotas"),
+		batchv1.GroupVersion.WithResource("jobtemplates"),
+	}}
+	for _, c := range controllersToSetUp {
+		for _, resource := range c.crds {
+			prerequisites.CRDs = append(prerequisites.CRDs, batchv1.GroupVersion.WithResource(resource))
+		}
+	}
+	if ctrlConfig.OperatorStatus.Enabled {
+		prerequisites.CRDs = append(prerequisites.CRDs, batchv1.GroupVersion.WithResource("operatorstatuses"))
+	}
+	if mutating {
+		prerequisites.MutatingWebhooks = []string{webhooks.MutatingWebhookName}
+	}
+	if validating {
+		prerequisites.ValidatingWebhooks = []string{webhooks.ValidatingWebhookName}
+	}
+	prerequisitesScheme := runtime.NewScheme()
+	utilruntime.Must(startup.AddToScheme(prerequisitesScheme))
+	var prerequisitesClient client.Client
+	exitOnError(startupBackoff.Retry(ctx, "create prerequisites client", func() (err error) {
+		prerequisitesClient, err = client.New(restConfig, client.Options{Scheme: prerequisitesScheme})
+		return err
+	}), "unable to create client for the prerequisites")
+	if dryRun {
+		prerequisitesClient = dryrun.WrapClient(prerequisitesClient)
+	}
+
+	// With --install-crds, the manager applies the CRDs it was built with before checking them.
+	if installCRDs {
+		crds, err := startup.ReadManifests(crd.Bases)
+		exitOnError(err, "unable to read the embedded CRDs")
+		exitOnError(startupBackoff.Retry(ctx, "install CRDs", func() error {
+			return startup.Apply(ctx, prerequisitesClient, crds)
+		}), "unable to install the CRDs")
+	}
+
+	exitOnError(startupBackoff.Retry(ctx, "check prerequisites", func() error {
+		return prerequisites.Check(ctx, prerequisitesClient)
+	}), "the prerequisites of the operator are not installed")
+
+	/*
+		The permissions the controller and the leader election need are reviewed next. A misapplied RBAC stops the
+		manager right away with the list of the missing permissions, instead of Forbidden errors once it runs.
+	*/
+	var permissions []startup.Permission
+	if ctrlConfig.GRPC.Enabled || ctrlConfig.HTTPTrigger.Enabled || (ctrlConfig.API.Enabled && ctrlConfig.API.Dashboard) {
+		group, namespaces := batchv1.GroupVersion.Group, ctrlConfig.WatchNamespaces
+		permissions = append(permissions, startup.Permissions(group, "cronjobs",
+			[]string{"get", "list", "watch", "patch"}, namespaces...)...)
+		permissions = append(permissions, startup.Permissions(group, "jobruns", []string{"get", "list", "watch"},
+			namespaces...)...)
+		permissions = append(permissions, startup.Permissions(group, "cronjobquotas", []string{"get", "list", "watch"},
+			namespaces...)...)
+		permissions = append(permissions, startup.Permissions("batch", "jobs",
+			[]string{"get", "list", "watch", "create"}, namespaces...)...)
 	}
+	if ctrlConfig.API.Enabled {
+		group, namespaces := batchv1.GroupVersion.Group, ctrlConfig.WatchNamespaces
+		permissions = append(permissions, startup.Permissions(group, "cronjobs", []string{"get", "list", "watch"},
+			namespaces...)...)
+		permissions = append(permissions, startup.Permissions(group, "jobruns", []string{"get", "list", "watch"},
+			namespaces...)...)
+	}
+	for _, c := range controllersToSetUp {
+		permissions = append(permissions, c.reconciler.Permissions(ctrlConfig.WatchNamespaces)...)
+	}
+	if config.IsControllerEnabled(config.JobRunController, ctrlConfig.Controllers) {
+		permissions = append(permissions, archive.Permissions(ctrlConfig.Archive, ctrlConfig.WatchNamespaces)...)
+		permissions = append(permissions, pushgateway.Permissions(ctrlConfig.Pushgateway, ctrlConfig.WatchNamespaces)...)
+	}
+	permissions = append(permissions, httptrigger.Permissions(ctrlConfig.HTTPTrigger)...)
+	permissions = append(permissions, cloudevents.Permissions(ctrlConfig.CloudEvents)...)
+	permissions = append(permissions, operatorstatus.Permissions(ctrlConfig.OperatorStatus)...)
+	if options.LeaderElection {
+		permissions = append(permissions, startup.LeaderElectionPermissions(options.LeaderElectionResourceLock,
+			options.LeaderElectionNamespace)...)
+	}
+	exitOnError(startupBackoff.Retry(ctx, "check permissions", func() error {
+		return startup.CheckPermissions(ctx, prerequisitesClient, permissions)
+	}), "the operator is missing permissions, check its RBAC")
 
-	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
-		setupLog.Error(err, "problem running manager")
+	buildInfo := version.Get()
+	setupLog.Info("starting manager", "version", buildInfo.Version, "gitCommit", buildInfo.GitCommit,
+		"buildDate", buildInfo.BuildDate)
+	exitOnError(mgr.Start(ctx), "problem running manager")
+}
+
+// managedController is a controller the manager runs.
+type managedController struct {
+	// name is the name of the controller in --controllers, kind the kind it reconciles.
+	name, kind string
+	reconciler interface {
+		SetupWithManager(mgr ctrl.Manager) error
+		Permissions(namespaces []string) []startup.Permission
+	}
+	// crds are the resources of the CRDs the controller needs, on top of the ones the manager always needs.
+	crds []string
+}
+
+// exitOnError logs the error of a step of the setup and exits, the manager does not start without all its components.
+func exitOnError(err error, msg string, keysAndValues ...interface{}) {
+	if err != nil {
+		setupLog.Error(err, msg, keysAndValues...)
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma separated list, dropping the empty and the duplicate entries.
+func splitList(list string) []string {
+	var items []string
+	seen := map[string]bool{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admissionpolicy
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// header is the beginning of the policy, up to its validations.
+const header = `# Code generated by "make admission-policy" from pkg/admissionpolicy. DO NOT EDIT.
+#
+# These rules are a subset of the ones enforced by the validating webhook (vcronjob.kb.io). They only cover
+# checks which can be expressed without any cluster lookups, so that the validating webhook can be disabled
+# entirely and CronJobs stay guarded while the webhook server is unavailable.
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingAdmissionPolicy
+metadata:
+  name: cronjob-policy.batch.example.com
+spec:
+  failurePolicy: Fail
+  matchConstraints:
+    resourceRules:
+    - apiGroups:
+      - batch.example.com
+      apiVersions:
+      - v1
+      operations:
+      - CREATE
+      - UPDATE
+      resources:
+      - cronjobs
+  validations:
+`
+
+// Generate writes the ValidatingAdmissionPolicy enforcing the Rules.
+func Generate(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(header)
+	for _, r := range Rules {
+		if r.Comment != "" {
+			for _, line := range strings.Split(r.Comment, "\n") {
+				fmt.Fprintf(&b, "  # %s\n", line)
+			}
+		}
+		fmt.Fprintf(&b, "  - expression: >-\n      %s\n", r.expression())
+		if r.Pattern != nil {
+			// the invalid value is shown like the webhook does
+			fmt.Fprintf(&b, "    messageExpression: \"'%s ' + object.%s + ' %s'\"\n", r.Field, r.Field, r.Message)
+		} else {
+			fmt.Fprintf(&b, "    message: %q\n", r.Field+" "+r.Message)
+		}
+		b.WriteString("    reason: Invalid\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
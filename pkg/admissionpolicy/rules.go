@@ -0,0 +1,203 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package admissionpolicy holds the rules of the validating webhook which only need the CronJob itself, so that the
+// ValidatingAdmissionPolicy of config/admission-policy is generated from the same definitions the webhook enforces.
+package admissionpolicy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+/*
+The CEL of the policy and the webhook must agree on what is valid, so the rules are written once, as regular
+expressions: CEL evaluates `matches` with RE2, the engine of the regexp package, so a pattern accepts the same values on
+both sides. The webhook still parses the schedule too, which the pattern is built to never contradict: it only accepts
+the values within the bounds of every field and the ranges which do not end before they start.
+
+The rules without a pattern only restate the schema of the CRD, the webhook leaves them to it.
+*/
+
+// Rule is a rule enforced by both the validating webhook and the ValidatingAdmissionPolicy.
+type Rule struct {
+	// Comment explains the rule in the policy.
+	Comment string
+	// Field is the path of the validated field, e.g. `spec.schedule`.
+	Field string
+	// Pattern matches the valid values of Field, if set.
+	Pattern *regexp.Regexp
+	// Expression is the CEL expression of the rules without a pattern.
+	Expression string
+	// Message tells why a value is invalid.
+	Message string
+}
+
+// Allows returns whether the rule with a pattern accepts the value.
+func (r *Rule) Allows(value string) bool {
+	return r.Pattern.MatchString(value)
+}
+
+// expression returns the CEL expression of the rule.
+func (r *Rule) expression() string {
+	if r.Pattern == nil {
+		return r.Expression
+	}
+	return fmt.Sprintf("object.%s.matches('%s')", r.Field, r.Pattern.String())
+}
+
+// nameMaxLength is the longest name of a CronJob: the names of its Jobs get an 11-character suffix, `-$TIMESTAMP`,
+// and are limited to 63 characters.
+const nameMaxLength = 63 - 11
+
+var (
+	// Name limits the length of the names of the CronJobs, which are DNS subdomains.
+	Name = &Rule{
+		Comment: "The cronjob controller appends a 11-character suffix to the cronjob (`-$TIMESTAMP`) when creating\n" +
+			"a job, and job names are limited to 63 characters.",
+		Field:   "metadata.name",
+		Pattern: regexp.MustCompile(fmt.Sprintf("^[a-z0-9]([-.a-z0-9]{0,%d}[a-z0-9])?$", nameMaxLength-2)),
+		Message: fmt.Sprintf("must be a lowercase DNS subdomain of no more than %d characters", nameMaxLength),
+	}
+
+	// Schedule accepts the schedules of 5 fields and the descriptors the controller runs.
+	Schedule = &Rule{
+		Comment: "Either one of the predefined descriptors, an @every <duration> expression or exactly 5 fields, each\n" +
+			"within the bounds of the field.",
+		Field:   "spec.schedule",
+		Pattern: regexp.MustCompile(schedulePattern()),
+		Message: "must be 5 fields within their bounds, one of @yearly, @annually, @monthly, @weekly, @daily, " +
+			"@midnight and @hourly, or @every <duration> like @every 1h30m",
+	}
+)
+
+// Rules are the validations of the policy, in order.
+var Rules = []*Rule{
+	Name,
+	Schedule,
+	{
+		Field:      "spec.startingDeadlineSeconds",
+		Expression: "!has(object.spec.startingDeadlineSeconds) || object.spec.startingDeadlineSeconds >= 0",
+		Message:    "must be greater than or equal to 0",
+	},
+	{
+		Field:      "spec.successfulJobsHistoryLimit",
+		Expression: "!has(object.spec.successfulJobsHistoryLimit) || object.spec.successfulJobsHistoryLimit >= 0",
+		Message:    "must be greater than or equal to 0",
+	},
+	{
+		Field:      "spec.failedJobsHistoryLimit",
+		Expression: "!has(object.spec.failedJobsHistoryLimit) || object.spec.failedJobsHistoryLimit >= 0",
+		Message:    "must be greater than or equal to 0",
+	},
+	{
+		Field: "spec.concurrencyPolicy",
+		Expression: "!has(object.spec.concurrencyPolicy) || " +
+			"object.spec.concurrencyPolicy in ['Allow', 'Forbid', 'Replace']",
+		Message: "must be one of Allow, Forbid or Replace",
+	},
+}
+
+// cronField is a field of the schedules, with the bounds and the names of its values.
+type cronField struct {
+	min, max int
+	// names are the names of the values from min, if any
+	names []string
+}
+
+var cronFields = []cronField{
+	{min: 0, max: 59},
+	{min: 0, max: 23},
+	{min: 1, max: 31},
+	{min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov",
+		"dec"}},
+	{min: 0, max: 6, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// schedulePattern returns the pattern of the schedules the parser of the controller, cron.ParseStandard, accepts.
+func schedulePattern() string {
+	fields := make([]string, 0, len(cronFields))
+	for _, f := range cronFields {
+		fields = append(fields, f.pattern())
+	}
+	// The durations are whole numbers of each unit, a few of them at most, so that they never overflow.
+	every := "@every ([0-9]{1,5}(h|m|s|ms|us|ns)){1,4}"
+	descriptors := "@(yearly|annually|monthly|weekly|daily|midnight|hourly)"
+	return "^(" + descriptors + "|" + every + "|" + strings.Join(fields, " +") + ")$"
+}
+
+// pattern returns the pattern of the comma separated list of the values, the ranges and the steps of the field.
+func (f cronField) pattern() string {
+	var ranges []string
+	for v := f.min; v <= f.max; v++ {
+		ranges = append(ranges, f.values(v, v)+"-"+f.values(v, f.max))
+	}
+	// a single value with a step runs until the maximum of the field, like `*`
+	element := "([*?]|" + f.values(f.min, f.max) + "|" + strings.Join(ranges, "|") + ")(/[1-9][0-9]?)?"
+	return element + "(," + element + ")*"
+}
+
+// values returns the pattern of the values of the field from lo to hi, numbers or names.
+func (f cronField) values(lo, hi int) string {
+	alternatives := numbers(lo, hi)
+	if f.names != nil {
+		alternatives = append(alternatives, "(?i:"+strings.Join(f.names[lo-f.min:hi-f.min+1], "|")+")")
+	}
+	return "(" + strings.Join(alternatives, "|") + ")"
+}
+
+// numbers returns the patterns of the decimal numbers from lo to hi, at most 99, the single digits with an optional
+// leading zero.
+func numbers(lo, hi int) []string {
+	var patterns []string
+	if lo <= 9 {
+		patterns = append(patterns, "0?"+digits(lo, min(hi, 9)))
+		lo = 10
+	}
+	for lo <= hi {
+		tens := lo / 10
+		if last := min(hi, tens*10+9); lo%10 != 0 || last%10 != 9 {
+			// a partial ten
+			patterns = append(patterns, fmt.Sprint(tens)+digits(lo%10, last%10))
+			lo = last + 1
+			continue
+		}
+		// the tens which are whole up to hi
+		lastTens := tens
+		for (lastTens+1)*10+9 <= hi {
+			lastTens++
+		}
+		patterns = append(patterns, digits(tens, lastTens)+"[0-9]")
+		lo = lastTens*10 + 10
+	}
+	return patterns
+}
+
+// digits returns the pattern of the digits from lo to hi.
+func digits(lo, hi int) string {
+	if lo == hi {
+		return fmt.Sprint(lo)
+	}
+	return fmt.Sprintf("[%d-%d]", lo, hi)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admissionpolicy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/robfig/cron"
+)
+
+var _ = Describe("Rules", func() {
+	It("Should accept the same values of every field as the parser of the controller", func() {
+		var values []string
+		for i := 0; i <= 99; i++ {
+			values = append(values, fmt.Sprint(i))
+		}
+		values = append(values, "05", "*", "?", "JAN", "Mar", "dec", "SUN", "fri", "foo")
+		var elements []string
+		for _, a := range values {
+			elements = append(elements, a, a+"/0", a+"/7", a+"/99")
+			for _, b := range values {
+				if a != "*" && a != "?" {
+					elements = append(elements, a+"-"+b)
+				}
+			}
+		}
+		elements = append(elements, "1-5/2", "1-5/0", "1,5", "1-2-3", "1/2/3", "-1", "")
+
+		for i := range cronFields {
+			for _, element := range elements {
+				fields := []string{"*", "*", "*", "*", "*"}
+				fields[i] = element
+				schedule := strings.Join(fields, " ")
+				_, err := cron.ParseStandard(schedule)
+				Expect(Schedule.Allows(schedule)).To(Equal(err == nil), "schedule %q, parser error %v", schedule, err)
+			}
+		}
+	})
+
+	It("Should only accept the descriptors and the durations the parser accepts", func() {
+		for schedule, valid := range map[string]bool{
+			"@daily":       true,
+			"@midnight":    true,
+			"@every 1h30m": true,
+			"@every 90s":   true,
+			"@every 100ms": true,
+			"@Daily":       false,
+			// the pattern is stricter than the parser on the values nobody writes, and the webhook enforces it too
+			"*/100 * * * *":  false,
+			"007 * * * *":    false,
+			"@every 1.5h":    false,
+			"*-5 * * * *":    false,
+			"1,,5 * * * *":   false,
+			"@every":         false,
+			"@every 1x":      false,
+			"@every h":       false,
+			"@fortnightly":   false,
+			"0 2 * * * *":    false,
+			"0 2 * *":        false,
+			"0  2 * * *":     true,
+			"0 2 * * SAT":    true,
+			"*/15 * * * 1-5": true,
+		} {
+			Expect(Schedule.Allows(schedule)).To(Equal(valid), "schedule %q", schedule)
+			if valid {
+				_, err := cron.ParseStandard(schedule)
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}
+	})
+
+	It("Should limit the names to the DNS subdomains the Jobs can be named after", func() {
+		Expect(Name.Allows("nightly-report.v2")).To(BeTrue())
+		Expect(Name.Allows(strings.Repeat("a", nameMaxLength))).To(BeTrue())
+		Expect(Name.Allows(strings.Repeat("a", nameMaxLength+1))).To(BeFalse())
+		Expect(Name.Allows(strings.Repeat("é", 20))).To(BeFalse())
+		Expect(Name.Allows("-report")).To(BeFalse())
+	})
+
+	It("Should match the generated policy of config/admission-policy", func() {
+		var generated bytes.Buffer
+		Expect(Generate(&generated)).To(Succeed())
+		committed, err := ioutil.ReadFile("../../config/admission-policy/validatingadmissionpolicy.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(generated.String()).To(Equal(string(committed)), "run make admission-policy")
+	})
+})
@@ -0,0 +1,263 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package archive writes the finished runs recorded in the JobRuns to an object storage, S3, GCS or Azure Blob
+// Storage, as a JSON document per run, and deletes them once they are older than the retention.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultRetention is how long the archived runs are kept by default.
+	DefaultRetention = 365 * 24 * time.Hour
+	// pruneInterval is how often the runs older than the retention are deleted.
+	pruneInterval = 24 * time.Hour
+	// maxLogTailBytes bounds the logs archived with a run.
+	maxLogTailBytes = 1 << 20
+	// recordSuffix ends the names of the archived runs, the other objects under the prefix are never deleted.
+	recordSuffix = ".json"
+)
+
+var log = logf.Log.WithName("archive")
+
+// Record is the archived document of a run.
+type Record struct {
+	// Cluster is the name of the cluster of the run.
+	Cluster string `json:"cluster,omitempty"`
+	// JobRun is the record of the run.
+	JobRun v1.JobRun `json:"jobRun"`
+	// LogTail is the end of the logs of the last Pod of the run.
+	LogTail string `json:"logTail,omitempty"`
+	// ArchivedAt is when the run was archived.
+	ArchivedAt time.Time `json:"archivedAt"`
+}
+
+// LogReader returns the last lines of the logs of the Job.
+type LogReader func(ctx context.Context, namespace, job string, lines int64) (string, error)
+
+// Archiver archives the finished runs and applies the retention.
+type Archiver struct {
+	store        Store
+	prefix       string
+	cluster      string
+	retention    time.Duration
+	logTailLines int64
+	logs         LogReader
+	now          func() time.Time
+}
+
+// NewArchiver returns an Archiver writing to the store per the settings. The logs are read with the LogReader, if
+// the settings ask for them.
+func NewArchiver(store Store, config configv1.ArchiveConfig, cluster string, logs LogReader) *Archiver {
+	retention := DefaultRetention
+	if config.Retention != nil {
+		retention = config.Retention.Duration
+	}
+	return &Archiver{
+		store:        store,
+		prefix:       config.Prefix,
+		cluster:      cluster,
+		retention:    retention,
+		logTailLines: config.LogTailLines,
+		logs:         logs,
+		now:          time.Now,
+	}
+}
+
+// Setup returns the Archiver writing to the store of the config, with the credentials read from their Secret. The
+// logs are read from the Pods if the config asks for them. It returns nil if no store is configured.
+func Setup(ctx context.Context, config configv1.ArchiveConfig, cluster string, secrets startup.SecretReader,
+	pods corev1client.PodsGetter) (*Archiver, error) {
+	if config.Provider == "" {
+		return nil, nil
+	}
+	credentials, err := secrets(ctx, config.CredentialsSecret)
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewStore(ctx, config, credentials)
+	if err != nil {
+		return nil, err
+	}
+	var logs LogReader
+	if config.LogTailLines > 0 {
+		logs = NewLogReader(pods)
+	}
+	log.Info("archiving the finished runs", "provider", config.Provider, "bucket", config.Bucket)
+	return NewArchiver(store, config, cluster, logs), nil
+}
+
+// Permissions returns the permissions the Archiver of the config needs in the watched namespaces, all of them if
+// none. It needs none without a store.
+func Permissions(config configv1.ArchiveConfig, namespaces []string) []startup.Permission {
+	if config.Provider == "" {
+		return nil
+	}
+	// the archived runs are annotated
+	permissions := startup.Permissions(v1.GroupVersion.Group, "jobruns", []string{"patch"}, namespaces...)
+	if config.CredentialsSecret != "" {
+		namespace, _ := startup.SplitRef(config.CredentialsSecret)
+		permissions = append(permissions, startup.Permissions("", "secrets", []string{"get"}, namespace)...)
+	}
+	if config.LogTailLines > 0 {
+		permissions = append(permissions, startup.Permissions("", "pods", []string{"list"}, namespaces...)...)
+		permissions = append(permissions, startup.Permissions("", "pods/log", []string{"get"}, namespaces...)...)
+	}
+	return permissions
+}
+
+/*
+The runs are laid out by the day they finished, then by namespace, e.g. `2021/06/05/default/report-1622851200.json`,
+so the archive of a day can be fetched at once. Archiving a run again overwrites the same object.
+*/
+
+// Key returns the name of the object of the run.
+func (a *Archiver) Key(run *v1.JobRun) string {
+	finished := run.CreationTimestamp.Time
+	if run.Status.CompletionTime != nil {
+		finished = run.Status.CompletionTime.Time
+	}
+	return fmt.Sprintf("%s%s/%s/%s%s", a.prefix, finished.UTC().Format("2006/01/02"), run.Namespace, run.Name,
+		recordSuffix)
+}
+
+// DryRun makes the Archiver log the runs it would write and delete instead of changing the store, for the dry-run
+// mode of the manager. The store is still listed.
+func (a *Archiver) DryRun() {
+	a.store = dryRunStore{Store: a.store}
+}
+
+// Archive writes the record of the run. The logs are best effort, since the Pods of the run may be gone already.
+func (a *Archiver) Archive(ctx context.Context, run *v1.JobRun) error {
+	record := Record{Cluster: a.cluster, JobRun: *run.DeepCopy(), ArchivedAt: a.now().UTC()}
+	record.JobRun.ManagedFields = nil
+	if a.logTailLines > 0 && a.logs != nil {
+		logTail, err := a.logs(ctx, run.Namespace, run.Spec.Job.Name, a.logTailLines)
+		if err != nil {
+			log.V(1).Info("unable to read the logs of the run", "jobRun", run.Namespace+"/"+run.Name,
+				"error", err.Error())
+		}
+		record.LogTail = logTail
+	}
+
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := a.store.Put(ctx, a.Key(run), body, "application/json"); err != nil {
+		return fmt.Errorf("unable to archive the run: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes the archived runs older than the retention, and returns how many were deleted.
+func (a *Archiver) Prune(ctx context.Context) (int, error) {
+	objects, err := a.store.List(ctx, a.prefix)
+	if err != nil {
+		return 0, fmt.Errorf("unable to list the archived runs: %w", err)
+	}
+	oldest := a.now().Add(-a.retention)
+	deleted := 0
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, recordSuffix) || !object.LastModified.Before(oldest) {
+			continue
+		}
+		if err := a.store.Delete(ctx, object.Key); err != nil {
+			return deleted, fmt.Errorf("unable to delete the archived run %q: %w", object.Key, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// Start implements manager.Runnable, it applies the retention daily until the manager stops.
+func (a *Archiver) Start(ctx context.Context) error {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		if deleted, err := a.Prune(ctx); err != nil {
+			log.Error(err, "unable to apply the retention of the archive")
+		} else if deleted > 0 {
+			log.Info("deleted the archived runs older than the retention", "deleted", deleted)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the leader applies the retention.
+func (a *Archiver) NeedLeaderElection() bool {
+	return true
+}
+
+// dryRunStore is a Store logging the writes and the deletes instead of sending them.
+type dryRunStore struct {
+	Store
+}
+
+// Put implements Store
+func (s dryRunStore) Put(_ context.Context, key string, body []byte, _ string) error {
+	log.Info("would archive a run", "key", key, "size", len(body))
+	return nil
+}
+
+// Delete implements Store
+func (s dryRunStore) Delete(_ context.Context, key string) error {
+	log.Info("would delete an archived run", "key", key)
+	return nil
+}
+
+// NewLogReader returns a LogReader reading the logs of the first container of the last Pod of the Job.
+func NewLogReader(pods corev1client.PodsGetter) LogReader {
+	return func(ctx context.Context, namespace, job string, lines int64) (string, error) {
+		podList, err := pods.Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + job})
+		if err != nil {
+			return "", err
+		}
+		if len(podList.Items) == 0 {
+			return "", fmt.Errorf("no Pod left")
+		}
+		sort.Slice(podList.Items, func(i, j int) bool {
+			return podList.Items[j].CreationTimestamp.Before(&podList.Items[i].CreationTimestamp)
+		})
+		pod := &podList.Items[0]
+		limitBytes := int64(maxLogTailBytes)
+		logs, err := pods.Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container:  pod.Spec.Containers[0].Name,
+			TailLines:  &lines,
+			LimitBytes: &limitBytes,
+		}).DoRaw(ctx)
+		return string(logs), err
+	}
+}
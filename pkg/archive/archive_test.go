@@ -0,0 +1,158 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// memoryStore is a Store keeping the objects in memory.
+type memoryStore struct {
+	objects map[string][]byte
+	times   map[string]time.Time
+}
+
+func (s *memoryStore) Put(_ context.Context, key string, body []byte, _ string) error {
+	s.objects[key] = body
+	return nil
+}
+
+func (s *memoryStore) List(_ context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{Key: key, LastModified: s.times[key]})
+		}
+	}
+	return objects, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+func configFor(provider, endpoint string) configv1.ArchiveConfig {
+	return configv1.ArchiveConfig{Provider: provider, Bucket: "runs", Endpoint: endpoint}
+}
+
+var _ = Describe("Archiver", func() {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 5, 12, 0, 0, 0, time.UTC)
+
+	var store *memoryStore
+	BeforeEach(func() {
+		store = &memoryStore{objects: map[string][]byte{}, times: map[string]time.Time{}}
+	})
+
+	It("Should archive the runs by the day they finished", func() {
+		completed := metav1.NewTime(time.Date(2021, 6, 4, 23, 0, 0, 0, time.UTC))
+		run := &batchv1.JobRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "report-1622847600", Namespace: "default",
+				ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "manager"}}},
+			Spec:   batchv1.JobRunSpec{Job: corev1.ObjectReference{Name: "report-1622847600"}},
+			Status: batchv1.JobRunStatus{CompletionTime: &completed},
+		}
+		logs := func(_ context.Context, namespace, job string, lines int64) (string, error) {
+			Expect(namespace).To(Equal("default"))
+			Expect(job).To(Equal("report-1622847600"))
+			Expect(lines).To(Equal(int64(10)))
+			return "done\n", nil
+		}
+		archiver := NewArchiver(store, configv1.ArchiveConfig{Prefix: "prod/", LogTailLines: 10}, "prod", logs)
+		archiver.now = func() time.Time { return now }
+
+		Expect(archiver.Archive(ctx, run)).To(Succeed())
+		body, ok := store.objects["prod/2021/06/04/default/report-1622847600.json"]
+		Expect(ok).To(BeTrue())
+		var record Record
+		Expect(json.Unmarshal(body, &record)).To(Succeed())
+		Expect(record.Cluster).To(Equal("prod"))
+		Expect(record.JobRun.Name).To(Equal("report-1622847600"))
+		Expect(record.JobRun.ManagedFields).To(BeEmpty())
+		Expect(record.LogTail).To(Equal("done\n"))
+		Expect(record.ArchivedAt).To(BeTemporally("==", now))
+	})
+
+	It("Should delete only the runs older than the retention", func() {
+		for key, age := range map[string]time.Duration{
+			"2020/06/01/default/old.json":    400 * 24 * time.Hour,
+			"2021/06/01/default/recent.json": 4 * 24 * time.Hour,
+			"2020/06/01/README":              400 * 24 * time.Hour,
+		} {
+			store.objects[key] = []byte("{}")
+			store.times[key] = now.Add(-age)
+		}
+		archiver := NewArchiver(store, configv1.ArchiveConfig{}, "", nil)
+		archiver.now = func() time.Time { return now }
+
+		deleted, err := archiver.Prune(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal(1))
+		Expect(store.objects).To(HaveKey("2021/06/01/default/recent.json"))
+		Expect(store.objects).To(HaveKey("2020/06/01/README"))
+		Expect(store.objects).NotTo(HaveKey("2020/06/01/default/old.json"))
+	})
+
+	It("Should neither write nor delete in dry-run mode", func() {
+		store.objects["2020/06/01/default/old.json"] = []byte("{}")
+		store.times["2020/06/01/default/old.json"] = now.Add(-400 * 24 * time.Hour)
+		completed := metav1.NewTime(now)
+		run := &batchv1.JobRun{
+			ObjectMeta: metav1.ObjectMeta{Name: "report-1622894400", Namespace: "default"},
+			Status:     batchv1.JobRunStatus{CompletionTime: &completed},
+		}
+		archiver := NewArchiver(store, configv1.ArchiveConfig{}, "", nil)
+		archiver.now = func() time.Time { return now }
+		archiver.DryRun()
+
+		Expect(archiver.Archive(ctx, run)).To(Succeed())
+		deleted, err := archiver.Prune(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal(1))
+		Expect(store.objects).To(HaveLen(1))
+		Expect(store.objects).To(HaveKey("2020/06/01/default/old.json"))
+	})
+
+	It("Should read the logs of the last Pod of the Job", func() {
+		pod := func(name string, created time.Time) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{
+					"job-name": "report"}, CreationTimestamp: metav1.NewTime(created)},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "main"}}},
+			}
+		}
+		clientset := fake.NewSimpleClientset(pod("report-a", now), pod("report-b", now.Add(time.Minute)))
+		logs, err := NewLogReader(clientset.CoreV1())(ctx, "default", "report", 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logs).To(Equal("fake logs"))
+
+		_, err = NewLogReader(clientset.CoreV1())(ctx, "default", "other", 10)
+		Expect(err).To(MatchError("no Pod left"))
+	})
+})
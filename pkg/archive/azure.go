@@ -0,0 +1,142 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/sigv4"
+)
+
+/*
+Azure Blob Storage is authenticated with a SAS token of the storage account, appended to the URLs of the requests. It
+needs the read, write, delete and list permissions on the container. The token is a credential, so the URLs never
+show up in the errors.
+*/
+
+const azureVersion = "2019-12-12"
+
+// azureStore stores the objects as the block blobs of an Azure Blob Storage container.
+type azureStore struct {
+	client       *http.Client
+	containerURL string
+	sasToken     url.Values
+}
+
+func newAzureStore(config configv1.ArchiveConfig, credentials map[string][]byte) (*azureStore, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("the endpoint of the storage account is required for Azure Blob Storage")
+	}
+	sasToken, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(string(credentials["sasToken"])), "?"))
+	if err != nil || len(sasToken) == 0 {
+		return nil, fmt.Errorf("the credentials of Azure Blob Storage need a valid sasToken key")
+	}
+	return &azureStore{
+		client:       &http.Client{Timeout: time.Minute},
+		containerURL: strings.TrimSuffix(config.Endpoint, "/") + "/" + url.PathEscape(config.Bucket),
+		sasToken:     sasToken,
+	}, nil
+}
+
+// Put implements Store
+func (s *azureStore) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	req, err := s.request(ctx, http.MethodPut, "/"+sigv4.EscapePath(key), nil, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	_, err = do(s.client, req)
+	return err
+}
+
+// azureListResult is the subset of the response of List Blobs which is read.
+type azureListResult struct {
+	Blobs []struct {
+		Name         string `xml:"Name"`
+		LastModified string `xml:"Properties>Last-Modified"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// List implements Store
+func (s *azureStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+	for {
+		req, err := s.request(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := do(s.client, req)
+		if err != nil {
+			return nil, err
+		}
+		var result azureListResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("unable to parse the listing: %w", err)
+		}
+		for _, blob := range result.Blobs {
+			lastModified, err := time.Parse(time.RFC1123, blob.LastModified)
+			if err != nil {
+				return nil, fmt.Errorf("invalid last modification time of blob %q: %w", blob.Name, err)
+			}
+			objects = append(objects, Object{Key: blob.Name, LastModified: lastModified})
+		}
+		if result.NextMarker == "" {
+			return objects, nil
+		}
+		query.Set("marker", result.NextMarker)
+	}
+}
+
+// Delete implements Store
+func (s *azureStore) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, "/"+sigv4.EscapePath(key), nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = do(s.client, req, http.StatusNotFound)
+	return err
+}
+
+// request returns a request to the path under the container, authenticated with the SAS token.
+func (s *azureStore) request(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Request,
+	error) {
+	values := url.Values{}
+	for name, value := range s.sasToken {
+		values[name] = value
+	}
+	for name, value := range query {
+		values[name] = value
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.containerURL+path+"?"+values.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	return req, nil
+}
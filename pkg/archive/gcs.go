@@ -0,0 +1,129 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	defaultGCSEndpoint = "https://storage.googleapis.com"
+	gcsScope           = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+// gcsStore stores the objects in a Google Cloud Storage bucket, with its JSON API.
+type gcsStore struct {
+	client   *http.Client
+	endpoint string
+	bucket   string
+}
+
+func newGCSStore(ctx context.Context, config configv1.ArchiveConfig, credentials map[string][]byte) (*gcsStore,
+	error) {
+	var client *http.Client
+	if key, ok := credentials["credentials.json"]; ok {
+		creds, err := google.CredentialsFromJSON(ctx, key, gcsScope)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Google Cloud credentials: %w", err)
+		}
+		client = oauth2.NewClient(ctx, creds.TokenSource)
+	} else {
+		var err error
+		if client, err = google.DefaultClient(ctx, gcsScope); err != nil {
+			return nil, fmt.Errorf("unable to find the Google Cloud credentials: %w", err)
+		}
+	}
+	client.Timeout = time.Minute
+
+	endpoint := defaultGCSEndpoint
+	if config.Endpoint != "" {
+		endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	}
+	return &gcsStore{client: client, endpoint: endpoint, bucket: url.PathEscape(config.Bucket)}, nil
+}
+
+// Put implements Store
+func (s *gcsStore) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", s.endpoint, s.bucket,
+		url.Values{"uploadType": {"media"}, "name": {key}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	_, err = do(s.client, req)
+	return err
+}
+
+// gcsListResult is the subset of the response of objects.list which is read.
+type gcsListResult struct {
+	Items []struct {
+		Name    string    `json:"name"`
+		Updated time.Time `json:"updated"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// List implements Store
+func (s *gcsStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{"prefix": {prefix}, "fields": {"items(name,updated),nextPageToken"}}
+	for {
+		u := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", s.endpoint, s.bucket, query.Encode())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := do(s.client, req)
+		if err != nil {
+			return nil, err
+		}
+		var result gcsListResult
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("unable to parse the listing: %w", err)
+		}
+		for _, item := range result.Items {
+			objects = append(objects, Object{Key: item.Name, LastModified: item.Updated})
+		}
+		if result.NextPageToken == "" {
+			return objects, nil
+		}
+		query.Set("pageToken", result.NextPageToken)
+	}
+}
+
+// Delete implements Store
+func (s *gcsStore) Delete(ctx context.Context, key string) error {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", s.endpoint, s.bucket, url.PathEscape(key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	_, err = do(s.client, req, http.StatusNotFound)
+	return err
+}
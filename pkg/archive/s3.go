@@ -0,0 +1,144 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/sigv4"
+)
+
+/*
+The requests to S3 are signed with Signature Version 4. Without an endpoint, the bucket is addressed as a virtual host
+of the regional endpoint of AWS; with one, e.g. of MinIO, it is addressed in the path.
+*/
+
+const defaultS3Region = "us-east-1"
+
+// s3Store stores the objects in an S3 bucket.
+type s3Store struct {
+	client *http.Client
+	// baseURL is the URL of the bucket.
+	baseURL *url.URL
+	signer  *sigv4.Signer
+}
+
+func newS3Store(config configv1.ArchiveConfig, credentials map[string][]byte) (*s3Store, error) {
+	accessKeyID, secretAccessKey := string(credentials["accessKeyID"]), string(credentials["secretAccessKey"])
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, fmt.Errorf("the credentials of S3 need the accessKeyID and secretAccessKey keys")
+	}
+	region := config.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	rawURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", config.Bucket, region)
+	if config.Endpoint != "" {
+		rawURL = strings.TrimSuffix(config.Endpoint, "/") + "/" + url.PathEscape(config.Bucket)
+	}
+	baseURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	return &s3Store{
+		client:  &http.Client{Timeout: time.Minute},
+		baseURL: baseURL,
+		signer: &sigv4.Signer{Region: region, Service: "s3", AccessKeyID: strings.TrimSpace(accessKeyID),
+			SecretAccessKey: strings.TrimSpace(secretAccessKey)},
+	}, nil
+}
+
+// Put implements Store
+func (s *s3Store) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	req, err := s.request(ctx, http.MethodPut, key, nil, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.signer.Sign(req, body)
+	_, err = do(s.client, req)
+	return err
+}
+
+// s3ListResult is the subset of the response of ListObjectsV2 which is read.
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List implements Store
+func (s *s3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		req, err := s.request(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		s.signer.Sign(req, nil)
+		body, err := do(s.client, req)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("unable to parse the listing: %w", err)
+		}
+		for _, content := range result.Contents {
+			objects = append(objects, Object{Key: content.Key, LastModified: content.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// Delete implements Store
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	s.signer.Sign(req, nil)
+	_, err = do(s.client, req, http.StatusNotFound)
+	return err
+}
+
+// request returns the request of the object, or of the bucket if the key is empty.
+func (s *s3Store) request(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Request,
+	error) {
+	u := *s.baseURL
+	if key != "" || u.Path == "" {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+		u.RawPath = strings.TrimSuffix(s.baseURL.EscapedPath(), "/") + "/" + sigv4.EscapePath(key)
+	}
+	u.RawQuery = sigv4.CanonicalQuery(query)
+	return http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+}
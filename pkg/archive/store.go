@@ -0,0 +1,105 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+)
+
+// The object storages the runs can be archived to.
+const (
+	ProviderS3        = "S3"
+	ProviderGCS       = "GCS"
+	ProviderAzureBlob = "AzureBlob"
+)
+
+// maxResponseSize bounds the responses read from the object storages, a page of a listing is well under it.
+const maxResponseSize = 16 << 20
+
+// Object is an object of the bucket.
+type Object struct {
+	// Key is the name of the object.
+	Key string
+	// LastModified is when the object was written.
+	LastModified time.Time
+}
+
+// Store writes, lists and deletes the objects of a bucket.
+type Store interface {
+	// Put writes the object.
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	// List returns the objects whose key starts with the prefix.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Delete deletes the object, deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+/*
+The stores speak the REST APIs of the providers directly, like the error reporting does, instead of pulling in the SDKs
+of three clouds. The credentials come from the keys of a Secret: `accessKeyID` and `secretAccessKey` for S3 and the S3
+compatible stores, `credentials.json`, a service account key, for GCS, and `sasToken` for Azure Blob Storage.
+*/
+
+// NewStore returns the store of the archive settings, with the credentials read from the data of the Secret.
+func NewStore(ctx context.Context, config configv1.ArchiveConfig, credentials map[string][]byte) (Store, error) {
+	switch config.Provider {
+	case ProviderS3:
+		return newS3Store(config, credentials)
+	case ProviderGCS:
+		return newGCSStore(ctx, config, credentials)
+	case ProviderAzureBlob:
+		return newAzureStore(config, credentials)
+	}
+	return nil, fmt.Errorf("unknown object storage provider %q", config.Provider)
+}
+
+// do sends the request and returns the body of a successful response. The errors never contain the URL, which may
+// carry a credential like a SAS token, nor the body of the response.
+func do(client *http.Client, req *http.Request, allowed ...int) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			return nil, fmt.Errorf("%s request failed: %w", strings.ToLower(req.Method), urlErr.Err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range allowed {
+		if resp.StatusCode == status {
+			return body, nil
+		}
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected response to %s request: %s", strings.ToLower(req.Method), resp.Status)
+	}
+	return body, nil
+}
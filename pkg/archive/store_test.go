@@ -0,0 +1,124 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stores", func() {
+	ctx := context.Background()
+
+	It("Should list all the pages of an S3 bucket", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/runs"))
+			Expect(r.URL.Query().Get("prefix")).To(Equal("cluster/"))
+			Expect(r.Header.Get("Authorization")).To(HavePrefix("AWS4-HMAC-SHA256 "))
+			if r.URL.Query().Get("continuation-token") == "" {
+				fmt.Fprint(w, `<ListBucketResult><Contents><Key>cluster/a.json</Key>`+
+					`<LastModified>2021-06-05T00:00:00.000Z</LastModified></Contents>`+
+					`<IsTruncated>true</IsTruncated><NextContinuationToken>next</NextContinuationToken>`+
+					`</ListBucketResult>`)
+				return
+			}
+			fmt.Fprint(w, `<ListBucketResult><Contents><Key>cluster/b.json</Key>`+
+				`<LastModified>2021-06-06T00:00:00.000Z</LastModified></Contents></ListBucketResult>`)
+		}))
+		defer server.Close()
+
+		store, err := NewStore(ctx, configFor(ProviderS3, server.URL),
+			map[string][]byte{"accessKeyID": []byte("id"), "secretAccessKey": []byte("secret")})
+		Expect(err).NotTo(HaveOccurred())
+		objects, err := store.List(ctx, "cluster/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects).To(Equal([]Object{
+			{Key: "cluster/a.json", LastModified: time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC)},
+			{Key: "cluster/b.json", LastModified: time.Date(2021, 6, 6, 0, 0, 0, 0, time.UTC)},
+		}))
+	})
+
+	It("Should put the blobs with the SAS token and never report it", func() {
+		var body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/runs/2021/06/05/default/report.json"))
+			Expect(r.URL.Query().Get("sig")).To(Equal("secret"))
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			Expect(r.Header.Get("x-ms-blob-type")).To(Equal("BlockBlob"))
+			data, _ := ioutil.ReadAll(r.Body)
+			body = string(data)
+		}))
+		defer server.Close()
+
+		store, err := NewStore(ctx, configFor(ProviderAzureBlob, server.URL),
+			map[string][]byte{"sasToken": []byte("?sv=2019-12-12&sig=secret")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Put(ctx, "2021/06/05/default/report.json", []byte("{}"), "application/json")).To(Succeed())
+		Expect(body).To(Equal("{}"))
+
+		err = store.Delete(ctx, "2021/06/05/default/report.json")
+		Expect(err).To(MatchError(ContainSubstring("403 Forbidden")))
+		Expect(err.Error()).NotTo(ContainSubstring("secret"))
+	})
+
+	It("Should upload, list and delete the objects of a GCS bucket", func() {
+		var deleted string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost:
+				Expect(r.URL.Path).To(Equal("/upload/storage/v1/b/runs/o"))
+				Expect(r.URL.Query().Get("name")).To(Equal("default/report.json"))
+			case http.MethodGet:
+				Expect(r.URL.Path).To(Equal("/storage/v1/b/runs/o"))
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"items": []map[string]string{{"name": "default/report.json", "updated": "2021-06-05T00:00:00Z"}},
+				})
+			case http.MethodDelete:
+				deleted = r.URL.EscapedPath()
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		store := &gcsStore{client: server.Client(), endpoint: server.URL, bucket: "runs"}
+		Expect(store.Put(ctx, "default/report.json", []byte("{}"), "application/json")).To(Succeed())
+		objects, err := store.List(ctx, "default/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects).To(HaveLen(1))
+		Expect(objects[0].Key).To(Equal("default/report.json"))
+		Expect(store.Delete(ctx, "default/report.json")).To(Succeed())
+		Expect(deleted).To(Equal("/storage/v1/b/runs/o/default%2Freport.json"))
+	})
+
+	It("Should reject the incomplete credentials", func() {
+		_, err := NewStore(ctx, configFor(ProviderS3, ""), map[string][]byte{"accessKeyID": []byte("id")})
+		Expect(err).To(MatchError(ContainSubstring("secretAccessKey")))
+		_, err = NewStore(ctx, configFor(ProviderAzureBlob, "https://account.blob.core.windows.net"), nil)
+		Expect(err).To(MatchError(ContainSubstring("sasToken")))
+	})
+})
@@ -0,0 +1,137 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records the decisions of the operator about the runs of the CronJobs in an audit stream, apart from
+// the logs: the runs created and skipped, the Jobs deleted, the policies applied and the manual runs triggered. The
+// incident reviews read it to know what was decided, when and why.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+/*
+A decision is a JSON document per line, with the CronJob it is about, the scheduled time of the run, and the inputs the
+decision was made from:
+
+	{"time":"2021-06-05T00:00:01Z","decision":"RunSkipped","namespace":"default","cronJob":"report",
+	 "scheduledTime":"2021-06-05T00:00:00Z","reason":"concurrency_policy",
+	 "inputs":{"activeJobs":1,"concurrencyPolicy":"Forbid"}}
+
+Unlike the logs, the decisions are neither sampled nor filtered by a level. A skipped run is decided again by every
+reconcile until the next run, it is recorded again only when the reason changes. The stream is written by every
+replica: the leader decides about the runs, every replica serves the admission webhooks applying the policies.
+*/
+
+// Kind is the kind of a decision.
+type Kind string
+
+const (
+	// RunCreated is a scheduled run started by the CronJob controller.
+	RunCreated Kind = "RunCreated"
+	// RunSkipped is a scheduled run the CronJob controller did not start.
+	RunSkipped Kind = "RunSkipped"
+	// JobDeleted is a Job deleted by the CronJob controller, per the history limits, the Replace concurrency policy or
+	// a draining maintenance window.
+	JobDeleted Kind = "JobDeleted"
+	// PolicyApplied is a CronJob admitted or denied by the CronJobPolicies selecting it.
+	PolicyApplied Kind = "PolicyApplied"
+	// TriggerAccepted is a manual run started on behalf of a caller.
+	TriggerAccepted Kind = "TriggerAccepted"
+	// TriggerDenied is a manual run refused, e.g. by the concurrency policy.
+	TriggerDenied Kind = "TriggerDenied"
+)
+
+// Decision is a record of the audit stream.
+type Decision struct {
+	// Time is when the decision was made, set when recorded.
+	Time time.Time `json:"time"`
+	// Decision is the kind of the decision.
+	Decision Kind `json:"decision"`
+	// Namespace is the namespace of the CronJob.
+	Namespace string `json:"namespace"`
+	// CronJob is the name of the CronJob.
+	CronJob string `json:"cronJob"`
+	// ScheduledTime is the scheduled time of the run, if the decision is about a run.
+	ScheduledTime *time.Time `json:"scheduledTime,omitempty"`
+	// Job is the name of the Job created or deleted.
+	Job string `json:"job,omitempty"`
+	// Reason tells why, e.g. the reason a run was skipped.
+	Reason string `json:"reason,omitempty"`
+	// Inputs are what the decision was made from.
+	Inputs map[string]interface{} `json:"inputs,omitempty"`
+}
+
+var (
+	lock sync.Mutex
+	out  *json.Encoder
+	now  = time.Now
+	// skipped holds the scheduled time and the reason of the last skipped run recorded per CronJob.
+	skipped = map[[2]string][2]string{}
+)
+
+// SetOutput writes the decisions to the writer, or stops recording them with a nil writer. Nothing is recorded until
+// it is called.
+func SetOutput(w io.Writer) {
+	lock.Lock()
+	defer lock.Unlock()
+	out = nil
+	if w != nil {
+		out = json.NewEncoder(w)
+	}
+	skipped = map[[2]string][2]string{}
+}
+
+// Record writes the decision to the audit stream.
+func Record(decision Decision) {
+	lock.Lock()
+	defer lock.Unlock()
+	if out == nil {
+		return
+	}
+	if decision.Decision == RunSkipped && decision.ScheduledTime != nil {
+		key := [2]string{decision.Namespace, decision.CronJob}
+		run := [2]string{decision.ScheduledTime.UTC().Format(time.RFC3339), decision.Reason}
+		if skipped[key] == run {
+			return
+		}
+		skipped[key] = run
+	}
+	if decision.Time.IsZero() {
+		decision.Time = now().UTC()
+	}
+	if decision.ScheduledTime != nil {
+		scheduled := decision.ScheduledTime.UTC()
+		decision.ScheduledTime = &scheduled
+	}
+	// a decision which can not be written is lost, the logs tell the same story
+	_ = out.Encode(decision)
+}
+
+// Forget drops what is remembered about a deleted CronJob.
+func Forget(namespace, cronJob string) {
+	lock.Lock()
+	defer lock.Unlock()
+	delete(skipped, [2]string{namespace, cronJob})
+}
+
+// At returns a pointer to the time, for the ScheduledTime of a Decision.
+func At(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Record", func() {
+	var buf *bytes.Buffer
+	scheduled := time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		SetOutput(buf)
+		now = func() time.Time { return scheduled.Add(time.Second) }
+	})
+
+	AfterEach(func() {
+		SetOutput(nil)
+		now = time.Now
+	})
+
+	lines := func() []map[string]interface{} {
+		var decisions []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var decision map[string]interface{}
+			Expect(json.Unmarshal([]byte(line), &decision)).To(Succeed())
+			decisions = append(decisions, decision)
+		}
+		return decisions
+	}
+
+	It("writes a JSON line per decision", func() {
+		Record(Decision{Decision: RunCreated, Namespace: "default", CronJob: "report", ScheduledTime: At(scheduled),
+			Job: "report-1622851200", Inputs: map[string]interface{}{"activeJobs": 0}})
+
+		Expect(lines()).To(Equal([]map[string]interface{}{{
+			"time":          "2021-06-05T00:00:01Z",
+			"decision":      "RunCreated",
+			"namespace":     "default",
+			"cronJob":       "report",
+			"scheduledTime": "2021-06-05T00:00:00Z",
+			"job":           "report-1622851200",
+			"inputs":        map[string]interface{}{"activeJobs": float64(0)},
+		}}))
+	})
+
+	It("records a skipped run again only when the reason changes", func() {
+		skip := func(scheduledTime time.Time, reason string) {
+			Record(Decision{Decision: RunSkipped, Namespace: "default", CronJob: "report",
+				ScheduledTime: At(scheduledTime), Reason: reason})
+		}
+		skip(scheduled, "concurrency_policy")
+		skip(scheduled, "concurrency_policy")
+		skip(scheduled, "quota")
+		skip(scheduled.Add(time.Hour), "quota")
+		Forget("default", "report")
+		skip(scheduled.Add(time.Hour), "quota")
+
+		var reasons []string
+		for _, decision := range lines() {
+			reasons = append(reasons, decision["reason"].(string))
+		}
+		Expect(reasons).To(Equal([]string{"concurrency_policy", "quota", "quota", "quota"}))
+	})
+
+	It("records nothing without an output", func() {
+		SetOutput(nil)
+		Record(Decision{Decision: TriggerAccepted, Namespace: "default", CronJob: "report"})
+
+		Expect(buf.Len()).To(BeZero())
+	})
+})
@@ -0,0 +1,225 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package calendar imports the dates of an iCalendar (ICS) feed, see RFC 5545. Only what a holiday calendar needs is
+// understood: the day or days of each event, and its summary.
+package calendar
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+const (
+	// MaxDates is the number of dates a feed may have, so the imported ones fit in the status of the Calendar.
+	MaxDates = 1000
+	// maxFeedSize is the size of the feed which is read at most.
+	maxFeedSize = 4 << 20
+	// maxEventDays is the length of the longest event which is imported, longer ones are rather mistakes.
+	maxEventDays = 366
+	// dateLayout is the layout of the dates of the Calendars.
+	dateLayout = "2006-01-02"
+)
+
+/*
+The events are turned into dates one by one. An event which can not be understood, e.g. a recurring one, is skipped
+and counted, instead of failing the whole import, the feeds are edited by hand more often than not. A feed which is
+not a calendar, or which has too many dates, is an error: the dates imported last time are better than a half of
+them.
+*/
+
+// Fetch downloads the feed at the URL and returns its dates, see Parse.
+func Fetch(ctx context.Context, client *http.Client, url string) ([]v1.CalendarDate, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	// the body is not reported, it may come from anywhere the controller can reach
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	body := &io.LimitedReader{R: resp.Body, N: maxFeedSize + 1}
+	dates, skipped, err := Parse(body)
+	if err == nil && body.N <= 0 {
+		err = fmt.Errorf("the feed is larger than %d bytes", maxFeedSize)
+	}
+	return dates, skipped, err
+}
+
+// Parse returns the dates of the events of the feed sorted, and the number of the events which were skipped. A date
+// of several events has the name of the first one.
+func Parse(r io.Reader) ([]v1.CalendarDate, int, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(lines) == 0 || !strings.EqualFold(lines[0], "BEGIN:VCALENDAR") {
+		return nil, 0, errors.New("not an iCalendar feed")
+	}
+
+	names := map[string]string{}
+	skipped := 0
+	var event map[string]property
+	for _, line := range lines {
+		p := parseProperty(line)
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			event = map[string]property{}
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT") && event != nil:
+			days, err := eventDays(event)
+			if err != nil {
+				skipped++
+			}
+			for _, day := range days {
+				if _, ok := names[day]; !ok {
+					names[day] = unescape(event["SUMMARY"].value)
+				}
+			}
+			if len(names) > MaxDates {
+				return nil, 0, fmt.Errorf("the feed has more than %d dates", MaxDates)
+			}
+			event = nil
+		case event != nil:
+			if _, ok := event[p.name]; !ok {
+				event[p.name] = p
+			}
+		}
+	}
+
+	dates := make([]v1.CalendarDate, 0, len(names))
+	for day, name := range names {
+		dates = append(dates, v1.CalendarDate{Date: day, Name: name})
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Date < dates[j].Date })
+	return dates, skipped, nil
+}
+
+// eventDays returns the days of the event, none if it was cancelled.
+func eventDays(event map[string]property) ([]string, error) {
+	if strings.EqualFold(event["STATUS"].value, "CANCELLED") {
+		return nil, nil
+	}
+	if _, ok := event["RRULE"]; ok {
+		return nil, errors.New("recurring events are not supported")
+	}
+	start, ok := event["DTSTART"]
+	if !ok {
+		return nil, errors.New("missing DTSTART")
+	}
+	first, _, err := parseDate(start.value)
+	if err != nil {
+		return nil, err
+	}
+
+	// the end of an event is exclusive, an event without one lasts a day
+	last := first
+	if end, ok := event["DTEND"]; ok {
+		day, midnight, err := parseDate(end.value)
+		if err != nil {
+			return nil, err
+		}
+		if last = day; midnight {
+			last = day.AddDate(0, 0, -1)
+		}
+		if last.Before(first) {
+			last = first
+		}
+	}
+	if last.Sub(first) >= maxEventDays*24*time.Hour {
+		return nil, fmt.Errorf("the event lasts more than %d days", maxEventDays)
+	}
+
+	var days []string
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format(dateLayout))
+	}
+	return days, nil
+}
+
+// parseDate returns the day of a DATE or a DATE-TIME value, and whether the value is the start of the day. The
+// DATE-TIME values are taken in the time zone they are written in.
+func parseDate(value string) (time.Time, bool, error) {
+	if len(value) < 8 {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	day, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	clock := strings.TrimSuffix(value[8:], "Z")
+	return day, clock == "" || clock == "T000000", nil
+}
+
+// property is a content line of the feed.
+type property struct {
+	name, value string
+}
+
+// parseProperty splits a content line into its upper-cased name and its value, the parameters are dropped. The colon
+// separating the value may appear in the quoted parameter values.
+func parseProperty(line string) property {
+	quoted := false
+	for i, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ':' && !quoted:
+			name := line[:i]
+			if j := strings.IndexByte(name, ';'); j >= 0 {
+				name = name[:j]
+			}
+			return property{name: strings.ToUpper(name), value: line[i+1:]}
+		}
+	}
+	return property{name: strings.ToUpper(line)}
+}
+
+// unfold returns the content lines of the feed, joining the lines folded on a space or a tab.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFeedSize)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// unescape resolves the escapes of a TEXT value.
+var unescape = strings.NewReplacer(`\\`, `\`, `\;`, `;`, `\,`, `,`, `\n`, " ", `\N`, " ").Replace
@@ -0,0 +1,112 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package calendar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const feed = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20210101\r\n" +
+	"DTEND;VALUE=DATE:20210102\r\n" +
+	"SUMMARY:New Year's Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20210512\r\n" +
+	"DTEND;VALUE=DATE:20210516\r\n" +
+	"SUMMARY:Ramadan \r\n" +
+	" Feast\\, Holiday\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;TZID=\"Europe/Istanbul:Turkey\":20210519T090000\r\n" +
+	"SUMMARY:Youth and Sports Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20210101\r\n" +
+	"SUMMARY:Duplicate\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20210301\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20210101\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:tomorrow\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+var _ = Describe("Parse", func() {
+	It("Should import the days of the events", func() {
+		dates, skipped, err := Parse(strings.NewReader(feed))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skipped).To(Equal(2))
+		Expect(dates).To(Equal([]v1.CalendarDate{
+			{Date: "2021-01-01", Name: "New Year's Day"},
+			{Date: "2021-05-12", Name: "Ramadan Feast, Holiday"},
+			{Date: "2021-05-13", Name: "Ramadan Feast, Holiday"},
+			{Date: "2021-05-14", Name: "Ramadan Feast, Holiday"},
+			{Date: "2021-05-15", Name: "Ramadan Feast, Holiday"},
+			{Date: "2021-05-19", Name: "Youth and Sports Day"},
+		}))
+	})
+
+	It("Should reject what is not a calendar", func() {
+		_, _, err := Parse(strings.NewReader("<html></html>"))
+		Expect(err).To(MatchError("not an iCalendar feed"))
+	})
+
+	It("Should reject the feeds with too many dates", func() {
+		_, _, err := Parse(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20210101\nDTEND:20240101\n" +
+			"END:VEVENT\nBEGIN:VEVENT\nDTSTART:20220101\nDTEND:20221231\nEND:VEVENT\n" +
+			"BEGIN:VEVENT\nDTSTART:20230101\nDTEND:20231231\nEND:VEVENT\n" +
+			"BEGIN:VEVENT\nDTSTART:20240101\nDTEND:20241230\nEND:VEVENT\nEND:VCALENDAR\n"))
+		Expect(err).To(MatchError(ContainSubstring("more than 1000 dates")))
+	})
+})
+
+var _ = Describe("Fetch", func() {
+	It("Should download the feed", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/holidays.ics" {
+				http.Error(w, "secret", http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(feed))
+		}))
+		defer server.Close()
+
+		dates, skipped, err := Fetch(context.Background(), server.Client(), server.URL+"/holidays.ics")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dates).To(HaveLen(6))
+		Expect(skipped).To(Equal(2))
+
+		_, _, err = Fetch(context.Background(), server.Client(), server.URL+"/missing.ics")
+		Expect(err).To(MatchError("unexpected response 404 Not Found"))
+	})
+})
@@ -0,0 +1,180 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotation
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// keyPair is a PEM encoded certificate with its private key.
+type keyPair struct {
+	cert []byte
+	key  []byte
+}
+
+// generateCA creates a self signed certificate authority which is valid for the given duration.
+func generateCA(commonName string, now time.Time, validity time.Duration) (*keyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return encode(der, key)
+}
+
+// generateServingCert creates a serving certificate for the given DNS names, signed by the given CA.
+func generateServingCert(ca *keyPair, dnsNames []string, now time.Time, validity time.Duration) (*keyPair, error) {
+	caPair, err := tls.X509KeyPair(ca.cert, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := x509.ParseCertificate(caPair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caPair.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return encode(der, key)
+}
+
+// validateServingCert checks that the serving certificate is signed by the CA, is valid for all the DNS names and
+// does not expire within the given lookahead.
+func validateServingCert(ca, serving *keyPair, dnsNames []string, now time.Time, lookahead time.Duration) error {
+	if ca == nil || serving == nil {
+		return errors.New("certificates are missing")
+	}
+
+	caCert, err := parseCert(ca)
+	if err != nil {
+		return fmt.Errorf("invalid CA: %w", err)
+	}
+	if now.Add(lookahead).After(caCert.NotAfter) {
+		return fmt.Errorf("CA expires at %s", caCert.NotAfter)
+	}
+
+	servingCert, err := parseCert(serving)
+	if err != nil {
+		return fmt.Errorf("invalid serving certificate: %w", err)
+	}
+	if now.Add(lookahead).After(servingCert.NotAfter) {
+		return fmt.Errorf("serving certificate expires at %s", servingCert.NotAfter)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	for _, name := range dnsNames {
+		if _, err := servingCert.Verify(x509.VerifyOptions{DNSName: name, Roots: pool, CurrentTime: now}); err != nil {
+			return fmt.Errorf("serving certificate is not valid for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// parseCert checks the key pair and returns its certificate.
+func parseCert(pair *keyPair) (*x509.Certificate, error) {
+	tlsPair, err := tls.X509KeyPair(pair.cert, pair.key)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(tlsPair.Certificate[0])
+}
+
+func encode(der []byte, key *ecdsa.PrivateKey) (*keyPair, error) {
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var certPEM, keyPEM bytes.Buffer
+	if err := pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		return nil, err
+	}
+	if err := pem.Encode(&keyPEM, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}); err != nil {
+		return nil, err
+	}
+	return &keyPair{cert: certPEM.Bytes(), key: keyPEM.Bytes()}, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// GenerateSelfSigned creates a serving certificate for the given DNS names, signed by a throwaway CA. It returns the
+// PEM encoded certificate and private key.
+func GenerateSelfSigned(dnsNames []string, validity time.Duration) (cert, key []byte, err error) {
+	now := time.Now()
+	ca, err := generateCA("kubebuilder-tutorial-self-signed-ca", now, validity)
+	if err != nil {
+		return nil, nil, err
+	}
+	serving, err := generateServingCert(ca, dnsNames, now, validity)
+	if err != nil {
+		return nil, nil, err
+	}
+	return serving.cert, serving.key, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotation
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Certificates", func() {
+	dnsNames := []string{"webhook-service.system.svc", "webhook-service.system.svc.cluster.local"}
+	now := time.Now()
+
+	It("Should generate a serving certificate valid for the DNS names", func() {
+		ca, err := generateCA("test-ca", now, caValidity)
+		Expect(err).NotTo(HaveOccurred())
+		serving, err := generateServingCert(ca, dnsNames, now, servingValidity)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(validateServingCert(ca, serving, dnsNames, now, lookahead)).To(Succeed())
+	})
+
+	It("Should reject a serving certificate which is about to expire", func() {
+		ca, err := generateCA("test-ca", now, caValidity)
+		Expect(err).NotTo(HaveOccurred())
+		serving, err := generateServingCert(ca, dnsNames, now, lookahead/2)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(validateServingCert(ca, serving, dnsNames, now, lookahead)).NotTo(Succeed())
+	})
+
+	It("Should reject a serving certificate signed by another CA", func() {
+		ca, err := generateCA("test-ca", now, caValidity)
+		Expect(err).NotTo(HaveOccurred())
+		otherCA, err := generateCA("other-ca", now, caValidity)
+		Expect(err).NotTo(HaveOccurred())
+		serving, err := generateServingCert(otherCA, dnsNames, now, servingValidity)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(validateServingCert(ca, serving, dnsNames, now, lookahead)).NotTo(Succeed())
+	})
+
+	It("Should reject a serving certificate issued for another service", func() {
+		ca, err := generateCA("test-ca", now, caValidity)
+		Expect(err).NotTo(HaveOccurred())
+		serving, err := generateServingCert(ca, []string{"other.system.svc"}, now, servingValidity)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(validateServingCert(ca, serving, dnsNames, now, lookahead)).NotTo(Succeed())
+	})
+})
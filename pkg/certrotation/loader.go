@@ -0,0 +1,86 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotation
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	certFile = "tls.crt"
+	keyFile  = "tls.key"
+
+	selfSignedValidity = 365 * 24 * time.Hour
+)
+
+// CertificateLoader loads the serving certificate of a server from a directory, reloading it when the files change.
+// A self-signed certificate is generated if the directory does not hold any.
+type CertificateLoader struct {
+	// Dir holds the tls.crt and tls.key files.
+	Dir string
+	// Server names the server in the logs, e.g. `metrics`.
+	Server string
+
+	lock    sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// GetCertificate can be used as tls.Config.GetCertificate
+func (l *CertificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	certPath, keyPath := filepath.Join(l.Dir, certFile), filepath.Join(l.Dir, keyFile)
+	info, err := os.Stat(certPath)
+	if l.Dir == "" || os.IsNotExist(err) {
+		if l.cert == nil {
+			log.Info("no certificate found, generating a self-signed one", "server", l.Server, "dir", l.Dir)
+			certPEM, keyPEM, err := GenerateSelfSigned([]string{"localhost"}, selfSignedValidity)
+			if err != nil {
+				return nil, err
+			}
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, err
+			}
+			l.cert = &cert
+		}
+		return l.cert, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if l.cert == nil || !info.ModTime().Equal(l.modTime) {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			if l.cert != nil {
+				// the files may be in the middle of an update, keep serving the previous certificate
+				log.Error(err, "unable to reload the certificate", "server", l.Server)
+				return l.cert, nil
+			}
+			return nil, err
+		}
+		l.cert, l.modTime = &cert, info.ModTime()
+	}
+	return l.cert, nil
+}
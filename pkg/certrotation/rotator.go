@@ -0,0 +1,328 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certrotation generates and rotates the serving certificate of the webhook server, so that the operator
+// can run without cert-manager.
+package certrotation
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+/*
+The CA and the serving certificate are kept in a Secret, which is the source of truth shared by all the replicas.
+Every replica makes sure the Secret holds a valid pair, writes the pair to the certificate directory of its webhook
+server (which reloads the files when they change) and injects the CA into the webhook configurations.
+
+The Secret is read uncached by its name, the rotator neither lists nor watches the Secrets. The ClusterRole of the
+manager grants no access to the Secrets for it: the namespaced Role of config/rbac/cert_rotation_role.yaml allows to
+create it and to read and update it by name.
+*/
+
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=get;list;watch;create;update;patch
+
+var log = logf.Log.WithName("cert-rotation")
+
+const (
+	caCertKey      = "ca.crt"
+	caKeyKey       = "ca.key"
+	servingCertKey = "tls.crt"
+	servingKeyKey  = "tls.key"
+
+	caValidity      = 10 * 365 * 24 * time.Hour
+	servingValidity = 365 * 24 * time.Hour
+	// lookahead is how long before their expiry the certificates are rotated.
+	lookahead = 30 * 24 * time.Hour
+	// checkInterval is how often the certificates are checked.
+	checkInterval = 12 * time.Hour
+
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Options configures the certificate rotation.
+type Options struct {
+	// Enabled turns on the built-in certificate management.
+	Enabled bool
+	// Namespace of the Secret and the webhook Service, defaults to the namespace of the pod.
+	Namespace string
+	// SecretName is the name of the Secret holding the certificates.
+	SecretName string
+	// ServiceName is the name of the Service in front of the webhook server.
+	ServiceName string
+	// MutatingWebhookConfiguration is the name of the MutatingWebhookConfiguration to inject the CA into.
+	MutatingWebhookConfiguration string
+	// ValidatingWebhookConfiguration is the name of the ValidatingWebhookConfiguration to inject the CA into.
+	ValidatingWebhookConfiguration string
+	// RegisterWebhooks creates or updates the webhook configurations instead of only injecting the CA into them.
+	RegisterWebhooks bool
+}
+
+// BindFlags binds the certificate rotation flags to the given flagset.
+func (o *Options) BindFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Enabled, "enable-cert-rotation", false,
+		"Generate and rotate the webhook serving certificate instead of relying on cert-manager.")
+	fs.StringVar(&o.Namespace, "cert-rotation-namespace", "",
+		"Namespace of the certificate Secret and the webhook Service. Defaults to the namespace of the pod.")
+	fs.StringVar(&o.SecretName, "cert-rotation-secret", "webhook-server-cert",
+		"Name of the Secret holding the webhook certificates.")
+	fs.StringVar(&o.ServiceName, "cert-rotation-service", "kubebuilder-tutorial-webhook-service",
+		"Name of the Service in front of the webhook server.")
+	fs.StringVar(&o.MutatingWebhookConfiguration, "cert-rotation-mutating-webhook",
+		"kubebuilder-tutorial-mutating-webhook-configuration",
+		"Name of the MutatingWebhookConfiguration to inject the CA bundle into.")
+	fs.StringVar(&o.ValidatingWebhookConfiguration, "cert-rotation-validating-webhook",
+		"kubebuilder-tutorial-validating-webhook-configuration",
+		"Name of the ValidatingWebhookConfiguration to inject the CA bundle into.")
+	fs.BoolVar(&o.RegisterWebhooks, "cert-rotation-register-webhooks", true,
+		"Create or update the webhook configurations with the webhooks served by the manager, instead of only "+
+			"injecting the CA bundle into the existing ones.")
+}
+
+// Rotator keeps the webhook serving certificate valid. It has to run before the webhook server starts, so the
+// Client should not be backed by the cache of the manager.
+type Rotator struct {
+	Client client.Client
+	Options
+	// CertDir is the directory the webhook server loads its certificate from.
+	CertDir string
+	// CertName and KeyName are the file names of the certificate and the key in CertDir, default to tls.crt and
+	// tls.key.
+	CertName, KeyName string
+	// MutatingWebhooks and ValidatingWebhooks are the webhooks registered in the webhook configurations when
+	// RegisterWebhooks is set. The webhook configuration of a kind without webhooks is left alone.
+	MutatingWebhooks   []admissionregistrationv1.MutatingWebhook
+	ValidatingWebhooks []admissionregistrationv1.ValidatingWebhook
+}
+
+var _ manager.Runnable = &Rotator{}
+
+// Start implements manager.Runnable, it keeps the certificates fresh until the context is done.
+func (r *Rotator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.EnsureCerts(ctx); err != nil {
+			log.Error(err, "unable to rotate the webhook certificates")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica serves webhooks.
+func (r *Rotator) NeedLeaderElection() bool {
+	return false
+}
+
+// EnsureCerts makes sure a valid certificate is stored in the Secret and the certificate directory, and that its
+// CA is injected into the webhook configurations.
+func (r *Rotator) EnsureCerts(ctx context.Context) error {
+	if r.Namespace == "" {
+		namespace, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+		if err != nil {
+			return fmt.Errorf("cert rotation namespace is not set and unable to detect it: %w", err)
+		}
+		r.Namespace = strings.TrimSpace(string(namespace))
+	}
+
+	ca, serving, err := r.ensureSecret(ctx)
+	if err != nil {
+		return err
+	}
+	if err := r.writeCertDir(serving); err != nil {
+		return err
+	}
+	return r.injectCABundle(ctx, ca.cert)
+}
+
+func (r *Rotator) dnsNames() []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", r.ServiceName, r.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", r.ServiceName, r.Namespace),
+	}
+}
+
+// ensureSecret returns the CA and the serving certificate from the Secret, refreshing them if they are invalid or
+// about to expire.
+func (r *Rotator) ensureSecret(ctx context.Context) (ca, serving *keyPair, err error) {
+	key := types.NamespacedName{Namespace: r.Namespace, Name: r.SecretName}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret := &corev1.Secret{}
+		err := r.Client.Get(ctx, key, secret)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		exists := err == nil
+
+		now := time.Now()
+		ca, serving = pairFromSecret(secret, caCertKey, caKeyKey), pairFromSecret(secret, servingCertKey, servingKeyKey)
+		if err := validateServingCert(ca, serving, r.dnsNames(), now, lookahead); err == nil {
+			return nil
+		} else if exists {
+			log.Info("refreshing the webhook certificates", "reason", err.Error())
+		}
+
+		// Keep the CA as long as it is valid, so the clients trusting it are not disrupted.
+		if ca == nil {
+			ca = &keyPair{}
+		}
+		if caCert, err := parseCert(ca); err != nil || now.Add(lookahead).After(caCert.NotAfter) {
+			if ca, err = generateCA("kubebuilder-tutorial-webhook-ca", now, caValidity); err != nil {
+				return err
+			}
+		}
+		if serving, err = generateServingCert(ca, r.dnsNames(), now, servingValidity); err != nil {
+			return err
+		}
+
+		secret.Name, secret.Namespace = key.Name, key.Namespace
+		secret.Data = map[string][]byte{
+			caCertKey:      ca.cert,
+			caKeyKey:       ca.key,
+			servingCertKey: serving.cert,
+			servingKeyKey:  serving.key,
+		}
+		if !exists {
+			secret.Type = corev1.SecretTypeTLS
+			err := r.Client.Create(ctx, secret)
+			if apierrors.IsAlreadyExists(err) {
+				// another replica was faster, take its certificates on the next attempt
+				return apierrors.NewConflict(corev1.Resource("secrets"), key.Name, err)
+			}
+			return err
+		}
+		return r.Client.Update(ctx, secret)
+	})
+	return ca, serving, err
+}
+
+func pairFromSecret(secret *corev1.Secret, certKey, keyKey string) *keyPair {
+	if len(secret.Data[certKey]) == 0 || len(secret.Data[keyKey]) == 0 {
+		return nil
+	}
+	return &keyPair{cert: secret.Data[certKey], key: secret.Data[keyKey]}
+}
+
+// writeCertDir writes the serving certificate to the certificate directory, if it changed.
+func (r *Rotator) writeCertDir(serving *keyPair) error {
+	if r.CertDir == "" {
+		// the same default as the webhook server of controller-runtime
+		r.CertDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+	}
+	if err := os.MkdirAll(r.CertDir, 0700); err != nil {
+		return err
+	}
+
+	certName, keyName := r.CertName, r.KeyName
+	if certName == "" {
+		certName = servingCertKey
+	}
+	if keyName == "" {
+		keyName = servingKeyKey
+	}
+
+	for name, content := range map[string][]byte{certName: serving.cert, keyName: serving.key} {
+		path := filepath.Join(r.CertDir, name)
+		if existing, err := ioutil.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+			continue
+		}
+		if err := ioutil.WriteFile(path, content, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injectCABundle registers the webhooks with the CA bundle, or sets the CA bundle of all the webhooks in the existing
+// webhook configurations.
+func (r *Rotator) injectCABundle(ctx context.Context, caBundle []byte) error {
+	if r.MutatingWebhookConfiguration != "" && r.RegisterWebhooks && len(r.MutatingWebhooks) > 0 {
+		if err := r.registerMutatingWebhooks(ctx, caBundle); err != nil {
+			return err
+		}
+	} else if r.MutatingWebhookConfiguration != "" {
+		config := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: r.MutatingWebhookConfiguration}, config); apierrors.IsNotFound(err) {
+			log.V(1).Info("mutating webhook configuration not found, skipping the CA injection", "name", r.MutatingWebhookConfiguration)
+		} else if err != nil {
+			return fmt.Errorf("unable to get the mutating webhook configuration: %w", err)
+		}
+		patch := client.MergeFrom(config.DeepCopy())
+		changed := false
+		for i := range config.Webhooks {
+			if !bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, caBundle) {
+				config.Webhooks[i].ClientConfig.CABundle = caBundle
+				changed = true
+			}
+		}
+		if changed {
+			if err := r.Client.Patch(ctx, config, patch); err != nil {
+				return err
+			}
+			log.Info("injected the CA bundle", "mutatingWebhookConfiguration", config.Name)
+		}
+	}
+
+	if r.ValidatingWebhookConfiguration != "" && r.RegisterWebhooks && len(r.ValidatingWebhooks) > 0 {
+		if err := r.registerValidatingWebhooks(ctx, caBundle); err != nil {
+			return err
+		}
+	} else if r.ValidatingWebhookConfiguration != "" {
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: r.ValidatingWebhookConfiguration}, config); apierrors.IsNotFound(err) {
+			log.V(1).Info("validating webhook configuration not found, skipping the CA injection", "name", r.ValidatingWebhookConfiguration)
+		} else if err != nil {
+			return fmt.Errorf("unable to get the validating webhook configuration: %w", err)
+		}
+		patch := client.MergeFrom(config.DeepCopy())
+		changed := false
+		for i := range config.Webhooks {
+			if !bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, caBundle) {
+				config.Webhooks[i].ClientConfig.CABundle = caBundle
+				changed = true
+			}
+		}
+		if changed {
+			if err := r.Client.Patch(ctx, config, patch); err != nil {
+				return err
+			}
+			log.Info("injected the CA bundle", "validatingWebhookConfiguration", config.Name)
+		}
+	}
+	return nil
+}
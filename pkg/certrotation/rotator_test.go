@@ -0,0 +1,190 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotation
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/config/webhook"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Rotator", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "certrotation")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("Should store the certificates and write them under the configured file names", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		rotator := &Rotator{
+			Client: c,
+			Options: Options{
+				Namespace:   "system",
+				SecretName:  "webhook-server-cert",
+				ServiceName: "webhook-service",
+			},
+			CertDir:  dir,
+			CertName: "serving.pem",
+			KeyName:  "serving-key.pem",
+		}
+		Expect(rotator.EnsureCerts(context.Background())).To(Succeed())
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Namespace: "system", Name: "webhook-server-cert"},
+			secret)).To(Succeed())
+		cert, err := ioutil.ReadFile(filepath.Join(dir, "serving.pem"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cert).To(Equal(secret.Data[servingCertKey]))
+		key, err := ioutil.ReadFile(filepath.Join(dir, "serving-key.pem"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(Equal(secret.Data[servingKeyKey]))
+	})
+
+	It("Should register the webhooks pointing at the Service with the CA bundle", func() {
+		mutating, validating, err := WebhooksFromManifests(webhook.Manifests)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mutating).To(HaveLen(1))
+		Expect(validating).To(HaveLen(1))
+
+		existing := &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "validating-webhook-configuration"},
+			Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "stale.kb.io"}},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build()
+		rotator := &Rotator{
+			Client: c,
+			Options: Options{
+				Namespace:                      "cronjob-system",
+				SecretName:                     "webhook-server-cert",
+				ServiceName:                    "cronjob-webhook-service",
+				MutatingWebhookConfiguration:   "mutating-webhook-configuration",
+				ValidatingWebhookConfiguration: "validating-webhook-configuration",
+				RegisterWebhooks:               true,
+			},
+			CertDir:            dir,
+			MutatingWebhooks:   mutating,
+			ValidatingWebhooks: validating,
+		}
+		Expect(rotator.EnsureCerts(context.Background())).To(Succeed())
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Namespace: "cronjob-system", Name: "webhook-server-cert"},
+			secret)).To(Succeed())
+		mutatingConfig := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Name: "mutating-webhook-configuration"},
+			mutatingConfig)).To(Succeed())
+		Expect(mutatingConfig.Webhooks).To(HaveLen(1))
+		Expect(mutatingConfig.Webhooks[0].Name).To(Equal("mcronjob.kb.io"))
+		Expect(mutatingConfig.Webhooks[0].Rules).To(Equal(mutating[0].Rules))
+		Expect(*mutatingConfig.Webhooks[0].ClientConfig.Service).To(Equal(admissionregistrationv1.ServiceReference{
+			Namespace: "cronjob-system",
+			Name:      "cronjob-webhook-service",
+			Path:      mutating[0].ClientConfig.Service.Path,
+		}))
+		Expect(mutatingConfig.Webhooks[0].ClientConfig.CABundle).To(Equal(secret.Data[caCertKey]))
+
+		validatingConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Name: "validating-webhook-configuration"},
+			validatingConfig)).To(Succeed())
+		Expect(validatingConfig.Webhooks).To(HaveLen(1))
+		Expect(validatingConfig.Webhooks[0].Name).To(Equal("vcronjob.kb.io"))
+		Expect(validatingConfig.Webhooks[0].ClientConfig.Service.Path).To(
+			Equal(pointer.StringPtr("/validate-batch-example-com-v1-cronjob")))
+		Expect(validatingConfig.Webhooks[0].ClientConfig.CABundle).To(Equal(secret.Data[caCertKey]))
+	})
+
+	It("Should keep the settings of the registered webhooks but their client config", func() {
+		_, validating, err := WebhooksFromManifests(webhook.Manifests)
+		Expect(err).NotTo(HaveOccurred())
+
+		selector := &metav1.LabelSelector{MatchLabels: map[string]string{"cronjobs": "enabled"}}
+		existing := &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "validating-webhook-configuration"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+				Name:              "vcronjob.kb.io",
+				NamespaceSelector: selector,
+				TimeoutSeconds:    pointer.Int32Ptr(5),
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service:  &admissionregistrationv1.ServiceReference{Namespace: "old", Name: "old-service"},
+					CABundle: []byte("old CA"),
+				},
+			}},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build()
+		rotator := &Rotator{
+			Client: c,
+			Options: Options{
+				Namespace:                      "cronjob-system",
+				SecretName:                     "webhook-server-cert",
+				ServiceName:                    "cronjob-webhook-service",
+				ValidatingWebhookConfiguration: "validating-webhook-configuration",
+				RegisterWebhooks:               true,
+			},
+			CertDir:            dir,
+			ValidatingWebhooks: validating,
+		}
+		Expect(rotator.EnsureCerts(context.Background())).To(Succeed())
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Namespace: "cronjob-system", Name: "webhook-server-cert"},
+			secret)).To(Succeed())
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		Expect(c.Get(context.Background(), types.NamespacedName{Name: "validating-webhook-configuration"},
+			config)).To(Succeed())
+		Expect(config.Webhooks).To(HaveLen(1))
+		Expect(config.Webhooks[0].NamespaceSelector).To(Equal(selector))
+		Expect(config.Webhooks[0].TimeoutSeconds).To(Equal(pointer.Int32Ptr(5)))
+		Expect(config.Webhooks[0].ClientConfig.Service.Name).To(Equal("cronjob-webhook-service"))
+		Expect(config.Webhooks[0].ClientConfig.CABundle).To(Equal(secret.Data[caCertKey]))
+	})
+
+	It("Should restrict the webhooks to the watched namespaces", func() {
+		mutating, validating, err := WebhooksFromManifests(webhook.Manifests)
+		Expect(err).NotTo(HaveOccurred())
+		SelectNamespaces(mutating, validating, nil)
+		Expect(mutating[0].NamespaceSelector).To(BeNil())
+
+		SelectNamespaces(mutating, validating, []string{"team-a", "team-b"})
+		for _, selector := range []*metav1.LabelSelector{mutating[0].NamespaceSelector,
+			validating[0].NamespaceSelector} {
+			Expect(selector.MatchExpressions).To(ConsistOf(metav1.LabelSelectorRequirement{
+				Key:      "kubernetes.io/metadata.name",
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   []string{"team-a", "team-b"},
+			}))
+		}
+	})
+})
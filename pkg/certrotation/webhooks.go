@@ -0,0 +1,215 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+In a cluster without cert-manager, the webhook configurations can be registered by the manager too, so that applying
+the Deployment is enough. The webhooks generated by controller-gen are embedded in the binary, and the rotator creates
+or updates the webhook configurations with them, pointing at the webhook Service and trusting the CA. The webhook
+configurations are then owned by the manager: the webhooks it does not serve are removed, and the ones it serves are
+added. Only the client config of the webhooks already registered is updated, so the changes made to their other
+settings by hand, like a namespace selector or a timeout, are kept.
+*/
+
+// WebhooksFromManifests returns the webhooks of the webhook configurations in the manifests.
+func WebhooksFromManifests(manifests []byte) (mutating []admissionregistrationv1.MutatingWebhook,
+	validating []admissionregistrationv1.ValidatingWebhook, err error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(manifests)))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			return mutating, validating, nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+
+		object, _, err := scheme.Codecs.UniversalDeserializer().Decode(document, nil, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to decode the webhook configurations: %w", err)
+		}
+		switch config := object.(type) {
+		case *admissionregistrationv1.MutatingWebhookConfiguration:
+			mutating = append(mutating, config.Webhooks...)
+		case *admissionregistrationv1.ValidatingWebhookConfiguration:
+			validating = append(validating, config.Webhooks...)
+		}
+	}
+}
+
+// namespaceNameLabel is the label the API server sets on every namespace to its name, from Kubernetes 1.21 on.
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// SelectNamespaces restricts the webhooks to the objects of the namespaces, they are left as they are if namespaces is
+// empty.
+func SelectNamespaces(mutating []admissionregistrationv1.MutatingWebhook,
+	validating []admissionregistrationv1.ValidatingWebhook, namespaces []string) {
+	if len(namespaces) == 0 {
+		return
+	}
+	selector := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+		Key:      namespaceNameLabel,
+		Operator: metav1.LabelSelectorOpIn,
+		Values:   namespaces,
+	}}}
+	for i := range mutating {
+		mutating[i].NamespaceSelector = selector.DeepCopy()
+	}
+	for i := range validating {
+		validating[i].NamespaceSelector = selector.DeepCopy()
+	}
+}
+
+// serviceReference returns the reference to the webhook Service, with the path and the port of the given reference.
+func (r *Rotator) serviceReference(
+	reference *admissionregistrationv1.ServiceReference) *admissionregistrationv1.ServiceReference {
+	service := &admissionregistrationv1.ServiceReference{Namespace: r.Namespace, Name: r.ServiceName}
+	if reference != nil {
+		service.Path, service.Port = reference.Path, reference.Port
+	}
+	return service
+}
+
+// mergeMutatingWebhooks returns the webhooks with the settings of the registered webhooks of the same name, but
+// their client config.
+func mergeMutatingWebhooks(registered,
+	webhooks []admissionregistrationv1.MutatingWebhook) []admissionregistrationv1.MutatingWebhook {
+	merged := make([]admissionregistrationv1.MutatingWebhook, len(webhooks))
+	for i, webhook := range webhooks {
+		merged[i] = webhook
+		for j := range registered {
+			if registered[j].Name == webhook.Name {
+				registered[j].DeepCopyInto(&merged[i])
+				merged[i].ClientConfig = webhook.ClientConfig
+			}
+		}
+	}
+	return merged
+}
+
+// registerMutatingWebhooks creates or updates the MutatingWebhookConfiguration with the webhooks of the rotator.
+func (r *Rotator) registerMutatingWebhooks(ctx context.Context, caBundle []byte) error {
+	webhooks := make([]admissionregistrationv1.MutatingWebhook, len(r.MutatingWebhooks))
+	for i, webhook := range r.MutatingWebhooks {
+		webhook.DeepCopyInto(&webhooks[i])
+		webhooks[i].ClientConfig = admissionregistrationv1.WebhookClientConfig{
+			Service:  r.serviceReference(webhook.ClientConfig.Service),
+			CABundle: caBundle,
+		}
+	}
+
+	config := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: r.MutatingWebhookConfiguration}, config)
+	if apierrors.IsNotFound(err) {
+		config = &admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: r.MutatingWebhookConfiguration},
+			Webhooks:   webhooks,
+		}
+		if err := r.Client.Create(ctx, config); err != nil {
+			return fmt.Errorf("unable to create the mutating webhook configuration: %w", err)
+		}
+		log.Info("registered the webhooks", "mutatingWebhookConfiguration", config.Name)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to get the mutating webhook configuration: %w", err)
+	}
+
+	patch := client.MergeFrom(config.DeepCopy())
+	resourceVersion := config.ResourceVersion
+	config.Webhooks = mergeMutatingWebhooks(config.Webhooks, webhooks)
+	if err := r.Client.Patch(ctx, config, patch); err != nil {
+		return fmt.Errorf("unable to update the mutating webhook configuration: %w", err)
+	}
+	if config.ResourceVersion != resourceVersion {
+		log.Info("updated the webhooks", "mutatingWebhookConfiguration", config.Name)
+	}
+	return nil
+}
+
+// mergeValidatingWebhooks returns the webhooks with the settings of the registered webhooks of the same name, but
+// their client config.
+func mergeValidatingWebhooks(registered,
+	webhooks []admissionregistrationv1.ValidatingWebhook) []admissionregistrationv1.ValidatingWebhook {
+	merged := make([]admissionregistrationv1.ValidatingWebhook, len(webhooks))
+	for i, webhook := range webhooks {
+		merged[i] = webhook
+		for j := range registered {
+			if registered[j].Name == webhook.Name {
+				registered[j].DeepCopyInto(&merged[i])
+				merged[i].ClientConfig = webhook.ClientConfig
+			}
+		}
+	}
+	return merged
+}
+
+// registerValidatingWebhooks creates or updates the ValidatingWebhookConfiguration with the webhooks of the rotator.
+func (r *Rotator) registerValidatingWebhooks(ctx context.Context, caBundle []byte) error {
+	webhooks := make([]admissionregistrationv1.ValidatingWebhook, len(r.ValidatingWebhooks))
+	for i, webhook := range r.ValidatingWebhooks {
+		webhook.DeepCopyInto(&webhooks[i])
+		webhooks[i].ClientConfig = admissionregistrationv1.WebhookClientConfig{
+			Service:  r.serviceReference(webhook.ClientConfig.Service),
+			CABundle: caBundle,
+		}
+	}
+
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: r.ValidatingWebhookConfiguration}, config)
+	if apierrors.IsNotFound(err) {
+		config = &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: r.ValidatingWebhookConfiguration},
+			Webhooks:   webhooks,
+		}
+		if err := r.Client.Create(ctx, config); err != nil {
+			return fmt.Errorf("unable to create the validating webhook configuration: %w", err)
+		}
+		log.Info("registered the webhooks", "validatingWebhookConfiguration", config.Name)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to get the validating webhook configuration: %w", err)
+	}
+
+	patch := client.MergeFrom(config.DeepCopy())
+	resourceVersion := config.ResourceVersion
+	config.Webhooks = mergeValidatingWebhooks(config.Webhooks, webhooks)
+	if err := r.Client.Patch(ctx, config, patch); err != nil {
+		return fmt.Errorf("unable to update the validating webhook configuration: %w", err)
+	}
+	if config.ResourceVersion != resourceVersion {
+		log.Info("updated the webhooks", "validatingWebhookConfiguration", config.Name)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cloudevents emits the lifecycle of the runs of the CronJobs as CloudEvents, to an HTTP endpoint or a Kafka
+// topic, for the event-driven systems reacting to the outcomes of the batches without watching the API server.
+package cloudevents
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+)
+
+/*
+The events follow the CloudEvents 1.0 specification. Their type is one of the constants below, their source names the
+CronJob, e.g. `/apis/batch.example.com/v1/namespaces/team-a/cronjobs/report`, and their subject is the Job of the run.
+The ID of an event is derived from the run and the type, so a consumer can drop the duplicates by source and ID: the
+controllers emit after their writes succeed, and an event may still be emitted twice after a restart.
+
+Like the notifications, the events are queued and sent in the background, retried with a backoff up to 5 attempts,
+and dropped on shutdown after a grace period.
+*/
+
+var log = logf.Log.WithName("cloudevents")
+
+// The types of the events.
+const (
+	// TypeRunScheduled is a Job created for a run, scheduled, backfilled or started by hand.
+	TypeRunScheduled = "com.example.batch.run.scheduled"
+	// TypeRunStarted is a run whose Job started.
+	TypeRunStarted = "com.example.batch.run.started"
+	// TypeRunSucceeded is a run whose Job completed.
+	TypeRunSucceeded = "com.example.batch.run.succeeded"
+	// TypeRunFailed is a run whose Job failed, or was deleted before it finished.
+	TypeRunFailed = "com.example.batch.run.failed"
+	// TypeRunMissed is a scheduled run not started within the starting deadline of its CronJob.
+	TypeRunMissed = "com.example.batch.run.missed"
+)
+
+// Types are all the types of the events.
+var Types = []string{TypeRunScheduled, TypeRunStarted, TypeRunSucceeded, TypeRunFailed, TypeRunMissed}
+
+const (
+	// SpecVersion is the version of the CloudEvents specification of the events.
+	SpecVersion = "1.0"
+	// DefaultSource is the prefix of the sources of the events.
+	DefaultSource = "/apis/batch.example.com/v1"
+
+	sendWorkers     = 2
+	maxSendAttempts = 5
+	sendTimeout     = 10 * time.Second
+	shutdownTimeout = 10 * time.Second
+	// dedupPeriod is how long the IDs of the sent events are remembered, the missed runs are seen by every
+	// reconcile of their CronJob until the next one.
+	dedupPeriod = time.Hour
+)
+
+// Event is a CloudEvent, in the structured JSON format.
+type Event struct {
+	SpecVersion string `json:"specversion"`
+	ID          string `json:"id"`
+	Source      string `json:"source"`
+	Type        string `json:"type"`
+	Subject     string `json:"subject,omitempty"`
+	// RunID is the `runid` extension attribute, the ID of the run shared by its Job, pods and notifications.
+	RunID           string    `json:"runid,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            RunData   `json:"data"`
+}
+
+// RunData is the data of the events, the run they are about.
+type RunData struct {
+	// Cluster is the name of the cluster of the manager, if configured.
+	Cluster   string `json:"cluster,omitempty"`
+	Namespace string `json:"namespace"`
+	CronJob   string `json:"cronJob"`
+	// Job is the name of the Job of the run, empty for a missed run.
+	Job            string           `json:"job,omitempty"`
+	Trigger        v1.JobRunTrigger `json:"trigger,omitempty"`
+	TriggeredBy    string           `json:"triggeredBy,omitempty"`
+	ScheduledTime  time.Time        `json:"scheduledTime"`
+	StartTime      *time.Time       `json:"startTime,omitempty"`
+	CompletionTime *time.Time       `json:"completionTime,omitempty"`
+	FailureReason  string           `json:"failureReason,omitempty"`
+	FailureMessage string           `json:"failureMessage,omitempty"`
+}
+
+// Sink sends the events.
+type Sink interface {
+	Send(ctx context.Context, event *Event) error
+}
+
+// Emitter sends the events of the runs to a sink.
+type Emitter struct {
+	sink    Sink
+	source  string
+	cluster string
+	types   map[string]bool
+	queue   workqueue.RateLimitingInterface
+	now     func() time.Time
+
+	lock sync.Mutex
+	// sent are the IDs of the events queued in the last dedupPeriod, with the time they were queued.
+	sent map[string]time.Time
+	// dryRun is set when the events are logged instead of sent.
+	dryRun bool
+}
+
+var _ manager.Runnable = &Emitter{}
+var _ manager.LeaderElectionRunnable = &Emitter{}
+
+// NewEmitter returns the emitter sending the events selected by the settings to the sink.
+func NewEmitter(sink Sink, config configv1.CloudEventsConfig, cluster string) *Emitter {
+	e := &Emitter{
+		sink:    sink,
+		source:  DefaultSource,
+		cluster: cluster,
+		queue: workqueue.NewNamedRateLimitingQueue(
+			workqueue.NewItemExponentialFailureRateLimiter(time.Second, time.Minute), "cloudevents"),
+		now:  time.Now,
+		sent: map[string]time.Time{},
+	}
+	if config.Source != "" {
+		e.source = config.Source
+	}
+	if len(config.Types) > 0 {
+		e.types = map[string]bool{}
+		for _, t := range config.Types {
+			e.types[t] = true
+		}
+	}
+	return e
+}
+
+// Setup returns the emitter sending the events to the sink of the config, with the credentials read from their Secret.
+// It returns nil if no sink is configured.
+func Setup(ctx context.Context, config configv1.CloudEventsConfig, clusterName string,
+	secrets startup.SecretReader) (*Emitter, error) {
+	if config.Sink == "" {
+		return nil, nil
+	}
+	credentials, err := secrets(ctx, config.CredentialsSecret)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := NewSink(config, credentials)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("emitting CloudEvents", "sink", config.Sink, "protocol", config.Protocol)
+	return NewEmitter(sink, config, clusterName), nil
+}
+
+// Permissions returns the permissions the emitter of the config needs, none without a sink.
+func Permissions(config configv1.CloudEventsConfig) []startup.Permission {
+	if config.Sink == "" || config.CredentialsSecret == "" {
+		return nil
+	}
+	namespace, _ := startup.SplitRef(config.CredentialsSecret)
+	return startup.Permissions("", "secrets", []string{"get"}, namespace)
+}
+
+// DryRun makes the emitter log the events instead of sending them, for the dry-run mode of the manager.
+func (e *Emitter) DryRun() {
+	e.dryRun = true
+}
+
+// RunEvent queues the event of the type about the run. A nil Emitter emits nothing.
+func (e *Emitter) RunEvent(eventType string, run *v1.JobRun) {
+	if e == nil {
+		return
+	}
+	data := RunData{
+		Namespace:      run.Namespace,
+		CronJob:        run.Spec.CronJob,
+		Job:            run.Name,
+		Trigger:        run.Spec.Trigger,
+		TriggeredBy:    run.Spec.TriggeredBy,
+		ScheduledTime:  run.Spec.ScheduledTime.Time,
+		FailureReason:  run.Status.FailureReason,
+		FailureMessage: run.Status.FailureMessage,
+	}
+	eventTime := run.CreationTimestamp.Time
+	if run.Status.StartTime != nil {
+		data.StartTime = &run.Status.StartTime.Time
+		if eventType == TypeRunStarted {
+			eventTime = run.Status.StartTime.Time
+		}
+	}
+	if run.Status.CompletionTime != nil {
+		data.CompletionTime = &run.Status.CompletionTime.Time
+		if eventType == TypeRunSucceeded || eventType == TypeRunFailed {
+			eventTime = run.Status.CompletionTime.Time
+		}
+	}
+	if run.Status.Phase == v1.JobRunLost && data.FailureReason == "" {
+		data.FailureReason = string(v1.JobRunLost)
+	}
+	e.emit(eventType, fmt.Sprintf("%s/%s", run.Spec.Job.UID, eventType), run.Spec.Job.Name, run.Spec.RunID,
+		eventTime, data)
+}
+
+// MissedRun queues the event of a run of the CronJob missed at the scheduled time. A nil Emitter emits nothing.
+func (e *Emitter) MissedRun(cronJob types.NamespacedName, uid types.UID, scheduledTime time.Time) {
+	if e == nil {
+		return
+	}
+	data := RunData{Namespace: cronJob.Namespace, CronJob: cronJob.Name, Trigger: v1.ScheduledTrigger,
+		ScheduledTime: scheduledTime}
+	e.emit(TypeRunMissed, fmt.Sprintf("%s/%s/%d", uid, TypeRunMissed, scheduledTime.Unix()), "", "", e.now(), data)
+}
+
+func (e *Emitter) emit(eventType, id, subject, runID string, eventTime time.Time, data RunData) {
+	if e.types != nil && !e.types[eventType] {
+		return
+	}
+	now := e.now()
+	e.lock.Lock()
+	for sentID, at := range e.sent {
+		if now.Sub(at) > dedupPeriod {
+			delete(e.sent, sentID)
+		}
+	}
+	_, duplicate := e.sent[id]
+	e.sent[id] = now
+	e.lock.Unlock()
+	if duplicate {
+		return
+	}
+
+	data.Cluster = e.cluster
+	e.queue.Add(&Event{
+		SpecVersion:     SpecVersion,
+		ID:              id,
+		Source:          fmt.Sprintf("%s/namespaces/%s/cronjobs/%s", e.source, data.Namespace, data.CronJob),
+		Type:            eventType,
+		Subject:         subject,
+		RunID:           runID,
+		Time:            eventTime.UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	})
+}
+
+// Start implements manager.Runnable, it sends the events until the manager stops, then waits a while for the ones in
+// the queue.
+func (e *Emitter) Start(ctx context.Context) error {
+	var workers sync.WaitGroup
+	for i := 0; i < sendWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for e.processNextEvent(ctx) {
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	e.queue.ShutDown()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		log.Info("gave up waiting for the events in the queue")
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the leader reconciles the runs.
+func (e *Emitter) NeedLeaderElection() bool {
+	return true
+}
+
+// processNextEvent sends the next event of the queue, it returns false once the queue is shut down.
+func (e *Emitter) processNextEvent(ctx context.Context) bool {
+	item, shutdown := e.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer e.queue.Done(item)
+	event := item.(*Event)
+	logger := log.WithValues("type", event.Type, "id", event.ID)
+
+	// the manager context is cancelled on shutdown, the events in the queue still get their chance
+	sendCtx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if e.dryRun {
+		logger.Info("would send an event", "source", event.Source, "subject", event.Subject)
+		e.queue.Forget(item)
+		return true
+	}
+	if err := e.sink.Send(sendCtx, event); err != nil {
+		if e.queue.NumRequeues(item) < maxSendAttempts-1 && ctx.Err() == nil {
+			logger.V(1).Info("retrying an event", "attempt", e.queue.NumRequeues(item)+1, "error", err.Error())
+			e.queue.AddRateLimited(item)
+			return true
+		}
+		logger.Error(err, "unable to send an event")
+		e.queue.Forget(item)
+		metrics.RecordCloudEvent(event.Type, metrics.EventFailed)
+		return true
+	}
+	logger.V(1).Info("sent an event")
+	e.queue.Forget(item)
+	metrics.RecordCloudEvent(event.Type, metrics.EventSent)
+	return true
+}
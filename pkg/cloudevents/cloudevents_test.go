@@ -0,0 +1,194 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// received is a request received by the test sink.
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestQueue retries right away.
+func newTestQueue() workqueue.RateLimitingInterface {
+	return workqueue.NewRateLimitingQueue(
+		workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, 10*time.Millisecond))
+}
+
+var _ = Describe("Emitter", func() {
+	now := time.Date(2021, 6, 5, 12, 30, 0, 0, time.UTC)
+	run := &v1.JobRun{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "report-1622894400"},
+		Spec: v1.JobRunSpec{
+			CronJob:       "report",
+			Trigger:       v1.ScheduledTrigger,
+			ScheduledTime: metav1.NewTime(now.Add(-30 * time.Minute)),
+			RunID:         "5f0c4a4e-7a3c-5d0e-9b1e-2f4f3c1d8a60",
+		},
+		Status: v1.JobRunStatus{
+			Phase:          v1.JobRunFailed,
+			StartTime:      &metav1.Time{Time: now.Add(-29 * time.Minute)},
+			CompletionTime: &metav1.Time{Time: now.Add(-time.Minute)},
+			FailureReason:  "BackoffLimitExceeded",
+		},
+	}
+	run.Spec.Job.Name, run.Spec.Job.UID = run.Name, "job-uid"
+
+	var (
+		server   *httptest.Server
+		lock     sync.Mutex
+		requests []received
+		failures int
+		cancel   context.CancelFunc
+	)
+	BeforeEach(func() {
+		requests, failures = nil, 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := ioutil.ReadAll(req.Body)
+			lock.Lock()
+			defer lock.Unlock()
+			if failures > 0 {
+				failures--
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			requests = append(requests, received{header: req.Header, body: body})
+		}))
+	})
+	AfterEach(func() {
+		if cancel != nil {
+			cancel()
+		}
+		server.Close()
+	})
+
+	start := func(config configv1.CloudEventsConfig, credentials map[string][]byte) *Emitter {
+		sink, err := NewSink(config, credentials)
+		Expect(err).NotTo(HaveOccurred())
+		e := NewEmitter(sink, config, "prod-eu")
+		e.now = func() time.Time { return now }
+		e.queue.ShutDown()
+		e.queue = newTestQueue()
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		go func() {
+			defer GinkgoRecover()
+			Expect(e.Start(ctx)).To(Succeed())
+		}()
+		return e
+	}
+	sent := func() []received {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]received(nil), requests...)
+	}
+
+	It("Should send the events in the binary mode over HTTP", func() {
+		e := start(configv1.CloudEventsConfig{Sink: server.URL}, map[string][]byte{"token": []byte("secret\n")})
+		e.RunEvent(TypeRunFailed, run)
+		Eventually(sent).Should(HaveLen(1))
+
+		header := sent()[0].header
+		Expect(header.Get("Authorization")).To(Equal("Bearer secret"))
+		Expect(header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(header.Get("ce-specversion")).To(Equal("1.0"))
+		Expect(header.Get("ce-id")).To(Equal("job-uid/com.example.batch.run.failed"))
+		Expect(header.Get("ce-type")).To(Equal(TypeRunFailed))
+		Expect(header.Get("ce-source")).To(Equal("/apis/batch.example.com/v1/namespaces/team-a/cronjobs/report"))
+		Expect(header.Get("ce-subject")).To(Equal("report-1622894400"))
+		Expect(header.Get("ce-time")).To(Equal("2021-06-05T12:29:00Z"))
+		Expect(header.Get("ce-runid")).To(Equal(run.Spec.RunID))
+
+		var data RunData
+		Expect(json.Unmarshal(sent()[0].body, &data)).To(Succeed())
+		Expect(data.Cluster).To(Equal("prod-eu"))
+		Expect(data.CronJob).To(Equal("report"))
+		Expect(data.FailureReason).To(Equal("BackoffLimitExceeded"))
+	})
+
+	It("Should produce the structured events through the Kafka REST proxy", func() {
+		e := start(configv1.CloudEventsConfig{Sink: server.URL, Protocol: ProtocolKafka, Topic: "runs",
+			Source: "https://prod-eu.example.com"}, map[string][]byte{"username": []byte("u"), "password": []byte("p")})
+		e.RunEvent(TypeRunStarted, run)
+		Eventually(sent).Should(HaveLen(1))
+
+		request := sent()[0]
+		Expect(request.header.Get("Content-Type")).To(Equal("application/vnd.kafka.json.v2+json"))
+		Expect(request.header.Get("Authorization")).To(HavePrefix("Basic "))
+		var records struct {
+			Records []struct {
+				Key   string `json:"key"`
+				Value Event  `json:"value"`
+			} `json:"records"`
+		}
+		Expect(json.Unmarshal(request.body, &records)).To(Succeed())
+		Expect(records.Records).To(HaveLen(1))
+		source := "https://prod-eu.example.com/namespaces/team-a/cronjobs/report"
+		Expect(records.Records[0].Key).To(Equal(source))
+		Expect(records.Records[0].Value.Source).To(Equal(source))
+		Expect(records.Records[0].Value.Type).To(Equal(TypeRunStarted))
+		Expect(records.Records[0].Value.RunID).To(Equal(run.Spec.RunID))
+		Expect(records.Records[0].Value.Time).To(BeTemporally("==", now.Add(-29*time.Minute)))
+	})
+
+	It("Should emit the selected types once, and retry the failed sends", func() {
+		failures = 2
+		e := start(configv1.CloudEventsConfig{Sink: server.URL, Types: []string{TypeRunMissed}}, nil)
+		e.RunEvent(TypeRunFailed, run)
+		cronJob := types.NamespacedName{Namespace: "team-a", Name: "report"}
+		e.MissedRun(cronJob, "cronjob-uid", now.Add(-time.Hour))
+		e.MissedRun(cronJob, "cronjob-uid", now.Add(-time.Hour))
+		Eventually(sent).Should(HaveLen(1))
+		Consistently(sent, 100*time.Millisecond).Should(HaveLen(1))
+
+		header := sent()[0].header
+		Expect(header.Get("ce-type")).To(Equal(TypeRunMissed))
+		Expect(header.Get("ce-id")).To(Equal("cronjob-uid/com.example.batch.run.missed/1622892600"))
+		Expect(header.Get("ce-subject")).To(BeEmpty())
+	})
+
+	It("Should only log the events in dry-run mode", func() {
+		e := start(configv1.CloudEventsConfig{Sink: server.URL}, nil)
+		e.DryRun()
+		e.RunEvent(TypeRunFailed, run)
+		Eventually(e.queue.Len).Should(BeZero())
+		Consistently(sent, 100*time.Millisecond).Should(BeEmpty())
+	})
+
+	It("Should emit nothing without an emitter", func() {
+		var e *Emitter
+		e.RunEvent(TypeRunScheduled, run)
+		e.MissedRun(types.NamespacedName{}, "", now)
+	})
+})
@@ -0,0 +1,157 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+)
+
+/*
+The HTTP sink uses the binary content mode of the HTTP protocol binding: the attributes of the event are sent as
+`ce-` headers and its data as the JSON body, which most receivers, e.g. Knative brokers, accept.
+
+Kafka is reached through a Kafka REST proxy (the v2 API of the Confluent REST Proxy), so the manager needs no Kafka
+client: the events are produced in the structured content mode, the whole event being the JSON value of the record.
+The key of the record is the source of the event, the events of a CronJob land in the same partition and keep their
+order.
+*/
+
+// The protocols of the sinks.
+const (
+	ProtocolHTTP  = "HTTP"
+	ProtocolKafka = "Kafka"
+)
+
+const kafkaContentType = "application/vnd.kafka.json.v2+json"
+
+// NewSink returns the sink of the settings, authenticated with the credentials, a `token` or a `username` and a
+// `password`, if any.
+func NewSink(config configv1.CloudEventsConfig, credentials map[string][]byte) (Sink, error) {
+	if _, err := url.Parse(config.Sink); err != nil {
+		return nil, fmt.Errorf("invalid sink %q: %w", config.Sink, err)
+	}
+	h := httpSender{client: &http.Client{Timeout: sendTimeout}}
+	if token := credentials["token"]; len(token) > 0 {
+		h.authorization = "Bearer " + strings.TrimSpace(string(token))
+	} else if username := credentials["username"]; len(username) > 0 {
+		h.username, h.password = string(username), string(credentials["password"])
+	}
+
+	switch config.Protocol {
+	case "", ProtocolHTTP:
+		return &httpSink{httpSender: h, url: config.Sink}, nil
+	case ProtocolKafka:
+		return &kafkaSink{httpSender: h,
+			url: strings.TrimSuffix(config.Sink, "/") + "/topics/" + url.PathEscape(config.Topic)}, nil
+	}
+	return nil, fmt.Errorf("unknown protocol %q", config.Protocol)
+}
+
+// httpSender posts the requests of the sinks.
+type httpSender struct {
+	client             *http.Client
+	authorization      string
+	username, password string
+}
+
+func (h *httpSender) post(ctx context.Context, target string, body []byte, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	if h.authorization != "" {
+		req.Header.Set("Authorization", h.authorization)
+	} else if h.username != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("the sink answered %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// httpSink sends the events in the binary content mode.
+type httpSink struct {
+	httpSender
+	url string
+}
+
+func (s *httpSink) Send(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", event.DataContentType)
+	header.Set("ce-specversion", event.SpecVersion)
+	header.Set("ce-id", event.ID)
+	header.Set("ce-source", event.Source)
+	header.Set("ce-type", event.Type)
+	header.Set("ce-time", event.Time.Format(time.RFC3339Nano))
+	if event.Subject != "" {
+		header.Set("ce-subject", event.Subject)
+	}
+	if event.RunID != "" {
+		header.Set("ce-runid", event.RunID)
+	}
+	return s.post(ctx, s.url, body, header)
+}
+
+// kafkaSink produces the events in the structured content mode through a Kafka REST proxy.
+type kafkaSink struct {
+	httpSender
+	url string
+}
+
+// kafkaRecords is the body of a produce request of the REST proxy.
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value *Event `json:"value"`
+}
+
+func (s *kafkaSink) Send(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(kafkaRecords{Records: []kafkaRecord{{Key: event.Source, Value: event}}})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", kafkaContentType)
+	header.Set("Accept", "application/vnd.kafka.v2+json")
+	return s.post(ctx, s.url, body, header)
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+	"k8s.io/client-go/rest"
+)
+
+/*
+The client of the manager is throttled on the client side, 20 requests per second by default. A controller
+reconciling thousands of objects spends most of its time waiting for the rate limiter, so the limits can be raised
+together with the API Priority and Fairness settings of the cluster. The user agent tells the requests of the
+operator apart from the other clients in the audit logs.
+*/
+
+// ApplyClientConfig applies the client settings of the config file to the REST config of the manager.
+func ApplyClientConfig(restConfig *rest.Config, client configv1.ClientConfig) {
+	if client.QPS > 0 {
+		restConfig.QPS = client.QPS
+	}
+	if client.Burst > 0 {
+		restConfig.Burst = client.Burst
+	}
+	if client.Timeout != nil {
+		restConfig.Timeout = client.Timeout.Duration
+	}
+	restConfig.UserAgent = client.UserAgent
+	if restConfig.UserAgent == "" {
+		restConfig.UserAgent = version.UserAgent()
+	}
+}
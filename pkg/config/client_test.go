@@ -0,0 +1,62 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+var _ = Describe("ApplyClientConfig", func() {
+	It("Should keep the limits and set the user agent of the operator by default", func() {
+		restConfig := &rest.Config{QPS: 20, Burst: 30}
+		ApplyClientConfig(restConfig, configv1.ClientConfig{})
+		Expect(restConfig.QPS).To(Equal(float32(20)))
+		Expect(restConfig.Burst).To(Equal(30))
+		Expect(restConfig.Timeout).To(BeZero())
+		Expect(restConfig.UserAgent).To(Equal(version.UserAgent()))
+		Expect(restConfig.UserAgent).To(HavePrefix("kubebuilder-tutorial/dev ("))
+	})
+
+	It("Should apply the client settings of the config file", func() {
+		restConfig := &rest.Config{QPS: 20, Burst: 30}
+		ApplyClientConfig(restConfig, configv1.ClientConfig{
+			QPS:       50.5,
+			Burst:     100,
+			Timeout:   &metav1.Duration{Duration: 30 * time.Second},
+			UserAgent: "cronjob-operator-staging",
+		})
+		Expect(restConfig.QPS).To(Equal(float32(50.5)))
+		Expect(restConfig.Burst).To(Equal(100))
+		Expect(restConfig.Timeout).To(Equal(30 * time.Second))
+		Expect(restConfig.UserAgent).To(Equal("cronjob-operator-staging"))
+	})
+
+	It("Should reject the invalid settings", func() {
+		Expect(Validate(&configv1.ProjectConfig{Client: configv1.ClientConfig{
+			QPS:     -1,
+			Burst:   -1,
+			Timeout: &metav1.Duration{},
+		}})).To(HaveLen(3))
+	})
+})
@@ -0,0 +1,162 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the ProjectConfig file of the manager, and reloads its settings when the file changes.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/merge"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/yaml"
+)
+
+/*
+The config can be split into several files, e.g. a base shared by the whole fleet and the overrides of a cluster. The
+files are merged in order like `kubectl patch --type merge` does: the maps are merged, the lists and the values are
+replaced, and null removes a setting. A directory stands for its `.yaml`, `.yml` and `.json` files, in the order of
+their names.
+*/
+
+// Load reads the ProjectConfig from the files or the directories at paths, merged in order. The scheme must know the
+// ProjectConfig kind.
+func Load(paths []string, scheme *runtime.Scheme) (*configv1.ProjectConfig, error) {
+	files, err := ExpandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]interface{}, 0, len(files))
+	for _, file := range files {
+		document, err := readDocument(file)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	merged, err := json.Marshal(merge.All(documents...))
+	if err != nil {
+		return nil, err
+	}
+
+	projectConfig := &configv1.ProjectConfig{}
+	if err := runtime.DecodeInto(serializer.NewCodecFactory(scheme).UniversalDecoder(), merged, projectConfig); err != nil {
+		return nil, fmt.Errorf("could not decode the config files %s: %w", strings.Join(files, ", "), err)
+	}
+	return projectConfig, nil
+}
+
+// ExpandPaths returns the config files at paths, replacing the directories with their config files.
+func ExpandPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, entry := range entries {
+			name := entry.Name()
+			// the hidden entries include the ..data directory of the mounted ConfigMaps
+			if strings.HasPrefix(name, ".") || !configExtensions[filepath.Ext(name)] {
+				continue
+			}
+			// the files of the mounted ConfigMaps are symlinks, so follow them
+			if info, err := os.Stat(filepath.Join(path, name)); err != nil || info.IsDir() {
+				continue
+			}
+			files = append(files, filepath.Join(path, name))
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("no config file in the directory %s", path)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no config file given")
+	}
+	return files, nil
+}
+
+var configExtensions = map[string]bool{".yaml": true, ".yml": true, ".json": true}
+
+// readDocument reads the config file into a document which can be merged.
+func readDocument(path string) (interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err = yaml.YAMLToJSON(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var document interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return document, nil
+}
+
+// ParseLogLevel parses the level of the logging settings, e.g. `info`, `debug` or the verbosity `2`.
+func ParseLogLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+
+	verbosity, err := strconv.Atoi(level)
+	if err != nil || verbosity < 0 {
+		return 0, fmt.Errorf("invalid log level %q, must be debug, info, error or a positive integer", level)
+	}
+	return zapcore.Level(-verbosity), nil
+}
+
+// ApplyLogLevel sets the level of the logging settings, if any, on the atomic level of the logger.
+func ApplyLogLevel(atomicLevel zap.AtomicLevel, logging configv1.LoggingConfig) error {
+	if logging.Level == "" {
+		return nil
+	}
+	level, err := ParseLogLevel(logging.Level)
+	if err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(level)
+	return nil
+}
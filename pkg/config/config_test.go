@@ -0,0 +1,395 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	componentconfigv1alpha1 "k8s.io/component-base/config/v1alpha1"
+)
+
+var _ = Describe("Config file", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "config")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("Should load the settings of our components", func() {
+		path := filepath.Join(dir, "config.yaml")
+		Expect(ioutil.WriteFile(path, []byte(`apiVersion: config.example.com/v1
+kind: ProjectConfig
+logging:
+  level: "2"
+admission:
+  activationHorizon: 1h
+`), 0600)).To(Succeed())
+
+		scheme := runtime.NewScheme()
+		utilruntime.Must(configv1.AddToScheme(scheme))
+		config, err := Load([]string{path}, scheme)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Logging.Level).To(Equal("2"))
+		Expect(config.Admission.ActivationHorizon).To(Equal(&metav1.Duration{Duration: time.Hour}))
+	})
+
+	It("Should point at the unknown fields in strict mode", func() {
+		path := filepath.Join(dir, "config.yaml")
+		Expect(ioutil.WriteFile(path, []byte(`apiVersion: config.example.com/v1
+kind: ProjectConfig
+leaderElection:
+  leaderElect: false
+admission:
+  defaultTimezone: Europe/Istanbul
+`), 0600)).To(Succeed())
+
+		scheme := runtime.NewScheme()
+		utilruntime.Must(configv1.AddToScheme(scheme))
+		_, err := LoadStrict([]string{path}, scheme)
+		Expect(err).To(MatchError(ContainSubstring(`line 6: unknown field "admission.defaultTimezone"`)))
+
+		_, err = Load([]string{path}, scheme)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Should point at the invalid values in strict mode", func() {
+		path := filepath.Join(dir, "config.yaml")
+		Expect(ioutil.WriteFile(path, []byte(`apiVersion: config.example.com/v1
+kind: ProjectConfig
+leaderElection:
+  leaderElect: false
+admission:
+  nativeCronJobCollision: Panic
+  maxCronJobsPerNamespace: 100
+`), 0600)).To(Succeed())
+
+		scheme := runtime.NewScheme()
+		utilruntime.Must(configv1.AddToScheme(scheme))
+		_, err := LoadStrict([]string{path}, scheme)
+		Expect(err).To(MatchError(ContainSubstring(`line 6: admission.nativeCronJobCollision: Unsupported value: "Panic"`)))
+	})
+
+	It("Should merge the config files in order", func() {
+		base := filepath.Join(dir, "base.yaml")
+		Expect(ioutil.WriteFile(base, []byte(`apiVersion: config.example.com/v1
+kind: ProjectConfig
+clusterName: base
+watchNamespaces: [a, b]
+logging:
+  level: info
+  encoder: json
+`), 0600)).To(Succeed())
+		overlay := filepath.Join(dir, "cluster.yaml")
+		Expect(ioutil.WriteFile(overlay, []byte(`clusterName: prod-eu
+watchNamespaces: [c]
+logging:
+  level: debug
+`), 0600)).To(Succeed())
+
+		scheme := runtime.NewScheme()
+		utilruntime.Must(configv1.AddToScheme(scheme))
+		config, err := LoadStrict([]string{base, overlay}, scheme)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.ClusterName).To(Equal("prod-eu"))
+		Expect(config.WatchNamespaces).To(Equal([]string{"c"}))
+		Expect(config.Logging.Level).To(Equal("debug"))
+		Expect(config.Logging.Encoder).To(Equal("json"))
+
+		// a directory stands for its config files, in the order of their names
+		dirConfig, err := Load([]string{dir}, scheme)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dirConfig).To(Equal(config))
+	})
+
+	It("Should point at the file setting an invalid value", func() {
+		base := filepath.Join(dir, "base.yaml")
+		Expect(ioutil.WriteFile(base, []byte(`apiVersion: config.example.com/v1
+kind: ProjectConfig
+admission:
+  maxCronJobsPerNamespace: 100
+`), 0600)).To(Succeed())
+		overlay := filepath.Join(dir, "cluster.yaml")
+		Expect(ioutil.WriteFile(overlay, []byte(`admission:
+  maxCronJobsPerNamespace: -1
+`), 0600)).To(Succeed())
+
+		scheme := runtime.NewScheme()
+		utilruntime.Must(configv1.AddToScheme(scheme))
+		_, err := LoadStrict([]string{base, overlay}, scheme)
+		Expect(err).To(MatchError(HavePrefix(overlay + `: line 2: admission.maxCronJobsPerNamespace: Invalid value`)))
+	})
+
+	It("Should reject the unknown feature gates", func() {
+		config := &configv1.ProjectConfig{FeatureGates: map[string]bool{"CronJobTimeZone": true}}
+		Expect(Validate(config)).To(BeEmpty())
+		config.FeatureGates["TimeTravel"] = true
+		Expect(Validate(config).ToAggregate()).To(MatchError(ContainSubstring("unrecognized feature gate: TimeTravel")))
+	})
+
+	It("Should reject the invalid tuning settings", func() {
+		jitter := metav1.Duration{Duration: 5 * time.Second}
+		ttl := metav1.Duration{Duration: 24 * time.Hour}
+		debounce := metav1.Duration{Duration: time.Second}
+		limit := int32(5)
+		config := &configv1.ProjectConfig{
+			CronJobController: configv1.CronJobControllerConfig{
+				RequeueJitter:           &jitter,
+				JobRunTTL:               &ttl,
+				StatusDebounce:          &debounce,
+				DeletePropagationPolicy: metav1.DeletePropagationForeground,
+			},
+			Admission: configv1.AdmissionConfig{Defaults: configv1.CronJobDefaults{
+				ConcurrencyPolicy:          "Forbid",
+				SuccessfulJobsHistoryLimit: &limit,
+			}},
+		}
+		Expect(Validate(config)).To(BeEmpty())
+
+		limit = -1
+		jitter.Duration = -time.Second
+		ttl.Duration = 0
+		debounce.Duration = -time.Second
+		config.CronJobController.DeletePropagationPolicy = "Later"
+		config.Admission.Defaults.ConcurrencyPolicy = "Queue"
+		Expect(Validate(config)).To(HaveLen(6))
+	})
+
+	It("Should reject the invalid webhook server settings", func() {
+		port := 0
+		config := &configv1.ProjectConfig{WebhookServer: configv1.WebhookServerConfig{
+			CertName: "serving.pem",
+			KeyName:  "serving-key.pem",
+		}}
+		Expect(Validate(config)).To(BeEmpty())
+		config.Webhook.Port = &port
+		config.WebhookServer.CertName = "certs/serving.pem"
+		config.WebhookServer.KeyName = ".."
+		Expect(Validate(config)).To(HaveLen(3))
+	})
+
+	It("Should reject the invalid audit file", func() {
+		config := &configv1.ProjectConfig{Audit: configv1.AuditConfig{Enabled: true}}
+		Expect(Validate(config)).To(BeEmpty())
+		config.Audit.File = &configv1.LogFileConfig{MaxBackups: -1}
+		errs := Validate(config)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Field).To(Equal("audit.file.path"))
+		Expect(errs[1].Field).To(Equal("audit.file.maxBackups"))
+	})
+
+	It("Should reject a non-positive operator status interval", func() {
+		config := &configv1.ProjectConfig{OperatorStatus: configv1.OperatorStatusConfig{Enabled: true}}
+		Expect(Validate(config)).To(BeEmpty())
+		config.OperatorStatus.Interval = &metav1.Duration{}
+		errs := Validate(config)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("operatorStatus.interval"))
+	})
+
+	It("Should reject the invalid error reporting settings", func() {
+		config := &configv1.ProjectConfig{}
+		config.ErrorReporting.SentryDSN = "https://public@o0.ingest.sentry.io/42"
+		Expect(Validate(config)).To(BeEmpty())
+
+		config.ErrorReporting.SentryDSN = "https://o0.ingest.sentry.io/42"
+		config.ErrorReporting.GoogleCloudProject = "my-project"
+		config.ErrorReporting.ReconcileErrorThreshold = -1
+		errs := Validate(config)
+		Expect(errs).To(HaveLen(3))
+		Expect(errs[0].Field).To(Equal("errorReporting.sentryDSN"))
+		Expect(errs[1].Field).To(Equal("errorReporting.googleCloudProject"))
+		Expect(errs[2].Field).To(Equal("errorReporting.reconcileErrorThreshold"))
+	})
+
+	It("Should reject the invalid archive settings", func() {
+		config := &configv1.ProjectConfig{}
+		config.Archive = configv1.ArchiveConfig{Provider: "S3", Bucket: "runs", CredentialsSecret: "system/archive"}
+		Expect(Validate(config)).To(BeEmpty())
+
+		config.Archive = configv1.ArchiveConfig{
+			Provider:          "AzureBlob",
+			CredentialsSecret: "archive",
+			Retention:         &metav1.Duration{},
+			LogTailLines:      -1,
+		}
+		errs := Validate(config)
+		Expect(errs).To(HaveLen(5))
+		Expect(errs[0].Field).To(Equal("archive.bucket"))
+		Expect(errs[1].Field).To(Equal("archive.endpoint"))
+		Expect(errs[2].Field).To(Equal("archive.credentialsSecret"))
+		Expect(errs[3].Field).To(Equal("archive.retention"))
+		Expect(errs[4].Field).To(Equal("archive.logTailLines"))
+
+		config.Archive = configv1.ArchiveConfig{Provider: "Swift", Endpoint: "minio:9000"}
+		Expect(Validate(config)).To(HaveLen(2))
+	})
+
+	It("Should reject the invalid CloudEvents settings", func() {
+		config := &configv1.ProjectConfig{}
+		config.CloudEvents = configv1.CloudEventsConfig{Sink: "http://kafka-rest:8082", Protocol: "Kafka",
+			Topic: "runs", Types: []string{"com.example.batch.run.failed"}, CredentialsSecret: "system/events"}
+		Expect(Validate(config)).To(BeEmpty())
+
+		config.CloudEvents = configv1.CloudEventsConfig{Sink: "broker", Protocol: "Kafka",
+			Types: []string{"run.failed"}, CredentialsSecret: "events"}
+		errs := Validate(config)
+		Expect(errs).To(HaveLen(4))
+		Expect(errs[0].Field).To(Equal("cloudEvents.sink"))
+		Expect(errs[1].Field).To(Equal("cloudEvents.topic"))
+		Expect(errs[2].Field).To(Equal("cloudEvents.types[0]"))
+		Expect(errs[3].Field).To(Equal("cloudEvents.credentialsSecret"))
+
+		config.CloudEvents = configv1.CloudEventsConfig{Sink: "https://broker", Protocol: "AMQP"}
+		Expect(Validate(config)).To(HaveLen(1))
+	})
+
+	It("Should reject the invalid Pushgateway settings", func() {
+		config := &configv1.ProjectConfig{}
+		config.Pushgateway = configv1.PushgatewayConfig{URL: "http://pushgateway.monitoring:9091",
+			CredentialsSecret: "monitoring/pushgateway"}
+		Expect(Validate(config)).To(BeEmpty())
+
+		config.Pushgateway = configv1.PushgatewayConfig{URL: "pushgateway:9091", CredentialsSecret: "pushgateway"}
+		errs := Validate(config)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Field).To(Equal("pushgateway.url"))
+		Expect(errs[1].Field).To(Equal("pushgateway.credentialsSecret"))
+	})
+
+	It("Should reject the colliding listen addresses", func() {
+		config := &configv1.ProjectConfig{}
+		config.Metrics.BindAddress = "127.0.0.1:8080"
+		config.Health.HealthProbeBindAddress = ":8081"
+		Expect(Validate(config)).To(BeEmpty())
+
+		config.Health.HealthProbeBindAddress = ":8080"
+		config.SecureMetrics = configv1.SecureMetricsConfig{Enabled: true, BindAddress: "9443"}
+		errs := Validate(config)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("secureMetrics.bindAddress"))
+
+		// the plain HTTP endpoint is replaced by the secure one
+		config.SecureMetrics.BindAddress = "0.0.0.0:9443"
+		errs = Validate(config)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("webhook.port"))
+		Expect(errs[0].Detail).To(Equal("collides with secureMetrics.bindAddress 0.0.0.0:9443"))
+
+		disabled := false
+		config.Admission.MutatingWebhook, config.Admission.ValidatingWebhook = &disabled, &disabled
+		Expect(Validate(config)).To(BeEmpty())
+
+		config.API = configv1.APIConfig{Enabled: true, BindAddress: ":9443"}
+		errs = Validate(config)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("api.bindAddress"))
+
+		config.API.BindAddress = ""
+		config.GRPC = configv1.GRPCConfig{Enabled: true, BindAddress: ":8444"}
+		errs = Validate(config)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Field).To(Equal("grpc.clientCAFile"))
+		Expect(errs[1].Field).To(Equal("grpc.bindAddress"))
+
+		config.GRPC = configv1.GRPCConfig{}
+		config.HTTPTrigger = configv1.HTTPTriggerConfig{Enabled: true, BindAddress: ":8444"}
+		errs = Validate(config)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Field).To(Equal("httpTrigger.callersSecret"))
+		Expect(errs[1].Field).To(Equal("httpTrigger.bindAddress"))
+
+		config.HTTPTrigger = configv1.HTTPTriggerConfig{Enabled: true, CallersSecret: "cronjob-system/trigger-callers"}
+		Expect(Validate(config)).To(BeEmpty())
+
+		config.HTTPTrigger.CallersSecret = "trigger-callers"
+		errs = Validate(config)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("httpTrigger.callersSecret"))
+
+		// the Slack commands may be served alone
+		config.HTTPTrigger = configv1.HTTPTriggerConfig{Enabled: true,
+			Slack: configv1.SlackConfig{SigningSecret: "cronjob-system/slack"}}
+		errs = Validate(config)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("httpTrigger.slack.usersConfigMap"))
+
+		config.HTTPTrigger.Slack.UsersConfigMap = "cronjob-system/slack-users"
+		Expect(Validate(config)).To(BeEmpty())
+	})
+
+	It("Should reject the invalid leader election lock", func() {
+		leaderElect := true
+		config := &configv1.ProjectConfig{}
+		config.LeaderElection = &componentconfigv1alpha1.LeaderElectionConfiguration{
+			LeaderElect:       &leaderElect,
+			ResourceLock:      "leases",
+			ResourceNamespace: "cronjob-system",
+			ResourceName:      "fdf6809e.example.com",
+		}
+		Expect(Validate(config)).To(BeEmpty())
+
+		config.LeaderElection.ResourceLock = "secrets"
+		config.LeaderElection.ResourceNamespace = "CronJob System"
+		config.LeaderElection.ResourceName = ""
+		errs := Validate(config)
+		Expect(errs).To(HaveLen(3))
+		Expect(errs[0].Field).To(Equal("leaderElection.resourceLock"))
+		Expect(errs[1].Field).To(Equal("leaderElection.resourceName"))
+		Expect(errs[2].Field).To(Equal("leaderElection.resourceNamespace"))
+	})
+
+	It("Should parse the log levels", func() {
+		Expect(ParseLogLevel("info")).To(Equal(zapcore.InfoLevel))
+		Expect(ParseLogLevel("Debug")).To(Equal(zapcore.DebugLevel))
+		Expect(ParseLogLevel("3")).To(Equal(zapcore.Level(-3)))
+		_, err := ParseLogLevel("verbose")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should only require a restart for the settings which are not reloadable", func() {
+		initial := &configv1.ProjectConfig{}
+		reloadable := initial.DeepCopy()
+		reloadable.Logging.Level = "debug"
+		reloadable.Admission.DefaultTimeZone = "Europe/Istanbul"
+		Expect(withoutReloadable(reloadable)).To(Equal(withoutReloadable(initial)))
+
+		restart := initial.DeepCopy()
+		restart.CronJobController.MaxConcurrentReconciles = 5
+		Expect(withoutReloadable(restart)).NotTo(Equal(withoutReloadable(initial)))
+
+		disabled := false
+		restart = initial.DeepCopy()
+		restart.Admission.ValidatingWebhook = &disabled
+		Expect(withoutReloadable(restart)).NotTo(Equal(withoutReloadable(initial)))
+	})
+})
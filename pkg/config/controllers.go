@@ -0,0 +1,125 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+/*
+The controllers can be switched off one by one, the same way as the ones of kube-controller-manager. The clusters
+which do not use a subsystem don't pay for its watches and its caches.
+*/
+
+// CronJobController is the name of the controller reconciling the CronJobs.
+const CronJobController = "cronjob"
+
+// JobTemplateController is the name of the controller rolling the JobTemplates out to their CronJobs.
+const JobTemplateController = "jobtemplate"
+
+// CronJobPolicyController is the name of the controller reporting the violations of the CronJobPolicies.
+const CronJobPolicyController = "cronjobpolicy"
+
+// ClusterCronJobPolicyController is the name of the controller reporting the violations of the ClusterCronJobPolicies.
+const ClusterCronJobPolicyController = "clustercronjobpolicy"
+
+// ClusterCronJobController is the name of the controller running the ClusterCronJobs.
+const ClusterCronJobController = "clustercronjob"
+
+// CronJobSetController is the name of the controller stamping out the CronJobs of the CronJobSets.
+const CronJobSetController = "cronjobset"
+
+// JobRunController is the name of the controller recording the runs of the CronJobs in JobRuns.
+const JobRunController = "jobrun"
+
+// CronJobGroupController is the name of the controller applying the CronJobGroups to their members.
+const CronJobGroupController = "cronjobgroup"
+
+// CronJobQuotaController is the name of the controller reporting the usage of the CronJobQuotas.
+const CronJobQuotaController = "cronjobquota"
+
+// BackfillController is the name of the controller replaying the runs of the CronJobs for the Backfills.
+const BackfillController = "backfill"
+
+// WorkflowController is the name of the controller running the Workflows. It is disabled by default.
+const WorkflowController = "workflow"
+
+// CalendarController is the name of the controller importing the feeds of the Calendars. It is disabled by default,
+// since it downloads the URLs the users give.
+const CalendarController = "calendar"
+
+// GitSyncController is the name of the controller applying the CronJobs of the GitSyncs. It is disabled by default,
+// since it clones the repositories the users give and creates the CronJobs found in them.
+const GitSyncController = "gitsync"
+
+// ScheduleImportController is the name of the controller importing the schedules of the cloud schedulers. It is
+// disabled by default, since it reaches the APIs of the clouds with the credentials the users give.
+const ScheduleImportController = "scheduleimport"
+
+// CronJobReportController is the name of the controller keeping the CronJobReports of the namespaces. It is
+// disabled by default, since it creates an object in every namespace with CronJobs.
+const CronJobReportController = "cronjobreport"
+
+// controllersDisabledByDefault are the controllers which only run when they are named explicitly.
+var controllersDisabledByDefault = map[string]bool{WorkflowController: true, CalendarController: true,
+	GitSyncController: true, ScheduleImportController: true, CronJobReportController: true}
+
+// KnownControllers returns the names of all the controllers, sorted.
+func KnownControllers() []string {
+	names := []string{CronJobController, JobTemplateController, CronJobPolicyController, ClusterCronJobPolicyController,
+		ClusterCronJobController, CronJobSetController, JobRunController, CronJobGroupController, CronJobQuotaController,
+		BackfillController, WorkflowController, CalendarController, GitSyncController, ScheduleImportController,
+		CronJobReportController}
+	sort.Strings(names)
+	return names
+}
+
+// IsControllerEnabled returns whether the named controller is enabled by the given selection. The selection is a list
+// of `*`, `name` and `-name` entries, an empty selection stands for `*`. An explicit entry wins over `*`.
+func IsControllerEnabled(name string, controllers []string) bool {
+	if len(controllers) == 0 {
+		controllers = []string{"*"}
+	}
+
+	hasStar := false
+	for _, entry := range controllers {
+		switch entry {
+		case name:
+			return true
+		case "-" + name:
+			return false
+		case "*":
+			hasStar = true
+		}
+	}
+	return hasStar && !controllersDisabledByDefault[name]
+}
+
+// isKnownControllerEntry returns whether the entry of a selection is `*` or names a known controller.
+func isKnownControllerEntry(entry string) bool {
+	if entry == "*" {
+		return true
+	}
+	name := strings.TrimPrefix(entry, "-")
+	for _, known := range KnownControllers() {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
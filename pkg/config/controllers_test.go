@@ -0,0 +1,56 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IsControllerEnabled", func() {
+	It("Should enable the controllers by default", func() {
+		Expect(IsControllerEnabled(CronJobController, nil)).To(BeTrue())
+		Expect(IsControllerEnabled(CronJobController, []string{"*"})).To(BeTrue())
+	})
+
+	It("Should follow the explicit entries", func() {
+		Expect(IsControllerEnabled(CronJobController, []string{"*", "-cronjob"})).To(BeFalse())
+		Expect(IsControllerEnabled(CronJobController, []string{"-cronjob", "*"})).To(BeFalse())
+		Expect(IsControllerEnabled(CronJobController, []string{"cronjob"})).To(BeTrue())
+	})
+
+	It("Should only enable the controllers disabled by default when they are named", func() {
+		Expect(IsControllerEnabled(WorkflowController, nil)).To(BeFalse())
+		Expect(IsControllerEnabled(WorkflowController, []string{"*"})).To(BeFalse())
+		Expect(IsControllerEnabled(WorkflowController, []string{"*", "workflow"})).To(BeTrue())
+		Expect(IsControllerEnabled(CalendarController, []string{"*"})).To(BeFalse())
+		Expect(IsControllerEnabled(GitSyncController, []string{"*"})).To(BeFalse())
+		Expect(IsControllerEnabled(ScheduleImportController, []string{"*"})).To(BeFalse())
+		Expect(IsControllerEnabled(CronJobReportController, []string{"*"})).To(BeFalse())
+	})
+
+	It("Should disable the controllers which are not selected", func() {
+		Expect(IsControllerEnabled(CronJobController, []string{"-gc"})).To(BeFalse())
+	})
+
+	It("Should reject the unknown controllers", func() {
+		Expect(Validate(&configv1.ProjectConfig{Controllers: []string{"*", "-cronjob"}})).To(BeEmpty())
+		Expect(Validate(&configv1.ProjectConfig{Controllers: []string{"*", "-gc"}}).ToAggregate()).To(
+			MatchError(ContainSubstring(`controllers[1]: Unsupported value: "-gc"`)))
+	})
+})
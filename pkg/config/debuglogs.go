@@ -0,0 +1,145 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// debugObjects holds the objects to debug, as `namespace/name` or `namespace` for all the objects of the namespace.
+type debugObjects map[string]bool
+
+// parseDebugObjects parses the debugObjects of the logging settings.
+func parseDebugObjects(list []string) (debugObjects, error) {
+	objects := debugObjects{}
+	for _, object := range list {
+		namespace, name := object, ""
+		if i := strings.Index(object, "/"); i >= 0 {
+			namespace, name = object[:i], object[i+1:]
+			if name == "" || strings.Contains(name, "/") {
+				return nil, fmt.Errorf("invalid debug object %q, must be namespace/name, /name or namespace", object)
+			}
+		} else if namespace == "" {
+			return nil, fmt.Errorf("invalid debug object %q, must be namespace/name, /name or namespace", object)
+		}
+		objects[object] = true
+	}
+	return objects, nil
+}
+
+// match tells whether the object of the namespace and name is debugged, the name may not be known yet.
+func (d debugObjects) match(namespace, name string) bool {
+	return (namespace != "" && d[namespace]) || (name != "" && d[namespace+"/"+name])
+}
+
+// objectCore writes the logs of the objects to debug from their own level and unsampled, and the others from the
+// level of the logger.
+type objectCore struct {
+	zapcore.Core
+	unsampled    zapcore.Core
+	level        zapcore.LevelEnabler
+	objects      debugObjects
+	objectsLevel zapcore.Level
+
+	namespace, name string
+	debugged        bool
+}
+
+// Enabled implements zapcore.Core
+func (c *objectCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level) || (c.debugged && level >= c.objectsLevel)
+}
+
+// With implements zapcore.Core
+func (c *objectCore) With(fields []zapcore.Field) zapcore.Core {
+	with := *c
+	with.Core, with.unsampled = c.Core.With(fields), c.unsampled.With(fields)
+	for _, field := range fields {
+		if field.Type != zapcore.StringType {
+			continue
+		}
+		switch field.Key {
+		case "namespace":
+			with.namespace = field.String
+		case "name":
+			with.name = field.String
+		}
+	}
+	with.debugged = c.objects.match(with.namespace, with.name)
+	return &with
+}
+
+// Check implements zapcore.Core
+func (c *objectCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.debugged && entry.Level >= c.objectsLevel {
+		return c.unsampled.Check(entry, checked)
+	}
+	if !c.level.Enabled(entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
+
+// messageSampler writes the first logs of every message every second, then every thereafter-th one. Unlike the
+// sampler of zap, it handles the verbose debug levels.
+type messageSampler struct {
+	zapcore.Core
+	counts *messageCounts
+}
+
+type messageCounts struct {
+	initial, thereafter uint64
+
+	lock   sync.Mutex
+	second time.Time
+	counts map[string]uint64
+}
+
+func newMessageSampler(core zapcore.Core, initial, thereafter int) *messageSampler {
+	return &messageSampler{Core: core, counts: &messageCounts{initial: uint64(initial), thereafter: uint64(thereafter),
+		counts: map[string]uint64{}}}
+}
+
+// With implements zapcore.Core
+func (s *messageSampler) With(fields []zapcore.Field) zapcore.Core {
+	return &messageSampler{Core: s.Core.With(fields), counts: s.counts}
+}
+
+// Check implements zapcore.Core
+func (s *messageSampler) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !s.Enabled(entry.Level) || !s.counts.sample(entry) {
+		return checked
+	}
+	return s.Core.Check(entry, checked)
+}
+
+// sample counts the entry in its second, and tells whether it is written.
+func (c *messageCounts) sample(entry zapcore.Entry) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if second := entry.Time.Truncate(time.Second); second.After(c.second) {
+		c.second, c.counts = second, map[string]uint64{}
+	}
+	c.counts[entry.Message]++
+	count := c.counts[entry.Message]
+	return count <= c.initial || (count-c.initial)%c.thereafter == 0
+}
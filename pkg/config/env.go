@@ -0,0 +1,226 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+Every setting of the config file can be overridden with an environment variable, so that a single knob can be turned
+from a Helm chart without templating the whole file. The name of the variable is EnvPrefix followed by the path of the
+setting in upper snake case, e.g. `admission.defaultTimeZone` is overridden by
+`CRONJOB_OPERATOR_ADMISSION_DEFAULT_TIME_ZONE`. The lists are comma separated, the durations use the Go syntax like
+`30s`, the feature gates are comma separated `Name=true` pairs. The lists of objects, like `admission.extraHandlers`, can not be overridden.
+
+The precedence is: environment variables, then flags, then the config file.
+*/
+
+// EnvPrefix is the prefix of the environment variables overriding the settings of the config file.
+const EnvPrefix = "CRONJOB_OPERATOR_"
+
+var durationType = reflect.TypeOf(metav1.Duration{})
+
+// ApplyEnv overrides the settings of the config with the environment variables, given as `KEY=value` like
+// os.Environ returns them. An unknown variable with EnvPrefix is an error, since it is most probably a typo.
+func ApplyEnv(config *configv1.ProjectConfig, environ []string) error {
+	env := map[string]string{}
+	for _, kv := range environ {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(parts[0], EnvPrefix) {
+			env[parts[0]] = parts[1]
+		}
+	}
+	if len(env) == 0 {
+		return nil
+	}
+
+	if _, err := applyEnv(reflect.ValueOf(config).Elem(), strings.TrimSuffix(EnvPrefix, "_"), env); err != nil {
+		return err
+	}
+
+	if len(env) > 0 {
+		unknown := make([]string, 0, len(env))
+		for name := range env {
+			unknown = append(unknown, name)
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown environment variables %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// applyEnv sets the fields of the struct from the variables, removing the ones it used. It returns whether any
+// field was set.
+func applyEnv(v reflect.Value, prefix string, env map[string]string) (bool, error) {
+	applied := false
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, inline := jsonName(field)
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		path := prefix
+		if !inline {
+			path = prefix + "_" + upperSnakeCase(name)
+		}
+
+		ok, err := applyEnvValue(v.Field(i), path, env)
+		if err != nil {
+			return false, err
+		}
+		applied = applied || ok
+	}
+	return applied, nil
+}
+
+func applyEnvValue(v reflect.Value, name string, env map[string]string) (bool, error) {
+	switch {
+	case v.Kind() == reflect.Ptr:
+		// only allocate the pointer if something is set below it
+		elem := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			elem.Elem().Set(v.Elem())
+		}
+		ok, err := applyEnvValue(elem.Elem(), name, env)
+		if ok {
+			v.Set(elem)
+		}
+		return ok, err
+	case v.Type() != durationType && v.Kind() == reflect.Struct:
+		return applyEnv(v, name, env)
+	}
+
+	raw, ok := env[name]
+	if !ok {
+		return false, nil
+	}
+	delete(env, name)
+	if err := setValue(v, raw); err != nil {
+		return false, fmt.Errorf("invalid value %q of %s: %w", raw, name, err)
+	}
+	return true, nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(metav1.Duration{Duration: d}))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("can not be set from the environment")
+		}
+		items := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(items)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.Bool {
+			return fmt.Errorf("can not be set from the environment")
+		}
+		entries := reflect.MakeMap(v.Type())
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			parts := strings.SplitN(item, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("missing bool value for %s", item)
+			}
+			b, err := strconv.ParseBool(strings.TrimSpace(parts[1]))
+			if err != nil {
+				return err
+			}
+			entries.SetMapIndex(reflect.ValueOf(strings.TrimSpace(parts[0])).Convert(v.Type().Key()),
+				reflect.ValueOf(b).Convert(v.Type().Elem()))
+		}
+		v.Set(entries)
+	default:
+		return fmt.Errorf("can not be set from the environment")
+	}
+	return nil
+}
+
+// jsonName returns the name of the field in the config file, and whether it is inlined.
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			return "", true
+		}
+	}
+	if parts[0] == "" {
+		if field.Anonymous {
+			return "", true
+		}
+		return field.Name, false
+	}
+	return parts[0], false
+}
+
+// upperSnakeCase converts a camel case name like `defaultTimeZone` to `DEFAULT_TIME_ZONE`.
+func upperSnakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
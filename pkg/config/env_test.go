@@ -0,0 +1,70 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Environment overrides", func() {
+	It("Should override the settings of the config file", func() {
+		config := &configv1.ProjectConfig{}
+		config.Admission.DefaultTimeZone = "Etc/UTC"
+
+		Expect(ApplyEnv(config, []string{
+			"HOME=/root",
+			"CRONJOB_OPERATOR_ADMISSION_DEFAULT_TIME_ZONE=Europe/Istanbul",
+			"CRONJOB_OPERATOR_ADMISSION_ACTIVATION_HORIZON=48h",
+			"CRONJOB_OPERATOR_ADMISSION_IMAGE_REGISTRIES_ALLOWED=gcr.io, quay.io/myorg",
+			"CRONJOB_OPERATOR_CRON_JOB_CONTROLLER_RATE_LIMIT_QPS=20",
+			"CRONJOB_OPERATOR_LEADER_ELECTION_LEADER_ELECT=true",
+			"CRONJOB_OPERATOR_METRICS_BIND_ADDRESS=:8080",
+			"CRONJOB_OPERATOR_FEATURE_GATES=CronJobTimeZone=true",
+		})).To(Succeed())
+
+		Expect(config.Admission.DefaultTimeZone).To(Equal("Europe/Istanbul"))
+		Expect(config.Admission.ActivationHorizon).To(Equal(&metav1.Duration{Duration: 48 * time.Hour}))
+		Expect(config.Admission.ImageRegistries.Allowed).To(Equal([]string{"gcr.io", "quay.io/myorg"}))
+		Expect(config.CronJobController.RateLimit.QPS).To(Equal(20))
+		Expect(config.LeaderElection).NotTo(BeNil())
+		Expect(*config.LeaderElection.LeaderElect).To(BeTrue())
+		Expect(config.Metrics.BindAddress).To(Equal(":8080"))
+		Expect(config.FeatureGates).To(Equal(map[string]bool{"CronJobTimeZone": true}))
+	})
+
+	It("Should leave the unset pointers alone", func() {
+		config := &configv1.ProjectConfig{}
+		Expect(ApplyEnv(config, []string{"CRONJOB_OPERATOR_CLUSTER_NAME=eu-1"})).To(Succeed())
+		Expect(config.ClusterName).To(Equal("eu-1"))
+		Expect(config.LeaderElection).To(BeNil())
+		Expect(config.Admission.ActivationHorizon).To(BeNil())
+	})
+
+	It("Should reject the unknown variables and the invalid values", func() {
+		Expect(ApplyEnv(&configv1.ProjectConfig{},
+			[]string{"CRONJOB_OPERATOR_ADMISSION_DEFAULT_TIMEZONE=Europe/Istanbul"})).NotTo(Succeed())
+		Expect(ApplyEnv(&configv1.ProjectConfig{},
+			[]string{"CRONJOB_OPERATOR_ADMISSION_ACTIVATION_HORIZON=forever"})).NotTo(Succeed())
+		Expect(ApplyEnv(&configv1.ProjectConfig{},
+			[]string{"CRONJOB_OPERATOR_FEATURE_GATES=CronJobTimeZone"})).NotTo(Succeed())
+	})
+})
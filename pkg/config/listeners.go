@@ -0,0 +1,142 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+/*
+The addresses of the metrics endpoint and of the health probes, as well as the graceful shutdown timeout, are fields
+of the ControllerManagerConfiguration embedded in the config file, under `metrics`, `health` and `gracefulShutDown`.
+Beside being overridable by the flags like the settings of our components, the addresses are validated with them.
+Since every server of the manager listens in the same pod, two of them on the same port would only fail when the
+manager starts, with an "address already in use" error of the server which happened to start last.
+*/
+
+// The default addresses of the servers of the manager.
+const (
+	defaultMetricsBindAddress       = ":8080"
+	defaultSecureMetricsBindAddress = ":8443"
+	defaultAPIBindAddress           = ":8444"
+	defaultGRPCBindAddress          = ":9090"
+	defaultHTTPTriggerBindAddress   = ":8445"
+	defaultWebhookPort              = 9443
+)
+
+// listenAddress is an address one of the servers of the manager listens on.
+type listenAddress struct {
+	fldPath    *field.Path
+	address    string
+	host, port string
+}
+
+func validateListenAddresses(config *configv1.ProjectConfig) field.ErrorList {
+	var allErrs field.ErrorList
+	var addresses []listenAddress
+	add := func(fldPath *field.Path, address string) {
+		host, port, err := net.SplitHostPort(address)
+		if err == nil {
+			_, err = strconv.ParseUint(port, 10, 16)
+		}
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath, address, "must be a host:port address, or 0 to disable it"))
+			return
+		}
+		addresses = append(addresses, listenAddress{fldPath: fldPath, address: address, host: host, port: port})
+	}
+
+	if config.SecureMetrics.Enabled {
+		// the plain HTTP endpoint is disabled
+		address := config.SecureMetrics.BindAddress
+		if address == "" {
+			address = defaultSecureMetricsBindAddress
+		}
+		add(field.NewPath("secureMetrics", "bindAddress"), address)
+	} else if address := config.Metrics.BindAddress; address != "0" {
+		if address == "" {
+			address = defaultMetricsBindAddress
+		}
+		add(field.NewPath("metrics", "bindAddress"), address)
+	}
+	if config.API.Enabled {
+		address := config.API.BindAddress
+		if address == "" {
+			address = defaultAPIBindAddress
+		}
+		add(field.NewPath("api", "bindAddress"), address)
+	}
+	if config.GRPC.Enabled {
+		address := config.GRPC.BindAddress
+		if address == "" {
+			address = defaultGRPCBindAddress
+		}
+		add(field.NewPath("grpc", "bindAddress"), address)
+	}
+	if config.HTTPTrigger.Enabled {
+		address := config.HTTPTrigger.BindAddress
+		if address == "" {
+			address = defaultHTTPTriggerBindAddress
+		}
+		add(field.NewPath("httpTrigger", "bindAddress"), address)
+	}
+	if address := config.Health.HealthProbeBindAddress; address != "" && address != "0" {
+		add(field.NewPath("health", "healthProbeBindAddress"), address)
+	}
+	if webhookServerEnabled(config.Admission) {
+		port := defaultWebhookPort
+		if config.Webhook.Port != nil {
+			port = *config.Webhook.Port
+		}
+		// an invalid port is reported on its own
+		if port > 0 && port <= 65535 {
+			add(field.NewPath("webhook", "port"), net.JoinHostPort(config.Webhook.Host, strconv.Itoa(port)))
+		}
+	}
+
+	for i, address := range addresses {
+		for _, previous := range addresses[:i] {
+			if address.port != "0" && address.port == previous.port && hostsOverlap(address.host, previous.host) {
+				allErrs = append(allErrs, field.Invalid(address.fldPath, address.address,
+					fmt.Sprintf("collides with %s %s", previous.fldPath, previous.address)))
+				break
+			}
+		}
+	}
+	return allErrs
+}
+
+// webhookServerEnabled returns whether the webhook server is started with the given settings.
+func webhookServerEnabled(admission configv1.AdmissionConfig) bool {
+	return admission.MutatingWebhook == nil || *admission.MutatingWebhook ||
+		admission.ValidatingWebhook == nil || *admission.ValidatingWebhook ||
+		len(admission.ExtraHandlers) > 0
+}
+
+// hostsOverlap returns whether two servers binding to the given hosts may listen on the same address.
+func hostsOverlap(a, b string) bool {
+	return a == b || isWildcardHost(a) || isWildcardHost(b)
+}
+
+func isWildcardHost(host string) bool {
+	return host == "" || host == "0.0.0.0" || host == "::"
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+/*
+The logger is built from the --zap-* flags and the logging settings of the config file, the latter win when they are
+set. We build it ourselves instead of with controller-runtime's zap.New, since the sampling of controller-runtime is
+decided once from the initial level: its sampler can not handle the verbose debug levels, which the level may be
+switched to when the config file is reloaded. Our sampler leaves these levels alone, unless the debug sampling is
+set: the debug logs and the more verbose ones are then sampled per message by a sampler of ours.
+
+The objects to debug are found on the `namespace` and `name` values of the loggers, which controller-runtime adds to
+the logger of every reconcile. The core is then built with the most verbose of the two levels, and the objectCore on
+top of it gates the logs of the other objects with the level of the logger.
+*/
+
+var timeEncodings = []string{"epoch", "millis", "nanos", "iso8601", "rfc3339", "rfc3339nano"}
+
+var logBackends = []string{"zap", "slog"}
+
+// NewLogger builds the logger of the manager. The level of the returned AtomicLevel can be changed while the logger
+// is in use.
+func NewLogger(opts *crzap.Options, logging configv1.LoggingConfig) (logr.Logger, zap.AtomicLevel, error) {
+	development := opts.Development
+	if logging.Development != nil {
+		development = *logging.Development
+	}
+
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	if development {
+		level.SetLevel(zapcore.DebugLevel)
+	}
+	if flagLevel, ok := opts.Level.(zap.AtomicLevel); ok {
+		level.SetLevel(flagLevel.Level())
+	}
+	if err := ApplyLogLevel(level, logging); err != nil {
+		return nil, level, err
+	}
+	if logging.Backend == "slog" {
+		logger, err := newSlogLogger(opts, logging, development, level)
+		return logger, level, err
+	}
+
+	var stacktraceLevel zapcore.LevelEnabler = zapcore.ErrorLevel
+	if development {
+		stacktraceLevel = zapcore.WarnLevel
+	}
+	if opts.StacktraceLevel != nil {
+		stacktraceLevel = opts.StacktraceLevel
+	}
+	if logging.StacktraceLevel != "" {
+		var l zapcore.Level
+		if err := l.UnmarshalText([]byte(logging.StacktraceLevel)); err != nil {
+			return nil, level, fmt.Errorf("invalid stack trace level %q: %w", logging.StacktraceLevel, err)
+		}
+		stacktraceLevel = l
+	}
+
+	encoderConfigOptions := opts.EncoderConfigOptions
+	if logging.TimeEncoding != "" {
+		if !containsString(timeEncodings, logging.TimeEncoding) {
+			return nil, level, fmt.Errorf("invalid time encoding %q", logging.TimeEncoding)
+		}
+		var timeEncoder zapcore.TimeEncoder
+		_ = timeEncoder.UnmarshalText([]byte(logging.TimeEncoding))
+		encoderConfigOptions = append(encoderConfigOptions, func(c *zapcore.EncoderConfig) {
+			c.EncodeTime = timeEncoder
+		})
+	}
+	encoder, err := newEncoder(opts, logging.Encoder, development, encoderConfigOptions)
+	if err != nil {
+		return nil, level, err
+	}
+
+	initial, thereafter := 100, 100
+	if development {
+		initial = 0
+	}
+	if logging.Sampling != nil {
+		initial, thereafter = logging.Sampling.Initial, logging.Sampling.Thereafter
+		if initial < 0 || (initial > 0 && thereafter <= 0) {
+			return nil, level, fmt.Errorf("invalid sampling, initial must not be negative and thereafter must be positive")
+		}
+	}
+	if sampling := logging.DebugSampling; sampling != nil {
+		if sampling.Initial < 0 || (sampling.Initial > 0 && sampling.Thereafter <= 0) {
+			return nil, level, fmt.Errorf("invalid debug sampling, initial must not be negative and thereafter " +
+				"must be positive")
+		}
+	}
+	objects, err := parseDebugObjects(logging.DebugObjects)
+	if err != nil {
+		return nil, level, err
+	}
+	objectsLevel := zapcore.DebugLevel
+	if logging.DebugObjectsLevel != "" {
+		if objectsLevel, err = ParseLogLevel(logging.DebugObjectsLevel); err != nil {
+			return nil, level, err
+		}
+	}
+
+	sink := zapcore.AddSync(opts.DestWriter)
+	if opts.DestWriter == nil {
+		sink = zapcore.AddSync(os.Stderr)
+	}
+	if file := logging.File; file != nil {
+		if file.Path == "" || file.MaxSizeMB < 0 || file.MaxAgeDays < 0 || file.MaxBackups < 0 {
+			return nil, level, fmt.Errorf("invalid log file, path is required and the limits must not be negative")
+		}
+		sink = zapcore.NewMultiWriteSyncer(sink, zapcore.AddSync(NewLogFile(file)))
+	}
+	var enabler zapcore.LevelEnabler = level
+	if len(objects) > 0 {
+		enabler = zap.LevelEnablerFunc(func(l zapcore.Level) bool { return level.Enabled(l) || l >= objectsLevel })
+	}
+	var core zapcore.Core = zapcore.NewCore(&crzap.KubeAwareEncoder{Encoder: encoder, Verbose: development}, sink,
+		enabler)
+	unsampled := core
+	if sampling := logging.DebugSampling; initial > 0 || (sampling != nil && sampling.Initial > 0) {
+		sampler := &debugSafeSampler{Core: core, sampled: core}
+		if initial > 0 {
+			sampler.sampled = zapcore.NewSampler(core, time.Second, initial, thereafter)
+		}
+		if sampling != nil && sampling.Initial > 0 {
+			sampler.debug = newMessageSampler(core, sampling.Initial, sampling.Thereafter)
+		}
+		core = sampler
+	}
+	if len(objects) > 0 {
+		core = &objectCore{Core: core, unsampled: unsampled, level: level, objects: objects,
+			objectsLevel: objectsLevel}
+	}
+
+	zapOpts := append([]zap.Option{}, opts.ZapOpts...)
+	if development {
+		zapOpts = append(zapOpts, zap.Development())
+	}
+	zapOpts = append(zapOpts, zap.AddStacktrace(stacktraceLevel), zap.AddCallerSkip(1), zap.ErrorOutput(sink))
+	return zapr.NewLogger(zap.New(core, zapOpts...)), level, nil
+}
+
+// newEncoder returns the encoder of the given name, falling back to the one of the --zap-encoder flag and then to
+// the default of the mode.
+func newEncoder(opts *crzap.Options, name string, development bool, configOptions []crzap.EncoderConfigOption) (zapcore.Encoder, error) {
+	if name == "" && opts.NewEncoder != nil {
+		return opts.NewEncoder(configOptions...), nil
+	}
+	if name == "" {
+		name = "json"
+		if development {
+			name = "console"
+		}
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	if development {
+		encoderConfig = zap.NewDevelopmentEncoderConfig()
+	}
+	for _, option := range configOptions {
+		option(&encoderConfig)
+	}
+	switch name {
+	case "json":
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	case "console":
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	}
+	return nil, fmt.Errorf("invalid encoder %q, must be json or console", name)
+}
+
+// debugSafeSampler samples the logs down to the debug level, and passes the more verbose ones through unsampled. The
+// debug logs and the more verbose ones go through the debug sampler instead, if any.
+type debugSafeSampler struct {
+	zapcore.Core
+	sampled zapcore.Core
+	debug   zapcore.Core
+}
+
+// With implements zapcore.Core
+func (s *debugSafeSampler) With(fields []zapcore.Field) zapcore.Core {
+	with := &debugSafeSampler{Core: s.Core.With(fields), sampled: s.sampled.With(fields)}
+	if s.debug != nil {
+		with.debug = s.debug.With(fields)
+	}
+	return with
+}
+
+// Check implements zapcore.Core
+func (s *debugSafeSampler) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if s.debug != nil && entry.Level <= zapcore.DebugLevel {
+		return s.debug.Check(entry, checked)
+	}
+	if entry.Level < zapcore.DebugLevel {
+		return s.Core.Check(entry, checked)
+	}
+	return s.sampled.Check(entry, checked)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NewLogFile returns a writer to the log file, rotated as configured. The file is opened on the first write.
+func NewLogFile(file *configv1.LogFileConfig) io.Writer {
+	return &lumberjack.Logger{
+		Filename:   file.Path,
+		MaxSize:    file.MaxSizeMB,
+		MaxAge:     file.MaxAgeDays,
+		MaxBackups: file.MaxBackups,
+		Compress:   file.Compress,
+	}
+}
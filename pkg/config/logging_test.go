@@ -0,0 +1,161 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap/zapcore"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var _ = Describe("Logger", func() {
+	var out *bytes.Buffer
+	var opts *crzap.Options
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+		opts = &crzap.Options{DestWriter: out}
+	})
+
+	It("Should default to the production mode", func() {
+		logger, level, err := NewLogger(opts, configv1.LoggingConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(level.Level()).To(Equal(zapcore.InfoLevel))
+
+		logger.Info("hello", "name", "world")
+		logger.V(1).Info("hidden")
+		Expect(out.String()).To(HavePrefix(`{"level":"info","ts":`))
+		Expect(out.String()).To(ContainSubstring(`"name":"world"`))
+		Expect(out.String()).NotTo(ContainSubstring("hidden"))
+	})
+
+	It("Should apply the logging settings of the config file", func() {
+		development := true
+		logger, level, err := NewLogger(opts, configv1.LoggingConfig{
+			Development:  &development,
+			Encoder:      "json",
+			TimeEncoding: "rfc3339",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(level.Level()).To(Equal(zapcore.DebugLevel))
+
+		logger.V(1).Info("hello")
+		Expect(out.String()).To(MatchRegexp(`^\{"L":"DEBUG","T":"\d{4}-\d\d-\d\dT`))
+	})
+
+	It("Should sample the repeated logs, but not the verbose ones", func() {
+		logger, level, err := NewLogger(opts, configv1.LoggingConfig{
+			Sampling: &configv1.LogSamplingConfig{Initial: 2, Thereafter: 100},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		for i := 0; i < 10; i++ {
+			logger.Info("repeated")
+		}
+		Expect(strings.Count(out.String(), "repeated")).To(Equal(2))
+
+		level.SetLevel(zapcore.Level(-3))
+		for i := 0; i < 10; i++ {
+			logger.V(3).Info("verbose")
+		}
+		Expect(strings.Count(out.String(), "verbose")).To(Equal(10))
+	})
+
+	It("Should sample the debug logs per message when asked to", func() {
+		logger, level, err := NewLogger(opts, configv1.LoggingConfig{
+			Level:         "2",
+			DebugSampling: &configv1.LogSamplingConfig{Initial: 2, Thereafter: 4},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(level.Level()).To(Equal(zapcore.Level(-2)))
+		for i := 0; i < 10; i++ {
+			logger.V(1).Info("reconciling")
+			logger.V(2).Info("verbose")
+			logger.Info("repeated")
+		}
+		// the first 2, then the 6th and the 10th
+		Expect(strings.Count(out.String(), "reconciling")).To(Equal(4))
+		Expect(strings.Count(out.String(), "verbose")).To(Equal(4))
+		Expect(strings.Count(out.String(), "repeated")).To(Equal(10))
+	})
+
+	It("Should write the logs of the objects to debug from their own level", func() {
+		logger, _, err := NewLogger(opts, configv1.LoggingConfig{
+			DebugSampling:     &configv1.LogSamplingConfig{Initial: 1, Thereafter: 100},
+			DebugObjects:      []string{"payments/nightly-settle", "staging", "/cluster-wide"},
+			DebugObjectsLevel: "2",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		reconcile := func(namespace, name string) {
+			objectLogger := logger.WithValues("name", name, "namespace", namespace)
+			for i := 0; i < 3; i++ {
+				objectLogger.V(2).Info("reconciling " + namespace + "/" + name)
+			}
+		}
+		reconcile("payments", "nightly-settle")
+		reconcile("payments", "hourly-sync")
+		reconcile("staging", "report")
+		reconcile("", "cluster-wide")
+		logger.WithValues("namespace", "payments").V(1).Info("reconciling the namespace")
+
+		Expect(strings.Count(out.String(), "reconciling payments/nightly-settle")).To(Equal(3))
+		Expect(out.String()).NotTo(ContainSubstring("hourly-sync"))
+		Expect(strings.Count(out.String(), "reconciling staging/report")).To(Equal(3))
+		Expect(strings.Count(out.String(), "reconciling /cluster-wide")).To(Equal(3))
+		Expect(out.String()).NotTo(ContainSubstring("reconciling the namespace"))
+	})
+
+	It("Should write the logs to the log file too", func() {
+		dir, err := ioutil.TempDir("", "logging")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "manager.log")
+		logger, _, err := NewLogger(opts, configv1.LoggingConfig{File: &configv1.LogFileConfig{Path: path}})
+		Expect(err).NotTo(HaveOccurred())
+		logger.Info("hello")
+
+		Expect(out.String()).To(ContainSubstring("hello"))
+		content, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal(out.String()))
+	})
+
+	It("Should reject the invalid settings", func() {
+		_, _, err := NewLogger(opts, configv1.LoggingConfig{Encoder: "xml"})
+		Expect(err).To(HaveOccurred())
+		_, _, err = NewLogger(opts, configv1.LoggingConfig{StacktraceLevel: "loud"})
+		Expect(err).To(HaveOccurred())
+		_, _, err = NewLogger(opts, configv1.LoggingConfig{DebugObjects: []string{"payments/"}})
+		Expect(err).To(HaveOccurred())
+		Expect(Validate(&configv1.ProjectConfig{Logging: configv1.LoggingConfig{
+			TimeEncoding:      "unix",
+			Sampling:          &configv1.LogSamplingConfig{Initial: 10},
+			DebugSampling:     &configv1.LogSamplingConfig{Initial: -1},
+			DebugObjects:      []string{"payments/nightly-settle", "", "a/b/c"},
+			DebugObjectsLevel: "loud",
+			File:              &configv1.LogFileConfig{MaxAgeDays: -1},
+		}})).To(HaveLen(8))
+	})
+})
@@ -0,0 +1,103 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+/*
+The settings of the manager in the config file follow the ControllerManagerConfiguration of controller-runtime, so
+the file format stays the same. Instead of the ComponentConfig loader of controller-runtime, which is deprecated in
+its later versions, they are mapped onto the options of the manager here. Like the loader did, the options which are
+already set keep their values.
+
+The runnables get 25 seconds to stop by default, below the 30 seconds of terminationGracePeriodSeconds, so they can
+flush or hand over the work they hold before the kubelet kills the pod.
+*/
+
+// DefaultGracefulShutdownTimeout is how long the runnables get to stop when neither the flags nor the config file
+// set it.
+const DefaultGracefulShutdownTimeout = 25 * time.Second
+
+// ManagerOptions returns the options with the manager settings of the config applied to the unset options.
+func ManagerOptions(options manager.Options, config *configv1.ProjectConfig) manager.Options {
+	if options.SyncPeriod == nil && config.SyncPeriod != nil {
+		options.SyncPeriod = &config.SyncPeriod.Duration
+	}
+	if options.Namespace == "" && config.CacheNamespace != "" {
+		options.Namespace = config.CacheNamespace
+	}
+	if options.GracefulShutdownTimeout == nil && config.GracefulShutdownTimeout != nil {
+		options.GracefulShutdownTimeout = &config.GracefulShutdownTimeout.Duration
+	}
+	if options.GracefulShutdownTimeout == nil {
+		timeout := DefaultGracefulShutdownTimeout
+		options.GracefulShutdownTimeout = &timeout
+	}
+
+	if leaderElection := config.LeaderElection; leaderElection != nil {
+		if !options.LeaderElection && leaderElection.LeaderElect != nil {
+			options.LeaderElection = *leaderElection.LeaderElect
+		}
+		if options.LeaderElectionResourceLock == "" {
+			options.LeaderElectionResourceLock = leaderElection.ResourceLock
+		}
+		if options.LeaderElectionNamespace == "" {
+			options.LeaderElectionNamespace = leaderElection.ResourceNamespace
+		}
+		if options.LeaderElectionID == "" {
+			options.LeaderElectionID = leaderElection.ResourceName
+		}
+		if options.LeaseDuration == nil && leaderElection.LeaseDuration.Duration != 0 {
+			options.LeaseDuration = &leaderElection.LeaseDuration.Duration
+		}
+		if options.RenewDeadline == nil && leaderElection.RenewDeadline.Duration != 0 {
+			options.RenewDeadline = &leaderElection.RenewDeadline.Duration
+		}
+		if options.RetryPeriod == nil && leaderElection.RetryPeriod.Duration != 0 {
+			options.RetryPeriod = &leaderElection.RetryPeriod.Duration
+		}
+	}
+
+	if options.MetricsBindAddress == "" {
+		options.MetricsBindAddress = config.Metrics.BindAddress
+	}
+	if options.HealthProbeBindAddress == "" {
+		options.HealthProbeBindAddress = config.Health.HealthProbeBindAddress
+	}
+	if options.ReadinessEndpointName == "" {
+		options.ReadinessEndpointName = config.Health.ReadinessEndpointName
+	}
+	if options.LivenessEndpointName == "" {
+		options.LivenessEndpointName = config.Health.LivenessEndpointName
+	}
+
+	if options.Port == 0 && config.Webhook.Port != nil {
+		options.Port = *config.Webhook.Port
+	}
+	if options.Host == "" {
+		options.Host = config.Webhook.Host
+	}
+	if options.CertDir == "" {
+		options.CertDir = config.Webhook.CertDir
+	}
+	return options
+}
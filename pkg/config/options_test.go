@@ -0,0 +1,98 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	componentconfigv1alpha1 "k8s.io/component-base/config/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+var _ = Describe("ManagerOptions", func() {
+	leaderElect := true
+	port := 9444
+	projectConfig := &configv1.ProjectConfig{
+		ControllerManagerConfigurationSpec: cfg.ControllerManagerConfigurationSpec{
+			SyncPeriod:              &metav1.Duration{Duration: time.Hour},
+			CacheNamespace:          "cronjobs",
+			GracefulShutdownTimeout: &metav1.Duration{Duration: time.Minute},
+			LeaderElection: &componentconfigv1alpha1.LeaderElectionConfiguration{
+				LeaderElect:       &leaderElect,
+				ResourceLock:      "leases",
+				ResourceNamespace: "cronjob-system",
+				ResourceName:      "80807133.tutorial.kubebuilder.io",
+				LeaseDuration:     metav1.Duration{Duration: 30 * time.Second},
+			},
+			Metrics: cfg.ControllerMetrics{BindAddress: "127.0.0.1:8080"},
+			Health: cfg.ControllerHealth{
+				HealthProbeBindAddress: ":8081",
+				ReadinessEndpointName:  "/readyz",
+			},
+			Webhook: cfg.ControllerWebhook{Port: &port, CertDir: "/tmp/certs"},
+		},
+	}
+
+	It("Should map the manager settings of the config file onto the options", func() {
+		options := ManagerOptions(ctrl.Options{}, projectConfig)
+		Expect(*options.SyncPeriod).To(Equal(time.Hour))
+		Expect(options.Namespace).To(Equal("cronjobs"))
+		Expect(*options.GracefulShutdownTimeout).To(Equal(time.Minute))
+		Expect(options.LeaderElection).To(BeTrue())
+		Expect(options.LeaderElectionResourceLock).To(Equal("leases"))
+		Expect(options.LeaderElectionNamespace).To(Equal("cronjob-system"))
+		Expect(options.LeaderElectionID).To(Equal("80807133.tutorial.kubebuilder.io"))
+		Expect(*options.LeaseDuration).To(Equal(30 * time.Second))
+		Expect(options.RenewDeadline).To(BeNil())
+		Expect(options.RetryPeriod).To(BeNil())
+		Expect(options.MetricsBindAddress).To(Equal("127.0.0.1:8080"))
+		Expect(options.HealthProbeBindAddress).To(Equal(":8081"))
+		Expect(options.ReadinessEndpointName).To(Equal("/readyz"))
+		Expect(options.LivenessEndpointName).To(BeEmpty())
+		Expect(options.Port).To(Equal(9444))
+		Expect(options.CertDir).To(Equal("/tmp/certs"))
+	})
+
+	It("Should keep the options which are already set", func() {
+		syncPeriod := 10 * time.Minute
+		options := ManagerOptions(ctrl.Options{
+			SyncPeriod:         &syncPeriod,
+			Namespace:          "default",
+			LeaderElectionID:   "cronjob-operator",
+			MetricsBindAddress: "0",
+			Port:               9443,
+		}, projectConfig)
+		Expect(*options.SyncPeriod).To(Equal(10 * time.Minute))
+		Expect(options.Namespace).To(Equal("default"))
+		Expect(options.LeaderElectionID).To(Equal("cronjob-operator"))
+		Expect(options.MetricsBindAddress).To(Equal("0"))
+		Expect(options.Port).To(Equal(9443))
+	})
+
+	It("Should accept a config file without the leader election settings", func() {
+		options := ManagerOptions(ctrl.Options{}, &configv1.ProjectConfig{})
+		Expect(options.LeaderElection).To(BeFalse())
+		Expect(options.LeaderElectionID).To(BeEmpty())
+		Expect(options.SyncPeriod).To(BeNil())
+		Expect(*options.GracefulShutdownTimeout).To(Equal(DefaultGracefulShutdownTimeout))
+	})
+})
@@ -0,0 +1,93 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+/*
+A config change can be checked before it is shipped, e.g. in the CI pipeline of the config repository, with
+`manager --validate-config --config=...`. The files are checked like in strict mode, then the config is checked again
+once the flags and the environment variables are applied. All the problems are reported at once, as JSON.
+*/
+
+// Report is the result of the validation of the config files, printed as JSON by --validate-config.
+type Report struct {
+	// Valid is true if there is no problem.
+	Valid bool `json:"valid"`
+	// Files are the config files which were checked, in the order they are merged.
+	Files []string `json:"files"`
+	// Problems are the unknown fields and the invalid values.
+	Problems []Problem `json:"problems"`
+}
+
+// Check validates the config files at paths, and the config once the overrides are applied.
+func Check(paths []string, scheme *runtime.Scheme, overrides func(config *configv1.ProjectConfig) error) *Report {
+	report := &Report{Files: []string{}, Problems: []Problem{}}
+	if files, err := ExpandPaths(paths); err == nil {
+		report.Files = files
+	}
+
+	config, problems, err := loadChecked(paths, scheme)
+	switch {
+	case err != nil:
+		report.Problems = append(report.Problems, Problem{Message: err.Error()})
+	case len(problems) > 0:
+		report.Problems = append(report.Problems, problems...)
+	default:
+		var overrideErr error
+		if overrides != nil {
+			overrideErr = overrides(config)
+		}
+		if overrideErr == nil {
+			overrideErr = Validate(config).ToAggregate()
+		}
+		report.Problems = append(report.Problems, errorProblems(overrideErr)...)
+	}
+
+	report.Valid = len(report.Problems) == 0
+	return report
+}
+
+// errorProblems turns the errors of the overrides into problems, one per error of an aggregate.
+func errorProblems(err error) []Problem {
+	if err == nil {
+		return nil
+	}
+	errs := []error{err}
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		errs = aggregate.Errors()
+	}
+
+	problems := make([]Problem, 0, len(errs))
+	for _, err := range errs {
+		problem := Problem{Message: err.Error()}
+		var fieldErr *field.Error
+		if errors.As(err, &fieldErr) {
+			problem.Field = fieldErr.Field
+		}
+		problems = append(problems, problem)
+	}
+	return problems
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+var _ = Describe("Check", func() {
+	var dir string
+	var scheme *runtime.Scheme
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "config")
+		Expect(err).NotTo(HaveOccurred())
+		scheme = runtime.NewScheme()
+		utilruntime.Must(configv1.AddToScheme(scheme))
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	It("Should report a valid config", func() {
+		path := write("config.yaml", `apiVersion: config.example.com/v1
+kind: ProjectConfig
+clusterName: prod
+`)
+		report := Check([]string{path}, scheme, nil)
+		Expect(report.Valid).To(BeTrue())
+		Expect(report.Files).To(Equal([]string{path}))
+		Expect(report.Problems).To(BeEmpty())
+	})
+
+	It("Should report all the invalid values with their location", func() {
+		path := write("config.yaml", `apiVersion: config.example.com/v1
+kind: ProjectConfig
+cronJobController:
+  maxConcurrentReconciles: -1
+admission:
+  defaultTimeZone: Mars/Olympus_Mons
+`)
+		report := Check([]string{path}, scheme, nil)
+		Expect(report.Valid).To(BeFalse())
+		Expect(report.Problems).To(ConsistOf(
+			Problem{File: path, Line: 4, Field: "cronJobController.maxConcurrentReconciles",
+				Message: "cronJobController.maxConcurrentReconciles: Invalid value: -1: must not be negative"},
+			Problem{File: path, Line: 6, Field: "admission.defaultTimeZone",
+				Message: `admission.defaultTimeZone: Invalid value: "Mars/Olympus_Mons": unknown time zone`},
+		))
+	})
+
+	It("Should report the problems of the overrides", func() {
+		path := write("config.yaml", `apiVersion: config.example.com/v1
+kind: ProjectConfig
+`)
+		report := Check([]string{path}, scheme, func(config *configv1.ProjectConfig) error {
+			config.Client.QPS = -1
+			return nil
+		})
+		Expect(report.Valid).To(BeFalse())
+		Expect(report.Problems).To(HaveLen(1))
+		Expect(report.Problems[0].Field).To(Equal("client.qps"))
+
+		report = Check([]string{path}, scheme, func(config *configv1.ProjectConfig) error {
+			return errors.New("unknown environment variables CRONJOB_OPERATOR_TYPO")
+		})
+		Expect(report.Problems).To(Equal([]Problem{{Message: "unknown environment variables CRONJOB_OPERATOR_TYPO"}}))
+	})
+
+	It("Should report the missing files", func() {
+		report := Check([]string{filepath.Join(dir, "missing.yaml")}, scheme, nil)
+		Expect(report.Valid).To(BeFalse())
+		Expect(report.Problems).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Validate", func() {
+	It("Should check the rules spanning several components", func() {
+		Expect(Validate(&configv1.ProjectConfig{
+			Client: configv1.ClientConfig{QPS: 50, Burst: 10},
+			CronJobController: configv1.CronJobControllerConfig{
+				RequeueJitter: &metav1.Duration{Duration: 30 * time.Second},
+			},
+			Admission: configv1.AdmissionConfig{
+				MinStartingDeadline: &metav1.Duration{Duration: 30 * time.Second},
+			},
+		})).To(HaveLen(2))
+	})
+})
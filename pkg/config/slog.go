@@ -0,0 +1,170 @@
+//go:build go1.21
+// +build go1.21
+
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+/*
+With the `slog` backend, the logs are handed to a handler of `log/slog` instead of zap, so the handlers shared across
+an organization, e.g. the ones redacting secrets or adding the identity of the workload, can be used as they are. The
+verbosity of logr maps to the slog levels below Info, and the level is still kept in the atomic level, so it can be
+changed at runtime like with zap. The bridge needs `log/slog`, so it is only built with Go 1.21 and newer.
+*/
+
+// SlogHandler is the handler of the `slog` backend. The programs embedding the manager set it before the logger is
+// built, the JSON or the text handler of `log/slog` is used when it is nil.
+var SlogHandler slog.Handler
+
+func newSlogLogger(opts *crzap.Options, logging configv1.LoggingConfig, development bool,
+	level zap.AtomicLevel) (logr.Logger, error) {
+	handler := SlogHandler
+	if handler == nil {
+		var out io.Writer = os.Stderr
+		if opts.DestWriter != nil {
+			out = opts.DestWriter
+		}
+		if file := logging.File; file != nil {
+			out = io.MultiWriter(out, NewLogFile(file))
+		}
+
+		// the level is checked by the bridge, the handler writes everything it gets
+		handlerOptions := &slog.HandlerOptions{AddSource: development, Level: slog.Level(-1 << 10)}
+		encoder := logging.Encoder
+		if encoder == "" && development {
+			encoder = "console"
+		}
+		if encoder == "console" {
+			handler = slog.NewTextHandler(out, handlerOptions)
+		} else {
+			handler = slog.NewJSONHandler(out, handlerOptions)
+		}
+	}
+	return &slogLogger{handler: handler, level: level}, nil
+}
+
+// slogLogger implements logr.Logger on top of a slog.Handler.
+type slogLogger struct {
+	handler   slog.Handler
+	level     zap.AtomicLevel
+	name      string
+	verbosity int
+}
+
+var _ logr.Logger = &slogLogger{}
+
+// Enabled implements logr.Logger
+func (l *slogLogger) Enabled() bool {
+	return l.level.Enabled(zapcore.Level(-l.verbosity))
+}
+
+// Info implements logr.Logger
+func (l *slogLogger) Info(msg string, keysAndValues ...interface{}) {
+	if !l.Enabled() {
+		return
+	}
+	// V(0) is Info, V(1) is Debug and the more verbose levels are below Debug
+	level := slog.LevelInfo
+	if l.verbosity > 0 {
+		level = slog.LevelDebug - slog.Level(l.verbosity-1)
+	}
+	l.log(level, msg, keysAndValues)
+}
+
+// Error implements logr.Logger
+func (l *slogLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	if !l.level.Enabled(zapcore.ErrorLevel) {
+		return
+	}
+	l.log(slog.LevelError, msg, append([]interface{}{"error", err}, keysAndValues...))
+}
+
+func (l *slogLogger) log(level slog.Level, msg string, keysAndValues []interface{}) {
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// skip runtime.Callers, log and Info or Error
+	runtime.Callers(3, pcs[:])
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	if l.name != "" {
+		record.AddAttrs(slog.String("logger", l.name))
+	}
+	record.AddAttrs(attrs(keysAndValues)...)
+	_ = l.handler.Handle(ctx, record)
+}
+
+// V implements logr.Logger
+func (l *slogLogger) V(level int) logr.Logger {
+	logger := *l
+	logger.verbosity += level
+	return &logger
+}
+
+// WithValues implements logr.Logger
+func (l *slogLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	logger := *l
+	logger.handler = l.handler.WithAttrs(attrs(keysAndValues))
+	return &logger
+}
+
+// WithName implements logr.Logger
+func (l *slogLogger) WithName(name string) logr.Logger {
+	logger := *l
+	logger.name = strings.TrimPrefix(l.name+"."+name, ".")
+	return &logger
+}
+
+// attrs converts the key and value pairs of logr into attributes. Like the KubeAwareEncoder of the zap backend, the
+// Kubernetes objects are reduced to their name and namespace.
+func attrs(keysAndValues []interface{}) []slog.Attr {
+	var result []slog.Attr
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok || i+1 == len(keysAndValues) {
+			// logr leaves the malformed pairs to the implementation, keep them visible
+			result = append(result, slog.Any("!BADKEY", keysAndValues[i]))
+			i--
+			continue
+		}
+		value := keysAndValues[i+1]
+		if object, ok := value.(metav1.Object); ok {
+			value = slog.GroupValue(slog.String("name", object.GetName()),
+				slog.String("namespace", object.GetNamespace()))
+		}
+		result = append(result, slog.Any(key, value))
+	}
+	return result
+}
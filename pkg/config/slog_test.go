@@ -0,0 +1,102 @@
+//go:build go1.21
+// +build go1.21
+
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// recordingHandler keeps the records it handles.
+type recordingHandler struct {
+	slog.Handler
+	records *[]slog.Record
+}
+
+func (h recordingHandler) Handle(_ context.Context, record slog.Record) error {
+	*h.records = append(*h.records, record)
+	return nil
+}
+
+var _ = Describe("Slog backend", func() {
+	var out *bytes.Buffer
+	var opts *crzap.Options
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+		opts = &crzap.Options{DestWriter: out}
+	})
+
+	AfterEach(func() {
+		SlogHandler = nil
+	})
+
+	It("Should write JSON with the built-in handler", func() {
+		logger, level, err := NewLogger(opts, configv1.LoggingConfig{Backend: "slog"})
+		Expect(err).NotTo(HaveOccurred())
+
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+		logger.WithName("controller").WithName("cronjob").WithValues("pod", pod).Info("hello", "count", 1)
+		Expect(out.String()).To(MatchRegexp(`^\{"time":"[^"]+","level":"INFO","msg":"hello",` +
+			`"pod":\{"name":"pod","namespace":"default"\},"logger":"controller.cronjob","count":1\}\n$`))
+
+		out.Reset()
+		logger.V(1).Info("hidden")
+		Expect(out.String()).To(BeEmpty())
+		level.SetLevel(zapcore.Level(-2))
+		logger.V(2).Info("verbose")
+		Expect(out.String()).To(ContainSubstring(`"level":"DEBUG-1","msg":"verbose"`))
+
+		out.Reset()
+		logger.Error(errors.New("boom"), "failed")
+		Expect(out.String()).To(ContainSubstring(`"level":"ERROR","msg":"failed","error":"boom"`))
+	})
+
+	It("Should write the text format with the console encoder", func() {
+		logger, _, err := NewLogger(opts, configv1.LoggingConfig{Backend: "slog", Encoder: "console"})
+		Expect(err).NotTo(HaveOccurred())
+		logger.Info("hello", "name", "world")
+		Expect(out.String()).To(MatchRegexp(`^time=\S+ level=INFO msg=hello name=world\n$`))
+	})
+
+	It("Should hand the logs to the plugged in handler", func() {
+		var records []slog.Record
+		handler := slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})
+		SlogHandler = recordingHandler{Handler: handler, records: &records}
+		logger, _, err := NewLogger(opts, configv1.LoggingConfig{Backend: "slog", Level: "debug"})
+		Expect(err).NotTo(HaveOccurred())
+
+		logger.V(1).Info("hello")
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Level).To(Equal(slog.LevelDebug))
+		Expect(records[0].Message).To(Equal("hello"))
+		Expect(out.String()).To(BeEmpty())
+	})
+})
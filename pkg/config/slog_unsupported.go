@@ -0,0 +1,34 @@
+//go:build !go1.21
+// +build !go1.21
+
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	crzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// newSlogLogger fails, log/slog is only available from Go 1.21.
+func newSlogLogger(*crzap.Options, configv1.LoggingConfig, bool, zap.AtomicLevel) (logr.Logger, error) {
+	return nil, fmt.Errorf("the slog logging backend requires a build with Go 1.21 or newer")
+}
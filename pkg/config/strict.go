@@ -0,0 +1,197 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+/*
+The decoder of controller-runtime silently drops the fields it does not know, so a typo like `defaultTimezone` goes
+unnoticed until somebody wonders why the setting has no effect. In strict mode, the file is first walked as a YAML
+tree against the fields of ProjectConfig, and every unknown field is reported with its line. Then the decoded settings
+are validated, and the invalid ones are reported with their lines as well.
+*/
+
+// LoadStrict reads the ProjectConfig from the files or the directories at paths like Load, but rejects the unknown
+// fields and the invalid values. An invalid value is reported in the last file which sets it.
+func LoadStrict(paths []string, scheme *runtime.Scheme) (*configv1.ProjectConfig, error) {
+	config, problems, err := loadChecked(paths, scheme)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, formatProblems(problems)
+	}
+	return config, nil
+}
+
+// loadChecked loads the config files, and returns the unknown fields or, if there are none, the invalid values.
+func loadChecked(paths []string, scheme *runtime.Scheme) (*configv1.ProjectConfig, []Problem, error) {
+	files, err := ExpandPaths(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	locations := map[string]Problem{}
+	var problems []Problem
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var root yaml.Node
+		if err := yaml.Unmarshal(content, &root); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		lines := map[string]int{}
+		var fileProblems []Problem
+		if len(root.Content) > 0 {
+			walkFields(root.Content[0], reflect.TypeOf(configv1.ProjectConfig{}), nil, lines, &fileProblems)
+		}
+		for _, p := range fileProblems {
+			p.File = file
+			problems = append(problems, p)
+		}
+		for fieldPath, line := range lines {
+			locations[fieldPath] = Problem{File: file, Line: line}
+		}
+	}
+	if len(problems) > 0 {
+		return nil, problems, nil
+	}
+
+	config, err := Load(files, scheme)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, err := range Validate(config) {
+		p, ok := locations[err.Field]
+		if !ok {
+			p = Problem{File: strings.Join(files, ", ")}
+		}
+		p.Field, p.Message = err.Field, err.Error()
+		problems = append(problems, p)
+	}
+	return config, problems, nil
+}
+
+// Problem is an unknown field or an invalid value of a config file.
+type Problem struct {
+	// File sets the field last, or lists all the files if the field is not set in any of them.
+	File string `json:"file,omitempty"`
+	// Line of the field in File, zero if unknown.
+	Line int `json:"line,omitempty"`
+	// Field is the path of the field, e.g. `admission.defaultTimeZone`.
+	Field string `json:"field,omitempty"`
+	// Message tells what is wrong.
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	return p.Message
+}
+
+// formatProblems joins the problems into an error, prefixing every run of problems with their file, e.g.
+// `base.yaml: line 3: ...; line 5: ...; cluster.yaml: line 2: ...`.
+func formatProblems(problems []Problem) error {
+	var b strings.Builder
+	for i, p := range problems {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		if p.File != "" && (i == 0 || problems[i-1].File != p.File) {
+			b.WriteString(p.File + ": ")
+		}
+		b.WriteString(p.String())
+	}
+	return errors.New(b.String())
+}
+
+// walkFields records the line of every field of the node, and reports the fields which are not in the type.
+func walkFields(node *yaml.Node, t reflect.Type, fldPath *field.Path, lines map[string]int, problems *[]Problem) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		return
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := jsonFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := field.NewPath(key.Value)
+			if fldPath != nil {
+				childPath = fldPath.Child(key.Value)
+			}
+			f, ok := fields[key.Value]
+			if !ok {
+				*problems = append(*problems, Problem{
+					Line:    key.Line,
+					Field:   childPath.String(),
+					Message: fmt.Sprintf("unknown field %q", childPath.String()),
+				})
+				continue
+			}
+			lines[childPath.String()] = key.Line
+			walkFields(value, f.Type, childPath, lines, problems)
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			walkFields(item, t.Elem(), fldPath.Index(i), lines, problems)
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkFields(node.Content[i+1], t.Elem(), fldPath.Key(node.Content[i].Value), lines, problems)
+		}
+	}
+}
+
+// jsonFields returns the fields of the struct by their names in the file, including the fields of the inlined structs.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, inline := jsonName(f)
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if inline {
+			for n, inlined := range jsonFields(f.Type) {
+				fields[n] = inlined
+			}
+			continue
+		}
+		fields[name] = f
+	}
+	return fields
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	cliflag "k8s.io/component-base/cli/flag"
+)
+
+/*
+The webhook server and the secure metrics endpoint are hardened the same way: TLS 1.2 at least, the cipher suites of
+Go unless some are listed, and HTTP/1.1 only unless HTTP/2 is enabled. The security scanners flag the servers
+accepting the older TLS versions, and the HTTP/2 servers were hit by the stream cancellation and rapid reset
+vulnerabilities.
+*/
+
+// supportedTLSVersions are the minimum TLS versions which can be configured.
+var supportedTLSVersions = []string{"VersionTLS12", "VersionTLS13"}
+
+// ApplyTLSConfig applies the TLS settings of the config file to the TLS config of a server.
+func ApplyTLSConfig(tlsConfig *tls.Config, c configv1.TLSConfig) error {
+	tlsConfig.MinVersion = tls.VersionTLS12
+	if c.MinVersion != "" {
+		version, err := cliflag.TLSVersion(c.MinVersion)
+		if err != nil {
+			return err
+		}
+		if version < tls.VersionTLS12 {
+			return fmt.Errorf("minimum TLS version %s is not supported, use VersionTLS12 or VersionTLS13", c.MinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+	if len(c.CipherSuites) > 0 {
+		suites, err := cliflag.TLSCipherSuites(c.CipherSuites)
+		if err != nil {
+			return err
+		}
+		tlsConfig.CipherSuites = suites
+	}
+	if c.EnableHTTP2 {
+		tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	} else {
+		tlsConfig.NextProtos = []string{"http/1.1"}
+	}
+	return nil
+}
+
+// TLSNextProto returns the TLSNextProto of an http.Server, which keeps the server from serving HTTP/2 when it is
+// disabled.
+func TLSNextProto(c configv1.TLSConfig) map[string]func(*http.Server, *tls.Conn, http.Handler) {
+	if c.EnableHTTP2 {
+		return nil
+	}
+	return map[string]func(*http.Server, *tls.Conn, http.Handler){}
+}
+
+func validateTLS(c configv1.TLSConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if c.MinVersion != "" {
+		supported := false
+		for _, version := range supportedTLSVersions {
+			supported = supported || c.MinVersion == version
+		}
+		if !supported {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("minVersion"), c.MinVersion,
+				supportedTLSVersions))
+		}
+	}
+	for i, suite := range c.CipherSuites {
+		if _, err := cliflag.TLSCipherSuites([]string{suite}); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("cipherSuites").Index(i), suite,
+				"unknown cipher suite, see the IANA names of the Go cipher suites"))
+		}
+	}
+	return allErrs
+}
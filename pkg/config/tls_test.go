@@ -0,0 +1,69 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/tls"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLS settings", func() {
+	It("Should default to TLS 1.2 and HTTP/1.1", func() {
+		tlsConfig := &tls.Config{}
+		Expect(ApplyTLSConfig(tlsConfig, configv1.TLSConfig{})).To(Succeed())
+		Expect(tlsConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+		Expect(tlsConfig.CipherSuites).To(BeNil())
+		Expect(tlsConfig.NextProtos).To(Equal([]string{"http/1.1"}))
+		Expect(TLSNextProto(configv1.TLSConfig{})).To(BeEmpty())
+		Expect(TLSNextProto(configv1.TLSConfig{})).NotTo(BeNil())
+	})
+
+	It("Should apply the TLS settings of the config file", func() {
+		c := configv1.TLSConfig{
+			MinVersion:   "VersionTLS13",
+			CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+			EnableHTTP2:  true,
+		}
+		tlsConfig := &tls.Config{}
+		Expect(ApplyTLSConfig(tlsConfig, c)).To(Succeed())
+		Expect(tlsConfig.MinVersion).To(Equal(uint16(tls.VersionTLS13)))
+		Expect(tlsConfig.CipherSuites).To(Equal([]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}))
+		Expect(tlsConfig.NextProtos).To(Equal([]string{"h2", "http/1.1"}))
+		Expect(TLSNextProto(c)).To(BeNil())
+	})
+
+	It("Should refuse the versions older than TLS 1.2", func() {
+		Expect(ApplyTLSConfig(&tls.Config{}, configv1.TLSConfig{MinVersion: "VersionTLS11"})).NotTo(Succeed())
+	})
+
+	It("Should reject the invalid TLS settings", func() {
+		config := &configv1.ProjectConfig{}
+		config.WebhookServer.TLS = configv1.TLSConfig{MinVersion: "VersionTLS12"}
+		config.SecureMetrics.TLS = configv1.TLSConfig{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}}
+		Expect(Validate(config)).To(BeEmpty())
+
+		config.WebhookServer.TLS.MinVersion = "VersionTLS10"
+		config.SecureMetrics.TLS.CipherSuites = append(config.SecureMetrics.TLS.CipherSuites, "TLS_RSA_WITH_RC4")
+		errs := Validate(config)
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Field).To(Equal("webhookServer.tls.minVersion"))
+		Expect(errs[1].Field).To(Equal("secureMetrics.tls.cipherSuites[1]"))
+	})
+})
@@ -0,0 +1,456 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/archive"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/cloudevents"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	componentconfigv1alpha1 "k8s.io/component-base/config/v1alpha1"
+)
+
+// Validate validates the values of the settings of our components.
+func Validate(config *configv1.ProjectConfig) field.ErrorList {
+	var allErrs field.ErrorList
+
+	allErrs = append(allErrs, validateLogging(config.Logging, field.NewPath("logging"))...)
+	if config.Audit.File != nil {
+		allErrs = append(allErrs, validateLogFile(config.Audit.File, field.NewPath("audit", "file"))...)
+	}
+
+	for i, entry := range config.Controllers {
+		if !isKnownControllerEntry(entry) {
+			allErrs = append(allErrs, field.NotSupported(field.NewPath("controllers").Index(i), entry,
+				append([]string{"*"}, KnownControllers()...)))
+		}
+	}
+
+	allErrs = append(allErrs, validateLeaderElection(config.LeaderElection, field.NewPath("leaderElection"))...)
+
+	if len(config.FeatureGates) > 0 {
+		// the gates are checked against a copy, they are only set once the whole config is valid
+		if err := featuregates.Gates.DeepCopy().SetFromMap(config.FeatureGates); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("featureGates"), config.FeatureGates, err.Error()))
+		}
+	}
+
+	clientPath := field.NewPath("client")
+	if config.Client.QPS < 0 {
+		allErrs = append(allErrs, field.Invalid(clientPath.Child("qps"), config.Client.QPS, "must not be negative"))
+	}
+	if config.Client.Burst < 0 {
+		allErrs = append(allErrs, field.Invalid(clientPath.Child("burst"), config.Client.Burst, "must not be negative"))
+	}
+	allErrs = append(allErrs, validatePositiveDuration(config.Client.Timeout, clientPath.Child("timeout"))...)
+	allErrs = append(allErrs, validatePositiveDuration(config.OperatorStatus.Interval,
+		field.NewPath("operatorStatus", "interval"))...)
+
+	controllerPath := field.NewPath("cronJobController")
+	if config.CronJobController.MaxConcurrentReconciles < 0 {
+		allErrs = append(allErrs, field.Invalid(controllerPath.Child("maxConcurrentReconciles"),
+			config.CronJobController.MaxConcurrentReconciles, "must not be negative"))
+	}
+	rateLimit := config.CronJobController.RateLimit
+	rateLimitPath := controllerPath.Child("rateLimit")
+	allErrs = append(allErrs, validatePositiveDuration(rateLimit.BaseDelay, rateLimitPath.Child("baseDelay"))...)
+	allErrs = append(allErrs, validatePositiveDuration(rateLimit.MaxDelay, rateLimitPath.Child("maxDelay"))...)
+	if rateLimit.BaseDelay != nil && rateLimit.MaxDelay != nil && rateLimit.BaseDelay.Duration > rateLimit.MaxDelay.Duration {
+		allErrs = append(allErrs, field.Invalid(rateLimitPath.Child("baseDelay"), rateLimit.BaseDelay.Duration.String(),
+			"must not be longer than maxDelay"))
+	}
+	if rateLimit.QPS < 0 {
+		allErrs = append(allErrs, field.Invalid(rateLimitPath.Child("qps"), rateLimit.QPS, "must not be negative"))
+	}
+	if rateLimit.Burst < 0 {
+		allErrs = append(allErrs, field.Invalid(rateLimitPath.Child("burst"), rateLimit.Burst, "must not be negative"))
+	}
+	allErrs = append(allErrs, validatePositiveDuration(config.CronJobController.RequeueJitter,
+		controllerPath.Child("requeueJitter"))...)
+	allErrs = append(allErrs, validatePositiveDuration(config.CronJobController.JobRunTTL,
+		controllerPath.Child("jobRunTTL"))...)
+	allErrs = append(allErrs, validatePositiveDuration(config.CronJobController.StatusDebounce,
+		controllerPath.Child("statusDebounce"))...)
+	switch policy := config.CronJobController.DeletePropagationPolicy; policy {
+	case "", metav1.DeletePropagationBackground, metav1.DeletePropagationForeground, metav1.DeletePropagationOrphan:
+	default:
+		allErrs = append(allErrs, field.NotSupported(controllerPath.Child("deletePropagationPolicy"), policy,
+			[]string{string(metav1.DeletePropagationBackground), string(metav1.DeletePropagationForeground),
+				string(metav1.DeletePropagationOrphan)}))
+	}
+
+	if ratio := config.Tracing.SamplingRatio; ratio != nil && (*ratio < 0 || *ratio > 1) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("tracing", "samplingRatio"), *ratio,
+			"must be between 0 and 1"))
+	}
+
+	allErrs = append(allErrs, validateErrorReporting(config.ErrorReporting, field.NewPath("errorReporting"))...)
+
+	if port := config.Webhook.Port; port != nil && (*port < 1 || *port > 65535) {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("webhook", "port"), *port, "must be between 1 and 65535"))
+	}
+	webhookServerPath := field.NewPath("webhookServer")
+	allErrs = append(allErrs, validateFileName(config.WebhookServer.CertName, webhookServerPath.Child("certName"))...)
+	allErrs = append(allErrs, validateFileName(config.WebhookServer.KeyName, webhookServerPath.Child("keyName"))...)
+	allErrs = append(allErrs, validateTLS(config.WebhookServer.TLS, webhookServerPath.Child("tls"))...)
+	allErrs = append(allErrs, validateTLS(config.SecureMetrics.TLS, field.NewPath("secureMetrics", "tls"))...)
+	allErrs = append(allErrs, validateTLS(config.API.TLS, field.NewPath("api", "tls"))...)
+	allErrs = append(allErrs, validateTLS(config.GRPC.TLS, field.NewPath("grpc", "tls"))...)
+	if config.GRPC.Enabled && config.GRPC.ClientCAFile == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("grpc", "clientCAFile"),
+			"the clients are authenticated with their certificates"))
+	}
+	allErrs = append(allErrs, validateTLS(config.HTTPTrigger.TLS, field.NewPath("httpTrigger", "tls"))...)
+	allErrs = append(allErrs, validateSecretRef(config.HTTPTrigger.CallersSecret,
+		field.NewPath("httpTrigger", "callersSecret"))...)
+	if config.HTTPTrigger.Enabled && config.HTTPTrigger.CallersSecret == "" &&
+		config.HTTPTrigger.Slack.SigningSecret == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("httpTrigger", "callersSecret"),
+			"the callers are authenticated with their secrets"))
+	}
+	slackPath := field.NewPath("httpTrigger", "slack")
+	allErrs = append(allErrs, validateSecretRef(config.HTTPTrigger.Slack.SigningSecret,
+		slackPath.Child("signingSecret"))...)
+	allErrs = append(allErrs, validateSecretRef(config.HTTPTrigger.Slack.UsersConfigMap,
+		slackPath.Child("usersConfigMap"))...)
+	if config.HTTPTrigger.Slack.SigningSecret != "" && config.HTTPTrigger.Slack.UsersConfigMap == "" {
+		allErrs = append(allErrs, field.Required(slackPath.Child("usersConfigMap"),
+			"the Slack users are only allowed the namespaces it maps them to"))
+	}
+	allErrs = append(allErrs, validateListenAddresses(config)...)
+
+	allErrs = append(allErrs, validateAdmission(config.Admission, field.NewPath("admission"))...)
+	allErrs = append(allErrs, validateArchive(config.Archive, field.NewPath("archive"))...)
+	pushgatewayPath := field.NewPath("pushgateway")
+	allErrs = append(allErrs, validateHTTPURL(config.Pushgateway.URL, pushgatewayPath.Child("url"))...)
+	allErrs = append(allErrs, validateSecretRef(config.Pushgateway.CredentialsSecret,
+		pushgatewayPath.Child("credentialsSecret"))...)
+	allErrs = append(allErrs, validateCloudEvents(config.CloudEvents, field.NewPath("cloudEvents"))...)
+
+	// the rules spanning several components
+	if c := config.Client; c.QPS > 0 && c.Burst > 0 && float32(c.Burst) < c.QPS {
+		allErrs = append(allErrs, field.Invalid(clientPath.Child("burst"), c.Burst, "must not be lower than client.qps"))
+	}
+	jitter, minDeadline := config.CronJobController.RequeueJitter, config.Admission.MinStartingDeadline
+	if jitter != nil && minDeadline != nil && minDeadline.Duration > 0 && jitter.Duration >= minDeadline.Duration {
+		allErrs = append(allErrs, field.Invalid(controllerPath.Child("requeueJitter"), jitter.Duration.String(),
+			"must be shorter than admission.minStartingDeadline, the Jobs would miss their starting deadlines"))
+	}
+	return allErrs
+}
+
+// leaderElectionResourceLocks are the kinds of leader election locks supported by client-go.
+var leaderElectionResourceLocks = []string{
+	resourcelock.LeasesResourceLock, resourcelock.ConfigMapsLeasesResourceLock, resourcelock.EndpointsLeasesResourceLock,
+	resourcelock.ConfigMapsResourceLock, resourcelock.EndpointsResourceLock,
+}
+
+func validateLeaderElection(leaderElection *componentconfigv1alpha1.LeaderElectionConfiguration,
+	fldPath *field.Path) field.ErrorList {
+	if leaderElection == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if lock := leaderElection.ResourceLock; lock != "" {
+		supported := false
+		for _, known := range leaderElectionResourceLocks {
+			supported = supported || lock == known
+		}
+		if !supported {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("resourceLock"), lock, leaderElectionResourceLocks))
+		}
+	}
+	if leaderElection.LeaderElect != nil && *leaderElection.LeaderElect && leaderElection.ResourceName == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("resourceName"), "is required with leader election"))
+	}
+	if namespace := leaderElection.ResourceNamespace; namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("resourceNamespace"), namespace, msg))
+		}
+	}
+	if name := leaderElection.ResourceName; name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("resourceName"), name, msg))
+		}
+	}
+	return allErrs
+}
+
+func validateErrorReporting(errorReporting configv1.ErrorReportingConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if dsn := errorReporting.SentryDSN; dsn != "" {
+		u, err := url.Parse(dsn)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User == nil || u.User.Username() == "" ||
+			strings.Trim(u.Path, "/") == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("sentryDSN"), dsn,
+				"must be like https://<public key>@<host>/<project ID>"))
+		}
+		if errorReporting.GoogleCloudProject != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("googleCloudProject"),
+				"only one of sentryDSN and googleCloudProject can be set"))
+		}
+	}
+	if errorReporting.ReconcileErrorThreshold < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("reconcileErrorThreshold"),
+			errorReporting.ReconcileErrorThreshold, "must not be negative"))
+	}
+	return allErrs
+}
+
+// validateFileName validates that the name is a file name without a directory.
+func validateFileName(name string, fldPath *field.Path) field.ErrorList {
+	if name != "" && (strings.Contains(name, "/") || name == "." || name == "..") {
+		return field.ErrorList{field.Invalid(fldPath, name, "must be a file name without a directory")}
+	}
+	return nil
+}
+
+func validateLogging(logging configv1.LoggingConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if logging.Level != "" {
+		if _, err := ParseLogLevel(logging.Level); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("level"), logging.Level,
+				"must be debug, info, error or a positive integer"))
+		}
+	}
+	switch logging.Encoder {
+	case "", "json", "console":
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("encoder"), logging.Encoder, []string{"json", "console"}))
+	}
+	if logging.StacktraceLevel != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(logging.StacktraceLevel)); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("stacktraceLevel"), logging.StacktraceLevel,
+				"must be debug, info, warn, error, dpanic, panic or fatal"))
+		}
+	}
+	if logging.Backend != "" && !containsString(logBackends, logging.Backend) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("backend"), logging.Backend, logBackends))
+	}
+	if logging.TimeEncoding != "" && !containsString(timeEncodings, logging.TimeEncoding) {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("timeEncoding"), logging.TimeEncoding, timeEncodings))
+	}
+	allErrs = append(allErrs, validateLogSampling(logging.Sampling, fldPath.Child("sampling"))...)
+	allErrs = append(allErrs, validateLogSampling(logging.DebugSampling, fldPath.Child("debugSampling"))...)
+	for i, object := range logging.DebugObjects {
+		if _, err := parseDebugObjects([]string{object}); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("debugObjects").Index(i), object,
+				"must be namespace/name, /name or namespace"))
+		}
+	}
+	if logging.DebugObjectsLevel != "" {
+		if _, err := ParseLogLevel(logging.DebugObjectsLevel); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("debugObjectsLevel"), logging.DebugObjectsLevel,
+				"must be debug, info, error or a positive integer"))
+		}
+	}
+	if logging.File != nil {
+		allErrs = append(allErrs, validateLogFile(logging.File, fldPath.Child("file"))...)
+	}
+	return allErrs
+}
+
+func validateLogSampling(sampling *configv1.LogSamplingConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if sampling == nil {
+		return nil
+	}
+	if sampling.Initial < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("initial"), sampling.Initial, "must not be negative"))
+	}
+	if sampling.Initial > 0 && sampling.Thereafter <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("thereafter"), sampling.Thereafter, "must be positive"))
+	}
+	return allErrs
+}
+
+func validateLogFile(file *configv1.LogFileConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if file.Path == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("path"), ""))
+	}
+	if file.MaxSizeMB < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxSizeMB"), file.MaxSizeMB, "must not be negative"))
+	}
+	if file.MaxAgeDays < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxAgeDays"), file.MaxAgeDays, "must not be negative"))
+	}
+	if file.MaxBackups < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxBackups"), file.MaxBackups, "must not be negative"))
+	}
+	return allErrs
+}
+
+func validateAdmission(admission configv1.AdmissionConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	allErrs = append(allErrs, validatePositiveDuration(admission.ActivationHorizon, fldPath.Child("activationHorizon"))...)
+	if admission.MinStartingDeadline != nil && admission.MinStartingDeadline.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minStartingDeadline"),
+			admission.MinStartingDeadline.Duration.String(), "must not be negative"))
+	}
+	allErrs = append(allErrs, validateDefaults(admission.Defaults, fldPath.Child("defaults"))...)
+	if admission.DefaultTimeZone != "" {
+		if _, err := time.LoadLocation(admission.DefaultTimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("defaultTimeZone"), admission.DefaultTimeZone,
+				"unknown time zone"))
+		}
+	}
+	switch admission.NativeCronJobCollision {
+	case "", configv1.CollisionWarn, configv1.CollisionReject, configv1.CollisionIgnore:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("nativeCronJobCollision"),
+			admission.NativeCronJobCollision, []string{string(configv1.CollisionWarn), string(configv1.CollisionReject),
+				string(configv1.CollisionIgnore)}))
+	}
+	if admission.MaxCronJobsPerNamespace < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxCronJobsPerNamespace"),
+			admission.MaxCronJobsPerNamespace, "must not be negative"))
+	}
+
+	paths := map[string]bool{}
+	for i, extra := range admission.ExtraHandlers {
+		extraPath := fldPath.Child("extraHandlers").Index(i)
+		if extra.Name == "" {
+			allErrs = append(allErrs, field.Required(extraPath.Child("name"), ""))
+		}
+		if !strings.HasPrefix(extra.Path, "/") {
+			allErrs = append(allErrs, field.Invalid(extraPath.Child("path"), extra.Path, "must start with /"))
+		} else if paths[extra.Path] {
+			allErrs = append(allErrs, field.Duplicate(extraPath.Child("path"), extra.Path))
+		}
+		paths[extra.Path] = true
+	}
+	return allErrs
+}
+
+func validateArchive(config configv1.ArchiveConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch config.Provider {
+	case "":
+	case archive.ProviderS3, archive.ProviderGCS, archive.ProviderAzureBlob:
+		if config.Bucket == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("bucket"), "is required with a provider"))
+		}
+		if config.Provider == archive.ProviderAzureBlob && config.Endpoint == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("endpoint"),
+				"the URL of the storage account is required for AzureBlob"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("provider"), config.Provider,
+			[]string{archive.ProviderS3, archive.ProviderGCS, archive.ProviderAzureBlob}))
+	}
+	allErrs = append(allErrs, validateHTTPURL(config.Endpoint, fldPath.Child("endpoint"))...)
+	allErrs = append(allErrs, validateSecretRef(config.CredentialsSecret, fldPath.Child("credentialsSecret"))...)
+	allErrs = append(allErrs, validatePositiveDuration(config.Retention, fldPath.Child("retention"))...)
+	if config.LogTailLines < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("logTailLines"), config.LogTailLines,
+			"must not be negative"))
+	}
+	return allErrs
+}
+
+func validateCloudEvents(config configv1.CloudEventsConfig, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	allErrs = append(allErrs, validateHTTPURL(config.Sink, fldPath.Child("sink"))...)
+	switch config.Protocol {
+	case "", cloudevents.ProtocolHTTP:
+	case cloudevents.ProtocolKafka:
+		if config.Topic == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("topic"), "is required with the Kafka protocol"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("protocol"), config.Protocol,
+			[]string{cloudevents.ProtocolHTTP, cloudevents.ProtocolKafka}))
+	}
+	for i, t := range config.Types {
+		if !containsString(cloudevents.Types, t) {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("types").Index(i), t, cloudevents.Types))
+		}
+	}
+	allErrs = append(allErrs, validateSecretRef(config.CredentialsSecret, fldPath.Child("credentialsSecret"))...)
+	return allErrs
+}
+
+// validateHTTPURL validates an optional http(s) URL.
+func validateHTTPURL(value string, fldPath *field.Path) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+	if u, err := url.Parse(value); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return field.ErrorList{field.Invalid(fldPath, value, "must be an http(s) URL")}
+	}
+	return nil
+}
+
+// validateSecretRef validates an optional namespace/name reference of a Secret.
+func validateSecretRef(ref string, fldPath *field.Path) field.ErrorList {
+	if ref == "" {
+		return nil
+	}
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || len(validation.IsDNS1123Label(parts[0])) > 0 ||
+		len(validation.IsDNS1123Subdomain(parts[1])) > 0 {
+		return field.ErrorList{field.Invalid(fldPath, ref, "must be like <namespace>/<name>")}
+	}
+	return nil
+}
+
+func validateDefaults(defaults configv1.CronJobDefaults, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch batchv1.ConcurrencyPolicy(defaults.ConcurrencyPolicy) {
+	case "", batchv1.AllowConcurrent, batchv1.ForbidConcurrent, batchv1.ReplaceConcurrent:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("concurrencyPolicy"), defaults.ConcurrencyPolicy,
+			[]string{string(batchv1.AllowConcurrent), string(batchv1.ForbidConcurrent),
+				string(batchv1.ReplaceConcurrent)}))
+	}
+	if limit := defaults.SuccessfulJobsHistoryLimit; limit != nil && *limit < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("successfulJobsHistoryLimit"), *limit,
+			"must not be negative"))
+	}
+	if limit := defaults.FailedJobsHistoryLimit; limit != nil && *limit < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("failedJobsHistoryLimit"), *limit,
+			"must not be negative"))
+	}
+	return allErrs
+}
+
+func validatePositiveDuration(d *metav1.Duration, fldPath *field.Path) field.ErrorList {
+	if d != nil && d.Duration <= 0 {
+		return field.ErrorList{field.Invalid(fldPath, d.Duration.String(), "must be positive")}
+	}
+	return nil
+}
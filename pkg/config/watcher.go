@@ -0,0 +1,196 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+/*
+The config file is usually mounted from a ConfigMap, which the kubelet updates by swapping a symlink in the directory
+of the file. So the Watcher watches the directory instead of the file, and reloads the file shortly after the last
+event. The reloaded file is validated as a whole, an invalid file applies none of its settings. The settings which can
+change on the fly are then applied by the Reloaders, every other change is only reported by the
+`cronjob_operator_config_restart_required` metric and in the logs, until the manager is restarted.
+*/
+
+var log = logf.Log.WithName("config-watcher")
+
+// debounceDelay is how long the Watcher waits for the file to settle before reloading it.
+const debounceDelay = time.Second
+
+var (
+	configReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_operator_config_reloads_total",
+		Help: "Total number of reloads of the config file, per result.",
+	}, []string{"result"})
+
+	configRestartRequired = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cronjob_operator_config_restart_required",
+		Help: "Whether the config file has changes which are only applied on restart.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(configReloads, configRestartRequired)
+}
+
+// Reloader applies the reloadable settings of the changed config.
+type Reloader func(config *configv1.ProjectConfig) error
+
+// Watcher reloads the config file when it changes.
+type Watcher struct {
+	// Paths of the config files or of the directories holding them, merged in order like Load does.
+	Paths []string
+	// Scheme knows the ProjectConfig kind.
+	Scheme *runtime.Scheme
+	// Strict loads the config file with LoadStrict.
+	Strict bool
+	// Overrides are applied on the reloaded config, like on the initial one.
+	Overrides func(config *configv1.ProjectConfig) error
+	// Initial is the config the manager was started with.
+	Initial *configv1.ProjectConfig
+	// Reloaders are called in order with every changed config.
+	Reloaders []Reloader
+
+	current *configv1.ProjectConfig
+}
+
+var _ manager.Runnable = &Watcher{}
+
+// Start implements manager.Runnable, it watches the config file until the context is done.
+func (w *Watcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for _, dir := range watchedDirs(w.Paths) {
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+	w.current = w.Initial
+	log.Info("watching the config files", "paths", w.Paths)
+
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-watcher.Events:
+			reload = time.After(debounceDelay)
+		case err := <-watcher.Errors:
+			log.Error(err, "error watching the config file")
+		case <-reload:
+			reload = nil
+			w.reload()
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica reloads its own settings.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+func (w *Watcher) reload() {
+	load := Load
+	if w.Strict {
+		load = LoadStrict
+	}
+	config, err := load(w.Paths, w.Scheme)
+	if err == nil && w.Overrides != nil {
+		err = w.Overrides(config)
+	}
+	if err == nil {
+		// the whole file is validated first, a Reloader failing on an invalid setting would leave the settings
+		// applied by the Reloaders before it
+		err = Validate(config).ToAggregate()
+	}
+	if err != nil {
+		configReloads.WithLabelValues("failure").Inc()
+		log.Error(err, "unable to reload the config file, keeping the current settings")
+		return
+	}
+	if reflect.DeepEqual(config, w.current) {
+		return
+	}
+
+	for _, reloader := range w.Reloaders {
+		if err := reloader(config); err != nil {
+			configReloads.WithLabelValues("failure").Inc()
+			log.Error(err, "unable to apply the reloaded config file")
+			return
+		}
+	}
+	w.current = config
+	configReloads.WithLabelValues("success").Inc()
+
+	if reflect.DeepEqual(withoutReloadable(config), withoutReloadable(w.Initial)) {
+		configRestartRequired.Set(0)
+		log.Info("reloaded the config file")
+	} else {
+		configRestartRequired.Set(1)
+		log.Info("reloaded the config file, some of the changes require a restart of the manager")
+	}
+}
+
+// watchedDirs returns the directories to watch for the changes of the config files: the directories of the files and
+// the directories given as they are, since files can be added to them.
+func watchedDirs(paths []string) []string {
+	var dirs []string
+	seen := map[string]bool{}
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dir = path
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// withoutReloadable returns a copy of the config without the settings which are reloaded on the fly.
+func withoutReloadable(config *configv1.ProjectConfig) *configv1.ProjectConfig {
+	config = config.DeepCopy()
+	config.Logging.Level = ""
+	config.CronJobController.RateLimit = configv1.RateLimitConfig{}
+	admission := config.Admission
+	config.Admission = configv1.AdmissionConfig{
+		MutatingWebhook:   admission.MutatingWebhook,
+		ValidatingWebhook: admission.ValidatingWebhook,
+		ExtraHandlers:     admission.ExtraHandlers,
+	}
+	return config
+}
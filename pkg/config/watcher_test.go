@@ -0,0 +1,118 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+var _ = Describe("Config watcher", func() {
+	var (
+		dir     string
+		path    string
+		watcher *Watcher
+
+		lock     sync.Mutex
+		reloaded []*configv1.ProjectConfig
+	)
+
+	write := func(content string) {
+		Expect(ioutil.WriteFile(path, []byte("apiVersion: config.example.com/v1\nkind: ProjectConfig\n"+content),
+			0600)).To(Succeed())
+	}
+	reloads := func() []*configv1.ProjectConfig {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]*configv1.ProjectConfig(nil), reloaded...)
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "config")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "config.yaml")
+		write("logging:\n  level: info\n")
+		reloaded = nil
+
+		scheme := runtime.NewScheme()
+		utilruntime.Must(configv1.AddToScheme(scheme))
+		initial, err := Load([]string{path}, scheme)
+		Expect(err).NotTo(HaveOccurred())
+		record := func(config *configv1.ProjectConfig) error {
+			lock.Lock()
+			defer lock.Unlock()
+			reloaded = append(reloaded, config)
+			return nil
+		}
+		watcher = &Watcher{
+			Paths:     []string{path},
+			Scheme:    scheme,
+			Initial:   initial,
+			Reloaders: []Reloader{record, record},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("Should apply the config file when it is written", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(stopped)
+			Expect(watcher.Start(ctx)).To(Succeed())
+		}()
+		defer func() {
+			cancel()
+			Eventually(stopped).Should(BeClosed())
+		}()
+
+		// the file is written again until the Watcher watches its directory, slower than the debounce delay
+		Eventually(func() []*configv1.ProjectConfig {
+			write("logging:\n  level: debug\n")
+			return reloads()
+		}, 10*time.Second, 2*debounceDelay).Should(HaveLen(2))
+		Expect(reloads()[0].Logging.Level).To(Equal("debug"))
+	})
+
+	It("Should apply nothing of an invalid config file", func() {
+		watcher.current = watcher.Initial
+		// the Reloaders would accept the level, the invalid rate limit makes the whole file invalid
+		write("logging:\n  level: debug\ncronJobController:\n  rateLimit:\n    baseDelay: -1s\n")
+		watcher.reload()
+		Expect(reloads()).To(BeEmpty())
+		Expect(watcher.current).To(BeIdenticalTo(watcher.Initial))
+
+		write("logging:\n  level: debug\n")
+		watcher.reload()
+		Expect(reloads()).To(HaveLen(2))
+		Expect(watcher.current.Logging.Level).To(Equal("debug"))
+	})
+})
@@ -0,0 +1,525 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cronctl implements the commands of cronctl, the CLI of the platform admins for the operations on the
+// CronJobs of whole clusters: finding the CronJobs which stopped succeeding, setting the time zones in bulk, exporting
+// and importing the CronJobs, and comparing the defaults they got with the current ones.
+package cronctl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/webhooks"
+)
+
+/*
+Unlike the kubectl-cronjob plugin, which works on one CronJob, every command of cronctl works on all the CronJobs of
+a namespace, `-A` of the cluster, optionally narrowed with a label selector. The commands which write have a
+`--dry-run` flag printing what would change.
+*/
+
+// Usage is the help of cronctl.
+const Usage = `Usage: cronctl [--context a,b] <command> [-n namespace | -A] [-l selector] [flags]
+
+Commands:
+  stale [--days 7]                        list the CronJobs which have not succeeded for days
+  set-timezone <zone> [--only-unset]      set the time zone of the CronJobs, [--dry-run]
+  export                                  print the CronJobs as YAML documents
+  import -f <file>                        create or update the CronJobs of the YAML documents, [--dry-run]
+  diff <cronjob> [--config file]          compare the defaults the CronJob got with the current ones
+`
+
+// ErrUsage is returned for invalid command lines, the caller prints Usage.
+var ErrUsage = errors.New("invalid command line")
+
+// Options are the client and the settings of the commands.
+type Options struct {
+	// Client reads and writes the CronJobs, and reads their Jobs and JobRuns.
+	Client client.Client
+	// Namespace is the namespace of the commands without -n.
+	Namespace string
+	// Out receives the output of the commands.
+	Out io.Writer
+	// In is read by `import -f -`.
+	In io.Reader
+	// LoadConfig loads the config files of `diff --config`.
+	LoadConfig func(paths []string) (*configv1.ProjectConfig, error)
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+}
+
+// flags are the flags of all the commands, each command uses some of them.
+type flags struct {
+	namespace     string
+	namespaceSet  bool
+	allNamespaces bool
+	selector      string
+	days          int
+	onlyUnset     bool
+	dryRun        bool
+	file          string
+	config        stringList
+}
+
+// stringList is a flag which can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// Run runs the command of the arguments, e.g. `stale -A --days 3`.
+func Run(ctx context.Context, o *Options, args []string) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	f := flags{namespace: o.Namespace}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&f.namespace, "n", f.namespace, "the namespace")
+	fs.BoolVar(&f.allNamespaces, "A", false, "all the namespaces")
+	fs.StringVar(&f.selector, "l", "", "the label selector of the CronJobs")
+	fs.IntVar(&f.days, "days", 7, "the days without a successful run")
+	fs.BoolVar(&f.onlyUnset, "only-unset", false, "only set the time zone of the CronJobs without one")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "print the changes without applying them")
+	fs.StringVar(&f.file, "f", "", "the file to import, - for the standard input")
+	fs.Var(&f.config, "config", "a config file of the manager, can be repeated")
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return ErrUsage
+	}
+	fs.Visit(func(fl *flag.Flag) { f.namespaceSet = f.namespaceSet || fl.Name == "n" })
+
+	switch {
+	case args[0] == "stale" && len(positional) == 0:
+		return stale(ctx, o, f)
+	case args[0] == "set-timezone" && len(positional) == 1:
+		return setTimeZone(ctx, o, f, positional[0])
+	case args[0] == "export" && len(positional) == 0:
+		return export(ctx, o, f)
+	case args[0] == "import" && len(positional) == 0 && f.file != "":
+		return importCronJobs(ctx, o, f)
+	case args[0] == "diff" && len(positional) == 1:
+		return diff(ctx, o, f, positional[0])
+	}
+	return ErrUsage
+}
+
+// parseInterspersed parses the flags wherever they are and returns the other arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional, args = append(positional, fs.Arg(0)), fs.Args()[1:]
+	}
+}
+
+// listOptions returns the options listing the objects of the namespaces of the command.
+func (f flags) listOptions() ([]client.ListOption, error) {
+	var opts []client.ListOption
+	if !f.allNamespaces {
+		opts = append(opts, client.InNamespace(f.namespace))
+	}
+	if f.selector != "" {
+		selector, err := labels.Parse(f.selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", f.selector, err)
+		}
+		opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
+	}
+	return opts, nil
+}
+
+func (f flags) listCronJobs(ctx context.Context, c client.Reader) ([]batchv1.CronJob, error) {
+	opts, err := f.listOptions()
+	if err != nil {
+		return nil, err
+	}
+	var cronJobs batchv1.CronJobList
+	if err := c.List(ctx, &cronJobs, opts...); err != nil {
+		return nil, err
+	}
+	sort.Slice(cronJobs.Items, func(i, j int) bool {
+		a, b := cronJobs.Items[i], cronJobs.Items[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Name < b.Name)
+	})
+	return cronJobs.Items, nil
+}
+
+/*
+The last success of a CronJob is read from its JobRuns, and from its Jobs for the clusters where the jobrun controller
+is disabled. The CronJobs younger than the period are not listed, they had no chance to succeed yet.
+*/
+
+// stale lists the CronJobs without a successful run for f.days days.
+func stale(ctx context.Context, o *Options, f flags) error {
+	cronJobs, err := f.listCronJobs(ctx, o.Client)
+	if err != nil {
+		return err
+	}
+	lastSuccess, err := lastSuccesses(ctx, o.Client, f)
+	if err != nil {
+		return err
+	}
+
+	since := o.Now().Add(-time.Duration(f.days) * 24 * time.Hour)
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tSUSPENDED\tLAST SUCCESS")
+	for _, cronJob := range cronJobs {
+		if !cronJob.CreationTimestamp.Time.Before(since) {
+			continue
+		}
+		last, ok := lastSuccess[types.NamespacedName{Namespace: cronJob.Namespace, Name: cronJob.Name}]
+		if ok && !last.Before(since) {
+			continue
+		}
+		shown := "never"
+		if ok {
+			shown = last.UTC().Format(time.RFC3339)
+		}
+		suspended := cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", cronJob.Namespace, cronJob.Name, suspended, shown)
+	}
+	return w.Flush()
+}
+
+// lastSuccesses returns the completion times of the last successful runs, by CronJob.
+func lastSuccesses(ctx context.Context, c client.Reader, f flags) (map[types.NamespacedName]time.Time, error) {
+	var opts []client.ListOption
+	if !f.allNamespaces {
+		opts = append(opts, client.InNamespace(f.namespace))
+	}
+	last := map[types.NamespacedName]time.Time{}
+	record := func(namespace, cronJob string, completed *metav1.Time) {
+		key := types.NamespacedName{Namespace: namespace, Name: cronJob}
+		if completed != nil && completed.Time.After(last[key]) {
+			last[key] = completed.Time
+		}
+	}
+
+	var jobRuns batchv1.JobRunList
+	if err := c.List(ctx, &jobRuns, opts...); err != nil {
+		return nil, err
+	}
+	for _, run := range jobRuns.Items {
+		if run.Status.Phase == batchv1.JobRunSucceeded {
+			record(run.Namespace, run.Spec.CronJob, run.Status.CompletionTime)
+		}
+	}
+
+	var jobs kbatch.JobList
+	if err := c.List(ctx, &jobs, opts...); err != nil {
+		return nil, err
+	}
+	for _, job := range jobs.Items {
+		owner := metav1.GetControllerOf(&job)
+		if owner == nil || owner.Kind != "CronJob" || owner.APIVersion != batchv1.GroupVersion.String() {
+			continue
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Type == kbatch.JobComplete && condition.Status == "True" {
+				record(job.Namespace, owner.Name, job.Status.CompletionTime)
+			}
+		}
+	}
+	return last, nil
+}
+
+// setTimeZone sets the time zone of the CronJobs. The CronJobs run in the time zone once the CronJobTimeZone feature
+// gate of the manager is enabled.
+func setTimeZone(ctx context.Context, o *Options, f flags, timeZone string) error {
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return fmt.Errorf("unknown time zone %q: %w", timeZone, err)
+	}
+	cronJobs, err := f.listCronJobs(ctx, o.Client)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for i := range cronJobs {
+		cronJob := &cronJobs[i]
+		current := cronJob.Spec.TimeZone
+		if current != nil && (*current == timeZone || f.onlyUnset) {
+			continue
+		}
+		if !f.dryRun {
+			patch := client.MergeFrom(cronJob.DeepCopy())
+			cronJob.Spec.TimeZone = &timeZone
+			if err := o.Client.Patch(ctx, cronJob, patch); err != nil {
+				// the other CronJobs are still updated, the webhook may reject only some of them
+				fmt.Fprintf(o.Out, "%s/%s: %v\n", cronJob.Namespace, cronJob.Name, err)
+				failed++
+				continue
+			}
+		}
+		fmt.Fprintf(o.Out, "cronjob.batch.example.com/%s in %s: time zone set to %s%s\n", cronJob.Name,
+			cronJob.Namespace, timeZone, dryRunSuffix(f.dryRun))
+	}
+	if failed > 0 {
+		return fmt.Errorf("unable to set the time zone of %d CronJobs", failed)
+	}
+	return nil
+}
+
+func dryRunSuffix(dryRun bool) string {
+	if dryRun {
+		return " (dry run)"
+	}
+	return ""
+}
+
+// ignoredAnnotations are not exported, they belong to the cluster the CronJobs come from.
+var ignoredAnnotations = sets.NewString(
+	"kubectl.kubernetes.io/last-applied-configuration",
+	webhooks.DefaultedFieldsAnnotation,
+)
+
+// export prints the CronJobs as YAML documents, without their status and their cluster specific metadata, so they
+// can be imported into another cluster.
+func export(ctx context.Context, o *Options, f flags) error {
+	cronJobs, err := f.listCronJobs(ctx, o.Client)
+	if err != nil {
+		return err
+	}
+	for _, cronJob := range cronJobs {
+		exported := batchv1.CronJob{
+			TypeMeta: metav1.TypeMeta{APIVersion: batchv1.GroupVersion.String(), Kind: "CronJob"},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cronJob.Namespace,
+				Name:      cronJob.Name,
+				Labels:    cronJob.Labels,
+			},
+			Spec: cronJob.Spec,
+		}
+		for key, value := range cronJob.Annotations {
+			if !ignoredAnnotations.Has(key) {
+				metav1.SetMetaDataAnnotation(&exported.ObjectMeta, key, value)
+			}
+		}
+		data, err := yaml.Marshal(&exported)
+		if err != nil {
+			return err
+		}
+		// the status is always marshaled, even if empty
+		data = bytes.Replace(data, []byte("status: {}\n"), nil, 1)
+		fmt.Fprintf(o.Out, "---\n%s", data)
+	}
+	return nil
+}
+
+// importCronJobs creates the CronJobs of the YAML documents, or updates their spec, labels and annotations if they
+// exist. -n overrides the namespaces of the documents.
+func importCronJobs(ctx context.Context, o *Options, f flags) error {
+	in := o.In
+	if f.file != "-" {
+		file, err := os.Open(f.file)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(in, 4096)
+	for {
+		var cronJob batchv1.CronJob
+		if err := decoder.Decode(&cronJob); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to decode %s: %w", f.file, err)
+		}
+		if cronJob.Name == "" {
+			// an empty document
+			continue
+		}
+		if cronJob.Kind != "CronJob" || cronJob.APIVersion != batchv1.GroupVersion.String() {
+			return fmt.Errorf("%s/%s is a %s, not a CronJob of %s", cronJob.APIVersion, cronJob.Name, cronJob.Kind,
+				batchv1.GroupVersion)
+		}
+		if f.namespaceSet || cronJob.Namespace == "" {
+			cronJob.Namespace = f.namespace
+		}
+		result, err := importCronJob(ctx, o.Client, &cronJob, f.dryRun)
+		if err != nil {
+			return fmt.Errorf("unable to import %s/%s: %w", cronJob.Namespace, cronJob.Name, err)
+		}
+		fmt.Fprintf(o.Out, "cronjob.batch.example.com/%s in %s %s%s\n", cronJob.Name, cronJob.Namespace, result,
+			dryRunSuffix(f.dryRun))
+	}
+}
+
+func importCronJob(ctx context.Context, c client.Client, cronJob *batchv1.CronJob, dryRun bool) (string, error) {
+	var createOpts []client.CreateOption
+	var updateOpts []client.UpdateOption
+	if dryRun {
+		createOpts, updateOpts = []client.CreateOption{client.DryRunAll}, []client.UpdateOption{client.DryRunAll}
+	}
+
+	var existing batchv1.CronJob
+	err := c.Get(ctx, client.ObjectKeyFromObject(cronJob), &existing)
+	if apierrors.IsNotFound(err) {
+		cronJob.ResourceVersion, cronJob.UID = "", ""
+		cronJob.Status = batchv1.CronJobStatus{}
+		return "created", c.Create(ctx, cronJob, createOpts...)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	updated := existing.DeepCopy()
+	updated.Spec = cronJob.Spec
+	for key, value := range cronJob.Labels {
+		metav1.SetMetaDataLabel(&updated.ObjectMeta, key, value)
+	}
+	for key, value := range cronJob.Annotations {
+		metav1.SetMetaDataAnnotation(&updated.ObjectMeta, key, value)
+	}
+	before, err := yaml.Marshal(&existing)
+	if err != nil {
+		return "", err
+	}
+	after, err := yaml.Marshal(updated)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(before, after) {
+		return "unchanged", nil
+	}
+	return "configured", c.Update(ctx, updated, updateOpts...)
+}
+
+/*
+The defaulted-fields annotation tells which fields the mutating webhook set and to what. `diff` compares them with the
+spec, which may have been changed since, and with the defaults of the config files of the manager, which may have
+changed since the CronJob was created. Without `--config`, the builtin defaults are used.
+*/
+
+// diff compares the defaults recorded in the CronJob with its spec and with the current defaults.
+func diff(ctx context.Context, o *Options, f flags, name string) error {
+	var cronJob batchv1.CronJob
+	if err := o.Client.Get(ctx, client.ObjectKey{Namespace: f.namespace, Name: name}, &cronJob); err != nil {
+		return err
+	}
+	admission := configv1.AdmissionConfig{}
+	if len(f.config) > 0 {
+		config, err := o.LoadConfig(f.config)
+		if err != nil {
+			return err
+		}
+		admission = config.Admission
+	}
+
+	recorded, current := map[string]webhooks.DefaultedField{}, map[string]webhooks.DefaultedField{}
+	fields := sets.NewString()
+	for _, field := range webhooks.DefaultedFields(&cronJob) {
+		recorded[field.Field] = field
+		fields.Insert(field.Field)
+	}
+	for _, field := range webhooks.EffectiveDefaults(admission) {
+		current[field.Field] = field
+		fields.Insert(field.Field)
+	}
+
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tSPEC\tDEFAULTED TO\tCURRENT DEFAULT\tSTATE")
+	for _, field := range fields.List() {
+		spec := specValue(&cronJob, field)
+		state := "defaulted"
+		r, wasDefaulted := recorded[field]
+		c, hasDefault := current[field]
+		switch {
+		case !wasDefaulted:
+			state = "set by the user"
+		case spec != r.Value:
+			state = "changed since defaulted"
+		case !hasDefault || c.Value != r.Value:
+			state = "default changed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", field, spec, formatDefault(r, wasDefaulted),
+			formatDefault(c, hasDefault), state)
+	}
+	return w.Flush()
+}
+
+func formatDefault(field webhooks.DefaultedField, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%s)", field.Value, field.Source)
+}
+
+// specValue returns the value of the defaulted field in the spec of the CronJob, formatted like in the
+// defaulted-fields annotation.
+func specValue(cronJob *batchv1.CronJob, field string) string {
+	switch field {
+	case "spec.concurrencyPolicy":
+		return string(cronJob.Spec.ConcurrencyPolicy)
+	case "spec.suspend":
+		if cronJob.Spec.Suspend != nil {
+			return strconv.FormatBool(*cronJob.Spec.Suspend)
+		}
+	case "spec.successfulJobsHistoryLimit":
+		if cronJob.Spec.SuccessfulJobsHistoryLimit != nil {
+			return strconv.Itoa(int(*cronJob.Spec.SuccessfulJobsHistoryLimit))
+		}
+	case "spec.failedJobsHistoryLimit":
+		if cronJob.Spec.FailedJobsHistoryLimit != nil {
+			return strconv.Itoa(int(*cronJob.Spec.FailedJobsHistoryLimit))
+		}
+	case "spec.timeZone":
+		if cronJob.Spec.TimeZone != nil {
+			return *cronJob.Spec.TimeZone
+		}
+	}
+	return ""
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronctl
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Run", func() {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 30, 12, 0, 0, 0, time.UTC)
+	created := metav1.NewTime(now.Add(-30 * 24 * time.Hour))
+
+	cronJob := func(namespace, name string) *v1.CronJob {
+		return &v1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, CreationTimestamp: created,
+				Labels: map[string]string{"team": namespace}},
+			Spec: v1.CronJobSpec{Schedule: "0 * * * *"},
+		}
+	}
+	succeededRun := func(name, cronJob string, completed time.Time) *v1.JobRun {
+		return &v1.JobRun{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: name},
+			Spec:       v1.JobRunSpec{CronJob: cronJob, Trigger: v1.ScheduledTrigger},
+			Status: v1.JobRunStatus{Phase: v1.JobRunSucceeded,
+				CompletionTime: &metav1.Time{Time: completed}},
+		}
+	}
+
+	var (
+		c   client.Client
+		o   *Options
+		out *bytes.Buffer
+	)
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+
+		zoned := cronJob("team-b", "zoned")
+		zoned.Spec.TimeZone = pointer.StringPtr("Europe/Istanbul")
+		defaulted := cronJob("team-a", "defaulted")
+		defaulted.Annotations = map[string]string{
+			"batch.example.com/defaulted-fields": "spec.concurrencyPolicy=Allow(builtin),spec.suspend=false(builtin)",
+		}
+		defaulted.Spec.ConcurrencyPolicy = v1.ForbidConcurrent
+		defaulted.Spec.Suspend = pointer.BoolPtr(false)
+		defaulted.Spec.FailedJobsHistoryLimit = pointer.Int32Ptr(5)
+
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			cronJob("team-a", "healthy"),
+			cronJob("team-a", "stale"),
+			cronJob("team-a", "legacy"),
+			defaulted,
+			zoned,
+			succeededRun("healthy-1", "healthy", now.Add(-time.Hour)),
+			succeededRun("stale-1", "stale", now.Add(-10*24*time.Hour)),
+			&kbatch.Job{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "legacy-1",
+					OwnerReferences: []metav1.OwnerReference{{APIVersion: v1.GroupVersion.String(), Kind: "CronJob",
+						Name: "legacy", UID: "uid", Controller: pointer.BoolPtr(true)}}},
+				Status: kbatch.JobStatus{
+					CompletionTime: &metav1.Time{Time: now.Add(-2 * 24 * time.Hour)},
+					Conditions:     []kbatch.JobCondition{{Type: kbatch.JobComplete, Status: corev1.ConditionTrue}},
+				},
+			},
+		).Build()
+		out = &bytes.Buffer{}
+		o = &Options{Client: c, Namespace: "team-a", Out: out, Now: func() time.Time { return now }}
+	})
+
+	It("Should list the CronJobs without a recent success", func() {
+		Expect(Run(ctx, o, []string{"stale", "-A", "--days", "7"})).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Expect(lines).To(HaveLen(4))
+		Expect(lines[1]).To(MatchRegexp(`^team-a\s+defaulted\s+false\s+never$`))
+		Expect(lines[2]).To(MatchRegexp(`^team-a\s+stale\s+false\s+2021-06-20T12:00:00Z$`))
+		Expect(lines[3]).To(MatchRegexp(`^team-b\s+zoned`))
+	})
+
+	It("Should set the time zones of the selected CronJobs", func() {
+		Expect(Run(ctx, o, []string{"set-timezone", "America/New_York", "-A", "--only-unset"})).To(Succeed())
+
+		var cronJobs v1.CronJobList
+		Expect(c.List(ctx, &cronJobs)).To(Succeed())
+		for _, cronJob := range cronJobs.Items {
+			Expect(cronJob.Spec.TimeZone).NotTo(BeNil())
+			if cronJob.Name == "zoned" {
+				Expect(*cronJob.Spec.TimeZone).To(Equal("Europe/Istanbul"))
+			} else {
+				Expect(*cronJob.Spec.TimeZone).To(Equal("America/New_York"))
+			}
+		}
+	})
+
+	It("Should not write in dry run, and refuse unknown time zones", func() {
+		Expect(Run(ctx, o, []string{"set-timezone", "UTC", "-l", "team=team-a", "--dry-run"})).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("(dry run)"))
+		var cronJob v1.CronJob
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "team-a", Name: "healthy"}, &cronJob)).To(Succeed())
+		Expect(cronJob.Spec.TimeZone).To(BeNil())
+
+		Expect(Run(ctx, o, []string{"set-timezone", "Mars/Olympus"})).To(MatchError(ContainSubstring("unknown")))
+	})
+
+	It("Should import the exported CronJobs", func() {
+		Expect(Run(ctx, o, []string{"export", "-n", "team-b"})).To(Succeed())
+		exported := out.String()
+		Expect(exported).To(ContainSubstring("name: zoned"))
+		Expect(exported).NotTo(ContainSubstring("status"))
+		Expect(exported).NotTo(ContainSubstring("resourceVersion"))
+
+		out.Reset()
+		o.In = strings.NewReader(exported)
+		Expect(Run(ctx, o, []string{"import", "-f", "-", "-n", "team-c"})).To(Succeed())
+		Expect(out.String()).To(Equal("cronjob.batch.example.com/zoned in team-c created\n"))
+
+		out.Reset()
+		o.In = strings.NewReader(exported)
+		Expect(Run(ctx, o, []string{"import", "-f", "-"})).To(Succeed())
+		Expect(out.String()).To(Equal("cronjob.batch.example.com/zoned in team-b unchanged\n"))
+
+		var cronJob v1.CronJob
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "team-c", Name: "zoned"}, &cronJob)).To(Succeed())
+		Expect(cronJob.Spec.TimeZone).To(Equal(pointer.StringPtr("Europe/Istanbul")))
+	})
+
+	It("Should compare the defaults of the CronJob with the current ones", func() {
+		o.LoadConfig = func(paths []string) (*configv1.ProjectConfig, error) {
+			Expect(paths).To(Equal([]string{"config.yaml"}))
+			return &configv1.ProjectConfig{Admission: configv1.AdmissionConfig{
+				Defaults: configv1.CronJobDefaults{ConcurrencyPolicy: "Replace"},
+			}}, nil
+		}
+		Expect(Run(ctx, o, []string{"diff", "defaulted", "--config", "config.yaml"})).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		Expect(lines).To(HaveLen(5))
+		Expect(lines[1]).To(MatchRegexp(`^spec.concurrencyPolicy\s+Forbid\s+Allow \(builtin\)\s+Replace \(config\)\s+changed since defaulted$`))
+		Expect(lines[2]).To(MatchRegexp(`^spec.failedJobsHistoryLimit\s+5\s+-\s+1 \(builtin\)\s+set by the user$`))
+		Expect(lines[4]).To(MatchRegexp(`^spec.suspend\s+false\s+false \(builtin\)\s+false \(builtin\)\s+defaulted$`))
+	})
+
+	It("Should refuse invalid command lines", func() {
+		Expect(Run(ctx, o, nil)).To(MatchError(ErrUsage))
+		Expect(Run(ctx, o, []string{"import"})).To(MatchError(ErrUsage))
+		Expect(Run(ctx, o, []string{"diff"})).To(MatchError(ErrUsage))
+		Expect(Run(ctx, o, []string{"stale", "--unknown"})).To(MatchError(ErrUsage))
+	})
+})
@@ -0,0 +1,245 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cronjobctl implements the subcommands of the kubectl-cronjob plugin, the day-two operations on the
+// CronJobs: starting a run now, suspending and resuming, listing the runs and the next ones, and reading the logs of
+// a run.
+package cronjobctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/controllers"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/restapi"
+)
+
+/*
+The plugin talks to the API server with the credentials of the user, not to the manager: a run started by `trigger`
+is created by the plugin itself, through the same code as the gRPC service and the dashboard, so the concurrency
+policy and the CronJobQuotas apply and the user needs the RBAC to create Jobs.
+*/
+
+// Usage is the help of the plugin.
+const Usage = `Usage: kubectl cronjob [-n namespace] <command> <cronjob> [flags]
+
+Commands:
+  trigger <cronjob>                 start a run now, outside of the schedule
+  suspend <cronjob>                 stop scheduling the CronJob
+  resume <cronjob>                  schedule the CronJob again
+  runs <cronjob> [--limit 20]       list the runs, the last scheduled first
+  next <cronjob> [--count 5]        list the next runs
+  logs <cronjob> [--run latest]     print the logs of the Pods of a run, [-f] to follow them
+`
+
+// ErrUsage is returned for invalid command lines, the caller prints Usage.
+var ErrUsage = errors.New("invalid command line")
+
+// Options are the clients and the settings of the commands.
+type Options struct {
+	// Client reads and writes the CronJobs and the Jobs.
+	Client client.Client
+	// Scheme knows the CronJobs and the Jobs.
+	Scheme *runtime.Scheme
+	// Pods reads the logs.
+	Pods corev1client.PodsGetter
+	// Namespace of the CronJob.
+	Namespace string
+	// Out receives the output of the commands.
+	Out io.Writer
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+}
+
+// Run runs the command of the arguments, e.g. `trigger report`.
+func Run(ctx context.Context, o *Options, args []string) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	command, flags := args[0], flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	limit := flags.Int("limit", 20, "the maximum number of runs")
+	count := flags.Int("count", 5, "the number of next runs")
+	run := flags.String("run", "latest", "the run, `latest` or the name of its Job")
+	follow := flags.Bool("f", false, "follow the logs")
+	container := flags.String("c", "", "the container, the first one if empty")
+	positional, err := parseInterspersed(flags, args[1:])
+	if err != nil || len(positional) != 1 {
+		return ErrUsage
+	}
+
+	var cronJob batchv1.CronJob
+	if err := o.Client.Get(ctx, client.ObjectKey{Namespace: o.Namespace, Name: positional[0]}, &cronJob); err != nil {
+		return err
+	}
+	switch command {
+	case "trigger":
+		job, err := controllers.TriggerRun(ctx, o.Client, o.Scheme, &cronJob, o.Now(), "kubectl-cronjob")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(o.Out, "job.batch/%s created\n", job.Name)
+	case "suspend", "resume":
+		suspend := command == "suspend"
+		changed, err := controllers.SuspendCronJob(ctx, o.Client, &cronJob, suspend)
+		if err != nil {
+			return err
+		}
+		result := map[bool]string{true: "suspended", false: "resumed"}[suspend]
+		if !changed {
+			result = "unchanged"
+		}
+		fmt.Fprintf(o.Out, "cronjob.batch.example.com/%s %s\n", cronJob.Name, result)
+	case "runs":
+		return printRuns(ctx, o, &cronJob, *limit)
+	case "next":
+		next, err := restapi.Next(&cronJob, o.Now(), *count)
+		if err != nil {
+			return err
+		}
+		if next.Suspended {
+			fmt.Fprintf(o.Out, "cronjob.batch.example.com/%s is suspended\n", cronJob.Name)
+		}
+		for _, t := range next.Next {
+			fmt.Fprintln(o.Out, t.Format(time.RFC3339))
+		}
+	case "logs":
+		return printLogs(ctx, o, &cronJob, *run, *container, *follow)
+	default:
+		return ErrUsage
+	}
+	return nil
+}
+
+// parseInterspersed parses the flags wherever they are, e.g. after the name of the CronJob like with kubectl, and
+// returns the other arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional, args = append(positional, flags.Arg(0)), flags.Args()[1:]
+	}
+}
+
+func printRuns(ctx context.Context, o *Options, cronJob *batchv1.CronJob, limit int) error {
+	runs, err := restapi.ListRuns(ctx, o.Client, cronJob, limit)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(o.Out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTRIGGER\tSCHEDULED\tPHASE\tDURATION\tREASON")
+	for _, run := range runs {
+		phase, duration := string(run.Phase), "-"
+		if phase == "" {
+			phase = "Pending"
+		}
+		if run.Duration != nil {
+			duration = run.Duration.Duration.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", run.Name, run.Trigger,
+			run.ScheduledTime.UTC().Format(time.RFC3339), phase, duration, run.FailureReason)
+	}
+	return w.Flush()
+}
+
+// printLogs prints the logs of the Pods of the Job of the run, the latest one created by the CronJob for `latest`.
+func printLogs(ctx context.Context, o *Options, cronJob *batchv1.CronJob, run, container string, follow bool) error {
+	job := run
+	if run == "latest" {
+		latest, err := latestJob(ctx, o.Client, cronJob)
+		if err != nil {
+			return err
+		}
+		job = latest
+	}
+
+	pods, err := o.Pods.Pods(cronJob.Namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + job})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no Pod found for the Job %s", job)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+	for _, pod := range pods.Items {
+		if len(pods.Items) > 1 {
+			fmt.Fprintf(o.Out, "==> pod/%s <==\n", pod.Name)
+		}
+		logs, err := o.Pods.Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container: container,
+			Follow:    follow,
+		}).Stream(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(o.Out, logs)
+		logs.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// latestJob returns the name of the last Job created by the CronJob, whether scheduled or started by hand.
+func latestJob(ctx context.Context, c client.Reader, cronJob *batchv1.CronJob) (string, error) {
+	var jobs kbatch.JobList
+	if err := c.List(ctx, &jobs, client.InNamespace(cronJob.Namespace)); err != nil {
+		return "", err
+	}
+	var latest *kbatch.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		owner := metav1.GetControllerOf(job)
+		if owner == nil || owner.Kind != "CronJob" || owner.Name != cronJob.Name ||
+			!strings.HasPrefix(owner.APIVersion, batchv1.GroupVersion.Group+"/") {
+			continue
+		}
+		if latest == nil || latest.CreationTimestamp.Before(&job.CreationTimestamp) ||
+			(latest.CreationTimestamp.Equal(&job.CreationTimestamp) && latest.Name < job.Name) {
+			latest = job
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no run found for the CronJob %s", cronJob.Name)
+	}
+	return latest.Name, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronjobctl
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Run", func() {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 5, 12, 30, 0, 0, time.UTC)
+
+	childJob := func(name string, created time.Time) *kbatch.Job {
+		return &kbatch.Job{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(created),
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: v1.GroupVersion.String(), Kind: "CronJob",
+				Name: "report", UID: "uid", Controller: pointer.BoolPtr(true)}},
+		}}
+	}
+
+	var (
+		c   client.Client
+		o   *Options
+		out *bytes.Buffer
+	)
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "report", UID: "uid"},
+				Spec:       v1.CronJobSpec{Schedule: "0 * * * *"},
+			},
+			&v1.JobRun{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "report-1"},
+				Spec: v1.JobRunSpec{CronJob: "report", ScheduledTime: metav1.NewTime(now.Add(-time.Hour)),
+					Trigger: v1.ScheduledTrigger},
+				Status: v1.JobRunStatus{Phase: v1.JobRunFailed, FailureReason: "BackoffLimitExceeded"},
+			},
+			childJob("report-1", now.Add(-time.Hour)),
+			childJob("report-2", now.Add(-time.Minute)),
+		).Build()
+		pods := kubefake.NewSimpleClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default", Name: "report-2-abcde", Labels: map[string]string{"job-name": "report-2"}}})
+		out = &bytes.Buffer{}
+		o = &Options{Client: c, Scheme: scheme, Pods: pods.CoreV1(), Namespace: "default", Out: out,
+			Now: func() time.Time { return now }}
+	})
+
+	It("Should start a manual run", func() {
+		Expect(Run(ctx, o, []string{"trigger", "report"})).To(Succeed())
+		Expect(out.String()).To(HavePrefix("job.batch/report-"))
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs, client.InNamespace("default"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(3))
+	})
+
+	It("Should suspend and resume the CronJob", func() {
+		Expect(Run(ctx, o, []string{"suspend", "report"})).To(Succeed())
+		Expect(Run(ctx, o, []string{"suspend", "report"})).To(Succeed())
+		Expect(out.String()).To(Equal("cronjob.batch.example.com/report suspended\n" +
+			"cronjob.batch.example.com/report unchanged\n"))
+
+		Expect(Run(ctx, o, []string{"resume", "report"})).To(Succeed())
+		var cronJob v1.CronJob
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "report"}, &cronJob)).To(Succeed())
+		Expect(cronJob.Spec.Suspend).To(Equal(pointer.BoolPtr(false)))
+	})
+
+	It("Should list the runs", func() {
+		Expect(Run(ctx, o, []string{"runs", "report", "--limit", "5"})).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("report-1"))
+		Expect(out.String()).To(ContainSubstring("BackoffLimitExceeded"))
+	})
+
+	It("Should list the next runs, with the flags after the name of the CronJob", func() {
+		Expect(Run(ctx, o, []string{"next", "report", "--count", "2"})).To(Succeed())
+		Expect(out.String()).To(Equal("2021-06-05T13:00:00Z\n2021-06-05T14:00:00Z\n"))
+	})
+
+	It("Should print the logs of the latest run", func() {
+		Expect(Run(ctx, o, []string{"logs", "report", "--run", "latest"})).To(Succeed())
+		Expect(out.String()).To(Equal("fake logs"))
+	})
+
+	It("Should fail without the Pods of the run", func() {
+		Expect(Run(ctx, o, []string{"logs", "report", "--run", "report-1"})).To(MatchError(ContainSubstring("no Pod")))
+	})
+
+	It("Should refuse invalid command lines", func() {
+		Expect(Run(ctx, o, nil)).To(MatchError(ErrUsage))
+		Expect(Run(ctx, o, []string{"trigger"})).To(MatchError(ErrUsage))
+		Expect(Run(ctx, o, []string{"runs", "report", "--unknown"})).To(MatchError(ErrUsage))
+		Expect(Run(ctx, o, []string{"delete", "report"})).To(MatchError(ErrUsage))
+	})
+})
@@ -0,0 +1,128 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cronjobreport summarizes the CronJobs of a namespace into the status of its CronJobReport.
+package cronjobreport
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+A CronJob is failing when its last finished run failed. The history limits delete the finished Jobs, so the runs are
+read from the JobRuns too, when the JobRun CRD is installed, and the latest of a Job and its JobRun wins. The next runs
+come from the CronJob controller, which knows the time zones, the calendars and the overrides of the schedules.
+*/
+
+// run is the last finished run of a CronJob.
+type run struct {
+	finished  time.Time
+	succeeded bool
+}
+
+// Summarize returns the report of the CronJobs of the namespace at now. nextRuns holds the next scheduled run of the
+// CronJobs by name, the CronJobs missing from it are left out of the next run.
+func Summarize(ctx context.Context, c client.Reader, namespace string, nextRuns map[string]time.Time,
+	now time.Time) (v1.CronJobReportStatus, error) {
+	status := v1.CronJobReportStatus{UpdateTime: &metav1.Time{Time: now}}
+	var cronJobs v1.CronJobList
+	if err := c.List(ctx, &cronJobs, client.InNamespace(namespace)); err != nil {
+		return status, fmt.Errorf("unable to list the CronJobs: %w", err)
+	}
+	lastRuns, err := lastRuns(ctx, c, namespace)
+	if err != nil {
+		return status, err
+	}
+
+	var longestSinceSuccess time.Duration
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		status.CronJobs++
+		if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+			status.Suspended++
+		}
+		if last, ok := lastRuns[cronJob.Name]; ok && !last.succeeded {
+			status.Failing++
+			status.FailingCronJobs = append(status.FailingCronJobs, cronJob.Name)
+		}
+
+		if next, ok := nextRuns[cronJob.Name]; ok && (status.NextRunTime == nil || next.Before(status.NextRunTime.Time)) {
+			status.NextRunTime = &metav1.Time{Time: next}
+			status.NextRunCronJob = cronJob.Name
+		}
+
+		lastSuccess := cronJob.CreationTimestamp.Time
+		if cronJob.Status.LastSuccessfulTime != nil {
+			lastSuccess = cronJob.Status.LastSuccessfulTime.Time
+		}
+		if since := now.Sub(lastSuccess); status.LongestSinceSuccess == nil || since > longestSinceSuccess {
+			longestSinceSuccess = since
+			status.LongestSinceSuccess = &metav1.Duration{Duration: since.Truncate(time.Second)}
+			status.LongestSinceSuccessCronJob = cronJob.Name
+		}
+	}
+	sort.Strings(status.FailingCronJobs)
+	return status, nil
+}
+
+// lastRuns returns the last finished run of the CronJobs of the namespace by name.
+func lastRuns(ctx context.Context, c client.Reader, namespace string) (map[string]run, error) {
+	runs := map[string]run{}
+	add := func(cronJob string, finished time.Time, succeeded bool) {
+		if last, ok := runs[cronJob]; !ok || finished.After(last.finished) {
+			runs[cronJob] = run{finished: finished, succeeded: succeeded}
+		}
+	}
+
+	var jobs kbatch.JobList
+	if err := c.List(ctx, &jobs, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("unable to list the Jobs: %w", err)
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		owner := metav1.GetControllerOf(job)
+		if owner == nil || owner.APIVersion != v1.GroupVersion.String() || owner.Kind != "CronJob" {
+			continue
+		}
+		for _, condition := range job.Status.Conditions {
+			if (condition.Type == kbatch.JobComplete || condition.Type == kbatch.JobFailed) &&
+				condition.Status == corev1.ConditionTrue {
+				add(owner.Name, condition.LastTransitionTime.Time, condition.Type == kbatch.JobComplete)
+			}
+		}
+	}
+
+	var jobRuns v1.JobRunList
+	if err := c.List(ctx, &jobRuns, client.InNamespace(namespace)); err != nil && !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("unable to list the JobRuns: %w", err)
+	}
+	for _, jobRun := range jobRuns.Items {
+		if jobRun.Status.CompletionTime != nil {
+			add(jobRun.Spec.CronJob, jobRun.Status.CompletionTime.Time, jobRun.Status.Phase == v1.JobRunSucceeded)
+		}
+	}
+	return runs, nil
+}
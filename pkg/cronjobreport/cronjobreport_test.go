@@ -0,0 +1,109 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronjobreport
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Summarize", func() {
+	now := time.Date(2021, 6, 5, 12, 0, 0, 0, time.UTC)
+
+	newClient := func(objects ...client.Object) client.Client {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	}
+
+	cronJob := func(name string, created time.Time, lastSuccess *time.Time, suspended bool) *v1.CronJob {
+		cronJob := &v1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, CreationTimestamp: metav1.Time{Time: created}},
+			Spec:       v1.CronJobSpec{Suspend: pointer.BoolPtr(suspended)},
+		}
+		if lastSuccess != nil {
+			cronJob.Status.LastSuccessfulTime = &metav1.Time{Time: *lastSuccess}
+		}
+		return cronJob
+	}
+
+	job := func(name, cronJob string, finished time.Time, conditionType kbatch.JobConditionType) *kbatch.Job {
+		return &kbatch.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name,
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: v1.GroupVersion.String(), Kind: "CronJob",
+					Name: cronJob, UID: "uid", Controller: pointer.BoolPtr(true)}}},
+			Status: kbatch.JobStatus{Conditions: []kbatch.JobCondition{{Type: conditionType,
+				Status: corev1.ConditionTrue, LastTransitionTime: metav1.Time{Time: finished}}}},
+		}
+	}
+
+	jobRun := func(name, cronJob string, finished time.Time, phase v1.JobRunPhase) *v1.JobRun {
+		return &v1.JobRun{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       v1.JobRunSpec{CronJob: cronJob},
+			Status:     v1.JobRunStatus{Phase: phase, CompletionTime: &metav1.Time{Time: finished}},
+		}
+	}
+
+	It("Should summarize the CronJobs of the namespace", func() {
+		hourAgo, dayAgo := now.Add(-time.Hour), now.Add(-24*time.Hour)
+		c := newClient(
+			cronJob("report", now.Add(-48*time.Hour), &hourAgo, false),
+			cronJob("backup", now.Add(-48*time.Hour), &dayAgo, false),
+			cronJob("cleanup", now.Add(-30*time.Hour), nil, true),
+			// the last run of report succeeded, the last one of backup failed and its Job was deleted
+			job("report-1", "report", now.Add(-2*time.Hour), kbatch.JobFailed),
+			job("report-2", "report", hourAgo, kbatch.JobComplete),
+			job("backup-1", "backup", dayAgo, kbatch.JobComplete),
+			jobRun("backup-2", "backup", now.Add(-10*time.Minute), v1.JobRunFailed))
+
+		status, err := Summarize(context.Background(), c, "default", map[string]time.Time{
+			"report": now.Add(time.Hour),
+			"backup": now.Add(10 * time.Minute),
+		}, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.CronJobs).To(Equal(int32(3)))
+		Expect(status.Suspended).To(Equal(int32(1)))
+		Expect(status.Failing).To(Equal(int32(1)))
+		Expect(status.FailingCronJobs).To(Equal([]string{"backup"}))
+		Expect(status.NextRunTime.Time).To(Equal(now.Add(10 * time.Minute)))
+		Expect(status.NextRunCronJob).To(Equal("backup"))
+		Expect(status.LongestSinceSuccess.Duration).To(Equal(30 * time.Hour))
+		Expect(status.LongestSinceSuccessCronJob).To(Equal("cleanup"))
+	})
+
+	It("Should report an empty namespace", func() {
+		status, err := Summarize(context.Background(), newClient(), "default", nil, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.CronJobs).To(BeZero())
+		Expect(status.NextRunTime).To(BeNil())
+		Expect(status.LongestSinceSuccess).To(BeNil())
+	})
+})
@@ -0,0 +1,176 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cronjobset generates the CronJobs of the CronJobSets: the instances of the parameter tuples in the
+// namespaces, and the CronJob of every instance rendered from the template of the set.
+package cronjobset
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SetLabel is the label naming the set of a CronJob.
+	SetLabel = "batch.example.com/cronjobset"
+	// TemplateHashAnnotation is the hash of the rendered template of a CronJob. It only changes with the template or
+	// the parameters, not with the defaults set by the webhook, so it tells whether the CronJob is up to date.
+	TemplateHashAnnotation = "batch.example.com/template-hash"
+	// NamespaceParameter is the parameter set to the namespace of the CronJob.
+	NamespaceParameter = "namespace"
+)
+
+// reference matches the `$(name)` references to the parameters.
+var reference = regexp.MustCompile(`\$\(([A-Za-z0-9_.-]+)\)`)
+
+// Instance is a CronJob generated by a set.
+type Instance struct {
+	// Namespace is the namespace of the CronJob.
+	Namespace string
+	// Name is the name of the CronJob.
+	Name string
+	// Parameters is the name of the parameter tuple of the CronJob.
+	Parameters string
+	// Values are the values of the parameter tuple.
+	Values map[string]string
+}
+
+// Instances returns the instances of the set, sorted by namespace and name. The tuples without a namespace generate
+// an instance in every given namespace.
+func Instances(set *v1.CronJobSet, namespaces []string) ([]Instance, error) {
+	tuples := set.Spec.Parameters
+	if len(tuples) == 0 {
+		tuples = []v1.CronJobSetParameters{{}}
+	}
+
+	names := map[string]bool{}
+	var instances []Instance
+	for _, tuple := range tuples {
+		if tuple.Name == "" && len(tuples) > 1 {
+			return nil, fmt.Errorf("the parameter tuples must be named when there are several of them")
+		}
+		if names[tuple.Name] {
+			return nil, fmt.Errorf("duplicate parameter tuple %q", tuple.Name)
+		}
+		names[tuple.Name] = true
+
+		name := set.Name
+		if tuple.Name != "" {
+			name += "-" + tuple.Name
+		}
+		tupleNamespaces := namespaces
+		if tuple.Namespace != "" {
+			tupleNamespaces = []string{tuple.Namespace}
+		}
+		for _, namespace := range tupleNamespaces {
+			instances = append(instances, Instance{Namespace: namespace, Name: name, Parameters: tuple.Name,
+				Values: tuple.Values})
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Namespace != instances[j].Namespace {
+			return instances[i].Namespace < instances[j].Namespace
+		}
+		return instances[i].Name < instances[j].Name
+	})
+	for i := 1; i < len(instances); i++ {
+		if instances[i].Namespace == instances[i-1].Namespace && instances[i].Name == instances[i-1].Name {
+			return nil, fmt.Errorf("several parameter tuples generate the CronJob %s/%s", instances[i].Namespace,
+				instances[i].Name)
+		}
+	}
+	return instances, nil
+}
+
+/*
+The template is rendered on its JSON form, so every string of it can refer to the parameters, whatever its field. The
+references to unknown parameters are left as they are, since `$(VAR)` is also how the commands of the containers refer
+to their environment variables.
+*/
+
+// Render returns the CronJob of the instance, labeled with the set and annotated with the hash of its template.
+func Render(set *v1.CronJobSet, instance Instance) (*v1.CronJob, error) {
+	values := map[string]string{NamespaceParameter: instance.Namespace}
+	for k, v := range instance.Values {
+		values[k] = v
+	}
+
+	raw, err := json.Marshal(set.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := json.Unmarshal(raw, &tree); err != nil {
+		return nil, err
+	}
+	if raw, err = json.Marshal(substitute(tree, values)); err != nil {
+		return nil, err
+	}
+	var template v1.CronJobSetTemplate
+	if err := json.Unmarshal(raw, &template); err != nil {
+		return nil, fmt.Errorf("unable to render the template: %w", err)
+	}
+	hash := fnv.New32a()
+	hash.Write(raw)
+
+	cronJob := &v1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   instance.Namespace,
+			Name:        instance.Name,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Spec: template.Spec,
+	}
+	for k, v := range template.Labels {
+		cronJob.Labels[k] = v
+	}
+	cronJob.Labels[SetLabel] = set.Name
+	for k, v := range template.Annotations {
+		cronJob.Annotations[k] = v
+	}
+	cronJob.Annotations[TemplateHashAnnotation] = fmt.Sprintf("%08x", hash.Sum32())
+	return cronJob, nil
+}
+
+// substitute replaces the references to the parameters in the strings of the JSON tree.
+func substitute(tree interface{}, values map[string]string) interface{} {
+	switch node := tree.(type) {
+	case string:
+		return reference.ReplaceAllStringFunc(node, func(ref string) string {
+			if value, ok := values[ref[2:len(ref)-1]]; ok {
+				return value
+			}
+			return ref
+		})
+	case map[string]interface{}:
+		for k, v := range node {
+			node[k] = substitute(v, values)
+		}
+	case []interface{}:
+		for i, v := range node {
+			node[i] = substitute(v, values)
+		}
+	}
+	return tree
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronjobset
+
+import (
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("CronJobSet", func() {
+	newSet := func(parameters ...v1.CronJobSetParameters) *v1.CronJobSet {
+		set := &v1.CronJobSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "report"},
+			Spec: v1.CronJobSetSpec{
+				Parameters: parameters,
+				Template: v1.CronJobSetTemplate{
+					Labels: map[string]string{"region": "$(region)"},
+					Spec:   v1.CronJobSpec{Schedule: "$(schedule)"},
+				},
+			},
+		}
+		set.Spec.Template.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{
+			Name:    "report",
+			Image:   "report:1.0",
+			Command: []string{"report", "--region=$(region)", "--namespace=$(namespace)", "--home=$(HOME)"},
+		}}
+		return set
+	}
+
+	It("Should generate an instance per tuple and namespace", func() {
+		set := newSet(
+			v1.CronJobSetParameters{Name: "eu", Values: map[string]string{"region": "eu-west-1"}},
+			v1.CronJobSetParameters{Name: "us", Namespace: "reports", Values: map[string]string{"region": "us-east-1"}})
+		instances, err := Instances(set, []string{"team-b", "team-a"})
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, instance := range instances {
+			names = append(names, instance.Namespace+"/"+instance.Name)
+		}
+		Expect(names).To(Equal([]string{"reports/report-us", "team-a/report-eu", "team-b/report-eu"}))
+
+		instances, err = Instances(newSet(), []string{"team-a"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instances).To(Equal([]Instance{{Namespace: "team-a", Name: "report"}}))
+	})
+
+	It("Should reject the tuples generating the same CronJob", func() {
+		_, err := Instances(newSet(v1.CronJobSetParameters{Name: "eu"}, v1.CronJobSetParameters{Name: "eu"}), nil)
+		Expect(err).To(MatchError(`duplicate parameter tuple "eu"`))
+		_, err = Instances(newSet(v1.CronJobSetParameters{Name: "eu"}, v1.CronJobSetParameters{}), nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should render the template with the parameters", func() {
+		set := newSet()
+		instance := Instance{Namespace: "team-a", Name: "report-eu", Parameters: "eu",
+			Values: map[string]string{"region": "eu-west-1", "schedule": "0 2 * * *"}}
+		cronJob, err := Render(set, instance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJob.Namespace).To(Equal("team-a"))
+		Expect(cronJob.Name).To(Equal("report-eu"))
+		Expect(cronJob.Labels).To(Equal(map[string]string{"region": "eu-west-1", SetLabel: "report"}))
+		Expect(cronJob.Spec.Schedule).To(Equal("0 2 * * *"))
+		Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Command).To(Equal(
+			[]string{"report", "--region=eu-west-1", "--namespace=team-a", "--home=$(HOME)"}))
+		// the template of the set is left as it was
+		Expect(set.Spec.Template.Spec.Schedule).To(Equal("$(schedule)"))
+
+		again, err := Render(set, instance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again.Annotations[TemplateHashAnnotation]).To(Equal(cronJob.Annotations[TemplateHashAnnotation]))
+		instance.Values = map[string]string{"region": "us-east-1", "schedule": "0 2 * * *"}
+		other, err := Render(set, instance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.Annotations[TemplateHashAnnotation]).NotTo(Equal(cronJob.Annotations[TemplateHashAnnotation]))
+	})
+})
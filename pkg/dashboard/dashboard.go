@@ -0,0 +1,348 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dashboard serves a web dashboard of the CronJobs for the users without kubectl: a timeline of their past and
+// upcoming runs, their health, and buttons to start a run or to suspend them.
+package dashboard
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/controllers"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/filters"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/restapi"
+)
+
+/*
+The dashboard is a static page calling a small JSON API next to it, both served under `/dashboard/` by the read-only
+API server, so they are authenticated and authorized like the rest of it: `get` on `/dashboard/*` shows the
+dashboard, `post` on `/dashboard/api/cronjobs/*` enables its buttons. The buttons act with the permissions of the
+manager, so a SubjectAccessReview also checks that the user may act on the CronJob itself: the custom `trigger` verb on
+cronjobs starts a run, `patch` suspends and resumes. The buttons go through the same code as the gRPC service: a run
+started from the dashboard is a manual run, with the concurrency policy and the quotas applied, recorded as started by
+`dashboard:<user>`. The CronJobs are read with the permissions of the manager too, so the overview only shows the
+CronJobs of the namespaces the user may list cronjobs in, reviewed once per namespace.
+
+The writes must be sent as JSON, which a browser does not send to another origin without a preflight request, so
+another site cannot press the buttons on behalf of a user behind an authenticating proxy.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobruns,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// Prefix is the path of the dashboard.
+const Prefix = "/dashboard/"
+
+const (
+	apiPrefix = Prefix + "api/cronjobs"
+	// shownRuns and shownNextRuns are the numbers of past and upcoming runs shown per CronJob.
+	shownRuns     = 10
+	shownNextRuns = 5
+)
+
+// actionVerbs are the verbs on the CronJob the user needs for the actions of the buttons.
+var actionVerbs = map[string]string{
+	"trigger": "trigger",
+	"suspend": "patch",
+	"resume":  "patch",
+}
+
+// The health of a CronJob, from its last finished run.
+const (
+	HealthHealthy   = "Healthy"
+	HealthFailing   = "Failing"
+	HealthSuspended = "Suspended"
+	HealthUnknown   = "Unknown"
+)
+
+//go:embed static
+var static embed.FS
+
+var log = logf.Log.WithName("dashboard")
+
+// CronJob is a CronJob as shown in the dashboard.
+type CronJob struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Schedule  string `json:"schedule"`
+	TimeZone  string `json:"timeZone,omitempty"`
+	Health    string `json:"health"`
+	// Active is the number of the running Jobs.
+	Active int `json:"active"`
+	// Runs are the last runs, the last scheduled first.
+	Runs []restapi.Run `json:"runs"`
+	// Next are the next runs.
+	Next []time.Time `json:"next"`
+	// Error tells why the next runs are unknown, e.g. an invalid time zone.
+	Error string `json:"error,omitempty"`
+}
+
+// Overview is the content of the dashboard.
+type Overview struct {
+	Now      time.Time `json:"now"`
+	CronJobs []CronJob `json:"cronJobs"`
+}
+
+// Handler serves the dashboard.
+type Handler struct {
+	client client.Client
+	scheme *runtime.Scheme
+	files  http.Handler
+	now    func() time.Time
+}
+
+// NewHandler returns the handler of the dashboard. The client reads from the cache and writes the Jobs and the
+// CronJobs of the buttons.
+func NewHandler(c client.Client, scheme *runtime.Scheme) *Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	return &Handler{
+		client: c,
+		scheme: scheme,
+		files:  http.StripPrefix(Prefix, http.FileServer(http.FS(files))),
+		now:    time.Now,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.URL.Path == apiPrefix && req.Method == http.MethodGet:
+		h.overview(w, req)
+	case strings.HasPrefix(req.URL.Path, apiPrefix+"/") && req.Method == http.MethodPost:
+		h.action(w, req)
+	case strings.HasPrefix(req.URL.Path, apiPrefix):
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	case req.Method == http.MethodGet:
+		h.files.ServeHTTP(w, req)
+	default:
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// overview lists the CronJobs the user may list, of the namespace of the query if any.
+func (h *Handler) overview(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	user, ok := filters.UserFrom(ctx)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var opts []client.ListOption
+	if namespace := req.URL.Query().Get("namespace"); namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	var cronJobs batchv1.CronJobList
+	if err := h.client.List(ctx, &cronJobs, opts...); err != nil {
+		log.Error(err, "unable to list CronJobs")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	now := h.now()
+	overview := Overview{Now: now, CronJobs: []CronJob{}}
+	listable := map[string]bool{}
+	for i := range cronJobs.Items {
+		cronJob := &cronJobs.Items[i]
+		allowed, reviewed := listable[cronJob.Namespace]
+		if !reviewed {
+			var err error
+			if allowed, err = h.authorize(ctx, user, cronJob.Namespace, "", "list"); err != nil {
+				log.Error(err, "unable to authorize the overview", "namespace", cronJob.Namespace, "user",
+					user.Username)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			listable[cronJob.Namespace] = allowed
+		}
+		if !allowed {
+			continue
+		}
+		runs, err := restapi.ListRuns(ctx, h.client, cronJob, shownRuns)
+		if err != nil {
+			log.Error(err, "unable to list JobRuns")
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		shown := CronJob{
+			Namespace: cronJob.Namespace,
+			Name:      cronJob.Name,
+			Schedule:  cronJob.Spec.Schedule,
+			Health:    health(cronJob, runs),
+			Active:    len(cronJob.Status.Active),
+			Runs:      runs,
+			Next:      []time.Time{},
+		}
+		if next, err := restapi.Next(cronJob, now, shownNextRuns); err != nil {
+			shown.Error = err.Error()
+		} else {
+			shown.Next, shown.TimeZone = next.Next, next.TimeZone
+		}
+		overview.CronJobs = append(overview.CronJobs, shown)
+	}
+	sort.Slice(overview.CronJobs, func(i, j int) bool {
+		a, b := overview.CronJobs[i], overview.CronJobs[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Name < b.Name)
+	})
+	writeJSON(w, http.StatusOK, overview)
+}
+
+// action runs the action of a button, `trigger`, `suspend` or `resume`, on a CronJob.
+func (h *Handler) action(w http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		http.Error(w, "the actions must be sent as application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, apiPrefix+"/"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, req)
+		return
+	}
+	namespace, name, action := parts[0], parts[1], parts[2]
+	verb, ok := actionVerbs[action]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	ctx := req.Context()
+	user, ok := filters.UserFrom(ctx)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// the access is reviewed first, a user not allowed on the CronJob cannot tell whether it exists
+	allowed, err := h.authorize(ctx, user, namespace, name, verb)
+	if err != nil {
+		log.Error(err, "unable to authorize the action", "cronJob", namespace+"/"+name, "user", user.Username)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("user %q is not allowed to %s cronjobs in the namespace %q", user.Username, verb,
+			namespace), http.StatusForbidden)
+		return
+	}
+
+	var cronJob batchv1.CronJob
+	if err := h.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &cronJob); err != nil {
+		writeError(w, err)
+		return
+	}
+	switch action {
+	case "trigger":
+		job, err := controllers.TriggerRun(ctx, h.client, h.scheme, &cronJob, h.now(), "dashboard:"+user.Username)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		log.Info("triggered a run", "cronJob", namespace+"/"+name, "job", job.Name, "user", user.Username)
+		writeJSON(w, http.StatusCreated, map[string]string{"job": job.Name})
+	case "suspend", "resume":
+		suspend := action == "suspend"
+		if _, err := controllers.SuspendCronJob(ctx, h.client, &cronJob, suspend); err != nil {
+			writeError(w, err)
+			return
+		}
+		log.Info("changed the suspension of a CronJob", "cronJob", namespace+"/"+name, "suspend", suspend,
+			"user", user.Username)
+		writeJSON(w, http.StatusOK, map[string]bool{"suspended": suspend})
+	}
+}
+
+// authorize returns whether the user may use the verb on the CronJob, or on the CronJobs of the namespace without
+// a name.
+func (h *Handler) authorize(ctx context.Context, user authenticationv1.UserInfo, namespace, name, verb string) (bool,
+	error) {
+	sar := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+		User:   user.Username,
+		UID:    user.UID,
+		Groups: user.Groups,
+		Extra:  filters.ConvertExtra(user.Extra),
+		ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Namespace: namespace,
+			Verb:      verb,
+			Group:     batchv1.GroupVersion.Group,
+			Resource:  "cronjobs",
+			Name:      name,
+		},
+	}}
+	if err := h.client.Create(ctx, sar); err != nil {
+		return false, err
+	}
+	return sar.Status.Allowed, nil
+}
+
+// health returns the health of the CronJob from its last finished run.
+func health(cronJob *batchv1.CronJob, runs []restapi.Run) string {
+	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+		return HealthSuspended
+	}
+	for _, run := range runs {
+		switch run.Phase {
+		case batchv1.JobRunSucceeded:
+			return HealthHealthy
+		case batchv1.JobRunFailed, batchv1.JobRunLost:
+			return HealthFailing
+		}
+	}
+	return HealthUnknown
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var denied *controllers.RunDeniedError
+	switch {
+	case errors.As(err, &denied):
+		http.Error(w, denied.Error(), http.StatusConflict)
+	case apierrors.IsNotFound(err):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case apierrors.IsConflict(err), apierrors.IsInvalid(err), apierrors.IsForbidden(err):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Error(err, "unable to run the action")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.V(1).Info("unable to write the response", "error", err.Error())
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/filters"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kbatch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// reviewClient answers the SubjectAccessReviews from the verbs allowed per user and namespace.
+type reviewClient struct {
+	client.Client
+	allowed map[string][]string
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	review, ok := obj.(*authorizationv1.SubjectAccessReview)
+	if !ok {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+	attributes := review.Spec.ResourceAttributes
+	if attributes.Group != v1.GroupVersion.Group || attributes.Resource != "cronjobs" {
+		return nil
+	}
+	for _, allowed := range c.allowed[review.Spec.User+"/"+attributes.Namespace] {
+		review.Status.Allowed = review.Status.Allowed || allowed == attributes.Verb
+	}
+	return nil
+}
+
+var _ = Describe("Handler", func() {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 5, 12, 30, 0, 0, time.UTC)
+
+	jobRun := func(name, cronJob string, scheduled time.Time, phase v1.JobRunPhase) *v1.JobRun {
+		return &v1.JobRun{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1.JobRunSpec{CronJob: cronJob, ScheduledTime: metav1.NewTime(scheduled),
+				Trigger: v1.ScheduledTrigger},
+			Status: v1.JobRunStatus{Phase: phase},
+		}
+	}
+
+	var (
+		handler *Handler
+		c       client.Client
+	)
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "report"},
+				Spec:       v1.CronJobSpec{Schedule: "0 * * * *"},
+			},
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "paused"},
+				Spec:       v1.CronJobSpec{Schedule: "0 * * * *", Suspend: pointer.BoolPtr(true)},
+			},
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "backup"},
+				Spec:       v1.CronJobSpec{Schedule: "0 * * * *"},
+			},
+			jobRun("report-1", "report", now.Add(-2*time.Hour), v1.JobRunSucceeded),
+			jobRun("report-2", "report", now.Add(-time.Hour), v1.JobRunFailed),
+		).Build()
+		handler = NewHandler(&reviewClient{Client: c, allowed: map[string][]string{
+			"alice/default": {"list", "trigger", "patch"},
+			"bob/default":   {"list", "trigger"},
+			"carol/other":   {"list"},
+		}}, scheme)
+		handler.now = func() time.Time { return now }
+	})
+
+	serveAs := func(user, method, path, contentType string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		if user != "" {
+			req = req.WithContext(filters.WithUser(req.Context(), authenticationv1.UserInfo{Username: user}))
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+	serve := func(method, path, contentType string) *httptest.ResponseRecorder {
+		return serveAs("alice", method, path, contentType)
+	}
+
+	It("Should show the health and the next runs of the CronJobs", func() {
+		recorder := serve(http.MethodGet, "/dashboard/api/cronjobs?namespace=default", "")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var overview Overview
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &overview)).To(Succeed())
+		Expect(overview.CronJobs).To(HaveLen(2))
+
+		paused, report := overview.CronJobs[0], overview.CronJobs[1]
+		Expect(paused.Name).To(Equal("paused"))
+		Expect(paused.Health).To(Equal(HealthSuspended))
+		Expect(report.Name).To(Equal("report"))
+		Expect(report.Health).To(Equal(HealthFailing))
+		Expect(report.Runs).To(HaveLen(2))
+		Expect(report.Next).To(HaveLen(shownNextRuns))
+		Expect(report.Next[0]).To(BeTemporally("==", time.Date(2021, 6, 5, 13, 0, 0, 0, time.UTC)))
+	})
+
+	It("Should only show the CronJobs of the namespaces the user may list", func() {
+		overviewOf := func(user, path string) []string {
+			recorder := serveAs(user, http.MethodGet, path, "")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			var overview Overview
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &overview)).To(Succeed())
+			names := []string{}
+			for _, cronJob := range overview.CronJobs {
+				names = append(names, cronJob.Namespace+"/"+cronJob.Name)
+			}
+			return names
+		}
+
+		Expect(overviewOf("carol", "/dashboard/api/cronjobs")).To(Equal([]string{"other/backup"}))
+		Expect(overviewOf("carol", "/dashboard/api/cronjobs?namespace=default")).To(BeEmpty())
+		Expect(overviewOf("alice", "/dashboard/api/cronjobs")).To(Equal([]string{"default/paused", "default/report"}))
+		Expect(serveAs("", http.MethodGet, "/dashboard/api/cronjobs", "").Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Should start a manual run of the CronJob", func() {
+		recorder := serve(http.MethodPost, "/dashboard/api/cronjobs/default/report/trigger", "application/json")
+		Expect(recorder.Code).To(Equal(http.StatusCreated))
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs, client.InNamespace("default"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(jobs.Items[0].Annotations).To(HaveKeyWithValue("batch.example.com/trigger", "Manual"))
+		Expect(jobs.Items[0].Annotations).To(HaveKeyWithValue("batch.example.com/triggered-by", "dashboard:alice"))
+	})
+
+	It("Should only run the actions the user is allowed to on the CronJob", func() {
+		Expect(serveAs("bob", http.MethodPost, "/dashboard/api/cronjobs/default/report/suspend", "application/json").
+			Code).To(Equal(http.StatusForbidden))
+		Expect(serveAs("alice", http.MethodPost, "/dashboard/api/cronjobs/other/backup/trigger", "application/json").
+			Code).To(Equal(http.StatusForbidden))
+		// an unknown CronJob is not told apart from a forbidden one
+		Expect(serveAs("bob", http.MethodPost, "/dashboard/api/cronjobs/other/missing/trigger", "application/json").
+			Code).To(Equal(http.StatusForbidden))
+		Expect(serveAs("", http.MethodPost, "/dashboard/api/cronjobs/default/report/trigger", "application/json").
+			Code).To(Equal(http.StatusUnauthorized))
+
+		var cronJob v1.CronJob
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "report"}, &cronJob)).To(Succeed())
+		Expect(cronJob.Spec.Suspend).To(BeNil())
+		Expect(serveAs("bob", http.MethodPost, "/dashboard/api/cronjobs/default/report/trigger", "application/json").
+			Code).To(Equal(http.StatusCreated))
+	})
+
+	It("Should suspend and resume the CronJob", func() {
+		Expect(serve(http.MethodPost, "/dashboard/api/cronjobs/default/report/suspend", "application/json").Code).
+			To(Equal(http.StatusOK))
+		var cronJob v1.CronJob
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "report"}, &cronJob)).To(Succeed())
+		Expect(cronJob.Spec.Suspend).To(Equal(pointer.BoolPtr(true)))
+
+		Expect(serve(http.MethodPost, "/dashboard/api/cronjobs/default/report/resume", "application/json").Code).
+			To(Equal(http.StatusOK))
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "report"}, &cronJob)).To(Succeed())
+		Expect(cronJob.Spec.Suspend).To(Equal(pointer.BoolPtr(false)))
+	})
+
+	It("Should refuse the actions not sent as JSON", func() {
+		recorder := serve(http.MethodPost, "/dashboard/api/cronjobs/default/report/trigger", "text/plain")
+		Expect(recorder.Code).To(Equal(http.StatusUnsupportedMediaType))
+	})
+
+	It("Should not find the actions on an unknown CronJob", func() {
+		recorder := serve(http.MethodPost, "/dashboard/api/cronjobs/default/missing/trigger", "application/json")
+		Expect(recorder.Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("Should serve the page of the dashboard", func() {
+		recorder := serve(http.MethodGet, "/dashboard/", "")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring("app.js"))
+	})
+})
@@ -0,0 +1,120 @@
+// The dashboard renders the overview of /dashboard/api/cronjobs, and posts the actions of its buttons next to it.
+(function () {
+  'use strict';
+
+  var api = 'api/cronjobs';
+  var day = 24 * 60 * 60 * 1000;
+
+  function headers() {
+    var h = { 'Content-Type': 'application/json' };
+    var token = sessionStorage.getItem('token');
+    if (token) {
+      h.Authorization = 'Bearer ' + token;
+    }
+    return h;
+  }
+
+  function request(method, path) {
+    return fetch(path, { method: method, headers: headers(), body: method === 'POST' ? '{}' : undefined })
+      .then(function (resp) {
+        if (!resp.ok) {
+          return resp.text().then(function (text) { throw new Error(resp.status + ': ' + text); });
+        }
+        return resp.json();
+      });
+  }
+
+  function el(tag, attrs, text) {
+    var e = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) { e.setAttribute(k, attrs[k]); });
+    if (text !== undefined) {
+      e.textContent = text;
+    }
+    return e;
+  }
+
+  function showError(err) {
+    var p = document.getElementById('error');
+    p.textContent = err ? err.message : '';
+    p.hidden = !err;
+  }
+
+  function formatTime(t) {
+    return t ? new Date(t).toLocaleString() : '-';
+  }
+
+  function renderTimeline(overview) {
+    var now = new Date(overview.now).getTime();
+    var timeline = document.getElementById('timeline');
+    timeline.textContent = '';
+    overview.cronJobs.forEach(function (cj) {
+      var row = el('div', { class: 'row' });
+      row.appendChild(el('span', { class: 'name' }, cj.namespace + '/' + cj.name));
+      var track = el('div', { class: 'track' });
+      track.appendChild(el('div', { class: 'now' }));
+      var mark = function (t, cls, title) {
+        var offset = (new Date(t).getTime() - now + day) / (2 * day);
+        if (offset >= 0 && offset <= 1) {
+          track.appendChild(el('span', { class: 'mark ' + cls, style: 'left:' + offset * 100 + '%', title: title }));
+        }
+      };
+      cj.runs.forEach(function (run) {
+        mark(run.scheduledTime, 'phase-' + (run.phase || 'Running'), run.name + ' ' + (run.phase || 'Running'));
+      });
+      cj.next.forEach(function (t) { mark(t, 'upcoming', 'next run ' + formatTime(t)); });
+      row.appendChild(track);
+      timeline.appendChild(row);
+    });
+  }
+
+  function button(label, cj, action) {
+    var b = el('button', { type: 'button' }, label);
+    b.addEventListener('click', function () {
+      request('POST', api + '/' + encodeURIComponent(cj.namespace) + '/' + encodeURIComponent(cj.name) + '/' + action)
+        .then(function () { showError(null); load(); }, showError);
+    });
+    return b;
+  }
+
+  function renderTable(overview) {
+    var body = document.getElementById('cronjobs');
+    body.textContent = '';
+    overview.cronJobs.forEach(function (cj) {
+      var tr = el('tr');
+      var last = cj.runs[0];
+      tr.appendChild(el('td', {}, cj.namespace + '/' + cj.name));
+      tr.appendChild(el('td', {}, cj.schedule + (cj.timeZone ? ' (' + cj.timeZone + ')' : '')));
+      tr.appendChild(el('td', { class: 'health-' + cj.health }, cj.health));
+      tr.appendChild(el('td', {}, String(cj.active)));
+      tr.appendChild(el('td', { class: last ? 'phase-' + last.phase : '' },
+        last ? formatTime(last.scheduledTime) + ' ' + (last.phase || 'Running') : '-'));
+      tr.appendChild(el('td', {}, cj.error || formatTime(cj.next[0])));
+      var actions = el('td');
+      actions.appendChild(button('Run now', cj, 'trigger'));
+      actions.appendChild(cj.health === 'Suspended' ? button('Resume', cj, 'resume') : button('Suspend', cj, 'suspend'));
+      tr.appendChild(actions);
+      body.appendChild(tr);
+    });
+  }
+
+  function load() {
+    var namespace = document.getElementById('namespace').value;
+    request('GET', api + (namespace ? '?namespace=' + encodeURIComponent(namespace) : ''))
+      .then(function (overview) {
+        showError(null);
+        renderTimeline(overview);
+        renderTable(overview);
+      }, showError);
+  }
+
+  document.getElementById('filters').addEventListener('submit', function (e) {
+    e.preventDefault();
+    var token = document.getElementById('token').value;
+    if (token) {
+      sessionStorage.setItem('token', token);
+    }
+    load();
+  });
+  load();
+  setInterval(load, 30000);
+})();
@@ -0,0 +1,151 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+/*
+The scheduling state answers "why didn't it fire" without adding logs and redeploying. It joins every CronJob of the
+cache, as the controller sees it, with what the last reconcile of this replica made of it: the next run, when the
+CronJob is reconciled again, the override and the maintenance window it applied and why it did not schedule. It is
+served as JSON on the metrics endpoint, to the users allowed to `get` the /debug/scheduling non-resource URL, and
+filtered with the `namespace` query parameter.
+*/
+
+// SchedulingPath is where the scheduling state is served.
+const SchedulingPath = "/debug/scheduling"
+
+// Scheduling is what the last reconcile of the CronJob controller made of a CronJob.
+type Scheduling struct {
+	// LastReconcile is when the CronJob was last reconciled.
+	LastReconcile time.Time `json:"lastReconcile"`
+	// NextRun is the next scheduled run, nil when the CronJob is not scheduled.
+	NextRun *time.Time `json:"nextRun,omitempty"`
+	// Requeue is when the CronJob is reconciled again, unless it changes before. Nil if only a change reconciles it.
+	Requeue *time.Time `json:"requeue,omitempty"`
+	// ScheduleOverride is the ScheduleOverride in effect.
+	ScheduleOverride string `json:"scheduleOverride,omitempty"`
+	// MaintenanceWindow is the open MaintenanceWindow or ClusterMaintenanceWindow, as `<kind>/<name>`.
+	MaintenanceWindow string `json:"maintenanceWindow,omitempty"`
+	// Skipped tells why the CronJob is not scheduled, e.g. `suspended`.
+	Skipped string `json:"skipped,omitempty"`
+	// Error is the error of the reconcile, which is retried with a backoff.
+	Error string `json:"error,omitempty"`
+}
+
+// SchedulingSource returns what the last reconcile made of the CronJob, false if this replica did not reconcile it.
+type SchedulingSource interface {
+	Scheduling(key types.NamespacedName) (Scheduling, bool)
+}
+
+// CronJobScheduling is the scheduling state of a CronJob.
+type CronJobScheduling struct {
+	CronJob                 string     `json:"cronJob"`
+	Schedule                string     `json:"schedule"`
+	TimeZone                string     `json:"timeZone,omitempty"`
+	Suspend                 bool       `json:"suspend"`
+	ConcurrencyPolicy       string     `json:"concurrencyPolicy,omitempty"`
+	StartingDeadlineSeconds *int64     `json:"startingDeadlineSeconds,omitempty"`
+	LastScheduleTime        *time.Time `json:"lastScheduleTime,omitempty"`
+	// Active are the names of the active Jobs.
+	Active []string `json:"active"`
+	// MissedSchedule is the reason and the message of the MissedSchedule condition, if true.
+	MissedSchedule string `json:"missedSchedule,omitempty"`
+	// Reconciler is what the last reconcile made of the CronJob, nil if this replica did not reconcile it.
+	Reconciler *Scheduling `json:"reconciler,omitempty"`
+}
+
+// SchedulingHandler serves the scheduling state of the CronJobs.
+type SchedulingHandler struct {
+	// Reader reads the CronJobs, usually the cache of the manager.
+	Reader client.Reader
+	// Source is the CronJob controller, the reconciler view is left out if nil.
+	Source SchedulingSource
+}
+
+var _ http.Handler = &SchedulingHandler{}
+
+// ServeHTTP implements http.Handler, it returns the scheduling state as JSON.
+func (h *SchedulingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	var cronJobs v1.CronJobList
+	if err := h.Reader.List(req.Context(), &cronJobs,
+		client.InNamespace(req.URL.Query().Get("namespace"))); err != nil {
+		log.Error(err, "unable to list the CronJobs")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	states := make([]CronJobScheduling, 0, len(cronJobs.Items))
+	for i := range cronJobs.Items {
+		states = append(states, h.scheduling(&cronJobs.Items[i]))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].CronJob < states[j].CronJob })
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(states)
+}
+
+// scheduling returns the scheduling state of the CronJob.
+func (h *SchedulingHandler) scheduling(cronJob *v1.CronJob) CronJobScheduling {
+	key := client.ObjectKeyFromObject(cronJob)
+	state := CronJobScheduling{
+		CronJob:                 key.String(),
+		Schedule:                cronJob.Spec.Schedule,
+		Suspend:                 cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend,
+		ConcurrencyPolicy:       string(cronJob.Spec.ConcurrencyPolicy),
+		StartingDeadlineSeconds: cronJob.Spec.StartingDeadlineSeconds,
+		Active:                  []string{},
+	}
+	if cronJob.Spec.TimeZone != nil {
+		state.TimeZone = *cronJob.Spec.TimeZone
+	}
+	if lastScheduleTime := cronJob.Status.LastScheduleTime; lastScheduleTime != nil {
+		state.LastScheduleTime = &lastScheduleTime.Time
+	}
+	for _, active := range cronJob.Status.Active {
+		state.Active = append(state.Active, active.Name)
+	}
+	if condition := meta.FindStatusCondition(cronJob.Status.Conditions, "MissedSchedule"); condition != nil &&
+		condition.Status == metav1.ConditionTrue {
+		state.MissedSchedule = condition.Reason + ": " + condition.Message
+	}
+	if h.Source != nil {
+		if scheduling, ok := h.Source.Scheduling(key); ok {
+			state.Reconciler = &scheduling
+		}
+	}
+	return state
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type staticScheduling map[types.NamespacedName]Scheduling
+
+func (s staticScheduling) Scheduling(key types.NamespacedName) (Scheduling, bool) {
+	scheduling, ok := s[key]
+	return scheduling, ok
+}
+
+var _ = Describe("Scheduling state", func() {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	var handler *SchedulingHandler
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		late := &v1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "late"},
+			Spec:       v1.CronJobSpec{Schedule: "*/5 * * * *", ConcurrencyPolicy: v1.ForbidConcurrent},
+			Status: v1.CronJobStatus{
+				Active: []corev1.ObjectReference{{Name: "late-1622548500"}},
+				Conditions: []metav1.Condition{{Type: "MissedSchedule", Status: metav1.ConditionTrue,
+					Reason: "ConcurrencyPolicy", Message: "a Job is still active"}},
+			},
+		}
+		other := &v1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "other"},
+			Spec:       v1.CronJobSpec{Schedule: "@daily"},
+		}
+		nextRun, requeue := now.Add(5*time.Minute), now.Add(5*time.Minute+time.Second)
+		handler = &SchedulingHandler{
+			Reader: fake.NewClientBuilder().WithScheme(scheme).WithObjects(late, other).Build(),
+			Source: staticScheduling{{Namespace: "team-a", Name: "late"}: {LastReconcile: now, NextRun: &nextRun,
+				Requeue: &requeue, MaintenanceWindow: "MaintenanceWindow/freeze"}},
+		}
+	})
+
+	It("Should join the cached CronJobs with the view of the reconciler", func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, SchedulingPath, nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		var states []CronJobScheduling
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &states)).To(Succeed())
+		Expect(states).To(HaveLen(2))
+		Expect(states[0].CronJob).To(Equal("team-a/late"))
+		Expect(states[0].Active).To(Equal([]string{"late-1622548500"}))
+		Expect(states[0].ConcurrencyPolicy).To(Equal("Forbid"))
+		Expect(states[0].MissedSchedule).To(Equal("ConcurrencyPolicy: a Job is still active"))
+		Expect(states[0].Reconciler.NextRun.Equal(now.Add(5 * time.Minute))).To(BeTrue())
+		Expect(states[0].Reconciler.MaintenanceWindow).To(Equal("MaintenanceWindow/freeze"))
+		Expect(states[1].CronJob).To(Equal("team-b/other"))
+		Expect(states[1].Active).To(BeEmpty())
+		Expect(states[1].Reconciler).To(BeNil())
+	})
+
+	It("Should filter the CronJobs by namespace", func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, SchedulingPath+"?namespace=team-b", nil))
+		var states []CronJobScheduling
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &states)).To(Succeed())
+		Expect(states).To(HaveLen(1))
+		Expect(states[0].CronJob).To(Equal("team-b/other"))
+	})
+
+	It("Should only serve GET", func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, SchedulingPath, nil))
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,164 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diagnostics dumps the internal view of the manager, for the incidents where the logs are not enough.
+package diagnostics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+)
+
+/*
+The state is served as JSON on the metrics endpoint, by the users allowed to `get` the /debug/state non-resource URL,
+and logged when the manager receives SIGUSR1, e.g. with `kubectl exec ... -- kill -USR1 1` on images with a shell.
+It holds the depth of the work queues, when every CronJob is due next and whether this replica holds the lease. The
+controller keeps no expectations, it lists the Jobs of a CronJob on every reconcile, so there is no expectation
+table to dump.
+*/
+
+var log = logf.Log.WithName("diagnostics")
+
+// Path is where the state is served.
+const Path = "/debug/state"
+
+// State is the internal view of the manager.
+type State struct {
+	Time    time.Time `json:"time"`
+	Version string    `json:"version"`
+	// Leader tells whether this replica holds the leader election lease, only the leader reconciles.
+	Leader bool `json:"leader"`
+	// QueueDepths is the number of the requests waiting in the work queue of every controller.
+	QueueDepths map[string]float64 `json:"queueDepths"`
+	// Wakeups is when every CronJob is due next, the earliest first.
+	Wakeups []Wakeup `json:"wakeups"`
+}
+
+// Wakeup is the next scheduled run of a CronJob.
+type Wakeup struct {
+	CronJob string    `json:"cronJob"`
+	NextRun time.Time `json:"nextRun"`
+}
+
+// WakeupSource returns the next scheduled runs of the CronJobs.
+type WakeupSource interface {
+	Wakeups() []Wakeup
+}
+
+// Dumper builds the state of the manager, serves it over HTTP and logs it on SIGUSR1.
+type Dumper struct {
+	// IsLeader tells whether this replica holds the lease.
+	IsLeader func() bool
+	// Wakeups are the next runs of the CronJobs, nil if the CronJob controller is disabled.
+	Wakeups WakeupSource
+	// Gatherer holds the metrics of the work queues, usually the registry of controller-runtime.
+	Gatherer prometheus.Gatherer
+}
+
+var _ http.Handler = &Dumper{}
+var _ manager.Runnable = &Dumper{}
+var _ manager.LeaderElectionRunnable = &Dumper{}
+
+// State returns the current state of the manager.
+func (d *Dumper) State() (*State, error) {
+	state := &State{
+		Time:        time.Now(),
+		Version:     version.Get().Version,
+		Leader:      d.IsLeader(),
+		QueueDepths: map[string]float64{},
+	}
+	if d.Wakeups != nil {
+		state.Wakeups = d.Wakeups.Wakeups()
+	}
+	sort.Slice(state.Wakeups, func(i, j int) bool {
+		return state.Wakeups[i].NextRun.Before(state.Wakeups[j].NextRun)
+	})
+
+	families, err := d.Gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	for _, family := range families {
+		if family.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" {
+					state.QueueDepths[label.GetValue()] = metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return state, nil
+}
+
+// ServeHTTP implements http.Handler, it returns the state as JSON.
+func (d *Dumper) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	state, err := d.State()
+	if err != nil {
+		log.Error(err, "unable to build the state")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(state)
+}
+
+// Start implements manager.Runnable, it logs the state on every SIGUSR1 until the context is done.
+func (d *Dumper) Start(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-signals:
+			state, err := d.State()
+			if err != nil {
+				log.Error(err, "unable to build the state")
+				continue
+			}
+			log.Info("state dump", "leader", state.Leader, "queueDepths", state.QueueDepths, "wakeups", state.Wakeups)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the standbys can be inspected too.
+func (d *Dumper) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type staticWakeups []Wakeup
+
+func (w staticWakeups) Wakeups() []Wakeup {
+	return w
+}
+
+var _ = Describe("State dump", func() {
+	var dumper *Dumper
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		registry := prometheus.NewRegistry()
+		depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workqueue_depth"}, []string{"name"})
+		registry.MustRegister(depth)
+		depth.WithLabelValues("cronjob").Set(3)
+
+		dumper = &Dumper{
+			IsLeader: func() bool { return true },
+			Wakeups: staticWakeups{
+				{CronJob: "default/late", NextRun: now.Add(time.Hour)},
+				{CronJob: "default/early", NextRun: now.Add(time.Minute)},
+			},
+			Gatherer: registry,
+		}
+	})
+
+	It("Should collect the queue depths and the wakeups, the earliest first", func() {
+		state, err := dumper.State()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Leader).To(BeTrue())
+		Expect(state.QueueDepths).To(Equal(map[string]float64{"cronjob": 3}))
+		Expect(state.Wakeups).To(HaveLen(2))
+		Expect(state.Wakeups[0].CronJob).To(Equal("default/early"))
+	})
+
+	It("Should serve the state as JSON", func() {
+		recorder := httptest.NewRecorder()
+		dumper.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		state := &State{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), state)).To(Succeed())
+		Expect(state.QueueDepths["cronjob"]).To(Equal(3.0))
+
+		recorder = httptest.NewRecorder()
+		dumper.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, Path, nil))
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
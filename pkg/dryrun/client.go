@@ -0,0 +1,150 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dryrun turns the writes of the clients into dry runs, so the manager can run against production data
+// without changing it.
+package dryrun
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+/*
+Every write is sent with `dryRun=All`: the API server runs the admission chain and the validation, and answers as if
+the write happened, without persisting it. The controllers and webhooks therefore exercise their whole logic without
+any dedicated support. The reviews of the authentication and authorization groups are queries rather than writes,
+they are sent as they are.
+*/
+
+var log = logf.Log.WithName("dry-run")
+
+// queryGroups are the groups whose objects are created to ask a question, like TokenReviews.
+var queryGroups = map[string]bool{
+	"authentication.k8s.io": true,
+	"authorization.k8s.io":  true,
+}
+
+// NewClientBuilder returns a builder of the manager client which builds dry-run clients with the given builder.
+func NewClientBuilder(builder cluster.ClientBuilder) cluster.ClientBuilder {
+	return &clientBuilder{ClientBuilder: builder}
+}
+
+type clientBuilder struct {
+	cluster.ClientBuilder
+}
+
+// WithUncached implements cluster.ClientBuilder
+func (b *clientBuilder) WithUncached(objs ...client.Object) cluster.ClientBuilder {
+	b.ClientBuilder = b.ClientBuilder.WithUncached(objs...)
+	return b
+}
+
+// Build implements cluster.ClientBuilder
+func (b *clientBuilder) Build(cache cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := b.ClientBuilder.Build(cache, config, options)
+	if err != nil {
+		return nil, err
+	}
+	return WrapClient(c), nil
+}
+
+// WrapClient returns a client which sends the writes of the given client as dry runs.
+func WrapClient(c client.Client) client.Client {
+	return &dryRunClient{Client: c}
+}
+
+type dryRunClient struct {
+	client.Client
+}
+
+// Create implements client.Client
+func (c *dryRunClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if c.isQuery(obj) {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+	c.logWrite("create", obj)
+	return c.Client.Create(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Update implements client.Client
+func (c *dryRunClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.logWrite("update", obj)
+	return c.Client.Update(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Patch implements client.Client
+func (c *dryRunClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.logWrite("patch", obj)
+	return c.Client.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+}
+
+// Delete implements client.Client
+func (c *dryRunClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.logWrite("delete", obj)
+	return c.Client.Delete(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// DeleteAllOf implements client.Client
+func (c *dryRunClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	c.logWrite("delete all of", obj)
+	return c.Client.DeleteAllOf(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Status implements client.Client
+func (c *dryRunClient) Status() client.StatusWriter {
+	return &dryRunStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type dryRunStatusWriter struct {
+	client.StatusWriter
+	client *dryRunClient
+}
+
+// Update implements client.StatusWriter
+func (w *dryRunStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	w.client.logWrite("update status", obj)
+	return w.StatusWriter.Update(ctx, obj, append(opts, client.DryRunAll)...)
+}
+
+// Patch implements client.StatusWriter
+func (w *dryRunStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.client.logWrite("patch status", obj)
+	return w.StatusWriter.Patch(ctx, obj, patch, append(opts, client.DryRunAll)...)
+}
+
+func (c *dryRunClient) gvk(obj client.Object) schema.GroupVersionKind {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return schema.GroupVersionKind{}
+	}
+	return gvk
+}
+
+func (c *dryRunClient) isQuery(obj client.Object) bool {
+	return queryGroups[c.gvk(obj).Group]
+}
+
+func (c *dryRunClient) logWrite(verb string, obj client.Object) {
+	log.Info("dry run "+verb, "kind", c.gvk(obj).Kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("WrapClient", func() {
+	var (
+		ctx        context.Context
+		underlying client.Client
+		dryRun     client.Client
+		existing   *corev1.ConfigMap
+		key        types.NamespacedName
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		existing = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "existing"},
+			Data:       map[string]string{"key": "value"},
+		}
+		key = client.ObjectKeyFromObject(existing)
+		underlying = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(existing).Build()
+		dryRun = WrapClient(underlying)
+	})
+
+	It("should not persist the creates", func() {
+		created := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "created"}}
+		Expect(dryRun.Create(ctx, created)).To(Succeed())
+
+		err := underlying.Get(ctx, client.ObjectKeyFromObject(created), &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should not persist the updates and the patches", func() {
+		updated := &corev1.ConfigMap{}
+		Expect(dryRun.Get(ctx, key, updated)).To(Succeed())
+		updated.Data["key"] = "updated"
+		Expect(dryRun.Update(ctx, updated)).To(Succeed())
+
+		patched := &corev1.ConfigMap{}
+		Expect(dryRun.Get(ctx, key, patched)).To(Succeed())
+		patch := client.MergeFrom(patched.DeepCopy())
+		patched.Data["key"] = "patched"
+		Expect(dryRun.Patch(ctx, patched, patch)).To(Succeed())
+
+		current := &corev1.ConfigMap{}
+		Expect(underlying.Get(ctx, key, current)).To(Succeed())
+		Expect(current.Data).To(HaveKeyWithValue("key", "value"))
+	})
+
+	It("should not persist the status updates", func() {
+		updated := &corev1.ConfigMap{}
+		Expect(dryRun.Get(ctx, key, updated)).To(Succeed())
+		updated.Data["key"] = "updated"
+		Expect(dryRun.Status().Update(ctx, updated)).To(Succeed())
+
+		current := &corev1.ConfigMap{}
+		Expect(underlying.Get(ctx, key, current)).To(Succeed())
+		Expect(current.Data).To(HaveKeyWithValue("key", "value"))
+	})
+
+	It("should send the reviews as they are", func() {
+		review := &authenticationv1.TokenReview{ObjectMeta: metav1.ObjectMeta{Name: "review"}}
+		Expect(dryRun.Create(ctx, review)).To(Succeed())
+
+		Expect(underlying.Get(ctx, client.ObjectKeyFromObject(review), &authenticationv1.TokenReview{})).To(Succeed())
+	})
+})
@@ -0,0 +1,107 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errorreporting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2/google"
+)
+
+/*
+Cloud Error Reporting groups the events by their stack trace, which it parses from the message in the format of the Go
+panics. The reconcile errors have no stack trace, they carry the location they were reported from instead.
+*/
+
+const cloudErrorReportingURL = "https://clouderrorreporting.googleapis.com/v1beta1/projects/%s/events:report"
+
+// cloudErrorReportingSender sends the events to the Error Reporting of a Google Cloud project.
+type cloudErrorReportingSender struct {
+	client   *http.Client
+	eventURL string
+}
+
+func newCloudErrorReportingSender(ctx context.Context, project string) (*cloudErrorReportingSender, error) {
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil, fmt.Errorf("unable to find the Google Cloud credentials: %w", err)
+	}
+	return &cloudErrorReportingSender{
+		client:   client,
+		eventURL: fmt.Sprintf(cloudErrorReportingURL, url.PathEscape(project)),
+	}, nil
+}
+
+// The subset of the ReportedErrorEvent of Cloud Error Reporting which is sent.
+type (
+	cloudErrorEvent struct {
+		EventTime      string              `json:"eventTime"`
+		ServiceContext cloudServiceContext `json:"serviceContext"`
+		Message        string              `json:"message"`
+		Context        *cloudErrorContext  `json:"context,omitempty"`
+	}
+	cloudServiceContext struct {
+		Service string `json:"service"`
+		Version string `json:"version,omitempty"`
+	}
+	cloudErrorContext struct {
+		ReportLocation cloudSourceLocation `json:"reportLocation"`
+	}
+	cloudSourceLocation struct {
+		FilePath     string `json:"filePath"`
+		LineNumber   int    `json:"lineNumber"`
+		FunctionName string `json:"functionName"`
+	}
+)
+
+func (s *cloudErrorReportingSender) send(ctx context.Context, event Event) error {
+	payload := cloudErrorEvent{
+		EventTime:      event.Time.UTC().Format(time.RFC3339Nano),
+		ServiceContext: cloudServiceContext{Service: serviceName, Version: event.Version},
+		Message:        event.title(),
+	}
+	if event.ClusterName != "" {
+		payload.ServiceContext.Service += "-" + event.ClusterName
+	}
+	if event.Panic() {
+		// the message has to start like the output of a crash to be parsed, debug.Stack() starts with the goroutine
+		payload.Message = fmt.Sprintf("%s [%s]\n\n%s", event.Message, event.identity(), event.Stack)
+	} else {
+		payload.Context = &cloudErrorContext{ReportLocation: cloudSourceLocation{
+			FilePath:     event.Location.File,
+			LineNumber:   event.Location.Line,
+			FunctionName: event.Location.Function,
+		}}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.eventURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return post(s.client, req)
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package errorreporting reports the panics and the repeated reconcile errors of the operator to an error tracking
+// service, Sentry or Google Cloud Error Reporting. A rare panic in a webhook is easily missed in the logs, the error
+// tracking services group the errors and alert on the new ones.
+package errorreporting
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+)
+
+/*
+The panics are reported before they are resumed, and waited for, since a panic in a reconcile crashes the manager. The
+reconcile errors are only reported once a CronJob failed several reconciles in a row, the occasional conflict or
+timeout is retried by the work queue anyway. They are sent in the background, so the reconciles are not slowed down
+by the error tracking service. The reports are rate limited, so a webhook panicking on every request does not flood
+the service. A nil ErrorReporter reports nothing, so the components call it unconditionally.
+*/
+
+var log = logf.Log.WithName("error-reporting")
+
+const (
+	serviceName = "kubebuilder-tutorial"
+
+	defaultReconcileErrorThreshold = 5
+	sendTimeout                    = 5 * time.Second
+	shutdownTimeout                = 5 * time.Second
+)
+
+// Event is an error reported to the error tracking service.
+type Event struct {
+	// Time is when the error occurred.
+	Time time.Time
+	// Component is the controller or the webhook the error occurred in.
+	Component string
+	// Object is the CronJob which was reconciled or admitted.
+	Object types.NamespacedName
+	// Message describes the error, it is prefixed with `panic: ` for the panics.
+	Message string
+	// Stack is the stack trace of the panics.
+	Stack []byte
+	// Location is where the error was reported, for the errors without a stack trace.
+	Location runtime.Frame
+	// ClusterName is the name of the cluster the operator runs in, if configured.
+	ClusterName string
+	// Version is the version of the operator.
+	Version string
+}
+
+// Panic returns whether the event is a panic.
+func (e Event) Panic() bool {
+	return len(e.Stack) > 0
+}
+
+// sender sends the events to an error tracking service.
+type sender interface {
+	send(ctx context.Context, event Event) error
+}
+
+// ErrorReporter reports the errors to the error tracking service of the config.
+type ErrorReporter struct {
+	sender      sender
+	clusterName string
+	threshold   int
+	limiter     *rate.Limiter
+
+	lock     sync.Mutex
+	failures map[reconcileKey]int
+	pending  sync.WaitGroup
+}
+
+var _ manager.Runnable = &ErrorReporter{}
+var _ manager.LeaderElectionRunnable = &ErrorReporter{}
+
+// reconcileKey identifies the reconciles of an object by a component.
+type reconcileKey struct {
+	component string
+	object    types.NamespacedName
+}
+
+// Setup returns the reporter sending the errors to the service of the config. It returns nil if no service is
+// configured.
+func Setup(ctx context.Context, config configv1.ErrorReportingConfig, clusterName string) (*ErrorReporter, error) {
+	var s sender
+	var err error
+	switch {
+	case config.SentryDSN != "" && config.GoogleCloudProject != "":
+		return nil, fmt.Errorf("only one of sentryDSN and googleCloudProject can be set")
+	case config.SentryDSN != "":
+		s, err = newSentrySender(config.SentryDSN)
+	case config.GoogleCloudProject != "":
+		s, err = newCloudErrorReportingSender(ctx, config.GoogleCloudProject)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := config.ReconcileErrorThreshold
+	if threshold <= 0 {
+		threshold = defaultReconcileErrorThreshold
+	}
+	log.Info("reporting errors", "sentry", config.SentryDSN != "", "googleCloudProject", config.GoogleCloudProject,
+		"reconcileErrorThreshold", threshold)
+	return newErrorReporter(s, clusterName, threshold), nil
+}
+
+func newErrorReporter(s sender, clusterName string, threshold int) *ErrorReporter {
+	return &ErrorReporter{
+		sender:      s,
+		clusterName: clusterName,
+		threshold:   threshold,
+		limiter:     rate.NewLimiter(rate.Every(time.Minute/10), 10),
+		failures:    map[reconcileKey]int{},
+	}
+}
+
+// Recover reports the panic in progress, if any, and resumes it. It must be deferred by the function whose panics
+// are reported.
+func (r *ErrorReporter) Recover(component string, object types.NamespacedName) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if r != nil {
+		r.report(Event{
+			Time:      time.Now(),
+			Component: component,
+			Object:    object,
+			Message:   fmt.Sprintf("panic: %v", p),
+			Stack:     debug.Stack(),
+		}, true)
+	}
+	panic(p)
+}
+
+// ReconcileResult records the result of a reconcile of the object, the error is reported once the object failed
+// the threshold of reconciles in a row.
+func (r *ErrorReporter) ReconcileResult(component string, object types.NamespacedName, err error) {
+	if r == nil {
+		return
+	}
+
+	key := reconcileKey{component: component, object: object}
+	r.lock.Lock()
+	if err == nil {
+		delete(r.failures, key)
+		r.lock.Unlock()
+		return
+	}
+	r.failures[key]++
+	failures := r.failures[key]
+	r.lock.Unlock()
+	if failures != r.threshold {
+		return
+	}
+
+	event := Event{
+		Time:      time.Now(),
+		Component: component,
+		Object:    object,
+		Message:   fmt.Sprintf("%d reconciles in a row failed: %v", failures, err),
+	}
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		event.Location, _ = runtime.CallersFrames([]uintptr{pc}).Next()
+	}
+	r.report(event, false)
+}
+
+// report sends the event, waiting for it to be sent if wait is set.
+func (r *ErrorReporter) report(event Event, wait bool) {
+	if !r.limiter.Allow() {
+		log.Info("dropped an error report over the rate limit", "component", event.Component,
+			"namespace", event.Object.Namespace, "name", event.Object.Name)
+		return
+	}
+	event.ClusterName = r.clusterName
+	event.Version = version.Get().Version
+
+	r.pending.Add(1)
+	send := func() {
+		defer r.pending.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		defer cancel()
+		if err := r.sender.send(ctx, event); err != nil {
+			log.Error(err, "unable to report an error", "component", event.Component,
+				"namespace", event.Object.Namespace, "name", event.Object.Name)
+		}
+	}
+	if wait {
+		send()
+	} else {
+		go send()
+	}
+}
+
+// Start implements manager.Runnable, it waits for the reports in flight when the manager stops.
+func (r *ErrorReporter) Start(ctx context.Context) error {
+	<-ctx.Done()
+	done := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		log.Info("gave up waiting for the error reports in flight")
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the standbys serve webhooks too.
+func (r *ErrorReporter) NeedLeaderElection() bool {
+	return false
+}
+
+// identity returns the component and the object the error occurred in.
+func (e Event) identity() string {
+	if e.Object.Name == "" {
+		return e.Component
+	}
+	return e.Component + " " + e.Object.String()
+}
+
+// title returns the first line of the reported error, with the identity of the object.
+func (e Event) title() string {
+	return e.identity() + ": " + e.Message
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errorreporting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+)
+
+// recordingSender keeps the events it sends.
+type recordingSender struct {
+	lock   sync.Mutex
+	events []Event
+}
+
+func (s *recordingSender) send(_ context.Context, event Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSender) sent() []Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Event{}, s.events...)
+}
+
+// request is a request received by the error tracking service.
+type request struct {
+	path   string
+	header http.Header
+	body   map[string]interface{}
+}
+
+// startService starts a fake error tracking service, which records the requests it receives.
+func startService() (*httptest.Server, <-chan request) {
+	requests := make(chan request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		data, err := ioutil.ReadAll(r.Body)
+		Expect(err).NotTo(HaveOccurred())
+		body := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &body)).To(Succeed())
+		requests <- request{path: r.URL.Path, header: r.Header, body: body}
+	}))
+	return server, requests
+}
+
+var cronJob = types.NamespacedName{Namespace: "default", Name: "cronjob"}
+
+var _ = Describe("Error reporting", func() {
+	It("Should not report without a service", func() {
+		reporter, err := Setup(context.Background(), configv1.ErrorReportingConfig{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reporter).To(BeNil())
+
+		reporter.ReconcileResult("cronjob-controller", cronJob, errors.New("boom"))
+		Expect(func() {
+			defer reporter.Recover("cronjob-controller", cronJob)
+			panic("boom")
+		}).To(PanicWith("boom"))
+	})
+
+	It("Should report the panics and resume them", func() {
+		sender := &recordingSender{}
+		reporter := newErrorReporter(sender, "prod-eu", 5)
+		Expect(func() {
+			defer reporter.Recover("webhook validating", cronJob)
+			panic("boom")
+		}).To(PanicWith("boom"))
+
+		// the panics are sent before they are resumed
+		events := sender.sent()
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Panic()).To(BeTrue())
+		Expect(events[0].title()).To(Equal("webhook validating default/cronjob: panic: boom"))
+		Expect(string(events[0].Stack)).To(HavePrefix("goroutine "))
+		Expect(events[0].ClusterName).To(Equal("prod-eu"))
+
+		Expect(func() {
+			defer reporter.Recover("webhook validating", cronJob)
+		}).NotTo(Panic())
+		Expect(sender.sent()).To(HaveLen(1))
+	})
+
+	It("Should report the reconcile errors once they repeat", func() {
+		sender := &recordingSender{}
+		reporter := newErrorReporter(sender, "", 3)
+		other := types.NamespacedName{Namespace: "default", Name: "other"}
+		for i := 0; i < 2; i++ {
+			reporter.ReconcileResult("cronjob-controller", cronJob, errors.New("conflict"))
+			reporter.ReconcileResult("cronjob-controller", other, errors.New("conflict"))
+		}
+		reporter.ReconcileResult("cronjob-controller", other, nil)
+		reporter.ReconcileResult("cronjob-controller", other, errors.New("conflict"))
+		Consistently(sender.sent).Should(BeEmpty())
+
+		reporter.ReconcileResult("cronjob-controller", cronJob, errors.New("conflict"))
+		Eventually(sender.sent).Should(HaveLen(1))
+		event := sender.sent()[0]
+		Expect(event.Panic()).To(BeFalse())
+		Expect(event.title()).To(Equal("cronjob-controller default/cronjob: 3 reconciles in a row failed: conflict"))
+		Expect(event.Location.File).To(HaveSuffix("errorreporting_test.go"))
+
+		// reported once per series of errors
+		reporter.ReconcileResult("cronjob-controller", cronJob, errors.New("conflict"))
+		Consistently(sender.sent).Should(HaveLen(1))
+	})
+
+	It("Should drop the reports over the rate limit", func() {
+		sender := &recordingSender{}
+		reporter := newErrorReporter(sender, "", 1)
+		for i := 0; i < 20; i++ {
+			Expect(func() {
+				defer reporter.Recover("webhook validating", cronJob)
+				panic("boom")
+			}).To(Panic())
+		}
+		Expect(sender.sent()).To(HaveLen(10))
+	})
+
+	It("Should send the events to Sentry", func() {
+		service, requests := startService()
+		defer service.Close()
+
+		dsn := strings.Replace(service.URL, "://", "://public@", 1) + "/42"
+		reporter, err := Setup(context.Background(), configv1.ErrorReportingConfig{SentryDSN: dsn}, "prod-eu")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(func() {
+			defer reporter.Recover("webhook validating", cronJob)
+			panic("boom")
+		}).To(Panic())
+
+		var req request
+		Expect(requests).To(Receive(&req))
+		Expect(req.path).To(Equal("/api/42/store/"))
+		Expect(req.header.Get("X-Sentry-Auth")).To(ContainSubstring("sentry_key=public"))
+		Expect(req.body).To(HaveKeyWithValue("level", "fatal"))
+		Expect(req.body).To(HaveKeyWithValue("environment", "prod-eu"))
+		Expect(req.body).To(HaveKeyWithValue("message", "webhook validating default/cronjob: panic: boom"))
+		Expect(req.body).To(HaveKeyWithValue("tags", map[string]interface{}{
+			"component": "webhook validating",
+			"namespace": "default",
+			"name":      "cronjob",
+		}))
+		Expect(req.body["event_id"]).To(HaveLen(32))
+	})
+
+	It("Should reject the invalid Sentry DSNs", func() {
+		_, err := newSentrySender("https://o0.ingest.sentry.io/42")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should send the events to Cloud Error Reporting", func() {
+		service, requests := startService()
+		defer service.Close()
+
+		sender := &cloudErrorReportingSender{client: http.DefaultClient, eventURL: service.URL + "/events:report"}
+		reporter := newErrorReporter(sender, "", 1)
+		Expect(func() {
+			defer reporter.Recover("cronjob-controller", cronJob)
+			panic("boom")
+		}).To(Panic())
+
+		var req request
+		Expect(requests).To(Receive(&req))
+		Expect(req.path).To(Equal("/events:report"))
+		Expect(req.body["message"]).To(HavePrefix("panic: boom [cronjob-controller default/cronjob]\n\ngoroutine "))
+		Expect(req.body).NotTo(HaveKey("context"))
+
+		reporter.ReconcileResult("cronjob-controller", cronJob, errors.New("conflict"))
+		Eventually(requests).Should(Receive(&req))
+		Expect(req.body["message"]).To(Equal("cronjob-controller default/cronjob: 1 reconciles in a row failed: conflict"))
+		Expect(req.body["context"]).To(HaveKeyWithValue("reportLocation", HaveKeyWithValue("lineNumber", BeNumerically(">", 0))))
+	})
+})
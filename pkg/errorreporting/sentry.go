@@ -0,0 +1,134 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errorreporting
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+The events are posted to the store endpoint of Sentry, which takes a single JSON event and needs nothing more than the
+DSN, so the operator does without the SDK of Sentry. The stack trace of a panic is attached as is, Sentry groups the
+events by their message and tags.
+*/
+
+// sentrySender sends the events to the project of a Sentry DSN.
+type sentrySender struct {
+	client    *http.Client
+	storeURL  string
+	publicKey string
+}
+
+func newSentrySender(dsn string) (*sentrySender, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	projectID := strings.Trim(u.Path, "/")
+	if (u.Scheme != "https" && u.Scheme != "http") || u.User == nil || u.User.Username() == "" || projectID == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN, must be like https://<public key>@<host>/<project ID>")
+	}
+	i := strings.LastIndex(projectID, "/")
+	storeURL := fmt.Sprintf("%s://%s/%sapi/%s/store/", u.Scheme, u.Host, projectID[:i+1], projectID[i+1:])
+	return &sentrySender{client: http.DefaultClient, storeURL: storeURL, publicKey: u.User.Username()}, nil
+}
+
+// sentryEvent is the subset of the event payload of Sentry which is sent.
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Logger      string            `json:"logger"`
+	Message     string            `json:"message"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+func (s *sentrySender) send(ctx context.Context, event Event) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	payload := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   event.Time.UTC().Format(time.RFC3339),
+		Platform:    "go",
+		Level:       "error",
+		Logger:      serviceName,
+		Message:     event.title(),
+		Release:     event.Version,
+		Environment: event.ClusterName,
+		Tags:        map[string]string{"component": event.Component},
+	}
+	payload.ServerName, _ = os.Hostname()
+	if event.Panic() {
+		payload.Level = "fatal"
+		payload.Extra = map[string]string{"stack": string(event.Stack)}
+	} else if event.Location.Function != "" {
+		payload.Extra = map[string]string{
+			"location": fmt.Sprintf("%s (%s:%d)", event.Location.Function, event.Location.File, event.Location.Line),
+		}
+	}
+	if event.Object.Name != "" {
+		payload.Tags["namespace"] = event.Object.Namespace
+		payload.Tags["name"] = event.Object.Name
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s/%s, sentry_key=%s",
+		serviceName, event.Version, s.publicKey))
+	return post(s.client, req)
+}
+
+// post sends the request and checks its status.
+func post(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
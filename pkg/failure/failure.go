@@ -0,0 +1,93 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package failure classifies the errors of the reconciles into a few reasons, so that they can be counted and shown in
+// the conditions of the objects instead of only being logged as free text.
+package failure
+
+import (
+	"errors"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+/*
+The reconciles mostly return the errors of the API server as they are, so most of them are classified from their
+status: the throttling, the ResourceQuotas denying a Job, the invalid Jobs and the kinds whose CRD is not installed
+yet. The errors the API server does not see, like a Job template which can not be turned into a Job, are wrapped with
+their reason by the reconciles through New.
+*/
+
+// Reason tells why a reconcile failed. It is meant for the reason of a condition, so it is in CamelCase.
+type Reason string
+
+const (
+	// TemplateInvalid is a spec which can not be turned into a run, e.g. an invalid Job template or schedule.
+	TemplateInvalid Reason = "TemplateInvalid"
+	// QuotaExceeded is a run denied by a ResourceQuota of its namespace.
+	QuotaExceeded Reason = "QuotaExceeded"
+	// APIThrottled is a request the API server asked to retry later.
+	APIThrottled Reason = "APIThrottled"
+	// DependencyNotReady is an object depending on something missing, e.g. the CRD of the runner of a CronJob.
+	DependencyNotReady Reason = "DependencyNotReady"
+	// Unknown is any other error.
+	Unknown Reason = "Unknown"
+)
+
+// Reasons lists the reasons, Unknown last.
+var Reasons = []Reason{TemplateInvalid, QuotaExceeded, APIThrottled, DependencyNotReady, Unknown}
+
+// Error is an error with its reason.
+type Error struct {
+	Reason Reason
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New wraps the error with its reason, nil stays nil.
+func New(reason Reason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Reason: reason, Err: err}
+}
+
+// ReasonOf returns the reason of the error: the one it was wrapped with, otherwise the one its API status tells.
+func ReasonOf(err error) Reason {
+	var failure *Error
+	switch {
+	case errors.As(err, &failure):
+		return failure.Reason
+	case apierrors.IsTooManyRequests(err), apierrors.IsServerTimeout(err):
+		return APIThrottled
+	case apierrors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota"):
+		return QuotaExceeded
+	case apierrors.IsInvalid(err):
+		return TemplateInvalid
+	case meta.IsNoMatchError(err):
+		return DependencyNotReady
+	}
+	return Unknown
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failure
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var _ = Describe("Reason", func() {
+	jobs := schema.GroupResource{Group: "batch", Resource: "jobs"}
+
+	It("Should keep the reason the error was wrapped with", func() {
+		err := fmt.Errorf("unable to construct the Job: %w", New(TemplateInvalid, errors.New("bad template")))
+		Expect(ReasonOf(err)).To(Equal(TemplateInvalid))
+		Expect(err).To(MatchError("unable to construct the Job: bad template"))
+		Expect(New(TemplateInvalid, nil)).To(BeNil())
+	})
+
+	It("Should classify the errors of the API server", func() {
+		Expect(ReasonOf(apierrors.NewTooManyRequests("slow down", 1))).To(Equal(APIThrottled))
+		Expect(ReasonOf(apierrors.NewServerTimeout(jobs, "create", 1))).To(Equal(APIThrottled))
+		Expect(ReasonOf(apierrors.NewForbidden(jobs, "nightly-1", errors.New(
+			"exceeded quota: compute, requested: cpu=2, used: cpu=9, limited: cpu=10")))).To(Equal(QuotaExceeded))
+		Expect(ReasonOf(apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, "nightly-1",
+			field.ErrorList{field.Required(field.NewPath("spec", "template"), "")}))).To(Equal(TemplateInvalid))
+		Expect(ReasonOf(&meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "argoproj.io",
+			Kind: "Workflow"}})).To(Equal(DependencyNotReady))
+	})
+
+	It("Should not classify the other errors", func() {
+		Expect(ReasonOf(apierrors.NewForbidden(jobs, "nightly-1", errors.New("RBAC denied")))).To(Equal(Unknown))
+		Expect(ReasonOf(errors.New("boom"))).To(Equal(Unknown))
+	})
+})
@@ -0,0 +1,85 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package featuregates holds the feature gates of the operator. The experimental capabilities are merged disabled by
+// default behind a gate, and enabled per cluster with the --feature-gates flag or the featureGates of the config file.
+package featuregates
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/component-base/featuregate"
+)
+
+/*
+Every gate goes through the usual stages of Kubernetes: an Alpha gate is disabled by default and may change or go
+away, a Beta gate is enabled by default, and a GA gate is always enabled and removed a few releases later, together
+with the code paths of the disabled gate.
+*/
+
+const (
+	// CronJobTimeZone enables spec.timeZone of the CronJobs and the defaultTimeZone of the admission settings.
+	// Without it, the schedules follow the time zone of the controller.
+	CronJobTimeZone featuregate.Feature = "CronJobTimeZone"
+
+	// ArgoWorkflowRunner enables the ArgoWorkflow runner of the CronJobs, submitting Argo Workflows instead of Jobs.
+	// It requires the CRDs of Argo Workflows.
+	ArgoWorkflowRunner featuregate.Feature = "ArgoWorkflowRunner"
+
+	// TektonPipelineRunner enables the TektonPipeline runner of the CronJobs, creating Tekton PipelineRuns instead of
+	// Jobs. It requires the CRDs of Tekton Pipelines.
+	TektonPipelineRunner featuregate.Feature = "TektonPipelineRunner"
+
+	// MetadataOnlyJobs caches the metadata of the Jobs only, without their pod templates, and reads their status from
+	// the API server when the controllers need it.
+	MetadataOnlyJobs featuregate.Feature = "MetadataOnlyJobs"
+
+	// JobTemplateCanaryRuns enables the canary runs of the JobTemplates with canaryRuns set.
+	JobTemplateCanaryRuns featuregate.Feature = "JobTemplateCanaryRuns"
+)
+
+var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	CronJobTimeZone:       {Default: false, PreRelease: featuregate.Alpha},
+	ArgoWorkflowRunner:    {Default: false, PreRelease: featuregate.Alpha},
+	TektonPipelineRunner:  {Default: false, PreRelease: featuregate.Alpha},
+	MetadataOnlyJobs:      {Default: false, PreRelease: featuregate.Alpha},
+	JobTemplateCanaryRuns: {Default: false, PreRelease: featuregate.Alpha},
+}
+
+// Gates is the feature gate of the operator. It is set once on startup and read only afterwards.
+var Gates featuregate.MutableFeatureGate = featuregate.NewFeatureGate()
+
+func init() {
+	runtime.Must(Gates.Add(defaultFeatureGates))
+}
+
+// Enabled returns whether the feature is enabled.
+func Enabled(feature featuregate.Feature) bool {
+	return Gates.Enabled(feature)
+}
+
+// EnabledFeatures returns the names of the enabled features, sorted.
+func EnabledFeatures() []string {
+	var names []string
+	for feature := range defaultFeatureGates {
+		if Enabled(feature) {
+			names = append(names, string(feature))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
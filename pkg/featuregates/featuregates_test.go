@@ -0,0 +1,42 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package featuregates
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/component-base/featuregate"
+)
+
+var _ = Describe("Feature gates", func() {
+	It("Should disable the alpha features by default", func() {
+		for feature, spec := range defaultFeatureGates {
+			if spec.PreRelease == featuregate.Alpha {
+				Expect(Enabled(feature)).To(BeFalse(), string(feature))
+			}
+		}
+		Expect(EnabledFeatures()).To(BeEmpty())
+	})
+
+	It("Should enable the features from the flag and reject the unknown ones", func() {
+		gates := Gates.DeepCopy()
+		Expect(gates.Set("CronJobTimeZone=true")).To(Succeed())
+		Expect(gates.Enabled(CronJobTimeZone)).To(BeTrue())
+		Expect(gates.Set("TimeTravel=true")).NotTo(Succeed())
+		Expect(Enabled(CronJobTimeZone)).To(BeFalse())
+	})
+})
@@ -0,0 +1,126 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package filters contains the HTTP filters protecting the endpoints served by the manager.
+package filters
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+/*
+This is what kube-rbac-proxy does in front of the metrics endpoint, without the sidecar. The bearer token of the
+request is authenticated with a TokenReview, then a SubjectAccessReview checks that its user may access the
+non-resource URL of the request, e.g. `get` on `/metrics`. The authenticated user is passed to the handler in the
+context of the request, for the handlers authorizing their actions further, see UserFrom.
+*/
+
+//+kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+var log = logf.Log.WithName("filters")
+
+// userKey is the key of the authenticated user in the context of the request.
+type userKey struct{}
+
+// UserFrom returns the user authenticated by WithAuthenticationAndAuthorization, if any.
+func UserFrom(ctx context.Context) (authenticationv1.UserInfo, bool) {
+	user, ok := ctx.Value(userKey{}).(authenticationv1.UserInfo)
+	return user, ok
+}
+
+// WithUser returns a copy of the context holding the authenticated user.
+func WithUser(ctx context.Context, user authenticationv1.UserInfo) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// WithAuthenticationAndAuthorization returns a filter which only lets through the requests of the users allowed to
+// access the requested path. The client must be able to create TokenReviews and SubjectAccessReviews.
+func WithAuthenticationAndAuthorization(c client.Client) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := req.Context()
+
+			token := bearerToken(req)
+			if token == "" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+			if err := c.Create(ctx, review); err != nil {
+				log.Error(err, "unable to authenticate the request", "path", req.URL.Path)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !review.Status.Authenticated {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			user := review.Status.User
+			sar := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   user.Username,
+				UID:    user.UID,
+				Groups: user.Groups,
+				Extra:  ConvertExtra(user.Extra),
+				NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+					Path: req.URL.Path,
+					Verb: strings.ToLower(req.Method),
+				},
+			}}
+			if err := c.Create(ctx, sar); err != nil {
+				log.Error(err, "unable to authorize the request", "path", req.URL.Path, "user", user.Username)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !sar.Status.Allowed {
+				log.V(1).Info("request forbidden", "path", req.URL.Path, "user", user.Username, "reason", sar.Status.Reason)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, req.WithContext(WithUser(ctx, user)))
+		})
+	}
+}
+
+// bearerToken returns the bearer token of the Authorization header, if any.
+func bearerToken(req *http.Request) string {
+	parts := strings.SplitN(req.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+// ConvertExtra converts the extra attributes of an authenticated user to the ones of a SubjectAccessReview.
+func ConvertExtra(extra map[string]authenticationv1.ExtraValue) map[string]authorizationv1.ExtraValue {
+	if extra == nil {
+		return nil
+	}
+	converted := make(map[string]authorizationv1.ExtraValue, len(extra))
+	for k, v := range extra {
+		converted[k] = authorizationv1.ExtraValue(v)
+	}
+	return converted
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reviewClient answers the TokenReviews and the SubjectAccessReviews from its fields.
+type reviewClient struct {
+	client.Client
+	tokens  map[string]string
+	allowed map[string]bool
+}
+
+func (c *reviewClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	switch review := obj.(type) {
+	case *authenticationv1.TokenReview:
+		if user, ok := c.tokens[review.Spec.Token]; ok {
+			review.Status.Authenticated = true
+			review.Status.User.Username = user
+		}
+	case *authorizationv1.SubjectAccessReview:
+		review.Status.Allowed = c.allowed[review.Spec.User] &&
+			review.Spec.NonResourceAttributes.Verb == "get" && review.Spec.NonResourceAttributes.Path == "/metrics"
+	}
+	return nil
+}
+
+var _ = Describe("Authentication and authorization filter", func() {
+	c := &reviewClient{
+		tokens:  map[string]string{"prometheus-token": "prometheus", "intruder-token": "intruder"},
+		allowed: map[string]bool{"prometheus": true},
+	}
+	handler := WithAuthenticationAndAuthorization(c)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, ok := UserFrom(req.Context())
+		Expect(ok).To(BeTrue())
+		Expect(user.Username).To(Equal("prometheus"))
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	It("Should reject the requests without a token", func() {
+		Expect(serve("")).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Should reject the requests with an unknown token", func() {
+		Expect(serve("made-up-token")).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("Should forbid the users without access to the path", func() {
+		Expect(serve("intruder-token")).To(Equal(http.StatusForbidden))
+	})
+
+	It("Should let the authorized users through", func() {
+		Expect(serve("prometheus-token")).To(Equal(http.StatusOK))
+	})
+})
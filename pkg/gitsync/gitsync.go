@@ -0,0 +1,232 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gitsync clones the branches of the GitSyncs, and renders the CronJobs of the manifests in them.
+package gitsync
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// ManagedByLabel is the label of the CronJobs applied by a GitSync, its value is the name of the GitSync.
+	ManagedByLabel = "batch.example.com/gitsync"
+	// DefaultBranch is the branch which is synced when the GitSync names none.
+	DefaultBranch = "main"
+	// UsernameKey and PasswordKey are the keys of the credentials in the secret of a GitSync.
+	UsernameKey = "username"
+	PasswordKey = "password"
+
+	// MaxCronJobs is the number of CronJobs a directory may have, so their names fit in the status of the GitSync.
+	MaxCronJobs = 500
+	// maxManifestSize is the size of a manifest file which is read at most.
+	maxManifestSize = 1 << 20
+)
+
+/*
+The branch is cloned into memory with a depth of one, only its last commit is needed and nothing is left on the disk
+of the manager. The manifests are rendered all or nothing: a directory with a manifest which is not a CronJob of the
+namespace of the GitSync is an error, applying a half of it could delete what the other half still wants.
+*/
+
+// cloneDepth is the number of the commits which are cloned, 0 for all of them.
+var cloneDepth = 1
+
+// Checkout is the last commit of a cloned branch.
+type Checkout struct {
+	// Commit is the hash of the commit.
+	Commit string
+	// Files are the files of the commit.
+	Files billy.Filesystem
+}
+
+// Clone clones the last commit of the branch of the repository at the URL. The auth may be nil.
+func Clone(ctx context.Context, url, branch string, auth transport.AuthMethod) (*Checkout, error) {
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	files := memfs.New()
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), files, &git.CloneOptions{
+		URL:           url,
+		Auth:          auth,
+		ReferenceName: plumbing.NewBranchReferenceName(branch),
+		SingleBranch:  true,
+		Depth:         cloneDepth,
+		Tags:          git.NoTags,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to clone the branch %s: %w", branch, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	return &Checkout{Commit: head.Hash().String(), Files: files}, nil
+}
+
+// Auth returns the credentials of the secret of a GitSync.
+func Auth(secret *corev1.Secret) (transport.AuthMethod, error) {
+	password := string(secret.Data[PasswordKey])
+	if password == "" {
+		return nil, fmt.Errorf("the secret %s has no %s", secret.Name, PasswordKey)
+	}
+	// the hosting services ignore the username of a token, but want one
+	username := string(secret.Data[UsernameKey])
+	if username == "" {
+		username = "git"
+	}
+	return &githttp.BasicAuth{Username: username, Password: password}, nil
+}
+
+// Render returns the CronJobs of the YAML and JSON manifests of the directory and its subdirectories, sorted by name.
+// They are put in the namespace, and labeled as applied by the GitSync.
+func Render(files billy.Filesystem, dir, namespace, gitSync string) ([]*unstructured.Unstructured, error) {
+	dir = path.Clean("/" + dir)
+	if info, err := files.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory of the repository", dir)
+	}
+
+	var cronJobs []*unstructured.Unstructured
+	seen := map[string]string{}
+	err := walk(files, dir, func(name string) error {
+		objs, err := decode(files, name)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			if err := check(obj, namespace); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if other, ok := seen[obj.GetName()]; ok {
+				return fmt.Errorf("%s: the CronJob %s is also in %s", name, obj.GetName(), other)
+			}
+			seen[obj.GetName()] = name
+			obj.SetNamespace(namespace)
+			labels := obj.GetLabels()
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels[ManagedByLabel] = gitSync
+			obj.SetLabels(labels)
+			cronJobs = append(cronJobs, obj)
+		}
+		if len(cronJobs) > MaxCronJobs {
+			return fmt.Errorf("the directory has more than %d CronJobs", MaxCronJobs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(cronJobs, func(i, j int) bool { return cronJobs[i].GetName() < cronJobs[j].GetName() })
+	return cronJobs, nil
+}
+
+// walk calls fn with the manifest files of the directory and its subdirectories, in lexical order.
+func walk(files billy.Filesystem, dir string, fn func(name string) error) error {
+	infos, err := files.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name() < infos[j].Name() })
+	for _, info := range infos {
+		name := path.Join(dir, info.Name())
+		switch {
+		case strings.HasPrefix(info.Name(), "."):
+			continue
+		case info.IsDir():
+			if err := walk(files, name, fn); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular() && isManifest(info.Name()):
+			if info.Size() > maxManifestSize {
+				return fmt.Errorf("%s is larger than %d bytes", name, maxManifestSize)
+			}
+			if err := fn(name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isManifest returns whether the file is a YAML or a JSON one.
+func isManifest(name string) bool {
+	switch path.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// decode returns the objects of the documents of the manifest file, the empty documents are skipped.
+func decode(files billy.Filesystem, name string) ([]*unstructured.Unstructured, error) {
+	f, err := files.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var objs []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		var content map[string]interface{}
+		if err := decoder.Decode(&content); err == io.EOF {
+			return objs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(content) > 0 {
+			objs = append(objs, &unstructured.Unstructured{Object: content})
+		}
+	}
+}
+
+// check returns why the object can not be applied as a CronJob of the namespace.
+func check(obj *unstructured.Unstructured, namespace string) error {
+	if obj.GetAPIVersion() != v1.GroupVersion.String() || obj.GetKind() != "CronJob" {
+		return fmt.Errorf("%s %s is not a %s CronJob", obj.GetAPIVersion(), obj.GetKind(), v1.GroupVersion)
+	}
+	if errs := validation.IsDNS1123Subdomain(obj.GetName()); len(errs) > 0 {
+		return fmt.Errorf("the CronJob has an invalid name %q: %s", obj.GetName(), strings.Join(errs, ", "))
+	}
+	if obj.GetNamespace() != "" && obj.GetNamespace() != namespace {
+		return fmt.Errorf("the CronJob %s is in the namespace %s, not in %s", obj.GetName(), obj.GetNamespace(),
+			namespace)
+	}
+	if obj.GetGenerateName() != "" || obj.GetResourceVersion() != "" || obj.GetUID() != "" {
+		return fmt.Errorf("the CronJob %s has a generateName, a resourceVersion or a uid", obj.GetName())
+	}
+	return nil
+}
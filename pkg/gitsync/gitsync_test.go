@@ -0,0 +1,150 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage/memory"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const report = `apiVersion: batch.example.com/v1
+kind: CronJob
+metadata:
+  name: report
+  labels:
+    team: finance
+spec:
+  schedule: "0 6 * * *"
+`
+
+// files returns an in-memory filesystem with the files.
+func files(contents map[string]string) billy.Filesystem {
+	fs := memfs.New()
+	for name, content := range contents {
+		Expect(util.WriteFile(fs, name, []byte(content), 0644)).To(Succeed())
+	}
+	return fs
+}
+
+var _ = Describe("Render", func() {
+	It("Should render the CronJobs of the directory and its subdirectories", func() {
+		fs := files(map[string]string{
+			"/cronjobs/report.yaml": report,
+			"/cronjobs/nightly/backup.yml": "---\n---\napiVersion: batch.example.com/v1\nkind: CronJob\n" +
+				"metadata:\n  name: backup\n  namespace: ops\nspec:\n  schedule: '@daily'\n",
+			"/cronjobs/cleanup.json": `{"apiVersion": "batch.example.com/v1", "kind": "CronJob",` +
+				` "metadata": {"name": "cleanup"}}`,
+			"/cronjobs/README.md":       "# the CronJobs of ops",
+			"/cronjobs/.hidden/x.yaml":  "kind: Secret",
+			"/other/not-a-cronjob.yaml": "kind: Secret",
+		})
+		cronJobs, err := Render(fs, "cronjobs/", "ops", "sync")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJobs).To(HaveLen(3))
+		Expect(cronJobs[0].GetName()).To(Equal("backup"))
+		Expect(cronJobs[1].GetName()).To(Equal("cleanup"))
+		Expect(cronJobs[2].GetName()).To(Equal("report"))
+		for _, cronJob := range cronJobs {
+			Expect(cronJob.GetNamespace()).To(Equal("ops"))
+			Expect(cronJob.GetLabels()).To(HaveKeyWithValue(ManagedByLabel, "sync"))
+		}
+		Expect(cronJobs[2].GetLabels()).To(HaveKeyWithValue("team", "finance"))
+	})
+
+	It("Should render nothing from an empty directory", func() {
+		fs := files(map[string]string{"/cronjobs/README.md": "nothing yet"})
+		Expect(Render(fs, "/cronjobs", "ops", "sync")).To(BeEmpty())
+	})
+
+	It("Should reject the directories which can not be applied whole", func() {
+		for contents, message := range map[string]string{
+			"kind: Secret\napiVersion: v1\nmetadata:\n  name: token\n": "is not a batch.example.com/v1 CronJob",
+			"apiVersion: batch.example.com/v1\nkind: CronJob\nmetadata:\n  name: report\n  namespace: prod\n": "" +
+				"is in the namespace prod",
+			"apiVersion: batch.example.com/v1\nkind: CronJob\nmetadata:\n  name: Report\n":          "invalid name",
+			"apiVersion: batch.example.com/v1\nkind: CronJob\nmetadata:\n  generateName: report-\n": "invalid name",
+			report + "---\n" + report: "is also in",
+			"apiVersion: [":           "error converting YAML",
+		} {
+			_, err := Render(files(map[string]string{"/a.yaml": contents}), "", "ops", "sync")
+			Expect(err).To(MatchError(ContainSubstring(message)), contents)
+		}
+
+		_, err := Render(files(map[string]string{"/a.yaml": report}), "missing", "ops", "sync")
+		Expect(err).To(MatchError("/missing is not a directory of the repository"))
+	})
+})
+
+var _ = Describe("Auth", func() {
+	It("Should use the credentials of the secret", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "token"},
+			Data: map[string][]byte{PasswordKey: []byte("s3cr3t")}}
+		Expect(Auth(secret)).To(Equal(&githttp.BasicAuth{Username: "git", Password: "s3cr3t"}))
+
+		secret.Data[UsernameKey] = []byte("ci")
+		Expect(Auth(secret)).To(Equal(&githttp.BasicAuth{Username: "ci", Password: "s3cr3t"}))
+
+		delete(secret.Data, PasswordKey)
+		_, err := Auth(secret)
+		Expect(err).To(MatchError("the secret token has no password"))
+	})
+})
+
+var _ = Describe("Clone", func() {
+	const url = "file:///cronjobs.git"
+
+	It("Should clone the last commit of the branch", func() {
+		repo, err := git.Init(memory.NewStorage(), memfs.New())
+		Expect(err).NotTo(HaveOccurred())
+		worktree, err := repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(util.WriteFile(worktree.Filesystem, "cronjobs/report.yaml", []byte(report), 0644)).To(Succeed())
+		_, err = worktree.Add("cronjobs/report.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		commit, err := worktree.Commit("Add the report", &git.CommitOptions{
+			Author: &object.Signature{Name: "ops", Email: "ops@example.com", When: time.Now()}})
+		Expect(err).NotTo(HaveOccurred())
+		// the repository is served in-process rather than with the git binaries, and such a server is not shallow
+		client.InstallProtocol("file", server.NewServer(server.MapLoader{url: repo.Storer}))
+		cloneDepth = 0
+		defer func() { cloneDepth = 1 }()
+
+		checkout, err := Clone(context.Background(), url, "master", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checkout.Commit).To(Equal(commit.String()))
+		cronJobs, err := Render(checkout.Files, "cronjobs", "ops", "sync")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJobs).To(HaveLen(1))
+
+		_, err = Clone(context.Background(), url, "", nil)
+		Expect(err).To(MatchError(ContainSubstring("unable to clone the branch main")))
+	})
+})
@@ -0,0 +1,174 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package grpcapi serves the gRPC service of pkg/grpcapi/v1 with mutual TLS, for the external schedulers and CI
+// systems starting, suspending and following the runs of the CronJobs.
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/certrotation"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/config"
+	grpcapiv1 "github.com/bilalcaliskan/kubebuilder-tutorial/pkg/grpcapi/v1"
+)
+
+/*
+The clients authenticate with a certificate of the client CA, and may be restricted to a list of names, matched
+against the common name and the DNS names of their certificate. A client allowed in calls every RPC on every CronJob
+the manager watches, with the permissions of the manager: the service is meant for a handful of trusted systems,
+not for the end users, which the API server authorizes better.
+*/
+
+var log = logf.Log.WithName("grpc")
+
+// Server serves the gRPC service.
+type Server struct {
+	// BindAddress is the address the server listens on, e.g. `:9090`.
+	BindAddress string
+	// CertDir holds the tls.crt and tls.key files of the server.
+	CertDir string
+	// ClientCAFile holds the certificate authorities of the client certificates.
+	ClientCAFile string
+	// AllowedClients are the names of the clients allowed in, every client is if empty.
+	AllowedClients []string
+	// Client reads the CronJobs and the runs, usually from the cache, and writes the Jobs and the CronJobs.
+	Client client.Client
+	// Scheme sets the owner of the Jobs of the runs.
+	Scheme *runtime.Scheme
+	// TLS holds the TLS settings of the service.
+	TLS configv1.TLSConfig
+}
+
+var _ manager.Runnable = &Server{}
+
+// Start implements manager.Runnable, it serves the service until the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	certs := &certrotation.CertificateLoader{Dir: s.CertDir, Server: "grpc"}
+	if _, err := certs.GetCertificate(nil); err != nil {
+		return fmt.Errorf("unable to load the gRPC certificate: %w", err)
+	}
+	clientCAs, err := loadCertPool(s.ClientCAFile)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{
+		GetCertificate: certs.GetCertificate,
+		ClientCAs:      clientCAs,
+		ClientAuth:     tls.RequireAndVerifyClientCert,
+	}
+	if err := config.ApplyTLSConfig(tlsConfig, s.TLS); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", s.BindAddress, err)
+	}
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)), grpc.UnaryInterceptor(s.authorize))
+	grpcapiv1.RegisterCronJobServiceServer(server, newService(s.Client, s.Scheme))
+
+	go func() {
+		<-ctx.Done()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			server.Stop()
+		}
+	}()
+
+	log.Info("serving the gRPC service over mutual TLS", "address", s.BindAddress)
+	return server.Serve(listener)
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica serves the service.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// authorize lets through the calls of the allowed clients only.
+func (s *Server) authorize(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	name, ok := clientName(ctx, s.AllowedClients)
+	if !ok {
+		log.V(1).Info("call forbidden", "method", info.FullMethod, "client", name)
+		return nil, status.Error(codes.PermissionDenied, "client not allowed")
+	}
+	log.V(1).Info("call", "method", info.FullMethod, "client", name)
+	return handler(ctx, req)
+}
+
+// clientName returns the common name of the verified client certificate of the call, and whether it is allowed.
+func clientName(ctx context.Context, allowed []string) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	cert := tlsInfo.State.VerifiedChains[0][0]
+	if len(allowed) == 0 {
+		return cert.Subject.CommonName, true
+	}
+	for _, name := range allowed {
+		if name == cert.Subject.CommonName {
+			return cert.Subject.CommonName, true
+		}
+		for _, dnsName := range cert.DNSNames {
+			if name == dnsName {
+				return cert.Subject.CommonName, true
+			}
+		}
+	}
+	return cert.Subject.CommonName, false
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificate found in the client CA %s", file)
+	}
+	return pool, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/controllers"
+	grpcapiv1 "github.com/bilalcaliskan/kubebuilder-tutorial/pkg/grpcapi/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/restapi"
+)
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch;patch
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobruns,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+
+// defaultRunsLimit is the number of runs listed when the request does not limit them.
+const defaultRunsLimit = 100
+
+// service implements the RPCs with the same code paths as the rest of the operator: the manual runs are started like
+// by controllers.TriggerRun, the runs are listed like by the read-only API.
+type service struct {
+	grpcapiv1.UnimplementedCronJobServiceServer
+	client client.Client
+	scheme *runtime.Scheme
+	now    func() time.Time
+}
+
+func newService(c client.Client, scheme *runtime.Scheme) *service {
+	return &service{client: c, scheme: scheme, now: time.Now}
+}
+
+// TriggerRun implements grpcapiv1.CronJobServiceServer
+func (s *service) TriggerRun(ctx context.Context, req *grpcapiv1.TriggerRunRequest) (*grpcapiv1.TriggerRunResponse,
+	error) {
+	cronJob, err := s.getCronJob(ctx, req.Namespace, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	caller, _ := clientName(ctx, nil)
+	job, err := controllers.TriggerRun(ctx, s.client, s.scheme, cronJob, now, "grpc:"+caller)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	log.Info("triggered a run", "cronJob", req.Namespace+"/"+req.Name, "job", job.Name)
+	return &grpcapiv1.TriggerRunResponse{Job: job.Name, ScheduledTime: timestamppb.New(now)}, nil
+}
+
+// SuspendCronJob implements grpcapiv1.CronJobServiceServer
+func (s *service) SuspendCronJob(ctx context.Context,
+	req *grpcapiv1.SuspendCronJobRequest) (*grpcapiv1.SuspendCronJobResponse, error) {
+	cronJob, err := s.getCronJob(ctx, req.Namespace, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if changed, err := controllers.SuspendCronJob(ctx, s.client, cronJob, req.Suspend); err != nil {
+		return nil, toStatus(err)
+	} else if changed {
+		log.Info("changed the suspension of a CronJob", "cronJob", req.Namespace+"/"+req.Name,
+			"suspend", req.Suspend)
+	}
+	return &grpcapiv1.SuspendCronJobResponse{Suspended: req.Suspend}, nil
+}
+
+// ListRuns implements grpcapiv1.CronJobServiceServer
+func (s *service) ListRuns(ctx context.Context, req *grpcapiv1.ListRunsRequest) (*grpcapiv1.ListRunsResponse,
+	error) {
+	cronJob, err := s.getCronJob(ctx, req.Namespace, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultRunsLimit
+	}
+	runs, err := restapi.ListRuns(ctx, s.client, cronJob, limit)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &grpcapiv1.ListRunsResponse{}
+	for _, run := range runs {
+		resp.Runs = append(resp.Runs, &grpcapiv1.Run{
+			Name:           run.Name,
+			Trigger:        string(run.Trigger),
+			ScheduledTime:  timestamppb.New(run.ScheduledTime.Time),
+			Phase:          string(run.Phase),
+			StartTime:      timestamp(run.StartTime),
+			CompletionTime: timestamp(run.CompletionTime),
+			Duration:       duration(run.Duration),
+			FailureReason:  run.FailureReason,
+			FailureMessage: run.FailureMessage,
+		})
+	}
+	return resp, nil
+}
+
+func (s *service) getCronJob(ctx context.Context, namespace, name string) (*batchv1.CronJob, error) {
+	if namespace == "" || name == "" {
+		return nil, status.Error(codes.InvalidArgument, "the namespace and the name of the CronJob are required")
+	}
+	var cronJob batchv1.CronJob
+	if err := s.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &cronJob); err != nil {
+		return nil, toStatus(err)
+	}
+	return &cronJob, nil
+}
+
+// toStatus returns the gRPC status of the error. The unexpected errors are logged, not returned as is.
+func toStatus(err error) error {
+	var denied *controllers.RunDeniedError
+	switch {
+	case errors.As(err, &denied):
+		return status.Error(codes.FailedPrecondition, denied.Error())
+	case apierrors.IsNotFound(err):
+		return status.Error(codes.NotFound, err.Error())
+	case apierrors.IsConflict(err):
+		return status.Error(codes.Aborted, err.Error())
+	case apierrors.IsInvalid(err), apierrors.IsForbidden(err):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	log.Error(err, "unable to serve the call")
+	return status.Error(codes.Internal, "internal error")
+}
+
+func timestamp(t *metav1.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(t.Time)
+}
+
+func duration(d *metav1.Duration) *durationpb.Duration {
+	if d == nil {
+		return nil
+	}
+	return durationpb.New(d.Duration)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	kbatch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	grpcapiv1 "github.com/bilalcaliskan/kubebuilder-tutorial/pkg/grpcapi/v1"
+)
+
+var _ = Describe("Service", func() {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 5, 12, 30, 0, 0, time.UTC)
+
+	var c client.Client
+	var svc *service
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(batchv1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "report", UID: "report-uid"},
+				Spec: batchv1.CronJobSpec{
+					Schedule:          "0 * * * *",
+					ConcurrencyPolicy: batchv1.ForbidConcurrent,
+				},
+			},
+			&batchv1.JobRun{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "report-1622890800"},
+				Spec: batchv1.JobRunSpec{CronJob: "report", Trigger: batchv1.ScheduledTrigger,
+					ScheduledTime: metav1.NewTime(now.Add(-30 * time.Minute))},
+				Status: batchv1.JobRunStatus{Phase: batchv1.JobRunSucceeded,
+					Duration: &metav1.Duration{Duration: time.Minute}},
+			},
+		).Build()
+		svc = newService(c, scheme)
+		svc.now = func() time.Time { return now }
+	})
+
+	It("Should start a manual run, unless the concurrency policy forbids it", func() {
+		resp, err := svc.TriggerRun(ctx, &grpcapiv1.TriggerRunRequest{Namespace: "default", Name: "report"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.ScheduledTime.AsTime()).To(BeTemporally("==", now))
+
+		var job kbatch.Job
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: resp.Job}, &job)).To(Succeed())
+		Expect(job.Annotations).To(HaveKeyWithValue("batch.example.com/trigger", "Manual"))
+		Expect(metav1.GetControllerOf(&job).Name).To(Equal("report"))
+
+		_, err = svc.TriggerRun(ctx, &grpcapiv1.TriggerRunRequest{Namespace: "default", Name: "report"})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+
+	It("Should suspend and resume the CronJob", func() {
+		resp, err := svc.SuspendCronJob(ctx, &grpcapiv1.SuspendCronJobRequest{Namespace: "default", Name: "report",
+			Suspend: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Suspended).To(BeTrue())
+
+		var cronJob batchv1.CronJob
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "report"}, &cronJob)).To(Succeed())
+		Expect(cronJob.Spec.Suspend).To(Equal(pointer.BoolPtr(true)))
+	})
+
+	It("Should list the runs of the CronJob", func() {
+		resp, err := svc.ListRuns(ctx, &grpcapiv1.ListRunsRequest{Namespace: "default", Name: "report"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Runs).To(HaveLen(1))
+		Expect(resp.Runs[0].Name).To(Equal("report-1622890800"))
+		Expect(resp.Runs[0].Phase).To(Equal("Succeeded"))
+		Expect(resp.Runs[0].Duration.AsDuration()).To(Equal(time.Minute))
+		Expect(resp.Runs[0].StartTime).To(BeNil())
+	})
+
+	It("Should map the missing CronJobs and the invalid requests to their codes", func() {
+		_, err := svc.ListRuns(ctx, &grpcapiv1.ListRunsRequest{Namespace: "default", Name: "missing"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		_, err = svc.TriggerRun(ctx, &grpcapiv1.TriggerRunRequest{Name: "report"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+})
+
+var _ = Describe("Client authorization", func() {
+	callFrom := func(cert *x509.Certificate) context.Context {
+		state := tls.ConnectionState{}
+		if cert != nil {
+			state.VerifiedChains = [][]*x509.Certificate{{cert}}
+		}
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+	}
+	ci := &x509.Certificate{Subject: pkix.Name{CommonName: "ci"}, DNSNames: []string{"ci.example.com"}}
+
+	It("Should allow every verified client without a list", func() {
+		name, ok := clientName(callFrom(ci), nil)
+		Expect(ok).To(BeTrue())
+		Expect(name).To(Equal("ci"))
+		_, ok = clientName(callFrom(nil), nil)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Should match the common name and the DNS names against the list", func() {
+		_, ok := clientName(callFrom(ci), []string{"ci.example.com"})
+		Expect(ok).To(BeTrue())
+		_, ok = clientName(callFrom(ci), []string{"scheduler"})
+		Expect(ok).To(BeFalse())
+	})
+})
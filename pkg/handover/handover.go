@@ -0,0 +1,149 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package handover keeps in a ConfigMap what a leader could not finish before it stopped, for the next leader to
+// resume: the notifications it did not deliver and the statuses of the CronJobs it did not write.
+package handover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+Every component keeps its leftovers under its own key of the ConfigMap, as JSON. A leader saves them on shutdown once
+its flushes gave up, and the next leader takes them, reading and removing the key, when it starts. The ConfigMap is
+read uncached, the manager does not watch the ConfigMaps of the cluster for a single one.
+
+The ConfigMap is kept next to the leader election lock, so the manager is allowed to read and update it by the
+namespaced Role of the leader election, config/rbac/leader_election_role.yaml, which names it. The ClusterRole of the
+manager grants no access to the ConfigMaps of the other namespaces.
+*/
+
+// DefaultName is the name of the ConfigMap by default.
+const DefaultName = "cronjob-operator-handover"
+
+// serviceAccountNamespaceFile holds the namespace of the pod, where the ConfigMap is kept by default.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Store reads and writes the keys of the handover ConfigMap. A nil Store keeps nothing.
+type Store struct {
+	// Client writes the ConfigMap.
+	Client client.Client
+	// Reader reads the ConfigMap, uncached.
+	Reader client.Reader
+	// Namespace of the ConfigMap, defaults to the namespace of the pod.
+	Namespace string
+	// Name of the ConfigMap, defaults to DefaultName.
+	Name string
+}
+
+// Save keeps the value as the JSON of the key, replacing the previous one, and creates the ConfigMap if needed.
+func (s *Store) Save(ctx context.Context, key string, value interface{}) error {
+	if s == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	objectKey, err := s.ObjectKey()
+	if err != nil {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var configMap corev1.ConfigMap
+		err := s.Reader.Get(ctx, objectKey, &configMap)
+		if apierrors.IsNotFound(err) {
+			configMap = corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: objectKey.Namespace, Name: objectKey.Name},
+				Data:       map[string]string{key: string(data)},
+			}
+			err = s.Client.Create(ctx, &configMap)
+			if apierrors.IsAlreadyExists(err) {
+				// another replica created it in between, read it again
+				return apierrors.NewConflict(corev1.Resource("configmaps"), objectKey.Name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[key] = string(data)
+		return s.Client.Update(ctx, &configMap)
+	})
+}
+
+// Take reads the JSON of the key into the value and removes the key. It returns false if there was no such key.
+func (s *Store) Take(ctx context.Context, key string, value interface{}) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	objectKey, err := s.ObjectKey()
+	if err != nil {
+		return false, err
+	}
+	found := false
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var configMap corev1.ConfigMap
+		if err := s.Reader.Get(ctx, objectKey, &configMap); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		data, ok := configMap.Data[key]
+		if !ok {
+			return nil
+		}
+		if err := json.Unmarshal([]byte(data), value); err != nil {
+			return fmt.Errorf("unable to read %s of ConfigMap %s: %w", key, objectKey, err)
+		}
+		delete(configMap.Data, key)
+		if err := s.Client.Update(ctx, &configMap); err != nil {
+			return err
+		}
+		found = true
+		return nil
+	})
+	return found, err
+}
+
+// ObjectKey returns the key of the ConfigMap.
+func (s *Store) ObjectKey() (client.ObjectKey, error) {
+	key := client.ObjectKey{Namespace: s.Namespace, Name: s.Name}
+	if key.Name == "" {
+		key.Name = DefaultName
+	}
+	if key.Namespace == "" {
+		namespace, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+		if err != nil {
+			return key, fmt.Errorf("the namespace of the handover ConfigMap is not set and unable to detect it: %w",
+				err)
+		}
+		key.Namespace = strings.TrimSpace(string(namespace))
+	}
+	return key, nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handover
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Store", func() {
+	ctx := context.Background()
+	var (
+		c     client.Client
+		store *Store
+	)
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		store = &Store{Client: c, Reader: c, Namespace: "operator-system"}
+	})
+
+	It("Should keep the keys until they are taken", func() {
+		Expect(store.Save(ctx, "notifications", []string{"ops"})).To(Succeed())
+		Expect(store.Save(ctx, "statuses", map[string]int{"default/report": 1})).To(Succeed())
+		var configMap corev1.ConfigMap
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "operator-system", Name: DefaultName}, &configMap)).
+			To(Succeed())
+		Expect(configMap.Data).To(HaveKeyWithValue("notifications", `["ops"]`))
+
+		var notifications []string
+		Expect(store.Take(ctx, "notifications", &notifications)).To(BeTrue())
+		Expect(notifications).To(Equal([]string{"ops"}))
+		Expect(store.Take(ctx, "notifications", &notifications)).To(BeFalse())
+		var statuses map[string]int
+		Expect(store.Take(ctx, "statuses", &statuses)).To(BeTrue())
+		Expect(statuses).To(HaveKeyWithValue("default/report", 1))
+	})
+
+	It("Should take nothing without a ConfigMap or a store", func() {
+		var notifications []string
+		Expect(store.Take(ctx, "notifications", &notifications)).To(BeFalse())
+		store = nil
+		Expect(store.Save(ctx, "notifications", []string{"ops"})).To(Succeed())
+		Expect(store.Take(ctx, "notifications", &notifications)).To(BeFalse())
+	})
+})
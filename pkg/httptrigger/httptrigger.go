@@ -0,0 +1,377 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httptrigger serves an authenticated webhook endpoint, `POST /trigger/<namespace>/<name>`, for the external
+// systems starting runs of the CronJobs outside of their schedule, e.g. CI pipelines or sensors of arriving data.
+package httptrigger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/controllers"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/certrotation"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/config"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/slack"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+)
+
+/*
+The callers are the keys of a Secret, their secrets its values, read on every request so the callers can be added and
+rotated without a restart. A caller authenticates either with its secret as a bearer token, or by signing the request
+with it, for the systems which sign their webhooks, like most git hosts:
+
+	X-Trigger-Caller:    ci
+	X-Trigger-Timestamp: 1622894400
+	X-Trigger-Signature: sha256=<hex of HMAC-SHA256(secret, timestamp + "\n" + path + "\n" + body)>
+
+A signature is only accepted within 5 minutes of its timestamp, for the path it was made for, and once: the handler
+remembers the signatures it accepted until they expire, so a retry has to be signed again. The signatures are kept in
+memory, a replica does not know about the ones accepted by the others. A caller with an empty secret is rejected, anyone
+could sign for it.
+
+The CronJobs opt in: a CronJob is only started by the callers listed in its `batch.example.com/external-triggers`
+annotation, `*` allowing every caller. The run is a manual run, started through the same code as the gRPC service, with
+the concurrency policy and the CronJobQuotas applied, and the caller recorded in its JobRun as `http:<caller>`.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+const (
+	// Prefix is the path of the endpoint.
+	Prefix = "/trigger/"
+	// DefaultBindAddress is the address the server listens on by default.
+	DefaultBindAddress = ":8445"
+	// AllowedCallersAnnotation lists the callers allowed to start runs of a CronJob, separated by commas.
+	AllowedCallersAnnotation = "batch.example.com/external-triggers"
+
+	callerHeader    = "X-Trigger-Caller"
+	timestampHeader = "X-Trigger-Timestamp"
+	signatureHeader = "X-Trigger-Signature"
+
+	maxSignatureAge = 5 * time.Minute
+	maxBodyBytes    = 64 << 10
+)
+
+var log = logf.Log.WithName("http-trigger")
+
+// Handler starts the runs of the authenticated requests.
+type Handler struct {
+	client  client.Client
+	reader  client.Reader
+	scheme  *runtime.Scheme
+	callers types.NamespacedName
+	now     func() time.Time
+
+	lock sync.Mutex
+	// accepted holds when the accepted signatures expire, by caller, timestamp and signature.
+	accepted map[string]time.Time
+}
+
+// NewHandler returns the handler authenticating the callers with the Secret, read with the reader, and starting the
+// runs with the client.
+func NewHandler(c client.Client, reader client.Reader, scheme *runtime.Scheme, callers types.NamespacedName) *Handler {
+	return &Handler{client: c, reader: reader, scheme: scheme, callers: callers, now: time.Now,
+		accepted: map[string]time.Time{}}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, Prefix), "/")
+	if !strings.HasPrefix(req.URL.Path, Prefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, req)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, "the body is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ctx := req.Context()
+	caller, err := h.authenticate(ctx, req, body)
+	if err != nil {
+		log.V(1).Info("request not authenticated", "path", req.URL.Path, "reason", err.Error())
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// a caller not allowed on the CronJob cannot tell whether it exists
+	var cronJob batchv1.CronJob
+	if err := h.client.Get(ctx, client.ObjectKey{Namespace: parts[0], Name: parts[1]}, &cronJob); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		log.Error(err, "unable to get CronJob")
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !allowed(&cronJob, caller) {
+		log.V(1).Info("caller not allowed", "cronJob", parts[0]+"/"+parts[1], "caller", caller)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	job, err := controllers.TriggerRun(ctx, h.client, h.scheme, &cronJob, h.now(), "http:"+caller)
+	var denied *controllers.RunDeniedError
+	switch {
+	case errors.As(err, &denied):
+		http.Error(w, denied.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Error(err, "unable to trigger a run", "cronJob", parts[0]+"/"+parts[1])
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	log.Info("triggered a run", "cronJob", parts[0]+"/"+parts[1], "job", job.Name, "caller", caller)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]string{"job": job.Name})
+}
+
+// authenticate returns the caller of the request, authenticated with a bearer token or a signature.
+func (h *Handler) authenticate(ctx context.Context, req *http.Request, body []byte) (string, error) {
+	var secret corev1.Secret
+	if err := h.reader.Get(ctx, h.callers, &secret); err != nil {
+		return "", fmt.Errorf("unable to read the callers: %w", err)
+	}
+
+	if token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "); token != "" &&
+		token != req.Header.Get("Authorization") {
+		// every caller is compared, the time taken does not tell which one matched
+		names := make([]string, 0, len(secret.Data))
+		for name := range secret.Data {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		caller := ""
+		for _, name := range names {
+			if len(secret.Data[name]) > 0 && subtle.ConstantTimeCompare([]byte(token), secret.Data[name]) == 1 && caller == "" {
+				caller = name
+			}
+		}
+		if caller == "" {
+			return "", errors.New("unknown token")
+		}
+		return caller, nil
+	}
+
+	caller := req.Header.Get(callerHeader)
+	key, ok := secret.Data[caller]
+	if caller == "" || !ok {
+		return "", errors.New("unknown caller")
+	}
+	if len(key) == 0 {
+		return "", fmt.Errorf("caller %q has an empty secret", caller)
+	}
+	timestamp, err := strconv.ParseInt(req.Header.Get(timestampHeader), 10, 64)
+	if err != nil {
+		return "", errors.New("invalid timestamp")
+	}
+	if age := h.now().Sub(time.Unix(timestamp, 0)); age > maxSignatureAge || age < -maxSignatureAge {
+		return "", errors.New("expired signature")
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(req.Header.Get(signatureHeader), "sha256="))
+	if err != nil || !hmac.Equal(signature, Sign(key, timestamp, req.URL.Path, body)) {
+		return "", errors.New("invalid signature")
+	}
+	if !h.accept(caller, timestamp, signature) {
+		return "", errors.New("replayed signature")
+	}
+	return caller, nil
+}
+
+// accept remembers the signature of the caller until it expires, and returns false if it was already accepted.
+func (h *Handler) accept(caller string, timestamp int64, signature []byte) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.now()
+	for key, expiry := range h.accepted {
+		if now.After(expiry) {
+			delete(h.accepted, key)
+		}
+	}
+	key := fmt.Sprintf("%s\n%d\n%x", caller, timestamp, signature)
+	if _, ok := h.accepted[key]; ok {
+		return false
+	}
+	h.accepted[key] = time.Unix(timestamp, 0).Add(maxSignatureAge)
+	return true
+}
+
+// Sign returns the signature of a request made at the Unix timestamp, with the secret of the caller.
+func Sign(secret []byte, timestamp int64, path string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d\n%s\n", timestamp, path)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// allowed returns whether the annotation of the CronJob allows the caller.
+func allowed(cronJob *batchv1.CronJob, caller string) bool {
+	for _, name := range strings.Split(cronJob.Annotations[AllowedCallersAnnotation], ",") {
+		if name = strings.TrimSpace(name); name == "*" || name == caller {
+			return true
+		}
+	}
+	return false
+}
+
+// Server serves the endpoint over TLS.
+type Server struct {
+	// BindAddress is the address the server listens on, e.g. `:8445`.
+	BindAddress string
+	// CertDir holds the tls.crt and tls.key files of the server.
+	CertDir string
+	// Handler authenticates the callers and starts the runs, the endpoint is not served without it.
+	Handler http.Handler
+	// Slack serves the slash commands of Slack on /slack/commands when set.
+	Slack http.Handler
+	// TLS holds the TLS settings of the endpoint.
+	TLS configv1.TLSConfig
+}
+
+var _ manager.Runnable = &Server{}
+
+// Setup returns the server of the config, serving the webhook of the callers of its Secret and the slash commands of
+// Slack when they are set. The runs are started with the client, the Secrets and the ConfigMap read with the reader.
+// It returns nil if the endpoint is disabled.
+func Setup(config configv1.HTTPTriggerConfig, c client.Client, reader client.Reader, scheme *runtime.Scheme) *Server {
+	if !config.Enabled {
+		return nil
+	}
+	s := &Server{BindAddress: config.BindAddress, CertDir: config.CertDir, TLS: config.TLS}
+	if s.BindAddress == "" {
+		s.BindAddress = DefaultBindAddress
+	}
+	if config.CallersSecret != "" {
+		namespace, name := startup.SplitRef(config.CallersSecret)
+		s.Handler = NewHandler(c, reader, scheme, client.ObjectKey{Namespace: namespace, Name: name})
+	}
+	// the slash commands of Slack are served on the same listener, next to the webhook
+	if slackConfig := config.Slack; slackConfig.SigningSecret != "" {
+		secretNamespace, secretName := startup.SplitRef(slackConfig.SigningSecret)
+		usersNamespace, usersName := startup.SplitRef(slackConfig.UsersConfigMap)
+		s.Slack = slack.NewHandler(c, reader, scheme, client.ObjectKey{Namespace: secretNamespace, Name: secretName},
+			client.ObjectKey{Namespace: usersNamespace, Name: usersName})
+	}
+	return s
+}
+
+// Permissions returns the permissions the server of the config needs to read the callers and the Slack settings, on
+// top of the ones of starting the runs.
+func Permissions(config configv1.HTTPTriggerConfig) []startup.Permission {
+	if !config.Enabled {
+		return nil
+	}
+	var permissions []startup.Permission
+	if config.CallersSecret != "" {
+		namespace, _ := startup.SplitRef(config.CallersSecret)
+		permissions = append(permissions, startup.Permissions("", "secrets", []string{"get"}, namespace)...)
+	}
+	if slackConfig := config.Slack; slackConfig.SigningSecret != "" {
+		namespace, _ := startup.SplitRef(slackConfig.SigningSecret)
+		permissions = append(permissions, startup.Permissions("", "secrets", []string{"get"}, namespace)...)
+		namespace, _ = startup.SplitRef(slackConfig.UsersConfigMap)
+		permissions = append(permissions, startup.Permissions("", "configmaps", []string{"get"}, namespace)...)
+	}
+	return permissions
+}
+
+// Start implements manager.Runnable, it serves the endpoint until the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	certs := &certrotation.CertificateLoader{Dir: s.CertDir, Server: "trigger"}
+	if _, err := certs.GetCertificate(nil); err != nil {
+		return fmt.Errorf("unable to load the trigger certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{GetCertificate: certs.GetCertificate}
+	if err := config.ApplyTLSConfig(tlsConfig, s.TLS); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", s.BindAddress, err)
+	}
+	listener = tls.NewListener(listener, tlsConfig)
+
+	mux := http.NewServeMux()
+	if s.Handler != nil {
+		mux.Handle(Prefix, s.Handler)
+	}
+	if s.Slack != nil {
+		mux.Handle(slack.Path, s.Slack)
+	}
+	server := &http.Server{
+		Handler:           mux,
+		TLSNextProto:      config.TLSNextProto(s.TLS),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "unable to shut down the trigger server")
+		}
+	}()
+
+	log.Info("serving the trigger endpoint over TLS", "address", s.BindAddress)
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica serves the endpoint.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
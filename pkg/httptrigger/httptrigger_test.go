@@ -0,0 +1,177 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httptrigger
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Handler", func() {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 5, 12, 30, 0, 0, time.UTC)
+
+	var (
+		handler *Handler
+		c       client.Client
+	)
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "report", UID: "report-uid",
+					Annotations: map[string]string{AllowedCallersAnnotation: "ci, sensor, legacy"}},
+				Spec: v1.CronJobSpec{Schedule: "0 * * * *", ConcurrencyPolicy: v1.ForbidConcurrent},
+			},
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "private"},
+				Spec:       v1.CronJobSpec{Schedule: "0 * * * *"},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "cronjob-system", Name: "trigger-callers"},
+				Data: map[string][]byte{"ci": []byte("ci-secret"), "sensor": []byte("sensor-secret"),
+					"legacy": {}},
+			},
+		).Build()
+		handler = NewHandler(c, c, scheme, types.NamespacedName{Namespace: "cronjob-system", Name: "trigger-callers"})
+		handler.now = func() time.Time { return now }
+	})
+
+	serve := func(method, path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+	signed := func(caller, secret, path string, at time.Time) http.Header {
+		signature := Sign([]byte(secret), at.Unix(), path, []byte("{}"))
+		return http.Header{
+			callerHeader:    {caller},
+			timestampHeader: {strconv.FormatInt(at.Unix(), 10)},
+			signatureHeader: {"sha256=" + hex.EncodeToString(signature)},
+		}
+	}
+
+	It("Should start a run for a caller with a token and record the caller", func() {
+		recorder := serve(http.MethodPost, "/trigger/default/report", bearer("ci-secret"))
+		Expect(recorder.Code).To(Equal(http.StatusCreated))
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs, client.InNamespace("default"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(recorder.Body.String()).To(ContainSubstring(jobs.Items[0].Name))
+		Expect(jobs.Items[0].Annotations).To(HaveKeyWithValue("batch.example.com/trigger", "Manual"))
+		Expect(jobs.Items[0].Annotations).To(HaveKeyWithValue("batch.example.com/triggered-by", "http:ci"))
+
+		// the concurrency policy still applies
+		recorder = serve(http.MethodPost, "/trigger/default/report", bearer("sensor-secret"))
+		Expect(recorder.Code).To(Equal(http.StatusConflict))
+	})
+
+	It("Should start a run for a caller with a valid signature", func() {
+		recorder := serve(http.MethodPost, "/trigger/default/report",
+			signed("sensor", "sensor-secret", "/trigger/default/report", now.Add(-time.Minute)))
+		Expect(recorder.Code).To(Equal(http.StatusCreated))
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs, client.InNamespace("default"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(jobs.Items[0].Annotations).To(HaveKeyWithValue("batch.example.com/triggered-by", "http:sensor"))
+	})
+
+	It("Should reject the unauthenticated requests", func() {
+		path := "/trigger/default/report"
+		for _, header := range []http.Header{
+			nil,
+			bearer("wrong"),
+			{"Authorization": {"Basic Y2k6Y2ktc2VjcmV0"}},
+			signed("ci", "wrong", path, now),
+			signed("unknown", "ci-secret", path, now),
+			signed("ci", "ci-secret", path, now.Add(-10*time.Minute)),
+			signed("ci", "ci-secret", "/trigger/default/private", now),
+		} {
+			Expect(serve(http.MethodPost, path, header).Code).To(Equal(http.StatusUnauthorized))
+		}
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs)).To(Succeed())
+		Expect(jobs.Items).To(BeEmpty())
+	})
+
+	It("Should accept a signature only once", func() {
+		path := "/trigger/default/report"
+		header := signed("sensor", "sensor-secret", path, now.Add(-time.Minute))
+		Expect(serve(http.MethodPost, path, header).Code).To(Equal(http.StatusCreated))
+		Expect(serve(http.MethodPost, path, header).Code).To(Equal(http.StatusUnauthorized))
+
+		// a new signature is authenticated, and denied by the concurrency policy
+		Expect(serve(http.MethodPost, path, signed("sensor", "sensor-secret", path, now)).Code).
+			To(Equal(http.StatusConflict))
+
+		// the signatures are forgotten once they expired
+		later := now.Add(maxSignatureAge + time.Minute)
+		handler.now = func() time.Time { return later }
+		Expect(serve(http.MethodPost, path, signed("ci", "ci-secret", path, later)).Code).
+			To(Equal(http.StatusConflict))
+		Expect(handler.accepted).To(HaveLen(1))
+	})
+
+	It("Should reject a caller with an empty secret", func() {
+		path := "/trigger/default/report"
+		Expect(serve(http.MethodPost, path, signed("legacy", "", path, now)).Code).To(Equal(http.StatusUnauthorized))
+		Expect(serve(http.MethodPost, path, bearer("")).Code).To(Equal(http.StatusUnauthorized))
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs)).To(Succeed())
+		Expect(jobs.Items).To(BeEmpty())
+	})
+
+	It("Should only start the CronJobs allowing the caller", func() {
+		Expect(serve(http.MethodPost, "/trigger/default/private", bearer("ci-secret")).Code).
+			To(Equal(http.StatusForbidden))
+		Expect(serve(http.MethodPost, "/trigger/default/missing", bearer("ci-secret")).Code).
+			To(Equal(http.StatusForbidden))
+		Expect(serve(http.MethodGet, "/trigger/default/report", bearer("ci-secret")).Code).
+			To(Equal(http.StatusMethodNotAllowed))
+		Expect(serve(http.MethodPost, "/trigger/default", bearer("ci-secret")).Code).To(Equal(http.StatusNotFound))
+	})
+})
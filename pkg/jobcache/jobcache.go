@@ -0,0 +1,203 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jobcache caches the Jobs as their metadata only, and reads their status from the API server on demand, to
+// keep the pod templates of all the Jobs of the cluster out of the memory of the manager.
+package jobcache
+
+import (
+	"context"
+	"sync"
+
+	kbatch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+)
+
+/*
+The controllers read the names, the owners, the annotations, the start times and the conditions of the Jobs, never
+their pod templates, which make most of their size. The cache then holds the metadata of the Jobs, and the client
+reads the Jobs as their cached metadata and their status, without their spec. The controllers keep their typed reads
+of the Jobs, and their indexes and watches are set on Object instead.
+
+The status of a Job is read from the API server the first time the client reads a version of the Job, and kept with
+its resourceVersion: the next reads of the same version, like the ones of its finished Jobs by every reconcile of a
+CronJob, do not reach the API server. A Job whose status changed has a new resourceVersion in the cache, its status is
+read again. The statuses are dropped with their Jobs.
+*/
+
+// Object returns the object the Jobs are cached, watched and indexed as.
+func Object() client.Object {
+	return &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{
+		APIVersion: kbatch.SchemeGroupVersion.String(),
+		Kind:       "Job",
+	}}
+}
+
+// NewClientBuilder returns a builder of the manager client which reads the Jobs through their metadata in the cache,
+// with the clients of the given builder.
+func NewClientBuilder(builder cluster.ClientBuilder) cluster.ClientBuilder {
+	return &clientBuilder{ClientBuilder: builder}
+}
+
+type clientBuilder struct {
+	cluster.ClientBuilder
+}
+
+// WithUncached implements cluster.ClientBuilder
+func (b *clientBuilder) WithUncached(objs ...client.Object) cluster.ClientBuilder {
+	b.ClientBuilder = b.ClientBuilder.WithUncached(objs...)
+	return b
+}
+
+// Build implements cluster.ClientBuilder
+func (b *clientBuilder) Build(cache cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+	c, err := b.ClientBuilder.Build(cache, config, options)
+	if err != nil {
+		return nil, err
+	}
+	apiReader, err := client.New(config, options)
+	if err != nil {
+		return nil, err
+	}
+	reader := NewReader(cache, apiReader)
+	informer, err := cache.GetInformer(context.Background(), Object())
+	if err != nil {
+		return nil, err
+	}
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{DeleteFunc: reader.forget})
+	return WrapClient(c, reader), nil
+}
+
+// WrapClient returns a client which reads the Jobs through the reader.
+func WrapClient(c client.Client, reader *Reader) client.Client {
+	return &jobClient{Client: c, jobs: reader}
+}
+
+type jobClient struct {
+	client.Client
+	jobs *Reader
+}
+
+// Get implements client.Client
+func (c *jobClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if job, ok := obj.(*kbatch.Job); ok {
+		return c.jobs.Get(ctx, key, job)
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+// List implements client.Client
+func (c *jobClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if jobs, ok := list.(*kbatch.JobList); ok {
+		return c.jobs.List(ctx, jobs, opts...)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+// Reader reads the Jobs as their metadata in a cache and their status.
+type Reader struct {
+	cache     client.Reader
+	apiReader client.Reader
+
+	lock     sync.Mutex
+	statuses map[types.NamespacedName]versionedStatus
+}
+
+// versionedStatus is the status of a version of a Job.
+type versionedStatus struct {
+	resourceVersion string
+	status          kbatch.JobStatus
+}
+
+// NewReader returns a reader of the Jobs cached as Object in the cache, whose statuses are read with the apiReader.
+func NewReader(cache client.Reader, apiReader client.Reader) *Reader {
+	return &Reader{cache: cache, apiReader: apiReader, statuses: map[types.NamespacedName]versionedStatus{}}
+}
+
+// Get reads the Job, without its spec.
+func (r *Reader) Get(ctx context.Context, key client.ObjectKey, job *kbatch.Job) error {
+	metadata := Object().(*metav1.PartialObjectMetadata)
+	if err := r.cache.Get(ctx, key, metadata); err != nil {
+		return err
+	}
+	return r.read(ctx, metadata, job)
+}
+
+// List lists the Jobs, without their spec. The Jobs deleted since they were cached are left out.
+func (r *Reader) List(ctx context.Context, jobs *kbatch.JobList, opts ...client.ListOption) error {
+	metadata := &metav1.PartialObjectMetadataList{}
+	metadata.SetGroupVersionKind(kbatch.SchemeGroupVersion.WithKind("JobList"))
+	if err := r.cache.List(ctx, metadata, opts...); err != nil {
+		return err
+	}
+	jobs.ResourceVersion = metadata.ResourceVersion
+	jobs.Items = make([]kbatch.Job, 0, len(metadata.Items))
+	for i := range metadata.Items {
+		var job kbatch.Job
+		if err := r.read(ctx, &metadata.Items[i], &job); client.IgnoreNotFound(err) != nil {
+			return err
+		} else if err == nil {
+			jobs.Items = append(jobs.Items, job)
+		}
+	}
+	return nil
+}
+
+// read sets the Job to the metadata and the status of its version, read from the API server unless kept.
+func (r *Reader) read(ctx context.Context, metadata *metav1.PartialObjectMetadata, job *kbatch.Job) error {
+	key := client.ObjectKeyFromObject(metadata)
+	r.lock.Lock()
+	kept, ok := r.statuses[key]
+	r.lock.Unlock()
+	if ok && kept.resourceVersion == metadata.ResourceVersion {
+		metrics.RecordJobStatusRead(metrics.JobStatusCached)
+		*job = kbatch.Job{ObjectMeta: metadata.ObjectMeta, Status: *kept.status.DeepCopy()}
+		return nil
+	}
+
+	var fetched kbatch.Job
+	if err := r.apiReader.Get(ctx, key, &fetched); err != nil {
+		return err
+	}
+	metrics.RecordJobStatusRead(metrics.JobStatusFetched)
+	r.lock.Lock()
+	r.statuses[key] = versionedStatus{resourceVersion: fetched.ResourceVersion, status: *fetched.Status.DeepCopy()}
+	r.lock.Unlock()
+	*job = kbatch.Job{ObjectMeta: fetched.ObjectMeta, Status: fetched.Status}
+	return nil
+}
+
+// forget drops the status of a deleted Job.
+func (r *Reader) forget(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	deleted, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+	r.lock.Lock()
+	delete(r.statuses, client.ObjectKeyFromObject(deleted))
+	r.lock.Unlock()
+}
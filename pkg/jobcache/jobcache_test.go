@@ -0,0 +1,126 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcache
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// countingReader counts the Gets of a reader.
+type countingReader struct {
+	client.Reader
+	gets int
+}
+
+func (r *countingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	r.gets++
+	return r.Reader.Get(ctx, key, obj)
+}
+
+var _ = Describe("Reader", func() {
+	var (
+		ctx       context.Context
+		api       client.Client
+		apiReader *countingReader
+		reader    *Reader
+		job       *kbatch.Job
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		job = &kbatch.Job{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly-1", Labels: map[string]string{"a": "b"}},
+			Spec: kbatch.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "main", Image: "busybox"}},
+			}}},
+			Status: kbatch.JobStatus{Active: 1},
+		}
+		api = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(job).Build()
+		apiReader = &countingReader{Reader: api}
+		// the fake client serves the metadata of its objects like the cache
+		reader = NewReader(api, apiReader)
+	})
+
+	It("should read the Jobs without their spec", func() {
+		var read kbatch.Job
+		Expect(reader.Get(ctx, client.ObjectKeyFromObject(job), &read)).To(Succeed())
+		Expect(read.Labels).To(HaveKeyWithValue("a", "b"))
+		Expect(read.Status.Active).To(Equal(int32(1)))
+		Expect(read.Spec.Template.Spec.Containers).To(BeEmpty())
+	})
+
+	It("should read the status of a version of a Job once", func() {
+		var jobs kbatch.JobList
+		Expect(reader.List(ctx, &jobs, client.InNamespace("default"))).To(Succeed())
+		Expect(reader.List(ctx, &jobs, client.InNamespace("default"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(apiReader.gets).To(Equal(1))
+
+		Expect(api.Get(ctx, client.ObjectKeyFromObject(job), job)).To(Succeed())
+		job.Status.Active, job.Status.Succeeded = 0, 1
+		Expect(api.Status().Update(ctx, job)).To(Succeed())
+		var read kbatch.Job
+		Expect(reader.Get(ctx, client.ObjectKeyFromObject(job), &read)).To(Succeed())
+		Expect(read.Status.Succeeded).To(Equal(int32(1)))
+		Expect(apiReader.gets).To(Equal(2))
+	})
+
+	It("should leave out the Jobs deleted since they were cached", func() {
+		cache := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(job.DeepCopy(),
+			&kbatch.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly-2"}}).Build()
+		reader = NewReader(cache, apiReader)
+
+		var jobs kbatch.JobList
+		Expect(reader.List(ctx, &jobs)).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(jobs.Items[0].Name).To(Equal("nightly-1"))
+	})
+
+	It("should forget the statuses of the deleted Jobs", func() {
+		var read kbatch.Job
+		Expect(reader.Get(ctx, client.ObjectKeyFromObject(job), &read)).To(Succeed())
+		reader.forget(job)
+		Expect(reader.statuses).To(BeEmpty())
+	})
+})
+
+var _ = Describe("WrapClient", func() {
+	It("should read the Jobs through the reader and the other objects through the client", func() {
+		ctx := context.Background()
+		job := &kbatch.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly-1"}}
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "settings"}}
+		underlying := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(job, configMap).Build()
+		apiReader := &countingReader{Reader: underlying}
+		c := WrapClient(underlying, NewReader(underlying, apiReader))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(configMap), &corev1.ConfigMap{})).To(Succeed())
+		Expect(apiReader.gets).To(Equal(0))
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs)).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(apiReader.gets).To(Equal(1))
+	})
+})
@@ -0,0 +1,102 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package leaderstatus reports whether this replica holds the leader election lease, so the active replica can be
+// told apart from the standbys.
+package leaderstatus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+/*
+The manager closes its Elected channel once it holds the lease, or right away when leader election is disabled. A
+replica never goes back to standby, the manager exits when it loses the lease. So the status only changes once, and
+the info metric below only needs its `leader` label flipped once.
+*/
+
+var replicaInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "cronjob_operator_replica_info",
+	Help: "Always 1, the leader label tells whether this replica holds the leader election lease.",
+}, []string{"leader"})
+
+func init() {
+	metrics.Registry.MustRegister(replicaInfo)
+}
+
+// errNotLeader is reported by the readiness check of the standbys.
+var errNotLeader = errors.New("not the leader")
+
+// Status tracks whether the manager was elected leader.
+type Status struct {
+	elected <-chan struct{}
+}
+
+var _ manager.Runnable = &Status{}
+var _ manager.LeaderElectionRunnable = &Status{}
+var _ healthz.Checker = (&Status{}).Check
+
+// New returns the status of the manager with the given Elected channel.
+func New(elected <-chan struct{}) *Status {
+	setLeader(false)
+	return &Status{elected: elected}
+}
+
+// Start implements manager.Runnable, it updates the metric once the manager is elected.
+func (s *Status) Start(ctx context.Context) error {
+	select {
+	case <-s.elected:
+		setLeader(true)
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the standbys report their status too.
+func (s *Status) NeedLeaderElection() bool {
+	return false
+}
+
+// IsLeader returns whether this replica holds the lease.
+func (s *Status) IsLeader() bool {
+	select {
+	case <-s.elected:
+		return true
+	default:
+		return false
+	}
+}
+
+// Check is a healthz.Checker which fails on the standbys.
+func (s *Status) Check(_ *http.Request) error {
+	if !s.IsLeader() {
+		return errNotLeader
+	}
+	return nil
+}
+
+func setLeader(leader bool) {
+	replicaInfo.Reset()
+	replicaInfo.WithLabelValues(strconv.FormatBool(leader)).Set(1)
+}
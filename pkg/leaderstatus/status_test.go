@@ -0,0 +1,47 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderstatus
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Leader status", func() {
+	It("Should only report the leader as ready", func() {
+		elected := make(chan struct{})
+		status := New(elected)
+		Expect(status.Check(nil)).To(MatchError(errNotLeader))
+		Expect(testutil.ToFloat64(replicaInfo.WithLabelValues("false"))).To(Equal(1.0))
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(status.Start(context.Background())).To(Succeed())
+		}()
+		close(elected)
+		Eventually(done).Should(BeClosed())
+
+		Expect(status.Check(nil)).To(Succeed())
+		Expect(testutil.CollectAndCount(replicaInfo)).To(Equal(1))
+		Expect(testutil.ToFloat64(replicaInfo.WithLabelValues("true"))).To(Equal(1.0))
+	})
+})
@@ -0,0 +1,95 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package loglevel serves the level of the logger over HTTP, so it can be raised during an incident without a
+// restart of the manager.
+package loglevel
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/config"
+)
+
+/*
+A GET returns the current level as `{"level":"info"}`, a PUT with the same body changes it. The levels are the ones of
+logging.level of the config file, e.g. `debug` or the verbosity `2`. The level set here lasts until the next restart,
+or until the config file changes and its level is applied again.
+*/
+
+var log = logf.Log.WithName("log-level")
+
+// Path is where the handler is served.
+const Path = "/loglevel"
+
+type payload struct {
+	Level string `json:"level"`
+}
+
+// Handler gets and sets the level of the logger.
+type Handler struct {
+	Level zap.AtomicLevel
+}
+
+var _ http.Handler = &Handler{}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var body payload
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+			return
+		}
+		level, err := config.ParseLogLevel(body.Level)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		previous := h.Level.Level()
+		h.Level.SetLevel(level)
+		log.Info("changed the log level", "from", FormatLevel(previous), "to", FormatLevel(level))
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		h.writeError(w, http.StatusMethodNotAllowed, "only GET and PUT are supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(payload{Level: FormatLevel(h.Level.Level())})
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// FormatLevel formats the level like logging.level of the config file, the verbose debug levels as their verbosity.
+func FormatLevel(level zapcore.Level) string {
+	if level < zapcore.DebugLevel {
+		return strconv.Itoa(-int(level))
+	}
+	return level.String()
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loglevel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var _ = Describe("Log level handler", func() {
+	var handler *Handler
+
+	BeforeEach(func() {
+		handler = &Handler{Level: zap.NewAtomicLevelAt(zapcore.InfoLevel)}
+	})
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, Path, strings.NewReader(body)))
+		return recorder
+	}
+
+	It("Should return the current level", func() {
+		resp := serve(http.MethodGet, "")
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(MatchJSON(`{"level":"info"}`))
+	})
+
+	It("Should change the level", func() {
+		resp := serve(http.MethodPut, `{"level":"2"}`)
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(MatchJSON(`{"level":"2"}`))
+		Expect(handler.Level.Level()).To(Equal(zapcore.Level(-2)))
+	})
+
+	It("Should reject the invalid requests", func() {
+		Expect(serve(http.MethodPut, `{"level":"loud"}`).Code).To(Equal(http.StatusBadRequest))
+		Expect(serve(http.MethodPut, `level=debug`).Code).To(Equal(http.StatusBadRequest))
+		Expect(serve(http.MethodPost, `{"level":"debug"}`).Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(handler.Level.Level()).To(Equal(zapcore.InfoLevel))
+	})
+})
@@ -0,0 +1,96 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+/*
+The controller, which skips the runs of a CronJob while a window is open, and the validating webhook, which makes sure
+a CronJob runs at all, select the windows of a CronJob the same way: the MaintenanceWindows of its namespace and the
+ClusterMaintenanceWindows selecting its namespace, whose selector matches its labels.
+*/
+
+// Named is the window of a MaintenanceWindow or a ClusterMaintenanceWindow.
+type Named struct {
+	// Kind and Name identify the MaintenanceWindow or the ClusterMaintenanceWindow.
+	Kind, Name string
+	Window     *Window
+	// Drain deletes the active Jobs of the CronJobs when the window opens.
+	Drain bool
+}
+
+// Select returns the windows applying to the CronJob, among the MaintenanceWindows of its namespace and the
+// ClusterMaintenanceWindows. The namespace of the CronJob is only got if a ClusterMaintenanceWindow selects the
+// namespaces. The windows with an invalid selector, schedule or time zone are skipped, and reported to invalid.
+func Select(cronJob *v1.CronJob, namespaced []v1.MaintenanceWindow, clusterWide []v1.ClusterMaintenanceWindow,
+	namespace func() (*corev1.Namespace, error), invalid func(kind, name string, err error)) ([]Named, error) {
+	var windows []Named
+	add := func(kind, name string, spec *v1.MaintenanceWindowSpec) {
+		selector, err := metav1.LabelSelectorAsSelector(&spec.Selector)
+		if err != nil {
+			invalid(kind, name, fmt.Errorf("invalid selector: %w", err))
+			return
+		}
+		if !selector.Matches(labels.Set(cronJob.Labels)) {
+			return
+		}
+		var timeZone string
+		if spec.TimeZone != nil {
+			timeZone = *spec.TimeZone
+		}
+		window, err := Parse(spec.Schedule, spec.Duration.Duration, timeZone)
+		if err != nil {
+			invalid(kind, name, err)
+			return
+		}
+		windows = append(windows, Named{Kind: kind, Name: name, Window: window, Drain: spec.DrainActiveJobs})
+	}
+
+	for i := range namespaced {
+		add("MaintenanceWindow", namespaced[i].Name, &namespaced[i].Spec)
+	}
+	var ns *corev1.Namespace
+	for i := range clusterWide {
+		clusterWindow := &clusterWide[i]
+		if clusterWindow.Spec.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(clusterWindow.Spec.NamespaceSelector)
+			if err != nil {
+				invalid("ClusterMaintenanceWindow", clusterWindow.Name,
+					fmt.Errorf("invalid namespace selector: %w", err))
+				continue
+			}
+			if ns == nil {
+				if ns, err = namespace(); err != nil {
+					return nil, err
+				}
+			}
+			if !selector.Matches(labels.Set(ns.Labels)) {
+				continue
+			}
+		}
+		add("ClusterMaintenanceWindow", clusterWindow.Name, &clusterWindow.Spec.MaintenanceWindowSpec)
+	}
+	return windows, nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+var _ = Describe("Select", func() {
+	spec := func(selector map[string]string) v1.MaintenanceWindowSpec {
+		return v1.MaintenanceWindowSpec{
+			Selector: metav1.LabelSelector{MatchLabels: selector},
+			Schedule: "0 2 * * *",
+			Duration: metav1.Duration{Duration: time.Hour},
+		}
+	}
+	cronJob := &v1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "data", Name: "report",
+		Labels: map[string]string{"team": "data"}}}
+
+	It("Should select the windows of the CronJob and its namespace", func() {
+		invalid := spec(nil)
+		invalid.Schedule = "0 2 * *"
+		namespaced := []v1.MaintenanceWindow{
+			{ObjectMeta: metav1.ObjectMeta{Name: "all"}, Spec: spec(nil)},
+			{ObjectMeta: metav1.ObjectMeta{Name: "data"}, Spec: spec(map[string]string{"team": "data"})},
+			{ObjectMeta: metav1.ObjectMeta{Name: "web"}, Spec: spec(map[string]string{"team": "web"})},
+			{ObjectMeta: metav1.ObjectMeta{Name: "invalid"}, Spec: invalid},
+		}
+		clusterWide := []v1.ClusterMaintenanceWindow{
+			{ObjectMeta: metav1.ObjectMeta{Name: "everywhere"},
+				Spec: v1.ClusterMaintenanceWindowSpec{MaintenanceWindowSpec: spec(nil)}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "production"},
+				Spec: v1.ClusterMaintenanceWindowSpec{MaintenanceWindowSpec: spec(nil),
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "production"}}}},
+		}
+		namespace := func() (*corev1.Namespace, error) {
+			return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "data",
+				Labels: map[string]string{"env": "staging"}}}, nil
+		}
+		var invalids []string
+		windows, err := Select(cronJob, namespaced, clusterWide, namespace, func(kind, name string, err error) {
+			invalids = append(invalids, kind+"/"+name)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, w := range windows {
+			names = append(names, w.Kind+"/"+w.Name)
+		}
+		Expect(names).To(Equal([]string{"MaintenanceWindow/all", "MaintenanceWindow/data",
+			"ClusterMaintenanceWindow/everywhere"}))
+		Expect(invalids).To(Equal([]string{"MaintenanceWindow/invalid"}))
+	})
+
+	It("Should only get the namespace for the windows selecting the namespaces", func() {
+		clusterWide := []v1.ClusterMaintenanceWindow{{ObjectMeta: metav1.ObjectMeta{Name: "everywhere"},
+			Spec: v1.ClusterMaintenanceWindowSpec{MaintenanceWindowSpec: spec(nil)}}}
+		namespace := func() (*corev1.Namespace, error) { return nil, errors.New("not cached") }
+		windows, err := Select(cronJob, nil, clusterWide, namespace, func(string, string, error) {})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(windows).To(HaveLen(1))
+
+		clusterWide[0].Spec.NamespaceSelector = &metav1.LabelSelector{}
+		_, err = Select(cronJob, nil, clusterWide, namespace, func(string, string, error) {})
+		Expect(err).To(MatchError("not cached"))
+	})
+})
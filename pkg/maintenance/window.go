@@ -0,0 +1,129 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package maintenance evaluates the recurring maintenance windows: whether a window is open at a given time, and when
+// it opens or closes next, so the CronJobs can be woken up at the boundaries.
+package maintenance
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+// maxOverlappingStarts bounds the starts inspected when the windows overlap, e.g. a window longer than its period.
+const maxOverlappingStarts = 1000
+
+// Window is a recurring window: it opens on every activation of its schedule and stays open for its duration.
+type Window struct {
+	schedule cron.Schedule
+	duration time.Duration
+	location *time.Location
+}
+
+// Parse returns the window opening on the cron schedule for the duration, in the time zone, the local one if empty.
+func Parse(schedule string, duration time.Duration, timeZone string) (*Window, error) {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("unparseable schedule %q: %v", schedule, err)
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive, got %s", duration)
+	}
+	location := time.Local
+	if timeZone != "" {
+		if location, err = time.LoadLocation(timeZone); err != nil {
+			return nil, fmt.Errorf("unknown time zone %q: %v", timeZone, err)
+		}
+	}
+	return &Window{schedule: sched, duration: duration, location: location}, nil
+}
+
+// Open returns whether the window is open at the time. If it is, the time it closes is returned, otherwise the time it
+// opens next, zero if never. Overlapping openings merge, the window closes at the end of the last one.
+func (w *Window) Open(t time.Time) (bool, time.Time) {
+	t = t.In(w.location)
+	var end time.Time
+	start := w.schedule.Next(t.Add(-w.duration))
+	for i := 0; i < maxOverlappingStarts && !start.IsZero() && !start.After(t); i++ {
+		end = start.Add(w.duration)
+		start = w.schedule.Next(start)
+	}
+	if end.IsZero() || !end.After(t) {
+		return false, w.schedule.Next(t)
+	}
+
+	// the following openings may start before this one closes
+	for i := 0; i < maxOverlappingStarts && !start.IsZero() && !start.After(end); i++ {
+		end = start.Add(w.duration)
+		start = w.schedule.Next(start)
+	}
+	return true, end
+}
+
+// LastClose returns the last time the window closed after since and before the time, zero if it did not, or if it is
+// open at the time.
+func (w *Window) LastClose(since, t time.Time) time.Time {
+	if open, _ := w.Open(t); open {
+		return time.Time{}
+	}
+	// the last close is the end of the last opening started a duration before the time, looked for in a period
+	// doubling until found
+	latestStart := t.In(w.location).Add(-w.duration)
+	earliestStart := since.Add(-w.duration)
+	var last time.Time
+	for lookback := w.duration; last.IsZero(); lookback *= 2 {
+		from := latestStart.Add(-lookback)
+		bounded := !from.After(earliestStart)
+		if bounded {
+			from = earliestStart
+		}
+		start := w.schedule.Next(from)
+		for i := 0; i < maxOverlappingStarts && !start.IsZero() && !start.After(latestStart); i++ {
+			last = start.Add(w.duration)
+			start = w.schedule.Next(start)
+		}
+		if bounded {
+			break
+		}
+	}
+	return last
+}
+
+// NextRun returns the first activation of the schedule after the time at which none of the windows is open, zero if
+// there is none until the given time. The activations are computed in the location of the time. The search gives up
+// on the activations after maxOverlappingStarts windows, and returns the last one it reached.
+func NextRun(sched cron.Schedule, windows []*Window, t, until time.Time) time.Time {
+	next := sched.Next(t)
+	for i := 0; i < maxOverlappingStarts && !next.IsZero() && !next.After(until); i++ {
+		var closes time.Time
+		for _, w := range windows {
+			if open, end := w.Open(next); open && end.After(closes) {
+				closes = end
+			}
+		}
+		if closes.IsZero() {
+			return next
+		}
+		// the first activation from the time the window closes, which may be when it closes
+		next = sched.Next(closes.In(t.Location()).Add(-time.Second))
+	}
+	if next.After(until) {
+		return time.Time{}
+	}
+	return next
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/robfig/cron"
+)
+
+var _ = Describe("Window", func() {
+	at := func(value string) time.Time {
+		t, err := time.Parse(time.RFC3339, value)
+		Expect(err).NotTo(HaveOccurred())
+		return t
+	}
+
+	It("Should reject the invalid windows", func() {
+		_, err := Parse("0 2 * *", time.Hour, "")
+		Expect(err).To(MatchError(ContainSubstring("unparseable schedule")))
+		_, err = Parse("0 2 * * *", 0, "")
+		Expect(err).To(MatchError(ContainSubstring("duration must be positive")))
+		_, err = Parse("0 2 * * *", time.Hour, "Mars/Olympus")
+		Expect(err).To(MatchError(ContainSubstring("unknown time zone")))
+	})
+
+	It("Should tell when the window opens and closes", func() {
+		// every Saturday from 02:00 for 48 hours
+		w, err := Parse("0 2 * * 6", 48*time.Hour, "UTC")
+		Expect(err).NotTo(HaveOccurred())
+
+		open, boundary := w.Open(at("2021-06-04T12:00:00Z"))
+		Expect(open).To(BeFalse())
+		Expect(boundary).To(BeTemporally("==", at("2021-06-05T02:00:00Z")))
+
+		open, boundary = w.Open(at("2021-06-05T02:00:00Z"))
+		Expect(open).To(BeTrue())
+		Expect(boundary).To(BeTemporally("==", at("2021-06-07T02:00:00Z")))
+
+		open, boundary = w.Open(at("2021-06-07T02:00:00Z"))
+		Expect(open).To(BeFalse())
+		Expect(boundary).To(BeTemporally("==", at("2021-06-12T02:00:00Z")))
+	})
+
+	It("Should tell when the window closed last", func() {
+		w, err := Parse("0 2 * * 6", 48*time.Hour, "UTC")
+		Expect(err).NotTo(HaveOccurred())
+
+		since := at("2021-06-01T00:00:00Z")
+		Expect(w.LastClose(since, at("2021-06-07T03:00:00Z"))).To(BeTemporally("==", at("2021-06-07T02:00:00Z")))
+		Expect(w.LastClose(since, at("2021-06-11T12:00:00Z"))).To(BeTemporally("==", at("2021-06-07T02:00:00Z")))
+		Expect(w.LastClose(since, at("2021-06-06T12:00:00Z"))).To(BeZero())
+		Expect(w.LastClose(at("2021-06-08T00:00:00Z"), at("2021-06-11T12:00:00Z"))).To(BeZero())
+	})
+
+	It("Should merge the overlapping openings", func() {
+		// every hour for 90 minutes, so always open once started
+		w, err := Parse("0 * * * *", 90*time.Minute, "UTC")
+		Expect(err).NotTo(HaveOccurred())
+
+		open, end := w.Open(at("2021-06-05T02:30:00Z"))
+		Expect(open).To(BeTrue())
+		Expect(end.Sub(at("2021-06-05T02:30:00Z"))).To(BeNumerically(">", 24*time.Hour))
+	})
+
+	It("Should follow the time zone", func() {
+		w, err := Parse("0 2 * * *", time.Hour, "Europe/Istanbul")
+		Expect(err).NotTo(HaveOccurred())
+
+		open, _ := w.Open(at("2021-06-05T23:30:00Z"))
+		Expect(open).To(BeTrue())
+	})
+
+	It("Should find the next run outside of the windows", func() {
+		sched, err := cron.ParseStandard("0 * * * *")
+		Expect(err).NotTo(HaveOccurred())
+		// every day from 02:00 for 3 hours
+		w, err := Parse("0 2 * * *", 3*time.Hour, "UTC")
+		Expect(err).NotTo(HaveOccurred())
+
+		now := at("2021-06-05T01:30:00Z")
+		Expect(NextRun(sched, nil, now, now.Add(time.Hour))).To(BeTemporally("==", at("2021-06-05T02:00:00Z")))
+		Expect(NextRun(sched, []*Window{w}, now, now.Add(24*time.Hour))).To(
+			BeTemporally("==", at("2021-06-05T05:00:00Z")))
+		Expect(NextRun(sched, []*Window{w}, now, now.Add(3*time.Hour))).To(BeZero())
+	})
+})
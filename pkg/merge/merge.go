@@ -0,0 +1,61 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package merge merges the documents decoded from JSON or YAML, to layer a config file over another.
+package merge
+
+/*
+The documents are merged the way `kubectl patch --type merge` merges a patch into an object (RFC 7386): the maps are
+merged key by key, recursively, everything else, including the lists, is replaced by the overlay. A null value in
+the overlay removes the key from the base, so an overlay can reset a setting to its default.
+*/
+
+// Merge returns the overlay merged into the base. The documents are the values produced by json.Unmarshal into an
+// interface{}: maps, lists and scalars. Neither of them is modified, the maps of the result are new ones.
+func Merge(base, overlay interface{}) interface{} {
+	overlayMap, ok := overlay.(map[string]interface{})
+	if !ok {
+		return overlay
+	}
+	baseMap, ok := base.(map[string]interface{})
+	if !ok {
+		baseMap = nil
+	}
+
+	merged := make(map[string]interface{}, len(baseMap)+len(overlayMap))
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range overlayMap {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = Merge(merged[key], value)
+	}
+	return merged
+}
+
+// All merges the documents in order, every document overlaying the ones before it. The empty documents are skipped.
+func All(documents ...interface{}) interface{} {
+	var merged interface{}
+	for _, document := range documents {
+		if document != nil {
+			merged = Merge(merged, document)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package merge
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func decode(document string) interface{} {
+	var decoded interface{}
+	Expect(json.Unmarshal([]byte(document), &decoded)).To(Succeed())
+	return decoded
+}
+
+var _ = Describe("Merge", func() {
+	It("Should merge the maps recursively", func() {
+		base := decode(`{"logging": {"level": "info", "encoder": "json"}, "clusterName": "base"}`)
+		overlay := decode(`{"logging": {"level": "debug"}}`)
+		Expect(Merge(base, overlay)).To(Equal(decode(
+			`{"logging": {"level": "debug", "encoder": "json"}, "clusterName": "base"}`)))
+	})
+
+	It("Should replace the lists and the scalars", func() {
+		base := decode(`{"watchNamespaces": ["a", "b"], "admission": {"maxCronJobsPerNamespace": 10}}`)
+		overlay := decode(`{"watchNamespaces": ["c"], "admission": {"maxCronJobsPerNamespace": 20}}`)
+		Expect(Merge(base, overlay)).To(Equal(decode(
+			`{"watchNamespaces": ["c"], "admission": {"maxCronJobsPerNamespace": 20}}`)))
+	})
+
+	It("Should replace a map with a scalar and a scalar with a map", func() {
+		Expect(Merge(decode(`{"a": {"b": 1}}`), decode(`{"a": 2}`))).To(Equal(decode(`{"a": 2}`)))
+		Expect(Merge(decode(`{"a": 2}`), decode(`{"a": {"b": 1}}`))).To(Equal(decode(`{"a": {"b": 1}}`)))
+	})
+
+	It("Should remove the keys set to null", func() {
+		base := decode(`{"tracing": {"endpoint": "collector:4317", "insecure": true}}`)
+		overlay := decode(`{"tracing": {"endpoint": null}, "unset": null}`)
+		Expect(Merge(base, overlay)).To(Equal(decode(`{"tracing": {"insecure": true}}`)))
+	})
+
+	It("Should not modify the documents", func() {
+		base := decode(`{"logging": {"level": "info"}}`)
+		overlay := decode(`{"logging": {"level": "debug"}}`)
+		Merge(base, overlay)
+		Expect(base).To(Equal(decode(`{"logging": {"level": "info"}}`)))
+		Expect(overlay).To(Equal(decode(`{"logging": {"level": "debug"}}`)))
+	})
+})
+
+var _ = Describe("All", func() {
+	It("Should merge the documents in order and skip the empty ones", func() {
+		Expect(All(
+			decode(`{"a": 1, "b": {"c": 1}}`),
+			nil,
+			decode(`{"b": {"d": 2}}`),
+			decode(`{"a": 3}`),
+		)).To(Equal(decode(`{"a": 3, "b": {"c": 1, "d": 2}}`)))
+	})
+
+	It("Should return nil without documents", func() {
+		Expect(All()).To(BeNil())
+	})
+})
@@ -0,0 +1,198 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/failure"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	jobsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_controller_jobs_created_total",
+		Help: "Total number of Jobs created for the CronJobs, per namespace.",
+	}, []string{"namespace"})
+
+	jobsDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_controller_jobs_deleted_total",
+		Help: "Total number of Jobs deleted by the controller, per reason.",
+	}, []string{"reason"})
+
+	runsSkipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_controller_runs_skipped_total",
+		Help: "Total number of scheduled runs which were not started, per reason.",
+	}, []string{"reason"})
+
+	// reconcileDuration doubles controller_runtime_reconcile_time_seconds, whose observations can not carry exemplars.
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cronjob_controller_reconcile_duration_seconds",
+		Help:    "How long the reconciles took, per controller and result, with the trace IDs as exemplars.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"controller", "result"})
+
+	// creationDelay is how late the controllers are, unlike the schedule delay of the CronJobs which adds the start of
+	// the Pods.
+	creationDelay = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cronjob_controller_job_creation_delay_seconds",
+		Help:    "How long after their scheduled time the Jobs of the scheduled runs were created, per controller.",
+		Buckets: creationDelayBuckets,
+	}, []string{"controller"})
+
+	statusWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_controller_status_writes_total",
+		Help: "Total number of the statuses of the CronJobs debounced by the controller, per result.",
+	}, []string{"result"})
+
+	missedStartsCapped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_controller_missed_starts_capped_total",
+		Help: "Total number of schedules which missed too many activations to walk them, per controller.",
+	}, []string{"controller"})
+
+	jobStatusReads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_controller_job_status_reads_total",
+		Help: "Total number of the statuses of the Jobs read with the metadata-only Job cache, per source.",
+	}, []string{"source"})
+
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_controller_reconcile_errors_total",
+		Help: "Total number of failed reconciles, per controller and reason.",
+	}, []string{"controller", "reason"})
+)
+
+// DeleteReason tells why the controller deleted a Job.
+type DeleteReason string
+
+const (
+	// DeleteHistoryLimit is a finished Job beyond the history limits of its CronJob.
+	DeleteHistoryLimit DeleteReason = "history_limit"
+	// DeleteReplaced is an active Job replaced by a new run with the Replace concurrency policy.
+	DeleteReplaced DeleteReason = "replaced"
+	// DeleteMaintenanceWindow is an active Job drained by a maintenance window.
+	DeleteMaintenanceWindow DeleteReason = "maintenance_window"
+)
+
+// SkipReason tells why a scheduled run was not started.
+type SkipReason string
+
+const (
+	// SkipStartingDeadline is a run missed by more than the starting deadline of its CronJob.
+	SkipStartingDeadline SkipReason = "starting_deadline"
+	// SkipConcurrencyPolicy is a run forbidden by the Forbid concurrency policy while a Job is active.
+	SkipConcurrencyPolicy SkipReason = "concurrency_policy"
+	// SkipQuota is a run denied by a CronJobQuota of the namespace of its CronJob, it is retried a minute later.
+	SkipQuota SkipReason = "quota"
+	// SkipRunner is a run its runner can not start, e.g. the ArgoWorkflow runner with its feature gate disabled.
+	SkipRunner SkipReason = "runner"
+)
+
+// StatusWriteResult tells what became of a status of a CronJob kept by the debounce.
+type StatusWriteResult string
+
+const (
+	// StatusWriteWritten is a status written once the debounce passed.
+	StatusWriteWritten StatusWriteResult = "written"
+	// StatusWriteCoalesced is a status replaced by a newer one before it was written.
+	StatusWriteCoalesced StatusWriteResult = "coalesced"
+	// StatusWriteUnchanged is a status the CronJob had already, e.g. written since with a condition.
+	StatusWriteUnchanged StatusWriteResult = "unchanged"
+	// StatusWriteConflict is a status whose CronJob changed since it was read, it is kept for the next debounce.
+	StatusWriteConflict StatusWriteResult = "conflict"
+)
+
+// JobStatusSource tells where the status of a Job cached as its metadata was read from.
+type JobStatusSource string
+
+const (
+	// JobStatusCached is a status kept from an earlier read of the same version of the Job.
+	JobStatusCached JobStatusSource = "cache"
+	// JobStatusFetched is a status read from the API server.
+	JobStatusFetched JobStatusSource = "api"
+)
+
+// Controller is a controller whose reconciles are timed.
+type Controller string
+
+const (
+	// ControllerCronJob is the controller of the CronJobs.
+	ControllerCronJob Controller = "cronjob"
+	// ControllerJobRun is the controller of the JobRuns.
+	ControllerJobRun Controller = "jobrun"
+	// ControllerClusterCronJob is the controller of the ClusterCronJobs.
+	ControllerClusterCronJob Controller = "clustercronjob"
+	// ControllerWorkflow is the controller of the Workflows.
+	ControllerWorkflow Controller = "workflow"
+)
+
+// creationDelayBuckets go from the latency of a reconcile to the back-offs of the retries.
+var creationDelayBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60, 120, 300}
+
+// RecordReconcile records a reconcile of the controller started at start, the context carries its trace. A failed
+// reconcile is also counted with the reason of its error.
+func RecordReconcile(ctx context.Context, controller Controller, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+		RecordReconcileError(controller, failure.ReasonOf(err))
+	}
+	observe(ctx, reconcileDuration.WithLabelValues(string(controller), result), time.Since(start).Seconds())
+}
+
+// RecordReconcileError records a reconcile of the controller which failed for the reason, without returning an error
+// when it could not be fixed by a retry.
+func RecordReconcileError(controller Controller, reason failure.Reason) {
+	reconcileErrors.WithLabelValues(string(controller), string(reason)).Inc()
+}
+
+// RecordJobCreationDelay records how long after its scheduled time the controller created the Job of a scheduled run.
+// The context carries the trace of the reconcile.
+func RecordJobCreationDelay(ctx context.Context, controller Controller, delay time.Duration) {
+	observe(ctx, creationDelay.WithLabelValues(string(controller)), delay.Seconds())
+}
+
+// RecordStatusWrite records what became of a debounced status of a CronJob.
+func RecordStatusWrite(result StatusWriteResult) {
+	statusWrites.WithLabelValues(string(result)).Inc()
+}
+
+// RecordMissedStartsCapped records a schedule which missed more activations than are walked, of which only the latest
+// one was considered.
+func RecordMissedStartsCapped(controller Controller) {
+	missedStartsCapped.WithLabelValues(string(controller)).Inc()
+}
+
+// RecordJobStatusRead records where the status of a Job cached as its metadata was read from.
+func RecordJobStatusRead(source JobStatusSource) {
+	jobStatusReads.WithLabelValues(string(source)).Inc()
+}
+
+// RecordJobCreated records a Job created for a CronJob of the namespace.
+func RecordJobCreated(namespace string) {
+	jobsCreated.WithLabelValues(namespace).Inc()
+}
+
+// RecordJobDeleted records a Job deleted by the controller.
+func RecordJobDeleted(reason DeleteReason) {
+	jobsDeleted.WithLabelValues(string(reason)).Inc()
+}
+
+// RecordRunSkipped records a scheduled run which was not started.
+func RecordRunSkipped(reason SkipReason) {
+	runsSkipped.WithLabelValues(string(reason)).Inc()
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+The metrics of the CronJobs are labelled with the namespace and the name of their CronJob, the signals of the SLOs of
+every CronJob. There is a series per CronJob, forgotten when the CronJob is deleted, so their cardinality follows the
+number of CronJobs.
+*/
+
+var (
+	lastRunStatus = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cronjob_last_run_status",
+		Help: "Whether the last finished run of the CronJob succeeded, 1, or failed, 0.",
+	}, []string{"namespace", "cronjob"})
+
+	runDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cronjob_run_duration_seconds",
+		Help:    "How long the finished runs of the CronJob ran.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+	}, []string{"namespace", "cronjob"})
+
+	missedRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_missed_runs_total",
+		Help: "Total number of scheduled runs of the CronJob not started before their starting deadline.",
+	}, []string{"namespace", "cronjob"})
+
+	activeJobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cronjob_active_jobs",
+		Help: "Number of active Jobs of the CronJob.",
+	}, []string{"namespace", "cronjob"})
+
+	scheduleDelay = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cronjob_schedule_delay_seconds",
+		Help:    "How long after their scheduled time the scheduled runs of the CronJob started.",
+		Buckets: []float64{0.5, 1, 2, 5, 10, 30, 60, 120, 300, 600, 1800},
+	}, []string{"namespace", "cronjob"})
+
+	timeSinceLastSuccess = &sinceCollector{
+		desc: prometheus.NewDesc("cronjob_time_since_last_success_seconds",
+			"Seconds since the last run of the CronJob succeeded, or since its creation if none did.",
+			[]string{"namespace", "cronjob"}, nil),
+		since: map[[2]string]time.Time{},
+		now:   time.Now,
+	}
+
+	sloRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_slo_runs_total",
+		Help: "Total number of scheduled runs of the CronJob evaluated against its SLO, by result.",
+	}, []string{"namespace", "cronjob", "result"})
+
+	runResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_run_results_total",
+		Help: "Total number of finished runs of the CronJob, by the result reported in their termination message.",
+	}, []string{"namespace", "cronjob", "result"})
+
+	cronJobCreationDelay = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cronjob_job_creation_delay_seconds",
+		Help:    "How long after their scheduled time the Jobs of the scheduled runs of the CronJob were created.",
+		Buckets: creationDelayBuckets,
+	}, []string{"namespace", "cronjob"})
+
+	sloObjective = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cronjob_slo_objective_ratio",
+		Help: "The ratio of the runs of the CronJob which should meet its SLO.",
+	}, []string{"namespace", "cronjob"})
+
+	cronJobVecs = []interface{ DeleteLabelValues(...string) bool }{
+		lastRunStatus, runDuration, missedRuns, activeJobs, scheduleDelay, timeSinceLastSuccess, sloObjective,
+		cronJobCreationDelay,
+	}
+)
+
+// The results of the runs evaluated against the SLO of their CronJob.
+const (
+	SLOMet      = "met"
+	SLOBreached = "breached"
+)
+
+/*
+The time since the last success grows between the reconciles, so it is computed when the metrics are scraped, from the
+last success recorded by the controller. An alert on a CronJob not succeeding for a day is then a mere threshold:
+
+	cronjob_time_since_last_success_seconds{cronjob="nightly-backup"} > 26 * 3600
+*/
+
+// sinceCollector collects the seconds elapsed since a time per CronJob.
+type sinceCollector struct {
+	desc *prometheus.Desc
+	now  func() time.Time
+
+	lock  sync.Mutex
+	since map[[2]string]time.Time
+}
+
+// Describe implements prometheus.Collector
+func (c *sinceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector
+func (c *sinceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.now()
+	for key, since := range c.since {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, now.Sub(since).Seconds(), key[0], key[1])
+	}
+}
+
+// DeleteLabelValues deletes the series of the CronJob.
+func (c *sinceCollector) DeleteLabelValues(labels ...string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := [2]string{labels[0], labels[1]}
+	_, found := c.since[key]
+	delete(c.since, key)
+	return found
+}
+
+func (c *sinceCollector) set(namespace, cronJob string, since time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.since[[2]string{namespace, cronJob}] = since
+}
+
+/*
+The results of the runs are reported by the runs themselves, so a CronJob could make up a new one every run. The first
+MaxRunResults results of a CronJob get their own series, the next ones are counted as OtherRunResult.
+*/
+
+// MaxRunResults is the number of results counted apart per CronJob.
+const MaxRunResults = 10
+
+// OtherRunResult counts the results of a CronJob beyond MaxRunResults.
+const OtherRunResult = "other"
+
+var (
+	runResultsLock sync.Mutex
+	seenRunResults = map[[2]string]map[string]bool{}
+)
+
+// RecordRunResult records a finished run of the CronJob which reported the result.
+func RecordRunResult(namespace, cronJob, result string) {
+	key := [2]string{namespace, cronJob}
+	runResultsLock.Lock()
+	seen := seenRunResults[key]
+	if seen == nil {
+		seen = map[string]bool{}
+		seenRunResults[key] = seen
+	}
+	if !seen[result] {
+		if len(seen) < MaxRunResults {
+			seen[result] = true
+		} else {
+			result = OtherRunResult
+		}
+	}
+	runResultsLock.Unlock()
+	runResults.WithLabelValues(namespace, cronJob, result).Inc()
+}
+
+// A missed run is seen by every reconcile until the next run, lastMissedRuns holds the last one counted per CronJob.
+var (
+	lastMissedRunsLock sync.Mutex
+	lastMissedRuns     = map[[2]string]time.Time{}
+)
+
+// RecordRunFinished records the result of a finished run of the CronJob, and its duration if it started. The duration
+// carries the trace of the context as exemplar.
+func RecordRunFinished(ctx context.Context, namespace, cronJob string, succeeded bool, duration *time.Duration) {
+	status := 0.0
+	if succeeded {
+		status = 1
+	}
+	lastRunStatus.WithLabelValues(namespace, cronJob).Set(status)
+	if duration != nil {
+		observe(ctx, runDuration.WithLabelValues(namespace, cronJob), duration.Seconds())
+	}
+}
+
+// RecordRunStarted records how long after its scheduled time a scheduled run of the CronJob started.
+func RecordRunStarted(namespace, cronJob string, delay time.Duration) {
+	scheduleDelay.WithLabelValues(namespace, cronJob).Observe(delay.Seconds())
+}
+
+// RecordCronJobCreationDelay records how long after its scheduled time the Job of a scheduled run of the CronJob was
+// created. It is only recorded per CronJob when asked to, see RecordJobCreationDelay for the one per controller.
+func RecordCronJobCreationDelay(ctx context.Context, namespace, cronJob string, delay time.Duration) {
+	observe(ctx, cronJobCreationDelay.WithLabelValues(namespace, cronJob), delay.Seconds())
+}
+
+// RecordMissedRun records a run of the CronJob scheduled at the time which missed its starting deadline, once.
+func RecordMissedRun(namespace, cronJob string, scheduledTime time.Time) {
+	key := [2]string{namespace, cronJob}
+	lastMissedRunsLock.Lock()
+	defer lastMissedRunsLock.Unlock()
+	if !scheduledTime.After(lastMissedRuns[key]) {
+		return
+	}
+	lastMissedRuns[key] = scheduledTime
+	missedRuns.WithLabelValues(namespace, cronJob).Inc()
+}
+
+// SetActiveJobs records the number of active Jobs of the CronJob.
+func SetActiveJobs(namespace, cronJob string, active int) {
+	activeJobs.WithLabelValues(namespace, cronJob).Set(float64(active))
+}
+
+// SetLastSuccess records when the last run of the CronJob succeeded, or when it was created if none did.
+func SetLastSuccess(namespace, cronJob string, since time.Time) {
+	timeSinceLastSuccess.set(namespace, cronJob, since)
+}
+
+// RecordSLOResult records a run of the CronJob evaluated against its SLO, SLOMet or SLOBreached.
+func RecordSLOResult(namespace, cronJob, result string) {
+	sloRuns.WithLabelValues(namespace, cronJob, result).Inc()
+}
+
+// SetSLOObjective records the objective of the SLO of the CronJob as a ratio, or deletes it if the CronJob has no SLO.
+func SetSLOObjective(namespace, cronJob string, objective float64, tracked bool) {
+	if !tracked {
+		sloObjective.DeleteLabelValues(namespace, cronJob)
+		return
+	}
+	sloObjective.WithLabelValues(namespace, cronJob).Set(objective)
+}
+
+// ForgetCronJob deletes the series of a deleted CronJob.
+func ForgetCronJob(namespace, cronJob string) {
+	for _, vec := range cronJobVecs {
+		vec.DeleteLabelValues(namespace, cronJob)
+	}
+	for _, result := range []string{SLOMet, SLOBreached} {
+		sloRuns.DeleteLabelValues(namespace, cronJob, result)
+	}
+	runResultsLock.Lock()
+	for result := range seenRunResults[[2]string{namespace, cronJob}] {
+		runResults.DeleteLabelValues(namespace, cronJob, result)
+	}
+	runResults.DeleteLabelValues(namespace, cronJob, OtherRunResult)
+	delete(seenRunResults, [2]string{namespace, cronJob})
+	runResultsLock.Unlock()
+	lastMissedRunsLock.Lock()
+	defer lastMissedRunsLock.Unlock()
+	delete(lastMissedRuns, [2]string{namespace, cronJob})
+}
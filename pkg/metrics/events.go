@@ -0,0 +1,41 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var cloudEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "cronjob_cloudevents_total",
+	Help: "Total number of CloudEvents emitted for the runs, per type and result.",
+}, []string{"type", "result"})
+
+// EventResult is the outcome of the emission of a CloudEvent.
+type EventResult string
+
+const (
+	// EventSent is an event accepted by the sink.
+	EventSent EventResult = "sent"
+	// EventFailed is an event the sink did not accept after the retries.
+	EventFailed EventResult = "failed"
+)
+
+// RecordCloudEvent records the outcome of a CloudEvent of the type.
+func RecordCloudEvent(eventType string, result EventResult) {
+	cloudEvents.WithLabelValues(eventType, string(result)).Inc()
+}
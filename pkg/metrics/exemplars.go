@@ -0,0 +1,45 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+/*
+The histograms of the reconciles and of the runs carry the ID of the trace they were observed in as an exemplar, so a
+dashboard leads from a slow bucket straight to a trace. There is no exemplar without tracing. The exemplars are only
+exposed in the OpenMetrics format, which the secure metrics endpoint negotiates with Prometheus, and Prometheus keeps
+them with `--enable-feature=exemplar-storage`.
+*/
+
+// traceIDLabel is the label of the exemplars holding the trace ID.
+const traceIDLabel = "trace_id"
+
+// observe observes the value, with the trace of the context as exemplar if it is traced.
+func observe(ctx context.Context, observer prometheus.Observer, value float64) {
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+			exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{traceIDLabel: traceID})
+			return
+		}
+	}
+	observer.Observe(value)
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics holds the operator-specific metrics of the controller and the webhooks. They are registered on the
+// registry of controller-runtime, so they are served on the metrics endpoint of the manager along with the metrics of
+// controller-runtime, and recorded through the typed functions of this package.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+/*
+A new metric is declared next to the ones of its component, added to the collectors below and recorded through a
+function taking typed arguments, so that the callers do not have to know the names and the order of the labels. The
+label values are either constants of this package or bounded sets, like the namespaces and the CronJobs, to keep the
+cardinality low.
+*/
+
+// collectors lists the collectors registered on the registry of controller-runtime.
+var collectors = []prometheus.Collector{
+	webhookRequests, webhookLatency, webhookRejections, webhookWarnings, webhookPolicyRejections,
+	webhookValidationWarnings,
+	jobsCreated, jobsDeleted, runsSkipped, reconcileDuration, reconcileErrors, creationDelay,
+	statusWrites, jobStatusReads, missedStartsCapped,
+	lastRunStatus, runDuration, missedRuns, activeJobs, scheduleDelay, timeSinceLastSuccess,
+	sloRuns, sloObjective, runResults, cronJobCreationDelay,
+	cloudEvents,
+}
+
+func init() {
+	metrics.Registry.MustRegister(collectors...)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/failure"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var _ = Describe("Metrics", func() {
+	It("Should register the collectors on the registry of controller-runtime", func() {
+		for _, collector := range collectors {
+			Expect(metrics.Registry.Register(collector)).NotTo(Succeed())
+		}
+	})
+
+	It("Should record the admission requests", func() {
+		requests := testutil.ToFloat64(webhookRequests.WithLabelValues("validating", "create", "denied"))
+		warnings := testutil.ToFloat64(webhookWarnings.WithLabelValues("validating"))
+		RecordAdmission("validating", "create", AdmissionDenied, 10*time.Millisecond, 2)
+		RecordAdmission("validating", "create", AdmissionDenied, 10*time.Millisecond, 0)
+		Expect(testutil.ToFloat64(webhookRequests.WithLabelValues("validating", "create", "denied"))).
+			To(Equal(requests + 2))
+		Expect(testutil.ToFloat64(webhookWarnings.WithLabelValues("validating"))).To(Equal(warnings + 2))
+
+		rejections := testutil.ToFloat64(webhookRejections.WithLabelValues("bad-schedule"))
+		RecordRejection("bad-schedule")
+		Expect(testutil.ToFloat64(webhookRejections.WithLabelValues("bad-schedule"))).To(Equal(rejections + 1))
+
+		RecordPolicyRejection("ClusterCronJobPolicy", "", "business-hours")
+		RecordPolicyRejection("CronJobPolicy", "team-a", "labels")
+		Expect(testutil.ToFloat64(webhookPolicyRejections.WithLabelValues("ClusterCronJobPolicy", "",
+			"business-hours"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(webhookPolicyRejections.WithLabelValues("CronJobPolicy", "team-a", "labels"))).
+			To(Equal(1.0))
+
+		RecordWarnings(WarningDeprecation, 2)
+		RecordWarnings("feature-gate", 0)
+		Expect(testutil.ToFloat64(webhookValidationWarnings.WithLabelValues("deprecation"))).To(Equal(2.0))
+		Expect(testutil.CollectAndCount(webhookValidationWarnings)).To(Equal(1))
+	})
+
+	It("Should record the work of the controller", func() {
+		RecordJobCreated("team-a")
+		RecordJobCreated("team-a")
+		Expect(testutil.ToFloat64(jobsCreated.WithLabelValues("team-a"))).To(Equal(2.0))
+
+		deleted := testutil.ToFloat64(jobsDeleted.WithLabelValues("replaced"))
+		RecordJobDeleted(DeleteReplaced)
+		Expect(testutil.ToFloat64(jobsDeleted.WithLabelValues("replaced"))).To(Equal(deleted + 1))
+
+		skipped := testutil.ToFloat64(runsSkipped.WithLabelValues("starting_deadline"))
+		RecordRunSkipped(SkipStartingDeadline)
+		Expect(testutil.ToFloat64(runsSkipped.WithLabelValues("starting_deadline"))).To(Equal(skipped + 1))
+
+		RecordJobCreationDelay(context.Background(), ControllerClusterCronJob, 300*time.Millisecond)
+		Expect(testutil.CollectAndCount(creationDelay)).To(Equal(1))
+
+		RecordStatusWrite(StatusWriteCoalesced)
+		RecordStatusWrite(StatusWriteCoalesced)
+		Expect(testutil.ToFloat64(statusWrites.WithLabelValues("coalesced"))).To(Equal(2.0))
+
+		RecordMissedStartsCapped(ControllerWorkflow)
+		Expect(testutil.ToFloat64(missedStartsCapped.WithLabelValues("workflow"))).To(Equal(1.0))
+
+		RecordJobStatusRead(JobStatusFetched)
+		Expect(testutil.ToFloat64(jobStatusReads.WithLabelValues("api"))).To(Equal(1.0))
+
+		RecordReconcileError(ControllerCronJob, failure.QuotaExceeded)
+		RecordReconcileError(ControllerJobRun, failure.APIThrottled)
+		Expect(testutil.ToFloat64(reconcileErrors.WithLabelValues("cronjob", "QuotaExceeded"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(reconcileErrors.WithLabelValues("jobrun", "APIThrottled"))).To(Equal(1.0))
+	})
+
+	It("Should record the runs of the CronJobs", func() {
+		duration := 90 * time.Second
+		RecordRunStarted("team-a", "report", 3*time.Second)
+		RecordCronJobCreationDelay(context.Background(), "team-a", "report", 2*time.Second)
+		RecordRunFinished(context.Background(), "team-a", "report", true, &duration)
+		RecordRunFinished(context.Background(), "team-a", "report", false, nil)
+		SetActiveJobs("team-a", "report", 2)
+		Expect(testutil.ToFloat64(lastRunStatus.WithLabelValues("team-a", "report"))).To(Equal(0.0))
+		Expect(testutil.ToFloat64(activeJobs.WithLabelValues("team-a", "report"))).To(Equal(2.0))
+		Expect(testutil.CollectAndCount(runDuration)).To(Equal(1))
+		Expect(testutil.CollectAndCount(scheduleDelay)).To(Equal(1))
+
+		scheduled := time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC)
+		RecordMissedRun("team-a", "report", scheduled)
+		RecordMissedRun("team-a", "report", scheduled)
+		RecordMissedRun("team-a", "report", scheduled.Add(time.Hour))
+		Expect(testutil.ToFloat64(missedRuns.WithLabelValues("team-a", "report"))).To(Equal(2.0))
+
+		timeSinceLastSuccess.now = func() time.Time { return scheduled.Add(26 * time.Hour) }
+		defer func() { timeSinceLastSuccess.now = time.Now }()
+		SetLastSuccess("team-a", "report", scheduled)
+		Expect(testutil.ToFloat64(timeSinceLastSuccess)).To(Equal(26 * 3600.0))
+
+		RecordSLOResult("team-a", "report", SLOMet)
+		RecordSLOResult("team-a", "report", SLOBreached)
+		SetSLOObjective("team-a", "report", 0.995, true)
+		Expect(testutil.ToFloat64(sloRuns.WithLabelValues("team-a", "report", SLOBreached))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(sloObjective.WithLabelValues("team-a", "report"))).To(Equal(0.995))
+
+		for i := 0; i < MaxRunResults+2; i++ {
+			RecordRunResult("team-a", "report", fmt.Sprintf("result-%d", i))
+		}
+		RecordRunResult("team-a", "report", "result-0")
+		Expect(testutil.ToFloat64(runResults.WithLabelValues("team-a", "report", "result-0"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(runResults.WithLabelValues("team-a", "report", "other"))).To(Equal(2.0))
+		Expect(testutil.CollectAndCount(runResults)).To(Equal(MaxRunResults + 1))
+
+		ForgetCronJob("team-a", "report")
+		for _, collector := range []prometheus.Collector{lastRunStatus, runDuration, missedRuns, activeJobs,
+			scheduleDelay, timeSinceLastSuccess, sloRuns, sloObjective, runResults, cronJobCreationDelay} {
+			Expect(testutil.CollectAndCount(collector)).To(BeZero())
+		}
+		RecordMissedRun("team-a", "report", scheduled)
+		Expect(testutil.ToFloat64(missedRuns.WithLabelValues("team-a", "report"))).To(Equal(1.0))
+		ForgetCronJob("team-a", "report")
+	})
+
+	It("Should record the CloudEvents", func() {
+		RecordCloudEvent("com.example.batch.run.failed", EventFailed)
+		Expect(testutil.ToFloat64(cloudEvents.WithLabelValues("com.example.batch.run.failed", "failed"))).
+			To(Equal(1.0))
+	})
+
+	It("Should attach the trace IDs of the traced observations as exemplars", func() {
+		traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6}
+		ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: traceID, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled}))
+		RecordReconcile(ctx, ControllerCronJob, time.Now(), nil)
+		RecordReconcile(context.Background(), ControllerJobRun, time.Now(), nil)
+
+		exemplars := func(controller Controller) []string {
+			var metric dto.Metric
+			observer := reconcileDuration.WithLabelValues(string(controller), "success")
+			Expect(observer.(prometheus.Metric).Write(&metric)).To(Succeed())
+			var traceIDs []string
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				for _, label := range bucket.GetExemplar().GetLabel() {
+					traceIDs = append(traceIDs, label.GetName()+"="+label.GetValue())
+				}
+			}
+			return traceIDs
+		}
+		Expect(exemplars(ControllerCronJob)).To(ConsistOf("trace_id=" + traceID.String()))
+		Expect(exemplars(ControllerJobRun)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,104 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	webhookRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_webhook_requests_total",
+		Help: "Total number of admission requests handled by the CronJob webhooks.",
+	}, []string{"webhook", "operation", "result"})
+
+	webhookLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cronjob_webhook_request_duration_seconds",
+		Help:    "Latency of the admission requests handled by the CronJob webhooks.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"webhook", "operation"})
+
+	webhookRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_webhook_rejections_total",
+		Help: "Total number of failed validation rules, per rule.",
+	}, []string{"rule"})
+
+	webhookWarnings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_webhook_warnings_total",
+		Help: "Total number of warnings returned by the CronJob webhooks.",
+	}, []string{"webhook"})
+
+	webhookPolicyRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_webhook_policy_rejections_total",
+		Help: "Total number of CronJobs violating a policy, per policy.",
+	}, []string{"kind", "namespace", "policy"})
+
+	webhookValidationWarnings = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cronjob_webhook_validation_warnings_total",
+		Help: "Total number of warnings returned by the validating webhook, per type.",
+	}, []string{"type"})
+)
+
+// The warnings of the validating webhook are typed by the rule returning them, or by one of these types.
+const (
+	// WarningBypass tells why a requested bypass of the validation is not honored.
+	WarningBypass = "bypass"
+	// WarningDeprecation is a deprecated field set by the CronJob.
+	WarningDeprecation = "deprecation"
+)
+
+// AdmissionResult is the outcome of an admission request.
+type AdmissionResult string
+
+const (
+	// AdmissionAllowed is an allowed request.
+	AdmissionAllowed AdmissionResult = "allowed"
+	// AdmissionDenied is a request denied by a check of the webhook.
+	AdmissionDenied AdmissionResult = "denied"
+	// AdmissionErrored is a request which could not be handled, e.g. because it could not be decoded.
+	AdmissionErrored AdmissionResult = "errored"
+)
+
+// RecordAdmission records an admission request handled by the webhook, its result, latency and warnings.
+func RecordAdmission(webhook, operation string, result AdmissionResult, latency time.Duration, warnings int) {
+	webhookLatency.WithLabelValues(webhook, operation).Observe(latency.Seconds())
+	webhookRequests.WithLabelValues(webhook, operation, string(result)).Inc()
+	if warnings > 0 {
+		webhookWarnings.WithLabelValues(webhook).Add(float64(warnings))
+	}
+}
+
+// RecordRejection records a failed validation rule of the validating webhook.
+func RecordRejection(rule string) {
+	webhookRejections.WithLabelValues(rule).Inc()
+}
+
+// RecordPolicyRejection records a CronJob violating a policy. The namespace is the one of a CronJobPolicy, and empty
+// for a ClusterCronJobPolicy.
+func RecordPolicyRejection(kind, namespace, policy string) {
+	webhookPolicyRejections.WithLabelValues(kind, namespace, policy).Inc()
+}
+
+// RecordWarnings records the warnings of a type returned by the validating webhook, the type is the name of a
+// validation rule, WarningBypass or WarningDeprecation.
+func RecordWarnings(warningType string, count int) {
+	if count > 0 {
+		webhookValidationWarnings.WithLabelValues(warningType).Add(float64(count))
+	}
+}
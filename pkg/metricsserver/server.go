@@ -0,0 +1,115 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metricsserver serves the metrics of the manager over TLS, to the authenticated and authorized clients only.
+package metricsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/certrotation"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/config"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/filters"
+)
+
+/*
+The metrics server of controller-runtime only speaks plain HTTP, that is why the scaffold puts kube-rbac-proxy in
+front of it. This server replaces both: it serves the same registry over TLS and authenticates and authorizes every
+scrape against the API server. The certificate is read from the certificate directory and reloaded when it changes,
+a self-signed one is generated if the directory does not hold any.
+*/
+
+var log = logf.Log.WithName("secure-metrics")
+
+// Server serves the metrics endpoint over TLS.
+type Server struct {
+	// BindAddress is the address the server listens on, e.g. `:8443`.
+	BindAddress string
+	// CertDir holds the tls.crt and tls.key files of the server.
+	CertDir string
+	// Client authenticates and authorizes the requests.
+	Client client.Client
+	// ExtraHandlers are served next to the metrics, by path. They are protected the same way.
+	ExtraHandlers map[string]http.Handler
+	// TLS holds the TLS settings of the endpoint.
+	TLS configv1.TLSConfig
+}
+
+var _ manager.Runnable = &Server{}
+
+// Start implements manager.Runnable, it serves the metrics until the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	certs := &certrotation.CertificateLoader{Dir: s.CertDir, Server: "metrics"}
+	if _, err := certs.GetCertificate(nil); err != nil {
+		return fmt.Errorf("unable to load the metrics certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{GetCertificate: certs.GetCertificate}
+	if err := config.ApplyTLSConfig(tlsConfig, s.TLS); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", s.BindAddress, err)
+	}
+	listener = tls.NewListener(listener, tlsConfig)
+
+	handler := promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.HTTPErrorOnError,
+		// the exemplars of the histograms are only exposed in the OpenMetrics format
+		EnableOpenMetrics: true,
+	})
+	mux := http.NewServeMux()
+	protect := filters.WithAuthenticationAndAuthorization(s.Client)
+	mux.Handle("/metrics", protect(handler))
+	for path, extra := range s.ExtraHandlers {
+		mux.Handle(path, protect(extra))
+	}
+	server := &http.Server{Handler: mux, TLSNextProto: config.TLSNextProto(s.TLS)}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "unable to shut down the metrics server")
+		}
+	}()
+
+	log.Info("serving metrics over TLS", "address", s.BindAddress)
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica serves its own metrics.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package missedstarts finds the latest missed activation of a schedule, however many activations were missed.
+package missedstarts
+
+import (
+	"time"
+
+	"github.com/robfig/cron"
+)
+
+/*
+The activations missed since the last run are walked one by one, and the latest one is started. A schedule missed for
+long, like an every-minute CronJob suspended for months or a controller whose clock is off by years, would walk
+millions of activations. Beyond MaxWalked activations, the walk stops and the latest activation is searched for
+instead: the next activation of a time only moves forward with the time, so the latest time whose next activation is
+not after now is found by bisection, in about 40 steps for a century. A schedule without any activation within five
+years, like the 30th of February, has no next activation.
+*/
+
+// MaxWalked is how many missed activations are walked one by one, before the latest one is searched for.
+const MaxWalked = 100
+
+// Latest returns the latest activation of the schedule after since and not after now, zero if none, and whether more
+// than MaxWalked activations were missed.
+func Latest(sched cron.Schedule, since, now time.Time) (latest time.Time, capped bool) {
+	walked := 0
+	for t := sched.Next(since); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		latest = t
+		if walked++; walked > MaxWalked {
+			return search(sched, t, now), true
+		}
+	}
+	return latest, false
+}
+
+// search returns the latest activation of the schedule not after now, from an activation not after now.
+func search(sched cron.Schedule, from, now time.Time) time.Time {
+	// the latest activation is low itself or after it, and not after high. The activations are whole seconds, so
+	// the next one of a time less than a second before high is the latest one, if it is not after now.
+	low, high := from, now
+	for high.Sub(low) > time.Second {
+		middle := low.Add(high.Sub(low) / 2)
+		if next := sched.Next(middle); next.IsZero() || next.After(now) {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+	if next := sched.Next(low); !next.IsZero() && !next.After(now) {
+		return next
+	}
+	return from
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package missedstarts
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/robfig/cron"
+)
+
+// walk returns the latest activation of the schedule after since and not after now by walking all of them.
+func walk(sched cron.Schedule, since, now time.Time) time.Time {
+	var latest time.Time
+	for t := sched.Next(since); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		latest = t
+	}
+	return latest
+}
+
+var _ = Describe("Latest", func() {
+	now := time.Date(2021, time.June, 15, 10, 30, 45, 500, time.UTC)
+
+	It("Should find the latest missed activation", func() {
+		for _, missed := range []struct {
+			schedule string
+			since    time.Time
+			capped   bool
+		}{
+			{"0 * * * *", now.AddDate(0, 0, -3), false},
+			{"* * * * *", now.AddDate(0, -6, 0), true},
+			{"@every 1s", now.AddDate(0, 0, -1), true},
+			{"* 9 * * 1-5", now.AddDate(-1, 0, 0), true},
+			{"* * 1 * *", now.AddDate(-2, 0, 0), true},
+			{"0 0 29 2 *", now.AddDate(-20, 0, 0), false},
+		} {
+			sched, err := cron.ParseStandard(missed.schedule)
+			Expect(err).NotTo(HaveOccurred())
+			latest, capped := Latest(sched, missed.since, now)
+			Expect(capped).To(Equal(missed.capped), missed.schedule)
+			Expect(latest).To(Equal(walk(sched, missed.since, now)), missed.schedule)
+		}
+	})
+
+	It("Should find nothing before the first activation", func() {
+		sched, err := cron.ParseStandard("0 12 * * *")
+		Expect(err).NotTo(HaveOccurred())
+		latest, capped := Latest(sched, now.Add(-time.Hour), now)
+		Expect(latest.IsZero()).To(BeTrue())
+		Expect(capped).To(BeFalse())
+	})
+
+	It("Should find nothing for a schedule without activations", func() {
+		sched, err := cron.ParseStandard("0 0 30 2 *")
+		Expect(err).NotTo(HaveOccurred())
+		latest, capped := Latest(sched, now.AddDate(-1, 0, 0), now)
+		Expect(latest.IsZero()).To(BeTrue())
+		Expect(capped).To(BeFalse())
+	})
+})
+
+func BenchmarkLatest(b *testing.B) {
+	now := time.Date(2021, time.June, 15, 10, 30, 45, 0, time.UTC)
+	for _, bench := range []struct {
+		name     string
+		schedule string
+		since    time.Time
+	}{
+		{"hourly missed for a day", "0 * * * *", now.AddDate(0, 0, -1)},
+		{"every minute suspended for 6 months", "* * * * *", now.AddDate(0, -6, 0)},
+		{"every second missed for a month", "@every 1s", now.AddDate(0, -1, 0)},
+		{"every minute of 9am on weekdays missed for a year", "* 9 * * 1-5", now.AddDate(-1, 0, 0)},
+		{"every 5 minutes with a clock off by 30 years", "*/5 * * * *", now.AddDate(-30, 0, 0)},
+	} {
+		sched, err := cron.ParseStandard(bench.schedule)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Latest(sched, bench.since, now)
+			}
+		})
+	}
+}
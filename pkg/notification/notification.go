@@ -0,0 +1,611 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package notification delivers the notifications of the CronJobs to their NotificationChannels: Slack, HTTP
+// endpoints, mailboxes, PagerDuty and Opsgenie. The deliveries are queued, retried and rate limited per channel, and
+// counted in the status of the channel.
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/handover"
+)
+
+/*
+The controller only queues the notifications, a few workers deliver them in the background, so a slow SMTP server
+does not hold the reconciles up. A failed delivery is retried with an exponential backoff, up to 5 attempts, then it
+is counted as failed. The channels and their Secrets are read from the API server on every delivery rather than
+cached: there are few notifications, and caching all the Secrets of the cluster is not worth it.
+
+Every channel has a rate limit, a CronJob failing every minute does not page anyone 60 times an hour. The
+notifications over the limit are dropped and counted, not delayed, the next ones tell the same story anyway. The
+messages resolving the alerts of a CronJob are not limited, a dropped one would leave an alert open.
+
+A missed run is seen by every reconcile until the next run, its message is only queued once.
+
+The notifications of a finished Job are queued together, and the controller is called back once all of them were
+delivered, failed or dropped, to mark the Job as reported. A Job whose notifications are still queued when the manager
+stops is not marked, so the next leader queues them again. On shutdown, the deliveries waiting for their backoff are
+tried once more right away, and the ones failing again are left to the next leader.
+
+With a handover store, the notifications which were not delivered by the end of the shutdown, the ones left to the
+next leader and the ones still in the queue, are saved in the handover ConfigMap, and the next leader queues them
+again when it starts. The batches keep their key: when the controller of the new leader queues the notifications of
+the same Job again, the saved ones are adopted instead, so the Job is marked once they were delivered and nothing is
+sent twice. A notification being sent when the shutdown gives up is saved too, it may be delivered twice.
+
+In dry-run mode, the notifications are logged instead of delivered, and the Jobs are not marked, a dry-run instance
+would not persist the mark anyway. The batches are then remembered as long as the missed runs, past the hour after
+which the controller stops reporting a finished Job, so the notifications of a Job are logged once.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=notificationchannels,verbs=get
+//+kubebuilder:rbac:groups=batch.example.com,resources=notificationchannels/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+var log = logf.Log.WithName("notification")
+
+const (
+	deliveryWorkers     = 4
+	maxDeliveryAttempts = 5
+	sendTimeout         = 10 * time.Second
+	shutdownTimeout     = 10 * time.Second
+	defaultMaxPerHour   = 60
+	// maxFailureMessageLength bounds the failure message in the status of a channel.
+	maxFailureMessageLength = 512
+	// missedRunsPeriod is how long the missed runs queued are remembered.
+	missedRunsPeriod = 24 * time.Hour
+	// completedBatchesPeriod is how long the completed batches are remembered, for the caches to see them completed.
+	completedBatchesPeriod = 10 * time.Minute
+	// handoverKey is the key of the undelivered notifications in the handover ConfigMap.
+	handoverKey = "notifications"
+)
+
+// Message is a notification about a Job of a CronJob.
+type Message struct {
+	// Event is what happened to the Job.
+	Event v1.NotificationEvent
+	// CronJob is the CronJob of the Job.
+	CronJob types.NamespacedName
+	// Job is the name of the Job, empty for a missed run.
+	Job string
+	// RunID is the ID of the run of the Job, if it has one.
+	RunID string
+	// Time is when the Job finished, or when the missed run was scheduled.
+	Time time.Time
+	// Reason is why the Job failed, if it did.
+	Reason string
+	// ResolveOnly is set on the succeeded Jobs sent to a channel only to resolve the alert of the CronJob, the
+	// channels which do not resolve alerts drop them.
+	ResolveOnly bool
+}
+
+// Summary returns a line describing the notification.
+func (m Message) Summary() string {
+	if m.Event == v1.MissedDeadlineEvent {
+		return fmt.Sprintf("Run of CronJob %s scheduled at %s missed its starting deadline", m.CronJob,
+			m.Time.UTC().Format(time.RFC3339))
+	}
+	verb := "succeeded"
+	if m.Event == v1.JobFailedEvent {
+		verb = "failed"
+	}
+	summary := fmt.Sprintf("Job %s of CronJob %s %s", m.Job, m.CronJob, verb)
+	if m.Reason != "" {
+		summary += ": " + m.Reason
+	}
+	return summary
+}
+
+// Notification is a message for a channel.
+type Notification struct {
+	Channel types.NamespacedName
+	Message Message
+}
+
+// delivery is a notification queued for a channel.
+type delivery struct {
+	channel types.NamespacedName
+	message Message
+	// batch is the batch of the notification, if it was queued with others.
+	batch *batch
+}
+
+// leftover is a delivery left to the next leader, as saved in the handover ConfigMap.
+type leftover struct {
+	Channel types.NamespacedName `json:"channel"`
+	Message Message              `json:"message"`
+	// Batch is the key of the batch of the delivery, if it was queued with others.
+	Batch string `json:"batch,omitempty"`
+}
+
+// batch is a set of notifications queued together.
+type batch struct {
+	key       string
+	remaining int
+	// done is nil for the batches resumed from the previous leader until the controller queues them again.
+	done func(ctx context.Context) error
+	// completed is when the last notification of the batch was delivered, failed or dropped.
+	completed time.Time
+}
+
+// outcome is the result of a delivery.
+type outcome int
+
+const (
+	delivered outcome = iota
+	failed
+	dropped
+)
+
+// Dispatcher delivers the notifications to their channels.
+type Dispatcher struct {
+	client     client.Client
+	reader     client.Reader
+	httpClient *http.Client
+	queue      workqueue.RateLimitingInterface
+
+	lock       sync.Mutex
+	limiters   map[types.NamespacedName]*channelLimiter
+	missedRuns map[delivery]time.Time
+	batches    map[string]*batch
+	// backoff holds the deliveries waiting for their backoff, which the queue drops on shutdown.
+	backoff map[*delivery]struct{}
+	// pending holds the deliveries queued and not finished yet, which are handed over on shutdown.
+	pending  map[*delivery]struct{}
+	stopping bool
+	// dryRun is set when the notifications are logged instead of delivered.
+	dryRun bool
+	// handover keeps the undelivered notifications for the next leader, nil if they are not kept.
+	handover *handover.Store
+}
+
+// channelLimiter is the rate limiter of a channel, for its limit.
+type channelLimiter struct {
+	maxPerHour int32
+	limiter    *rate.Limiter
+}
+
+var _ manager.Runnable = &Dispatcher{}
+var _ manager.LeaderElectionRunnable = &Dispatcher{}
+
+// NewDispatcher returns the dispatcher reading the channels and their Secrets with the reader, and updating the
+// status of the channels with the client.
+func NewDispatcher(c client.Client, reader client.Reader) *Dispatcher {
+	return &Dispatcher{
+		client:     c,
+		reader:     reader,
+		httpClient: http.DefaultClient,
+		queue: workqueue.NewNamedRateLimitingQueue(
+			workqueue.NewItemExponentialFailureRateLimiter(5*time.Second, 5*time.Minute), "notifications"),
+		limiters:   map[types.NamespacedName]*channelLimiter{},
+		missedRuns: map[delivery]time.Time{},
+		batches:    map[string]*batch{},
+		backoff:    map[*delivery]struct{}{},
+		pending:    map[*delivery]struct{}{},
+	}
+}
+
+// DryRun makes the dispatcher log the notifications instead of delivering them, for the dry-run mode of the manager.
+func (d *Dispatcher) DryRun() {
+	d.dryRun = true
+}
+
+// Handover makes the dispatcher save the notifications it did not deliver before it stopped in the store, and resume
+// the ones saved by the previous leader when it starts.
+func (d *Dispatcher) Handover(store *handover.Store) {
+	d.handover = store
+}
+
+// Deliver queues the message for the channel. A nil Dispatcher delivers nothing.
+func (d *Dispatcher) Deliver(channel types.NamespacedName, message Message) {
+	if d == nil {
+		return
+	}
+	if message.Event == v1.MissedDeadlineEvent && !d.firstMissedRun(delivery{channel: channel, message: message}) {
+		return
+	}
+	d.add(&delivery{channel: channel, message: message})
+}
+
+// DeliverAll queues the notifications, and calls done once all of them were delivered, failed or dropped. The
+// notifications queued under the same key are not queued again until a while after, and done is not called if the
+// dispatcher stops before. The notifications resumed from the previous leader under the key are adopted instead. A
+// nil Dispatcher delivers nothing.
+func (d *Dispatcher) DeliverAll(key string, notifications []Notification, done func(ctx context.Context) error) {
+	if d == nil || len(notifications) == 0 {
+		return
+	}
+	now := time.Now()
+	period := completedBatchesPeriod
+	if d.dryRun {
+		period = missedRunsPeriod
+	}
+	d.lock.Lock()
+	for queued, b := range d.batches {
+		if !b.completed.IsZero() && now.Sub(b.completed) > period {
+			delete(d.batches, queued)
+		}
+	}
+	if b, ok := d.batches[key]; ok {
+		resumed := b.done == nil
+		if resumed {
+			b.done = done
+		}
+		completed := resumed && !b.completed.IsZero()
+		d.lock.Unlock()
+		if completed {
+			go d.complete(b)
+		}
+		return
+	}
+	b := &batch{key: key, remaining: len(notifications), done: done}
+	d.batches[key] = b
+	d.lock.Unlock()
+
+	for _, n := range notifications {
+		d.add(&delivery{channel: n.Channel, message: n.Message, batch: b})
+	}
+}
+
+// add queues the delivery.
+func (d *Dispatcher) add(item *delivery) {
+	d.lock.Lock()
+	d.pending[item] = struct{}{}
+	d.lock.Unlock()
+	d.queue.Add(item)
+}
+
+// firstMissedRun returns whether the missed run was not queued yet for the channel, and remembers it.
+func (d *Dispatcher) firstMissedRun(missed delivery) bool {
+	now := time.Now()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for queued, at := range d.missedRuns {
+		if now.Sub(at) > missedRunsPeriod {
+			delete(d.missedRuns, queued)
+		}
+	}
+	missed.message.Time = missed.message.Time.UTC()
+	if _, ok := d.missedRuns[missed]; ok {
+		return false
+	}
+	d.missedRuns[missed] = now
+	return true
+}
+
+// Start implements manager.Runnable, it resumes the notifications of the previous leader, delivers the notifications
+// until the manager stops, then waits a while for the ones in the queue and hands the undelivered ones over.
+func (d *Dispatcher) Start(ctx context.Context) error {
+	d.resume(ctx)
+	var workers sync.WaitGroup
+	for i := 0; i < deliveryWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for d.processNextDelivery(ctx) {
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	d.lock.Lock()
+	d.stopping = true
+	for item := range d.backoff {
+		d.queue.Add(item)
+	}
+	d.backoff = map[*delivery]struct{}{}
+	d.lock.Unlock()
+	d.queue.ShutDown()
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		log.Info("gave up waiting for the notifications in the queue")
+	}
+	d.handOver()
+	return nil
+}
+
+// resume queues the notifications saved by the previous leader.
+func (d *Dispatcher) resume(ctx context.Context) {
+	if d.handover == nil {
+		return
+	}
+	var leftovers []leftover
+	found, err := d.handover.Take(ctx, handoverKey, &leftovers)
+	if err != nil {
+		log.Error(err, "unable to resume the notifications of the previous leader")
+		return
+	}
+	if !found {
+		return
+	}
+
+	d.lock.Lock()
+	resumed := map[string]*batch{}
+	var items []*delivery
+	for _, l := range leftovers {
+		item := &delivery{channel: l.Channel, message: l.Message}
+		if l.Batch != "" {
+			b, ok := resumed[l.Batch]
+			if !ok {
+				if _, queued := d.batches[l.Batch]; queued {
+					// the controller queued the notifications of the Job again already
+					continue
+				}
+				b = &batch{key: l.Batch}
+				resumed[l.Batch] = b
+				d.batches[l.Batch] = b
+			}
+			b.remaining++
+			item.batch = b
+		}
+		items = append(items, item)
+	}
+	d.lock.Unlock()
+
+	for _, item := range items {
+		if item.batch == nil && item.message.Event == v1.MissedDeadlineEvent && !d.firstMissedRun(*item) {
+			continue
+		}
+		d.add(item)
+	}
+	log.Info("resumed the notifications of the previous leader", "notifications", len(items))
+}
+
+// handOver saves the notifications which were not delivered for the next leader.
+func (d *Dispatcher) handOver() {
+	if d.handover == nil {
+		return
+	}
+	d.lock.Lock()
+	leftovers := make([]leftover, 0, len(d.pending))
+	for item := range d.pending {
+		l := leftover{Channel: item.channel, Message: item.message}
+		if item.batch != nil {
+			l.Batch = item.batch.key
+		}
+		leftovers = append(leftovers, l)
+	}
+	d.lock.Unlock()
+	if len(leftovers) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := d.handover.Save(ctx, handoverKey, leftovers); err != nil {
+		log.Error(err, "unable to hand the undelivered notifications over", "notifications", len(leftovers))
+		return
+	}
+	log.Info("handed the undelivered notifications over to the next leader", "notifications", len(leftovers))
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the leader reconciles the CronJobs.
+func (d *Dispatcher) NeedLeaderElection() bool {
+	return true
+}
+
+// processNextDelivery delivers the next notification of the queue, it returns false once the queue is shut down.
+func (d *Dispatcher) processNextDelivery(ctx context.Context) bool {
+	item, shutdown := d.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer d.queue.Done(item)
+	next := item.(*delivery)
+	d.lock.Lock()
+	delete(d.backoff, next)
+	d.lock.Unlock()
+	logger := log.WithValues("channel", next.channel, "cronJob", next.message.CronJob, "job", next.message.Job)
+
+	// the manager context is cancelled on shutdown, the notifications in the queue still get their chance
+	sendCtx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	var channel v1.NotificationChannel
+	if err := d.reader.Get(sendCtx, next.channel, &channel); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("dropped a notification of a missing channel")
+			d.forgetLimiter(next.channel)
+			d.finish(sendCtx, next)
+			return true
+		}
+		d.retry(next, logger, err)
+		return true
+	}
+
+	resolves := next.message.Event == v1.JobSucceededEvent && autoResolves(&channel)
+	if next.message.ResolveOnly && !resolves {
+		d.finish(sendCtx, next)
+		return true
+	}
+	if d.dryRun {
+		logger.Info("would deliver a notification", "event", next.message.Event, "summary", next.message.Summary())
+		d.finish(sendCtx, next)
+		return true
+	}
+	if !resolves && d.queue.NumRequeues(item) == 0 && !d.allow(&channel) {
+		logger.Info("dropped a notification over the rate limit of the channel")
+		d.record(sendCtx, next.channel, dropped, nil)
+		d.finish(sendCtx, next)
+		return true
+	}
+
+	s, err := newSender(sendCtx, d.reader, d.httpClient, &channel)
+	if err == nil {
+		err = s.send(sendCtx, next.message)
+	}
+	if err != nil {
+		if d.queue.NumRequeues(item) < maxDeliveryAttempts-1 {
+			d.retry(next, logger, err)
+			return true
+		}
+		logger.Error(err, "unable to deliver a notification")
+		d.record(sendCtx, next.channel, failed, err)
+		d.finish(sendCtx, next)
+		return true
+	}
+	logger.V(1).Info("delivered a notification")
+	d.record(sendCtx, next.channel, delivered, nil)
+	d.finish(sendCtx, next)
+	return true
+}
+
+// retry queues the delivery again after a backoff. Once the dispatcher is stopping, the delivery is left to the next
+// leader instead.
+func (d *Dispatcher) retry(next *delivery, logger logr.Logger, err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.stopping {
+		logger.Info("left a notification to the next leader", "error", err.Error())
+		d.queue.Forget(next)
+		return
+	}
+	logger.V(1).Info("retrying a notification", "attempt", d.queue.NumRequeues(next)+1, "error", err.Error())
+	d.backoff[next] = struct{}{}
+	d.queue.AddRateLimited(next)
+}
+
+// finish forgets the delivery, and calls back the batch of the delivery once it was the last one of the batch.
+func (d *Dispatcher) finish(ctx context.Context, next *delivery) {
+	d.queue.Forget(next)
+	d.lock.Lock()
+	delete(d.pending, next)
+	b := next.batch
+	if b == nil {
+		d.lock.Unlock()
+		return
+	}
+	b.remaining--
+	last := b.remaining == 0
+	done := b.done
+	d.lock.Unlock()
+	if !last {
+		return
+	}
+	if d.dryRun {
+		log.V(1).Info("not marking the notifications complete in dry-run mode", "key", b.key)
+	} else if done != nil {
+		if err := done(ctx); err != nil {
+			log.Error(err, "unable to complete the notifications", "key", b.key)
+		}
+	}
+	d.lock.Lock()
+	b.completed = time.Now()
+	d.lock.Unlock()
+}
+
+// complete calls back a resumed batch which completed before the controller queued it again.
+func (d *Dispatcher) complete(b *batch) {
+	if d.dryRun {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := b.done(ctx); err != nil {
+		log.Error(err, "unable to complete the notifications", "key", b.key)
+	}
+}
+
+// allow returns whether the rate limit of the channel allows one more notification.
+func (d *Dispatcher) allow(channel *v1.NotificationChannel) bool {
+	maxPerHour := int32(defaultMaxPerHour)
+	if channel.Spec.MaxPerHour != nil && *channel.Spec.MaxPerHour > 0 {
+		maxPerHour = *channel.Spec.MaxPerHour
+	}
+
+	key := client.ObjectKeyFromObject(channel)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	l, ok := d.limiters[key]
+	if !ok || l.maxPerHour != maxPerHour {
+		l = &channelLimiter{
+			maxPerHour: maxPerHour,
+			limiter:    rate.NewLimiter(rate.Every(time.Hour/time.Duration(maxPerHour)), int(maxPerHour)),
+		}
+		d.limiters[key] = l
+	}
+	return l.limiter.Allow()
+}
+
+// autoResolves returns whether the channel resolves the alerts of the CronJobs.
+func autoResolves(channel *v1.NotificationChannel) bool {
+	spec := &channel.Spec
+	return (spec.PagerDuty != nil && spec.PagerDuty.AutoResolve) || (spec.Opsgenie != nil && spec.Opsgenie.AutoResolve)
+}
+
+// forgetLimiter drops the rate limiter of a deleted channel.
+func (d *Dispatcher) forgetLimiter(key types.NamespacedName) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.limiters, key)
+}
+
+// record counts the outcome of a delivery in the status of the channel.
+func (d *Dispatcher) record(ctx context.Context, key types.NamespacedName, result outcome, deliveryErr error) {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var channel v1.NotificationChannel
+		if err := d.reader.Get(ctx, key, &channel); err != nil {
+			return err
+		}
+		now := metav1.Now()
+		switch result {
+		case delivered:
+			channel.Status.Delivered++
+			channel.Status.LastDeliveryTime = &now
+		case failed:
+			channel.Status.Failed++
+			channel.Status.LastFailureTime = &now
+			channel.Status.LastFailureMessage = truncate(deliveryErr.Error(), maxFailureMessageLength)
+		case dropped:
+			channel.Status.Dropped++
+		}
+		return d.client.Status().Update(ctx, &channel)
+	})
+	if client.IgnoreNotFound(err) != nil {
+		log.Error(err, "unable to update NotificationChannel status", "channel", key)
+	}
+}
+
+// truncate returns the first n bytes of s.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,420 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/handover"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Dispatcher", func() {
+	var (
+		server   *httptest.Server
+		requests chan map[string]interface{}
+		status   int
+		message  = Message{
+			Event:   v1.JobFailedEvent,
+			CronJob: types.NamespacedName{Namespace: "default", Name: "report"},
+			Job:     "report-1622851200",
+			RunID:   "5f0c4a4e-7a3c-5d0e-9b1e-2f4f3c1d8a60",
+			Time:    time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC),
+			Reason:  "BackoffLimitExceeded",
+		}
+	)
+
+	BeforeEach(func() {
+		requests = make(chan map[string]interface{}, 10)
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			body["authorization"] = r.Header.Get("Authorization")
+			body["path"] = r.URL.RequestURI()
+			requests <- body
+			w.WriteHeader(status)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newDispatcher := func(objects ...client.Object) (*Dispatcher, client.Client) {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+		d := NewDispatcher(c, c)
+		d.httpClient = server.Client()
+		return d, c
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "credentials"},
+		Data:       map[string][]byte{"token": []byte("Bearer s3cr3t\n")},
+	}
+	channelStatus := func(c client.Client, name string) v1.NotificationChannelStatus {
+		var channel v1.NotificationChannel
+		Expect(c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, &channel)).
+			To(Succeed())
+		return channel.Status
+	}
+
+	It("Should post to the webhooks and count the deliveries", func() {
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ops"},
+			Spec: v1.NotificationChannelSpec{Webhook: &v1.WebhookChannel{URL: server.URL,
+				AuthorizationSecretRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"}, Key: "token"}}},
+		}
+		d, c := newDispatcher(secret, channel)
+
+		d.Deliver(client.ObjectKeyFromObject(channel), message)
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(requests).To(Receive(And(
+			HaveKeyWithValue("event", "JobFailed"),
+			HaveKeyWithValue("cronJob", "report"),
+			HaveKeyWithValue("runID", "5f0c4a4e-7a3c-5d0e-9b1e-2f4f3c1d8a60"),
+			HaveKeyWithValue("reason", "BackoffLimitExceeded"),
+			HaveKeyWithValue("authorization", "Bearer s3cr3t"),
+		)))
+		Expect(channelStatus(c, "ops").Delivered).To(Equal(int64(1)))
+	})
+
+	It("Should retry the failed deliveries before giving up", func() {
+		status = http.StatusServiceUnavailable
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ops"},
+			Spec:       v1.NotificationChannelSpec{Webhook: &v1.WebhookChannel{URL: server.URL}},
+		}
+		d, c := newDispatcher(channel)
+		item := &delivery{channel: client.ObjectKeyFromObject(channel), message: message}
+
+		for attempt := 1; attempt < maxDeliveryAttempts; attempt++ {
+			d.queue.Add(item)
+			Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+			Expect(d.queue.NumRequeues(item)).To(Equal(attempt))
+			Expect(channelStatus(c, "ops").Failed).To(BeZero())
+		}
+		d.queue.Add(item)
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(requests).To(HaveLen(maxDeliveryAttempts))
+		Expect(channelStatus(c, "ops").Failed).To(Equal(int64(1)))
+		Expect(channelStatus(c, "ops").LastFailureMessage).To(Equal("unexpected response 503 Service Unavailable"))
+	})
+
+	It("Should drop the notifications over the rate limit", func() {
+		maxPerHour := int32(1)
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ops"},
+			Spec:       v1.NotificationChannelSpec{Webhook: &v1.WebhookChannel{URL: server.URL}, MaxPerHour: &maxPerHour},
+		}
+		d, c := newDispatcher(channel)
+
+		d.Deliver(client.ObjectKeyFromObject(channel), message)
+		d.Deliver(client.ObjectKeyFromObject(channel), message)
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(requests).To(HaveLen(1))
+		Expect(channelStatus(c, "ops").Delivered).To(Equal(int64(1)))
+		Expect(channelStatus(c, "ops").Dropped).To(Equal(int64(1)))
+	})
+
+	It("Should fail the channels without exactly one kind", func() {
+		channel := &v1.NotificationChannel{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ops"}}
+		_, err := newSender(context.Background(), nil, nil, channel)
+		Expect(err).To(MatchError(ContainSubstring("exactly one of")))
+	})
+
+	It("Should trigger PagerDuty incidents", func() {
+		defer func(url string) { pagerDutyEventsURL = url }(pagerDutyEventsURL)
+		pagerDutyEventsURL = server.URL
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pager"},
+			Spec: v1.NotificationChannelSpec{PagerDuty: &v1.PagerDutyChannel{RoutingKeySecretRef: corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"}, Key: "token"}}},
+		}
+		d, _ := newDispatcher(secret, channel)
+
+		d.Deliver(client.ObjectKeyFromObject(channel), message)
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		var body map[string]interface{}
+		Expect(requests).To(Receive(&body))
+		Expect(body).To(HaveKeyWithValue("routing_key", "Bearer s3cr3t"))
+		Expect(body).To(HaveKeyWithValue("dedup_key", "default/report/report-1622851200"))
+		Expect(body["payload"]).To(HaveKeyWithValue("severity", "error"))
+	})
+
+	It("Should resolve the PagerDuty incident of a CronJob when a Job succeeds", func() {
+		defer func(url string) { pagerDutyEventsURL = url }(pagerDutyEventsURL)
+		pagerDutyEventsURL = server.URL
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pager"},
+			Spec: v1.NotificationChannelSpec{PagerDuty: &v1.PagerDutyChannel{RoutingKeySecretRef: corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"}, Key: "token"},
+				AutoResolve: true}},
+		}
+		d, _ := newDispatcher(secret, channel)
+
+		d.Deliver(client.ObjectKeyFromObject(channel), message)
+		succeeded := Message{Event: v1.JobSucceededEvent, CronJob: message.CronJob, Job: "report-1622854800",
+			Time: message.Time.Add(time.Hour), ResolveOnly: true}
+		d.Deliver(client.ObjectKeyFromObject(channel), succeeded)
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+
+		var trigger, resolve map[string]interface{}
+		Expect(requests).To(Receive(&trigger))
+		Expect(trigger).To(HaveKeyWithValue("event_action", "trigger"))
+		Expect(trigger).To(HaveKeyWithValue("dedup_key", "default/report"))
+		Expect(requests).To(Receive(&resolve))
+		Expect(resolve).To(HaveKeyWithValue("event_action", "resolve"))
+		Expect(resolve).To(HaveKeyWithValue("dedup_key", "default/report"))
+		Expect(resolve).NotTo(HaveKey("payload"))
+	})
+
+	It("Should create and close Opsgenie alerts", func() {
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "genie"},
+			Spec: v1.NotificationChannelSpec{Opsgenie: &v1.OpsgenieChannel{APIKeySecretRef: corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "opsgenie"}, Key: "apiKey"},
+				APIURL: server.URL, Priority: "P2", AutoResolve: true}},
+		}
+		d, c := newDispatcher(channel, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "opsgenie"},
+			Data:       map[string][]byte{"apiKey": []byte("genie-key")},
+		})
+
+		missed := Message{Event: v1.MissedDeadlineEvent, CronJob: message.CronJob, Time: message.Time}
+		d.Deliver(client.ObjectKeyFromObject(channel), missed)
+		d.Deliver(client.ObjectKeyFromObject(channel), Message{Event: v1.JobSucceededEvent, CronJob: message.CronJob,
+			Job: "report-1622854800", Time: message.Time.Add(time.Hour)})
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+
+		var alert, closed map[string]interface{}
+		Expect(requests).To(Receive(&alert))
+		Expect(alert).To(HaveKeyWithValue("path", "/v2/alerts"))
+		Expect(alert).To(HaveKeyWithValue("authorization", "GenieKey genie-key"))
+		Expect(alert).To(HaveKeyWithValue("alias", "default/report"))
+		Expect(alert).To(HaveKeyWithValue("priority", "P2"))
+		Expect(alert).To(HaveKeyWithValue("message",
+			"Run of CronJob default/report scheduled at 2021-06-05T00:00:00Z missed its starting deadline"))
+		Expect(requests).To(Receive(&closed))
+		Expect(closed).To(HaveKeyWithValue("path", "/v2/alerts/default%2Freport/close?identifierType=alias"))
+		Expect(channelStatus(c, "genie").Delivered).To(Equal(int64(2)))
+	})
+
+	It("Should drop the messages resolving alerts for the channels without alerts", func() {
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ops"},
+			Spec:       v1.NotificationChannelSpec{Webhook: &v1.WebhookChannel{URL: server.URL}},
+		}
+		d, c := newDispatcher(channel)
+
+		d.Deliver(client.ObjectKeyFromObject(channel), Message{Event: v1.JobSucceededEvent, CronJob: message.CronJob,
+			Job: "report-1622854800", Time: message.Time, ResolveOnly: true})
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(requests).To(BeEmpty())
+		Expect(channelStatus(c, "ops")).To(Equal(v1.NotificationChannelStatus{}))
+	})
+
+	It("Should call back once all the notifications queued together were delivered", func() {
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ops"},
+			Spec:       v1.NotificationChannelSpec{Webhook: &v1.WebhookChannel{URL: server.URL}},
+		}
+		d, _ := newDispatcher(channel)
+		notifications := []Notification{
+			{Channel: client.ObjectKeyFromObject(channel), Message: message},
+			{Channel: types.NamespacedName{Namespace: "default", Name: "missing"}, Message: message},
+		}
+		done := 0
+		d.DeliverAll("default/report-1622851200", notifications, func(context.Context) error {
+			done++
+			return nil
+		})
+		d.DeliverAll("default/report-1622851200", notifications, func(context.Context) error {
+			done++
+			return nil
+		})
+		Expect(d.queue.Len()).To(Equal(2))
+
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(done).To(BeZero())
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(done).To(Equal(1))
+		Expect(requests).To(HaveLen(1))
+	})
+
+	It("Should try the notifications waiting for a retry once more on shutdown", func() {
+		status = http.StatusServiceUnavailable
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ops"},
+			Spec:       v1.NotificationChannelSpec{Webhook: &v1.WebhookChannel{URL: server.URL}},
+		}
+		d, c := newDispatcher(channel)
+		done := make(chan struct{})
+		d.DeliverAll("default/report-1622851200", []Notification{{Channel: client.ObjectKeyFromObject(channel),
+			Message: message}}, func(context.Context) error {
+			close(done)
+			return nil
+		})
+		failed := message
+		failed.Job = "report-1622854800"
+		d.DeliverAll("default/report-1622854800", []Notification{{Channel: client.ObjectKeyFromObject(channel),
+			Message: failed}}, func(context.Context) error {
+			Fail("the notification failed on shutdown")
+			return nil
+		})
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(requests).To(HaveLen(2))
+		Expect(d.backoff).To(HaveLen(2))
+
+		// the first one succeeds on shutdown, the other one is left to the next leader
+		<-requests
+		<-requests
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			requests <- body
+			if body["job"] != "report-1622851200" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(d.Start(ctx)).To(Succeed())
+		Expect(done).To(BeClosed())
+		Expect(requests).To(HaveLen(2))
+		Expect(channelStatus(c, "ops").Delivered).To(Equal(int64(1)))
+		Expect(channelStatus(c, "ops").Failed).To(BeZero())
+	})
+
+	It("Should resume the notifications left by the previous leader once", func() {
+		status = http.StatusServiceUnavailable
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ops"},
+			Spec:       v1.NotificationChannelSpec{Webhook: &v1.WebhookChannel{URL: server.URL}},
+		}
+		d, c := newDispatcher(channel)
+		store := &handover.Store{Client: c, Reader: c, Namespace: "operator-system"}
+		d.Handover(store)
+		notifications := []Notification{{Channel: client.ObjectKeyFromObject(channel), Message: message}}
+		d.DeliverAll("default/report-1622851200", notifications, func(context.Context) error {
+			Fail("the notification failed on shutdown")
+			return nil
+		})
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+
+		// the leader stops in the middle of the retries
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(d.Start(ctx)).To(Succeed())
+		Expect(requests).To(HaveLen(2))
+		Expect(channelStatus(c, "ops").Delivered).To(BeZero())
+
+		// the next leader resumes them, and adopts them when its controller queues them again
+		status = http.StatusOK
+		next := NewDispatcher(c, c)
+		next.httpClient = server.Client()
+		next.Handover(store)
+		next.resume(context.Background())
+		Expect(next.queue.Len()).To(Equal(1))
+		marked := 0
+		next.DeliverAll("default/report-1622851200", notifications, func(context.Context) error {
+			marked++
+			return nil
+		})
+		Expect(next.queue.Len()).To(Equal(1))
+		Expect(next.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(requests).To(HaveLen(3))
+		Expect(channelStatus(c, "ops").Delivered).To(Equal(int64(1)))
+		Expect(marked).To(Equal(1))
+
+		var leftovers []leftover
+		Expect(store.Take(context.Background(), handoverKey, &leftovers)).To(BeFalse())
+	})
+
+	It("Should only log the notifications in dry-run mode", func() {
+		channel := &v1.NotificationChannel{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "ops"},
+			Spec:       v1.NotificationChannelSpec{Webhook: &v1.WebhookChannel{URL: server.URL}},
+		}
+		d, c := newDispatcher(channel)
+		d.DryRun()
+		notifications := []Notification{{Channel: client.ObjectKeyFromObject(channel), Message: message}}
+		d.DeliverAll("default/report-1622851200", notifications, func(context.Context) error {
+			Fail("the Job was marked in dry-run mode")
+			return nil
+		})
+		Expect(d.processNextDelivery(context.Background())).To(BeTrue())
+		Expect(requests).To(BeEmpty())
+		Expect(channelStatus(c, "ops").Delivered).To(BeZero())
+
+		// the Job is never marked, its notifications are not logged again on the next reconciles
+		d.batches["default/report-1622851200"].completed = time.Now().Add(-2 * completedBatchesPeriod)
+		d.DeliverAll("default/report-1622851200", notifications, func(context.Context) error {
+			Fail("the Job was marked in dry-run mode")
+			return nil
+		})
+		Expect(d.queue.Len()).To(BeZero())
+	})
+
+	It("Should queue a missed run once", func() {
+		channel := types.NamespacedName{Namespace: "default", Name: "ops"}
+		d, _ := newDispatcher()
+		missed := Message{Event: v1.MissedDeadlineEvent, CronJob: message.CronJob, Time: message.Time}
+
+		d.Deliver(channel, missed)
+		d.Deliver(channel, missed)
+		Expect(d.queue.Len()).To(Equal(1))
+		missed.Time = missed.Time.Add(time.Hour)
+		d.Deliver(channel, missed)
+		Expect(d.queue.Len()).To(Equal(2))
+	})
+})
+
+var _ = Describe("emailSender", func() {
+	It("Should write the mail with its headers", func() {
+		s := &emailSender{from: "cron@example.com", to: []string{"ops@example.com", "dev@example.com"}}
+		mail := string(s.mail(Message{
+			Event:   v1.JobSucceededEvent,
+			CronJob: types.NamespacedName{Namespace: "default", Name: "report"},
+			Job:     "report-1622851200",
+			Time:    time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC),
+		}))
+		Expect(mail).To(ContainSubstring("To: ops@example.com, dev@example.com\r\n"))
+		Expect(mail).To(ContainSubstring("Subject: Job report-1622851200 of CronJob default/report succeeded\r\n"))
+	})
+})
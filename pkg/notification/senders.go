@@ -0,0 +1,436 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+/*
+Each kind of channel has its sender, built from the spec of the channel and its Secrets for every delivery, so a
+rotated credential is picked up right away. The responses of the endpoints are not reported, only their status: the
+URLs come from the users, the response of whatever they point to does not belong to the status of the channel.
+*/
+
+var (
+	// pagerDutyEventsURL is the endpoint of the Events API v2 of PagerDuty.
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	// opsgenieAPIURL is the default URL of the Alert API of Opsgenie.
+	opsgenieAPIURL = "https://api.opsgenie.com"
+)
+
+// opsgenieMaxMessageLength is the length of the message of an Opsgenie alert at most.
+const opsgenieMaxMessageLength = 130
+
+// sender delivers the messages to a channel.
+type sender interface {
+	send(ctx context.Context, message Message) error
+}
+
+// newSender returns the sender of the channel, reading its Secrets with the reader.
+func newSender(ctx context.Context, reader client.Reader, httpClient *http.Client,
+	channel *v1.NotificationChannel) (sender, error) {
+	spec := &channel.Spec
+	kinds := 0
+	for _, set := range []bool{spec.Slack != nil, spec.Webhook != nil, spec.Email != nil, spec.PagerDuty != nil,
+		spec.Opsgenie != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, errors.New("exactly one of slack, webhook, email, pagerDuty and opsgenie must be set")
+	}
+
+	secrets := secretReader{reader: reader, namespace: channel.Namespace}
+	switch {
+	case spec.Slack != nil:
+		url, err := secrets.value(ctx, spec.Slack.WebhookURLSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		return &slackSender{client: httpClient, url: url}, nil
+	case spec.Webhook != nil:
+		s := &webhookSender{client: httpClient, url: spec.Webhook.URL}
+		if ref := spec.Webhook.AuthorizationSecretRef; ref != nil {
+			var err error
+			if s.authorization, err = secrets.value(ctx, *ref); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	case spec.Email != nil:
+		s := &emailSender{address: spec.Email.SMTPAddress, from: spec.Email.From, to: spec.Email.To}
+		if ref := spec.Email.CredentialsSecretRef; ref != nil {
+			var err error
+			if s.username, err = secrets.value(ctx, corev1.SecretKeySelector{LocalObjectReference: *ref,
+				Key: "username"}); err != nil {
+				return nil, err
+			}
+			if s.password, err = secrets.value(ctx, corev1.SecretKeySelector{LocalObjectReference: *ref,
+				Key: "password"}); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	case spec.PagerDuty != nil:
+		routingKey, err := secrets.value(ctx, spec.PagerDuty.RoutingKeySecretRef)
+		if err != nil {
+			return nil, err
+		}
+		severity := spec.PagerDuty.Severity
+		if severity == "" {
+			severity = "error"
+		}
+		return &pagerDutySender{client: httpClient, routingKey: routingKey, severity: severity,
+			autoResolve: spec.PagerDuty.AutoResolve}, nil
+	default:
+		apiKey, err := secrets.value(ctx, spec.Opsgenie.APIKeySecretRef)
+		if err != nil {
+			return nil, err
+		}
+		s := &opsgenieSender{client: httpClient, url: strings.TrimSuffix(spec.Opsgenie.APIURL, "/"), apiKey: apiKey,
+			priority: spec.Opsgenie.Priority, autoResolve: spec.Opsgenie.AutoResolve}
+		if s.url == "" {
+			s.url = opsgenieAPIURL
+		}
+		if s.priority == "" {
+			s.priority = "P3"
+		}
+		return s, nil
+	}
+}
+
+// secretReader reads the keys of the Secrets of a namespace.
+type secretReader struct {
+	reader    client.Reader
+	namespace string
+}
+
+func (r secretReader) value(ctx context.Context, ref corev1.SecretKeySelector) (string, error) {
+	var secret corev1.Secret
+	if err := r.reader.Get(ctx, client.ObjectKey{Namespace: r.namespace, Name: ref.Name}, &secret); err != nil {
+		return "", fmt.Errorf("unable to read Secret %s: %w", ref.Name, err)
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("key %s missing from Secret %s", ref.Key, ref.Name)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// slackSender posts the messages to a Slack incoming webhook.
+type slackSender struct {
+	client *http.Client
+	url    string
+}
+
+func (s *slackSender) send(ctx context.Context, message Message) error {
+	return postJSON(ctx, s.client, s.url, map[string]string{"text": message.Summary()}, nil)
+}
+
+// webhookSender posts the messages as JSON to an HTTP endpoint.
+type webhookSender struct {
+	client        *http.Client
+	url           string
+	authorization string
+}
+
+// webhookPayload is the body posted to the webhook channels.
+type webhookPayload struct {
+	Event     v1.NotificationEvent `json:"event"`
+	Namespace string               `json:"namespace"`
+	CronJob   string               `json:"cronJob"`
+	Job       string               `json:"job,omitempty"`
+	RunID     string               `json:"runID,omitempty"`
+	Time      string               `json:"time"`
+	Reason    string               `json:"reason,omitempty"`
+	Summary   string               `json:"summary"`
+}
+
+func (s *webhookSender) send(ctx context.Context, message Message) error {
+	payload := webhookPayload{
+		Event:     message.Event,
+		Namespace: message.CronJob.Namespace,
+		CronJob:   message.CronJob.Name,
+		Job:       message.Job,
+		RunID:     message.RunID,
+		Time:      message.Time.UTC().Format(time.RFC3339),
+		Reason:    message.Reason,
+		Summary:   message.Summary(),
+	}
+	var header http.Header
+	if s.authorization != "" {
+		header = http.Header{"Authorization": []string{s.authorization}}
+	}
+	return postJSON(ctx, s.client, s.url, payload, header)
+}
+
+// alertKey returns the key deduplicating the alerts of the message: the CronJob when its alert is resolved by the
+// next succeeded Job, else the Job or the missed run.
+func alertKey(message Message, autoResolve bool) string {
+	switch {
+	case autoResolve:
+		return message.CronJob.String()
+	case message.Event == v1.MissedDeadlineEvent:
+		return fmt.Sprintf("%s/%d", message.CronJob, message.Time.Unix())
+	default:
+		return message.CronJob.String() + "/" + message.Job
+	}
+}
+
+// pagerDutySender triggers incidents with the Events API v2 of PagerDuty.
+type pagerDutySender struct {
+	client      *http.Client
+	routingKey  string
+	severity    string
+	autoResolve bool
+}
+
+// The subset of the events of the Events API v2 which is sent.
+type (
+	pagerDutyEvent struct {
+		RoutingKey  string            `json:"routing_key"`
+		EventAction string            `json:"event_action"`
+		DedupKey    string            `json:"dedup_key"`
+		Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	}
+	pagerDutyPayload struct {
+		Summary   string `json:"summary"`
+		Source    string `json:"source"`
+		Severity  string `json:"severity"`
+		Timestamp string `json:"timestamp"`
+		Component string `json:"component"`
+	}
+)
+
+func (s *pagerDutySender) send(ctx context.Context, message Message) error {
+	dedupKey := alertKey(message, s.autoResolve)
+	if s.autoResolve && message.Event == v1.JobSucceededEvent {
+		return postJSON(ctx, s.client, pagerDutyEventsURL, pagerDutyEvent{
+			RoutingKey:  s.routingKey,
+			EventAction: "resolve",
+			DedupKey:    dedupKey,
+		}, nil)
+	}
+	severity := s.severity
+	if message.Event == v1.JobSucceededEvent {
+		severity = "info"
+	}
+	return postJSON(ctx, s.client, pagerDutyEventsURL, pagerDutyEvent{
+		RoutingKey:  s.routingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKey,
+		Payload: &pagerDutyPayload{
+			Summary:   message.Summary(),
+			Source:    message.CronJob.String(),
+			Severity:  severity,
+			Timestamp: message.Time.UTC().Format(time.RFC3339),
+			Component: "cronjob",
+		},
+	}, nil)
+}
+
+// opsgenieSender creates alerts with the Alert API of Opsgenie.
+type opsgenieSender struct {
+	client      *http.Client
+	url         string
+	apiKey      string
+	priority    string
+	autoResolve bool
+}
+
+// The subsets of the requests of the Alert API which are sent.
+type (
+	opsgenieAlert struct {
+		Message     string            `json:"message"`
+		Alias       string            `json:"alias"`
+		Description string            `json:"description,omitempty"`
+		Source      string            `json:"source"`
+		Priority    string            `json:"priority"`
+		Tags        []string          `json:"tags"`
+		Details     map[string]string `json:"details"`
+	}
+	opsgenieClose struct {
+		Source string `json:"source"`
+		Note   string `json:"note"`
+	}
+)
+
+func (s *opsgenieSender) send(ctx context.Context, message Message) error {
+	alias := alertKey(message, s.autoResolve)
+	header := http.Header{"Authorization": []string{"GenieKey " + s.apiKey}}
+	if s.autoResolve && message.Event == v1.JobSucceededEvent {
+		url := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", s.url, neturl.PathEscape(alias))
+		return postJSON(ctx, s.client, url, opsgenieClose{Source: message.CronJob.String(), Note: message.Summary()},
+			header)
+	}
+	priority := s.priority
+	if message.Event == v1.JobSucceededEvent {
+		priority = "P5"
+	}
+	details := map[string]string{"namespace": message.CronJob.Namespace, "cronJob": message.CronJob.Name}
+	if message.Job != "" {
+		details["job"] = message.Job
+	}
+	if message.RunID != "" {
+		details["runID"] = message.RunID
+	}
+	return postJSON(ctx, s.client, s.url+"/v2/alerts", opsgenieAlert{
+		Message:     truncate(message.Summary(), opsgenieMaxMessageLength),
+		Alias:       alias,
+		Description: message.Reason,
+		Source:      message.CronJob.String(),
+		Priority:    priority,
+		Tags:        []string{"cronjob", string(message.Event)},
+		Details:     details,
+	}, header)
+}
+
+// emailSender mails the messages through an SMTP server.
+type emailSender struct {
+	address            string
+	from               string
+	to                 []string
+	username, password string
+}
+
+func (s *emailSender) send(ctx context.Context, message Message) error {
+	host, _, err := net.SplitHostPort(s.address)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %w", s.address, err)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	// net/smtp refuses to send the credentials without TLS, except to localhost
+	if s.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.username, s.password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range s.to {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.mail(message)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// mail returns the mail of the message, with its headers.
+func (s *emailSender) mail(message Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue(message.Summary())))
+	fmt.Fprintf(&b, "Date: %s\r\n", message.Time.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&b, "%s\r\n\r\nNamespace: %s\r\nCronJob: %s\r\n", message.Summary(), message.CronJob.Namespace,
+		message.CronJob.Name)
+	if message.Job != "" {
+		fmt.Fprintf(&b, "Job: %s\r\n", message.Job)
+	}
+	if message.RunID != "" {
+		fmt.Fprintf(&b, "Run ID: %s\r\n", message.RunID)
+	}
+	return b.Bytes()
+}
+
+// headerValue returns the text as a single header line.
+func headerValue(text string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(text)
+}
+
+// postJSON posts the payload as JSON and checks the status of the response.
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}, header http.Header) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// the URL of a Slack webhook is a credential
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("request failed: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response %s", resp.Status)
+	}
+	return nil
+}
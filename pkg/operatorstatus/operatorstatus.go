@@ -0,0 +1,272 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package operatorstatus keeps the OperatorStatus singleton, the health of the operator as an object of the API.
+package operatorstatus
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+/*
+The health of the controllers comes from the metrics of controller-runtime, the ones the dashboards read: the depth of
+the work queues, the reconciles and the reconcile errors. The counters are totals since the manager started, the error
+rate is computed from their increase since the previous update, so it follows the current health rather than the
+whole life of the replica.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=operatorstatuses,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=batch.example.com,resources=operatorstatuses/status,verbs=get;update
+
+var log = logf.Log.WithName("operatorstatus")
+
+// defaultInterval is how often the OperatorStatus is updated by default.
+const defaultInterval = 30 * time.Second
+
+// Updater keeps the OperatorStatus up to date while this replica holds the lease.
+type Updater struct {
+	Client client.Client
+	// APIReader reads the OperatorStatus, so the singleton is not cached by the manager. Defaults to Client.
+	APIReader client.Reader
+	// Gatherer holds the metrics of the controllers, usually the registry of controller-runtime.
+	Gatherer prometheus.Gatherer
+	// Identity is the identity of this replica, the name of its pod.
+	Identity string
+	// Interval is how often the OperatorStatus is updated, defaults to 30 seconds.
+	Interval time.Duration
+
+	lock        sync.Mutex
+	configHash  string
+	leaderSince metav1.Time
+	previous    map[string]v1.ControllerHealth
+}
+
+var _ manager.Runnable = &Updater{}
+var _ manager.LeaderElectionRunnable = &Updater{}
+
+// Setup returns the Updater of the config, identified by the hostname of the replica and hashing the config. It
+// returns nil if the OperatorStatus is disabled.
+func Setup(config *configv1.ProjectConfig, c client.Client, reader client.Reader,
+	gatherer prometheus.Gatherer) (*Updater, error) {
+	if !config.OperatorStatus.Enabled {
+		return nil, nil
+	}
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	u := &Updater{Client: c, APIReader: reader, Gatherer: gatherer, Identity: identity}
+	if interval := config.OperatorStatus.Interval; interval != nil {
+		u.Interval = interval.Duration
+	}
+	if err := u.SetConfig(config); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Permissions returns the permissions the Updater of the config needs, none if the OperatorStatus is disabled.
+func Permissions(config configv1.OperatorStatusConfig) []startup.Permission {
+	if !config.Enabled {
+		return nil
+	}
+	group := v1.GroupVersion.Group
+	return append(startup.Permissions(group, "operatorstatuses", []string{"get", "create"}),
+		startup.Permissions(group, "operatorstatuses/status", []string{"update"})...)
+}
+
+// ConfigHash returns the SHA-256 hash of the config, as hexadecimal.
+func ConfigHash(config *configv1.ProjectConfig) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SetConfig sets the config in effect, it is a config.Reloader so the hash follows the reloads of the config file.
+func (u *Updater) SetConfig(config *configv1.ProjectConfig) error {
+	hash, err := ConfigHash(config)
+	if err != nil {
+		return err
+	}
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.configHash = hash
+	return nil
+}
+
+// Start implements manager.Runnable, it updates the OperatorStatus on every interval until the context is done.
+func (u *Updater) Start(ctx context.Context) error {
+	interval := u.Interval
+	if interval == 0 {
+		interval = defaultInterval
+	}
+	u.lock.Lock()
+	u.leaderSince = metav1.Now()
+	u.lock.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := u.Update(ctx); err != nil {
+			log.Error(err, "unable to update the OperatorStatus")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the leader writes the OperatorStatus.
+func (u *Updater) NeedLeaderElection() bool {
+	return true
+}
+
+// Update writes the current status to the OperatorStatus, which is created if missing.
+func (u *Updater) Update(ctx context.Context) error {
+	reader := u.APIReader
+	if reader == nil {
+		reader = u.Client
+	}
+	var operatorStatus v1.OperatorStatus
+	err := reader.Get(ctx, client.ObjectKey{Name: v1.OperatorStatusName}, &operatorStatus)
+	if apierrors.IsNotFound(err) {
+		operatorStatus = v1.OperatorStatus{ObjectMeta: metav1.ObjectMeta{Name: v1.OperatorStatusName}}
+		err = u.Client.Create(ctx, &operatorStatus)
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := u.Status()
+	if err != nil {
+		return err
+	}
+	operatorStatus.Status = *status
+	return u.Client.Status().Update(ctx, &operatorStatus)
+}
+
+// Status returns the current status of the operator. The error rates are the ones since the previous call.
+func (u *Updater) Status() (*v1.OperatorStatusStatus, error) {
+	controllers, err := u.controllers()
+	if err != nil {
+		return nil, err
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	for i := range controllers {
+		health := &controllers[i]
+		previous := u.previous[health.Name]
+		if reconciles := health.Reconciles - previous.Reconciles; reconciles > 0 {
+			rate := float64(health.Errors-previous.Errors) / float64(reconciles)
+			health.ErrorRate = strconv.FormatFloat(rate, 'f', 4, 64)
+		}
+	}
+	u.previous = map[string]v1.ControllerHealth{}
+	for _, health := range controllers {
+		u.previous[health.Name] = health
+	}
+
+	info := version.Get()
+	now := metav1.Now()
+	status := v1.OperatorStatusStatus{
+		Version:      info.Version,
+		GitCommit:    info.GitCommit,
+		Leader:       u.Identity,
+		FeatureGates: featuregates.EnabledFeatures(),
+		ConfigHash:   u.configHash,
+		Controllers:  controllers,
+		UpdateTime:   &now,
+	}
+	if !u.leaderSince.IsZero() {
+		leaderSince := u.leaderSince
+		status.LeaderSince = &leaderSince
+	}
+	return &status, nil
+}
+
+// controllers returns the health of the controllers from their metrics, sorted by name.
+func (u *Updater) controllers() ([]v1.ControllerHealth, error) {
+	families, err := u.Gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*v1.ControllerHealth{}
+	add := func(name string, f func(health *v1.ControllerHealth)) {
+		health, ok := byName[name]
+		if !ok {
+			health = &v1.ControllerHealth{Name: name}
+			byName[name] = health
+		}
+		f(health)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			switch family.GetName() {
+			case "workqueue_depth":
+				add(labels["name"], func(health *v1.ControllerHealth) {
+					health.QueueDepth = int64(metric.GetGauge().GetValue())
+				})
+			case "controller_runtime_reconcile_total":
+				add(labels["controller"], func(health *v1.ControllerHealth) {
+					health.Reconciles += int64(metric.GetCounter().GetValue())
+				})
+			case "controller_runtime_reconcile_errors_total":
+				add(labels["controller"], func(health *v1.ControllerHealth) {
+					health.Errors = int64(metric.GetCounter().GetValue())
+				})
+			}
+		}
+	}
+
+	controllers := make([]v1.ControllerHealth, 0, len(byName))
+	for _, health := range byName {
+		controllers = append(controllers, *health)
+	}
+	sort.Slice(controllers, func(i, j int) bool { return controllers[i].Name < controllers[j].Name })
+	return controllers, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operatorstatus
+
+import (
+	"context"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Updater", func() {
+	var (
+		depth      *prometheus.GaugeVec
+		reconciles *prometheus.CounterVec
+		errors     *prometheus.CounterVec
+		updater    *Updater
+	)
+
+	BeforeEach(func() {
+		registry := prometheus.NewRegistry()
+		depth = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workqueue_depth"}, []string{"name"})
+		reconciles = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "controller_runtime_reconcile_total"},
+			[]string{"controller", "result"})
+		errors = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "controller_runtime_reconcile_errors_total"},
+			[]string{"controller"})
+		registry.MustRegister(depth, reconciles, errors)
+
+		scheme := runtime.NewScheme()
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		updater = &Updater{
+			Client:   fake.NewClientBuilder().WithScheme(scheme).Build(),
+			Gatherer: registry,
+			Identity: "controller-manager-7d4b9c-x2x8k",
+		}
+	})
+
+	It("Should create the OperatorStatus and keep it up to date", func() {
+		depth.WithLabelValues("cronjob").Set(3)
+		reconciles.WithLabelValues("cronjob", "success").Add(18)
+		reconciles.WithLabelValues("cronjob", "error").Add(2)
+		errors.WithLabelValues("cronjob").Add(2)
+		depth.WithLabelValues("jobrun").Set(0)
+		Expect(updater.SetConfig(&configv1.ProjectConfig{})).To(Succeed())
+
+		Expect(updater.Update(context.Background())).To(Succeed())
+		var operatorStatus v1.OperatorStatus
+		Expect(updater.Client.Get(context.Background(), client.ObjectKey{Name: v1.OperatorStatusName},
+			&operatorStatus)).To(Succeed())
+		status := operatorStatus.Status
+		Expect(status.Leader).To(Equal("controller-manager-7d4b9c-x2x8k"))
+		Expect(status.Version).To(Equal("dev"))
+		Expect(status.ConfigHash).To(HaveLen(64))
+		Expect(status.UpdateTime).NotTo(BeNil())
+		Expect(status.Controllers).To(Equal([]v1.ControllerHealth{
+			{Name: "cronjob", QueueDepth: 3, Reconciles: 20, Errors: 2, ErrorRate: "0.1000"},
+			{Name: "jobrun"},
+		}))
+
+		// the error rates only count the reconciles since the previous update
+		reconciles.WithLabelValues("cronjob", "success").Add(10)
+		Expect(updater.Update(context.Background())).To(Succeed())
+		Expect(updater.Client.Get(context.Background(), client.ObjectKey{Name: v1.OperatorStatusName},
+			&operatorStatus)).To(Succeed())
+		Expect(operatorStatus.Status.Controllers[0].ErrorRate).To(Equal("0.0000"))
+		Expect(operatorStatus.Status.Controllers[0].Reconciles).To(Equal(int64(30)))
+	})
+
+	It("Should hash the config", func() {
+		hash, err := ConfigHash(&configv1.ProjectConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		other, err := ConfigHash(&configv1.ProjectConfig{Audit: configv1.AuditConfig{Enabled: true}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hash).NotTo(Equal(other))
+	})
+})
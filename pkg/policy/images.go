@@ -0,0 +1,119 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+/*
+The images of every container of the job template, including the init and the ephemeral containers, are checked
+against the allowed and the denied registries. The same rules are used for the registries of the config file and for
+the ones of the CronJobPolicies.
+*/
+
+const defaultRegistry = "docker.io"
+
+// ValidateImageRegistries validates that the images of the job template come from the allowed registries and not
+// from the denied ones. Nothing is checked if both are empty.
+func ValidateImageRegistries(r *batchv1.CronJob, allowed, denied []string) field.ErrorList {
+	if len(allowed) == 0 && len(denied) == 0 {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	podSpec := &r.Spec.JobTemplate.Spec.Template.Spec
+	specPath := field.NewPath("spec", "jobTemplate", "spec", "template", "spec")
+	check := func(image string, fldPath *field.Path) {
+		if err := CheckImageRegistry(image, allowed, denied); err != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath, err))
+		}
+	}
+
+	for i, c := range podSpec.InitContainers {
+		check(c.Image, specPath.Child("initContainers").Index(i).Child("image"))
+	}
+	for i, c := range podSpec.Containers {
+		check(c.Image, specPath.Child("containers").Index(i).Child("image"))
+	}
+	for i, c := range podSpec.EphemeralContainers {
+		check(c.Image, specPath.Child("ephemeralContainers").Index(i).Child("image"))
+	}
+	return allErrs
+}
+
+// CheckImageRegistry returns why the image is not allowed, or an empty string if it is.
+func CheckImageRegistry(image string, allowed, denied []string) string {
+	repository := normalizeImageRepository(image)
+	for _, entry := range denied {
+		if matchesRegistry(entry, repository) {
+			return fmt.Sprintf("image %q comes from the denied registry %q", image, entry)
+		}
+	}
+
+	if len(allowed) == 0 {
+		return ""
+	}
+	for _, entry := range allowed {
+		if matchesRegistry(entry, repository) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("image %q does not come from any of the allowed registries %s", image,
+		strings.Join(allowed, ", "))
+}
+
+// normalizeImageRepository returns the fully qualified repository of the image, without its tag and digest, e.g.
+// `busybox:1.33` becomes `docker.io/library/busybox`.
+func normalizeImageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		image = image[:i]
+	}
+
+	segments := strings.Split(image, "/")
+	// the first segment is a registry only if it looks like a host name
+	if len(segments) == 1 || !(strings.ContainsAny(segments[0], ".:") || segments[0] == "localhost") {
+		if len(segments) == 1 {
+			segments = append([]string{"library"}, segments...)
+		}
+		segments = append([]string{defaultRegistry}, segments...)
+	}
+	return strings.Join(segments, "/")
+}
+
+// matchesRegistry returns whether the repository is under the given registry entry.
+func matchesRegistry(entry, repository string) bool {
+	patternSegments := strings.Split(strings.TrimSuffix(entry, "/*"), "/")
+	repositorySegments := strings.Split(repository, "/")
+	if len(repositorySegments) < len(patternSegments) {
+		return false
+	}
+	for i, pattern := range patternSegments {
+		if ok, err := path.Match(pattern, repositorySegments[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
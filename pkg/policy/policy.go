@@ -0,0 +1,201 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy evaluates the CronJobPolicies, on admission by the validating webhook and continuously on the
+// existing CronJobs by the policy controller, so both report the same violations.
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/robfig/cron"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// maxIntervalChecks bounds the number of activations inspected for the minimum interval.
+	maxIntervalChecks = 10000
+	// intervalCheckWindow is how far ahead the activations are inspected for the minimum interval.
+	intervalCheckWindow = 366 * 24 * time.Hour
+)
+
+// scheduleDescriptors are the predefined schedules, expanded before the schedules are compared.
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Evaluate returns the violations of the policy by the CronJob. The details do not name the policy, the callers add
+// it where it is needed.
+func Evaluate(r *batchv1.CronJob, spec *batchv1.CronJobPolicySpec) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	for _, key := range spec.RequiredLabels {
+		if _, ok := r.Labels[key]; !ok {
+			allErrs = append(allErrs, field.Required(field.NewPath("metadata", "labels").Key(key),
+				"label is required"))
+		}
+	}
+
+	if len(spec.AllowedTimeZones) > 0 && featuregates.Enabled(featuregates.CronJobTimeZone) {
+		timeZone := ""
+		if r.Spec.TimeZone != nil {
+			timeZone = *r.Spec.TimeZone
+		}
+		if !containsString(spec.AllowedTimeZones, timeZone) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("timeZone"), timeZone,
+				fmt.Sprintf("time zone must be one of %s", strings.Join(spec.AllowedTimeZones, ", "))))
+		}
+	}
+
+	for _, forbidden := range spec.ForbiddenConcurrencyPolicies {
+		if r.Spec.ConcurrencyPolicy == forbidden {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("concurrencyPolicy"),
+				fmt.Sprintf("concurrency policy %s is forbidden", forbidden)))
+		}
+	}
+
+	allErrs = append(allErrs, validateHistoryLimit(r.Spec.SuccessfulJobsHistoryLimit, spec.SuccessfulJobsHistoryLimit,
+		specPath.Child("successfulJobsHistoryLimit"))...)
+	allErrs = append(allErrs, validateHistoryLimit(r.Spec.FailedJobsHistoryLimit, spec.FailedJobsHistoryLimit,
+		specPath.Child("failedJobsHistoryLimit"))...)
+
+	if len(spec.AllowedSchedules) > 0 && !MatchesAnySchedule(r.Spec.Schedule, spec.AllowedSchedules) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("schedule"), r.Spec.Schedule,
+			fmt.Sprintf("schedule must match one of %s", strings.Join(spec.AllowedSchedules, ", "))))
+	}
+
+	if spec.MinInterval != nil {
+		if interval, ok := ShortestInterval(r, spec.MinInterval.Duration); ok && interval < spec.MinInterval.Duration {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("schedule"), r.Spec.Schedule,
+				fmt.Sprintf("runs %s apart, the minimum interval is %s", interval, spec.MinInterval.Duration)))
+		}
+	}
+
+	if rules := spec.ImageRegistries; rules != nil {
+		allErrs = append(allErrs, ValidateImageRegistries(r, rules.Allowed, rules.Denied)...)
+	}
+	return allErrs
+}
+
+// validateHistoryLimit validates the history limit is within the bounds. An unset limit is not checked.
+func validateHistoryLimit(limit *int32, bounds *batchv1.HistoryLimitBounds, fldPath *field.Path) field.ErrorList {
+	if limit == nil || bounds == nil {
+		return nil
+	}
+	if bounds.Min != nil && *limit < *bounds.Min {
+		return field.ErrorList{field.Invalid(fldPath, *limit, fmt.Sprintf("must be at least %d", *bounds.Min))}
+	}
+	if bounds.Max != nil && *limit > *bounds.Max {
+		return field.ErrorList{field.Invalid(fldPath, *limit, fmt.Sprintf("must be at most %d", *bounds.Max))}
+	}
+	return nil
+}
+
+// MatchesAnySchedule returns whether the schedule matches one of the patterns. The schedules are compared field by
+// field, a `*` field of the pattern matches any value, and the descriptors like `@daily` are expanded first.
+func MatchesAnySchedule(schedule string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchesSchedule(schedule, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesSchedule(schedule, pattern string) bool {
+	scheduleFields, patternFields := scheduleFields(schedule), scheduleFields(pattern)
+	if len(scheduleFields) != len(patternFields) {
+		return false
+	}
+	for i, p := range patternFields {
+		if p != "*" && p != scheduleFields[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scheduleFields splits the schedule in its fields, expanding the descriptors. `@every` schedules are kept whole.
+func scheduleFields(schedule string) []string {
+	schedule = strings.TrimSpace(schedule)
+	if expanded, ok := scheduleDescriptors[schedule]; ok {
+		schedule = expanded
+	}
+	if strings.HasPrefix(schedule, "@") {
+		return []string{schedule}
+	}
+	return strings.Fields(schedule)
+}
+
+// ShortestInterval returns the shortest time between two upcoming activations of the CronJob, stopping as soon as
+// an interval shorter than min is found. It returns false if the schedule can not be inspected.
+func ShortestInterval(r *batchv1.CronJob, min time.Duration) (time.Duration, bool) {
+	sched, err := cron.ParseStandard(r.Spec.Schedule)
+	if err != nil {
+		// reported by the validation of the schedule
+		return 0, false
+	}
+
+	now := time.Now()
+	if r.Spec.TimeZone != nil {
+		loc, err := time.LoadLocation(*r.Spec.TimeZone)
+		if err != nil {
+			return 0, false
+		}
+		now = now.In(loc)
+	}
+
+	prev := sched.Next(now)
+	if prev.IsZero() {
+		return 0, false
+	}
+	shortest, found := time.Duration(0), false
+	end := now.Add(intervalCheckWindow)
+	for i := 0; i < maxIntervalChecks && prev.Before(end); i++ {
+		next := sched.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if interval := next.Sub(prev); !found || interval < shortest {
+			shortest, found = interval, true
+			if shortest < min {
+				break
+			}
+		}
+		prev = next
+	}
+	return shortest, found
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"time"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Evaluate", func() {
+	var cronJob *batchv1.CronJob
+
+	BeforeEach(func() {
+		cronJob = &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"team": "a"}},
+			Spec: batchv1.CronJobSpec{
+				Schedule: "0 * * * *",
+			},
+		}
+		cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{Name: "c", Image: "busybox"}}
+	})
+
+	It("should accept a CronJob satisfying the policy", func() {
+		five := int32(5)
+		cronJob.Spec.SuccessfulJobsHistoryLimit = &five
+		Expect(Evaluate(cronJob, &batchv1.CronJobPolicySpec{
+			AllowedSchedules:           []string{"0 * * * *"},
+			MinInterval:                &metav1.Duration{Duration: time.Hour},
+			RequiredLabels:             []string{"team"},
+			SuccessfulJobsHistoryLimit: &batchv1.HistoryLimitBounds{Max: &five},
+			ImageRegistries:            &batchv1.ImageRegistryRules{Allowed: []string{"docker.io"}},
+		})).To(BeEmpty())
+	})
+
+	It("should report every violated field", func() {
+		ten, five := int32(10), int32(5)
+		cronJob.Spec.FailedJobsHistoryLimit = &ten
+		errs := Evaluate(cronJob, &batchv1.CronJobPolicySpec{
+			AllowedSchedules:       []string{"@daily"},
+			MinInterval:            &metav1.Duration{Duration: 2 * time.Hour},
+			RequiredLabels:         []string{"owner"},
+			FailedJobsHistoryLimit: &batchv1.HistoryLimitBounds{Max: &five},
+			ImageRegistries:        &batchv1.ImageRegistryRules{Denied: []string{"docker.io/library/*"}},
+		})
+		var fields []string
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		Expect(fields).To(ConsistOf("metadata.labels[owner]", "spec.failedJobsHistoryLimit", "spec.schedule",
+			"spec.schedule", "spec.jobTemplate.spec.template.spec.containers[0].image"))
+	})
+})
+
+var _ = Describe("MatchesAnySchedule", func() {
+	It("should compare the schedules field by field", func() {
+		Expect(MatchesAnySchedule("0 3 * * *", []string{"0 * * * *"})).To(BeTrue())
+		Expect(MatchesAnySchedule("*/5 3 * * *", []string{"0 * * * *"})).To(BeFalse())
+		Expect(MatchesAnySchedule("*/5 3 * * *", []string{"0 * * * *", "* * * * *"})).To(BeTrue())
+		Expect(MatchesAnySchedule("0 3 * *", []string{"* * * * *"})).To(BeFalse())
+	})
+
+	It("should expand the descriptors", func() {
+		Expect(MatchesAnySchedule("@daily", []string{"0 0 * * *"})).To(BeTrue())
+		Expect(MatchesAnySchedule("0 0 * * *", []string{"@midnight"})).To(BeTrue())
+		Expect(MatchesAnySchedule("@every 1h", []string{"* * * * *"})).To(BeFalse())
+		Expect(MatchesAnySchedule("@every 1h", []string{"@every 1h"})).To(BeTrue())
+	})
+})
+
+var _ = Describe("CheckImageRegistry", func() {
+	It("should match the normalized repositories", func() {
+		Expect(CheckImageRegistry("busybox:1.33", []string{"docker.io/library"}, nil)).To(BeEmpty())
+		Expect(CheckImageRegistry("quay.io/team/app@sha256:abc", []string{"quay.io/*/app"}, nil)).To(BeEmpty())
+		Expect(CheckImageRegistry("localhost:5000/app", []string{"docker.io"}, nil)).To(
+			ContainSubstring("does not come from any of the allowed registries"))
+	})
+
+	It("should give precedence to the denied registries", func() {
+		Expect(CheckImageRegistry("quay.io/team/app", []string{"quay.io"}, []string{"quay.io/team"})).To(
+			ContainSubstring("denied registry"))
+	})
+})
@@ -0,0 +1,185 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+/*
+The policies applying to a namespace are layered: the ClusterCronJobPolicies selecting it come first, then the
+CronJobPolicies of the namespace. A CronJob has to satisfy every layer, so a namespaced policy can add restrictions
+but never lift the ones of the cluster, and the fields it leaves unset are the ones of the cluster. IneffectiveFields
+tells the namespaced fields which are looser than a cluster policy, and so change nothing.
+*/
+
+const (
+	// ClusterCronJobPolicyKind is the kind of the layers of the ClusterCronJobPolicies.
+	ClusterCronJobPolicyKind = "ClusterCronJobPolicy"
+	// CronJobPolicyKind is the kind of the layers of the CronJobPolicies.
+	CronJobPolicyKind = "CronJobPolicy"
+)
+
+// Layer is a policy applying to a namespace.
+type Layer struct {
+	// Kind is ClusterCronJobPolicy or CronJobPolicy.
+	Kind string
+	// Name is the name of the policy.
+	Name string
+	// Spec is the restrictions of the policy.
+	Spec *batchv1.CronJobPolicySpec
+}
+
+// Selects returns whether the cluster policy applies to the namespace. A policy without a namespace selector applies
+// to all the namespaces.
+func Selects(p *batchv1.ClusterCronJobPolicy, namespace *corev1.Namespace) (bool, error) {
+	if p.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid namespace selector of ClusterCronJobPolicy %q: %w", p.Name, err)
+	}
+	return selector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+// Resolve returns the layers applying to the namespace in precedence order: the cluster policies selecting the
+// namespace, then the policies of the namespace, each sorted by name.
+func Resolve(namespace *corev1.Namespace, clusterPolicies []batchv1.ClusterCronJobPolicy,
+	policies []batchv1.CronJobPolicy) ([]Layer, error) {
+	var clusterLayers, layers []Layer
+	for i := range clusterPolicies {
+		p := &clusterPolicies[i]
+		selected, err := Selects(p, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if selected {
+			clusterLayers = append(clusterLayers, Layer{Kind: ClusterCronJobPolicyKind, Name: p.Name,
+				Spec: &p.Spec.CronJobPolicySpec})
+		}
+	}
+	for i := range policies {
+		p := &policies[i]
+		layers = append(layers, Layer{Kind: CronJobPolicyKind, Name: p.Name, Spec: &p.Spec})
+	}
+
+	byName := func(layers []Layer) {
+		sort.Slice(layers, func(i, j int) bool { return layers[i].Name < layers[j].Name })
+	}
+	byName(clusterLayers)
+	byName(layers)
+	return append(clusterLayers, layers...), nil
+}
+
+// EvaluateAll returns the violations of all the layers by the CronJob. The details name the violated policy.
+func EvaluateAll(r *batchv1.CronJob, layers []Layer) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, layer := range layers {
+		errs := Evaluate(r, layer.Spec)
+		for _, err := range errs {
+			err.Detail = fmt.Sprintf("violates %s %q: %s", layer.Kind, layer.Name, err.Detail)
+		}
+		allErrs = append(allErrs, errs...)
+	}
+	return allErrs
+}
+
+// IneffectiveFields returns the fields of the namespaced policy which are looser than the cluster policy. The
+// unions, like the required labels and the denied registries, can not be loosened.
+func IneffectiveFields(namespaced *batchv1.CronJobPolicySpec, cluster Layer) []string {
+	var fields []string
+	specPath := field.NewPath("spec")
+	looser := func(fldPath *field.Path, value, clusterValue interface{}) {
+		fields = append(fields, fmt.Sprintf("%s: %v is looser than %v of %s %q", fldPath, value, clusterValue,
+			cluster.Kind, cluster.Name))
+	}
+	spec := cluster.Spec
+
+	if namespaced.MinInterval != nil && spec.MinInterval != nil &&
+		namespaced.MinInterval.Duration < spec.MinInterval.Duration {
+		looser(specPath.Child("minInterval"), namespaced.MinInterval.Duration, spec.MinInterval.Duration)
+	}
+
+	if len(namespaced.AllowedTimeZones) > 0 && len(spec.AllowedTimeZones) > 0 {
+		for _, timeZone := range namespaced.AllowedTimeZones {
+			if !containsString(spec.AllowedTimeZones, timeZone) {
+				looser(specPath.Child("allowedTimeZones"), strings.Join(namespaced.AllowedTimeZones, ", "),
+					strings.Join(spec.AllowedTimeZones, ", "))
+				break
+			}
+		}
+	}
+
+	if len(namespaced.AllowedSchedules) > 0 && len(spec.AllowedSchedules) > 0 {
+		for _, schedule := range namespaced.AllowedSchedules {
+			// a pattern is within the cluster ones if its fields, wildcards included, match one of them
+			if !MatchesAnySchedule(schedule, spec.AllowedSchedules) {
+				looser(specPath.Child("allowedSchedules"), strings.Join(namespaced.AllowedSchedules, ", "),
+					strings.Join(spec.AllowedSchedules, ", "))
+				break
+			}
+		}
+	}
+
+	historyLimit := func(fldPath *field.Path, bounds, clusterBounds *batchv1.HistoryLimitBounds) {
+		if bounds == nil || clusterBounds == nil {
+			return
+		}
+		if bounds.Min != nil && clusterBounds.Min != nil && *bounds.Min < *clusterBounds.Min {
+			looser(fldPath.Child("min"), *bounds.Min, *clusterBounds.Min)
+		}
+		if bounds.Max != nil && clusterBounds.Max != nil && *bounds.Max > *clusterBounds.Max {
+			looser(fldPath.Child("max"), *bounds.Max, *clusterBounds.Max)
+		}
+	}
+	historyLimit(specPath.Child("successfulJobsHistoryLimit"), namespaced.SuccessfulJobsHistoryLimit,
+		spec.SuccessfulJobsHistoryLimit)
+	historyLimit(specPath.Child("failedJobsHistoryLimit"), namespaced.FailedJobsHistoryLimit,
+		spec.FailedJobsHistoryLimit)
+
+	if namespaced.ImageRegistries != nil && spec.ImageRegistries != nil &&
+		len(namespaced.ImageRegistries.Allowed) > 0 && len(spec.ImageRegistries.Allowed) > 0 {
+		for _, entry := range namespaced.ImageRegistries.Allowed {
+			if !registryWithin(entry, spec.ImageRegistries.Allowed) {
+				looser(specPath.Child("imageRegistries", "allowed"),
+					strings.Join(namespaced.ImageRegistries.Allowed, ", "),
+					strings.Join(spec.ImageRegistries.Allowed, ", "))
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// registryWithin returns whether every repository matched by the registry entry is matched by one of the entries.
+func registryWithin(entry string, entries []string) bool {
+	for _, e := range entries {
+		if matchesRegistry(e, strings.TrimSuffix(entry, "/*")) {
+			return true
+		}
+	}
+	return false
+}
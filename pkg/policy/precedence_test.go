@@ -0,0 +1,104 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"time"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Resolve", func() {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a",
+		Labels: map[string]string{"tier": "prod"}}}
+	clusterPolicy := func(name string, selector *metav1.LabelSelector) batchv1.ClusterCronJobPolicy {
+		return batchv1.ClusterCronJobPolicy{ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: batchv1.ClusterCronJobPolicySpec{NamespaceSelector: selector}}
+	}
+
+	It("should put the selecting cluster policies first", func() {
+		layers, err := Resolve(namespace, []batchv1.ClusterCronJobPolicy{
+			clusterPolicy("prod", &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "prod"}}),
+			clusterPolicy("dev", &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "dev"}}),
+			clusterPolicy("baseline", nil),
+		}, []batchv1.CronJobPolicy{{ObjectMeta: metav1.ObjectMeta{Name: "a"}}})
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for _, layer := range layers {
+			names = append(names, layer.Kind+"/"+layer.Name)
+		}
+		Expect(names).To(Equal([]string{"ClusterCronJobPolicy/baseline", "ClusterCronJobPolicy/prod",
+			"CronJobPolicy/a"}))
+	})
+
+	It("should fail on an invalid selector", func() {
+		_, err := Resolve(namespace, []batchv1.ClusterCronJobPolicy{
+			clusterPolicy("invalid", &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "tier", Operator: "Near"}}}),
+		}, nil)
+		Expect(err).To(MatchError(ContainSubstring(`ClusterCronJobPolicy "invalid"`)))
+	})
+})
+
+var _ = Describe("EvaluateAll", func() {
+	It("should not let a namespaced policy loosen a cluster policy", func() {
+		cronJob := &batchv1.CronJob{Spec: batchv1.CronJobSpec{Schedule: "*/10 * * * *"}}
+		errs := EvaluateAll(cronJob, []Layer{
+			{Kind: ClusterCronJobPolicyKind, Name: "baseline",
+				Spec: &batchv1.CronJobPolicySpec{MinInterval: &metav1.Duration{Duration: time.Hour}}},
+			{Kind: CronJobPolicyKind, Name: "team",
+				Spec: &batchv1.CronJobPolicySpec{MinInterval: &metav1.Duration{Duration: time.Minute}}},
+		})
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Detail).To(HavePrefix(`violates ClusterCronJobPolicy "baseline": `))
+	})
+})
+
+var _ = Describe("IneffectiveFields", func() {
+	It("should report the looser fields only", func() {
+		five, ten := int32(5), int32(10)
+		cluster := Layer{Kind: ClusterCronJobPolicyKind, Name: "baseline", Spec: &batchv1.CronJobPolicySpec{
+			MinInterval:            &metav1.Duration{Duration: time.Hour},
+			AllowedSchedules:       []string{"0 * * * *"},
+			FailedJobsHistoryLimit: &batchv1.HistoryLimitBounds{Max: &five},
+			ImageRegistries:        &batchv1.ImageRegistryRules{Allowed: []string{"quay.io/*"}},
+		}}
+		Expect(IneffectiveFields(&batchv1.CronJobPolicySpec{
+			MinInterval:            &metav1.Duration{Duration: 2 * time.Hour},
+			AllowedSchedules:       []string{"0 3 * * *"},
+			FailedJobsHistoryLimit: &batchv1.HistoryLimitBounds{Max: &five},
+			ImageRegistries:        &batchv1.ImageRegistryRules{Allowed: []string{"quay.io/team/*"}},
+		}, cluster)).To(BeEmpty())
+
+		Expect(IneffectiveFields(&batchv1.CronJobPolicySpec{
+			MinInterval:            &metav1.Duration{Duration: time.Minute},
+			AllowedSchedules:       []string{"* * * * *"},
+			FailedJobsHistoryLimit: &batchv1.HistoryLimitBounds{Max: &ten},
+			ImageRegistries:        &batchv1.ImageRegistryRules{Allowed: []string{"docker.io"}},
+		}, cluster)).To(ConsistOf(
+			`spec.minInterval: 1m0s is looser than 1h0m0s of ClusterCronJobPolicy "baseline"`,
+			`spec.allowedSchedules: * * * * * is looser than 0 * * * * of ClusterCronJobPolicy "baseline"`,
+			`spec.failedJobsHistoryLimit.max: 10 is looser than 5 of ClusterCronJobPolicy "baseline"`,
+			`spec.imageRegistries.allowed: docker.io is looser than quay.io/* of ClusterCronJobPolicy "baseline"`,
+		))
+	})
+})
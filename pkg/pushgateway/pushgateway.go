@@ -0,0 +1,297 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pushgateway pushes the results of the finished runs to a Prometheus Pushgateway, for the clusters where the
+// Pods of the runs are gone before they are scraped and the metrics of the manager are aggregated away.
+package pushgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/startup"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+/*
+The results of a CronJob are a group of the Pushgateway, labelled with the job, the namespace and the name of the
+CronJob, and the name of the cluster if set. The group keeps these labels from one run to the next, so the series
+stay the same and a push replaces the result of the previous run:
+
+	cronjob_last_run_success{job="cronjob",namespace="default",cronjob="report"} 1
+	cronjob_last_run_duration_seconds{job="cronjob",namespace="default",cronjob="report"} 83
+	cronjob_last_run_exit_code{job="cronjob",namespace="default",cronjob="report"} 0
+	cronjob_last_run_start_delay_seconds{job="cronjob",namespace="default",cronjob="report"} 2
+	cronjob_last_run_completion_timestamp_seconds{job="cronjob",namespace="default",cronjob="report"} 1.622851285e+09
+
+The exit code is the one of the first container of the last Pod of the run, it is left out when the Pod is gone. The
+start delay is how long after its scheduled time the run started.
+*/
+
+const (
+	// DefaultJob is the job label of the results by default.
+	DefaultJob  = "cronjob"
+	pushTimeout = 10 * time.Second
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var log = logf.Log.WithName("pushgateway")
+
+// ExitCodeReader returns the exit code of the Job, false if it is not known.
+type ExitCodeReader func(ctx context.Context, namespace, job string) (int32, bool, error)
+
+// Pusher pushes the results of the runs.
+type Pusher struct {
+	client             *http.Client
+	url                string
+	job                string
+	cluster            string
+	authorization      string
+	username, password string
+	exitCodes          ExitCodeReader
+	// dryRun is set when the results are logged instead of pushed.
+	dryRun bool
+}
+
+// NewPusher returns the Pusher of the settings, authenticated with the credentials, a `token` or a `username` and a
+// `password`, if any. The exit codes are read with the ExitCodeReader, they are not pushed if nil.
+func NewPusher(config configv1.PushgatewayConfig, cluster string, credentials map[string][]byte,
+	exitCodes ExitCodeReader) (*Pusher, error) {
+	if _, err := url.Parse(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid Pushgateway URL %q: %w", config.URL, err)
+	}
+	p := &Pusher{
+		client:    &http.Client{Timeout: pushTimeout},
+		url:       strings.TrimSuffix(config.URL, "/"),
+		job:       config.Job,
+		cluster:   cluster,
+		exitCodes: exitCodes,
+	}
+	if p.job == "" {
+		p.job = DefaultJob
+	}
+	if token := credentials["token"]; len(token) > 0 {
+		p.authorization = "Bearer " + strings.TrimSpace(string(token))
+	} else if username := credentials["username"]; len(username) > 0 {
+		p.username, p.password = string(username), string(credentials["password"])
+	}
+	return p, nil
+}
+
+// Setup returns the Pusher of the config, with the credentials read from their Secret and the exit codes read from
+// the Pods. It returns nil if no Pushgateway is configured.
+func Setup(ctx context.Context, config configv1.PushgatewayConfig, cluster string, secrets startup.SecretReader,
+	pods corev1client.PodsGetter) (*Pusher, error) {
+	if config.URL == "" {
+		return nil, nil
+	}
+	credentials, err := secrets(ctx, config.CredentialsSecret)
+	if err != nil {
+		return nil, err
+	}
+	p, err := NewPusher(config, cluster, credentials, NewExitCodeReader(pods))
+	if err != nil {
+		return nil, err
+	}
+	log.Info("pushing the results of the finished runs", "url", config.URL)
+	return p, nil
+}
+
+// Permissions returns the permissions the Pusher of the config needs in the watched namespaces, all of them if none.
+// It needs none without a Pushgateway.
+func Permissions(config configv1.PushgatewayConfig, namespaces []string) []startup.Permission {
+	if config.URL == "" {
+		return nil
+	}
+	// the pushed runs are annotated, and the exit codes read from the Pods
+	permissions := startup.Permissions(v1.GroupVersion.Group, "jobruns", []string{"patch"}, namespaces...)
+	permissions = append(permissions, startup.Permissions("", "pods", []string{"list"}, namespaces...)...)
+	if config.CredentialsSecret != "" {
+		namespace, _ := startup.SplitRef(config.CredentialsSecret)
+		permissions = append(permissions, startup.Permissions("", "secrets", []string{"get"}, namespace)...)
+	}
+	return permissions
+}
+
+// DryRun makes the Pusher log the results instead of pushing them, for the dry-run mode of the manager.
+func (p *Pusher) DryRun() {
+	p.dryRun = true
+}
+
+// Push replaces the results of the CronJob of the finished run with the ones of the run.
+func (p *Pusher) Push(ctx context.Context, run *v1.JobRun) error {
+	body, err := p.metrics(ctx, run)
+	if err != nil {
+		return err
+	}
+	if p.dryRun {
+		log.Info("would push the results of a run", "jobRun", run.Namespace+"/"+run.Name,
+			"group", p.GroupURL(run.Namespace, run.Spec.CronJob))
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.GroupURL(run.Namespace, run.Spec.CronJob),
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if p.authorization != "" {
+		req.Header.Set("Authorization", p.authorization)
+	} else if p.username != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("the Pushgateway answered %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// GroupURL returns the URL of the group of the results of the CronJob.
+func (p *Pusher) GroupURL(namespace, cronJob string) string {
+	group := p.url + "/metrics" + groupingLabel("job", p.job) + groupingLabel("namespace", namespace) +
+		groupingLabel("cronjob", cronJob)
+	if p.cluster != "" {
+		group += groupingLabel("cluster", p.cluster)
+	}
+	return group
+}
+
+// groupingLabel returns the path segments of a grouping label, the values which can not be a segment are base64
+// encoded.
+func groupingLabel(name, value string) string {
+	if value == "" || strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value)) + pad(value)
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
+
+// pad returns the padding of the base64 encoding of the empty value, a segment can not be empty.
+func pad(value string) string {
+	if value == "" {
+		return "="
+	}
+	return ""
+}
+
+// metrics returns the results of the run in the text format.
+func (p *Pusher) metrics(ctx context.Context, run *v1.JobRun) ([]byte, error) {
+	if run.Status.CompletionTime == nil {
+		return nil, fmt.Errorf("the run %s/%s did not finish", run.Namespace, run.Name)
+	}
+	success := 0.0
+	if run.Status.Phase == v1.JobRunSucceeded {
+		success = 1
+	}
+	results := map[string]result{
+		"cronjob_last_run_success": {"Whether the last run of the CronJob succeeded.", success},
+		"cronjob_last_run_completion_timestamp_seconds": {"When the last run of the CronJob finished, since the epoch.",
+			seconds(run.Status.CompletionTime.Time)},
+	}
+	if run.Status.Duration != nil {
+		results["cronjob_last_run_duration_seconds"] = result{"How long the last run of the CronJob ran.",
+			run.Status.Duration.Seconds()}
+	}
+	if run.Status.StartTime != nil && !run.Spec.ScheduledTime.IsZero() {
+		results["cronjob_last_run_start_delay_seconds"] = result{
+			"How long after its scheduled time the last run of the CronJob started.",
+			run.Status.StartTime.Sub(run.Spec.ScheduledTime.Time).Seconds()}
+	}
+	if p.exitCodes != nil {
+		code, ok, err := p.exitCodes(ctx, run.Namespace, run.Spec.Job.Name)
+		if err != nil {
+			log.V(1).Info("unable to read the exit code of the run", "jobRun", run.Namespace+"/"+run.Name,
+				"error", err.Error())
+		} else if ok {
+			results["cronjob_last_run_exit_code"] = result{"The exit code of the last run of the CronJob.", float64(code)}
+		}
+	}
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %v\n", name, results[name].help, name, name,
+			results[name].value)
+	}
+	return b.Bytes(), nil
+}
+
+// result is a metric of a run.
+type result struct {
+	help  string
+	value float64
+}
+
+// seconds returns the time in seconds since the epoch.
+func seconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+// NewExitCodeReader returns an ExitCodeReader reading the exit code of the first container of the last Pod of the
+// Job.
+func NewExitCodeReader(pods corev1client.PodsGetter) ExitCodeReader {
+	return func(ctx context.Context, namespace, job string) (int32, bool, error) {
+		podList, err := pods.Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + job})
+		if err != nil {
+			return 0, false, err
+		}
+		if len(podList.Items) == 0 {
+			return 0, false, nil
+		}
+		sort.Slice(podList.Items, func(i, j int) bool {
+			return podList.Items[j].CreationTimestamp.Before(&podList.Items[i].CreationTimestamp)
+		})
+		pod := &podList.Items[0]
+		if len(pod.Status.ContainerStatuses) == 0 {
+			return 0, false, nil
+		}
+		status := pod.Status.ContainerStatuses[0]
+		for _, candidate := range pod.Status.ContainerStatuses {
+			if len(pod.Spec.Containers) > 0 && candidate.Name == pod.Spec.Containers[0].Name {
+				status = candidate
+			}
+		}
+		if terminated := status.State.Terminated; terminated != nil {
+			return terminated.ExitCode, true, nil
+		}
+		if terminated := status.LastTerminationState.Terminated; terminated != nil {
+			return terminated.ExitCode, true, nil
+		}
+		return 0, false, nil
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pushgateway
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Pusher", func() {
+	ctx := context.Background()
+	scheduled := time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC)
+
+	var (
+		server   *httptest.Server
+		requests chan *http.Request
+		bodies   chan string
+		status   int
+	)
+	BeforeEach(func() {
+		requests, bodies, status = make(chan *http.Request, 10), make(chan string, 10), http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests <- r
+			bodies <- string(body)
+			w.WriteHeader(status)
+		}))
+	})
+	AfterEach(func() {
+		server.Close()
+	})
+
+	run := &v1.JobRun{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "report-1622851200"},
+		Spec: v1.JobRunSpec{CronJob: "report", ScheduledTime: metav1.NewTime(scheduled),
+			Job: corev1.ObjectReference{Name: "report-1622851200"}},
+		Status: v1.JobRunStatus{
+			Phase:          v1.JobRunSucceeded,
+			StartTime:      &metav1.Time{Time: scheduled.Add(2 * time.Second)},
+			CompletionTime: &metav1.Time{Time: scheduled.Add(85 * time.Second)},
+			Duration:       &metav1.Duration{Duration: 83 * time.Second},
+		},
+	}
+
+	It("Should replace the results of the CronJob with the ones of the run", func() {
+		exitCodes := func(_ context.Context, namespace, job string) (int32, bool, error) {
+			Expect(namespace).To(Equal("default"))
+			Expect(job).To(Equal("report-1622851200"))
+			return 0, true, nil
+		}
+		p, err := NewPusher(configv1.PushgatewayConfig{URL: server.URL + "/"}, "prod/eu",
+			map[string][]byte{"token": []byte("s3cr3t\n")}, exitCodes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Push(ctx, run)).To(Succeed())
+
+		var req *http.Request
+		Expect(requests).To(Receive(&req))
+		Expect(req.Method).To(Equal(http.MethodPut))
+		Expect(req.URL.Path).To(Equal("/metrics/job/cronjob/namespace/default/cronjob/report/cluster@base64/cHJvZC9ldQ"))
+		Expect(req.Header.Get("Authorization")).To(Equal("Bearer s3cr3t"))
+		var body string
+		Expect(bodies).To(Receive(&body))
+		Expect(body).To(ContainSubstring("# TYPE cronjob_last_run_success gauge\ncronjob_last_run_success 1\n"))
+		Expect(body).To(ContainSubstring("\ncronjob_last_run_duration_seconds 83\n"))
+		Expect(body).To(ContainSubstring("\ncronjob_last_run_exit_code 0\n"))
+		Expect(body).To(ContainSubstring("\ncronjob_last_run_start_delay_seconds 2\n"))
+		Expect(body).To(ContainSubstring("\ncronjob_last_run_completion_timestamp_seconds 1.622851285e+09\n"))
+	})
+
+	It("Should not push in dry-run mode", func() {
+		p, err := NewPusher(configv1.PushgatewayConfig{URL: server.URL}, "", nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		p.DryRun()
+		Expect(p.Push(ctx, run)).To(Succeed())
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("Should push the failed runs without their unknown results", func() {
+		failed := run.DeepCopy()
+		failed.Status = v1.JobRunStatus{Phase: v1.JobRunLost, CompletionTime: run.Status.CompletionTime}
+		p, err := NewPusher(configv1.PushgatewayConfig{URL: server.URL, Job: "batch"}, "",
+			map[string][]byte{"username": []byte("push"), "password": []byte("secret")}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Push(ctx, failed)).To(Succeed())
+
+		var req *http.Request
+		Expect(requests).To(Receive(&req))
+		Expect(req.URL.Path).To(Equal("/metrics/job/batch/namespace/default/cronjob/report"))
+		username, password, _ := req.BasicAuth()
+		Expect([]string{username, password}).To(Equal([]string{"push", "secret"}))
+		var body string
+		Expect(bodies).To(Receive(&body))
+		Expect(body).To(ContainSubstring("\ncronjob_last_run_success 0\n"))
+		Expect(body).NotTo(ContainSubstring("duration"))
+		Expect(body).NotTo(ContainSubstring("exit_code"))
+
+		status = http.StatusBadRequest
+		Expect(p.Push(ctx, failed)).To(MatchError(ContainSubstring("400 Bad Request")))
+		Expect(p.Push(ctx, &v1.JobRun{})).To(MatchError(ContainSubstring("did not finish")))
+	})
+
+	It("Should read the exit code of the last Pod of the Job", func() {
+		pod := func(name string, created time.Time, code int32) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name,
+					Labels: map[string]string{"job-name": "report"}, CreationTimestamp: metav1.NewTime(created)},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "report"}, {Name: "sidecar"}}},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "sidecar", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}},
+					{Name: "report", State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: code}}},
+				}},
+			}
+		}
+		clientset := fake.NewSimpleClientset(pod("report-a", scheduled, 1), pod("report-b", scheduled.Add(time.Minute), 3))
+		code, ok, err := NewExitCodeReader(clientset.CoreV1())(ctx, "default", "report")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(code).To(Equal(int32(3)))
+
+		_, ok, err = NewExitCodeReader(clientset.CoreV1())(ctx, "default", "other")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+})
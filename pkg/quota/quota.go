@@ -0,0 +1,121 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota counts what the CronJobs of a namespace use against its CronJobQuotas, and tells whether a quota
+// allows one more CronJob or one more run. The webhook, the CronJob controller and the quota controller share it, so
+// they all count alike.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RunWindow is the window the runs are counted in for MaxRunsPerHour.
+const RunWindow = time.Hour
+
+/*
+The history limits delete the finished Jobs, possibly right after they finish, so the runs of the last hour can not
+be counted from the Jobs alone. They are counted from the JobRuns too, which are named like their Jobs, when the JobRun
+CRD is installed. Only the runs started by the CronJobs count, not the ones of the Backfills.
+*/
+
+// Usage counts the CronJobs of the namespace, their active Jobs and their runs started within the RunWindow before
+// now.
+func Usage(ctx context.Context, c client.Reader, namespace string, now time.Time) (v1.CronJobQuotaUsage, error) {
+	var usage v1.CronJobQuotaUsage
+	var cronJobs v1.CronJobList
+	if err := c.List(ctx, &cronJobs, client.InNamespace(namespace)); err != nil {
+		return usage, fmt.Errorf("unable to list the CronJobs: %w", err)
+	}
+	usage.CronJobs = int32(len(cronJobs.Items))
+
+	since := now.Add(-RunWindow)
+	runs := sets.NewString()
+	var jobs kbatch.JobList
+	if err := c.List(ctx, &jobs, client.InNamespace(namespace)); err != nil {
+		return usage, fmt.Errorf("unable to list the Jobs: %w", err)
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if !isCronJobRun(job) {
+			continue
+		}
+		if !finished(job) {
+			usage.ActiveJobs++
+		}
+		if job.CreationTimestamp.Time.After(since) {
+			runs.Insert(job.Name)
+		}
+	}
+
+	var jobRuns v1.JobRunList
+	if err := c.List(ctx, &jobRuns, client.InNamespace(namespace)); err != nil && !meta.IsNoMatchError(err) {
+		return usage, fmt.Errorf("unable to list the JobRuns: %w", err)
+	}
+	for _, run := range jobRuns.Items {
+		if run.Spec.Trigger == v1.ScheduledTrigger && run.CreationTimestamp.Time.After(since) {
+			runs.Insert(run.Name)
+		}
+	}
+	usage.RunsLastHour = int32(runs.Len())
+	return usage, nil
+}
+
+// CronJobDenied returns why the quota does not allow one more CronJob, or an empty string if it does.
+func CronJobDenied(quota *v1.CronJobQuota, usage v1.CronJobQuotaUsage) string {
+	if max := quota.Spec.MaxCronJobs; max != nil && usage.CronJobs >= *max {
+		return fmt.Sprintf("CronJobQuota %q: %d CronJobs, the limit is %d", quota.Name, usage.CronJobs, *max)
+	}
+	return ""
+}
+
+// RunDenied returns why the quota does not allow one more run, or an empty string if it does.
+func RunDenied(quota *v1.CronJobQuota, usage v1.CronJobQuotaUsage) string {
+	if max := quota.Spec.MaxActiveJobs; max != nil && usage.ActiveJobs >= *max {
+		return fmt.Sprintf("CronJobQuota %q: %d active Jobs, the limit is %d", quota.Name, usage.ActiveJobs, *max)
+	}
+	if max := quota.Spec.MaxRunsPerHour; max != nil && usage.RunsLastHour >= *max {
+		return fmt.Sprintf("CronJobQuota %q: %d runs in the last hour, the limit is %d", quota.Name,
+			usage.RunsLastHour, *max)
+	}
+	return ""
+}
+
+// isCronJobRun returns whether the Job was started by a CronJob.
+func isCronJobRun(job *kbatch.Job) bool {
+	owner := metav1.GetControllerOf(job)
+	return owner != nil && owner.APIVersion == v1.GroupVersion.String() && owner.Kind == "CronJob"
+}
+
+// finished returns whether the Job completed or failed.
+func finished(job *kbatch.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == kbatch.JobComplete || c.Type == kbatch.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Quota", func() {
+	now := time.Date(2021, 6, 5, 12, 0, 0, 0, time.UTC)
+
+	newClient := func(objects ...client.Object) client.Client {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	}
+
+	cronJob := func(name string) *v1.CronJob {
+		return &v1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	}
+
+	job := func(name, ownerKind string, created time.Time, finished bool) *kbatch.Job {
+		job := &kbatch.Job{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              name,
+			CreationTimestamp: metav1.Time{Time: created},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: v1.GroupVersion.String(), Kind: ownerKind,
+				Name: "report", UID: "uid", Controller: pointer.BoolPtr(true)}},
+		}}
+		if finished {
+			job.Status.Conditions = []kbatch.JobCondition{{Type: kbatch.JobComplete, Status: corev1.ConditionTrue}}
+		}
+		return job
+	}
+
+	jobRun := func(name string, trigger v1.JobRunTrigger, created time.Time) *v1.JobRun {
+		return &v1.JobRun{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, CreationTimestamp: metav1.Time{Time: created}},
+			Spec:       v1.JobRunSpec{CronJob: "report", Trigger: trigger},
+		}
+	}
+
+	It("Should count the CronJobs, their active Jobs and their runs of the last hour", func() {
+		c := newClient(cronJob("report"), cronJob("cleanup"),
+			job("report-1", "CronJob", now.Add(-2*time.Hour), false),
+			job("report-2", "CronJob", now.Add(-30*time.Minute), true),
+			job("report-3", "CronJob", now.Add(-time.Minute), false),
+			job("june-1", "Backfill", now.Add(-time.Minute), false),
+			// the Job of report-2 is recorded in its JobRun too, the one of report-0 was deleted
+			jobRun("report-2", v1.ScheduledTrigger, now.Add(-30*time.Minute)),
+			jobRun("report-0", v1.ScheduledTrigger, now.Add(-45*time.Minute)),
+			jobRun("june-0", v1.BackfillTrigger, now.Add(-time.Minute)),
+			jobRun("report-old", v1.ScheduledTrigger, now.Add(-3*time.Hour)))
+
+		usage, err := Usage(context.Background(), c, "default", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(usage).To(Equal(v1.CronJobQuotaUsage{CronJobs: 2, ActiveJobs: 2, RunsLastHour: 3}))
+
+		usage, err = Usage(context.Background(), c, "other", now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(usage).To(Equal(v1.CronJobQuotaUsage{}))
+	})
+
+	It("Should deny the CronJobs and the runs beyond the limits", func() {
+		quota := &v1.CronJobQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+			Spec: v1.CronJobQuotaSpec{MaxCronJobs: pointer.Int32Ptr(2), MaxActiveJobs: pointer.Int32Ptr(3),
+				MaxRunsPerHour: pointer.Int32Ptr(10)},
+		}
+		Expect(CronJobDenied(quota, v1.CronJobQuotaUsage{CronJobs: 1})).To(BeEmpty())
+		Expect(CronJobDenied(quota, v1.CronJobQuotaUsage{CronJobs: 2})).To(
+			Equal(`CronJobQuota "team-a": 2 CronJobs, the limit is 2`))
+
+		Expect(RunDenied(quota, v1.CronJobQuotaUsage{ActiveJobs: 2, RunsLastHour: 9})).To(BeEmpty())
+		Expect(RunDenied(quota, v1.CronJobQuotaUsage{ActiveJobs: 3})).To(
+			Equal(`CronJobQuota "team-a": 3 active Jobs, the limit is 3`))
+		Expect(RunDenied(quota, v1.CronJobQuotaUsage{RunsLastHour: 10})).To(
+			Equal(`CronJobQuota "team-a": 10 runs in the last hour, the limit is 10`))
+
+		unlimited := &v1.CronJobQuota{}
+		Expect(CronJobDenied(unlimited, v1.CronJobQuotaUsage{CronJobs: 1000})).To(BeEmpty())
+		Expect(RunDenied(unlimited, v1.CronJobQuotaUsage{ActiveJobs: 1000, RunsLastHour: 1000})).To(BeEmpty())
+	})
+})
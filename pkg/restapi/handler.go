@@ -0,0 +1,246 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/robfig/cron"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+Two resources are served per CronJob, under `/api/v1/cronjobs/<namespace>/<name>/`:
+
+  - `runs`, its runs recorded in the JobRuns, the last scheduled first, at most `limit` of them (100 by default),
+  - `next`, its next `count` activations (10 by default), computed like the controller does, in the time zone of the
+    CronJob when the time zones are enabled. A suspended CronJob has none.
+*/
+
+// Prefix is the path of the CronJobs in the API.
+const Prefix = "/api/v1/cronjobs/"
+
+const (
+	defaultRunsLimit = 100
+	maxRunsLimit     = 1000
+	defaultNextCount = 10
+	maxNextCount     = 100
+)
+
+// Run is a run of a CronJob.
+type Run struct {
+	// Name is the name of the JobRun, and of its Job.
+	Name           string                `json:"name"`
+	Trigger        batchv1.JobRunTrigger `json:"trigger"`
+	TriggeredBy    string                `json:"triggeredBy,omitempty"`
+	RunID          string                `json:"runID,omitempty"`
+	ScheduledTime  metav1.Time           `json:"scheduledTime"`
+	Phase          batchv1.JobRunPhase   `json:"phase,omitempty"`
+	StartTime      *metav1.Time          `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time          `json:"completionTime,omitempty"`
+	Duration       *metav1.Duration      `json:"duration,omitempty"`
+	FailureReason  string                `json:"failureReason,omitempty"`
+	FailureMessage string                `json:"failureMessage,omitempty"`
+}
+
+// RunList is the response of `runs`.
+type RunList struct {
+	// CronJob is the namespace/name of the CronJob.
+	CronJob string `json:"cronJob"`
+	Runs    []Run  `json:"runs"`
+}
+
+// NextRuns is the response of `next`.
+type NextRuns struct {
+	// CronJob is the namespace/name of the CronJob.
+	CronJob string `json:"cronJob"`
+	// TimeZone is the time zone the schedule is evaluated in, empty for the one of the controller.
+	TimeZone string `json:"timeZone,omitempty"`
+	// Suspended tells that the CronJob is suspended, it has no next run.
+	Suspended bool        `json:"suspended,omitempty"`
+	Next      []time.Time `json:"next"`
+}
+
+// Handler serves the API from a reader, usually the cache of the manager.
+type Handler struct {
+	reader client.Reader
+	now    func() time.Time
+}
+
+// NewHandler returns the handler of the API.
+func NewHandler(reader client.Reader) *Handler {
+	return &Handler{reader: reader, now: time.Now}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, Prefix), "/")
+	if !strings.HasPrefix(req.URL.Path, Prefix) || len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, req)
+		return
+	}
+	namespace, name, resource := parts[0], parts[1], parts[2]
+	if resource != "runs" && resource != "next" {
+		http.NotFound(w, req)
+		return
+	}
+
+	ctx := req.Context()
+	var cronJob batchv1.CronJob
+	if err := h.reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &cronJob); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("CronJob %s/%s not found", namespace, name), http.StatusNotFound)
+			return
+		}
+		log.Error(err, "unable to get CronJob", "namespace", namespace, "name", name)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	switch resource {
+	case "runs":
+		limit, err := intParam(req, "limit", defaultRunsLimit, maxRunsLimit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		runs, err := ListRuns(ctx, h.reader, &cronJob, limit)
+		if err != nil {
+			log.Error(err, "unable to list JobRuns", "namespace", namespace, "name", name)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, &RunList{CronJob: namespace + "/" + name, Runs: runs})
+	case "next":
+		count, err := intParam(req, "count", defaultNextCount, maxNextCount)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		next, err := Next(&cronJob, h.now(), count)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		writeJSON(w, next)
+	}
+}
+
+// ListRuns returns the last runs of the CronJob, the last scheduled first. Without the JobRun CRD, there is no run.
+func ListRuns(ctx context.Context, reader client.Reader, cronJob *batchv1.CronJob, limit int) ([]Run, error) {
+	runs := []Run{}
+	var jobRuns batchv1.JobRunList
+	if err := reader.List(ctx, &jobRuns, client.InNamespace(cronJob.Namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return runs, nil
+		}
+		return nil, err
+	}
+	for _, run := range jobRuns.Items {
+		if run.Spec.CronJob != cronJob.Name {
+			continue
+		}
+		runs = append(runs, Run{
+			Name:           run.Name,
+			Trigger:        run.Spec.Trigger,
+			TriggeredBy:    run.Spec.TriggeredBy,
+			RunID:          run.Spec.RunID,
+			ScheduledTime:  run.Spec.ScheduledTime,
+			Phase:          run.Status.Phase,
+			StartTime:      run.Status.StartTime,
+			CompletionTime: run.Status.CompletionTime,
+			Duration:       run.Status.Duration,
+			FailureReason:  run.Status.FailureReason,
+			FailureMessage: run.Status.FailureMessage,
+		})
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].ScheduledTime.Equal(&runs[j].ScheduledTime) {
+			return runs[j].ScheduledTime.Before(&runs[i].ScheduledTime)
+		}
+		return runs[i].Name < runs[j].Name
+	})
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// Next returns the next count activations of the CronJob after now.
+func Next(cronJob *batchv1.CronJob, now time.Time, count int) (*NextRuns, error) {
+	next := &NextRuns{CronJob: cronJob.Namespace + "/" + cronJob.Name, Next: []time.Time{}}
+	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+		next.Suspended = true
+		return next, nil
+	}
+	sched, err := cron.ParseStandard(cronJob.Spec.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("unparseable schedule %q: %v", cronJob.Spec.Schedule, err)
+	}
+
+	if cronJob.Spec.TimeZone != nil && featuregates.Enabled(featuregates.CronJobTimeZone) {
+		loc, err := time.LoadLocation(*cronJob.Spec.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone %q: %v", *cronJob.Spec.TimeZone, err)
+		}
+		next.TimeZone, now = *cronJob.Spec.TimeZone, now.In(loc)
+	}
+	for t := sched.Next(now); !t.IsZero() && len(next.Next) < count; t = sched.Next(t) {
+		next.Next = append(next.Next, t)
+	}
+	return next, nil
+}
+
+// intParam returns the positive integer query parameter, capped to the maximum.
+func intParam(req *http.Request, name string, defaultValue, max int) (int, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	if n > max {
+		n = max
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.V(1).Info("unable to write the response", "error", err.Error())
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Handler", func() {
+	now := time.Date(2021, 6, 5, 12, 30, 0, 0, time.UTC)
+
+	jobRun := func(name, cronJob string, scheduled time.Time, phase v1.JobRunPhase) *v1.JobRun {
+		return &v1.JobRun{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1.JobRunSpec{CronJob: cronJob, ScheduledTime: metav1.NewTime(scheduled),
+				Trigger: v1.ScheduledTrigger},
+			Status: v1.JobRunStatus{Phase: phase},
+		}
+	}
+
+	var handler *Handler
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		objects := []client.Object{
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "report"},
+				Spec:       v1.CronJobSpec{Schedule: "0 * * * *"},
+			},
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "paused"},
+				Spec:       v1.CronJobSpec{Schedule: "0 * * * *", Suspend: pointer.BoolPtr(true)},
+			},
+			jobRun("report-1", "report", now.Add(-2*time.Hour), v1.JobRunSucceeded),
+			jobRun("report-2", "report", now.Add(-time.Hour), v1.JobRunFailed),
+			jobRun("backup-1", "backup", now.Add(-time.Hour), v1.JobRunSucceeded),
+		}
+		handler = NewHandler(fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build())
+		handler.now = func() time.Time { return now }
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	It("Should list the runs of the CronJob, the last scheduled first", func() {
+		recorder := get("/api/v1/cronjobs/default/report/runs")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var list RunList
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &list)).To(Succeed())
+		Expect(list.CronJob).To(Equal("default/report"))
+		Expect(list.Runs).To(HaveLen(2))
+		Expect(list.Runs[0].Name).To(Equal("report-2"))
+		Expect(list.Runs[0].Phase).To(Equal(v1.JobRunFailed))
+		Expect(list.Runs[1].Name).To(Equal("report-1"))
+
+		Expect(json.Unmarshal(get("/api/v1/cronjobs/default/report/runs?limit=1").Body.Bytes(), &list)).To(Succeed())
+		Expect(list.Runs).To(HaveLen(1))
+		Expect(get("/api/v1/cronjobs/default/report/runs?limit=0").Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("Should compute the next runs of the CronJob", func() {
+		recorder := get("/api/v1/cronjobs/default/report/next?count=3")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var next NextRuns
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &next)).To(Succeed())
+		Expect(next.Next).To(HaveLen(3))
+		Expect(next.Next[0]).To(BeTemporally("==", time.Date(2021, 6, 5, 13, 0, 0, 0, time.UTC)))
+		Expect(next.Next[2]).To(BeTemporally("==", time.Date(2021, 6, 5, 15, 0, 0, 0, time.UTC)))
+
+		Expect(json.Unmarshal(get("/api/v1/cronjobs/default/report/next").Body.Bytes(), &next)).To(Succeed())
+		Expect(next.Next).To(HaveLen(defaultNextCount))
+
+		next = NextRuns{}
+		Expect(json.Unmarshal(get("/api/v1/cronjobs/default/paused/next").Body.Bytes(), &next)).To(Succeed())
+		Expect(next.Suspended).To(BeTrue())
+		Expect(next.Next).To(BeEmpty())
+	})
+
+	It("Should reject the unknown CronJobs, paths and methods", func() {
+		Expect(get("/api/v1/cronjobs/default/missing/runs").Code).To(Equal(http.StatusNotFound))
+		Expect(get("/api/v1/cronjobs/default/report/jobs").Code).To(Equal(http.StatusNotFound))
+		Expect(get("/api/v1/cronjobs/default/report").Code).To(Equal(http.StatusNotFound))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/cronjobs/default/report/runs", nil))
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,116 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package restapi serves a read-only HTTP API over TLS for the portals showing the CronJobs to their users: the run
+// history of a CronJob and its next runs, read from the cache of the manager. The requests are authenticated and
+// authorized against the API server like the ones of the secure metrics endpoint.
+package restapi
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/certrotation"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/config"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/filters"
+)
+
+/*
+The authorization is the one of a non-resource URL, `get` on the path of the request, so a ClusterRole with
+`nonResourceURLs: ["/api/v1/cronjobs/team-a/*"]` gives access to the CronJobs of the `team-a` namespace only, and no
+access to the CronJobs themselves through the API server.
+*/
+
+var log = logf.Log.WithName("rest-api")
+
+// Server serves the API over TLS.
+type Server struct {
+	// BindAddress is the address the server listens on, e.g. `:8444`.
+	BindAddress string
+	// CertDir holds the tls.crt and tls.key files of the server.
+	CertDir string
+	// Client authenticates and authorizes the requests.
+	Client client.Client
+	// Reader reads the CronJobs and the JobRuns, usually from the cache.
+	Reader client.Reader
+	// TLS holds the TLS settings of the API.
+	TLS configv1.TLSConfig
+	// Handlers are served next to the API by path prefix, behind the same authentication and authorization, e.g.
+	// the dashboard.
+	Handlers map[string]http.Handler
+}
+
+var _ manager.Runnable = &Server{}
+
+// Start implements manager.Runnable, it serves the API until the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	certs := &certrotation.CertificateLoader{Dir: s.CertDir, Server: "api"}
+	if _, err := certs.GetCertificate(nil); err != nil {
+		return fmt.Errorf("unable to load the API certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{GetCertificate: certs.GetCertificate}
+	if err := config.ApplyTLSConfig(tlsConfig, s.TLS); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.BindAddress)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %s: %w", s.BindAddress, err)
+	}
+	listener = tls.NewListener(listener, tlsConfig)
+
+	withAuth := filters.WithAuthenticationAndAuthorization(s.Client)
+	mux := http.NewServeMux()
+	mux.Handle(Prefix, withAuth(NewHandler(s.Reader)))
+	for prefix, handler := range s.Handlers {
+		mux.Handle(prefix, withAuth(handler))
+	}
+	server := &http.Server{
+		Handler:           mux,
+		TLSNextProto:      config.TLSNextProto(s.TLS),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "unable to shut down the API server")
+		}
+	}()
+
+	log.Info("serving the API over TLS", "address", s.BindAddress)
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica serves the API from its cache.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package runresult reads the result a run reports in the termination message of its Pod, like
+// `{"result":"succeeded-with-warnings"}`, so the outcomes a phase can not tell apart become visible.
+package runresult
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+/*
+The results are free-form, but they end up in a label, so they are kept short and simple: lowercase alphanumerics
+and dashes, like the names of the objects. A termination message which is not a JSON object with such a result, like
+the end of the logs written by the FallbackToLogsOnError policy, is ignored.
+*/
+
+// MaxLength is the length of the longest result.
+const MaxLength = 63
+
+var resultPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+type message struct {
+	Result string `json:"result"`
+}
+
+// Parse returns the result of the termination message, if it has a valid one.
+func Parse(terminationMessage string) (string, bool) {
+	var m message
+	if err := json.Unmarshal([]byte(terminationMessage), &m); err != nil {
+		return "", false
+	}
+	if len(m.Result) > MaxLength || !resultPattern.MatchString(m.Result) {
+		return "", false
+	}
+	return m.Result, true
+}
+
+// FromPods returns the result reported by the last Pod of the run: the one of its first terminated container with a
+// valid result, in the order of the spec.
+func FromPods(pods []corev1.Pod) string {
+	if len(pods) == 0 {
+		return ""
+	}
+	pods = append([]corev1.Pod(nil), pods...)
+	sort.SliceStable(pods, func(i, j int) bool {
+		return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp)
+	})
+	last := pods[0]
+	for _, container := range last.Spec.Containers {
+		for _, status := range last.Status.ContainerStatuses {
+			if status.Name != container.Name || status.State.Terminated == nil {
+				continue
+			}
+			if result, ok := Parse(status.State.Terminated.Message); ok {
+				return result
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runresult
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Run result", func() {
+	It("Should parse the results of the termination messages", func() {
+		result, ok := Parse(`{"result":"succeeded-with-warnings","warnings":3}`)
+		Expect(ok).To(BeTrue())
+		Expect(result).To(Equal("succeeded-with-warnings"))
+		for _, invalid := range []string{"", "done", `{"status":"ok"}`, `{"result":""}`, `{"result":"With Spaces"}`,
+			`{"result":"-partial"}`, `{"result":"` + strings.Repeat("a", MaxLength+1) + `"}`, `["partial"]`} {
+			_, ok := Parse(invalid)
+			Expect(ok).To(BeFalse(), invalid)
+		}
+	})
+
+	It("Should read the result of the last Pod", func() {
+		created := time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC)
+		pod := func(age time.Duration, messages map[string]string) corev1.Pod {
+			pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created.Add(-age))}}
+			for _, name := range []string{"main", "sidecar"} {
+				pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: name})
+			}
+			for name, message := range messages {
+				pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{Name: name,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}}})
+			}
+			return pod
+		}
+
+		Expect(FromPods(nil)).To(BeEmpty())
+		Expect(FromPods([]corev1.Pod{
+			pod(0, map[string]string{"main": `{"result":"partial"}`, "sidecar": `{"result":"flushed"}`}),
+			pod(time.Hour, map[string]string{"main": `{"result":"failed"}`}),
+		})).To(Equal("partial"))
+		Expect(FromPods([]corev1.Pod{
+			pod(time.Hour, map[string]string{"main": `{"result":"failed"}`}),
+			pod(0, map[string]string{"main": "panic: boom", "sidecar": `{"result":"flushed"}`}),
+		})).To(Equal("flushed"))
+		Expect(FromPods([]corev1.Pod{pod(0, nil)})).To(BeEmpty())
+	})
+})
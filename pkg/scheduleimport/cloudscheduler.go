@@ -0,0 +1,141 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduleimport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	defaultCloudSchedulerEndpoint = "https://cloudscheduler.googleapis.com"
+	// cloudSchedulerScope is the scope of the tokens, Cloud Scheduler has no narrower one. The service account needs
+	// the roles/cloudscheduler.viewer role.
+	cloudSchedulerScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// cloudSchedulerSource lists the jobs of Google Cloud Scheduler.
+type cloudSchedulerSource struct {
+	client *http.Client
+	// parent is the URL of the jobs of the location.
+	parent string
+}
+
+func newCloudSchedulerSource(ctx context.Context, source v1.CloudSchedulerSource, credentials map[string][]byte,
+	endpoint string) (*cloudSchedulerSource, error) {
+	key, ok := credentials["credentials.json"]
+	if !ok {
+		return nil, fmt.Errorf("the credentials of Cloud Scheduler need the credentials.json key")
+	}
+	creds, err := google.CredentialsFromJSON(ctx, key, cloudSchedulerScope)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Google Cloud credentials: %w", err)
+	}
+	client := oauth2.NewClient(ctx, creds.TokenSource)
+	client.Timeout = time.Minute
+
+	if endpoint == "" {
+		endpoint = defaultCloudSchedulerEndpoint
+	}
+	return &cloudSchedulerSource{client: client, parent: fmt.Sprintf("%s/v1/projects/%s/locations/%s/jobs",
+		strings.TrimSuffix(endpoint, "/"), url.PathEscape(source.Project), url.PathEscape(source.Location))}, nil
+}
+
+// cloudSchedulerJob is the subset of a job which is read.
+type cloudSchedulerJob struct {
+	Name       string `json:"name"`
+	Schedule   string `json:"schedule"`
+	TimeZone   string `json:"timeZone"`
+	State      string `json:"state"`
+	HTTPTarget *struct {
+		URI  string `json:"uri"`
+		Body string `json:"body"`
+	} `json:"httpTarget"`
+	PubsubTarget *struct {
+		TopicName string `json:"topicName"`
+		Data      string `json:"data"`
+	} `json:"pubsubTarget"`
+	AppEngineHTTPTarget *struct {
+		RelativeURI string `json:"relativeUri"`
+		Body        string `json:"body"`
+	} `json:"appEngineHttpTarget"`
+}
+
+// cloudSchedulerListResult is the subset of the response of jobs.list which is read.
+type cloudSchedulerListResult struct {
+	Jobs          []cloudSchedulerJob `json:"jobs"`
+	NextPageToken string              `json:"nextPageToken"`
+}
+
+// List implements Source. The jobs are filtered by the prefix here, the API has no filter.
+func (s *cloudSchedulerSource) List(ctx context.Context, prefix string) ([]Schedule, error) {
+	var schedules []Schedule
+	query := url.Values{"pageSize": {"500"}}
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.parent+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := do(s.client, req)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list the jobs: %w", err)
+		}
+		var result cloudSchedulerListResult
+		if err := json.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("unable to parse the listing: %w", err)
+		}
+		for _, job := range result.Jobs {
+			name := path.Base(job.Name)
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			schedule := Schedule{Name: name, ID: job.Name, Expression: job.Schedule, TimeZone: job.TimeZone,
+				Enabled: job.State == "ENABLED"}
+			// the payloads are base64 in the API
+			var payload string
+			switch {
+			case job.HTTPTarget != nil:
+				schedule.Target, payload = job.HTTPTarget.URI, job.HTTPTarget.Body
+			case job.PubsubTarget != nil:
+				schedule.Target, payload = job.PubsubTarget.TopicName, job.PubsubTarget.Data
+			case job.AppEngineHTTPTarget != nil:
+				schedule.Target, payload = job.AppEngineHTTPTarget.RelativeURI, job.AppEngineHTTPTarget.Body
+			}
+			input, err := base64.StdEncoding.DecodeString(payload)
+			if err != nil {
+				return nil, fmt.Errorf("invalid payload of the job %s: %w", name, err)
+			}
+			schedule.Input = string(input)
+			schedules = append(schedules, schedule)
+		}
+		if result.NextPageToken == "" {
+			return schedules, nil
+		}
+		query.Set("pageToken", result.NextPageToken)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduleimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/sigv4"
+)
+
+// defaultEventBridgeGroup is the schedule group of the schedules created without one.
+const defaultEventBridgeGroup = "default"
+
+// eventBridgeSource lists the schedules of AWS EventBridge Scheduler. It needs the scheduler:ListSchedules and
+// scheduler:GetSchedule permissions.
+type eventBridgeSource struct {
+	client   *http.Client
+	endpoint string
+	group    string
+	signer   *sigv4.Signer
+}
+
+func newEventBridgeSource(source v1.EventBridgeSchedulerSource, credentials map[string][]byte,
+	endpoint string) (*eventBridgeSource, error) {
+	accessKeyID, secretAccessKey := string(credentials["accessKeyID"]), string(credentials["secretAccessKey"])
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, fmt.Errorf("the credentials of EventBridge Scheduler need the accessKeyID and secretAccessKey keys")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://scheduler.%s.amazonaws.com", source.Region)
+	}
+	return &eventBridgeSource{
+		client:   &http.Client{Timeout: time.Minute},
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		group:    source.GroupName,
+		signer: &sigv4.Signer{Region: source.Region, Service: "scheduler",
+			AccessKeyID: strings.TrimSpace(accessKeyID), SecretAccessKey: strings.TrimSpace(secretAccessKey)},
+	}, nil
+}
+
+// eventBridgeListResult is the subset of the response of ListSchedules which is read.
+type eventBridgeListResult struct {
+	Schedules []struct {
+		Name      string `json:"Name"`
+		GroupName string `json:"GroupName"`
+	} `json:"Schedules"`
+	NextToken string `json:"NextToken"`
+}
+
+// eventBridgeSchedule is the subset of the response of GetSchedule which is read.
+type eventBridgeSchedule struct {
+	Arn                        string `json:"Arn"`
+	Name                       string `json:"Name"`
+	GroupName                  string `json:"GroupName"`
+	ScheduleExpression         string `json:"ScheduleExpression"`
+	ScheduleExpressionTimezone string `json:"ScheduleExpressionTimezone"`
+	State                      string `json:"State"`
+	Target                     struct {
+		Arn   string `json:"Arn"`
+		Input string `json:"Input"`
+	} `json:"Target"`
+}
+
+// List implements Source. The listing has neither the expressions nor the targets, so every schedule is read too.
+func (s *eventBridgeSource) List(ctx context.Context, prefix string) ([]Schedule, error) {
+	var schedules []Schedule
+	query := url.Values{"MaxResults": {"100"}}
+	if prefix != "" {
+		query.Set("NamePrefix", prefix)
+	}
+	if s.group != "" {
+		query.Set("GroupName", s.group)
+	}
+	for {
+		var result eventBridgeListResult
+		if err := s.get(ctx, "/schedules", query, &result); err != nil {
+			return nil, fmt.Errorf("unable to list the schedules: %w", err)
+		}
+		for _, item := range result.Schedules {
+			var schedule eventBridgeSchedule
+			if err := s.get(ctx, "/schedules/"+url.PathEscape(item.Name), url.Values{"groupName": {item.GroupName}},
+				&schedule); err != nil {
+				return nil, fmt.Errorf("unable to get the schedule %s: %w", item.Name, err)
+			}
+			name := schedule.Name
+			if schedule.GroupName != defaultEventBridgeGroup {
+				name = schedule.GroupName + "/" + name
+			}
+			schedules = append(schedules, Schedule{
+				Name:       name,
+				ID:         schedule.Arn,
+				Expression: schedule.ScheduleExpression,
+				TimeZone:   schedule.ScheduleExpressionTimezone,
+				Enabled:    schedule.State == "ENABLED",
+				Target:     schedule.Target.Arn,
+				Input:      schedule.Target.Input,
+			})
+		}
+		if result.NextToken == "" {
+			return schedules, nil
+		}
+		query.Set("NextToken", result.NextToken)
+	}
+}
+
+// get sends a signed GET request and decodes its response into the result.
+func (s *eventBridgeSource) get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		s.endpoint+path+"?"+sigv4.CanonicalQuery(query), nil)
+	if err != nil {
+		return err
+	}
+	s.signer.Sign(req, nil)
+	body, err := do(s.client, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("unable to parse the response: %w", err)
+	}
+	return nil
+}
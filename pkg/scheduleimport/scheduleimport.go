@@ -0,0 +1,261 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package scheduleimport lists the schedules of the cloud schedulers, and renders the CronJobs mirroring them.
+package scheduleimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/robfig/cron"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ImportLabel is the label of the CronJobs of a ScheduleImport, its value is the name of the import. Removing it
+	// releases the CronJob from the import.
+	ImportLabel = "batch.example.com/schedule-import"
+	// SourceLabel is the label of the CronJobs sourced from outside of the cluster, its value is the provider.
+	SourceLabel = "batch.example.com/source"
+	// ScheduleAnnotation is the ARN of the EventBridge schedule, or the resource name of the Cloud Scheduler job,
+	// of a CronJob.
+	ScheduleAnnotation = "batch.example.com/external-schedule"
+	// HashAnnotation is the hash of the rendered CronJob. It only changes with the schedule or the import, not with
+	// the defaults set by the webhook, so it tells whether the CronJob is up to date.
+	HashAnnotation = "batch.example.com/import-hash"
+
+	// The environment variables given to the containers of the CronJobs.
+	ScheduleNameEnv   = "SCHEDULE_NAME"
+	ScheduleTargetEnv = "SCHEDULE_TARGET"
+	ScheduleInputEnv  = "SCHEDULE_INPUT"
+
+	// maxCronJobNameLength is the length of the longest name of a CronJob, so the names of its Jobs fit in a label.
+	maxCronJobNameLength = 52
+	// maxResponseSize bounds the responses read from the cloud schedulers, a page of a listing is well under it.
+	maxResponseSize = 16 << 20
+	// defaultTimeZone is the time zone of the schedules which have none, both schedulers default to UTC.
+	defaultTimeZone = "UTC"
+)
+
+// Schedule is a schedule of a cloud scheduler.
+type Schedule struct {
+	// Name is the name of the schedule, prefixed with its group for the EventBridge schedules of another group than
+	// the default one.
+	Name string
+	// ID is the ARN of the EventBridge schedule, or the resource name of the Cloud Scheduler job.
+	ID string
+	// Expression is the schedule expression, in the syntax of the scheduler.
+	Expression string
+	// TimeZone is the time zone of the expression, empty for UTC.
+	TimeZone string
+	// Enabled is whether the scheduler runs the schedule.
+	Enabled bool
+	// Target is what the schedule triggers, e.g. the ARN of a Lambda function or the URL of an HTTP target.
+	Target string
+	// Input is the payload sent to the target.
+	Input string
+}
+
+// Source lists the schedules of a cloud scheduler.
+type Source interface {
+	// List returns the schedules whose name starts with the prefix.
+	List(ctx context.Context, prefix string) ([]Schedule, error)
+}
+
+/*
+The sources speak the REST APIs of the schedulers directly, like the log archive does, with read-only credentials from
+a Secret of the namespace of the import. The credentials of the manager itself are never used, they would let the
+users of any namespace read the schedules of the cloud account of the cluster.
+*/
+
+// NewSource returns the source of the import, with the credentials read from the data of its Secret.
+func NewSource(ctx context.Context, spec *v1.ScheduleImportSpec, credentials map[string][]byte) (Source, error) {
+	switch spec.Provider {
+	case v1.EventBridgeSchedulerProvider:
+		if spec.EventBridgeScheduler == nil {
+			return nil, errors.New("the EventBridgeScheduler provider needs eventBridgeScheduler")
+		}
+		return newEventBridgeSource(*spec.EventBridgeScheduler, credentials, "")
+	case v1.CloudSchedulerProvider:
+		if spec.CloudScheduler == nil {
+			return nil, errors.New("the CloudScheduler provider needs cloudScheduler")
+		}
+		return newCloudSchedulerSource(ctx, *spec.CloudScheduler, credentials, "")
+	}
+	return nil, fmt.Errorf("unknown provider %q", spec.Provider)
+}
+
+var (
+	// invalidNameChars are the runs of characters which are not allowed in the names of the CronJobs.
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+	// eventBridgeDays matches the days of week of the EventBridge cron expressions, but not the steps.
+	eventBridgeDays = regexp.MustCompile(`(^|[,-])([1-7])`)
+	// eventBridgeRate matches the EventBridge rate expressions.
+	eventBridgeRate = regexp.MustCompile(`^rate\((\d+) (minute|minutes|hour|hours|day|days)\)$`)
+)
+
+// CronJobName returns the name of the CronJob of the schedule of the import, the import name and the schedule name
+// made a DNS label. A name which is too long is cut, and made unique again with a hash.
+func CronJobName(importName, scheduleName string) string {
+	name := importName + "-" + strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(scheduleName), "-"), "-")
+	if len(name) <= maxCronJobNameLength {
+		return name
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(scheduleName))
+	return fmt.Sprintf("%s-%08x", strings.TrimRight(name[:maxCronJobNameLength-9], "-"), hash.Sum32())
+}
+
+// CronSchedule returns the cron schedule of the expression of the provider. The EventBridge cron expressions lose
+// their year, which must be `*`, and count the days of week from 0; their rate expressions become `@every`
+// schedules. The expressions without an equivalent, e.g. the one-time ones or the last day of the month, are errors.
+func CronSchedule(provider v1.ScheduleImportProvider, expression string) (string, error) {
+	expression = strings.TrimSpace(expression)
+	schedule := expression
+	if provider == v1.EventBridgeSchedulerProvider {
+		switch {
+		case strings.HasPrefix(expression, "at("):
+			return "", fmt.Errorf("the one-time schedule %q has no cron equivalent", expression)
+		case strings.HasPrefix(expression, "rate("):
+			match := eventBridgeRate.FindStringSubmatch(expression)
+			if match == nil {
+				return "", fmt.Errorf("invalid rate expression %q", expression)
+			}
+			n, _ := strconv.Atoi(match[1])
+			unit := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour}[match[2][0]]
+			schedule = fmt.Sprintf("@every %s", time.Duration(n)*unit)
+		case strings.HasPrefix(expression, "cron(") && strings.HasSuffix(expression, ")"):
+			fields := strings.Fields(expression[len("cron(") : len(expression)-1])
+			if len(fields) != 6 {
+				return "", fmt.Errorf("invalid cron expression %q", expression)
+			}
+			if fields[5] != "*" {
+				return "", fmt.Errorf("the years of %q have no cron equivalent", expression)
+			}
+			for i := range fields {
+				fields[i] = strings.ReplaceAll(fields[i], "?", "*")
+			}
+			fields[4] = eventBridgeDays.ReplaceAllStringFunc(fields[4], func(day string) string {
+				n := day[len(day)-1] - '1'
+				return day[:len(day)-1] + strconv.Itoa(int(n))
+			})
+			schedule = strings.Join(fields[:5], " ")
+		default:
+			return "", fmt.Errorf("invalid schedule expression %q", expression)
+		}
+	}
+	if strings.ContainsAny(schedule, "LW#") {
+		return "", fmt.Errorf("the schedule %q has no cron equivalent", expression)
+	}
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return "", fmt.Errorf("the schedule %q has no cron equivalent: %w", expression, err)
+	}
+	return schedule, nil
+}
+
+/*
+The CronJobs of the disabled schedules are suspended, like the ones of all the schedules unless the import is active:
+the cloud scheduler is running them, the CronJobs would run them a second time. The environment variables of the
+schedule are added to the containers of the job template, unless a container sets them itself.
+*/
+
+// Render returns the CronJob of the schedule of the import, labeled with the import and annotated with its hash.
+func Render(imp *v1.ScheduleImport, schedule Schedule) (*v1.CronJob, error) {
+	cronSchedule, err := CronSchedule(imp.Spec.Provider, schedule.Expression)
+	if err != nil {
+		return nil, err
+	}
+	timeZone := schedule.TimeZone
+	if timeZone == "" {
+		timeZone = defaultTimeZone
+	}
+	suspend := !imp.Spec.Active || !schedule.Enabled
+
+	template := imp.Spec.JobTemplate.DeepCopy()
+	env := []corev1.EnvVar{{Name: ScheduleNameEnv, Value: schedule.Name},
+		{Name: ScheduleTargetEnv, Value: schedule.Target}, {Name: ScheduleInputEnv, Value: schedule.Input}}
+	containers := template.Spec.Template.Spec.Containers
+	for i := range containers {
+		set := map[string]bool{}
+		for _, envVar := range containers[i].Env {
+			set[envVar.Name] = true
+		}
+		for _, envVar := range env {
+			if !set[envVar.Name] {
+				containers[i].Env = append(containers[i].Env, envVar)
+			}
+		}
+	}
+
+	cronJob := &v1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   imp.Namespace,
+			Name:        CronJobName(imp.Name, schedule.Name),
+			Labels:      map[string]string{ImportLabel: imp.Name, SourceLabel: string(imp.Spec.Provider)},
+			Annotations: map[string]string{ScheduleAnnotation: schedule.ID},
+		},
+		Spec: v1.CronJobSpec{
+			Schedule:    cronSchedule,
+			TimeZone:    &timeZone,
+			Suspend:     &suspend,
+			JobTemplate: *template,
+		},
+	}
+	raw, err := json.Marshal(cronJob)
+	if err != nil {
+		return nil, err
+	}
+	hash := fnv.New32a()
+	hash.Write(raw)
+	cronJob.Annotations[HashAnnotation] = fmt.Sprintf("%08x", hash.Sum32())
+	return cronJob, nil
+}
+
+// do sends the request and returns the body of a successful response. The errors never contain the body of the
+// response.
+func do(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *neturl.Error
+		if errors.As(err, &urlErr) {
+			return nil, fmt.Errorf("%s request failed: %w", strings.ToLower(req.Method), urlErr.Err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected response to %s request: %s", strings.ToLower(req.Method), resp.Status)
+	}
+	return body, nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduleimport
+
+import (
+	"strings"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("CronSchedule", func() {
+	It("Should convert the EventBridge expressions", func() {
+		for expression, schedule := range map[string]string{
+			"cron(0 6 * * ? *)":         "0 6 * * *",
+			"cron(15 10 ? * 2-6 *)":     "15 10 * * 1-5",
+			"cron(0/30 8-17 ? * 1,7 *)": "0/30 8-17 * * 0,6",
+			"cron(0 0 ? * MON-FRI *)":   "0 0 * * MON-FRI",
+			"cron(0 0 1 JAN ? *)":       "0 0 1 JAN *",
+			"rate(5 minutes)":           "@every 5m0s",
+			"rate(1 hour)":              "@every 1h0m0s",
+			"rate(2 days)":              "@every 48h0m0s",
+		} {
+			Expect(CronSchedule(v1.EventBridgeSchedulerProvider, expression)).To(Equal(schedule), expression)
+		}
+	})
+
+	It("Should reject the EventBridge expressions without a cron equivalent", func() {
+		for expression, message := range map[string]string{
+			"at(2022-11-20T13:00:00)": "one-time schedule",
+			"cron(0 6 * * ? 2022)":    "the years of",
+			"cron(0 6 L * ? *)":       "no cron equivalent",
+			"cron(0 6 ? * 6#3 *)":     "no cron equivalent",
+			"cron(0 6 * *)":           "invalid cron expression",
+			"rate(1 week)":            "invalid rate expression",
+			"0 6 * * *":               "invalid schedule expression",
+			"cron(0 25 * * ? *)":      "no cron equivalent",
+		} {
+			_, err := CronSchedule(v1.EventBridgeSchedulerProvider, expression)
+			Expect(err).To(MatchError(ContainSubstring(message)), expression)
+		}
+	})
+
+	It("Should keep the unix-cron expressions of Cloud Scheduler", func() {
+		Expect(CronSchedule(v1.CloudSchedulerProvider, "*/10 9-17 * * 1-5")).To(Equal("*/10 9-17 * * 1-5"))
+		_, err := CronSchedule(v1.CloudSchedulerProvider, "every 5 minutes")
+		Expect(err).To(MatchError(ContainSubstring("no cron equivalent")))
+	})
+})
+
+var _ = Describe("CronJobName", func() {
+	It("Should make a DNS label of the import and schedule names", func() {
+		Expect(CronJobName("aws", "Nightly_Report")).To(Equal("aws-nightly-report"))
+		Expect(CronJobName("aws", "billing/Invoices.Daily")).To(Equal("aws-billing-invoices-daily"))
+
+		long := CronJobName("aws", strings.Repeat("a", 60))
+		Expect(long).To(HaveLen(52))
+		Expect(long).To(HavePrefix("aws-aaaa"))
+		Expect(CronJobName("aws", strings.Repeat("a", 61))).NotTo(Equal(long))
+	})
+})
+
+var _ = Describe("Render", func() {
+	imp := &v1.ScheduleImport{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "billing", Name: "aws"},
+		Spec: v1.ScheduleImportSpec{
+			Provider: v1.EventBridgeSchedulerProvider,
+		},
+	}
+	imp.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{Name: "invoke", Image: "invoker",
+		Env: []corev1.EnvVar{{Name: ScheduleInputEnv, Value: "{}"}}}}
+	schedule := Schedule{Name: "invoices", ID: "arn:aws:scheduler:eu-west-1:123456789012:schedule/default/invoices",
+		Expression: "cron(0 6 * * ? *)", TimeZone: "Europe/Istanbul", Enabled: true,
+		Target: "arn:aws:lambda:eu-west-1:123456789012:function:invoices", Input: `{"month": "current"}`}
+
+	It("Should render a suspended CronJob of the schedule", func() {
+		cronJob, err := Render(imp, schedule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJob.Namespace).To(Equal("billing"))
+		Expect(cronJob.Name).To(Equal("aws-invoices"))
+		Expect(cronJob.Labels).To(Equal(map[string]string{ImportLabel: "aws",
+			SourceLabel: "EventBridgeScheduler"}))
+		Expect(cronJob.Annotations).To(HaveKeyWithValue(ScheduleAnnotation, schedule.ID))
+		Expect(cronJob.Annotations).To(HaveKey(HashAnnotation))
+		Expect(cronJob.Spec.Schedule).To(Equal("0 6 * * *"))
+		Expect(*cronJob.Spec.TimeZone).To(Equal("Europe/Istanbul"))
+		Expect(*cronJob.Spec.Suspend).To(BeTrue())
+		Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+			{Name: ScheduleInputEnv, Value: "{}"}, {Name: ScheduleNameEnv, Value: "invoices"},
+			{Name: ScheduleTargetEnv, Value: schedule.Target}}))
+		// the template of the import is not modified
+		Expect(imp.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Env).To(HaveLen(1))
+	})
+
+	It("Should only run the enabled schedules of an active import", func() {
+		active := imp.DeepCopy()
+		active.Spec.Active = true
+		cronJob, err := Render(active, schedule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*cronJob.Spec.Suspend).To(BeFalse())
+
+		disabled := schedule
+		disabled.Enabled, disabled.TimeZone = false, ""
+		other, err := Render(active, disabled)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*other.Spec.Suspend).To(BeTrue())
+		Expect(*other.Spec.TimeZone).To(Equal("UTC"))
+		Expect(other.Annotations[HashAnnotation]).NotTo(Equal(cronJob.Annotations[HashAnnotation]))
+	})
+
+	It("Should reject the schedules without a cron equivalent", func() {
+		oneTime := schedule
+		oneTime.Expression = "at(2022-11-20T13:00:00)"
+		_, err := Render(imp, oneTime)
+		Expect(err).To(MatchError(ContainSubstring("one-time schedule")))
+	})
+})
@@ -0,0 +1,125 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduleimport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sources", func() {
+	ctx := context.Background()
+
+	It("Should list and read the schedules of EventBridge Scheduler", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodGet))
+			Expect(r.Header.Get("Authorization")).To(ContainSubstring("/eu-west-1/scheduler/aws4_request"))
+			var response interface{}
+			switch r.URL.Path {
+			case "/schedules":
+				Expect(r.URL.Query().Get("NamePrefix")).To(Equal("billing-"))
+				if r.URL.Query().Get("NextToken") == "" {
+					response = map[string]interface{}{"NextToken": "2",
+						"Schedules": []map[string]string{{"Name": "billing-invoices", "GroupName": "default"}}}
+				} else {
+					response = map[string]interface{}{
+						"Schedules": []map[string]string{{"Name": "billing-reminders", "GroupName": "mail"}}}
+				}
+			case "/schedules/billing-invoices", "/schedules/billing-reminders":
+				response = map[string]interface{}{
+					"Arn": "arn:" + r.URL.Query().Get("groupName"), "Name": r.URL.Path[len("/schedules/"):],
+					"GroupName": r.URL.Query().Get("groupName"), "ScheduleExpression": "rate(1 hour)",
+					"State": "ENABLED", "Target": map[string]string{"Arn": "arn:lambda", "Input": "{}"}}
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			Expect(json.NewEncoder(w).Encode(response)).To(Succeed())
+		}))
+		defer server.Close()
+
+		source, err := newEventBridgeSource(v1.EventBridgeSchedulerSource{Region: "eu-west-1"},
+			map[string][]byte{"accessKeyID": []byte("id"), "secretAccessKey": []byte("secret")}, server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		schedules, err := source.List(ctx, "billing-")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedules).To(Equal([]Schedule{
+			{Name: "billing-invoices", ID: "arn:default", Expression: "rate(1 hour)", Enabled: true,
+				Target: "arn:lambda", Input: "{}"},
+			{Name: "mail/billing-reminders", ID: "arn:mail", Expression: "rate(1 hour)", Enabled: true,
+				Target: "arn:lambda", Input: "{}"},
+		}))
+	})
+
+	It("Should list the jobs of Cloud Scheduler", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/v1/projects/acme/locations/europe-west1/jobs"))
+			Expect(json.NewEncoder(w).Encode(map[string]interface{}{"jobs": []map[string]interface{}{
+				{"name": "projects/acme/locations/europe-west1/jobs/billing-invoices", "schedule": "0 6 * * *",
+					"timeZone": "Europe/Istanbul", "state": "ENABLED",
+					"httpTarget": map[string]string{"uri": "https://billing.example.com/invoices", "body": "e30="}},
+				{"name": "projects/acme/locations/europe-west1/jobs/billing-reminders", "schedule": "0 9 * * 1",
+					"state": "PAUSED", "pubsubTarget": map[string]string{"topicName": "projects/acme/topics/mail"}},
+				{"name": "projects/acme/locations/europe-west1/jobs/cleanup", "schedule": "0 0 * * *"},
+			}})).To(Succeed())
+		}))
+		defer server.Close()
+
+		source := &cloudSchedulerSource{client: server.Client(),
+			parent: server.URL + "/v1/projects/acme/locations/europe-west1/jobs"}
+		schedules, err := source.List(ctx, "billing-")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedules).To(Equal([]Schedule{
+			{Name: "billing-invoices", ID: "projects/acme/locations/europe-west1/jobs/billing-invoices",
+				Expression: "0 6 * * *", TimeZone: "Europe/Istanbul", Enabled: true,
+				Target: "https://billing.example.com/invoices", Input: "{}"},
+			{Name: "billing-reminders", ID: "projects/acme/locations/europe-west1/jobs/billing-reminders",
+				Expression: "0 9 * * 1", Target: "projects/acme/topics/mail"},
+		}))
+	})
+
+	It("Should report the failed requests without their response", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("secret"))
+		}))
+		defer server.Close()
+
+		source := &cloudSchedulerSource{client: server.Client(), parent: server.URL}
+		_, err := source.List(ctx, "")
+		Expect(err).To(MatchError(ContainSubstring("403 Forbidden")))
+		Expect(err.Error()).NotTo(ContainSubstring("secret"))
+	})
+
+	It("Should reject the incomplete sources and credentials", func() {
+		_, err := NewSource(ctx, &v1.ScheduleImportSpec{Provider: v1.EventBridgeSchedulerProvider}, nil)
+		Expect(err).To(MatchError(ContainSubstring("needs eventBridgeScheduler")))
+		_, err = NewSource(ctx, &v1.ScheduleImportSpec{Provider: v1.EventBridgeSchedulerProvider,
+			EventBridgeScheduler: &v1.EventBridgeSchedulerSource{Region: "eu-west-1"}},
+			map[string][]byte{"accessKeyID": []byte("id")})
+		Expect(err).To(MatchError(ContainSubstring("secretAccessKey")))
+		_, err = NewSource(ctx, &v1.ScheduleImportSpec{Provider: v1.CloudSchedulerProvider,
+			CloudScheduler: &v1.CloudSchedulerSource{Project: "acme", Location: "europe-west1"}}, nil)
+		Expect(err).To(MatchError(ContainSubstring("credentials.json")))
+	})
+})
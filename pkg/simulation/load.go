@@ -0,0 +1,93 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/config"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadCronJobs returns the CronJobs of the YAML or JSON files, a directory standing for the files in it. The other
+// kinds of objects are skipped.
+func ReadCronJobs(paths []string, scheme *runtime.Scheme) ([]batchv1.CronJob, error) {
+	files, err := config.ExpandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	var cronJobs []batchv1.CronJob
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+		for {
+			document, err := reader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("unable to read %s: %w", file, err)
+			}
+			if len(bytes.TrimSpace(document)) == 0 {
+				continue
+			}
+
+			object, _, err := decoder.Decode(document, nil, nil)
+			if runtime.IsNotRegisteredError(err) {
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("unable to decode %s: %w", file, err)
+			}
+			switch object := object.(type) {
+			case *batchv1.CronJob:
+				cronJobs = append(cronJobs, *object)
+			case *batchv1.CronJobList:
+				cronJobs = append(cronJobs, object.Items...)
+			}
+		}
+	}
+	return cronJobs, nil
+}
+
+// ListCronJobs returns the CronJobs of the namespaces in the cluster, or of all the namespaces if none is given.
+func ListCronJobs(ctx context.Context, c client.Reader, namespaces []string) ([]batchv1.CronJob, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	var cronJobs []batchv1.CronJob
+	for _, namespace := range namespaces {
+		list := &batchv1.CronJobList{}
+		if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		cronJobs = append(cronJobs, list.Items...)
+	}
+	return cronJobs, nil
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Loading the CronJobs", func() {
+	var dir string
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(batchv1.AddToScheme(scheme)).To(Succeed())
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "simulation")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("Should read the CronJobs of the files and skip the other objects", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "a.yaml"), []byte(`
+apiVersion: batch.example.com/v1
+kind: CronJob
+metadata:
+  name: a
+spec:
+  schedule: "*/5 * * * *"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: unknown
+`), 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "b.json"), []byte(`{
+  "apiVersion": "batch.example.com/v1",
+  "kind": "CronJobList",
+  "items": [{"metadata": {"name": "b"}, "spec": {"schedule": "@daily"}}]
+}`), 0600)).To(Succeed())
+
+		cronJobs, err := ReadCronJobs([]string{dir}, scheme)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJobs).To(HaveLen(2))
+		Expect(cronJobs[0].Name).To(Equal("a"))
+		Expect(cronJobs[0].Spec.Schedule).To(Equal("*/5 * * * *"))
+		Expect(cronJobs[1].Name).To(Equal("b"))
+	})
+
+	It("Should report the file which can not be decoded", func() {
+		file := filepath.Join(dir, "broken.yaml")
+		Expect(ioutil.WriteFile(file, []byte("apiVersion: batch.example.com/v1\nkind: CronJob\nspec: [\n"), 0600)).
+			To(Succeed())
+		_, err := ReadCronJobs([]string{file}, scheme)
+		Expect(err).To(MatchError(ContainSubstring(file)))
+	})
+
+	It("Should list the CronJobs of the namespaces", func() {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "a"}},
+			&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "b"}},
+			&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "c", Name: "c"}},
+		).Build()
+
+		cronJobs, err := ListCronJobs(context.Background(), c, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJobs).To(HaveLen(3))
+
+		cronJobs, err = ListCronJobs(context.Background(), c, []string{"a", "c"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJobs).To(HaveLen(2))
+		Expect(cronJobs[1].Namespace).To(Equal("c"))
+	})
+})
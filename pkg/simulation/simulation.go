@@ -0,0 +1,159 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package simulation computes the activations of CronJobs over a time window without running them, so that the
+// schedules can be checked before they are applied.
+package simulation
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/robfig/cron"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/*
+The activations are computed like the controller does: the schedule is parsed with the same cron library, in the time
+zone of the CronJob when the time zones are enabled, and a suspended CronJob is never activated. The missed runs and
+the starting deadline do not matter here, the simulation starts from a clean slate.
+*/
+
+// maxActivations bounds the activations listed per CronJob, so that a schedule running every minute does not flood
+// the timeline.
+const maxActivations = 100
+
+// Simulator computes the activations of the CronJobs in a time window.
+type Simulator struct {
+	// From is the start of the window.
+	From time.Time
+	// Horizon is the length of the window.
+	Horizon time.Duration
+	// TimeZones honors spec.timeZone of the CronJobs, as the controller does with the CronJobTimeZone feature gate.
+	TimeZones bool
+}
+
+// Activation is a planned run of a CronJob.
+type Activation struct {
+	// CronJob is the activated CronJob.
+	CronJob types.NamespacedName
+	// Time is the activation time, in the time zone of the CronJob.
+	Time time.Time
+}
+
+// Timeline holds the activations of the CronJobs in the window, and why some CronJobs have fewer than planned.
+type Timeline struct {
+	From, To    time.Time
+	Activations []Activation
+	// Notes tells, per CronJob, why it is not activated or why some of its activations are not listed.
+	Notes map[types.NamespacedName]string
+}
+
+// Run computes the timeline of the CronJobs.
+func (s Simulator) Run(cronJobs []batchv1.CronJob) Timeline {
+	timeline := Timeline{
+		From:  s.From,
+		To:    s.From.Add(s.Horizon),
+		Notes: map[types.NamespacedName]string{},
+	}
+	for i := range cronJobs {
+		cronJob := &cronJobs[i]
+		key := types.NamespacedName{Namespace: cronJob.Namespace, Name: cronJob.Name}
+		activations, note := s.activations(cronJob, timeline.To)
+		for _, t := range activations {
+			timeline.Activations = append(timeline.Activations, Activation{CronJob: key, Time: t})
+		}
+		if note != "" {
+			timeline.Notes[key] = note
+		}
+	}
+
+	sort.SliceStable(timeline.Activations, func(i, j int) bool {
+		return timeline.Activations[i].Time.Before(timeline.Activations[j].Time)
+	})
+	return timeline
+}
+
+// activations returns the activations of the CronJob up to the given time, and a note if some are left out.
+func (s Simulator) activations(cronJob *batchv1.CronJob, to time.Time) ([]time.Time, string) {
+	if cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+		return nil, "suspended, never activated"
+	}
+	sched, err := cron.ParseStandard(cronJob.Spec.Schedule)
+	if err != nil {
+		return nil, fmt.Sprintf("unparseable schedule %q: %v", cronJob.Spec.Schedule, err)
+	}
+
+	from := s.From
+	if cronJob.Spec.TimeZone != nil && s.TimeZones {
+		loc, err := time.LoadLocation(*cronJob.Spec.TimeZone)
+		if err != nil {
+			return nil, fmt.Sprintf("unknown time zone %q: %v", *cronJob.Spec.TimeZone, err)
+		}
+		from = from.In(loc)
+	}
+
+	var activations []time.Time
+	for t := sched.Next(from); !t.IsZero() && !t.After(to); t = sched.Next(t) {
+		if len(activations) == maxActivations {
+			return activations, fmt.Sprintf("activated more than %d times, only the first %d are listed",
+				maxActivations, maxActivations)
+		}
+		activations = append(activations, t)
+	}
+	if len(activations) == 0 {
+		return nil, "not activated in the window"
+	}
+	return activations, ""
+}
+
+// Print writes the timeline, one activation per line, followed by the notes.
+func (t Timeline) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Activations from %s to %s:\n", t.From.UTC().Format(time.RFC3339), t.To.UTC().Format(time.RFC3339))
+	for _, activation := range t.Activations {
+		fmt.Fprintf(tw, "%s\t%s\t%s %s\n", activation.Time.UTC().Format(time.RFC3339), displayName(activation.CronJob),
+			activation.Time.Format(time.RFC3339), activation.Time.Location())
+	}
+
+	if len(t.Notes) > 0 {
+		keys := make([]types.NamespacedName, 0, len(t.Notes))
+		for key := range t.Notes {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		fmt.Fprintln(tw, "\nNotes:")
+		for _, key := range keys {
+			fmt.Fprintf(tw, "%s\t%s\n", displayName(key), t.Notes[key])
+		}
+	}
+	return tw.Flush()
+}
+
+// displayName returns the namespace and the name of the CronJob, or its name only when it has no namespace, e.g. in
+// a file.
+func displayName(key types.NamespacedName) string {
+	if key.Namespace == "" {
+		return key.Name
+	}
+	return key.String()
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulation
+
+import (
+	"bytes"
+	"time"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Simulator", func() {
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	cronJob := func(name, schedule string, timeZone *string, suspend bool) batchv1.CronJob {
+		return batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       batchv1.CronJobSpec{Schedule: schedule, TimeZone: timeZone, Suspend: &suspend},
+		}
+	}
+	istanbul := "Europe/Istanbul"
+
+	It("Should list the activations of the CronJobs in order", func() {
+		timeline := Simulator{From: from, Horizon: 12 * time.Hour}.Run([]batchv1.CronJob{
+			cronJob("every-4h", "0 */4 * * *", nil, false),
+			cronJob("at-5", "0 5 * * *", nil, false),
+		})
+		var activations []string
+		for _, activation := range timeline.Activations {
+			activations = append(activations, activation.Time.Format("15:04")+" "+activation.CronJob.Name)
+		}
+		Expect(activations).To(Equal([]string{"04:00 every-4h", "05:00 at-5", "08:00 every-4h", "12:00 every-4h"}))
+		Expect(timeline.Notes).To(BeEmpty())
+	})
+
+	It("Should follow the time zone of the CronJob only when the time zones are enabled", func() {
+		cronJobs := []batchv1.CronJob{cronJob("at-5", "0 5 * * *", &istanbul, false)}
+
+		timeline := Simulator{From: from, Horizon: 24 * time.Hour, TimeZones: true}.Run(cronJobs)
+		Expect(timeline.Activations).To(HaveLen(1))
+		Expect(timeline.Activations[0].Time.UTC()).To(Equal(time.Date(2021, 6, 1, 2, 0, 0, 0, time.UTC)))
+		Expect(timeline.Activations[0].Time.Location().String()).To(Equal(istanbul))
+
+		timeline = Simulator{From: from, Horizon: 24 * time.Hour}.Run(cronJobs)
+		Expect(timeline.Activations[0].Time).To(Equal(time.Date(2021, 6, 1, 5, 0, 0, 0, time.UTC)))
+	})
+
+	It("Should tell why a CronJob is not activated", func() {
+		unknown := "Mars/Olympus_Mons"
+		timeline := Simulator{From: from, Horizon: time.Hour, TimeZones: true}.Run([]batchv1.CronJob{
+			cronJob("suspended", "* * * * *", nil, true),
+			cronJob("invalid", "every day", nil, false),
+			cronJob("unknown-zone", "* * * * *", &unknown, false),
+			cronJob("yearly", "@yearly", nil, false),
+			cronJob("every-minute", "* * * * *", nil, false),
+		})
+		Expect(timeline.Activations).To(HaveLen(60))
+		Expect(timeline.Notes).To(HaveLen(4))
+		Expect(timeline.Notes[types.NamespacedName{Namespace: "default", Name: "suspended"}]).
+			To(Equal("suspended, never activated"))
+		Expect(timeline.Notes[types.NamespacedName{Namespace: "default", Name: "invalid"}]).
+			To(HavePrefix(`unparseable schedule "every day"`))
+		Expect(timeline.Notes[types.NamespacedName{Namespace: "default", Name: "unknown-zone"}]).
+			To(HavePrefix(`unknown time zone "Mars/Olympus_Mons"`))
+		Expect(timeline.Notes[types.NamespacedName{Namespace: "default", Name: "yearly"}]).
+			To(Equal("not activated in the window"))
+	})
+
+	It("Should bound the activations listed per CronJob", func() {
+		timeline := Simulator{From: from, Horizon: 24 * time.Hour}.Run([]batchv1.CronJob{
+			cronJob("every-minute", "* * * * *", nil, false),
+		})
+		Expect(timeline.Activations).To(HaveLen(maxActivations))
+		Expect(timeline.Notes[types.NamespacedName{Namespace: "default", Name: "every-minute"}]).
+			To(Equal("activated more than 100 times, only the first 100 are listed"))
+	})
+
+	It("Should print the timeline", func() {
+		timeline := Simulator{From: from, Horizon: 6 * time.Hour, TimeZones: true}.Run([]batchv1.CronJob{
+			cronJob("at-5", "0 5 * * *", &istanbul, false),
+			cronJob("suspended", "* * * * *", nil, true),
+		})
+		out := &bytes.Buffer{}
+		Expect(timeline.Print(out)).To(Succeed())
+		Expect(out.String()).To(Equal("Activations from 2021-06-01T00:00:00Z to 2021-06-01T06:00:00Z:\n" +
+			"2021-06-01T02:00:00Z  default/at-5  2021-06-01T05:00:00+03:00 Europe/Istanbul\n" +
+			"\n" +
+			"Notes:\n" +
+			"default/suspended  suspended, never activated\n"))
+	})
+})
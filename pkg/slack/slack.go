@@ -0,0 +1,293 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package slack serves the slash commands of a Slack app, so the on-call engineers start and inspect the runs of the
+// CronJobs from Slack: `/cronjob run <namespace>/<name>` and `/cronjob status <namespace>/<name>`.
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/controllers"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/restapi"
+)
+
+/*
+Every request is verified with the signing secret of the Slack app, see
+https://api.slack.com/authentication/verifying-requests-from-slack, and only accepted within 5 minutes of its
+timestamp. The signing secret and the users are read on every request, so both can be changed without a restart.
+
+Slack knows who sent a command, not what they may do in the cluster: a ConfigMap maps the Slack user IDs to the
+namespaces they may use, and a user who is not in it may use none. A CronJob of another namespace is answered like a
+missing one. The runs are manual runs, started through the same code as the trigger endpoint, and recorded in their
+JobRun as triggered by `slack:<user ID>`.
+
+The answers are ephemeral, only shown to the user, except the one of a started run which is posted to the channel, so
+the others on call see it. Slack shows an error for any response other than 200, so the failed commands are answered
+with a 200 too, only the requests which are not from Slack are rejected.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobruns,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+
+const (
+	// Path is the path of the endpoint of the commands.
+	Path = "/slack/commands"
+	// SigningSecretKey is the key of the signing secret in its Secret.
+	SigningSecretKey = "signingSecret"
+
+	timestampHeader = "X-Slack-Request-Timestamp"
+	signatureHeader = "X-Slack-Signature"
+
+	maxSignatureAge = 5 * time.Minute
+	maxBodyBytes    = 64 << 10
+	// lastRuns is the number of the runs listed by the status command.
+	lastRuns = 5
+
+	usage = "Usage: `/cronjob run <namespace>/<name>` starts a run of the CronJob, " +
+		"`/cronjob status <namespace>/<name>` shows its last runs."
+)
+
+var log = logf.Log.WithName("slack")
+
+// Handler answers the slash commands.
+type Handler struct {
+	client        client.Client
+	reader        client.Reader
+	scheme        *runtime.Scheme
+	signingSecret types.NamespacedName
+	users         types.NamespacedName
+	now           func() time.Time
+}
+
+// NewHandler returns the handler verifying the requests with the signing secret of the Secret, and authorizing the
+// users with the ConfigMap, both read with the reader. The runs are started with the client.
+func NewHandler(c client.Client, reader client.Reader, scheme *runtime.Scheme, signingSecret,
+	users types.NamespacedName) *Handler {
+	return &Handler{client: c, reader: reader, scheme: scheme, signingSecret: signingSecret, users: users,
+		now: time.Now}
+}
+
+// response is the answer to a command.
+type response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, "the body is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ctx := req.Context()
+	if err := h.verify(ctx, req, body); err != nil {
+		log.V(1).Info("request not verified", "reason", err.Error())
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	answer := h.command(ctx, form.Get("user_id"), strings.Fields(form.Get("text")))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(answer)
+}
+
+// verify returns why the request is not signed with the signing secret.
+func (h *Handler) verify(ctx context.Context, req *http.Request, body []byte) error {
+	var secret corev1.Secret
+	if err := h.reader.Get(ctx, h.signingSecret, &secret); err != nil {
+		return fmt.Errorf("unable to read the signing secret: %w", err)
+	}
+	key := secret.Data[SigningSecretKey]
+	if len(key) == 0 {
+		return fmt.Errorf("the Secret has no %s", SigningSecretKey)
+	}
+	timestamp, err := strconv.ParseInt(req.Header.Get(timestampHeader), 10, 64)
+	if err != nil {
+		return errors.New("invalid timestamp")
+	}
+	if age := h.now().Sub(time.Unix(timestamp, 0)); age > maxSignatureAge || age < -maxSignatureAge {
+		return errors.New("expired signature")
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(req.Header.Get(signatureHeader), "v0="))
+	if err != nil || !hmac.Equal(signature, Sign(key, timestamp, body)) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// Sign returns the signature of a request sent at the Unix timestamp, with the signing secret.
+func Sign(secret []byte, timestamp int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "v0:%d:", timestamp)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// command runs the command of the user and returns its answer.
+func (h *Handler) command(ctx context.Context, user string, args []string) response {
+	if len(args) != 2 || (args[0] != "run" && args[0] != "status") {
+		return ephemeral(usage)
+	}
+	parts := strings.Split(args[1], "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ephemeral(usage)
+	}
+	key := client.ObjectKey{Namespace: parts[0], Name: parts[1]}
+
+	allowed, err := h.allowed(ctx, user, key.Namespace)
+	if err != nil {
+		log.Error(err, "unable to read the Slack users")
+		return ephemeral("Something went wrong, please try again later.")
+	}
+	var cronJob batchv1.CronJob
+	if allowed {
+		err = h.client.Get(ctx, key, &cronJob)
+	}
+	if !allowed || apierrors.IsNotFound(err) {
+		log.V(1).Info("CronJob not allowed or not found", "cronJob", key.String(), "user", user)
+		return ephemeral(fmt.Sprintf("There is no CronJob `%s` you may use.", key))
+	}
+	if err != nil {
+		log.Error(err, "unable to get CronJob", "cronJob", key.String())
+		return ephemeral("Something went wrong, please try again later.")
+	}
+
+	if args[0] == "status" {
+		return h.status(ctx, &cronJob)
+	}
+	job, err := controllers.TriggerRun(ctx, h.client, h.scheme, &cronJob, h.now(), "slack:"+user)
+	var denied *controllers.RunDeniedError
+	switch {
+	case errors.As(err, &denied):
+		return ephemeral(fmt.Sprintf("The run of `%s` was not started, %s.", key, escape(denied.Reason)))
+	case err != nil:
+		log.Error(err, "unable to trigger a run", "cronJob", key.String())
+		return ephemeral("Something went wrong, please try again later.")
+	}
+	log.Info("triggered a run", "cronJob", key.String(), "job", job.Name, "user", user)
+	return response{ResponseType: "in_channel",
+		Text: fmt.Sprintf("<@%s> started the run `%s` of `%s`.", user, job.Name, key)}
+}
+
+// allowed returns whether the ConfigMap of the users allows the user the namespace.
+func (h *Handler) allowed(ctx context.Context, user, namespace string) (bool, error) {
+	var users corev1.ConfigMap
+	if err := h.reader.Get(ctx, h.users, &users); err != nil {
+		return false, err
+	}
+	if user == "" {
+		return false, nil
+	}
+	for _, name := range strings.Split(users.Data[user], ",") {
+		if name = strings.TrimSpace(name); name == "*" || name == namespace {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// status returns the schedule, the next run and the last runs of the CronJob.
+func (h *Handler) status(ctx context.Context, cronJob *batchv1.CronJob) response {
+	var text strings.Builder
+	fmt.Fprintf(&text, "`%s/%s` runs `%s`", cronJob.Namespace, cronJob.Name, cronJob.Spec.Schedule)
+	if cronJob.Spec.TimeZone != nil {
+		fmt.Fprintf(&text, " in %s", *cronJob.Spec.TimeZone)
+	}
+	switch {
+	case cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend:
+		text.WriteString(", it is suspended.")
+	default:
+		if next, err := restapi.Next(cronJob, h.now(), 1); err == nil && len(next.Next) > 0 {
+			fmt.Fprintf(&text, ", next at %s.", next.Next[0].UTC().Format(time.RFC3339))
+		} else {
+			text.WriteString(".")
+		}
+	}
+	if active := len(cronJob.Status.Active); active > 0 {
+		fmt.Fprintf(&text, " %d run(s) active.", active)
+	}
+
+	runs, err := restapi.ListRuns(ctx, h.reader, cronJob, lastRuns)
+	if err != nil {
+		log.Error(err, "unable to list the runs", "cronJob", cronJob.Namespace+"/"+cronJob.Name)
+		return ephemeral("Something went wrong, please try again later.")
+	}
+	if len(runs) == 0 {
+		text.WriteString("\nNo run recorded yet.")
+	}
+	for _, run := range runs {
+		phase := string(run.Phase)
+		if phase == "" {
+			phase = "Pending"
+		}
+		fmt.Fprintf(&text, "\n• `%s` %s, scheduled at %s", run.Name, phase,
+			run.ScheduledTime.UTC().Format(time.RFC3339))
+		if run.Duration != nil {
+			fmt.Fprintf(&text, ", took %s", run.Duration.Duration)
+		}
+		if run.FailureReason != "" {
+			fmt.Fprintf(&text, ", %s", escape(run.FailureReason))
+		}
+		if run.TriggeredBy != "" {
+			fmt.Fprintf(&text, ", by %s", escape(run.TriggeredBy))
+		}
+	}
+	return ephemeral(text.String())
+}
+
+// ephemeral returns an answer only shown to the user.
+func ephemeral(text string) response {
+	return response{ResponseType: "ephemeral", Text: text}
+}
+
+// escape escapes the characters Slack gives a meaning to.
+func escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
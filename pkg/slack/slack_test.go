@@ -0,0 +1,165 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slack
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kbatch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Handler", func() {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 5, 12, 30, 0, 0, time.UTC)
+
+	var (
+		handler *Handler
+		c       client.Client
+	)
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "payroll-ns", Name: "payroll", UID: "payroll-uid"},
+				Spec:       v1.CronJobSpec{Schedule: "0 18 * * *", ConcurrencyPolicy: v1.ForbidConcurrent},
+			},
+			&v1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "billing", Name: "invoices"},
+				Spec:       v1.CronJobSpec{Schedule: "0 * * * *"},
+			},
+			&v1.JobRun{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "payroll-ns", Name: "payroll-1622829600"},
+				Spec: v1.JobRunSpec{CronJob: "payroll", Trigger: v1.ScheduledTrigger,
+					ScheduledTime: metav1.NewTime(now.Add(-18*time.Hour - 30*time.Minute))},
+				Status: v1.JobRunStatus{Phase: v1.JobRunFailed, FailureReason: "BackoffLimitExceeded"},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "cronjob-system", Name: "slack"},
+				Data:       map[string][]byte{SigningSecretKey: []byte("signing-secret")},
+			},
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "cronjob-system", Name: "slack-users"},
+				Data:       map[string]string{"U-ONCALL": "payroll-ns, reports", "U-ADMIN": "*"},
+			},
+		).Build()
+		handler = NewHandler(c, c, scheme, types.NamespacedName{Namespace: "cronjob-system", Name: "slack"},
+			types.NamespacedName{Namespace: "cronjob-system", Name: "slack-users"})
+		handler.now = func() time.Time { return now }
+	})
+
+	signedRequest := func(secret string, at time.Time, user, text string) *http.Request {
+		body := url.Values{"user_id": {user}, "text": {text}, "command": {"/cronjob"}}.Encode()
+		req := httptest.NewRequest(http.MethodPost, Path, strings.NewReader(body))
+		req.Header.Set(timestampHeader, strconv.FormatInt(at.Unix(), 10))
+		req.Header.Set(signatureHeader, "v0="+hex.EncodeToString(Sign([]byte(secret), at.Unix(), []byte(body))))
+		return req
+	}
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+	command := func(user, text string) response {
+		recorder := serve(signedRequest("signing-secret", now, user, text))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var answer response
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &answer)).To(Succeed())
+		return answer
+	}
+
+	It("Should start a run for an allowed user and post it to the channel", func() {
+		answer := command("U-ONCALL", "run payroll-ns/payroll")
+		Expect(answer.ResponseType).To(Equal("in_channel"))
+		Expect(answer.Text).To(ContainSubstring("<@U-ONCALL>"))
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs, client.InNamespace("payroll-ns"))).To(Succeed())
+		Expect(jobs.Items).To(HaveLen(1))
+		Expect(answer.Text).To(ContainSubstring(jobs.Items[0].Name))
+		Expect(jobs.Items[0].Annotations).To(HaveKeyWithValue("batch.example.com/triggered-by", "slack:U-ONCALL"))
+
+		// the concurrency policy still applies
+		answer = command("U-ADMIN", "run payroll-ns/payroll")
+		Expect(answer.ResponseType).To(Equal("ephemeral"))
+		Expect(answer.Text).To(ContainSubstring("was not started"))
+	})
+
+	It("Should show the status of a CronJob", func() {
+		answer := command("U-ONCALL", "status payroll-ns/payroll")
+		Expect(answer.ResponseType).To(Equal("ephemeral"))
+		Expect(answer.Text).To(ContainSubstring("`0 18 * * *`, next at 2021-06-05T18:00:00Z"))
+		Expect(answer.Text).To(ContainSubstring("`payroll-1622829600` Failed"))
+		Expect(answer.Text).To(ContainSubstring("BackoffLimitExceeded"))
+	})
+
+	It("Should answer the CronJobs of the namespaces not allowed like the missing ones", func() {
+		for _, user := range []string{"U-ONCALL", "U-STRANGER"} {
+			answer := command(user, "run billing/invoices")
+			Expect(answer.Text).To(ContainSubstring("There is no CronJob `billing/invoices`"))
+		}
+		Expect(command("U-ONCALL", "status payroll-ns/missing").Text).To(ContainSubstring("There is no CronJob"))
+		Expect(command("U-ADMIN", "status billing/invoices").Text).To(ContainSubstring("`0 * * * *`"))
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs)).To(Succeed())
+		Expect(jobs.Items).To(BeEmpty())
+	})
+
+	It("Should answer the usage to the unknown commands", func() {
+		for _, text := range []string{"", "help", "delete payroll-ns/payroll", "run payroll", "run /payroll"} {
+			Expect(command("U-ONCALL", text).Text).To(HavePrefix("Usage:"), text)
+		}
+	})
+
+	It("Should reject the requests not signed by Slack", func() {
+		Expect(serve(signedRequest("other-secret", now, "U-ADMIN", "run payroll-ns/payroll")).Code).
+			To(Equal(http.StatusUnauthorized))
+		Expect(serve(signedRequest("signing-secret", now.Add(-10*time.Minute), "U-ADMIN", "run payroll-ns/payroll")).Code).
+			To(Equal(http.StatusUnauthorized))
+
+		req := signedRequest("signing-secret", now, "U-ADMIN", "run payroll-ns/payroll")
+		req.Header.Del(signatureHeader)
+		Expect(serve(req).Code).To(Equal(http.StatusUnauthorized))
+
+		req = httptest.NewRequest(http.MethodGet, Path, nil)
+		Expect(serve(req).Code).To(Equal(http.StatusMethodNotAllowed))
+
+		var jobs kbatch.JobList
+		Expect(c.List(ctx, &jobs)).To(Succeed())
+		Expect(jobs.Items).To(BeEmpty())
+	})
+})
@@ -0,0 +1,145 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package slo evaluates the service level objectives of the CronJobs: whether a scheduled run met the objective of its
+// CronJob, and the compliance of the last runs with it.
+package slo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+const (
+	// DefaultObjective is the percentage of the runs meeting the objective when the CronJob does not set one.
+	DefaultObjective = "99"
+	// DefaultWindow is the number of the last runs the compliance is computed over.
+	DefaultWindow = 30
+)
+
+// Objective returns the objective of the SLO as a ratio, e.g. 0.995 for `99.5`.
+func Objective(slo *v1.CronJobSLO) (float64, error) {
+	value := slo.Objective
+	if value == "" {
+		value = DefaultObjective
+	}
+	percent, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(percent) || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("must be a percentage above 0 and up to 100, got %q", value)
+	}
+	return percent / 100, nil
+}
+
+/*
+A run is evaluated from the status of its JobRun while it runs. It breaches the objective as soon as it is late, so a
+run stuck in the queue is counted before it finishes, and meets it once every delay of the objective is met: the run
+started in time, and succeeded in time if the objective has a completion delay. Until then, the result is unknown and
+the run is evaluated again at the next deadline.
+*/
+
+// Evaluate returns whether the run scheduled at the time met the SLO, given its status at the time now, and why it
+// breached it. The result is empty while unknown, the run is evaluated again at the returned time at the latest.
+func Evaluate(slo *v1.CronJobSLO, scheduledTime time.Time, status *v1.JobRunStatus,
+	now time.Time) (v1.SLOResult, string, time.Time) {
+	var recheck time.Time
+	later := func(deadline time.Time) {
+		if recheck.IsZero() || deadline.Before(recheck) {
+			recheck = deadline
+		}
+	}
+
+	if slo.MaxStartDelay != nil {
+		deadline := scheduledTime.Add(slo.MaxStartDelay.Duration)
+		switch {
+		case status.StartTime != nil && status.StartTime.After(deadline):
+			return v1.SLOBreached, fmt.Sprintf("started %s after its scheduled time, the objective is %s",
+				status.StartTime.Sub(scheduledTime), slo.MaxStartDelay.Duration), time.Time{}
+		case status.StartTime == nil && status.CompletionTime != nil:
+			return v1.SLOBreached, "finished without starting", time.Time{}
+		case status.StartTime == nil && now.After(deadline):
+			return v1.SLOBreached, fmt.Sprintf("not started %s after its scheduled time", slo.MaxStartDelay.Duration),
+				time.Time{}
+		case status.StartTime == nil:
+			later(deadline)
+		}
+	}
+
+	if slo.CompleteWithin != nil {
+		deadline := scheduledTime.Add(slo.CompleteWithin.Duration)
+		switch {
+		case status.CompletionTime != nil && status.Phase != v1.JobRunSucceeded:
+			return v1.SLOBreached, "the run " + strings.ToLower(string(status.Phase)), time.Time{}
+		case status.CompletionTime != nil && status.CompletionTime.After(deadline):
+			return v1.SLOBreached, fmt.Sprintf("succeeded %s after its scheduled time, the objective is %s",
+				status.CompletionTime.Sub(scheduledTime), slo.CompleteWithin.Duration), time.Time{}
+		case status.CompletionTime == nil && now.After(deadline):
+			return v1.SLOBreached, fmt.Sprintf("not succeeded %s after its scheduled time",
+				slo.CompleteWithin.Duration), time.Time{}
+		case status.CompletionTime == nil:
+			later(deadline)
+		}
+	}
+
+	if !recheck.IsZero() {
+		return "", "", recheck
+	}
+	return v1.SLOMet, "", time.Time{}
+}
+
+// Compliance returns the compliance of the last runs of the window with the SLO, among the given runs of a CronJob.
+// The runs without a result are left out.
+func Compliance(slo *v1.CronJobSLO, runs []v1.JobRun) (*v1.CronJobSLOStatus, error) {
+	objective, err := Objective(slo)
+	if err != nil {
+		return nil, err
+	}
+	window := DefaultWindow
+	if slo.Window != nil {
+		window = int(*slo.Window)
+	}
+
+	var evaluated []*v1.JobRun
+	for i := range runs {
+		if runs[i].Status.SLOResult != "" {
+			evaluated = append(evaluated, &runs[i])
+		}
+	}
+	sort.Slice(evaluated, func(i, j int) bool {
+		return evaluated[i].Spec.ScheduledTime.After(evaluated[j].Spec.ScheduledTime.Time)
+	})
+	if len(evaluated) > window {
+		evaluated = evaluated[:window]
+	}
+
+	status := &v1.CronJobSLOStatus{Runs: int32(len(evaluated)), Compliant: true}
+	for _, run := range evaluated {
+		if run.Status.SLOResult == v1.SLOMet {
+			status.MetRuns++
+		}
+	}
+	if status.Runs > 0 {
+		ratio := float64(status.MetRuns) / float64(status.Runs)
+		status.Ratio = strconv.FormatFloat(ratio, 'f', 4, 64)
+		status.Compliant = ratio >= objective
+	}
+	return status, nil
+}
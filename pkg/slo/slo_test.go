@@ -0,0 +1,113 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package slo
+
+import (
+	"time"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("SLO", func() {
+	scheduled := time.Date(2021, 6, 5, 2, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *metav1.Time {
+		return &metav1.Time{Time: scheduled.Add(d)}
+	}
+	spec := &v1.CronJobSLO{
+		MaxStartDelay:  &metav1.Duration{Duration: 5 * time.Minute},
+		CompleteWithin: &metav1.Duration{Duration: time.Hour},
+	}
+
+	It("Should parse the objective", func() {
+		Expect(Objective(&v1.CronJobSLO{})).To(Equal(0.99))
+		Expect(Objective(&v1.CronJobSLO{Objective: "99.5"})).To(Equal(0.995))
+		for _, objective := range []string{"0", "100.1", "NaN", "high"} {
+			_, err := Objective(&v1.CronJobSLO{Objective: objective})
+			Expect(err).To(MatchError(ContainSubstring("must be a percentage")))
+		}
+	})
+
+	It("Should wait for the deadlines before deciding", func() {
+		result, _, recheck := Evaluate(spec, scheduled, &v1.JobRunStatus{}, scheduled.Add(time.Minute))
+		Expect(result).To(BeEmpty())
+		Expect(recheck).To(Equal(scheduled.Add(5 * time.Minute)))
+
+		running := &v1.JobRunStatus{Phase: v1.JobRunRunning, StartTime: at(time.Minute)}
+		result, _, recheck = Evaluate(spec, scheduled, running, scheduled.Add(10*time.Minute))
+		Expect(result).To(BeEmpty())
+		Expect(recheck).To(Equal(scheduled.Add(time.Hour)))
+	})
+
+	It("Should breach the late runs as soon as they are late", func() {
+		result, message, _ := Evaluate(spec, scheduled, &v1.JobRunStatus{}, scheduled.Add(6*time.Minute))
+		Expect(result).To(Equal(v1.SLOBreached))
+		Expect(message).To(Equal("not started 5m0s after its scheduled time"))
+
+		started := &v1.JobRunStatus{Phase: v1.JobRunRunning, StartTime: at(10 * time.Minute)}
+		result, message, _ = Evaluate(spec, scheduled, started, scheduled.Add(10*time.Minute))
+		Expect(result).To(Equal(v1.SLOBreached))
+		Expect(message).To(Equal("started 10m0s after its scheduled time, the objective is 5m0s"))
+
+		running := &v1.JobRunStatus{Phase: v1.JobRunRunning, StartTime: at(time.Minute)}
+		result, _, _ = Evaluate(spec, scheduled, running, scheduled.Add(2*time.Hour))
+		Expect(result).To(Equal(v1.SLOBreached))
+
+		failed := &v1.JobRunStatus{Phase: v1.JobRunFailed, StartTime: at(time.Minute), CompletionTime: at(time.Minute)}
+		result, message, _ = Evaluate(spec, scheduled, failed, scheduled.Add(2*time.Minute))
+		Expect(result).To(Equal(v1.SLOBreached))
+		Expect(message).To(Equal("the run failed"))
+	})
+
+	It("Should meet the runs succeeding in time", func() {
+		succeeded := &v1.JobRunStatus{Phase: v1.JobRunSucceeded, StartTime: at(time.Minute),
+			CompletionTime: at(30 * time.Minute)}
+		result, _, _ := Evaluate(spec, scheduled, succeeded, scheduled.Add(30*time.Minute))
+		Expect(result).To(Equal(v1.SLOMet))
+
+		startOnly := &v1.CronJobSLO{MaxStartDelay: spec.MaxStartDelay}
+		running := &v1.JobRunStatus{Phase: v1.JobRunRunning, StartTime: at(time.Minute)}
+		result, _, _ = Evaluate(startOnly, scheduled, running, scheduled.Add(time.Minute))
+		Expect(result).To(Equal(v1.SLOMet))
+	})
+
+	It("Should compute the compliance of the last runs", func() {
+		run := func(hours int, result v1.SLOResult) v1.JobRun {
+			return v1.JobRun{
+				Spec:   v1.JobRunSpec{ScheduledTime: metav1.Time{Time: scheduled.Add(time.Duration(hours) * time.Hour)}},
+				Status: v1.JobRunStatus{SLOResult: result},
+			}
+		}
+		window := int32(3)
+		runs := []v1.JobRun{run(0, v1.SLOBreached), run(1, v1.SLOMet), run(2, v1.SLOBreached), run(3, v1.SLOMet),
+			run(4, v1.SLOMet), run(5, "")}
+
+		status, err := Compliance(&v1.CronJobSLO{Objective: "60", Window: &window}, runs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*status).To(Equal(v1.CronJobSLOStatus{Runs: 3, MetRuns: 2, Ratio: "0.6667", Compliant: true}))
+
+		status, err = Compliance(&v1.CronJobSLO{}, runs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*status).To(Equal(v1.CronJobSLOStatus{Runs: 5, MetRuns: 3, Ratio: "0.6000", Compliant: false}))
+
+		status, err = Compliance(&v1.CronJobSLO{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*status).To(Equal(v1.CronJobSLOStatus{Compliant: true}))
+	})
+})
@@ -0,0 +1,64 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"testing/fstest"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/config/crd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReadManifests", func() {
+	It("Should read the embedded CRDs", func() {
+		objects, err := ReadManifests(crd.Bases)
+		Expect(err).NotTo(HaveOccurred())
+
+		var names []string
+		for _, object := range objects {
+			Expect(object.GetKind()).To(Equal("CustomResourceDefinition"))
+			names = append(names, object.GetName())
+		}
+		Expect(names).To(Equal([]string{"backfills.batch.example.com", "calendars.batch.example.com",
+			"clustercronjobpolicies.batch.example.com", "clustercronjobs.batch.example.com",
+			"clustermaintenancewindows.batch.example.com", "cronjobgroups.batch.example.com",
+			"cronjobpolicies.batch.example.com", "cronjobquotas.batch.example.com", "cronjobreports.batch.example.com",
+			"cronjobs.batch.example.com", "cronjobsets.batch.example.com", "gitsyncs.batch.example.com",
+			"jobruns.batch.example.com", "jobtemplates.batch.example.com", "maintenancewindows.batch.example.com",
+			"notificationchannels.batch.example.com", "operatorstatuses.batch.example.com",
+			"scheduleimports.batch.example.com", "scheduleoverrides.batch.example.com", "workflows.batch.example.com"}))
+	})
+
+	It("Should read every document of the YAML files only", func() {
+		objects, err := ReadManifests(fstest.MapFS{
+			"crds/a.yaml": {Data: []byte("---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\n" +
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n")},
+			"crds/b.yml":      {Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: c\n")},
+			"crds/README.md":  {Data: []byte("# CRDs")},
+			"crds/empty.yaml": {Data: []byte("---\n")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objects).To(HaveLen(3))
+		Expect(objects[2].GetName()).To(Equal("c"))
+	})
+
+	It("Should report the file which can not be decoded", func() {
+		_, err := ReadManifests(fstest.MapFS{"crds/a.yaml": {Data: []byte("kind: [")}})
+		Expect(err).To(MatchError(ContainSubstring("unable to decode crds/a.yaml")))
+	})
+})
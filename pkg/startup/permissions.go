@@ -0,0 +1,164 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+Misapplied RBAC does not stop the manager from starting: the caches of the resources it may not watch never sync, and
+the writes it may not do fail with Forbidden errors, possibly hours later when the first Job is due. So the manager
+reviews the permissions it needs with SelfSubjectAccessReviews before it starts, and stops right away with the list of
+the missing ones. A missing permission is not retried, the RBAC of the manager is applied along with it.
+*/
+
+// Permission is an access to the API the manager needs.
+type Permission struct {
+	// Verb is the API verb, e.g. `list`.
+	Verb string
+	// Group, Resource and Subresource identify the resource, e.g. `batch.example.com`, `cronjobs` and `status`.
+	Group, Resource, Subresource string
+	// Namespace restricts the permission to a namespace, it is needed in all the namespaces if empty.
+	Namespace string
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Namespace == "" {
+		return fmt.Sprintf("%s %s in all the namespaces", p.Verb, resource)
+	}
+	return fmt.Sprintf("%s %s in namespace %s", p.Verb, resource, p.Namespace)
+}
+
+// Permissions returns the permissions of the verbs on the resource in each of the namespaces, or in all the
+// namespaces if none is given.
+func Permissions(group, resource string, verbs []string, namespaces ...string) []Permission {
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	subresource := ""
+	if i := strings.Index(resource, "/"); i >= 0 {
+		resource, subresource = resource[:i], resource[i+1:]
+	}
+
+	var permissions []Permission
+	for _, namespace := range namespaces {
+		for _, verb := range verbs {
+			permissions = append(permissions, Permission{
+				Verb:        verb,
+				Group:       group,
+				Resource:    resource,
+				Subresource: subresource,
+				Namespace:   namespace,
+			})
+		}
+	}
+	return permissions
+}
+
+// serviceAccountNamespaceFile holds the namespace of the pod, where controller-runtime puts the leader election lock
+// by default.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// LeaderElectionPermissions returns the permissions needed to hold the leader election lock of the given kind in
+// the namespace, and to record the leader election events. The namespace defaults to the one of the pod, no
+// permission is returned outside of a pod.
+func LeaderElectionPermissions(resourceLock, namespace string) []Permission {
+	if namespace == "" {
+		data, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+		if err != nil {
+			// the manager fails to start with a clearer error
+			return nil
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	verbs := []string{"get", "create", "update"}
+	var permissions []Permission
+	switch resourceLock {
+	case resourcelock.LeasesResourceLock:
+		permissions = Permissions("coordination.k8s.io", "leases", verbs, namespace)
+	case resourcelock.ConfigMapsResourceLock:
+		permissions = Permissions("", "configmaps", verbs, namespace)
+	case resourcelock.EndpointsResourceLock:
+		permissions = Permissions("", "endpoints", verbs, namespace)
+	case resourcelock.EndpointsLeasesResourceLock:
+		permissions = append(Permissions("", "endpoints", verbs, namespace),
+			Permissions("coordination.k8s.io", "leases", verbs, namespace)...)
+	default:
+		// configmapsleases, the default of controller-runtime
+		permissions = append(Permissions("", "configmaps", verbs, namespace),
+			Permissions("coordination.k8s.io", "leases", verbs, namespace)...)
+	}
+	return append(permissions, Permissions("", "events", []string{"create", "patch"}, namespace)...)
+}
+
+// MissingPermissionsError lists the permissions the manager lacks.
+type MissingPermissionsError struct {
+	Permissions []Permission
+}
+
+func (e *MissingPermissionsError) Error() string {
+	missing := make([]string, 0, len(e.Permissions))
+	for _, permission := range e.Permissions {
+		missing = append(missing, permission.String())
+	}
+	return fmt.Sprintf("the manager is not allowed to %s", strings.Join(missing, ", "))
+}
+
+// CheckPermissions reviews the permissions with SelfSubjectAccessReviews. It returns a MissingPermissionsError
+// listing all the permissions which are not granted.
+func CheckPermissions(ctx context.Context, c client.Client, permissions []Permission) error {
+	var missing []Permission
+	for _, permission := range permissions {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   permission.Namespace,
+					Verb:        permission.Verb,
+					Group:       permission.Group,
+					Resource:    permission.Resource,
+					Subresource: permission.Subresource,
+				},
+			},
+		}
+		if err := c.Create(ctx, review); err != nil {
+			return fmt.Errorf("unable to review the permission to %s: %w", permission, err)
+		}
+		if !review.Status.Allowed {
+			missing = append(missing, permission)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingPermissionsError{Permissions: missing}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// reviewingClient answers the SelfSubjectAccessReviews with the granted permissions.
+type reviewingClient struct {
+	client.Client
+	granted map[Permission]bool
+	reviews int
+}
+
+func (c *reviewingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if review, ok := obj.(*authorizationv1.SelfSubjectAccessReview); ok {
+		c.reviews++
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = c.granted[Permission{
+			Verb:        attributes.Verb,
+			Group:       attributes.Group,
+			Resource:    attributes.Resource,
+			Subresource: attributes.Subresource,
+			Namespace:   attributes.Namespace,
+		}]
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+var _ = Describe("Permissions", func() {
+	var c *reviewingClient
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(AddToScheme(scheme)).To(Succeed())
+		c = &reviewingClient{
+			Client:  fake.NewClientBuilder().WithScheme(scheme).Build(),
+			granted: map[Permission]bool{},
+		}
+	})
+
+	It("Should expand the verbs and the namespaces", func() {
+		Expect(Permissions("batch.example.com", "cronjobs/status", []string{"get", "update"}, "a", "b")).To(Equal([]Permission{
+			{Verb: "get", Group: "batch.example.com", Resource: "cronjobs", Subresource: "status", Namespace: "a"},
+			{Verb: "update", Group: "batch.example.com", Resource: "cronjobs", Subresource: "status", Namespace: "a"},
+			{Verb: "get", Group: "batch.example.com", Resource: "cronjobs", Subresource: "status", Namespace: "b"},
+			{Verb: "update", Group: "batch.example.com", Resource: "cronjobs", Subresource: "status", Namespace: "b"},
+		}))
+		Expect(Permissions("batch", "jobs", []string{"list"})).To(Equal([]Permission{
+			{Verb: "list", Group: "batch", Resource: "jobs"},
+		}))
+	})
+
+	It("Should need the lock and the events for the leader election", func() {
+		permissions := LeaderElectionPermissions("leases", "cronjob-system")
+		Expect(permissions).To(HaveLen(5))
+		Expect(permissions[0].String()).To(Equal("get leases.coordination.k8s.io in namespace cronjob-system"))
+		Expect(permissions[4].String()).To(Equal("patch events in namespace cronjob-system"))
+		Expect(LeaderElectionPermissions("", "cronjob-system")).To(HaveLen(8))
+	})
+
+	It("Should pass when every permission is granted", func() {
+		permissions := Permissions("batch", "jobs", []string{"list", "create"})
+		for _, permission := range permissions {
+			c.granted[permission] = true
+		}
+		Expect(CheckPermissions(context.Background(), c, permissions)).To(Succeed())
+		Expect(c.reviews).To(Equal(2))
+	})
+
+	It("Should list all the missing permissions", func() {
+		permissions := append(Permissions("batch", "jobs", []string{"list", "create"}),
+			Permissions("batch.example.com", "cronjobs/status", []string{"update"}, "default")...)
+		c.granted[permissions[0]] = true
+
+		err := CheckPermissions(context.Background(), c, permissions)
+		Expect(err).To(MatchError("the manager is not allowed to create jobs.batch in all the namespaces, " +
+			"update cronjobs.batch.example.com/status in namespace default"))
+		Expect(IsTransient(err)).To(BeFalse())
+	})
+})
@@ -0,0 +1,158 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+Without its CRDs, the manager starts anyway and its caches never sync, so it only fails after a timeout which does not
+say what is missing. The same goes for the webhook configurations, without them the CronJobs are silently neither
+defaulted nor validated. So before the manager starts, we check that they are installed. A missing prerequisite is
+waited for, like an unavailable API server, since it may be applied right after the operator.
+*/
+
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations;validatingwebhookconfigurations,verbs=list
+
+// Prerequisites lists what has to be installed in the cluster before the manager starts.
+type Prerequisites struct {
+	// CRDs are the resources whose CustomResourceDefinitions have to be established and serve the version.
+	CRDs []schema.GroupVersionResource
+	// MutatingWebhooks and ValidatingWebhooks are the names of the webhooks which have to be in a webhook
+	// configuration.
+	MutatingWebhooks, ValidatingWebhooks []string
+}
+
+// NotInstalledError reports a prerequisite which is not installed, or not ready yet.
+type NotInstalledError struct {
+	// Kind and Name identify the missing object.
+	Kind, Name string
+	// Reason tells what is wrong with it.
+	Reason string
+}
+
+func (e *NotInstalledError) Error() string {
+	return fmt.Sprintf("%s %s %s", e.Kind, e.Name, e.Reason)
+}
+
+// AddToScheme adds the types read by Check and CheckPermissions to the scheme.
+func AddToScheme(scheme *runtime.Scheme) error {
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := authorizationv1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	return admissionregistrationv1.AddToScheme(scheme)
+}
+
+// Check returns an error for the first prerequisite which is not installed.
+func (p Prerequisites) Check(ctx context.Context, c client.Reader) error {
+	for _, resource := range p.CRDs {
+		if err := checkCRD(ctx, c, resource); err != nil {
+			return err
+		}
+	}
+
+	if len(p.MutatingWebhooks) > 0 {
+		configs := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+		if err := c.List(ctx, configs); err != nil {
+			return err
+		}
+		installed := map[string]bool{}
+		for _, config := range configs.Items {
+			for _, webhook := range config.Webhooks {
+				installed[webhook.Name] = true
+			}
+		}
+		if err := checkWebhooks("mutating webhook", p.MutatingWebhooks, installed); err != nil {
+			return err
+		}
+	}
+
+	if len(p.ValidatingWebhooks) > 0 {
+		configs := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+		if err := c.List(ctx, configs); err != nil {
+			return err
+		}
+		installed := map[string]bool{}
+		for _, config := range configs.Items {
+			for _, webhook := range config.Webhooks {
+				installed[webhook.Name] = true
+			}
+		}
+		if err := checkWebhooks("validating webhook", p.ValidatingWebhooks, installed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkCRD(ctx context.Context, c client.Reader, resource schema.GroupVersionResource) error {
+	name := resource.GroupResource().String()
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, crd); apierrors.IsNotFound(err) {
+		return &NotInstalledError{Kind: "CustomResourceDefinition", Name: name, Reason: "is not installed"}
+	} else if err != nil {
+		return err
+	}
+
+	established := false
+	for _, condition := range crd.Status.Conditions {
+		if condition.Type == apiextensionsv1.Established {
+			established = condition.Status == apiextensionsv1.ConditionTrue
+		}
+	}
+	if !established {
+		return &NotInstalledError{Kind: "CustomResourceDefinition", Name: name, Reason: "is not established yet"}
+	}
+	for _, version := range crd.Spec.Versions {
+		if version.Name == resource.Version && version.Served {
+			return nil
+		}
+	}
+	return &NotInstalledError{Kind: "CustomResourceDefinition", Name: name,
+		Reason: fmt.Sprintf("does not serve the version %s", resource.Version)}
+}
+
+func checkWebhooks(kind string, names []string, installed map[string]bool) error {
+	for _, name := range names {
+		if !installed[name] {
+			return &NotInstalledError{Kind: kind, Name: name, Reason: "is not in any webhook configuration"}
+		}
+	}
+	return nil
+}
+
+// isNotInstalled returns whether the error reports a missing prerequisite.
+func isNotInstalled(err error) bool {
+	var notInstalled *NotInstalledError
+	return errors.As(err, &notInstalled)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Prerequisites", func() {
+	cronJobs := schema.GroupVersionResource{Group: "batch.example.com", Version: "v1", Resource: "cronjobs"}
+	prerequisites := Prerequisites{
+		CRDs:               []schema.GroupVersionResource{cronJobs},
+		MutatingWebhooks:   []string{"mcronjob.kb.io"},
+		ValidatingWebhooks: []string{"vcronjob.kb.io"},
+	}
+
+	crd := func(established apiextensionsv1.ConditionStatus, version string) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "cronjobs.batch.example.com"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: version, Served: true}},
+			},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{
+				Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{{
+					Type:   apiextensionsv1.Established,
+					Status: established,
+				}},
+			},
+		}
+	}
+	mutatingConfig := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubebuilder-tutorial-mutating-webhook-configuration"},
+		Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "mcronjob.kb.io"}},
+	}
+	validatingConfig := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "kubebuilder-tutorial-validating-webhook-configuration"},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "vcronjob.kb.io"}},
+	}
+
+	check := func(p Prerequisites, objects ...client.Object) error {
+		scheme := runtime.NewScheme()
+		Expect(AddToScheme(scheme)).To(Succeed())
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+		return p.Check(context.Background(), c)
+	}
+
+	It("Should pass when everything is installed", func() {
+		Expect(check(prerequisites, crd(apiextensionsv1.ConditionTrue, "v1"), mutatingConfig, validatingConfig)).
+			To(Succeed())
+	})
+
+	It("Should tell which prerequisite is missing", func() {
+		err := check(prerequisites, mutatingConfig, validatingConfig)
+		Expect(err).To(MatchError("CustomResourceDefinition cronjobs.batch.example.com is not installed"))
+		Expect(IsTransient(err)).To(BeTrue())
+
+		Expect(check(prerequisites, crd(apiextensionsv1.ConditionFalse, "v1"), mutatingConfig, validatingConfig)).
+			To(MatchError("CustomResourceDefinition cronjobs.batch.example.com is not established yet"))
+		Expect(check(prerequisites, crd(apiextensionsv1.ConditionTrue, "v2"), mutatingConfig, validatingConfig)).
+			To(MatchError("CustomResourceDefinition cronjobs.batch.example.com does not serve the version v1"))
+		Expect(check(prerequisites, crd(apiextensionsv1.ConditionTrue, "v1"), validatingConfig)).
+			To(MatchError("mutating webhook mcronjob.kb.io is not in any webhook configuration"))
+		Expect(check(prerequisites, crd(apiextensionsv1.ConditionTrue, "v1"), mutatingConfig)).
+			To(MatchError("validating webhook vcronjob.kb.io is not in any webhook configuration"))
+	})
+
+	It("Should not check the webhook configurations of the disabled webhooks", func() {
+		Expect(check(Prerequisites{CRDs: prerequisites.CRDs}, crd(apiextensionsv1.ConditionTrue, "v1"))).To(Succeed())
+	})
+
+	It("Should wait for a prerequisite until it is installed", func() {
+		backoff := Backoff{InitialDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond, Timeout: time.Second}
+		attempts := 0
+		err := backoff.Retry(context.Background(), "check prerequisites", func() error {
+			if attempts++; attempts < 3 {
+				return check(prerequisites)
+			}
+			return check(prerequisites, crd(apiextensionsv1.ConditionTrue, "v1"), mutatingConfig, validatingConfig)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attempts).To(Equal(3))
+	})
+})
@@ -0,0 +1,99 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package startup checks the prerequisites of the manager and retries the steps of its startup which fail while the
+// API server is unavailable.
+package startup
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+/*
+At the boot of a cluster, the manager may start before the API server accepts connections. Exiting on the first
+error sends the pod into a crash loop with a growing back-off, so the steps talking to the API server are retried
+for a while instead. Only the errors which may go away by themselves are retried, an invalid setting still stops the
+manager right away.
+*/
+
+var log = logf.Log.WithName("startup")
+
+// Backoff bounds the retries of a startup step.
+type Backoff struct {
+	// InitialDelay is the delay before the first retry, doubled on every retry.
+	InitialDelay time.Duration
+	// MaxDelay caps the delay between two retries.
+	MaxDelay time.Duration
+	// Timeout is how long a step is retried before giving up.
+	Timeout time.Duration
+}
+
+// DefaultBackoff retries a step for 2 minutes.
+var DefaultBackoff = Backoff{
+	InitialDelay: time.Second,
+	MaxDelay:     30 * time.Second,
+	Timeout:      2 * time.Minute,
+}
+
+// Retry calls fn until it succeeds, it fails with an error which is not transient, the timeout of the back-off
+// expires or the context is done. It returns the last error of fn.
+func (b Backoff) Retry(ctx context.Context, step string, fn func() error) error {
+	deadline := time.Now().Add(b.Timeout)
+	delay := b.InitialDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !IsTransient(err) {
+			return err
+		}
+		if time.Now().Add(delay).After(deadline) {
+			log.Error(err, "giving up", "step", step, "attempts", attempt)
+			return err
+		}
+
+		log.Info("retrying", "step", step, "attempt", attempt, "delay", delay.String(), "error", err.Error())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > b.MaxDelay {
+			delay = b.MaxDelay
+		}
+	}
+}
+
+// IsTransient returns whether the error may go away by itself, like the API server being unreachable or
+// overloaded, or a prerequisite which is not installed yet.
+func IsTransient(err error) bool {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr), isNotInstalled(err):
+		return true
+	case utilnet.IsConnectionRefused(err), utilnet.IsConnectionReset(err), utilnet.IsProbableEOF(err):
+		return true
+	case apierrors.IsServerTimeout(err), apierrors.IsTimeout(err), apierrors.IsTooManyRequests(err),
+		apierrors.IsServiceUnavailable(err), apierrors.IsInternalError(err):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var _ = Describe("Retry", func() {
+	backoff := Backoff{InitialDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond, Timeout: time.Second}
+	refused := &url.Error{Op: "Get", URL: "https://10.96.0.1:443/api", Err: &net.OpError{
+		Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED,
+	}}
+
+	It("Should retry the transient errors until the step succeeds", func() {
+		attempts := 0
+		err := backoff.Retry(context.Background(), "test", func() error {
+			if attempts++; attempts < 3 {
+				return refused
+			}
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("Should not retry the permanent errors", func() {
+		attempts := 0
+		permanent := errors.New("invalid config")
+		err := backoff.Retry(context.Background(), "test", func() error {
+			attempts++
+			return permanent
+		})
+		Expect(err).To(Equal(permanent))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("Should give up after the timeout", func() {
+		short := Backoff{InitialDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond, Timeout: 50 * time.Millisecond}
+		attempts := 0
+		err := short.Retry(context.Background(), "test", func() error {
+			attempts++
+			return refused
+		})
+		Expect(err).To(Equal(refused))
+		Expect(attempts).To(BeNumerically("<=", 6))
+	})
+
+	It("Should stop when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		attempts := 0
+		err := backoff.Retry(ctx, "test", func() error {
+			attempts++
+			return refused
+		})
+		Expect(err).To(Equal(refused))
+		Expect(attempts).To(Equal(1))
+	})
+})
+
+var _ = Describe("IsTransient", func() {
+	It("Should tell the transient errors apart", func() {
+		Expect(IsTransient(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})).To(BeTrue())
+		Expect(IsTransient(apierrors.NewServiceUnavailable("starting"))).To(BeTrue())
+		Expect(IsTransient(apierrors.NewTooManyRequests("slow down", 1))).To(BeTrue())
+
+		Expect(IsTransient(errors.New("invalid config"))).To(BeFalse())
+		Expect(IsTransient(apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "cert", errors.New("rbac")))).To(BeFalse())
+	})
+})
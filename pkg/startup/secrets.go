@@ -0,0 +1,52 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SecretReader returns the data of the Secret of a namespace/name reference, nil if the reference is empty.
+type SecretReader func(ctx context.Context, ref string) (map[string][]byte, error)
+
+// SecretReader returns the reader of the Secrets holding the credentials of the components. The Secrets are read
+// uncached, so they are not watched, and retried with the back-off while the API server is unavailable.
+func (b Backoff) SecretReader(reader client.Reader) SecretReader {
+	return func(ctx context.Context, ref string) (map[string][]byte, error) {
+		if ref == "" {
+			return nil, nil
+		}
+		namespace, name := SplitRef(ref)
+		var secret corev1.Secret
+		if err := b.Retry(ctx, "read Secret "+ref, func() error {
+			return reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &secret)
+		}); err != nil {
+			return nil, err
+		}
+		return secret.Data, nil
+	}
+}
+
+// SplitRef splits the namespace/name reference of an object, validated with the config.
+func SplitRef(ref string) (namespace, name string) {
+	parts := strings.SplitN(ref, "/", 2)
+	return parts[0], parts[1]
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package startup
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// unavailableReader fails the first reads as if the API server was starting.
+type unavailableReader struct {
+	client.Reader
+	failures int
+}
+
+func (r *unavailableReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if r.failures > 0 {
+		r.failures--
+		return apierrors.NewServiceUnavailable("the API server is starting")
+	}
+	return r.Reader.Get(ctx, key, obj)
+}
+
+var _ = Describe("SecretReader", func() {
+	backoff := Backoff{InitialDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond, Timeout: time.Second}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "cronjob-system", Name: "archive"},
+		Data:       map[string][]byte{"accessKey": []byte("AKIA")},
+	}
+
+	It("Should read the Secret once the API server is available", func() {
+		reader := &unavailableReader{Reader: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build(),
+			failures: 2}
+		data, err := backoff.SecretReader(reader)(context.Background(), "cronjob-system/archive")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(HaveKeyWithValue("accessKey", []byte("AKIA")))
+		Expect(reader.failures).To(BeZero())
+	})
+
+	It("Should read nothing without a reference", func() {
+		data, err := backoff.SecretReader(nil)(context.Background(), "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(BeNil())
+	})
+
+	It("Should fail on a missing Secret", func() {
+		reader := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		_, err := backoff.SecretReader(reader)(context.Background(), "cronjob-system/archive")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,127 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+/*
+The client is wrapped, so every call made during a reconcile or an admission request is a child span of it. The
+reads of the cached client are traced too: they are cheap, but a missing one in a slow trace tells that the cache
+was not synced.
+*/
+
+// WrapClient returns a client which traces the calls of the given client.
+func WrapClient(c client.Client) client.Client {
+	return &tracingClient{Client: c}
+}
+
+type tracingClient struct {
+	client.Client
+}
+
+// Get implements client.Client
+func (c *tracingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) (err error) {
+	ctx, span := c.start(ctx, "Get", obj, key.Namespace, key.Name)
+	defer func() { End(span, err) }()
+	return c.Client.Get(ctx, key, obj)
+}
+
+// List implements client.Client
+func (c *tracingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) (err error) {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	ctx, span := c.start(ctx, "List", list, listOpts.Namespace, "")
+	defer func() { End(span, err) }()
+	return c.Client.List(ctx, list, opts...)
+}
+
+// Create implements client.Client
+func (c *tracingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) (err error) {
+	ctx, span := c.start(ctx, "Create", obj, obj.GetNamespace(), obj.GetName())
+	defer func() { End(span, err) }()
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// Delete implements client.Client
+func (c *tracingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) (err error) {
+	ctx, span := c.start(ctx, "Delete", obj, obj.GetNamespace(), obj.GetName())
+	defer func() { End(span, err) }()
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+// Update implements client.Client
+func (c *tracingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) (err error) {
+	ctx, span := c.start(ctx, "Update", obj, obj.GetNamespace(), obj.GetName())
+	defer func() { End(span, err) }()
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// Patch implements client.Client
+func (c *tracingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) (err error) {
+	ctx, span := c.start(ctx, "Patch", obj, obj.GetNamespace(), obj.GetName())
+	defer func() { End(span, err) }()
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// Status implements client.Client
+func (c *tracingClient) Status() client.StatusWriter {
+	return &tracingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type tracingStatusWriter struct {
+	client.StatusWriter
+	client *tracingClient
+}
+
+// Update implements client.StatusWriter
+func (w *tracingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) (err error) {
+	ctx, span := w.client.start(ctx, "UpdateStatus", obj, obj.GetNamespace(), obj.GetName())
+	defer func() { End(span, err) }()
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+// Patch implements client.StatusWriter
+func (w *tracingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) (err error) {
+	ctx, span := w.client.start(ctx, "PatchStatus", obj, obj.GetNamespace(), obj.GetName())
+	defer func() { End(span, err) }()
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// start starts the span of a call, named like `Get Job`.
+func (c *tracingClient) start(ctx context.Context, verb string, obj runtime.Object, namespace, name string) (context.Context, trace.Span) {
+	kind := ""
+	if gvk, err := apiutil.GVKForObject(obj, c.Scheme()); err == nil {
+		kind = strings.TrimSuffix(gvk.Kind, "List")
+	}
+	attributes := []attribute.KeyValue{attribute.String("k8s.kind", kind)}
+	if namespace != "" {
+		attributes = append(attributes, attribute.String("k8s.namespace.name", namespace))
+	}
+	if name != "" {
+		attributes = append(attributes, attribute.String("k8s.object.name", name))
+	}
+	return Tracer().Start(ctx, verb+" "+kind, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	kbatch "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Tracing client", func() {
+	var recorder *tracetest.SpanRecorder
+
+	BeforeEach(func() {
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	})
+
+	AfterEach(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	})
+
+	It("Should trace the calls as children of the span of the context", func() {
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		c := WrapClient(fake.NewClientBuilder().WithScheme(scheme).Build())
+
+		ctx, parent := Tracer().Start(context.Background(), "Reconcile CronJob")
+		job := &kbatch.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "hello"}}
+		Expect(c.Create(ctx, job)).To(Succeed())
+		Expect(c.List(ctx, &kbatch.JobList{}, client.InNamespace("default"))).To(Succeed())
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "missing"}, &kbatch.Job{})).NotTo(Succeed())
+		parent.End()
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(4))
+		Expect(spans[0].Name()).To(Equal("Create Job"))
+		Expect(spans[1].Name()).To(Equal("List Job"))
+		Expect(spans[2].Name()).To(Equal("Get Job"))
+		Expect(spans[2].Status().Code).To(Equal(codes.Error))
+		for _, span := range spans[:3] {
+			Expect(span.Parent().SpanID()).To(Equal(parent.SpanContext().SpanID()))
+		}
+		Expect(TraceID(ctx)).To(Equal(parent.SpanContext().TraceID().String()))
+	})
+
+	It("Should not export anything without a collector", func() {
+		provider, err := Setup(context.Background(), configv1.TracingConfig{}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(provider).To(BeNil())
+		Expect(TraceID(context.Background())).To(BeEmpty())
+	})
+})
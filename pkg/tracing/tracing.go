@@ -0,0 +1,134 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing exports the traces of the operator with OpenTelemetry. Every reconcile and admission request is a
+// trace, with a child span per call to the API server.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+)
+
+/*
+The spans are started with the global tracer provider of OpenTelemetry. Until Setup installs ours, it is a no-op
+provider, so the instrumented code costs next to nothing when the traces are not exported.
+*/
+
+var log = logf.Log.WithName("tracing")
+
+const (
+	// instrumentationName is the name of the tracer of the operator.
+	instrumentationName = "github.com/bilalcaliskan/kubebuilder-tutorial"
+	serviceName         = "kubebuilder-tutorial"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Tracer returns the tracer of the operator.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Provider exports the spans until the manager stops, then flushes the remaining ones.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+}
+
+var _ manager.Runnable = &Provider{}
+var _ manager.LeaderElectionRunnable = &Provider{}
+
+// Setup installs the global tracer provider exporting the spans to the OTLP collector of the config. It returns nil
+// if no collector is configured.
+func Setup(ctx context.Context, config configv1.TracingConfig, clusterName string) (*Provider, error) {
+	if config.Endpoint == "" {
+		return nil, nil
+	}
+
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the OTLP exporter: %w", err)
+	}
+
+	attributes := []attribute.KeyValue{semconv.ServiceNameKey.String(serviceName)}
+	if clusterName != "" {
+		attributes = append(attributes, semconv.K8SClusterNameKey.String(clusterName))
+	}
+	samplingRatio := 1.0
+	if config.SamplingRatio != nil {
+		samplingRatio = *config.SamplingRatio
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attributes...)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplingRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	log.Info("exporting traces", "endpoint", config.Endpoint, "samplingRatio", samplingRatio)
+	return &Provider{provider: provider}, nil
+}
+
+// Start implements manager.Runnable, it flushes the spans when the manager stops.
+func (p *Provider) Start(ctx context.Context) error {
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return p.provider.Shutdown(shutdownCtx)
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the standbys serve webhooks too.
+func (p *Provider) NeedLeaderElection() bool {
+	return false
+}
+
+// End records the error, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the ID of the trace of the context, or an empty string if the context is not traced.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
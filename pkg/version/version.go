@@ -0,0 +1,80 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version holds the build information of the operator, which is embedded into the binary with -ldflags.
+package version
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+/*
+The Makefile and the Dockerfile set the variables below, e.g.
+
+	go build -ldflags "-X github.com/bilalcaliskan/kubebuilder-tutorial/pkg/version.version=v1.2.0" main.go
+
+The binaries built without the flags, like the ones of `go run`, report a `dev` version.
+*/
+
+var (
+	version   = "dev"
+	gitCommit = "unknown"
+	buildDate = "unknown"
+)
+
+var buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "cronjob_operator_build_info",
+	Help: "Always 1, the labels tell the version of the running operator.",
+}, []string{"version", "git_commit", "build_date", "go_version"})
+
+func init() {
+	metrics.Registry.MustRegister(buildInfo)
+	info := Get()
+	buildInfo.WithLabelValues(info.Version, info.GitCommit, info.BuildDate, info.GoVersion).Set(1)
+}
+
+// Info is the build information of the operator.
+type Info struct {
+	Version   string
+	GitCommit string
+	BuildDate string
+	GoVersion string
+}
+
+// Get returns the build information of the running binary.
+func Get() Info {
+	return Info{
+		Version:   version,
+		GitCommit: gitCommit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// String formats the build information for the --version flag.
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s with %s)", i.Version, i.GitCommit, i.BuildDate, i.GoVersion)
+}
+
+// UserAgent returns the user agent of the operator's requests to the API server, in the format of the Kubernetes
+// components, e.g. `kubebuilder-tutorial/v1.2.0 (linux/amd64) 1a2b3c4`.
+func UserAgent() string {
+	return fmt.Sprintf("kubebuilder-tutorial/%s (%s/%s) %s", version, runtime.GOOS, runtime.GOARCH, gitCommit)
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhookserver serves the admission webhooks with the hardened TLS settings of the config file.
+package webhookserver
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/config"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+/*
+The webhook server of controller-runtime builds its TLS config when it starts, without a way to change it. So the
+webhooks are still registered on a webhook.Server, which injects the decoder and the other dependencies into them, but
+the requests are served by this server, with the TLS settings of the config file. It is added to the manager instead
+of the server of the manager, which is never created.
+*/
+
+var log = logf.Log.WithName("webhook-server")
+
+// The defaults of the webhook server of controller-runtime.
+const (
+	defaultPort     = 9443
+	defaultCertName = "tls.crt"
+	defaultKeyName  = "tls.key"
+)
+
+// Server serves the webhooks registered on the embedded webhook.Server.
+type Server struct {
+	*webhook.Server
+	// TLS holds the TLS settings of the server.
+	TLS configv1.TLSConfig
+}
+
+var _ manager.Runnable = &Server{}
+
+// New returns a server listening on the host and the port of the manager options, with the certificate of the given
+// file names in the certificate directory of the options.
+func New(options manager.Options, certName, keyName string, tlsConfig configv1.TLSConfig) *Server {
+	server := &webhook.Server{
+		Host:     options.Host,
+		Port:     options.Port,
+		CertDir:  options.CertDir,
+		CertName: certName,
+		KeyName:  keyName,
+	}
+	if server.Port == 0 {
+		server.Port = defaultPort
+	}
+	if server.CertDir == "" {
+		server.CertDir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+	}
+	if server.CertName == "" {
+		server.CertName = defaultCertName
+	}
+	if server.KeyName == "" {
+		server.KeyName = defaultKeyName
+	}
+	return &Server{Server: server, TLS: tlsConfig}
+}
+
+// Start implements manager.Runnable, it serves the webhooks until the context is done.
+func (s *Server) Start(ctx context.Context) error {
+	certs := &certificateLoader{
+		certPath: filepath.Join(s.CertDir, s.CertName),
+		keyPath:  filepath.Join(s.CertDir, s.KeyName),
+	}
+	if _, err := certs.GetCertificate(nil); err != nil {
+		return fmt.Errorf("unable to load the webhook certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{GetCertificate: certs.GetCertificate}
+	if err := config.ApplyTLSConfig(tlsConfig, s.TLS); err != nil {
+		return err
+	}
+	listener, err := tls.Listen("tcp", net.JoinHostPort(s.Host, strconv.Itoa(s.Port)), tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	handler := s.WebhookMux
+	if handler == nil {
+		// no webhook was registered
+		handler = http.NewServeMux()
+	}
+	server := &http.Server{Handler: handler, TLSNextProto: config.TLSNextProto(s.TLS)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "unable to shut down the webhook server")
+		}
+	}()
+
+	log.Info("serving webhooks", "host", s.Host, "port", s.Port, "http2", s.TLS.EnableHTTP2)
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica serves webhooks.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// certificateLoader loads the certificate from the files, reloading it when they change, e.g. when cert-manager or
+// the cert rotation renews it.
+type certificateLoader struct {
+	certPath, keyPath string
+
+	lock    sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// GetCertificate can be used as tls.Config.GetCertificate
+func (l *certificateLoader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	info, err := os.Stat(l.certPath)
+	if err != nil {
+		if l.cert != nil {
+			log.Error(err, "unable to reload the webhook certificate")
+			return l.cert, nil
+		}
+		return nil, err
+	}
+
+	if l.cert == nil || !info.ModTime().Equal(l.modTime) {
+		cert, err := tls.LoadX509KeyPair(l.certPath, l.keyPath)
+		if err != nil {
+			if l.cert != nil {
+				// the files may be in the middle of an update, keep serving the previous certificate
+				log.Error(err, "unable to reload the webhook certificate")
+				return l.cert, nil
+			}
+			return nil, err
+		}
+		l.cert, l.modTime = &cert, info.ModTime()
+	}
+	return l.cert, nil
+}
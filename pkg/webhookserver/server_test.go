@@ -0,0 +1,108 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhookserver
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/certrotation"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var _ = Describe("Webhook server", func() {
+	var options manager.Options
+	var cancel context.CancelFunc
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "webhook-server")
+		Expect(err).NotTo(HaveOccurred())
+		cert, key, err := certrotation.GenerateSelfSigned([]string{"localhost"}, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "serving.pem"), cert, 0600)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "serving-key.pem"), key, 0600)).To(Succeed())
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		options = manager.Options{
+			Host:    "127.0.0.1",
+			Port:    listener.Addr().(*net.TCPAddr).Port,
+			CertDir: dir,
+		}
+		Expect(listener.Close()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		cancel()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	start := func(tlsConfig configv1.TLSConfig) string {
+		server := New(options, "serving.pem", "serving-key.pem", tlsConfig)
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		go func() {
+			defer GinkgoRecover()
+			Expect(server.Start(ctx)).To(Succeed())
+		}()
+
+		addr := net.JoinHostPort(options.Host, strconv.Itoa(options.Port))
+		Eventually(func() error {
+			conn, err := net.Dial("tcp", addr)
+			if err == nil {
+				conn.Close()
+			}
+			return err
+		}).Should(Succeed())
+		return addr
+	}
+
+	It("Should refuse TLS 1.1 and HTTP/2 by default", func() {
+		addr := start(configv1.TLSConfig{})
+
+		_, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS11})
+		Expect(err).To(HaveOccurred())
+
+		conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2", "http/1.1"}})
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		Expect(conn.ConnectionState().NegotiatedProtocol).To(Equal("http/1.1"))
+	})
+
+	It("Should apply the TLS settings", func() {
+		addr := start(configv1.TLSConfig{MinVersion: "VersionTLS13", EnableHTTP2: true})
+
+		_, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12})
+		Expect(err).To(HaveOccurred())
+
+		conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"h2", "http/1.1"}})
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		Expect(conn.ConnectionState().NegotiatedProtocol).To(Equal("h2"))
+	})
+})
@@ -0,0 +1,160 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workflow runs the DAG of a Workflow: it validates the dependencies and, from what the Jobs of a run say
+// about its nodes, decides which nodes start next and the phase of the run.
+package workflow
+
+import (
+	"fmt"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+// NodeState is what the Jobs of a node tell about it in a run.
+type NodeState struct {
+	// Attempts is the number of Jobs created for the node.
+	Attempts int32
+	// JobName is the name of the Job of the latest attempt.
+	JobName string
+	// Finished is whether the Job of the latest attempt is finished.
+	Finished bool
+	// Succeeded is whether the Job of the latest attempt succeeded.
+	Succeeded bool
+}
+
+// Validate returns an error if the node names are not unique, a dependency is unknown or the dependencies form a
+// cycle.
+func Validate(nodes []batchv1.WorkflowNode) error {
+	_, err := order(nodes)
+	return err
+}
+
+// order returns the indexes of the nodes in dependency order.
+func order(nodes []batchv1.WorkflowNode) ([]int, error) {
+	indexes := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		if _, ok := indexes[node.Name]; ok {
+			return nil, fmt.Errorf("duplicate node %q", node.Name)
+		}
+		indexes[node.Name] = i
+	}
+
+	// Kahn's algorithm, the nodes keep their order of the spec among the ready ones
+	pending := make([]int, len(nodes))
+	dependents := make([][]int, len(nodes))
+	for i, node := range nodes {
+		for _, dependency := range node.Dependencies {
+			j, ok := indexes[dependency]
+			if !ok {
+				return nil, fmt.Errorf("node %q depends on the unknown node %q", node.Name, dependency)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+	var ready, ordered []int
+	for i := range nodes {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, i)
+		for _, j := range dependents[i] {
+			if pending[j]--; pending[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+	if len(ordered) != len(nodes) {
+		for i := range nodes {
+			if pending[i] > 0 {
+				return nil, fmt.Errorf("node %q is part of a dependency cycle", nodes[i].Name)
+			}
+		}
+	}
+	return ordered, nil
+}
+
+// Step returns the state of a run from the states of its nodes, and the nodes to create a Job for, in dependency
+// order: the ones whose dependencies all succeeded, and the failed ones which have retries left. The nodes must be
+// valid.
+func Step(nodes []batchv1.WorkflowNode, states map[string]NodeState) (batchv1.WorkflowRunPhase,
+	[]batchv1.WorkflowNodeStatus, []string) {
+	ordered, err := order(nodes)
+	if err != nil {
+		return batchv1.WorkflowRunFailed, nil, nil
+	}
+
+	phases := make(map[string]batchv1.NodePhase, len(nodes))
+	statuses := make([]batchv1.WorkflowNodeStatus, len(nodes))
+	var start []string
+	for _, i := range ordered {
+		node := &nodes[i]
+		state := states[node.Name]
+		phase := batchv1.NodePending
+		switch {
+		case state.Attempts > 0 && !state.Finished:
+			phase = batchv1.NodeRunning
+		case state.Attempts > 0 && state.Succeeded:
+			phase = batchv1.NodeSucceeded
+		case state.Attempts > 0:
+			phase = batchv1.NodeFailed
+			if node.RetryPolicy != nil && state.Attempts <= node.RetryPolicy.Limit {
+				phase = batchv1.NodeRunning
+				start = append(start, node.Name)
+			}
+		default:
+			phase = dependenciesPhase(node, phases)
+			if phase == batchv1.NodeRunning {
+				start = append(start, node.Name)
+			}
+		}
+		phases[node.Name] = phase
+		statuses[i] = batchv1.WorkflowNodeStatus{Name: node.Name, Phase: phase, Attempts: state.Attempts,
+			JobName: state.JobName}
+	}
+
+	runPhase := batchv1.WorkflowRunSucceeded
+	for _, status := range statuses {
+		switch status.Phase {
+		case batchv1.NodePending, batchv1.NodeRunning:
+			return batchv1.WorkflowRunRunning, statuses, start
+		case batchv1.NodeFailed, batchv1.NodeSkipped:
+			runPhase = batchv1.WorkflowRunFailed
+		}
+	}
+	return runPhase, statuses, start
+}
+
+// dependenciesPhase returns the phase of a node without Job from the phases of its dependencies: Running if it
+// can start, Skipped if it never will, Pending otherwise.
+func dependenciesPhase(node *batchv1.WorkflowNode, phases map[string]batchv1.NodePhase) batchv1.NodePhase {
+	phase := batchv1.NodeRunning
+	for _, dependency := range node.Dependencies {
+		switch phases[dependency] {
+		case batchv1.NodeFailed, batchv1.NodeSkipped:
+			return batchv1.NodeSkipped
+		case batchv1.NodeSucceeded:
+		default:
+			phase = batchv1.NodePending
+		}
+	}
+	return phase
+}
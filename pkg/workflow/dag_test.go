@@ -0,0 +1,118 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// diamond is a → b, c → d, b being retried once.
+func diamond() []batchv1.WorkflowNode {
+	return []batchv1.WorkflowNode{
+		{Name: "a"},
+		{Name: "b", Dependencies: []string{"a"}, RetryPolicy: &batchv1.NodeRetryPolicy{Limit: 1}},
+		{Name: "c", Dependencies: []string{"a"}},
+		{Name: "d", Dependencies: []string{"b", "c"}},
+	}
+}
+
+func phases(statuses []batchv1.WorkflowNodeStatus) map[string]batchv1.NodePhase {
+	phases := map[string]batchv1.NodePhase{}
+	for _, status := range statuses {
+		phases[status.Name] = status.Phase
+	}
+	return phases
+}
+
+var _ = Describe("Validate", func() {
+	It("Should accept a DAG", func() {
+		Expect(Validate(diamond())).To(Succeed())
+	})
+
+	It("Should reject the duplicates, the unknown dependencies and the cycles", func() {
+		Expect(Validate([]batchv1.WorkflowNode{{Name: "a"}, {Name: "a"}})).To(MatchError(`duplicate node "a"`))
+		Expect(Validate([]batchv1.WorkflowNode{{Name: "a", Dependencies: []string{"b"}}})).To(
+			MatchError(`node "a" depends on the unknown node "b"`))
+		Expect(Validate([]batchv1.WorkflowNode{
+			{Name: "a"},
+			{Name: "b", Dependencies: []string{"a", "c"}},
+			{Name: "c", Dependencies: []string{"b"}},
+		})).To(MatchError(`node "b" is part of a dependency cycle`))
+	})
+})
+
+var _ = Describe("Step", func() {
+	It("Should start the roots of a new run", func() {
+		phase, statuses, start := Step(diamond(), nil)
+		Expect(phase).To(Equal(batchv1.WorkflowRunRunning))
+		Expect(start).To(Equal([]string{"a"}))
+		Expect(phases(statuses)).To(Equal(map[string]batchv1.NodePhase{"a": batchv1.NodeRunning,
+			"b": batchv1.NodePending, "c": batchv1.NodePending, "d": batchv1.NodePending}))
+	})
+
+	It("Should start the nodes whose dependencies succeeded", func() {
+		_, _, start := Step(diamond(), map[string]NodeState{"a": {Attempts: 1, Finished: true, Succeeded: true}})
+		Expect(start).To(Equal([]string{"b", "c"}))
+
+		_, _, start = Step(diamond(), map[string]NodeState{
+			"a": {Attempts: 1, Finished: true, Succeeded: true},
+			"b": {Attempts: 1, Finished: true, Succeeded: true},
+			"c": {Attempts: 1},
+		})
+		Expect(start).To(BeEmpty())
+	})
+
+	It("Should retry a failed node within its limit", func() {
+		states := map[string]NodeState{
+			"a": {Attempts: 1, Finished: true, Succeeded: true},
+			"b": {Attempts: 1, Finished: true},
+			"c": {Attempts: 1, Finished: true, Succeeded: true},
+		}
+		phase, _, start := Step(diamond(), states)
+		Expect(phase).To(Equal(batchv1.WorkflowRunRunning))
+		Expect(start).To(Equal([]string{"b"}))
+
+		states["b"] = NodeState{Attempts: 2, Finished: true}
+		phase, statuses, start := Step(diamond(), states)
+		Expect(phase).To(Equal(batchv1.WorkflowRunFailed))
+		Expect(start).To(BeEmpty())
+		Expect(phases(statuses)).To(Equal(map[string]batchv1.NodePhase{"a": batchv1.NodeSucceeded,
+			"b": batchv1.NodeFailed, "c": batchv1.NodeSucceeded, "d": batchv1.NodeSkipped}))
+	})
+
+	It("Should let the independent branches finish after a failure", func() {
+		phase, statuses, start := Step(diamond()[:3], map[string]NodeState{
+			"a": {Attempts: 1, Finished: true, Succeeded: true},
+			"b": {Attempts: 2, Finished: true},
+			"c": {Attempts: 1},
+		})
+		Expect(phase).To(Equal(batchv1.WorkflowRunRunning))
+		Expect(start).To(BeEmpty())
+		Expect(phases(statuses)["c"]).To(Equal(batchv1.NodeRunning))
+	})
+
+	It("Should succeed once all the nodes succeeded", func() {
+		states := map[string]NodeState{}
+		for _, name := range []string{"a", "b", "c", "d"} {
+			states[name] = NodeState{Attempts: 1, Finished: true, Succeeded: true}
+		}
+		phase, _, _ := Step(diamond(), states)
+		Expect(phase).To(Equal(batchv1.WorkflowRunSucceeded))
+	})
+})
@@ -0,0 +1,88 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+// policyPatterns returns the patterns of the ValidatingAdmissionPolicy of config/admission-policy, by validated field.
+func policyPatterns() map[string]*regexp.Regexp {
+	raw, err := ioutil.ReadFile("../config/admission-policy/validatingadmissionpolicy.yaml")
+	Expect(err).NotTo(HaveOccurred())
+	var policy struct {
+		Spec struct {
+			Validations []struct {
+				Expression string `json:"expression"`
+			} `json:"validations"`
+		} `json:"spec"`
+	}
+	Expect(yaml.Unmarshal(raw, &policy)).To(Succeed())
+
+	// CEL evaluates matches with RE2, like the regexp package
+	matches := regexp.MustCompile(`^object\.([a-zA-Z.]+)\.matches\('([^']*)'\)$`)
+	patterns := map[string]*regexp.Regexp{}
+	for _, validation := range policy.Spec.Validations {
+		if m := matches.FindStringSubmatch(validation.Expression); m != nil {
+			patterns[m[1]] = regexp.MustCompile(m[2])
+		}
+	}
+	return patterns
+}
+
+var _ = Describe("ValidatingAdmissionPolicy", func() {
+	patterns := policyPatterns()
+
+	It("Should accept the same names as the webhook", func() {
+		Expect(patterns).To(HaveKey("metadata.name"))
+		for _, name := range []string{
+			"nightly", "nightly-report.v2", strings.Repeat("a", 52), strings.Repeat("a", 53),
+			strings.Repeat("é", 20), "-nightly", "nightly-", "Nightly",
+		} {
+			cronJob := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			Expect(patterns["metadata.name"].MatchString(name)).To(Equal(len(validateCronJobName(cronJob)) == 0),
+				"name %q", name)
+		}
+	})
+
+	It("Should accept the same schedules as the webhook", func() {
+		Expect(patterns).To(HaveKey("spec.schedule"))
+		for _, schedule := range []string{
+			"0 2 * * *", "*/15 * * * MON-FRI", "0 0 1,15 * *", "30 6 * JAN-MAR sun", "0 0 * * 0-6/2", "5/10 * * * *",
+			"@daily", "@every 1h30m", "@every 90s",
+			"60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 7", "0 0 * * FRI-MON", "30-10 * * * *",
+			"*/0 * * * *", "0 2 * *", "0 2 * * * *", "@Daily", "@every 1x", "@every", "", "daily",
+			"*/100 * * * *", "@every 1.5h", "*-5 * * * *",
+		} {
+			cronJob := &batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "nightly"},
+				Spec:       batchv1.CronJobSpec{Schedule: schedule},
+			}
+			Expect(patterns["spec.schedule"].MatchString(schedule)).To(Equal(len(validateCronJobSpec(cronJob)) == 0),
+				"schedule %q", schedule)
+		}
+	})
+})
@@ -0,0 +1,91 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+/*
+During incidents we sometimes need to push a change which would be rejected by one of the guardrails. Setting the
+bypass-validation annotation skips the rules which are not safety critical, but only for the users which are allowed
+to use the custom `bypass` verb on cronjobs, e.g. with the following rule in a (Cluster)Role:
+
+	- apiGroups: ["batch.example.com"]
+	  resources: ["cronjobs"]
+	  verbs: ["bypass"]
+
+We ask the API server whether the requester holds that permission with a SubjectAccessReview.
+*/
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+const (
+	// bypassValidationAnnotation requests skipping the validations which are not safety critical.
+	bypassValidationAnnotation = "batch.example.com/bypass-validation"
+	// bypassVerb is the custom verb on cronjobs the requester needs for bypassing the validations.
+	bypassVerb = "bypass"
+	// bypassAuditAnnotation lists the skipped rules in the audit event of the request.
+	bypassAuditAnnotation = "bypassed-rules"
+)
+
+// checkBypass returns whether the non safety critical validations should be skipped for the request. The returned
+// warnings tell the requester why a requested bypass is not honored.
+func (v *cronJobValidator) checkBypass(ctx context.Context, req admission.Request, cronJob *batchv1.CronJob) (bool, []string, error) {
+	if cronJob.Annotations[bypassValidationAnnotation] != "true" {
+		return false, nil, nil
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(req.UserInfo.Extra))
+	for k, v := range req.UserInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			Groups: req.UserInfo.Groups,
+			UID:    req.UserInfo.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: req.Namespace,
+				Verb:      bypassVerb,
+				Group:     batchv1.GroupVersion.Group,
+				Resource:  "cronjobs",
+				Name:      req.Name,
+			},
+		},
+	}
+	if err := v.Client.Create(ctx, sar); err != nil {
+		return false, nil, fmt.Errorf("unable to review access of %q for bypassing validation: %w", req.UserInfo.Username, err)
+	}
+
+	if !sar.Status.Allowed {
+		cronjoblog.Info("bypass-validation denied", "name", cronJob.Name, "user", req.UserInfo.Username)
+		return false, []string{fmt.Sprintf("%s annotation ignored: user %q is not allowed to %s cronjobs",
+			bypassValidationAnnotation, req.UserInfo.Username, bypassVerb)}, nil
+	}
+
+	cronjoblog.Info("bypass-validation granted", "name", cronJob.Name, "user", req.UserInfo.Username)
+	return true, []string{fmt.Sprintf("%s annotation set: only the safety critical validations were run",
+		bypassValidationAnnotation)}, nil
+}
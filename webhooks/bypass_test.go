@@ -0,0 +1,175 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+// accessReviewer answers the SubjectAccessReviews like the API server would, and keeps them.
+type accessReviewer struct {
+	client.Client
+	allowed bool
+	reviews []*authorizationv1.SubjectAccessReview
+}
+
+func (c *accessReviewer) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	sar, ok := obj.(*authorizationv1.SubjectAccessReview)
+	if !ok {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+	sar.Status.Allowed = c.allowed
+	c.reviews = append(c.reviews, sar.DeepCopy())
+	return nil
+}
+
+var _ = Describe("CronJob bypass-validation", func() {
+	var (
+		reviewer  *accessReviewer
+		validator *cronJobValidator
+		cronJob   *batchv1.CronJob
+	)
+
+	// handle sends the creation of the CronJob by another user than the operator to the validating webhook.
+	handle := func() admission.Response {
+		raw, err := json.Marshal(cronJob)
+		Expect(err).NotTo(HaveOccurred())
+		return validator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: cronJob.Namespace,
+			Name:      cronJob.Name,
+			Object:    runtime.RawExtension{Raw: raw},
+			UserInfo: authenticationv1.UserInfo{
+				Username: "oncall@example.com",
+				UID:      "5c0c8b4e",
+				Groups:   []string{"sre", "system:authenticated"},
+				Extra:    map[string]authenticationv1.ExtraValue{"scopes": {"incident"}},
+			},
+		}})
+	}
+
+	BeforeEach(func() {
+		reviewer = &accessReviewer{Client: newFakeClient()}
+		validator = &cronJobValidator{
+			Client:              reviewer,
+			decoder:             newDecoder(),
+			activationHorizon:   time.Hour,
+			minStartingDeadline: time.Minute,
+		}
+		// the next run is in three hours, past the activation horizon: only the never-runs rule is violated
+		later := time.Now().Add(3 * time.Hour)
+		cronJob = &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "nightly",
+				Annotations: map[string]string{bypassValidationAnnotation: "true"},
+			},
+			Spec: batchv1.CronJobSpec{Schedule: fmt.Sprintf("%d %d * * *", later.Minute(), later.Hour())},
+		}
+		cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{Name: "report", Image: "report"}}
+	})
+
+	It("Should skip only the rules which are not safety critical when the requester may bypass them", func() {
+		reviewer.allowed = true
+
+		resp := handle()
+		Expect(resp.Allowed).To(BeTrue())
+		var skipped []string
+		for _, rule := range validator.rules() {
+			if !rule.safetyCritical {
+				skipped = append(skipped, rule.name)
+			}
+		}
+		Expect(resp.AuditAnnotations).To(HaveKeyWithValue(bypassAuditAnnotation, strings.Join(skipped, ",")))
+		Expect(resp.AuditAnnotations[bypassAuditAnnotation]).To(ContainSubstring("never-runs"))
+		Expect(resp.Warnings).To(ContainElement(ContainSubstring("only the safety critical validations were run")))
+	})
+
+	It("Should run every rule and reject when the requester may not bypass them", func() {
+		reviewer.allowed = false
+
+		resp := handle()
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring("the CronJob would never run"))
+		Expect(resp.AuditAnnotations).NotTo(HaveKey(bypassAuditAnnotation))
+		Expect(resp.Warnings).To(ContainElement(
+			`batch.example.com/bypass-validation annotation ignored: user "oncall@example.com" is not allowed to ` +
+				"bypass cronjobs"))
+	})
+
+	It("Should run every rule without reviewing the access when the annotation is not set", func() {
+		reviewer.allowed = true
+		cronJob.Annotations = nil
+
+		resp := handle()
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring("the CronJob would never run"))
+		Expect(resp.AuditAnnotations).NotTo(HaveKey(bypassAuditAnnotation))
+		Expect(reviewer.reviews).To(BeEmpty())
+	})
+
+	It("Should still reject the violations of the safety critical rules when the requester may bypass", func() {
+		reviewer.allowed = true
+
+		cronJob.Spec.Schedule = "not a schedule"
+		resp := handle()
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring("spec.schedule"))
+
+		cronJob.Spec.Schedule = "0 2 * * *"
+		cronJob.Name = strings.Repeat("a", 53)
+		resp = handle()
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring("metadata.name"))
+	})
+
+	It("Should review the access of the requester rather than the one of the operator", func() {
+		reviewer.allowed = true
+
+		handle()
+		Expect(reviewer.reviews).To(HaveLen(1))
+		spec := reviewer.reviews[0].Spec
+		Expect(spec.User).To(Equal("oncall@example.com"))
+		Expect(spec.UID).To(Equal("5c0c8b4e"))
+		Expect(spec.Groups).To(Equal([]string{"sre", "system:authenticated"}))
+		Expect(spec.Extra).To(Equal(map[string]authorizationv1.ExtraValue{"scopes": {"incident"}}))
+		Expect(spec.ResourceAttributes).To(Equal(&authorizationv1.ResourceAttributes{
+			Namespace: "default",
+			Verb:      bypassVerb,
+			Group:     batchv1.GroupVersion.Group,
+			Resource:  "cronjobs",
+			Name:      "nightly",
+		}))
+	})
+})
@@ -0,0 +1,91 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+/*
+Our CronJob shares its kind and its short names with the native CronJob of the `batch` group, so `kubectl get cronjob`
+silently picks one of them. A CronJob with the same name as a native one in the same namespace is confusing at best,
+so new CronJobs are checked for such collisions. Depending on the config file, a collision is a warning (the
+default), a rejection, or ignored. Only the metadata of the native CronJob is fetched, directly from the API server.
+*/
+
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get
+
+// nativeCronJobVersions are the versions of the native CronJob, in order of preference.
+var nativeCronJobVersions = []string{"v1", "v1beta1"}
+
+// validateNativeCronJobCollision validates that no native CronJob has the same name as a newly created CronJob.
+func (v *cronJobValidator) validateNativeCronJobCollision(ctx context.Context, req admission.Request,
+	r *batchv1.CronJob) (field.ErrorList, []string) {
+	if v.nativeCronJobCollision == configv1.CollisionIgnore || v.APIReader == nil ||
+		req.Operation != admissionv1.Create {
+		return nil, nil
+	}
+
+	found, err := v.nativeCronJobExists(ctx, types.NamespacedName{Namespace: req.Namespace, Name: r.Name})
+	if err != nil {
+		// an unavailable lookup should not block the CronJobs
+		cronjoblog.Error(err, "unable to look up the native CronJob", "name", r.Name, "namespace", req.Namespace)
+		return nil, nil
+	}
+	if !found {
+		return nil, nil
+	}
+
+	msg := fmt.Sprintf("a native batch/v1 CronJob named %q already exists in namespace %q", r.Name, req.Namespace)
+	if v.nativeCronJobCollision == configv1.CollisionReject {
+		return field.ErrorList{field.Duplicate(field.NewPath("metadata", "name"), r.Name)}, []string{msg}
+	}
+	return nil, []string{msg}
+}
+
+// nativeCronJobExists returns whether a native CronJob with the given name exists, in any of the served versions.
+func (v *cronJobValidator) nativeCronJobExists(ctx context.Context, key types.NamespacedName) (bool, error) {
+	for _, version := range nativeCronJobVersions {
+		native := &metav1.PartialObjectMetadata{}
+		native.SetGroupVersionKind(schema.GroupVersionKind{Group: "batch", Version: version, Kind: "CronJob"})
+		err := v.APIReader.Get(ctx, key, native)
+		switch {
+		case err == nil:
+			return true, nil
+		case apierrors.IsNotFound(err):
+			return false, nil
+		case meta.IsNoMatchError(err):
+			// the version is not served by this cluster, try the next one
+			continue
+		default:
+			return false, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	kbatchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+)
+
+// servedVersions fails to read the versions of the native CronJob the API server does not serve.
+type servedVersions struct {
+	client.Reader
+	versions []string
+}
+
+func (r *servedVersions) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	for _, version := range r.versions {
+		if gvk.Version == version {
+			return r.Reader.Get(ctx, key, obj)
+		}
+	}
+	return &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+}
+
+var _ = Describe("Native CronJob collisions", func() {
+	var (
+		validator *cronJobValidator
+		request   admission.Request
+		cronJob   *batchv1.CronJob
+	)
+
+	BeforeEach(func() {
+		// client-go only knows the native CronJob of batch/v1beta1, like the API servers before Kubernetes 1.21
+		native := &kbatchv1beta1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly"}}
+		validator = &cronJobValidator{
+			APIReader:              &servedVersions{Reader: newFakeClient(native), versions: []string{"v1beta1"}},
+			nativeCronJobCollision: configv1.CollisionReject,
+		}
+		cronJob = &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly"}}
+		request = admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "default",
+		}}
+	})
+
+	It("Should reject a CronJob named like a native CronJob of its namespace", func() {
+		errs, warnings := validator.validateNativeCronJobCollision(context.Background(), request, cronJob)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Type).To(Equal(field.ErrorTypeDuplicate))
+		Expect(errs[0].Field).To(Equal("metadata.name"))
+		Expect(warnings).To(ConsistOf(`a native batch/v1 CronJob named "nightly" already exists in namespace "default"`))
+	})
+
+	It("Should only warn about the collision by default", func() {
+		validator.nativeCronJobCollision = configv1.CollisionWarn
+		errs, warnings := validator.validateNativeCronJobCollision(context.Background(), request, cronJob)
+		Expect(errs).To(BeEmpty())
+		Expect(warnings).To(HaveLen(1))
+	})
+
+	It("Should admit the CronJobs without a native namesake", func() {
+		cronJob.Name = "weekly"
+		errs, warnings := validator.validateNativeCronJobCollision(context.Background(), request, cronJob)
+		Expect(errs).To(BeEmpty())
+		Expect(warnings).To(BeEmpty())
+
+		cronJob.Name, request.Namespace = "nightly", "other"
+		errs, _ = validator.validateNativeCronJobCollision(context.Background(), request, cronJob)
+		Expect(errs).To(BeEmpty())
+
+		// the existing CronJobs are not checked again
+		request.Namespace, request.Operation = "default", admissionv1.Update
+		errs, _ = validator.validateNativeCronJobCollision(context.Background(), request, cronJob)
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("Should admit the CronJobs when the API server serves no native CronJob", func() {
+		validator.APIReader.(*servedVersions).versions = nil
+		errs, warnings := validator.validateNativeCronJobCollision(context.Background(), request, cronJob)
+		Expect(errs).To(BeEmpty())
+		Expect(warnings).To(BeEmpty())
+	})
+})
@@ -0,0 +1,738 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/admissionpolicy"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/maintenance"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/policy"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/slo"
+	"github.com/robfig/cron"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	validationutils "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// We’ll setup a logger for the webhooks.
+var cronjoblog = logf.Log.WithName("cronjob-resource")
+
+/*
+The webhooks used to be implemented with the webhook.Defaulter and webhook.Validator interfaces on the CronJob type
+itself. Those interfaces only hand us the object, but some of our checks need to know who sent the request and have to
+talk to the API server. So, like the [webhooks for core types](https://book.kubebuilder.io/reference/webhook-for-core-types.html),
+we implement admission.Handler ourselves and register the handlers on the webhook server of the manager.
+*/
+
+const (
+	mutatingWebhookPath   = "/mutate-batch-example-com-v1-cronjob"
+	validatingWebhookPath = "/validate-batch-example-com-v1-cronjob"
+
+	// MutatingWebhookName and ValidatingWebhookName are the names of the webhooks in the webhook configurations, as
+	// set by the markers below.
+	MutatingWebhookName   = "mcronjob.kb.io"
+	ValidatingWebhookName = "vcronjob.kb.io"
+)
+
+// CronJobWebhook serves the defaulting and validating admission webhooks of the CronJob kind.
+type CronJobWebhook struct {
+	// Client is used by the validating webhook for the checks which need to look into the cluster.
+	Client client.Client
+	// APIReader is used by the validating webhook for the lookups which should not be served from the cache.
+	APIReader client.Reader
+	// WatchNamespaces are the namespaces the cache of Client is restricted to, all of them if empty.
+	WatchNamespaces []string
+	// Config holds the admission settings of the config file.
+	Config configv1.AdmissionConfig
+	// Server is the webhook server the webhooks are registered on, defaults to the webhook server of the manager.
+	Server *webhook.Server
+	// ErrorReporter reports the panics of the webhooks, nothing is reported if nil.
+	ErrorReporter *errorreporting.ErrorReporter
+
+	defaulter reloadableHandler
+	validator reloadableHandler
+}
+
+// defaultActivationHorizon is used when the config file does not set an activation horizon.
+const defaultActivationHorizon = 4 * 365 * 24 * time.Hour
+
+// defaultMinStartingDeadline is used when the config file does not set a minimum starting deadline.
+const defaultMinStartingDeadline = 10 * time.Second
+
+// SetupWebhookWithManager sets up the webhook with the manager which also manages controllers
+func (w *CronJobWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	if err := w.UpdateConfig(w.Config); err != nil {
+		return err
+	}
+
+	server := w.Server
+	if server == nil {
+		server = mgr.GetWebhookServer()
+	}
+	if MutatingWebhookEnabled(w.Config) {
+		server.Register(mutatingWebhookPath, &webhook.Admission{
+			Handler: w.instrument("defaulting", &w.defaulter),
+		})
+	}
+	if ValidatingWebhookEnabled(w.Config) {
+		server.Register(validatingWebhookPath, &webhook.Admission{
+			Handler: w.instrument("validating", &w.validator),
+		})
+	}
+	return w.registerExtraHandlers(server)
+}
+
+// MutatingWebhookEnabled returns whether the defaulting webhook is served with the given settings.
+func MutatingWebhookEnabled(config configv1.AdmissionConfig) bool {
+	return config.MutatingWebhook == nil || *config.MutatingWebhook
+}
+
+// ValidatingWebhookEnabled returns whether the validating webhook is served with the given settings.
+func ValidatingWebhookEnabled(config configv1.AdmissionConfig) bool {
+	return config.ValidatingWebhook == nil || *config.ValidatingWebhook
+}
+
+/*
+The admission settings can change while the manager runs, when the config file is reloaded. Instead of reading the
+settings under a lock on every request, UpdateConfig builds new handlers from the settings and swaps them in. The
+extra handlers are not reloaded, since they are bound to paths of the webhook server.
+*/
+
+// UpdateConfig applies the admission settings to the webhooks.
+func (w *CronJobWebhook) UpdateConfig(config configv1.AdmissionConfig) error {
+	activationHorizon := defaultActivationHorizon
+	if config.ActivationHorizon != nil {
+		activationHorizon = config.ActivationHorizon.Duration
+	}
+	minStartingDeadline := defaultMinStartingDeadline
+	if config.MinStartingDeadline != nil {
+		minStartingDeadline = config.MinStartingDeadline.Duration
+	}
+	if config.DefaultTimeZone != "" {
+		if _, err := time.LoadLocation(config.DefaultTimeZone); err != nil {
+			return fmt.Errorf("invalid default time zone %q: %w", config.DefaultTimeZone, err)
+		}
+	}
+
+	if err := w.defaulter.set(&cronJobDefaulter{
+		client:          w.Client,
+		defaults:        config.Defaults,
+		defaultTimeZone: config.DefaultTimeZone,
+	}); err != nil {
+		return err
+	}
+	return w.validator.set(&cronJobValidator{
+		Client:                 w.Client,
+		APIReader:              w.APIReader,
+		watchNamespaces:        w.WatchNamespaces,
+		activationHorizon:      activationHorizon,
+		minStartingDeadline:    minStartingDeadline,
+		imageRegistries:        config.ImageRegistries,
+		nativeCronJobCollision: config.NativeCronJobCollision,
+		maxCronJobs:            config.MaxCronJobsPerNamespace,
+	})
+}
+
+// reloadableHandler serves the requests with the latest handler it was given.
+type reloadableHandler struct {
+	lock    sync.Mutex
+	decoder *admission.Decoder
+	current atomic.Value
+}
+
+var _ admission.Handler = &reloadableHandler{}
+var _ admission.DecoderInjector = &reloadableHandler{}
+
+// Handle implements admission.Handler
+func (h *reloadableHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	return h.current.Load().(admission.Handler).Handle(ctx, req)
+}
+
+// InjectDecoder implements admission.DecoderInjector, the decoder is passed on to the current and the next handlers
+func (h *reloadableHandler) InjectDecoder(decoder *admission.Decoder) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.decoder = decoder
+	if current, ok := h.current.Load().(admission.DecoderInjector); ok {
+		return current.InjectDecoder(decoder)
+	}
+	return nil
+}
+
+// set swaps in the handler, which has to be an admission.DecoderInjector.
+func (h *reloadableHandler) set(handler admission.Handler) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.decoder != nil {
+		if err := handler.(admission.DecoderInjector).InjectDecoder(h.decoder); err != nil {
+			return err
+		}
+	}
+	h.current.Store(handler)
+	return nil
+}
+
+/*
+Notice that we use kubebuilder markers to generate webhook manifests. This marker is responsible for generating a
+mutating webhook manifest.
+
+The meaning of each marker can be found on https://book.kubebuilder.io/reference/markers.html.
+*/
+//+kubebuilder:webhook:path=/mutate-batch-example-com-v1-cronjob,mutating=true,failurePolicy=fail,sideEffects=None,groups=batch.example.com,resources=cronjobs,verbs=create;update,versions=v1,name=mcronjob.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// cronJobDefaulter sets the defaults of the CronJobs. It answers with a JSON patch of the changes it made.
+type cronJobDefaulter struct {
+	decoder *admission.Decoder
+
+	// defaults replace the builtin defaults of the fields they set.
+	defaults configv1.CronJobDefaults
+	// defaultTimeZone is set on the new CronJobs without a time zone.
+	defaultTimeZone string
+	// client reads the JobTemplates referenced by the CronJobs.
+	client client.Reader
+}
+
+var _ admission.Handler = &cronJobDefaulter{}
+var _ admission.DecoderInjector = &cronJobDefaulter{}
+
+// Handle implements admission.Handler so the defaulting webhook can be served
+func (d *cronJobDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	cronJob := &batchv1.CronJob{}
+	if err := d.decoder.Decode(req, cronJob); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	cronjoblog.Info("default", "name", cronJob.Name)
+	if err := d.resolveJobTemplate(ctx, cronJob); err != nil {
+		return responseFromError(apierrors.NewInvalid(schema.GroupKind{Group: "batch.example.com", Kind: "CronJob"},
+			cronJob.Name, field.ErrorList{err}))
+	}
+	decisions := defaultCronJob(cronJob, d.defaults)
+	/*
+		The time zone is only defaulted on creation. The CronJobs created before it was configured keep running in the
+		time zone of the controller, instead of silently moving to another one on their next update.
+	*/
+	if req.Operation == admissionv1.Create && cronJob.Spec.TimeZone == nil && d.defaultTimeZone != "" &&
+		featuregates.Enabled(featuregates.CronJobTimeZone) {
+		timeZone := d.defaultTimeZone
+		cronJob.Spec.TimeZone = &timeZone
+		decisions = append(decisions, configDefault("spec.timeZone", d.defaultTimeZone))
+	}
+	recordDefaults(cronJob, decisions)
+
+	marshaled, err := json.Marshal(cronJob)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	resp := admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+	if len(decisions) > 0 {
+		resp.AuditAnnotations = map[string]string{defaultedFieldsAuditAnnotation: formatDecisions(decisions)}
+	}
+	return resp
+}
+
+// InjectDecoder implements admission.DecoderInjector, the decoder is injected when the handler is registered
+func (d *cronJobDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// defaultCronJob mutates the given CronJob, setting the defaults of the unset fields. The defaults of the config
+// file take precedence over the builtin ones. It returns the fields it set.
+func defaultCronJob(r *batchv1.CronJob, defaults configv1.CronJobDefaults) []defaultingDecision {
+	var decisions []defaultingDecision
+	if r.Spec.ConcurrencyPolicy == "" {
+		if defaults.ConcurrencyPolicy != "" {
+			r.Spec.ConcurrencyPolicy = batchv1.ConcurrencyPolicy(defaults.ConcurrencyPolicy)
+			decisions = append(decisions, configDefault("spec.concurrencyPolicy", r.Spec.ConcurrencyPolicy))
+		} else {
+			r.Spec.ConcurrencyPolicy = batchv1.AllowConcurrent
+			decisions = append(decisions, builtinDefault("spec.concurrencyPolicy", r.Spec.ConcurrencyPolicy))
+		}
+	}
+
+	if r.Spec.Suspend == nil {
+		r.Spec.Suspend = new(bool)
+		decisions = append(decisions, builtinDefault("spec.suspend", *r.Spec.Suspend))
+	}
+
+	if r.Spec.SuccessfulJobsHistoryLimit == nil {
+		r.Spec.SuccessfulJobsHistoryLimit = new(int32)
+		if defaults.SuccessfulJobsHistoryLimit != nil {
+			*r.Spec.SuccessfulJobsHistoryLimit = *defaults.SuccessfulJobsHistoryLimit
+			decisions = append(decisions, configDefault("spec.successfulJobsHistoryLimit", *r.Spec.SuccessfulJobsHistoryLimit))
+		} else {
+			*r.Spec.SuccessfulJobsHistoryLimit = 3
+			decisions = append(decisions, builtinDefault("spec.successfulJobsHistoryLimit", *r.Spec.SuccessfulJobsHistoryLimit))
+		}
+	}
+
+	if r.Spec.FailedJobsHistoryLimit == nil {
+		r.Spec.FailedJobsHistoryLimit = new(int32)
+		if defaults.FailedJobsHistoryLimit != nil {
+			*r.Spec.FailedJobsHistoryLimit = *defaults.FailedJobsHistoryLimit
+			decisions = append(decisions, configDefault("spec.failedJobsHistoryLimit", *r.Spec.FailedJobsHistoryLimit))
+		} else {
+			*r.Spec.FailedJobsHistoryLimit = 1
+			decisions = append(decisions, builtinDefault("spec.failedJobsHistoryLimit", *r.Spec.FailedJobsHistoryLimit))
+		}
+	}
+	return decisions
+}
+
+// TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
+
+// This marker is responsible for generating a validating webhook manifest.
+//+kubebuilder:webhook:path=/validate-batch-example-com-v1-cronjob,mutating=false,failurePolicy=fail,sideEffects=None,groups=batch.example.com,resources=cronjobs,verbs=create;update,versions=v1,name=vcronjob.kb.io,admissionReviewVersions={v1,v1beta1}
+
+/*
+To validate our CRD beyond what’s possible with declarative validation. Generally, declarative validation should be
+sufficient, but sometimes more advanced use cases call for complex validation.
+
+For instance, we’ll see below that we use this to validate a well-formed cron schedule without making up a long
+regular expression.
+
+Every check is described as a validationRule. Rules which are not safety critical, meaning that violating them can not
+break the controller or the Jobs it creates, can be skipped during incidents with the bypass-validation annotation.
+*/
+
+// cronJobValidator validates the CronJobs on creation and update.
+type cronJobValidator struct {
+	Client client.Client
+	// APIReader reads from the API server directly, for the objects which are not worth caching.
+	APIReader client.Reader
+	decoder   *admission.Decoder
+	// watchNamespaces are the namespaces the cache of Client is restricted to, all of them if empty.
+	watchNamespaces []string
+
+	// activationHorizon is how far ahead a CronJob must have at least one activation.
+	activationHorizon time.Duration
+	// minStartingDeadline is the shortest starting deadline accepted.
+	minStartingDeadline time.Duration
+	// imageRegistries restricts the registries the images come from.
+	imageRegistries configv1.ImageRegistriesConfig
+	// nativeCronJobCollision is what to do with the CronJobs named like a native CronJob.
+	nativeCronJobCollision configv1.CollisionPolicy
+	// maxCronJobs is the maximum number of CronJobs per namespace, zero means no limit.
+	maxCronJobs int
+}
+
+var _ admission.Handler = &cronJobValidator{}
+var _ admission.DecoderInjector = &cronJobValidator{}
+
+// validationRule is a single check of the validating webhook.
+type validationRule struct {
+	// name identifies the rule in the audit annotations and the metrics.
+	name string
+	// safetyCritical rules are never skipped, even if the bypass-validation annotation is set.
+	safetyCritical bool
+	// validate returns the violations of the rule, and the warnings for the requester.
+	validate validateFunc
+}
+
+type validateFunc func(ctx context.Context, req admission.Request, cronJob *batchv1.CronJob) (field.ErrorList, []string)
+
+// objectRule adapts the checks which only need the object itself to validateFunc.
+func objectRule(f func(cronJob *batchv1.CronJob) field.ErrorList) validateFunc {
+	return func(_ context.Context, _ admission.Request, cronJob *batchv1.CronJob) (field.ErrorList, []string) {
+		return f(cronJob), nil
+	}
+}
+
+// rules returns the rules run against every created or updated CronJob, in order.
+func (v *cronJobValidator) rules() []validationRule {
+	return []validationRule{
+		{name: "namespace-quota", safetyCritical: true, validate: v.validateNamespaceQuota},
+		{name: "cronjob-quota", safetyCritical: true, validate: v.validateCronJobQuotas},
+		{name: "name-too-long", safetyCritical: true, validate: objectRule(validateCronJobName)},
+		{name: "feature-gate", safetyCritical: true, validate: v.validateFeatureGates},
+		{name: "bad-schedule", safetyCritical: true, validate: objectRule(validateCronJobSpec)},
+		{name: "bad-notifications", safetyCritical: true, validate: objectRule(validateNotifications)},
+		{name: "bad-runner", safetyCritical: true, validate: objectRule(validateRunner)},
+		{name: "bad-slo", safetyCritical: true, validate: objectRule(validateSLO)},
+		{name: "never-runs", validate: v.validateActivationHorizon},
+		{name: "starting-deadline", safetyCritical: true, validate: v.validateStartingDeadline},
+		{name: "image-registry", safetyCritical: true, validate: objectRule(v.validateImageRegistries)},
+		{name: "native-cronjob-collision", validate: v.validateNativeCronJobCollision},
+		{name: "cronjob-policy", validate: v.validateCronJobPolicies},
+	}
+}
+
+// Handle implements admission.Handler so the validating webhook can be served
+func (v *cronJobValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	cronJob := &batchv1.CronJob{}
+	if err := v.decoder.Decode(req, cronJob); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	cronjoblog.Info("validate "+strings.ToLower(string(req.Operation)), "name", cronJob.Name)
+
+	bypass, warnings, err := v.checkBypass(ctx, req, cronJob)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	metrics.RecordWarnings(metrics.WarningBypass, len(warnings))
+
+	var allErrs field.ErrorList
+	var skipped []string
+	for _, rule := range v.rules() {
+		if bypass && !rule.safetyCritical {
+			skipped = append(skipped, rule.name)
+			continue
+		}
+		errs, ruleWarnings := rule.validate(ctx, req, cronJob)
+		if len(errs) != 0 {
+			metrics.RecordRejection(rule.name)
+			allErrs = append(allErrs, errs...)
+		}
+		metrics.RecordWarnings(rule.name, len(ruleWarnings))
+		warnings = append(warnings, ruleWarnings...)
+	}
+	deprecations := deprecationWarnings(cronJob)
+	metrics.RecordWarnings(metrics.WarningDeprecation, len(deprecations))
+	warnings = append(warnings, deprecations...)
+
+	if len(allErrs) != 0 {
+		err := apierrors.NewInvalid(schema.GroupKind{Group: "batch.example.com", Kind: "CronJob"}, cronJob.Name, allErrs)
+		return responseFromError(err).WithWarnings(warnings...)
+	}
+
+	resp := admission.Allowed("").WithWarnings(warnings...)
+	if bypass {
+		resp.AuditAnnotations = map[string]string{bypassAuditAnnotation: strings.Join(skipped, ",")}
+	}
+	return resp
+}
+
+// InjectDecoder implements admission.DecoderInjector, the decoder is injected when the handler is registered
+func (v *cronJobValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+// responseFromError builds a denying response, keeping the structured status of the API errors.
+func responseFromError(err error) admission.Response {
+	var apiStatus apierrors.APIStatus
+	if goerrors.As(err, &apiStatus) {
+		status := apiStatus.Status()
+		return admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{Allowed: false, Result: &status}}
+	}
+	return admission.Denied(err.Error())
+}
+
+/*
+Some fields are declaratively validated by OpenAPI schema. You can find kubebuilder validation markers (prefixed
+with // +kubebuilder:validation) in the https://book.kubebuilder.io/cronjob-tutorial/api-design.html.
+*/
+
+/*
+validateCronJobName validates the ObjectMeta.Name field of the object
+
+Validating the length of a string field can be done declaratively by the validation schema.
+
+But the ObjectMeta.Name field is defined in a shared package under the apimachinery repo, so we can’t
+declaratively validate it using the validation schema.
+
+The rule is shared with the ValidatingAdmissionPolicy of config/admission-policy, see pkg/admissionpolicy.
+*/
+func validateCronJobName(r *batchv1.CronJob) field.ErrorList {
+	/*
+		The job name length is 63 character like all Kubernetes objects (which must fit in a DNS subdomain).
+		The cronjob controller appends a 11-character suffix to the cronjob (`-$TIMESTAMP`) when creating
+		a job. The job name length limit is 63 characters. Therefore cronjob names must have length <= 63-11=52. If
+		we don't validate this here, then job creation will fail later.
+	*/
+	if !admissionpolicy.Name.Allows(r.Name) {
+		return field.ErrorList{
+			field.Invalid(field.NewPath("metadata").Child("name"), r.Name, admissionpolicy.Name.Message),
+		}
+	}
+	return nil
+}
+
+// validateCronJobSpec validates the .spec of our CRD
+func validateCronJobSpec(r *batchv1.CronJob) field.ErrorList {
+	// The field helpers from the kubernetes API machinery help us return nicely structured validation errors.
+	var allErrs field.ErrorList
+	if err := validateScheduleFormat(
+		r.Spec.Schedule,
+		field.NewPath("spec").Child("schedule")); err != nil {
+		allErrs = append(allErrs, err)
+	}
+	if r.Spec.TimeZone != nil {
+		if _, err := time.LoadLocation(*r.Spec.TimeZone); err != nil || *r.Spec.TimeZone == "" {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec").Child("timeZone"), *r.Spec.TimeZone,
+				"unknown time zone"))
+		}
+	}
+	return allErrs
+}
+
+// validateNotifications validates the notifications name their channels once each.
+func validateNotifications(r *batchv1.CronJob) field.ErrorList {
+	var allErrs field.ErrorList
+	seen := map[string]bool{}
+	for i, n := range r.Spec.Notifications {
+		fldPath := field.NewPath("spec").Child("notifications").Index(i).Child("channel")
+		for _, msg := range validationutils.IsDNS1123Subdomain(n.Channel) {
+			allErrs = append(allErrs, field.Invalid(fldPath, n.Channel, msg))
+		}
+		if seen[n.Channel] {
+			allErrs = append(allErrs, field.Duplicate(fldPath, n.Channel))
+		}
+		seen[n.Channel] = true
+	}
+	return allErrs
+}
+
+// validateRunner validates the CronJob describes the runs of its runner, and only them.
+func validateRunner(r *batchv1.CronJob) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	switch r.Spec.Runner {
+	case batchv1.ArgoWorkflowRunner:
+		allErrs = append(allErrs, validateArgoWorkflow(r.Spec.ArgoWorkflow, specPath.Child("argoWorkflow"))...)
+	case batchv1.TektonPipelineRunner:
+		allErrs = append(allErrs, validateTektonPipeline(r.Spec.TektonPipeline, specPath.Child("tektonPipeline"))...)
+	default:
+		if len(r.Spec.JobTemplate.Spec.Template.Spec.Containers) == 0 {
+			allErrs = append(allErrs, field.Required(specPath.Child("jobTemplate"), "required by the Job runner"))
+		}
+	}
+
+	if r.Spec.ArgoWorkflow != nil && r.Spec.Runner != batchv1.ArgoWorkflowRunner {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("argoWorkflow"),
+			"only used by the ArgoWorkflow runner"))
+	}
+	if r.Spec.TektonPipeline != nil && r.Spec.Runner != batchv1.TektonPipelineRunner {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("tektonPipeline"),
+			"only used by the TektonPipeline runner"))
+	}
+	return allErrs
+}
+
+// validateSLO validates the SLO has a positive delay and a valid objective.
+func validateSLO(r *batchv1.CronJob) field.ErrorList {
+	spec := r.Spec.SLO
+	if spec == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	fldPath := field.NewPath("spec").Child("slo")
+	if spec.MaxStartDelay == nil && spec.CompleteWithin == nil {
+		allErrs = append(allErrs, field.Required(fldPath, "maxStartDelay or completeWithin is required"))
+	}
+	if spec.MaxStartDelay != nil && spec.MaxStartDelay.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxStartDelay"), spec.MaxStartDelay.Duration.String(),
+			"must be positive"))
+	}
+	if spec.CompleteWithin != nil && spec.CompleteWithin.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("completeWithin"), spec.CompleteWithin.Duration.String(),
+			"must be positive"))
+	}
+	if _, err := slo.Objective(spec); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("objective"), spec.Objective, err.Error()))
+	}
+	return allErrs
+}
+
+// validateArgoWorkflow validates the Workflow of the ArgoWorkflow runner.
+func validateArgoWorkflow(spec *batchv1.ArgoWorkflowSpec, fldPath *field.Path) field.ErrorList {
+	if spec == nil {
+		return field.ErrorList{field.Required(fldPath, "required by the ArgoWorkflow runner")}
+	}
+	var allErrs field.ErrorList
+	templatePath := fldPath.Child("workflowTemplateRef", "name")
+	for _, msg := range validationutils.IsDNS1123Subdomain(spec.WorkflowTemplateRef.Name) {
+		allErrs = append(allErrs, field.Invalid(templatePath, spec.WorkflowTemplateRef.Name, msg))
+	}
+	seen := map[string]bool{}
+	for i, p := range spec.Parameters {
+		if seen[p.Name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("parameters").Index(i).Child("name"), p.Name))
+		}
+		seen[p.Name] = true
+	}
+	return allErrs
+}
+
+// validateTektonPipeline validates the PipelineRun of the TektonPipeline runner.
+func validateTektonPipeline(spec *batchv1.TektonPipelineSpec, fldPath *field.Path) field.ErrorList {
+	if spec == nil {
+		return field.ErrorList{field.Required(fldPath, "required by the TektonPipeline runner")}
+	}
+	var allErrs field.ErrorList
+	pipelinePath := fldPath.Child("pipelineRef", "name")
+	for _, msg := range validationutils.IsDNS1123Subdomain(spec.PipelineRef.Name) {
+		allErrs = append(allErrs, field.Invalid(pipelinePath, spec.PipelineRef.Name, msg))
+	}
+	seen := map[string]bool{}
+	for i, p := range spec.Params {
+		if seen[p.Name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("params").Index(i).Child("name"), p.Name))
+		}
+		seen[p.Name] = true
+	}
+	if spec.ServiceAccountName != "" {
+		for _, msg := range validationutils.IsDNS1123Subdomain(spec.ServiceAccountName) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("serviceAccountName"), spec.ServiceAccountName, msg))
+		}
+	}
+	return allErrs
+}
+
+/*
+A schedule can be well-formatted and still never fire, like `0 0 30 2 *` (the 30th of February), or only fire while a
+maintenance window keeps the CronJob from running. Such a CronJob is almost always a mistake, so we make sure it has at
+least one activation within the activation horizon, computed like the controller does: in the time zone of the CronJob
+with the CronJobTimeZone feature gate, and outside of its maintenance windows.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=maintenancewindows,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=clustermaintenancewindows,verbs=get;list;watch
+
+// validateActivationHorizon validates the CronJob is scheduled at least once within the activation horizon.
+func (v *cronJobValidator) validateActivationHorizon(ctx context.Context, req admission.Request,
+	r *batchv1.CronJob) (field.ErrorList, []string) {
+	sched, err := cron.ParseStandard(r.Spec.Schedule)
+	if err != nil {
+		// reported by validateCronJobSpec
+		return nil, nil
+	}
+
+	now := time.Now()
+	if r.Spec.TimeZone != nil && featuregates.Enabled(featuregates.CronJobTimeZone) {
+		if loc, err := time.LoadLocation(*r.Spec.TimeZone); err == nil {
+			now = now.In(loc)
+		}
+	}
+	windows, err := v.maintenanceWindows(ctx, req, r)
+	if err != nil {
+		// the schedule alone is still checked
+		cronjoblog.Error(err, "unable to list the maintenance windows", "namespace", req.Namespace, "name", r.Name)
+	}
+	if next := maintenance.NextRun(sched, windows, now, now.Add(v.activationHorizon)); next.IsZero() {
+		reason := "has no activation"
+		if len(windows) > 0 {
+			reason = "has no activation outside of its maintenance windows"
+		}
+		return field.ErrorList{field.Invalid(field.NewPath("spec").Child("schedule"), r.Spec.Schedule,
+			fmt.Sprintf("%s within the next %s, the CronJob would never run", reason, v.activationHorizon))}, nil
+	}
+	return nil, nil
+}
+
+// maintenanceWindows returns the maintenance windows applying to the CronJob. The invalid windows are skipped, like
+// the controller does.
+func (v *cronJobValidator) maintenanceWindows(ctx context.Context, req admission.Request,
+	r *batchv1.CronJob) ([]*maintenance.Window, error) {
+	if v.Client == nil {
+		return nil, nil
+	}
+	var namespaced batchv1.MaintenanceWindowList
+	if err := v.namespacedReader(req.Namespace).List(ctx, &namespaced, client.InNamespace(req.Namespace)); err != nil {
+		return nil, err
+	}
+	var clusterWide batchv1.ClusterMaintenanceWindowList
+	if err := v.clusterReader().List(ctx, &clusterWide); err != nil {
+		return nil, err
+	}
+	named, err := maintenance.Select(r, namespaced.Items, clusterWide.Items, func() (*corev1.Namespace, error) {
+		namespace := &corev1.Namespace{}
+		return namespace, v.clusterReader().Get(ctx, client.ObjectKey{Name: req.Namespace}, namespace)
+	}, func(string, string, error) {})
+	if err != nil {
+		return nil, err
+	}
+	windows := make([]*maintenance.Window, 0, len(named))
+	for _, w := range named {
+		windows = append(windows, w.Window)
+	}
+	return windows, nil
+}
+
+/*
+The reconciler gives up on the missed runs older than startingDeadlineSeconds, and only starts the latest missed run
+without it, however many runs were missed. A deadline shorter than the reaction time of the controller makes every run
+missed, while a deadline longer than the interval between two runs does not bound anything, so we warn about the
+latter. The deadline is only checked when it or the schedule changes, so raising the minimum does not block the other
+updates of the existing CronJobs, like removing their finalizers.
+*/
+
+// validateStartingDeadline validates the starting deadline is long enough, and warns if it exceeds the schedule interval.
+func (v *cronJobValidator) validateStartingDeadline(_ context.Context, req admission.Request,
+	r *batchv1.CronJob) (field.ErrorList, []string) {
+	if r.Spec.StartingDeadlineSeconds == nil {
+		return nil, nil
+	}
+	if req.Operation == admissionv1.Update {
+		old := &batchv1.CronJob{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err == nil && old.Spec.Schedule == r.Spec.Schedule &&
+			equality.Semantic.DeepEqual(old.Spec.StartingDeadlineSeconds, r.Spec.StartingDeadlineSeconds) {
+			return nil, nil
+		}
+	}
+
+	fldPath := field.NewPath("spec").Child("startingDeadlineSeconds")
+	deadline := time.Duration(*r.Spec.StartingDeadlineSeconds) * time.Second
+	if deadline < v.minStartingDeadline {
+		return field.ErrorList{field.Invalid(fldPath, *r.Spec.StartingDeadlineSeconds,
+			fmt.Sprintf("must be at least %s, the controller may not react faster", v.minStartingDeadline))}, nil
+	}
+
+	if interval, ok := policy.ShortestInterval(r, deadline); ok && interval < deadline {
+		return nil, []string{fmt.Sprintf("%s (%s) exceeds the interval between two runs (%s), it does not bound the missed runs",
+			fldPath, deadline, interval)}
+	}
+	return nil, nil
+}
+
+// validateScheduleFormat validates the cron schedule is well-formatted. Besides being parsed, it must match the pattern
+// shared with the ValidatingAdmissionPolicy, which is stricter on the values nobody writes, like a step of 100.
+func validateScheduleFormat(schedule string, fldPath *field.Path) *field.Error {
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return field.Invalid(fldPath, schedule, err.Error())
+	}
+	if !admissionpolicy.Schedule.Allows(schedule) {
+		return field.Invalid(fldPath, schedule, admissionpolicy.Schedule.Message)
+	}
+	return nil
+}
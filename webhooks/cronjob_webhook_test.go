@@ -0,0 +1,263 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+)
+
+// newDecoder returns the decoder of the admission requests for the CronJobs.
+func newDecoder() *admission.Decoder {
+	scheme := runtime.NewScheme()
+	Expect(batchv1.AddToScheme(scheme)).To(Succeed())
+	decoder, err := admission.NewDecoder(scheme)
+	Expect(err).NotTo(HaveOccurred())
+	return decoder
+}
+
+// updateRequest returns the admission request updating the old CronJob.
+func updateRequest(old *batchv1.CronJob) admission.Request {
+	raw, err := json.Marshal(old)
+	Expect(err).NotTo(HaveOccurred())
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Update,
+		Namespace: old.Namespace,
+		OldObject: runtime.RawExtension{Raw: raw},
+	}}
+}
+
+var _ = Describe("CronJob validating webhook", func() {
+	var (
+		validator *cronJobValidator
+		cronJob   *batchv1.CronJob
+	)
+
+	BeforeEach(func() {
+		validator = &cronJobValidator{
+			decoder:             newDecoder(),
+			activationHorizon:   defaultActivationHorizon,
+			minStartingDeadline: time.Minute,
+		}
+		deadline := int64(30)
+		cronJob = &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly"},
+			Spec:       batchv1.CronJobSpec{Schedule: "0 2 * * *", StartingDeadlineSeconds: &deadline},
+		}
+	})
+
+	Context("starting deadline", func() {
+		It("Should reject a starting deadline below the minimum on create", func() {
+			errs, _ := validator.validateStartingDeadline(context.Background(),
+				admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Create}},
+				cronJob)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("spec.startingDeadlineSeconds"))
+		})
+
+		It("Should not check the starting deadline again on the updates which keep it", func() {
+			updated := cronJob.DeepCopy()
+			updated.Labels = map[string]string{"team": "data"}
+			errs, _ := validator.validateStartingDeadline(context.Background(), updateRequest(cronJob), updated)
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("Should check the starting deadline on the updates which change it or the schedule", func() {
+			updated := cronJob.DeepCopy()
+			updated.Spec.Schedule = "0 3 * * *"
+			errs, _ := validator.validateStartingDeadline(context.Background(), updateRequest(cronJob), updated)
+			Expect(errs).To(HaveLen(1))
+
+			updated = cronJob.DeepCopy()
+			deadline := int64(20)
+			updated.Spec.StartingDeadlineSeconds = &deadline
+			errs, _ = validator.validateStartingDeadline(context.Background(), updateRequest(cronJob), updated)
+			Expect(errs).To(HaveLen(1))
+		})
+	})
+
+	Context("activation horizon", func() {
+		create := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "default",
+		}}
+
+		BeforeEach(func() {
+			validator.activationHorizon = time.Hour
+		})
+
+		It("Should compute the activations in the time zone of the CronJob with the feature gate only", func() {
+			defer featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.CronJobTimeZone): false})
+			tokyo, err := time.LoadLocation("Asia/Tokyo")
+			Expect(err).NotTo(HaveOccurred())
+			// in half an hour in Tokyo, hours away in the time zone of the manager
+			soon := time.Now().In(tokyo).Add(30 * time.Minute)
+			cronJob.Spec.Schedule = fmt.Sprintf("%d %d * * *", soon.Minute(), soon.Hour())
+			cronJob.Spec.TimeZone = pointer.StringPtr("Asia/Tokyo")
+			if _, offset := time.Now().Zone(); offset == 9*60*60 {
+				Skip("the manager runs in the time zone of Tokyo")
+			}
+
+			errs, _ := validator.validateActivationHorizon(context.Background(), create, cronJob)
+			Expect(errs).To(HaveLen(1))
+			Expect(featuregates.Gates.SetFromMap(map[string]bool{string(featuregates.CronJobTimeZone): true})).
+				To(Succeed())
+			errs, _ = validator.validateActivationHorizon(context.Background(), create, cronJob)
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("Should not count the activations within the maintenance windows of the CronJob", func() {
+			// open every hour, for the whole hour
+			window := &batchv1.MaintenanceWindow{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "freeze"},
+				Spec: batchv1.MaintenanceWindowSpec{
+					Selector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "data"}},
+					Schedule: "0 * * * *",
+					Duration: metav1.Duration{Duration: time.Hour},
+				},
+			}
+			validator.Client = newFakeClient(window)
+			cronJob.Spec.Schedule = "*/5 * * * *"
+
+			errs, _ := validator.validateActivationHorizon(context.Background(), create, cronJob)
+			Expect(errs).To(BeEmpty())
+
+			cronJob.Labels = map[string]string{"team": "data"}
+			errs, _ = validator.validateActivationHorizon(context.Background(), create, cronJob)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Detail).To(ContainSubstring("no activation outside of its maintenance windows"))
+		})
+	})
+
+	Context("image registries", func() {
+		BeforeEach(func() {
+			validator.imageRegistries = configv1.ImageRegistriesConfig{
+				Allowed: []string{"registry.example.com", "docker.io/library"},
+			}
+			cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{
+				{Name: "report", Image: "registry.example.com/team/report:1.2"},
+			}
+		})
+
+		It("Should admit the images of the allowed registries", func() {
+			Expect(validator.validateImageRegistries(cronJob)).To(BeEmpty())
+		})
+
+		It("Should reject the images of the other registries", func() {
+			cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image = "quay.io/team/report:1.2"
+			errs := validator.validateImageRegistries(cronJob)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Type).To(Equal(field.ErrorTypeForbidden))
+			Expect(errs[0].Field).To(Equal("spec.jobTemplate.spec.template.spec.containers[0].image"))
+		})
+
+		It("Should check the images of the init containers", func() {
+			cronJob.Spec.JobTemplate.Spec.Template.Spec.InitContainers = []corev1.Container{
+				{Name: "fetch", Image: "quay.io/team/fetch"},
+			}
+			errs := validator.validateImageRegistries(cronJob)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Field).To(Equal("spec.jobTemplate.spec.template.spec.initContainers[0].image"))
+		})
+
+		It("Should take the images without a registry from the default one", func() {
+			cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image = "busybox:1.33"
+			Expect(validator.validateImageRegistries(cronJob)).To(BeEmpty())
+
+			cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image = "team/report"
+			Expect(validator.validateImageRegistries(cronJob)).To(HaveLen(1))
+
+			validator.imageRegistries = configv1.ImageRegistriesConfig{Denied: []string{"docker.io"}}
+			cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image = "busybox"
+			Expect(validator.validateImageRegistries(cronJob)).To(HaveLen(1))
+		})
+	})
+
+	Context("deprecations", func() {
+		var servedFields []deprecatedField
+
+		BeforeEach(func() {
+			servedFields = deprecatedFields
+			deprecatedFields = []deprecatedField{
+				{
+					path:        "spec.startingDeadlineSeconds",
+					replacement: "spec.startingDeadline",
+					isSet:       func(cronJob *batchv1.CronJob) bool { return cronJob.Spec.StartingDeadlineSeconds != nil },
+				},
+				{
+					path:  "spec.argoWorkflow",
+					isSet: func(cronJob *batchv1.CronJob) bool { return cronJob.Spec.ArgoWorkflow != nil },
+				},
+			}
+		})
+
+		AfterEach(func() {
+			deprecatedFields = servedFields
+		})
+
+		It("Should deprecate no field while v1 is the only version served", func() {
+			Expect(servedFields).To(BeEmpty())
+		})
+
+		It("Should warn about the deprecated fields and name their replacements", func() {
+			cronJob.Spec.Runner = batchv1.ArgoWorkflowRunner
+			cronJob.Spec.ArgoWorkflow = &batchv1.ArgoWorkflowSpec{
+				WorkflowTemplateRef: batchv1.ArgoWorkflowTemplateRef{Name: "report"},
+			}
+			Expect(deprecationWarnings(cronJob)).To(Equal([]string{
+				"spec.startingDeadlineSeconds is deprecated, use spec.startingDeadline of v2 instead",
+				"spec.argoWorkflow is deprecated and is dropped in v2",
+			}))
+
+			cronJob.Spec.StartingDeadlineSeconds = nil
+			cronJob.Spec.ArgoWorkflow = nil
+			Expect(deprecationWarnings(cronJob)).To(BeEmpty())
+		})
+
+		It("Should return the deprecation warnings with the response", func() {
+			validator.Client = newFakeClient()
+			deadline := int64(120)
+			cronJob.Spec.StartingDeadlineSeconds = &deadline
+			raw, err := json.Marshal(cronJob)
+			Expect(err).NotTo(HaveOccurred())
+			resp := validator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Namespace: cronJob.Namespace,
+				Name:      cronJob.Name,
+				Object:    runtime.RawExtension{Raw: raw},
+			}})
+			Expect(resp.Warnings).To(ContainElement(
+				"spec.startingDeadlineSeconds is deprecated, use spec.startingDeadline of v2 instead"))
+		})
+	})
+})
@@ -0,0 +1,147 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	configv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/config/v1"
+)
+
+/*
+After an upgrade of the operator, it is hard to tell whether a field was set by the user or defaulted by the
+mutating webhook. So the defaulting webhook records what it defaulted, to which value and where the value came from,
+both in an annotation of the CronJob and in the audit annotations of the request. The annotation is compact, e.g.
+`spec.suspend=false(builtin),spec.failedJobsHistoryLimit=1(builtin)`, and keeps the decisions of the earlier requests.
+*/
+
+const (
+	// DefaultedFieldsAnnotation lists the fields of the CronJob which were defaulted by the mutating webhook.
+	DefaultedFieldsAnnotation = "batch.example.com/defaulted-fields"
+
+	defaultedFieldsAuditAnnotation = "defaulted-fields"
+
+	// defaultSourceBuiltin marks the defaults hardcoded in the operator.
+	defaultSourceBuiltin = "builtin"
+	// defaultSourceConfig marks the defaults coming from the config file.
+	defaultSourceConfig = "config"
+)
+
+// defaultingDecision is a field set by the mutating webhook.
+type defaultingDecision struct {
+	field  string
+	value  string
+	source string
+}
+
+func (d defaultingDecision) String() string {
+	return fmt.Sprintf("%s=%s(%s)", d.field, d.value, d.source)
+}
+
+func builtinDefault(field string, value interface{}) defaultingDecision {
+	return defaultingDecision{field: field, value: fmt.Sprint(value), source: defaultSourceBuiltin}
+}
+
+func configDefault(field string, value interface{}) defaultingDecision {
+	return defaultingDecision{field: field, value: fmt.Sprint(value), source: defaultSourceConfig}
+}
+
+func formatDecisions(decisions []defaultingDecision) string {
+	entries := make([]string, 0, len(decisions))
+	for _, d := range decisions {
+		entries = append(entries, d.String())
+	}
+	return strings.Join(entries, ",")
+}
+
+// recordDefaults merges the decisions into the defaulted-fields annotation of the CronJob. A field defaulted again
+// replaces its previous entry.
+func recordDefaults(r *batchv1.CronJob, decisions []defaultingDecision) {
+	if len(decisions) == 0 {
+		return
+	}
+
+	entries := map[string]string{}
+	if existing := r.Annotations[DefaultedFieldsAnnotation]; existing != "" {
+		for _, entry := range strings.Split(existing, ",") {
+			entries[strings.SplitN(entry, "=", 2)[0]] = entry
+		}
+	}
+	for _, d := range decisions {
+		entries[d.field] = d.String()
+	}
+
+	merged := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		merged = append(merged, entry)
+	}
+	sort.Strings(merged)
+
+	if r.Annotations == nil {
+		r.Annotations = map[string]string{}
+	}
+	r.Annotations[DefaultedFieldsAnnotation] = strings.Join(merged, ",")
+}
+
+// DefaultedField is a field set by the mutating webhook, as recorded in the defaulted-fields annotation.
+type DefaultedField struct {
+	// Field is the path of the field, e.g. `spec.suspend`.
+	Field string
+	// Value is the value it was defaulted to.
+	Value string
+	// Source is where the value came from, `builtin` or `config`.
+	Source string
+}
+
+// DefaultedFields returns the fields recorded in the defaulted-fields annotation of the CronJob, sorted by path.
+func DefaultedFields(r *batchv1.CronJob) []DefaultedField {
+	var fields []DefaultedField
+	if existing := r.Annotations[DefaultedFieldsAnnotation]; existing != "" {
+		for _, entry := range strings.Split(existing, ",") {
+			parts := strings.SplitN(entry, "=", 2)
+			field := DefaultedField{Field: parts[0]}
+			if len(parts) == 2 {
+				field.Value = parts[1]
+				if open := strings.LastIndex(parts[1], "("); open >= 0 && strings.HasSuffix(parts[1], ")") {
+					field.Value, field.Source = parts[1][:open], parts[1][open+1:len(parts[1])-1]
+				}
+			}
+			fields = append(fields, field)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+// EffectiveDefaults returns the fields the mutating webhook would default on a new CronJob with the admission
+// settings, sorted by path. The default time zone is listed whenever it is configured, the CronJobTimeZone feature
+// gate of the manager is not checked.
+func EffectiveDefaults(config configv1.AdmissionConfig) []DefaultedField {
+	decisions := defaultCronJob(&batchv1.CronJob{}, config.Defaults)
+	if config.DefaultTimeZone != "" {
+		decisions = append(decisions, configDefault("spec.timeZone", config.DefaultTimeZone))
+	}
+	fields := make([]DefaultedField, 0, len(decisions))
+	for _, d := range decisions {
+		fields = append(fields, DefaultedField{Field: d.field, Value: d.value, Source: d.source})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
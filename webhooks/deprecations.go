@@ -0,0 +1,66 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"fmt"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+/*
+Once a new version of the CronJob API is served, the fields of v1 which do not survive the conversion should not be
+dropped silently. Every such field is listed in deprecatedFields along with the field replacing it, and the validating
+webhook warns the clients which still set it, so `kubectl` shows them what to migrate to.
+
+v1 is the only version served so far, so no field is deprecated yet. A field is only listed here along with the new
+version and the conversion which drops it, a warning naming a field of an unserved version would send the users
+nowhere.
+*/
+
+// nextVersion is the version of the API the deprecated fields are dropped from.
+const nextVersion = "v2"
+
+// deprecatedField is a v1 field which is deprecated in a newer version of the API.
+type deprecatedField struct {
+	// path of the deprecated field, e.g. `spec.startingDeadlineSeconds`
+	path string
+	// replacement is the path of the field to use instead, empty if the field is removed without a replacement.
+	replacement string
+	// isSet returns whether the CronJob sets the deprecated field.
+	isSet func(cronJob *batchv1.CronJob) bool
+}
+
+// deprecatedFields are the v1 fields dropped by nextVersion, none until it is served.
+var deprecatedFields []deprecatedField
+
+// deprecationWarnings returns a warning for every deprecated field the CronJob sets.
+func deprecationWarnings(r *batchv1.CronJob) []string {
+	var warnings []string
+	for _, f := range deprecatedFields {
+		if !f.isSet(r) {
+			continue
+		}
+		if f.replacement == "" {
+			warnings = append(warnings, fmt.Sprintf("%s is deprecated and is dropped in %s", f.path, nextVersion))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("%s is deprecated, use %s of %s instead", f.path, f.replacement,
+				nextVersion))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"fmt"
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+/*
+Platform teams can serve their own admission checks from the webhook server of the operator. Their package registers
+a named HandlerFactory with RegisterHandler, usually from an init function, and is blank imported by main.go. The
+`admission.extraHandlers` of the config file then decides which of the registered handlers are served, and at which
+paths. The webhook configurations pointing at those paths are deployed by the platform teams themselves.
+
+The handlers are instrumented like ours, and get the decoder and the other dependencies injected by the webhook server.
+*/
+
+// HandlerFactory creates an admission.Handler. The client is the one of the manager.
+type HandlerFactory func(c client.Client) (admission.Handler, error)
+
+var (
+	handlerFactoriesLock sync.Mutex
+	handlerFactories     = map[string]HandlerFactory{}
+)
+
+// RegisterHandler makes the handler available to the extraHandlers of the config file under the given name.
+// It panics if the name is registered twice.
+func RegisterHandler(name string, factory HandlerFactory) {
+	handlerFactoriesLock.Lock()
+	defer handlerFactoriesLock.Unlock()
+
+	if _, ok := handlerFactories[name]; ok {
+		panic(fmt.Sprintf("admission handler %q is already registered", name))
+	}
+	handlerFactories[name] = factory
+}
+
+// registerExtraHandlers serves the configured extra handlers on the webhook server.
+func (w *CronJobWebhook) registerExtraHandlers(server *webhook.Server) error {
+	handlerFactoriesLock.Lock()
+	defer handlerFactoriesLock.Unlock()
+
+	paths := map[string]bool{mutatingWebhookPath: true, validatingWebhookPath: true}
+	for _, extra := range w.Config.ExtraHandlers {
+		factory, ok := handlerFactories[extra.Name]
+		if !ok {
+			return fmt.Errorf("admission handler %q is not registered", extra.Name)
+		}
+		if paths[extra.Path] {
+			return fmt.Errorf("admission handler %q: path %q is already served", extra.Name, extra.Path)
+		}
+		paths[extra.Path] = true
+
+		handler, err := factory(w.Client)
+		if err != nil {
+			return fmt.Errorf("unable to create admission handler %q: %w", extra.Name, err)
+		}
+		server.Register(extra.Path, &webhook.Admission{Handler: w.instrument(extra.Name, handler)})
+		cronjoblog.Info("serving extra admission handler", "name", extra.Name, "path", extra.Path)
+	}
+	return nil
+}
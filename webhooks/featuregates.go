@@ -0,0 +1,85 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/featuregates"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/component-base/featuregate"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+/*
+The fields of the experimental features are part of the API, but they are only accepted when the feature gate is
+enabled. Otherwise the field would be stored and silently ignored by the controller. The CronJobs which already use
+a field, from before the gate was disabled, can still be updated, so that disabling a gate does not lock them. They
+are warned that the field has no effect.
+*/
+
+// gatedFields are the fields of the experimental features, with their gate.
+var gatedFields = []struct {
+	path    *field.Path
+	feature featuregate.Feature
+	used    func(cronJob *batchv1.CronJob) bool
+}{
+	{
+		path:    field.NewPath("spec", "timeZone"),
+		feature: featuregates.CronJobTimeZone,
+		used:    func(cronJob *batchv1.CronJob) bool { return cronJob.Spec.TimeZone != nil },
+	},
+	{
+		path:    field.NewPath("spec", "runner"),
+		feature: featuregates.ArgoWorkflowRunner,
+		used:    func(cronJob *batchv1.CronJob) bool { return cronJob.Spec.Runner == batchv1.ArgoWorkflowRunner },
+	},
+	{
+		path:    field.NewPath("spec", "runner"),
+		feature: featuregates.TektonPipelineRunner,
+		used:    func(cronJob *batchv1.CronJob) bool { return cronJob.Spec.Runner == batchv1.TektonPipelineRunner },
+	},
+}
+
+// validateFeatureGates rejects the fields of the disabled features, unless the CronJob already used them.
+func (v *cronJobValidator) validateFeatureGates(_ context.Context, req admission.Request, cronJob *batchv1.CronJob) (field.ErrorList, []string) {
+	var allErrs field.ErrorList
+	var warnings []string
+	var old *batchv1.CronJob
+	for _, gated := range gatedFields {
+		if !gated.used(cronJob) || featuregates.Enabled(gated.feature) {
+			continue
+		}
+		if old == nil && req.Operation == admissionv1.Update {
+			old = &batchv1.CronJob{}
+			if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+				old = &batchv1.CronJob{}
+			}
+		}
+		if old != nil && gated.used(old) {
+			warnings = append(warnings, fmt.Sprintf("%s has no effect while the %s feature gate is disabled",
+				gated.path, gated.feature))
+			continue
+		}
+		allErrs = append(allErrs, field.Forbidden(gated.path, fmt.Sprintf("requires the %s feature gate",
+			gated.feature)))
+	}
+	return allErrs, warnings
+}
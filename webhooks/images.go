@@ -0,0 +1,35 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/policy"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+/*
+The operator admins can restrict where the images of the Jobs come from. Every container of the job template,
+including the init and the ephemeral containers, is checked against the allowed and the denied registries of the
+config file. Since this is a security guardrail, it can not be bypassed. The matching is shared with the
+CronJobPolicies, see the policy package.
+*/
+
+// validateImageRegistries validates that the images of the job template come from the allowed registries.
+func (v *cronJobValidator) validateImageRegistries(r *batchv1.CronJob) field.ErrorList {
+	return policy.ValidateImageRegistries(r, v.imageRegistries.Allowed, v.imageRegistries.Denied)
+}
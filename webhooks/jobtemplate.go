@@ -0,0 +1,65 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+/*
+A CronJob referencing a JobTemplate gets its jobTemplate from it on admission, so the controller, the validating
+webhook and the schema of the CRD keep seeing a complete Job template. The template is only copied when the CronJob
+does not hold a revision of the referenced JobTemplate yet. Later changes of the JobTemplate are rolled out by the
+JobTemplate controller, which may start canary runs for them.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=jobtemplates,verbs=get;list;watch
+
+// resolveJobTemplate copies the referenced JobTemplate into the jobTemplate of the CronJob and records its revision.
+func (d *cronJobDefaulter) resolveJobTemplate(ctx context.Context, r *batchv1.CronJob) *field.Error {
+	ref := r.Spec.JobTemplateRef
+	if ref == nil {
+		return nil
+	}
+	if name, _, ok := batchv1.ParseJobTemplateRevision(r.Annotations[batchv1.JobTemplateRevisionAnnotation]); ok &&
+		name == ref.Name {
+		return nil
+	}
+
+	refPath := field.NewPath("spec", "jobTemplateRef", "name")
+	var template batchv1.JobTemplate
+	if err := d.client.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: ref.Name}, &template); err != nil {
+		if apierrors.IsNotFound(err) {
+			return field.NotFound(refPath, ref.Name)
+		}
+		return field.InternalError(refPath, fmt.Errorf("unable to get the JobTemplate: %w", err))
+	}
+
+	r.Spec.JobTemplate = *template.Spec.Template.DeepCopy()
+	if r.Annotations == nil {
+		r.Annotations = map[string]string{}
+	}
+	r.Annotations[batchv1.JobTemplateRevisionAnnotation] = batchv1.JobTemplateRevision(template.Name,
+		template.Generation)
+	return nil
+}
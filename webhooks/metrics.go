@@ -0,0 +1,100 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/errorreporting"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/tracing"
+)
+
+/*
+Every admission handler is wrapped with instrumentedHandler, which records the request in the metrics of the webhooks
+and traces it, the validating webhook additionally counts the rejections per validation rule. The panics of the
+handlers are reported to the error tracking service, they would otherwise only be logged by the HTTP server.
+*/
+
+// instrumentedHandler records the request count, latency and warnings of the wrapped admission.Handler.
+type instrumentedHandler struct {
+	webhook  string
+	handler  admission.Handler
+	reporter *errorreporting.ErrorReporter
+}
+
+var _ admission.Handler = &instrumentedHandler{}
+var _ inject.Injector = &instrumentedHandler{}
+
+// instrument wraps the given handler, name is used as the webhook label of the metrics.
+func (w *CronJobWebhook) instrument(name string, handler admission.Handler) admission.Handler {
+	return &instrumentedHandler{webhook: name, handler: handler, reporter: w.ErrorReporter}
+}
+
+// Handle implements admission.Handler
+func (h *instrumentedHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	operation := strings.ToLower(string(req.Operation))
+	ctx, span := tracing.Tracer().Start(ctx, "Admit "+h.webhook, trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("admission.operation", operation),
+			attribute.String("k8s.kind", req.Kind.Kind),
+			attribute.String("k8s.namespace.name", req.Namespace),
+			attribute.String("k8s.object.name", req.Name),
+		))
+	defer span.End()
+	defer h.reporter.Recover("webhook "+h.webhook, types.NamespacedName{Namespace: req.Namespace, Name: req.Name})
+
+	start := time.Now()
+	resp := h.handler.Handle(ctx, req)
+
+	result := responseResult(resp)
+	span.SetAttributes(attribute.String("admission.result", string(result)))
+	if result == metrics.AdmissionErrored && resp.Result != nil {
+		span.SetStatus(codes.Error, resp.Result.Message)
+	}
+	metrics.RecordAdmission(h.webhook, operation, result, time.Since(start), len(resp.Warnings))
+	return resp
+}
+
+// InjectFunc implements inject.Injector, so the decoder and the other dependencies reach the wrapped handler
+func (h *instrumentedHandler) InjectFunc(f inject.Func) error {
+	return f(h.handler)
+}
+
+// responseResult classifies the response for the result label.
+func responseResult(resp admission.Response) metrics.AdmissionResult {
+	switch {
+	case resp.Allowed:
+		return metrics.AdmissionAllowed
+	case resp.Result != nil && resp.Result.Code >= http.StatusInternalServerError:
+		return metrics.AdmissionErrored
+	case resp.Result != nil && resp.Result.Code == http.StatusBadRequest:
+		return metrics.AdmissionErrored
+	default:
+		return metrics.AdmissionDenied
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+// gathered returns the metric of the family with the given labels from the registry the metrics are served from,
+// nil if it was not recorded yet.
+func gathered(family string, labels map[string]string) *dto.Metric {
+	families, err := metrics.Registry.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, f := range families {
+		if f.GetName() != family {
+			continue
+		}
+	next:
+		for _, metric := range f.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value != label.GetValue() {
+					continue next
+				}
+			}
+			return metric
+		}
+	}
+	return nil
+}
+
+// counted returns the value of the counter with the given labels, zero if it was not recorded yet.
+func counted(family string, labels map[string]string) float64 {
+	return gathered(family, labels).GetCounter().GetValue()
+}
+
+// observed returns the observations of the histogram with the given labels, zero if it was not recorded yet.
+func observed(family string, labels map[string]string) uint64 {
+	return gathered(family, labels).GetHistogram().GetSampleCount()
+}
+
+var _ = Describe("CronJob webhook metrics", func() {
+	var (
+		webhook string
+		handler admission.Handler
+		cronJob *batchv1.CronJob
+	)
+
+	// handle sends the creation of the CronJob to the instrumented validating webhook.
+	handle := func() admission.Response {
+		raw, err := json.Marshal(cronJob)
+		Expect(err).NotTo(HaveOccurred())
+		return handler.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: cronJob.Namespace,
+			Name:      cronJob.Name,
+			Object:    runtime.RawExtension{Raw: raw},
+		}})
+	}
+
+	BeforeEach(func() {
+		// every spec has its own webhook label, the counters are shared by the whole suite
+		webhook = fmt.Sprintf("validating-%d", time.Now().UnixNano())
+		handler = &instrumentedHandler{webhook: webhook, handler: &cronJobValidator{
+			Client:              &accessReviewer{Client: newFakeClient()},
+			decoder:             newDecoder(),
+			activationHorizon:   defaultActivationHorizon,
+			minStartingDeadline: time.Minute,
+		}}
+		cronJob = &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly"},
+			Spec:       batchv1.CronJobSpec{Schedule: "0 * * * *"},
+		}
+		cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{Name: "report", Image: "report"}}
+	})
+
+	It("Should count and time the admitted requests", func() {
+		Expect(handle().Allowed).To(BeTrue())
+		Expect(handle().Allowed).To(BeTrue())
+
+		Expect(counted("cronjob_webhook_requests_total",
+			map[string]string{"webhook": webhook, "operation": "create", "result": "allowed"})).To(Equal(2.0))
+		Expect(observed("cronjob_webhook_request_duration_seconds",
+			map[string]string{"webhook": webhook, "operation": "create"})).To(BeEquivalentTo(2))
+		Expect(counted("cronjob_webhook_warnings_total", map[string]string{"webhook": webhook})).To(BeZero())
+		Expect(testutil.GatherAndCount(metrics.Registry, "cronjob_webhook_requests_total")).To(BeNumerically(">=", 1))
+	})
+
+	It("Should count the rejections per rule", func() {
+		rejections := counted("cronjob_webhook_rejections_total", map[string]string{"rule": "bad-schedule"})
+		cronJob.Spec.Schedule = "not a schedule"
+
+		resp := handle()
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(counted("cronjob_webhook_requests_total",
+			map[string]string{"webhook": webhook, "operation": "create", "result": "denied"})).To(Equal(1.0))
+		Expect(counted("cronjob_webhook_requests_total",
+			map[string]string{"webhook": webhook, "operation": "create", "result": "allowed"})).To(BeZero())
+		Expect(counted("cronjob_webhook_rejections_total", map[string]string{"rule": "bad-schedule"})).
+			To(Equal(rejections + 1))
+		Expect(observed("cronjob_webhook_request_duration_seconds",
+			map[string]string{"webhook": webhook, "operation": "create"})).To(BeEquivalentTo(1))
+	})
+
+	It("Should count the warnings of the responses", func() {
+		bypass := counted("cronjob_webhook_validation_warnings_total", map[string]string{"type": "bypass"})
+		// the requester may not bypass the validation, so the annotation is ignored with a warning
+		cronJob.Annotations = map[string]string{bypassValidationAnnotation: "true"}
+
+		resp := handle()
+		Expect(resp.Warnings).NotTo(BeEmpty())
+		Expect(counted("cronjob_webhook_warnings_total", map[string]string{"webhook": webhook})).
+			To(BeEquivalentTo(len(resp.Warnings)))
+		Expect(counted("cronjob_webhook_validation_warnings_total", map[string]string{"type": "bypass"})).
+			To(Equal(bypass + 1))
+	})
+
+	It("Should count the requests which could not be handled", func() {
+		resp := handler.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			Object:    runtime.RawExtension{Raw: []byte("{")},
+		}})
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(counted("cronjob_webhook_requests_total",
+			map[string]string{"webhook": webhook, "operation": "update", "result": "errored"})).To(Equal(1.0))
+	})
+})
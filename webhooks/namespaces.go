@@ -0,0 +1,48 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import "sigs.k8s.io/controller-runtime/pkg/client"
+
+/*
+The cache of the manager only holds the watched namespaces. With a single watched namespace, it still reads the
+cluster-scoped objects of the whole cluster, but with several of them it keeps a cache per namespace, which can neither
+read the objects of the other namespaces nor get the cluster-scoped ones. The webhooks receive the CronJobs of every
+namespace their namespaceSelector lets through, so the lookups the cache can not serve go to the API server instead.
+*/
+
+// namespacedReader returns the reader of the objects of the namespace, the cached client if the namespace is watched.
+func (v *cronJobValidator) namespacedReader(namespace string) client.Reader {
+	if len(v.watchNamespaces) == 0 || v.APIReader == nil {
+		return v.Client
+	}
+	for _, watched := range v.watchNamespaces {
+		if watched == namespace {
+			return v.Client
+		}
+	}
+	return v.APIReader
+}
+
+// clusterReader returns the reader of the cluster-scoped objects, the cached client unless it keeps a cache per
+// namespace.
+func (v *cronJobValidator) clusterReader() client.Reader {
+	if len(v.watchNamespaces) <= 1 || v.APIReader == nil {
+		return v.Client
+	}
+	return v.APIReader
+}
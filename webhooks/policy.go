@@ -0,0 +1,109 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/audit"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/metrics"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/policy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+/*
+The CronJobPolicies of the namespace and the ClusterCronJobPolicies are read through the cached client when it holds
+them, so the webhook does not hit the API server on every request. The policy package layers them, the cluster policies
+first, and a CronJob has to satisfy all of them. The errors name the violated policy, so the tenants know who to talk
+to.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch.example.com,resources=clustercronjobpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// validateCronJobPolicies validates the CronJob against the policies applying to its namespace.
+func (v *cronJobValidator) validateCronJobPolicies(ctx context.Context, req admission.Request,
+	r *batchv1.CronJob) (field.ErrorList, []string) {
+	fldPath := field.NewPath("metadata", "namespace")
+	var policies batchv1.CronJobPolicyList
+	if err := v.namespacedReader(req.Namespace).List(ctx, &policies, client.InNamespace(req.Namespace)); err != nil {
+		return field.ErrorList{field.InternalError(fldPath, fmt.Errorf("unable to list the CronJobPolicies: %w", err))},
+			nil
+	}
+	var clusterPolicies batchv1.ClusterCronJobPolicyList
+	if err := v.clusterReader().List(ctx, &clusterPolicies); err != nil {
+		return field.ErrorList{field.InternalError(fldPath,
+			fmt.Errorf("unable to list the ClusterCronJobPolicies: %w", err))}, nil
+	}
+
+	var namespace corev1.Namespace
+	if len(clusterPolicies.Items) > 0 {
+		if err := v.clusterReader().Get(ctx, client.ObjectKey{Name: req.Namespace}, &namespace); err != nil {
+			return field.ErrorList{field.InternalError(fldPath, fmt.Errorf("unable to get the namespace: %w", err))},
+				nil
+		}
+	}
+	layers, err := policy.Resolve(&namespace, clusterPolicies.Items, policies.Items)
+	if err != nil {
+		return field.ErrorList{field.InternalError(fldPath, err)}, nil
+	}
+	// the violations are counted per policy, so the most hit guardrails stand out
+	var errs field.ErrorList
+	for i, layer := range layers {
+		layerErrs := policy.EvaluateAll(r, layers[i:i+1])
+		if len(layerErrs) > 0 {
+			namespace := ""
+			if layer.Kind == policy.CronJobPolicyKind {
+				namespace = req.Namespace
+			}
+			metrics.RecordPolicyRejection(layer.Kind, namespace, layer.Name)
+		}
+		errs = append(errs, layerErrs...)
+	}
+	if len(layers) > 0 {
+		applied := make([]string, 0, len(layers))
+		for _, layer := range layers {
+			applied = append(applied, layer.Kind+"/"+layer.Name)
+		}
+		violations := make([]string, 0, len(errs))
+		for _, e := range errs {
+			violations = append(violations, e.Error())
+		}
+		decision := audit.Decision{
+			Decision:  audit.PolicyApplied,
+			Namespace: req.Namespace,
+			CronJob:   r.Name,
+			Reason:    "admitted",
+			Inputs: map[string]interface{}{"policies": applied, "violations": violations,
+				"operation": req.Operation, "user": req.UserInfo.Username},
+		}
+		if len(errs) > 0 {
+			decision.Reason = "denied"
+		}
+		if req.DryRun != nil && *req.DryRun {
+			decision.Inputs["dryRun"] = true
+		}
+		audit.Record(decision)
+	}
+	return errs, nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+var _ = Describe("CronJob policies", func() {
+	var (
+		reviewer  *accessReviewer
+		validator *cronJobValidator
+		cronJob   *batchv1.CronJob
+	)
+
+	create := func(namespace string) admission.Request {
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: namespace,
+			Name:      cronJob.Name,
+		}}
+	}
+
+	BeforeEach(func() {
+		reviewer = &accessReviewer{Client: newFakeClient(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "data", Labels: map[string]string{"team": "data"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+			&batchv1.ClusterCronJobPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "owners"},
+				Spec: batchv1.ClusterCronJobPolicySpec{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "data"}},
+					CronJobPolicySpec: batchv1.CronJobPolicySpec{RequiredLabels: []string{"owner"}},
+				},
+			},
+			&batchv1.CronJobPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "data", Name: "hourly"},
+				Spec:       batchv1.CronJobPolicySpec{MinInterval: &metav1.Duration{Duration: time.Hour}},
+			},
+		)}
+		validator = &cronJobValidator{
+			Client:              reviewer,
+			decoder:             newDecoder(),
+			activationHorizon:   defaultActivationHorizon,
+			minStartingDeadline: time.Minute,
+		}
+		cronJob = &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "data", Name: "nightly"},
+			Spec:       batchv1.CronJobSpec{Schedule: "0 * * * *"},
+		}
+		cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{Name: "report", Image: "report"}}
+	})
+
+	It("Should reject the CronJobs violating a policy of their namespace", func() {
+		errs, _ := validator.validateCronJobPolicies(context.Background(), create("data"), cronJob)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("metadata.labels[owner]"))
+		Expect(errs[0].Detail).To(ContainSubstring(`violates ClusterCronJobPolicy "owners"`))
+
+		cronJob.Labels = map[string]string{"owner": "data-team"}
+		cronJob.Spec.Schedule = "*/5 * * * *"
+		errs, _ = validator.validateCronJobPolicies(context.Background(), create("data"), cronJob)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Detail).To(ContainSubstring(`violates CronJobPolicy "hourly"`))
+
+		cronJob.Spec.Schedule = "0 * * * *"
+		errs, _ = validator.validateCronJobPolicies(context.Background(), create("data"), cronJob)
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("Should admit the CronJobs of the namespaces no policy applies to", func() {
+		cronJob.Namespace = "web"
+		cronJob.Spec.Schedule = "*/5 * * * *"
+		errs, _ := validator.validateCronJobPolicies(context.Background(), create("web"), cronJob)
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("Should skip the policies when the requester may bypass them", func() {
+		cronJob.Annotations = map[string]string{bypassValidationAnnotation: "true"}
+		raw, err := json.Marshal(cronJob)
+		Expect(err).NotTo(HaveOccurred())
+		req := create("data")
+		req.Object = runtime.RawExtension{Raw: raw}
+		req.UserInfo.Username = "oncall@example.com"
+
+		reviewer.allowed = false
+		resp := validator.Handle(context.Background(), req)
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring(`violates ClusterCronJobPolicy "owners"`))
+
+		reviewer.allowed = true
+		resp = validator.Handle(context.Background(), req)
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.AuditAnnotations[bypassAuditAnnotation]).To(ContainSubstring("cronjob-policy"))
+	})
+})
@@ -0,0 +1,94 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+	"github.com/bilalcaliskan/kubebuilder-tutorial/pkg/quota"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+/*
+A runaway automation can create thousands of CronJobs, each of them creating Jobs on its own. The cheapest defense is
+to cap the number of CronJobs per namespace at admission. The CronJobs of the watched namespaces are counted through the
+cached client, which the controller already keeps warm, so the count may lag behind by a few objects under a burst of
+creates.
+*/
+
+// validateNamespaceQuota validates that creating the CronJob does not exceed the per-namespace limit.
+func (v *cronJobValidator) validateNamespaceQuota(ctx context.Context, req admission.Request,
+	r *batchv1.CronJob) (field.ErrorList, []string) {
+	if v.maxCronJobs <= 0 || req.Operation != admissionv1.Create {
+		return nil, nil
+	}
+
+	var cronJobs batchv1.CronJobList
+	if err := v.namespacedReader(req.Namespace).List(ctx, &cronJobs, client.InNamespace(req.Namespace)); err != nil {
+		return field.ErrorList{field.InternalError(field.NewPath("metadata", "namespace"),
+			fmt.Errorf("unable to count the CronJobs: %w", err))}, nil
+	}
+	if len(cronJobs.Items) >= v.maxCronJobs {
+		return field.ErrorList{field.Forbidden(field.NewPath("metadata", "namespace"),
+			fmt.Sprintf("namespace %q already has %d CronJobs, the limit is %d", req.Namespace,
+				len(cronJobs.Items), v.maxCronJobs))}, nil
+	}
+	return nil, nil
+}
+
+/*
+The CronJobQuotas of the namespace cap the CronJobs too, with the limits the tenants are told about in the status of the
+quotas. They are read, and the CronJobs counted, through the cached client like above.
+*/
+
+//+kubebuilder:rbac:groups=batch.example.com,resources=cronjobquotas,verbs=get;list;watch
+
+// validateCronJobQuotas validates that creating the CronJob does not exceed the CronJobQuotas of the namespace.
+func (v *cronJobValidator) validateCronJobQuotas(ctx context.Context, req admission.Request,
+	r *batchv1.CronJob) (field.ErrorList, []string) {
+	if req.Operation != admissionv1.Create {
+		return nil, nil
+	}
+
+	fldPath := field.NewPath("metadata", "namespace")
+	var quotas batchv1.CronJobQuotaList
+	if err := v.namespacedReader(req.Namespace).List(ctx, &quotas, client.InNamespace(req.Namespace)); err != nil {
+		return field.ErrorList{field.InternalError(fldPath, fmt.Errorf("unable to list the CronJobQuotas: %w", err))},
+			nil
+	}
+	if len(quotas.Items) == 0 {
+		return nil, nil
+	}
+	var cronJobs batchv1.CronJobList
+	if err := v.namespacedReader(req.Namespace).List(ctx, &cronJobs, client.InNamespace(req.Namespace)); err != nil {
+		return field.ErrorList{field.InternalError(fldPath, fmt.Errorf("unable to count the CronJobs: %w", err))}, nil
+	}
+
+	usage := batchv1.CronJobQuotaUsage{CronJobs: int32(len(cronJobs.Items))}
+	var allErrs field.ErrorList
+	for i := range quotas.Items {
+		if denied := quota.CronJobDenied(&quotas.Items[i], usage); denied != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath, denied))
+		}
+	}
+	return allErrs, nil
+}
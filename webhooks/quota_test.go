@@ -0,0 +1,157 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	batchv1 "github.com/bilalcaliskan/kubebuilder-tutorial/apis/batch/v1"
+)
+
+// newFakeClient returns a fake client holding the objects, with the types of the CronJobs and the built-in ones.
+func newFakeClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(batchv1.AddToScheme(scheme)).To(Succeed())
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+// namespacedCache fails to read the namespaces it does not hold, like the cache of several namespaces.
+type namespacedCache struct {
+	client.Client
+	namespace string
+}
+
+func (c *namespacedCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.Namespace != c.namespace {
+		return fmt.Errorf("unable to get: %v because of unknown namespace for the cache", listOpts.Namespace)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+var _ = Describe("CronJob quotas", func() {
+	var (
+		validator *cronJobValidator
+		request   admission.Request
+		cronJob   *batchv1.CronJob
+	)
+
+	BeforeEach(func() {
+		maxCronJobs := int32(1)
+		quota := &batchv1.CronJobQuota{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "unwatched", Name: "cronjobs"},
+			Spec:       batchv1.CronJobQuotaSpec{MaxCronJobs: &maxCronJobs},
+		}
+		existing := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "unwatched", Name: "existing"}}
+		apiServer := newFakeClient(quota, existing)
+		validator = &cronJobValidator{
+			Client:          &namespacedCache{Client: newFakeClient(), namespace: "watched"},
+			APIReader:       apiServer,
+			watchNamespaces: []string{"watched", "other"},
+		}
+		cronJob = &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "unwatched", Name: "nightly"}}
+		request = admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "unwatched",
+		}}
+	})
+
+	It("Should read the quotas of the namespaces which are not watched from the API server", func() {
+		errs, _ := validator.validateCronJobQuotas(context.Background(), request, cronJob)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Type).To(Equal(field.ErrorTypeForbidden))
+		Expect(errs[0].Detail).To(ContainSubstring(`CronJobQuota "cronjobs"`))
+	})
+
+	It("Should read the quotas of the watched namespaces from the cache", func() {
+		request.Namespace, cronJob.Namespace = "watched", "watched"
+		errs, _ := validator.validateCronJobQuotas(context.Background(), request, cronJob)
+		Expect(errs).To(BeEmpty())
+	})
+})
+
+var _ = Describe("CronJob namespace quota", func() {
+	var (
+		validator *cronJobValidator
+		cronJob   *batchv1.CronJob
+	)
+
+	request := func(operation admissionv1.Operation) admission.Request {
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: operation,
+			Namespace: "default",
+		}}
+	}
+
+	BeforeEach(func() {
+		validator = &cronJobValidator{
+			Client: newFakeClient(
+				&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "first"}},
+				&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "second"}},
+				&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "third"}},
+			),
+			maxCronJobs: 3,
+		}
+		cronJob = &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nightly"}}
+	})
+
+	It("Should admit the CronJob filling the namespace up to the limit", func() {
+		errs, _ := validator.validateNamespaceQuota(context.Background(), request(admissionv1.Create), cronJob)
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("Should reject the CronJobs of a namespace at the limit", func() {
+		validator.maxCronJobs = 2
+		errs, _ := validator.validateNamespaceQuota(context.Background(), request(admissionv1.Create), cronJob)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Type).To(Equal(field.ErrorTypeForbidden))
+		Expect(errs[0].Detail).To(Equal(`namespace "default" already has 2 CronJobs, the limit is 2`))
+	})
+
+	It("Should reject the CronJobs of a namespace over the limit, e.g. after the limit was lowered", func() {
+		validator.maxCronJobs = 1
+		errs, _ := validator.validateNamespaceQuota(context.Background(), request(admissionv1.Create), cronJob)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Detail).To(Equal(`namespace "default" already has 2 CronJobs, the limit is 1`))
+	})
+
+	It("Should not count the updates, which do not add a CronJob", func() {
+		validator.maxCronJobs = 1
+		errs, _ := validator.validateNamespaceQuota(context.Background(), request(admissionv1.Update), cronJob)
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("Should not limit the CronJobs without a limit", func() {
+		validator.maxCronJobs = 0
+		errs, _ := validator.validateNamespaceQuota(context.Background(), request(admissionv1.Create), cronJob)
+		Expect(errs).To(BeEmpty())
+	})
+})